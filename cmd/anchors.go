@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/samzong/mdctl/internal/anchors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	slugStyle  string
+	renameFile string
+
+	anchorsCmd = &cobra.Command{
+		Use:   "anchors",
+		Short: "Manage heading anchors and the links pointing at them",
+		Long: `Keep deep links stable across heading edits.
+
+Anchors are computed the way the site generator does: --slug-style github
+(GitHub, Hugo, Docusaurus) or mkdocs (Python-Markdown toc). Explicit IDs
+written as "## Title {#custom-id}" always take precedence.
+
+Examples:
+  # Freeze the current anchors as explicit IDs
+  mdctl anchors add docs/ --slug-style mkdocs
+
+  # Report duplicate slugs and links to missing anchors
+  mdctl anchors check docs/
+
+  # Update links after headings were renamed
+  mdctl anchors rename --map renames.yaml docs/`,
+	}
+
+	anchorsAddCmd = &cobra.Command{
+		Use:   "add [path]",
+		Short: "Add explicit {#id} attributes to headings without one",
+		Long: `Append an explicit ID to every heading that has none, using the anchor the
+heading currently gets. Later edits of the heading text no longer change its
+anchor, so existing deep links keep working.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := anchors.ValidStyle(slugStyle); err != nil {
+				return err
+			}
+			changes, err := anchors.Add(indexRoot(args), slugStyle, dryRun)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(changes)
+			}
+			total := 0
+			for _, c := range changes {
+				total += c.Headings
+				fmt.Printf("%s: %d headings\n", c.File, c.Headings)
+			}
+			verb := "Added"
+			if dryRun {
+				verb = "Would add"
+			}
+			fmt.Printf("%s %d explicit IDs in %d files\n", verb, total, len(changes))
+			return nil
+		},
+	}
+
+	anchorsCheckCmd = &cobra.Command{
+		Use:   "check [path]",
+		Short: "Check for duplicate slugs and links to missing anchors",
+		Long: `Report headings of a page that share a slug, whose anchors then depend on
+their order, and links whose #fragment matches no heading of the target page.
+Exits with a non-zero status when problems are found.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := anchors.ValidStyle(slugStyle); err != nil {
+				return err
+			}
+			problems, err := anchors.Check(indexRoot(args), slugStyle)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				if err := printJSON(problems); err != nil {
+					return err
+				}
+			} else {
+				for _, p := range problems {
+					fmt.Printf("%s:%d: %s: %s\n", p.File, p.Line, p.Kind, p.Message)
+				}
+				if len(problems) == 0 {
+					fmt.Println("✓ No anchor problems found")
+				}
+			}
+
+			if len(problems) > 0 {
+				exitWith(1)
+			}
+			return nil
+		},
+	}
+
+	anchorsRenameCmd = &cobra.Command{
+		Use:   "rename --map <file> [path]",
+		Short: "Update links to renamed headings",
+		Long: `Rewrite the #fragment of links pointing at renamed headings. The mapping file
+is a YAML list; file limits an entry to the headings of one page, relative to
+the path:
+
+  - file: guide/install.md
+    from: installing-on-linux
+    to: linux
+  - from: faq
+    to: frequently-asked-questions`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			renames, err := anchors.LoadRenames(renameFile)
+			if err != nil {
+				return err
+			}
+			changes, err := anchors.Rename(indexRoot(args), renames, dryRun)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(changes)
+			}
+			for _, c := range changes {
+				fmt.Printf("%s:%d: %s -> %s\n", c.File, c.Line, c.Old, c.New)
+			}
+			verb := "Updated"
+			if dryRun {
+				verb = "Would update"
+			}
+			fmt.Printf("%s %d links\n", verb, len(changes))
+			return nil
+		},
+	}
+)
+
+func init() {
+	anchorsCmd.PersistentFlags().StringVar(&slugStyle, "slug-style", anchors.StyleGitHub, "Slug style of the site generator: github or mkdocs")
+	anchorsRenameCmd.Flags().StringVar(&renameFile, "map", "", "YAML file mapping old anchors to new ones")
+	anchorsRenameCmd.MarkFlagRequired("map")
+
+	anchorsCmd.AddCommand(anchorsAddCmd)
+	anchorsCmd.AddCommand(anchorsCheckCmd)
+	anchorsCmd.AddCommand(anchorsRenameCmd)
+	anchorsCmd.GroupID = "core"
+	rootCmd.AddCommand(anchorsCmd)
+}
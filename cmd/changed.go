@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/samzong/mdctl/internal/changed"
+	"github.com/spf13/cobra"
+)
+
+var (
+	changedSince  string
+	changedStaged bool
+)
+
+// addChangedFlags registers the git change selection flags on a command
+func addChangedFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&changedSince, "since", "", "Only process files changed since this git ref (e.g. origin/main)")
+	cmd.Flags().BoolVar(&changedStaged, "staged", false, "Only process files staged for commit")
+}
+
+// changedOptions returns the change selection of the current command
+func changedOptions() changed.Options {
+	return changed.Options{Since: changedSince, Staged: changedStaged}
+}
+
+// changedFiles returns the files changed below the given files or directories
+func changedFiles(paths ...string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		list, err := changed.Files(path, changedOptions())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list changed files in %s: %v", path, err)
+		}
+		files = append(files, list...)
+	}
+	return files, nil
+}
@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/anchors"
+	"github.com/samzong/mdctl/internal/check"
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/linter"
+	"github.com/samzong/mdctl/internal/schema"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkDir          string
+	checkSkip         []string
+	checkOnly         []string
+	checkFormat       string
+	checkOutput       string
+	checkLintConfig   string
+	checkBaseline     string
+	checkStyles       []string
+	checkSchema       string
+	checkSlugStyle    string
+	checkMaxImageSize int64
+	checkFailOn       string
+	checkMaxWarnings  int
+	checkMax          map[string]int
+	checkConcurrency  int
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Run lint, link, front matter and image checks as one quality gate",
+	Long: `Check a documentation directory in one pass and report all findings together,
+the single entry point of a CI pipeline:
+
+  lint         markdownlint rules and prose styles, as mdctl lint
+  links        links to missing pages (LINK001) or anchors (LINK002) and
+               headings sharing an anchor (LINK003)
+  frontmatter  front matter YAML syntax and, with --frontmatter-schema, the
+               schema (FM001)
+  images       local images that do not exist (IMG001) or are larger than
+               --max-image-size (IMG002)
+
+--only and --skip select the checks. The report is text, json (also --json),
+sarif for code scanning services, or github for GitHub Actions annotations;
+--output writes it to a file and prints the summary instead.
+
+The run fails when a finding reaches the --fail-on severity (default error),
+when there are more than --max-warnings warnings, or when a check has more
+findings than its --max limit.
+
+Examples:
+  mdctl check -d docs/
+  mdctl check -d docs/ --frontmatter-schema schema.yaml --slug-style mkdocs
+  mdctl check -d docs/ --format sarif --output mdctl.sarif
+  mdctl check -d docs/ --skip images --fail-on warning
+  mdctl check -d docs/ --max-warnings 20 --max links=0,lint=50`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format := checkFormat
+		if jsonOutput {
+			format = "json"
+		}
+		switch format {
+		case "text", "json", "sarif", "github":
+		default:
+			return fmt.Errorf("unsupported format: %s (must be text, json, sarif or github)", format)
+		}
+		thresholds := check.Thresholds{FailOn: checkFailOn, MaxWarnings: checkMaxWarnings, Max: checkMax}
+		if err := thresholds.Validate(); err != nil {
+			return err
+		}
+		if err := anchors.ValidStyle(checkSlugStyle); err != nil {
+			return err
+		}
+		checks, err := selectedChecks()
+		if err != nil {
+			return err
+		}
+
+		opts := check.Options{
+			Dir:          checkDir,
+			Checks:       checks,
+			Lint:         &linter.Config{RulesFile: checkLintConfig, Verbose: verbose},
+			SlugStyle:    checkSlugStyle,
+			MaxImageSize: checkMaxImageSize,
+			Concurrency:  checkConcurrency,
+		}
+		if opts.Lint.Styles, err = linter.LoadStyles(checkStyles); err != nil {
+			return err
+		}
+		if checkBaseline != "" {
+			if opts.Lint.Baseline, err = linter.LoadBaseline(checkBaseline); err != nil {
+				return err
+			}
+		}
+		if checkSchema != "" {
+			if opts.Schema, err = schema.Load(checkSchema); err != nil {
+				return err
+			}
+		}
+
+		report, err := check.Run(opts)
+		if err != nil {
+			return err
+		}
+		failed := report.Evaluate(thresholds)
+
+		var out bytes.Buffer
+		switch format {
+		case "json":
+			err = printCheckJSON(&out, report)
+		case "sarif":
+			err = report.WriteSARIF(&out, Version)
+		case "github":
+			printCheckGitHub(&out, report)
+		default:
+			printCheckText(&out, report)
+		}
+		if err != nil {
+			return err
+		}
+
+		if checkOutput != "" {
+			if err := fsutil.WriteFileAtomic(checkOutput, out.Bytes(), 0644); err != nil {
+				return fmt.Errorf("failed to write report: %v", err)
+			}
+			fmt.Printf("Report written to %s\n", checkOutput)
+			printCheckSummary(os.Stdout, report)
+		} else if _, err := resultWriter.Write(out.Bytes()); err != nil {
+			return err
+		}
+
+		if failed {
+			exitWith(1)
+		}
+		return nil
+	},
+}
+
+// selectedChecks returns the checks chosen by --only and --skip
+func selectedChecks() ([]string, error) {
+	for _, c := range append(append([]string{}, checkOnly...), checkSkip...) {
+		if !check.ValidCheck(c) {
+			return nil, fmt.Errorf("unknown check: %s (must be one of %s)", c, strings.Join(check.Checks, ", "))
+		}
+	}
+	selected := checkOnly
+	if len(selected) == 0 {
+		selected = check.Checks
+	}
+	var checks []string
+	for _, c := range selected {
+		if !containsString(checkSkip, c) {
+			checks = append(checks, c)
+		}
+	}
+	if len(checks) == 0 {
+		return nil, fmt.Errorf("no checks left to run")
+	}
+	return checks, nil
+}
+
+// printCheckJSON writes the report as an indented JSON document
+func printCheckJSON(out io.Writer, report *check.Report) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// printCheckText writes the findings grouped by file and the summary
+func printCheckText(out io.Writer, report *check.Report) {
+	file := ""
+	for _, f := range report.Findings {
+		if f.File != file {
+			file = f.File
+			fmt.Fprintf(out, "%s:\n", file)
+		}
+		fmt.Fprintf(out, "  Line %d [%s] %s: %s (%s)\n", f.Line, f.Check, f.Severity, f.Message, f.Rule)
+	}
+	if len(report.Findings) == 0 {
+		fmt.Fprintln(out, "✓ No problems found")
+	}
+	printCheckSummary(out, report)
+}
+
+// printCheckGitHub writes the findings as GitHub Actions workflow commands
+func printCheckGitHub(out io.Writer, report *check.Report) {
+	for _, f := range report.Findings {
+		level := f.Severity
+		if level == linter.SeverityInfo {
+			level = "notice"
+		}
+		fmt.Fprintf(out, "::%s file=%s,line=%d::%s\n", level, githubProperty(f.File), f.Line, githubData(f.Message+" ("+f.Rule+")"))
+	}
+	printCheckSummary(out, report)
+}
+
+// githubData escapes the message of a workflow command, which ends at the
+// first line break
+func githubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// githubProperty escapes a property value of a workflow command, where commas
+// and colons also end the value
+func githubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ",", "%2C", ":", "%3A").Replace(s)
+}
+
+// printCheckSummary writes the counts of the findings and the exceeded
+// thresholds
+func printCheckSummary(out io.Writer, report *check.Report) {
+	s := report.Summary
+	fmt.Fprintf(out, "\nChecked %d files (%s): %d errors, %d warnings, %d info\n",
+		s.Files, strings.Join(report.Checks, ", "), s.Errors, s.Warnings, s.Info)
+	for _, c := range report.Checks {
+		fmt.Fprintf(out, "  %-12s %d\n", c, s.ByCheck[c])
+	}
+	for _, failure := range report.Failures {
+		fmt.Fprintf(out, "Failed: %s\n", failure)
+	}
+}
+
+func init() {
+	checkCmd.Flags().StringVarP(&checkDir, "dir", "d", ".", "Documentation directory to check")
+	checkCmd.Flags().StringSliceVar(&checkOnly, "only", nil, "Only run these checks (lint, links, frontmatter, images)")
+	checkCmd.Flags().StringSliceVar(&checkSkip, "skip", nil, "Skip these checks (lint, links, frontmatter, images)")
+	checkCmd.Flags().StringVar(&checkFormat, "format", "text", "Report format: text, json, sarif, github")
+	checkCmd.Flags().StringVarP(&checkOutput, "output", "o", "", "Write the report to this file and print the summary")
+	checkCmd.Flags().StringVar(&checkLintConfig, "lint-config", "", "Path to markdownlint configuration file (default .markdownlint.json when present)")
+	checkCmd.Flags().StringVar(&checkBaseline, "baseline", "", "Lint baseline file of known issues that are not reported")
+	checkCmd.Flags().StringSliceVar(&checkStyles, "style", nil, "Prose style rule files (default .mdctl-style.yaml when present)")
+	checkCmd.Flags().StringVar(&checkSchema, "frontmatter-schema", "", "JSON Schema (JSON or YAML) the front matter must match")
+	checkCmd.Flags().StringVar(&checkSlugStyle, "slug-style", anchors.StyleGitHub, "Anchor style of the site generator: github or mkdocs")
+	checkCmd.Flags().Int64Var(&checkMaxImageSize, "max-image-size", 1<<20, "Largest local image in bytes (0 for no limit)")
+	checkCmd.Flags().StringVar(&checkFailOn, "fail-on", linter.SeverityError, "Lowest severity failing the run: error, warning, info or none")
+	checkCmd.Flags().IntVar(&checkMaxWarnings, "max-warnings", -1, "Fail when there are more warnings than this (-1 for no limit)")
+	checkCmd.Flags().StringToIntVar(&checkMax, "max", nil, "Findings allowed per check before the run fails, e.g. links=0,lint=50")
+	checkCmd.Flags().IntVar(&checkConcurrency, "concurrency", runtime.NumCPU(), "Number of files linted concurrently")
+
+	checks := cobra.FixedCompletions(check.Checks, cobra.ShellCompDirectiveNoFileComp)
+	registerCompletion(checkCmd, "only", checks)
+	registerCompletion(checkCmd, "skip", checks)
+	registerCompletion(checkCmd, "format", cobra.FixedCompletions([]string{"text", "json", "sarif", "github"}, cobra.ShellCompDirectiveNoFileComp))
+	registerCompletion(checkCmd, "fail-on", cobra.FixedCompletions(check.FailLevels, cobra.ShellCompDirectiveNoFileComp))
+	registerCompletion(checkCmd, "slug-style", cobra.FixedCompletions([]string{anchors.StyleGitHub, anchors.StyleMkDocs}, cobra.ShellCompDirectiveNoFileComp))
+
+	rootCmd.AddCommand(checkCmd)
+	checkCmd.GroupID = "core"
+}
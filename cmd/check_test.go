@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/check"
+	"github.com/samzong/mdctl/internal/linter"
+)
+
+func TestPrintCheckGitHub(t *testing.T) {
+	tests := []struct {
+		name    string
+		finding check.Finding
+		want    string
+	}{
+		{
+			"plain",
+			check.Finding{Rule: "MD001", Severity: linter.SeverityWarning, File: "docs/index.md", Line: 3, Message: "Heading levels should only increment by one level at a time"},
+			"::warning file=docs/index.md,line=3::Heading levels should only increment by one level at a time (MD001)",
+		},
+		{
+			"info is a notice",
+			check.Finding{Rule: "MD013", Severity: linter.SeverityInfo, File: "a.md", Line: 1, Message: "Line length"},
+			"::notice file=a.md,line=1::Line length (MD013)",
+		},
+		{
+			"message escapes",
+			check.Finding{Rule: "FM001", Severity: linter.SeverityError, File: "a.md", Line: 1, Message: "100% invalid:\nline 2\r\nline 3, done"},
+			"::error file=a.md,line=1::100%25 invalid:%0Aline 2%0D%0Aline 3, done (FM001)",
+		},
+		{
+			"property escapes",
+			check.Finding{Rule: "LINK001", Severity: linter.SeverityError, File: "C:/docs/a,b%.md", Line: 7, Message: "Broken link"},
+			"::error file=C%3A/docs/a%2Cb%25.md,line=7::Broken link (LINK001)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			printCheckGitHub(&out, &check.Report{Findings: []check.Finding{tt.finding}})
+			if got, _, _ := strings.Cut(out.String(), "\n"); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/exporter"
+	"github.com/samzong/mdctl/internal/linter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	noDescriptions bool
+
+	completionCmd = &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Generate the autocompletion script for a shell",
+		Long: `Generate the autocompletion script for mdctl for the given shell.
+
+Besides commands and flags, values are completed for flags such as --storage
+(configured storages), --format, --theme and the rule IDs of lint --enable
+and --disable.
+
+Examples:
+  # Bash, current session
+  source <(mdctl completion bash)
+
+  # Bash, every session (Linux)
+  mdctl completion bash > /etc/bash_completion.d/mdctl
+
+  # Zsh, every session
+  mdctl completion zsh > "${fpath[1]}/_mdctl"
+
+  # Fish, every session
+  mdctl completion fish > ~/.config/fish/completions/mdctl.fish
+
+  # PowerShell, current session
+  mdctl completion powershell | Out-String | Invoke-Expression`,
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			switch args[0] {
+			case "bash":
+				return rootCmd.GenBashCompletionV2(out, !noDescriptions)
+			case "zsh":
+				if noDescriptions {
+					return rootCmd.GenZshCompletionNoDesc(out)
+				}
+				return rootCmd.GenZshCompletion(out)
+			case "fish":
+				return rootCmd.GenFishCompletion(out, !noDescriptions)
+			default:
+				if noDescriptions {
+					return rootCmd.GenPowerShellCompletion(out)
+				}
+				return rootCmd.GenPowerShellCompletionWithDesc(out)
+			}
+		},
+	}
+)
+
+// completeStorages completes the names of the configured storages
+func completeStorages(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for name, storage := range cfg.CloudStorages {
+		description := storage.Provider
+		if name == cfg.DefaultStorage {
+			description += " (default)"
+		}
+		names = append(names, name+"\t"+description)
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeRuleIDs completes lint rule IDs, the IDs already given in a comma
+// separated list are kept as prefix and not offered again
+func completeRuleIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	prefix := ""
+	given := make(map[string]bool)
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix = toComplete[:i+1]
+		for _, id := range strings.Split(toComplete[:i], ",") {
+			given[id] = true
+		}
+	}
+	var ids []string
+	for id, description := range linter.RuleDescriptions() {
+		if !given[id] {
+			ids = append(ids, prefix+id+"\t"+description)
+		}
+	}
+	sort.Strings(ids)
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeThemes completes the stored export templates and built-in themes
+func completeThemes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	templates, err := exporter.DefaultTemplateStore().List()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, t := range templates {
+		description := strings.Join(t.Formats, ", ")
+		if t.Description != "" {
+			description = t.Description
+		}
+		names = append(names, t.Name+"\t"+description)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// registerCompletion registers the value completion of a flag, a missing
+// flag is a programming error
+func registerCompletion(cmd *cobra.Command, flag string, complete func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective)) {
+	if err := cmd.RegisterFlagCompletionFunc(flag, complete); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to register completion for --%s: %v\n", flag, err)
+	}
+}
+
+func init() {
+	completionCmd.Flags().BoolVar(&noDescriptions, "no-descriptions", false, "Disable completion descriptions")
+	rootCmd.AddCommand(completionCmd)
+}
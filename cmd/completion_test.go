@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+func TestDynamicCompletion(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	templates := filepath.Join(home, ".config", "mdctl", "templates")
+	if err := os.MkdirAll(templates, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(templates, "corporate.css"), []byte("body {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(home, "mdctl.json")
+	cfg := `{"cloud_storages": {"assets": {"provider": "s3"}, "backup": {"provider": "r2"}}, "default_storage": "assets"}`
+	if err := os.WriteFile(path, []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.ConfigEnv, path)
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string // Completions that are offered
+		notWant []string // Completions that are not offered
+	}{
+		{"storages", []string{"upload", "--storage", ""}, []string{"assets\ts3 (default)", "backup\tr2"}, nil},
+		{"rule ids", []string{"lint", "--enable", ""}, []string{"MD001\t", "MD009\t"}, nil},
+		{"rule id list", []string{"lint", "--disable", "MD001,"}, []string{"MD001,MD009\t"}, []string{"MD001,MD001\t"}},
+		{"themes", []string{"export", "--theme", ""}, []string{"corporate\tepub"}, nil},
+		{"fixed values", []string{"lint", "--format", ""}, []string{"default", "json", "github"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := runStream(t, "", append([]string{"__complete"}, tt.args...)...)
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			if directive := lines[len(lines)-1]; directive != ":4" {
+				t.Errorf("expected file completion to be disabled, got directive %s", directive)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("expected %q to be offered in:\n%s", want, out)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out, notWant) {
+					t.Errorf("expected %q not to be offered in:\n%s", notWant, out)
+				}
+			}
+		})
+	}
+}
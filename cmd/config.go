@@ -6,19 +6,34 @@ import (
 	"strings"
 
 	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/netutil"
+	mdstorage "github.com/samzong/mdctl/internal/storage"
 	"github.com/spf13/cobra"
 )
 
 var (
-	configKey   string
-	configValue string
-	storageName string
+	configKey      string
+	configValue    string
+	storageName    string
+	secretsBackend string
 )
 
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Manage configuration",
-	Long:  `View and modify configuration settings`,
+	Long: `View and modify configuration settings.
+
+Settings are stored in ~/.config/mdctl/config.json. The global --config flag
+or the MDCTL_CONFIG environment variable select another file, so several
+profiles can coexist. Such files are never created or repaired when read
+and a missing one is an error; "mdctl config set" creates them on first use.
+
+Examples:
+  # Keep a separate profile for work
+  mdctl --config ~/.config/mdctl/work.json config set --key model --value gpt-4o
+
+  # Use it for every command of a CI job
+  MDCTL_CONFIG=/etc/mdctl/config.json mdctl translate -f README.md -l zh`,
 }
 
 var configListCmd = &cobra.Command{
@@ -33,24 +48,48 @@ var configListCmd = &cobra.Command{
 		// Create a temporary struct to control JSON output
 		type ConfigDisplay struct {
 			TranslatePrompt   string                        `json:"translate_prompt"`
+			TranslatePrompts  map[string]string             `json:"translate_prompts,omitempty"`
 			OpenAIEndpointURL string                        `json:"endpoint"`
 			OpenAIAPIKey      string                        `json:"api_key"`
 			ModelName         string                        `json:"model"`
+			EmbeddingModel    string                        `json:"embedding_model,omitempty"`
 			Temperature       float64                       `json:"temperature"`
 			TopP              float64                       `json:"top_p"`
 			CloudStorages     map[string]config.CloudConfig `json:"cloud_storages,omitempty"`
 			DefaultStorage    string                        `json:"default_storage,omitempty"`
+			AIRequestsPerMin  int                           `json:"ai_requests_per_minute,omitempty"`
+			AITokensPerMin    int                           `json:"ai_tokens_per_minute,omitempty"`
+			AIDailyTokenQuota int                           `json:"ai_daily_token_quota,omitempty"`
+			AITimeout         int                           `json:"ai_timeout,omitempty"`
+			AIMaxRetries      int                           `json:"ai_max_retries,omitempty"`
+			AIStream          bool                          `json:"ai_stream,omitempty"`
+			Proxy             string                        `json:"proxy,omitempty"`
+			SecretsBackend    string                        `json:"secrets_backend,omitempty"`
+			Metrics           bool                          `json:"metrics,omitempty"`
+			Author            string                        `json:"author,omitempty"`
 		}
 
 		display := ConfigDisplay{
 			TranslatePrompt:   cfg.TranslatePrompt,
+			TranslatePrompts:  cfg.TranslatePrompts,
 			OpenAIEndpointURL: cfg.OpenAIEndpointURL,
 			OpenAIAPIKey:      cfg.OpenAIAPIKey,
 			ModelName:         cfg.ModelName,
+			EmbeddingModel:    cfg.EmbeddingModel,
 			Temperature:       cfg.Temperature,
 			TopP:              cfg.TopP,
 			CloudStorages:     cfg.CloudStorages,
 			DefaultStorage:    cfg.DefaultStorage,
+			AIRequestsPerMin:  cfg.AIRequestsPerMin,
+			AITokensPerMin:    cfg.AITokensPerMin,
+			AIDailyTokenQuota: cfg.AIDailyTokenQuota,
+			AITimeout:         cfg.AITimeout,
+			AIMaxRetries:      cfg.AIMaxRetries,
+			AIStream:          cfg.AIStream,
+			Proxy:             cfg.Proxy,
+			SecretsBackend:    cfg.SecretsBackend,
+			Metrics:           cfg.Metrics,
+			Author:            cfg.Author,
 		}
 
 		data, err := json.MarshalIndent(display, "", "  ")
@@ -68,11 +107,50 @@ var configSetCmd = &cobra.Command{
 	Short: "Set a configuration value",
 	Example: `  mdctl config set --key api_key --value "your-api-key"
   mdctl config set --key model --value "gpt-4"
+  mdctl config set --key embedding_model --value "text-embedding-3-large"
   mdctl config set --key temperature --value "0.8"
+
+  # Translation prompt of a single target language
+  mdctl config set --key translate_prompts.ja --value "Translate the markdown to Japanese using the polite desu/masu form ..."
+
+  # AI usage limits (0 disables a limit)
+  mdctl config set --key ai_requests_per_minute --value 20
+  mdctl config set --key ai_tokens_per_minute --value 40000
+  mdctl config set --key ai_daily_token_quota --value 500000
+
+  # AI request timeout in seconds, retries (-1 disables them) and streaming
+  mdctl config set --key ai_timeout --value 300
+  mdctl config set --key ai_max_retries --value 5
+  mdctl config set --key ai_stream --value true
+
+  # Record local usage metrics of every run, see "mdctl stats runs"
+  mdctl config set --key metrics --value true
+
+  # Author filled into documents created with "mdctl new"
+  mdctl config set --key author --value "Jane Doe"
+
+  # Proxy of all HTTP requests (default: HTTP_PROXY/HTTPS_PROXY/NO_PROXY)
+  mdctl config set --key proxy --value "http://proxy.example.com:3128"
   
   # Cloud storage configuration
   mdctl config set --key cloud_storages.my-s3.provider --value "s3"
-  mdctl config set --key cloud_storages.my-r2.provider --value "r2"`,
+  mdctl config set --key cloud_storages.my-r2.provider --value "r2"
+
+  # Backblaze B2, DigitalOcean Spaces and Wasabi derive the endpoint and
+  # public URLs from the region
+  mdctl config set --key cloud_storages.my-spaces.provider --value "spaces"
+  mdctl config set --key cloud_storages.my-spaces.region --value "nyc3"
+
+  # S3 object settings and server-side encryption
+  mdctl config set --key cloud_storages.my-s3.storage_class --value STANDARD_IA
+  mdctl config set --key cloud_storages.my-s3.sse --value SSE-KMS
+  mdctl config set --key cloud_storages.my-s3.sse_kms_key_id --value "arn:aws:kms:..."
+  mdctl config set --key cloud_storages.my-s3.multipart_threshold --value 64
+
+  # Aliyun OSS, Tencent COS and Qiniu Kodo
+  mdctl config set --key cloud_storages.my-oss.provider --value "oss"
+  mdctl config set --key cloud_storages.my-oss.region --value "cn-hangzhou"
+  mdctl config set --key cloud_storages.my-oss.provider_opts.signed_url_ttl --value "8760h"`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if configKey == "" {
 			return fmt.Errorf("key is required")
@@ -81,7 +159,8 @@ var configSetCmd = &cobra.Command{
 			return fmt.Errorf("value is required")
 		}
 
-		cfg, err := config.LoadConfig()
+		// A file chosen with --config is created by its first value
+		cfg, err := config.LoadConfigForUpdate()
 		if err != nil {
 			return fmt.Errorf("failed to load config: %v", err)
 		}
@@ -146,7 +225,33 @@ var configSetCmd = &cobra.Command{
 				storage.ConflictPolicy = policy
 			case "cache_dir":
 				storage.CacheDir = configValue
+			case "multipart_threshold":
+				var threshold int
+				if _, err := fmt.Sscanf(configValue, "%d", &threshold); err != nil || threshold < 5 {
+					return fmt.Errorf("invalid multipart threshold: %s (must be at least 5 MiB)", configValue)
+				}
+				storage.MultipartThreshold = threshold
+			case "storage_class":
+				storage.StorageClass = configValue
+			case "acl":
+				storage.ACL = configValue
+			case "sse":
+				sse, err := mdstorage.NormalizeSSE(configValue)
+				if err != nil {
+					return err
+				}
+				storage.SSE = sse
+			case "sse_kms_key_id":
+				storage.SSEKMSKeyID = configValue
 			default:
+				// Provider specific options, e.g. provider_opts.signed_url_ttl
+				if opt := strings.TrimPrefix(strings.ToLower(field), "provider_opts."); opt != strings.ToLower(field) && opt != "" {
+					if storage.ProviderOpts == nil {
+						storage.ProviderOpts = make(map[string]string)
+					}
+					storage.ProviderOpts[opt] = configValue
+					break
+				}
 				return fmt.Errorf("unknown cloud storage configuration key: %s", field)
 			}
 
@@ -158,6 +263,15 @@ var configSetCmd = &cobra.Command{
 				cfg.DefaultStorage = storageName
 			}
 
+		} else if lang := strings.TrimPrefix(strings.ToLower(configKey), "translate_prompts."); lang != strings.ToLower(configKey) {
+			// Prompts of a target language with translate_prompts.<lang>
+			if lang == "" {
+				return fmt.Errorf("invalid config key format: %s", configKey)
+			}
+			if cfg.TranslatePrompts == nil {
+				cfg.TranslatePrompts = make(map[string]string)
+			}
+			cfg.TranslatePrompts[lang] = configValue
 		} else {
 			// Handle existing config settings
 			switch strings.ToLower(configKey) {
@@ -169,6 +283,8 @@ var configSetCmd = &cobra.Command{
 				cfg.OpenAIAPIKey = configValue
 			case "model":
 				cfg.ModelName = configValue
+			case "embedding_model":
+				cfg.EmbeddingModel = configValue
 			case "temperature":
 				var temp float64
 				if _, err := fmt.Sscanf(configValue, "%f", &temp); err != nil {
@@ -181,11 +297,51 @@ var configSetCmd = &cobra.Command{
 					return fmt.Errorf("invalid top_p value: %s", configValue)
 				}
 				cfg.TopP = topP
+			case "ai_requests_per_minute", "ai_tokens_per_minute", "ai_daily_token_quota":
+				var limit int
+				if _, err := fmt.Sscanf(configValue, "%d", &limit); err != nil || limit < 0 {
+					return fmt.Errorf("invalid %s value: %s", configKey, configValue)
+				}
+				switch strings.ToLower(configKey) {
+				case "ai_requests_per_minute":
+					cfg.AIRequestsPerMin = limit
+				case "ai_tokens_per_minute":
+					cfg.AITokensPerMin = limit
+				default:
+					cfg.AIDailyTokenQuota = limit
+				}
+			case "ai_timeout":
+				var timeout int
+				if _, err := fmt.Sscanf(configValue, "%d", &timeout); err != nil || timeout < 0 {
+					return fmt.Errorf("invalid ai_timeout value: %s", configValue)
+				}
+				cfg.AITimeout = timeout
+			case "ai_max_retries":
+				var retries int
+				if _, err := fmt.Sscanf(configValue, "%d", &retries); err != nil || retries < -1 {
+					return fmt.Errorf("invalid ai_max_retries value: %s", configValue)
+				}
+				cfg.AIMaxRetries = retries
+			case "ai_stream":
+				cfg.AIStream = strings.ToLower(configValue) == "true"
+			case "metrics":
+				cfg.Metrics = strings.ToLower(configValue) == "true"
+			case "author":
+				cfg.Author = configValue
+			case "proxy":
+				if err := netutil.SetProxy(configValue); err != nil {
+					return err
+				}
+				cfg.Proxy = configValue
 			default:
 				return fmt.Errorf("unknown configuration key: %s", configKey)
 			}
 		}
 
+		if dryRun {
+			fmt.Printf("Would set %s to %s in %s\n", configKey, configValue, config.GetConfigPath())
+			return nil
+		}
 		if err := config.SaveConfig(cfg); err != nil {
 			return fmt.Errorf("failed to save config: %v", err)
 		}
@@ -256,7 +412,21 @@ var configGetCmd = &cobra.Command{
 				value = cfg.CloudStorages[storageName].ConflictPolicy
 			case "cache_dir":
 				value = cfg.CloudStorages[storageName].CacheDir
+			case "multipart_threshold":
+				value = cfg.CloudStorages[storageName].MultipartThreshold
+			case "storage_class":
+				value = cfg.CloudStorages[storageName].StorageClass
+			case "acl":
+				value = cfg.CloudStorages[storageName].ACL
+			case "sse":
+				value = cfg.CloudStorages[storageName].SSE
+			case "sse_kms_key_id":
+				value = cfg.CloudStorages[storageName].SSEKMSKeyID
 			default:
+				if opt := strings.TrimPrefix(strings.ToLower(field), "provider_opts."); opt != strings.ToLower(field) && opt != "" {
+					value = cfg.CloudStorages[storageName].ProviderOpts[opt]
+					break
+				}
 				return fmt.Errorf("unknown cloud storage configuration key: %s", field)
 			}
 
@@ -264,6 +434,11 @@ var configGetCmd = &cobra.Command{
 			return nil
 		}
 
+		if lang := strings.TrimPrefix(strings.ToLower(configKey), "translate_prompts."); lang != strings.ToLower(configKey) {
+			fmt.Printf("%v\n", cfg.TranslatePrompts[lang])
+			return nil
+		}
+
 		// Handle existing config settings
 		var value interface{}
 		switch strings.ToLower(configKey) {
@@ -275,10 +450,30 @@ var configGetCmd = &cobra.Command{
 			value = cfg.OpenAIAPIKey
 		case "model":
 			value = cfg.ModelName
+		case "embedding_model":
+			value = cfg.EmbeddingModel
 		case "temperature":
 			value = cfg.Temperature
 		case "top_p":
 			value = cfg.TopP
+		case "ai_requests_per_minute":
+			value = cfg.AIRequestsPerMin
+		case "ai_tokens_per_minute":
+			value = cfg.AITokensPerMin
+		case "ai_daily_token_quota":
+			value = cfg.AIDailyTokenQuota
+		case "ai_timeout":
+			value = cfg.AITimeout
+		case "ai_max_retries":
+			value = cfg.AIMaxRetries
+		case "ai_stream":
+			value = cfg.AIStream
+		case "metrics":
+			value = cfg.Metrics
+		case "author":
+			value = cfg.Author
+		case "proxy":
+			value = cfg.Proxy
 		default:
 			return fmt.Errorf("unknown configuration key: %s", configKey)
 		}
@@ -309,6 +504,10 @@ var configSetDefaultStorageCmd = &cobra.Command{
 
 		cfg.DefaultStorage = storageName
 
+		if dryRun {
+			fmt.Printf("Would set the default storage to %s\n", storageName)
+			return nil
+		}
 		if err := config.SaveConfig(cfg); err != nil {
 			return fmt.Errorf("failed to save config: %v", err)
 		}
@@ -354,15 +553,58 @@ var configListStoragesCmd = &cobra.Command{
 	},
 }
 
+var configMigrateSecretsCmd = &cobra.Command{
+	Use:   "migrate-secrets",
+	Short: "Move API keys and secret keys out of the configuration file",
+	Long: `Move the API key and the secret keys of the cloud storages to another
+backend. Secrets set later are stored with the same backend, and they are
+decrypted transparently whenever the configuration is loaded.
+
+Backends:
+  keyring     The OS keychain: macOS Keychain or the Secret Service on Linux
+              (requires secret-tool). The file only keeps references.
+  passphrase  age encryption (scrypt) with the passphrase in
+              MDCTL_PASSPHRASE, which has to be set for commands using
+              the secrets; without it they are empty.
+  plain       Store the secrets in the file again.`,
+	Example: `  mdctl config migrate-secrets --backend keyring
+  MDCTL_PASSPHRASE=... mdctl config migrate-secrets --backend passphrase`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.ValidSecretsBackend(secretsBackend); err != nil {
+			return err
+		}
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %v", err)
+		}
+
+		count, err := cfg.MigrateSecrets(secretsBackend)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			fmt.Printf("Would move %d secrets to the %s backend\n", count, secretsBackend)
+			return nil
+		}
+		if err := config.SaveConfig(cfg); err != nil {
+			return fmt.Errorf("failed to save config: %v", err)
+		}
+
+		fmt.Printf("Moved %d secrets to the %s backend\n", count, secretsBackend)
+		return nil
+	},
+}
+
 func init() {
 	configCmd.AddCommand(configListCmd)
 	configCmd.AddCommand(configSetCmd)
 	configCmd.AddCommand(configGetCmd)
 	configCmd.AddCommand(configSetDefaultStorageCmd)
 	configCmd.AddCommand(configListStoragesCmd)
+	configCmd.AddCommand(configMigrateSecretsCmd)
 
 	configSetCmd.Flags().StringVarP(&configKey, "key", "k", "", "Configuration key to set")
-	configSetCmd.Flags().StringVarP(&configValue, "value", "v", "", "Value to set")
+	configSetCmd.Flags().StringVar(&configValue, "value", "", "Value to set")
 	configSetCmd.MarkFlagRequired("key")
 	configSetCmd.MarkFlagRequired("value")
 
@@ -371,4 +613,7 @@ func init() {
 
 	configSetDefaultStorageCmd.Flags().StringVarP(&storageName, "name", "n", "", "Storage name to set as default")
 	configSetDefaultStorageCmd.MarkFlagRequired("name")
+
+	configMigrateSecretsCmd.Flags().StringVar(&secretsBackend, "backend", config.SecretsKeyring, "Secrets backend: keyring, passphrase or plain")
+	registerCompletion(configMigrateSecretsCmd, "backend", cobra.FixedCompletions([]string{config.SecretsKeyring, config.SecretsPassphrase, config.SecretsPlain}, cobra.ShellCompDirectiveNoFileComp))
 }
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+func TestConfigFlag(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(config.ConfigEnv, "")
+	defer func() { configFile = ""; config.SetConfigPath("") }()
+	run := func(args ...string) error {
+		t.Helper()
+		rootCmd.SetArgs(args)
+		return rootCmd.Execute()
+	}
+
+	path := filepath.Join(t.TempDir(), "mdctl.json")
+	if err := run("--config", path, "config", "get", "--key", "model"); err == nil {
+		t.Errorf("expected an error for a missing --config file")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("config get created %s", path)
+	}
+
+	if err := run("--config", path, "config", "set", "--key", "model", "--value", "gpt-4o"); err != nil {
+		t.Fatalf("config set failed: %v", err)
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil || cfg.ModelName != "gpt-4o" {
+		t.Errorf("expected config set to create %s, got %+v, %v", path, cfg, err)
+	}
+
+	// MDCTL_CONFIG selects the file without the flag
+	configFile = ""
+	t.Setenv(config.ConfigEnv, filepath.Join(t.TempDir(), "missing.json"))
+	if err := run("config", "get", "--key", "model"); err == nil {
+		t.Errorf("expected an error for a missing %s file", config.ConfigEnv)
+	}
+	t.Setenv(config.ConfigEnv, path)
+	if err := run("config", "get", "--key", "model"); err != nil {
+		t.Errorf("config get with %s failed: %v", config.ConfigEnv, err)
+	}
+}
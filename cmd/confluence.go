@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/samzong/mdctl/internal/confluence"
+	"github.com/samzong/mdctl/internal/exporter"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	confluenceURL    string
+	confluenceUser   string
+	confluenceToken  string
+	confluenceSpace  string
+	confluencePage   string
+	confluenceOutput string
+	confluenceFile   string
+	confluenceDir    string
+	confluenceParent string
+
+	importConfluenceCmd = &cobra.Command{
+		Use:   "confluence",
+		Short: "Import a Confluence space or page tree as markdown",
+		Long: `Pull pages from Confluence through its REST API and convert them to markdown.
+
+The page hierarchy becomes the directory structure: pages with children are
+written to <page>/index.md. Attached images are downloaded to images/<page>/
+next to each page, external images are downloaded like mdctl download does,
+and links between imported pages become relative links. The front matter
+records the page ID so mdctl export confluence updates the same pages.
+
+Credentials are read from --url, --user and --token or the CONFLUENCE_URL,
+CONFLUENCE_USER and CONFLUENCE_TOKEN environment variables. Without a user the
+token is sent as a bearer token (Server/Data Center personal access tokens).
+
+Examples:
+  mdctl import confluence --url https://example.atlassian.net/wiki --space DOCS -o docs/
+  mdctl import confluence --page 123456 -o docs/guide`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if confluenceSpace == "" && confluencePage == "" {
+				return fmt.Errorf("either a space (--space) or a root page (--page) must be specified")
+			}
+			client, err := confluenceClient()
+			if err != nil {
+				return err
+			}
+
+			stats, err := confluence.Import(cmd.Context(), client, confluence.ImportOptions{
+				Space:     confluenceSpace,
+				PageID:    confluencePage,
+				OutputDir: confluenceOutput,
+				DryRun:    dryRun,
+			})
+			if stats == nil {
+				return err
+			}
+			if err != nil && interrupted(cmd) {
+				err = fmt.Errorf("import interrupted after %d pages", stats.Pages)
+			}
+
+			if jsonOutput {
+				printJSON(stats)
+				return err
+			}
+			fmt.Printf("Imported %d pages to %s (%d attachments, %d images downloaded)\n",
+				stats.Pages, confluenceOutput, stats.Attachments, stats.Images)
+			return err
+		},
+	}
+
+	exportConfluenceCmd = &cobra.Command{
+		Use:   "confluence",
+		Short: "Publish markdown files as Confluence pages",
+		Long: `Convert markdown files to Confluence storage format with Pandoc and publish
+one page per file.
+
+Files imported with mdctl import confluence update their original page, other
+files update the page with the same title in the space or create a new page
+below --parent. The title comes from the title front matter, the first heading
+or the file name. Local images are uploaded as attachments and links between
+the published files become page links.
+
+Examples:
+  mdctl export confluence -f guide.md --space DOCS
+  mdctl export confluence -d docs/ --space DOCS --parent 123456
+  mdctl export confluence -d docs/ --space DOCS --dry-run`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if confluenceFile == "" && confluenceDir == "" {
+				return fmt.Errorf("either source file (-f) or source directory (-d) must be specified")
+			}
+			if confluenceFile != "" && confluenceDir != "" {
+				return fmt.Errorf("cannot specify both source file (-f) and source directory (-d)")
+			}
+			if confluenceSpace == "" {
+				return fmt.Errorf("space (--space) must be specified")
+			}
+			if err := exporter.CheckPandocAvailability(); err != nil {
+				return err
+			}
+			client, err := confluenceClient()
+			if err != nil {
+				return err
+			}
+
+			files := []string{confluenceFile}
+			if confluenceDir != "" {
+				if files, err = markdownFiles(confluenceDir); err != nil {
+					return err
+				}
+			}
+
+			stats, err := confluence.Publish(cmd.Context(), client, files, confluence.PublishOptions{
+				Space:    confluenceSpace,
+				ParentID: confluencePage,
+				DryRun:   dryRun,
+			})
+			if stats == nil {
+				return err
+			}
+
+			if jsonOutput {
+				printJSON(stats)
+				return err
+			}
+			for _, page := range stats.Pages {
+				fmt.Printf("%s: %s %q\n", page.File, page.Action, page.Title)
+			}
+			fmt.Printf("Created %d and updated %d pages, uploaded %d attachments\n", stats.Created, stats.Updated, stats.Attachments)
+			return err
+		},
+	}
+)
+
+// confluenceClient builds a Confluence client from the flags and environment
+func confluenceClient() (*confluence.Client, error) {
+	for _, setting := range []struct {
+		value *string
+		env   string
+	}{
+		{&confluenceURL, "CONFLUENCE_URL"},
+		{&confluenceUser, "CONFLUENCE_USER"},
+		{&confluenceToken, "CONFLUENCE_TOKEN"},
+	} {
+		if *setting.value == "" {
+			*setting.value = os.Getenv(setting.env)
+		}
+	}
+
+	if confluenceURL == "" {
+		return nil, fmt.Errorf("confluence URL (--url or CONFLUENCE_URL) must be specified")
+	}
+	if confluenceToken == "" {
+		return nil, fmt.Errorf("confluence token (--token or CONFLUENCE_TOKEN) must be specified")
+	}
+	return confluence.NewClient(confluenceURL, confluenceUser, confluenceToken), nil
+}
+
+// markdownFiles lists the markdown files of a directory
+func markdownFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && mddoc.IsMarkdown(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list markdown files: %v", err)
+	}
+	return files, nil
+}
+
+// addConfluenceFlags adds the connection flags shared by import and export
+func addConfluenceFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&confluenceURL, "url", "", "Confluence site URL, e.g. https://example.atlassian.net/wiki (default $CONFLUENCE_URL)")
+	cmd.Flags().StringVar(&confluenceUser, "user", "", "Account email for API token authentication (default $CONFLUENCE_USER)")
+	cmd.Flags().StringVar(&confluenceToken, "token", "", "API token or personal access token (default $CONFLUENCE_TOKEN)")
+	cmd.Flags().StringVar(&confluenceSpace, "space", "", "Space key")
+}
+
+func init() {
+	addConfluenceFlags(importConfluenceCmd)
+	importConfluenceCmd.Flags().StringVar(&confluencePage, "page", "", "Root page ID, imports the page and its descendants instead of the whole space")
+	importConfluenceCmd.Flags().StringVarP(&confluenceOutput, "output", "o", ".", "Output directory")
+	importCmd.AddCommand(importConfluenceCmd)
+
+	addConfluenceFlags(exportConfluenceCmd)
+	exportConfluenceCmd.Flags().StringVarP(&confluenceFile, "file", "f", "", "Source markdown file to publish")
+	exportConfluenceCmd.Flags().StringVarP(&confluenceDir, "dir", "d", "", "Source directory containing markdown files to publish")
+	exportConfluenceCmd.Flags().StringVar(&confluencePage, "parent", "", "Parent page ID of new pages")
+	exportCmd.AddCommand(exportConfluenceCmd)
+}
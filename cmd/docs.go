@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+var (
+	manDir string
+
+	docsCmd = &cobra.Command{
+		Use:   "docs",
+		Short: "Generate documentation for mdctl",
+	}
+
+	docsManCmd = &cobra.Command{
+		Use:   "man",
+		Short: "Generate man pages for every command",
+		Long: `Generate a man page in section 1 for mdctl and each of its commands.
+
+Examples:
+  mdctl docs man --dir ./man
+  sudo cp man/*.1 /usr/local/share/man/man1/`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dryRun {
+				fmt.Printf("Would write man pages to %s\n", manDir)
+				return nil
+			}
+			if err := os.MkdirAll(manDir, 0755); err != nil {
+				return fmt.Errorf("failed to create man page directory: %v", err)
+			}
+
+			// Without the generation date the pages are reproducible
+			rootCmd.DisableAutoGenTag = true
+			header := &doc.GenManHeader{
+				Title:   "MDCTL",
+				Section: "1",
+				Source:  "mdctl " + Version,
+				Manual:  "mdctl Manual",
+			}
+			if err := doc.GenManTree(rootCmd, header, manDir); err != nil {
+				return fmt.Errorf("failed to generate man pages: %v", err)
+			}
+			fmt.Printf("Man pages written to %s\n", manDir)
+			return nil
+		},
+	}
+)
+
+func init() {
+	docsManCmd.Flags().StringVarP(&manDir, "dir", "d", "man", "Directory to write the man pages to")
+
+	docsCmd.AddCommand(docsManCmd)
+	rootCmd.AddCommand(docsCmd)
+}
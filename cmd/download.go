@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/samzong/mdctl/internal/processor"
 
@@ -12,6 +14,7 @@ var (
 	sourceFile     string
 	sourceDir      string
 	imageOutputDir string
+	downloadAssets []string
 
 	downloadCmd = &cobra.Command{
 		Use:   "download",
@@ -20,7 +23,15 @@ var (
 Examples:
   mdctl download -f post.md
   mdctl download -d content/posts
-  mdctl download -f post.md -o assets/images`,
+  mdctl download -f post.md -o assets/images
+  cat post.md | mdctl download -f - -o images > post_local.md
+
+With -f - the markdown is read from stdin and the rewritten document is
+written to stdout, images are saved relative to the current directory.
+
+Remote images of the front matter fields listed in assets_keys of the project
+configuration (.mdctl.yaml) or given with --assets-key, e.g. cover.image, are
+downloaded as well.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if sourceFile == "" && sourceDir == "" {
 				return fmt.Errorf("either source file (-f) or source directory (-d) must be specified")
@@ -29,14 +40,66 @@ Examples:
 				return fmt.Errorf("cannot specify both source file (-f) and source directory (-d)")
 			}
 
+			source := sourceDir
+			if source == "" {
+				source = filepath.Dir(sourceFile)
+			}
+			assetsKeys, err := assetsKeysFor(cmd, downloadAssets, source)
+			if err != nil {
+				return err
+			}
+
+			if sourceFile == stdioPath {
+				return downloadStream(assetsKeys)
+			}
+
 			p := processor.New(sourceFile, sourceDir, imageOutputDir)
-			return p.Process()
+			p.AssetsKeys = assetsKeys
+			p.DryRun = dryRun
+			err = p.Process()
+			if jsonOutput && err == nil {
+				return printJSON(p.Stats)
+			}
+			return err
 		},
 	}
 )
 
+// downloadStream downloads the images of markdown piped through stdin and
+// writes the rewritten document to stdout
+func downloadStream(assetsKeys []string) error {
+	if err := reserveStdout(); err != nil {
+		return err
+	}
+
+	content, err := readInput(stdioPath)
+	if err != nil {
+		return err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %v", err)
+	}
+
+	// Images are located relative to a virtual file in the working directory
+	outputDir := imageOutputDir
+	if outputDir != "" && !filepath.IsAbs(outputDir) {
+		outputDir = filepath.Join(cwd, outputDir)
+	}
+	p := processor.New("", "", outputDir)
+	p.AssetsKeys = assetsKeys
+	p.DryRun = dryRun
+	result, err := p.ProcessContent(string(content), filepath.Join(cwd, "stdin.md"))
+	if err != nil {
+		return err
+	}
+	return writeOutput(stdioPath, []byte(result))
+}
+
 func init() {
-	downloadCmd.Flags().StringVarP(&sourceFile, "file", "f", "", "Source markdown file to process")
+	downloadCmd.Flags().StringVarP(&sourceFile, "file", "f", "", "Source markdown file to process, - reads stdin and writes stdout")
 	downloadCmd.Flags().StringVarP(&sourceDir, "dir", "d", "", "Source directory containing markdown files to process")
 	downloadCmd.Flags().StringVarP(&imageOutputDir, "output", "o", "", "Output directory for downloaded images (optional)")
+	downloadCmd.Flags().StringSliceVar(&downloadAssets, "assets-key", nil, "Front matter field referencing an image, e.g. cover or cover.image (default: assets_keys of .mdctl.yaml)")
 }
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+// snapshot returns the directories and file contents below root
+func snapshot(t *testing.T, root string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			files[path] = "<dir>"
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		files[path] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestDryRunWritesNothing(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			w.Write([]byte(`<urlset><url><loc>` + server.URL + `/page</loc></url><url><loc>` + server.URL + `/missing</loc></url></urlset>`))
+		case "/page":
+			w.Write([]byte(`<html><head><title>Page</title></head><body><p>Text</p></body></html>`))
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG\r\n\x1a\n"))
+		case "/acme.css":
+			w.Write([]byte("body {}"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	home := filepath.Join(root, "home")
+	docs := filepath.Join(root, "docs")
+	for path, content := range map[string]string{
+		filepath.Join(home, "mdctl.json"): `{"model": "gpt-4o"}`,
+		filepath.Join(docs, "post.md"):    "![logo](" + server.URL + "/logo.png)\n",
+		filepath.Join(root, "acme.css"):   "body {}",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("HOME", home)
+	t.Setenv(config.ConfigEnv, "")
+	reset := func() {
+		dryRun, configFile, configKey, configValue = false, "", "", ""
+		sourceFile, sourceDir, outputPath, llmstxtErrorReport, templateName = "", "", "", "", ""
+		config.SetConfigPath("")
+	}
+	defer reset()
+
+	tests := []struct {
+		name string
+		args []string
+		want string // Reported instead of writing
+	}{
+		{"download file", []string{"download", "-f", filepath.Join(docs, "post.md")}, ""},
+		{"download dir", []string{"download", "-d", docs}, ""},
+		{"llmstxt", []string{"llmstxt", server.URL + "/sitemap.xml", "-o", filepath.Join(root, "llms.txt"), "--error-report", filepath.Join(root, "errors.json")}, "Would write " + filepath.Join(root, "llms.txt")},
+		{"config set", []string{"--config", filepath.Join(home, "mdctl.json"), "config", "set", "--key", "model", "--value", "gpt-4.1"}, "Would set model to gpt-4.1"},
+		{"templates add", []string{"export", "templates", "add", filepath.Join(root, "acme.css"), "--name", "acme"}, "Would save template to " + filepath.Join(home, ".config", "mdctl", "templates", "acme.css")},
+		{"templates fetch", []string{"export", "templates", "fetch", server.URL + "/acme.css"}, "Would save template to " + filepath.Join(home, ".config", "mdctl", "templates", "acme.css")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset()
+			before := snapshot(t, root)
+			out := runStream(t, "", append(tt.args, "--dry-run")...)
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("expected %q in the output:\n%s", tt.want, out)
+			}
+			if after := snapshot(t, root); !reflect.DeepEqual(after, before) {
+				t.Errorf("--dry-run changed files:\n before %q\n after  %q", before, after)
+			}
+		})
+	}
+}
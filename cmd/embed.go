@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/embed"
+	"github.com/samzong/mdctl/internal/sitegen"
+	"github.com/spf13/cobra"
+)
+
+var (
+	embedDir        string
+	embedOutput     string
+	embedModel      string
+	embedMaxChars   int
+	embedBatchSize  int
+	embedStore      string
+	embedChromaURL  string
+	embedCollection string
+	embedDB         string
+	embedTable      string
+	embedInclude    []string
+	embedExclude    []string
+
+	embedCmd = &cobra.Command{
+		Use:   "embed",
+		Short: "Export markdown chunks with embeddings for RAG pipelines",
+		Long: `Split markdown files into chunks by headings, compute their embeddings with
+the configured OpenAI-compatible endpoint and write them as JSONL or to a
+vector store for retrieval-augmented generation.
+
+Every heading starts a chunk, sections longer than --max-chars are split
+further at blank lines outside code blocks. Each JSONL line has the id, text,
+metadata (path, title, heading, section, line, index) and vector of a chunk.
+
+--store chroma upserts the chunks into a collection of a Chroma server,
+--store sqlite writes them to a table of a SQLite database, vectors as JSON
+arrays that sqlite-vec can search. Chunks of earlier versions of the same
+files are replaced.
+
+The endpoint, API key and usage limits are those of translate, the model is
+embedding_model (default text-embedding-3-small) unless --model is given.
+
+Examples:
+  mdctl embed -d docs/ -o embeddings.jsonl
+  mdctl embed -d docs/ --store chroma --chroma-url http://localhost:8000 --collection docs
+  mdctl embed -d docs/ --store sqlite --db docs.db --model nomic-embed-text`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %v", err)
+			}
+
+			pages, err := sitegen.Collect(embedDir, sitegen.Options{Include: embedInclude, Exclude: embedExclude})
+			if err != nil {
+				return err
+			}
+			var chunks []embed.Chunk
+			for _, page := range pages {
+				content, err := os.ReadFile(filepath.Join(embedDir, filepath.FromSlash(page.Path)))
+				if err != nil {
+					return fmt.Errorf("failed to read file %s: %v", page.Path, err)
+				}
+				chunks = append(chunks, embed.Split(page.Path, content, embedMaxChars)...)
+			}
+
+			destination := embedOutput
+			switch embedStore {
+			case "":
+			case embed.StoreChroma:
+				destination = embedChromaURL + " (" + embedCollection + ")"
+			case embed.StoreSQLite:
+				destination = embedDB
+			default:
+				return fmt.Errorf("unsupported store: %s (must be chroma or sqlite)", embedStore)
+			}
+
+			if !dryRun {
+				client := embed.NewClient(cfg, embedModel)
+				client.BatchSize = embedBatchSize
+				if err := client.Embed(cmd.Context(), chunks); err != nil {
+					return err
+				}
+
+				switch embedStore {
+				case embed.StoreChroma:
+					store := &embed.Chroma{URL: embedChromaURL, Collection: embedCollection}
+					err = store.Write(cmd.Context(), chunks)
+				case embed.StoreSQLite:
+					store := &embed.SQLite{Path: embedDB, Table: embedTable}
+					err = store.Write(cmd.Context(), chunks)
+				default:
+					err = writeEmbeddings(chunks)
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+			if jsonOutput {
+				return printJSON(struct {
+					DryRun      bool   `json:"dry_run"`
+					Files       int    `json:"files"`
+					Chunks      int    `json:"chunks"`
+					Destination string `json:"destination"`
+				}{DryRun: dryRun, Files: len(pages), Chunks: len(chunks), Destination: destination})
+			}
+			if embedStore == "" && embedOutput == "-" && !dryRun {
+				return nil
+			}
+			if dryRun {
+				fmt.Printf("Would embed %d chunks of %d files into %s\n", len(chunks), len(pages), destination)
+				return nil
+			}
+			fmt.Printf("Embedded %d chunks of %d files into %s\n", len(chunks), len(pages), destination)
+			return nil
+		},
+	}
+)
+
+// writeEmbeddings writes the chunks as JSONL to the output file or stdout
+func writeEmbeddings(chunks []embed.Chunk) error {
+	if embedOutput == "-" {
+		return embed.WriteJSONL(os.Stdout, chunks)
+	}
+	f, err := os.Create(embedOutput)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	if err := embed.WriteJSONL(f, chunks); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write embeddings: %v", err)
+	}
+	return f.Close()
+}
+
+func init() {
+	embedCmd.Flags().StringVarP(&embedDir, "dir", "d", ".", "Directory of the markdown files")
+	embedCmd.Flags().StringVarP(&embedOutput, "output", "o", "embeddings.jsonl", "Output JSONL file, - for stdout")
+	embedCmd.Flags().StringVar(&embedModel, "model", "", "Embedding model (default: embedding_model from the config)")
+	embedCmd.Flags().IntVar(&embedMaxChars, "max-chars", embed.DefaultMaxChars, "Maximum size of a chunk in characters")
+	embedCmd.Flags().IntVar(&embedBatchSize, "batch-size", embed.DefaultBatchSize, "Number of chunks per embeddings request")
+	embedCmd.Flags().StringVar(&embedStore, "store", "", "Write to a vector store instead of a file (chroma, sqlite)")
+	embedCmd.Flags().StringVar(&embedChromaURL, "chroma-url", "http://localhost:8000", "URL of the Chroma server")
+	embedCmd.Flags().StringVar(&embedCollection, "collection", "mdctl", "Chroma collection to write to")
+	embedCmd.Flags().StringVar(&embedDB, "db", "embeddings.db", "SQLite database to write to")
+	embedCmd.Flags().StringVar(&embedTable, "table", "chunks", "SQLite table to write to")
+	embedCmd.Flags().StringSliceVar(&embedInclude, "include", nil, "Glob patterns for files to include, relative to the directory (can be specified multiple times)")
+	embedCmd.Flags().StringSliceVar(&embedExclude, "exclude", nil, "Glob patterns for files to exclude, relative to the directory (can be specified multiple times)")
+	registerCompletion(embedCmd, "store", cobra.FixedCompletions([]string{embed.StoreChroma, embed.StoreSQLite}, cobra.ShellCompDirectiveNoFileComp))
+
+	embedCmd.GroupID = "core"
+	rootCmd.AddCommand(embedCmd)
+}
@@ -2,16 +2,23 @@ package cmd
 
 import (
 	"fmt"
-	"io"
-	"log"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/samzong/mdctl/internal/exporter"
+	"github.com/samzong/mdctl/internal/exporter/sitereader"
+	"github.com/samzong/mdctl/internal/gitref"
+	"github.com/samzong/mdctl/internal/logging"
 	"github.com/spf13/cobra"
 )
 
 var (
-	exportFile          string
+	exportFiles         []string
+	exportFileList      string
 	exportDir           string
 	siteType            string
 	exportOutput        string
@@ -21,8 +28,40 @@ var (
 	shiftHeadingLevelBy int
 	fileAsTitle         bool
 	tocDepth            int
-	navPath             string
-	logger              *log.Logger
+	navPaths            []string
+	listNav             bool
+	exportDedupe        string
+	exportFrom          string
+	exportGitRef        string
+	exportStamp         bool
+	exportTitle         string
+	exportAuthors       []string
+	exportLang          string
+	exportIdentifier    string
+	epubCoverImage      string
+	epubEmbedFonts      []string
+	maxImageWidth       string
+	imageDPI            int
+	linkBaseURL         string
+	numberHeadings      bool
+	exportGlossary      string
+	expandAcronyms      bool
+	luaFilters          []string
+	pandocArgs          []string
+	exportTheme         string
+	headerText          string
+	footerText          string
+	pageNumbers         bool
+	watermark           string
+	exportOutputDir     string
+	exportSplitBy       string
+	exportPerNavDir     string
+	pdfEngineOpts       []string
+	skipPDFCheck        bool
+	exportJobs          int
+	exportEmoji         string
+	exportEmojiFont     string
+	logger              *logging.Logger
 
 	exportCmd = &cobra.Command{
 		Use:   "export",
@@ -32,42 +71,272 @@ Uses Pandoc as the underlying conversion tool.
 
 Examples:
   mdctl export -f README.md -o output.docx
+  mdctl export -f intro.md -f api/auth.md -f changelog.md -o combined.pdf -F pdf
+  mdctl export --file-list manifest.txt -o combined.docx
   mdctl export -d docs/ -o documentation.docx
   mdctl export -d docs/ -s mkdocs -o site_docs.docx
   mdctl export -d docs/ -o report.docx -t templates/corporate.docx
+  mdctl export -d docs/ -o report.pdf -F pdf --theme academic
   mdctl export -d docs/ -o documentation.docx --shift-heading-level-by 2
   mdctl export -d docs/ -o documentation.docx --toc --toc-depth 4
-  mdctl export -d docs/ -o documentation.pdf -F pdf`,
+  mdctl export -d docs/ -o documentation.pdf -F pdf
+  mdctl export -d docs/ -o book.epub -F epub --title "User Guide" --author "Docs Team" --lang en-US --epub-cover-image cover.png
+  mdctl export -d docs/ -o manual.docx --max-image-width 6in --image-dpi 150
+  mdctl export -d docs/ -o guide.docx --lua-filter acronyms.lua --pandoc-arg=--number-sections
+  mdctl export -d docs/ -o report.pdf -F pdf --header-text "ACME Confidential" --page-numbers --watermark DRAFT
+  mdctl export -d docs/ -s mkdocs --output-dir handbooks/ -F pdf
+  mdctl export -d docs/ --output-dir out/ --split-by file --jobs 4
+  mdctl export -d . -s mkdocs --per-nav-output-dir manuals/ -F pdf
+  mdctl export -d site/ -s hugo --per-nav-output-dir manuals/
+  mdctl export -d docs/ -s mkdocs -o site_docs.docx --dry-run
+  mdctl export -d docs/ -s mkdocs --list-nav
+  mdctl export -d docs/ -s mkdocs -o docs-v1.2.0.pdf -F pdf --git-ref v1.2.0
+  mdctl export -d docs/ -o docs.pdf -F pdf --stamp
+  mdctl export -d docs/ -s mkdocs -o api.pdf -F pdf -n "User Guide/*" -n "API/*"
+  mdctl export -d docs/ -s mkdocs -o manual.pdf -F pdf --link-base-url https://docs.example.com
+  mdctl export -d docs/ -o manual.docx -t templates/corporate.docx --number-headings
+  mdctl export -d docs/ -o guide.pdf -F pdf --from gfm+smart
+  mdctl export -d docs/ -o spec.docx --glossary glossary.yaml --expand-acronyms
+
+EPUB chapters are split at file boundaries: every merged file starts a chapter
+at the top heading level the files start at, files that do not start with such
+a heading get their file name as title. --toc-depth also sets the depth of the
+e-book navigation.
+
+--max-image-width downscales local PNG, JPEG and GIF images that are wider
+than the given length (in, cm, mm, pt or px) at --image-dpi and limits their
+display width, so large screenshots stay within the page margins.
+
+--lua-filter and --pandoc-arg are passed to Pandoc after the arguments mdctl
+sets. Give arguments that start with a dash as --pandoc-arg=--flag.
+
+--header-text, --footer-text, --page-numbers and --watermark apply to PDF and
+DOCX output. PDF output needs the fancyhdr and draftwatermark LaTeX packages,
+DOCX headers and footers replace those of the template.
+
+PDF output keeps a bookmark for every heading down to --toc-depth, and
+table of contents entries and internal links stay clickable. After the export
+mdctl counts the bookmarks of the PDF and fails without writing it when they
+do not match the headings, for example because a template dropped hyperref.
+--skip-pdf-check turns this off, --pdf-engine-opt passes options to xelatex.
+
+The main fonts of PDF output have no emoji, which come out as empty boxes.
+--emoji font sets them in --emoji-font (default Noto Emoji, xelatex needs a
+monochrome font), --emoji twemoji replaces them with Twemoji images, downloaded
+once into the cache, and --emoji strip removes them. Emoji in code are kept.
+
+--nav-path exports the part of the navigation of a site below a path of
+titles such as "User Guide/Install". It can be given several times, every level
+may use the wildcards *, ? and [...], and a matching section exports all pages
+below it. --list-nav prints the navigation with the path of every entry.
+
+--git-ref exports the sources as of a tag, branch or commit of their git
+repository. The tree of that commit is copied into a temporary directory, the
+working tree and the checked out branch stay as they are.
+
+--stamp records the mdctl version, the git commit of the sources (marked
+-dirty when they have uncommitted changes) and the generation date in the
+footer of PDF and DOCX output and in the document properties, so readers can
+tell which version of the docs they have. SOURCE_DATE_EPOCH overrides the date.
+
+--link-base-url turns relative links between pages, which lead nowhere in a
+DOCX or PDF, into absolute links to the published site. The URLs follow the
+site type: MkDocs pages of docs_dir at directory URLs (guide/install/, or
+guide/install.html with use_directory_urls: false), Hugo pages of content/ at
+lowercased pretty URLs, Docusaurus docs below docs/ without extension and
+number prefixes, and other sites at directory URLs. Links within the document
+and to images and other files are kept.
+
+--number-headings writes section numbers (1., 1.1, 1.2.3) into the headings
+while merging and marks every heading with a sec-1-2-3 anchor, for reference
+DOCX templates whose heading styles clash with Pandoc's --number-sections.
+Headings marked {-} or {.unnumbered} are not numbered.
+
+--glossary appends an unnumbered glossary section to the merged document,
+listing the terms of a glossary file that the content uses in alphabetical
+order. Terms are not looked for in code, and acronyms (terms without
+lowercase letters) are matched case-sensitively. --expand-acronyms also
+spells out the first use of every term with an expansion, e.g. "Application
+Programming Interface (API)", unless the expansion already came before:
+
+  title: Glossary
+  terms:
+    - term: API
+      expansion: Application Programming Interface
+      definition: Rules by which programs talk to each other.
+      aliases: [APIs]
+
+The sources are read as GitHub-flavored markdown (--from gfm): task lists,
+strikethrough, tables and autolinks as on GitHub, plus footnotes, heading
+attributes such as {#id} or {.unlisted}, and the anchor spans and raw LaTeX
+mdctl adds. --from takes another Pandoc markdown reader (commonmark,
+commonmark_x, markdown, markdown_strict, markdown_phpextra, markdown_mmd) and
+extension toggles, e.g. gfm+smart or gfm-footnotes.
+
+-f can be given several times to merge the files in the given order, for a
+selection that is no directory or navigation path. --file-list reads the files
+from a manifest, one path per line relative to the manifest, skipping blank
+lines and lines starting with #; they follow the files of -f.
+
+A basic directory is merged by the _order.yaml files of its directories, a
+YAML list of file and subdirectory names to put first, then by the weight or
+order front matter of the files (a subdirectory by that of its index page),
+lowest first, and then by name.
+
+A file the navigation lists more than once is merged at its first place only,
+later places get a link to it (--dedupe link-to-first). --dedupe skip leaves
+them out, --dedupe duplicate merges every copy.
+
+--output-dir writes one document per top-level navigation entry (or, for a
+basic directory, per top-level file and subdirectory) named after it instead
+of a single merged file. --split-by file writes one document per source file
+at the same relative path. --jobs exports that many documents at the same time.
+--per-nav-output-dir is short for --output-dir with --split-by nav and exports
+as many documents at the same time as there are CPUs unless --jobs is given,
+one manual per module of a MkDocs or Hugo site in a single run.
+
+The navigation of a Hugo site (-s hugo) is read from its content directory:
+every directory is a section starting with its _index.md, ordered like Hugo
+by the weight of the front matter, then by title.
+
+Every export works on temporary files with unique names, so several mdctl
+export processes, for example of a parallel build, can run at once.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Initialize logger
-			if verbose {
-				logger = log.New(os.Stdout, "[EXPORT] ", log.LstdFlags)
-			} else {
-				logger = log.New(io.Discard, "", 0)
-			}
+			logger = logging.New("EXPORT")
 
 			logger.Println("Starting export process...")
 
+			// Files of -f followed by those of --file-list, in the given order
+			sourceFiles := append([]string{}, exportFiles...)
+			if exportFileList != "" {
+				listed, err := exporter.ReadFileList(exportFileList)
+				if err != nil {
+					return err
+				}
+				sourceFiles = append(sourceFiles, listed...)
+			}
+
+			// The sources, or their copy as of --git-ref
+			inputFiles, inputDir := sourceFiles, exportDir
+			var commit string
+			if exportGitRef != "" {
+				source := exportDir
+				if source == "" && len(sourceFiles) == 1 {
+					source = sourceFiles[0]
+				}
+				if len(sourceFiles) > 1 {
+					return fmt.Errorf("--git-ref takes a single source file (-f) or a directory (-d)")
+				}
+				if source == "" {
+					return fmt.Errorf("--git-ref requires a source file (-f) or directory (-d)")
+				}
+				snapshot, err := gitref.Checkout(source, exportGitRef)
+				if err != nil {
+					return err
+				}
+				defer snapshot.Close()
+				logger.Printf("Exporting %s as of %s (%s)", source, exportGitRef, snapshot.Commit)
+				commit = snapshot.Commit
+				if exportDir != "" {
+					inputDir = snapshot.Path
+				} else {
+					inputFiles = []string{snapshot.Path}
+				}
+			}
+
+			if listNav {
+				return listNavigation(inputDir)
+			}
+
 			// Parameter validation
-			if exportFile == "" && exportDir == "" {
-				return fmt.Errorf("either source file (-f) or source directory (-d) must be specified")
+			if len(sourceFiles) == 0 && exportDir == "" {
+				return fmt.Errorf("either source file (-f, --file-list) or source directory (-d) must be specified")
+			}
+			if len(sourceFiles) > 0 && exportDir != "" {
+				return fmt.Errorf("cannot specify both source files (-f, --file-list) and source directory (-d)")
+			}
+			for _, file := range inputFiles {
+				if _, err := os.Stat(file); err != nil {
+					return fmt.Errorf("input file does not exist: %s", file)
+				}
+			}
+			if err := applyPerNavOutputDir(cmd); err != nil {
+				return err
+			}
+			if exportOutput == "" && exportOutputDir == "" {
+				return fmt.Errorf("output file (-o) or output directory (--output-dir) must be specified")
+			}
+			if exportOutput != "" && exportOutputDir != "" {
+				return fmt.Errorf("cannot specify both output file (-o) and output directory (--output-dir)")
+			}
+			if exportOutputDir != "" && exportDir == "" {
+				return fmt.Errorf("--output-dir requires a source directory (-d)")
+			}
+			if exportSplitBy != exporter.SplitByNav && exportSplitBy != exporter.SplitByFile {
+				return fmt.Errorf("unsupported split mode: %s (must be nav or file)", exportSplitBy)
+			}
+			if (epubCoverImage != "" || len(epubEmbedFonts) > 0) && exportFormat != "epub" {
+				return fmt.Errorf("--epub-cover-image and --epub-embed-font require the epub format (-F epub)")
+			}
+			if exportTheme != "" && exportTemplate != "" {
+				return fmt.Errorf("cannot specify both --template and --theme")
+			}
+			if (headerText != "" || footerText != "" || pageNumbers || watermark != "") && exportFormat != "pdf" && exportFormat != "docx" {
+				return fmt.Errorf("--header-text, --footer-text, --page-numbers and --watermark require the pdf or docx format")
+			}
+			if (len(pdfEngineOpts) > 0 || skipPDFCheck) && exportFormat != "pdf" {
+				return fmt.Errorf("--pdf-engine-opt and --skip-pdf-check require the pdf format (-F pdf)")
+			}
+			if (exportEmoji != "" || exportEmojiFont != "") && exportFormat != "pdf" {
+				return fmt.Errorf("--emoji and --emoji-font require the pdf format (-F pdf)")
+			}
+			switch exportEmoji {
+			case "", exporter.EmojiFont, exporter.EmojiTwemoji, exporter.EmojiStrip:
+			default:
+				return fmt.Errorf("unsupported emoji mode: %s (must be font, twemoji or strip)", exportEmoji)
+			}
+			if exportEmojiFont != "" && exportEmoji != exporter.EmojiFont {
+				return fmt.Errorf("--emoji-font requires --emoji font")
+			}
+			switch exportDedupe {
+			case exporter.DedupeLinkToFirst, exporter.DedupeSkip, exporter.DedupeDuplicate:
+			default:
+				return fmt.Errorf("unsupported dedupe mode: %s (must be link-to-first, skip or duplicate)", exportDedupe)
+			}
+			if _, _, err := exporter.ParseInputFormat(exportFrom); err != nil {
+				return err
+			}
+			if maxImageWidth != "" {
+				if _, err := exporter.NewImageResizer(maxImageWidth, imageDPI, nil); err != nil {
+					return err
+				}
 			}
-			if exportFile != "" && exportDir != "" {
-				return fmt.Errorf("cannot specify both source file (-f) and source directory (-d)")
+			if linkBaseURL != "" {
+				if _, err := exporter.ParseLinkBaseURL(linkBaseURL); err != nil {
+					return err
+				}
 			}
-			if exportOutput == "" {
-				return fmt.Errorf("output file (-o) must be specified")
+			if expandAcronyms && exportGlossary == "" {
+				return fmt.Errorf("--expand-acronyms requires --glossary")
+			}
+			if exportGlossary != "" {
+				if _, err := exporter.LoadGlossary(exportGlossary); err != nil {
+					return err
+				}
 			}
 
-			logger.Printf("Validating parameters: file=%s, dir=%s, output=%s, format=%s, site-type=%s",
-				exportFile, exportDir, exportOutput, exportFormat, siteType)
+			logger.Printf("Validating parameters: files=%v, dir=%s, output=%s, format=%s, site-type=%s",
+				sourceFiles, exportDir, exportOutput, exportFormat, siteType)
 
-			// Check if Pandoc is available
-			logger.Println("Checking Pandoc availability...")
-			if err := exporter.CheckPandocAvailability(); err != nil {
-				return err
+			// Check if Pandoc is available, a dry run never invokes it
+			if !dryRun {
+				logger.Println("Checking Pandoc availability...")
+				if err := exporter.CheckPandocAvailability(); err != nil {
+					return err
+				}
+				logger.Println("Pandoc is available.")
 			}
-			logger.Println("Pandoc is available.")
 
 			// Create export options
 			options := exporter.ExportOptions{
@@ -80,7 +349,44 @@ Examples:
 				Verbose:             verbose,
 				Logger:              logger,
 				TocDepth:            tocDepth,
-				NavPath:             navPath,
+				NavPaths:            navPaths,
+				DryRun:              dryRun,
+				Title:               exportTitle,
+				Authors:             exportAuthors,
+				Language:            exportLang,
+				Identifier:          exportIdentifier,
+				CoverImage:          epubCoverImage,
+				EmbedFonts:          epubEmbedFonts,
+				MaxImageWidth:       maxImageWidth,
+				ImageDPI:            imageDPI,
+				LinkBaseURL:         linkBaseURL,
+				NumberHeadings:      numberHeadings,
+				Glossary:            exportGlossary,
+				ExpandAcronyms:      expandAcronyms,
+				LuaFilters:          luaFilters,
+				PandocArgs:          pandocArgs,
+				Theme:               exportTheme,
+				HeaderText:          headerText,
+				FooterText:          footerText,
+				PageNumbers:         pageNumbers,
+				Watermark:           watermark,
+				PDFEngineOpts:       pdfEngineOpts,
+				SkipPDFCheck:        skipPDFCheck,
+				Jobs:                exportJobs,
+				Emoji:               exportEmoji,
+				EmojiFont:           exportEmojiFont,
+				Dedupe:              exportDedupe,
+				InputFormat:         exportFrom,
+			}
+			if exportStamp {
+				source := inputDir
+				if source == "" {
+					source = inputFiles[0]
+				}
+				options.Stamp = buildStamp(source, commit)
+			}
+			if dryRun {
+				options.Plan = &exporter.ExportPlan{}
 			}
 
 			logger.Printf("Export options: template=%s, toc=%v, toc-depth=%d, shift-heading=%d, file-as-title=%v",
@@ -89,36 +395,249 @@ Examples:
 			// Execute export
 			exp := exporter.NewExporter()
 			var err error
+			var outputs []string
 
-			if exportFile != "" {
-				logger.Printf("Exporting single file: %s -> %s", exportFile, exportOutput)
-				err = exp.ExportFile(exportFile, exportOutput, options)
+			if exportOutputDir != "" {
+				logger.Printf("Exporting directory by %s: %s -> %s", exportSplitBy, exportDir, exportOutputDir)
+				outputs, err = exp.ExportDirectoryToDir(cmd.Context(), inputDir, exportOutputDir, exportSplitBy, options)
+			} else if len(inputFiles) == 1 {
+				logger.Printf("Exporting single file: %s -> %s", inputFiles[0], exportOutput)
+				err = exp.ExportFile(cmd.Context(), inputFiles[0], exportOutput, options)
+			} else if len(inputFiles) > 1 {
+				logger.Printf("Exporting %d files: %s -> %s", len(inputFiles), strings.Join(sourceFiles, ", "), exportOutput)
+				err = exp.ExportFiles(cmd.Context(), inputFiles, exportOutput, options)
 			} else {
 				logger.Printf("Exporting directory: %s -> %s", exportDir, exportOutput)
-				err = exp.ExportDirectory(exportDir, exportOutput, options)
+				err = exp.ExportDirectory(cmd.Context(), inputDir, exportOutput, options)
 			}
 
 			if err != nil {
+				if interrupted(cmd) {
+					if len(outputs) > 0 {
+						return fmt.Errorf("export interrupted after %d documents", len(outputs))
+					}
+					return fmt.Errorf("export interrupted, output left untouched")
+				}
 				logger.Printf("Export failed: %s", err)
 				return err
 			}
 
+			if dryRun {
+				return printExportPlan(options.Plan)
+			}
+
 			logger.Println("Export completed successfully.")
+
+			if exportOutputDir != "" {
+				if jsonOutput {
+					return printJSON(withGitRef(map[string]interface{}{
+						"source":    exportDir,
+						"outputs":   outputs,
+						"format":    exportFormat,
+						"site_type": siteType,
+					}, commit))
+				}
+				fmt.Printf("Exported %d documents to %s\n", len(outputs), exportOutputDir)
+				return nil
+			}
+
+			if jsonOutput {
+				result := map[string]interface{}{
+					"source":    exportDir,
+					"output":    exportOutput,
+					"format":    exportFormat,
+					"site_type": siteType,
+				}
+				if len(sourceFiles) == 1 {
+					result["source"] = sourceFiles[0]
+				} else if len(sourceFiles) > 1 {
+					result["source"] = sourceFiles
+				}
+				return printJSON(withGitRef(result, commit))
+			}
 			return nil
 		},
 	}
 )
 
+// printExportPlan shows the files and Pandoc command of a dry-run export
+func printExportPlan(plan *exporter.ExportPlan) error {
+	if jsonOutput {
+		return printJSON(plan)
+	}
+
+	if len(plan.Documents) > 0 {
+		fmt.Printf("Would export %d documents:\n", len(plan.Documents))
+		for _, document := range plan.Documents {
+			fmt.Printf("  %s\n", strings.Join(document.Command, " "))
+			for _, file := range document.Files {
+				fmt.Printf("    - %s\n", file)
+			}
+		}
+		return nil
+	}
+
+	fmt.Printf("Would export %d files in this order:\n", len(plan.Files))
+	for i, file := range plan.Files {
+		fmt.Printf("  %d. %s\n", i+1, file)
+	}
+	fmt.Printf("Pandoc command:\n  %s\n", strings.Join(plan.Command, " "))
+	return nil
+}
+
+// buildStamp returns the --stamp information of an export of source, commit
+// is the commit of --git-ref and looked up in source when empty
+func buildStamp(source, commit string) *exporter.BuildStamp {
+	stamp := &exporter.BuildStamp{Version: Version, Commit: commit, Date: time.Now()}
+	// Reproducible builds fix the date
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		if seconds, err := strconv.ParseInt(epoch, 10, 64); err == nil {
+			stamp.Date = time.Unix(seconds, 0).UTC()
+		}
+	}
+	if commit == "" {
+		head, dirty, err := gitref.Head(source)
+		if err != nil {
+			logger.Printf("No git commit for the stamp of %s: %v", source, err)
+		} else {
+			stamp.Commit, stamp.Dirty = head, dirty
+		}
+	}
+	return stamp
+}
+
+// withGitRef adds the ref and commit of a --git-ref export to a JSON result
+func withGitRef(result map[string]interface{}, commit string) map[string]interface{} {
+	if exportGitRef != "" {
+		result["git_ref"] = exportGitRef
+		result["commit"] = commit
+	}
+	return result
+}
+
+// applyPerNavOutputDir turns --per-nav-output-dir into --output-dir with
+// --split-by nav and, unless --jobs is given, a job per CPU
+func applyPerNavOutputDir(cmd *cobra.Command) error {
+	if exportPerNavDir == "" {
+		return nil
+	}
+	if exportOutputDir != "" {
+		return fmt.Errorf("cannot specify both --output-dir and --per-nav-output-dir")
+	}
+	if exportSplitBy != exporter.SplitByNav {
+		return fmt.Errorf("--per-nav-output-dir splits by nav, use --output-dir with --split-by %s", exportSplitBy)
+	}
+	exportOutputDir = exportPerNavDir
+	if !cmd.Flags().Changed("jobs") {
+		exportJobs = runtime.NumCPU()
+	}
+	return nil
+}
+
+// listNavigation prints the navigation tree of the site in dir, the source
+// directory or its --git-ref copy, with the paths --nav-path accepts
+func listNavigation(dir string) error {
+	if dir == "" {
+		return fmt.Errorf("--list-nav requires a source directory (-d)")
+	}
+	if siteType == "basic" {
+		return fmt.Errorf("--list-nav requires a site type with navigation, e.g. -s mkdocs")
+	}
+	reader, err := sitereader.GetSiteReader(siteType, verbose, logger)
+	if err != nil {
+		return err
+	}
+	entries, err := reader.ReadNavigation(dir, "")
+	if err != nil {
+		return err
+	}
+
+	type navItem struct {
+		Path string `json:"path"`
+		File string `json:"file,omitempty"`
+	}
+	var items []navItem
+	var walk func(entries []sitereader.NavEntry, parent string, depth int)
+	walk = func(entries []sitereader.NavEntry, parent string, depth int) {
+		for _, entry := range entries {
+			path := entry.Title
+			if parent != "" {
+				path = parent + "/" + entry.Title
+			}
+			file := entry.File
+			if rel, err := filepath.Rel(dir, file); err == nil && file != "" {
+				file = filepath.ToSlash(rel)
+			}
+			items = append(items, navItem{Path: path, File: file})
+			if !jsonOutput {
+				if file != "" {
+					fmt.Printf("%s%s (%s)\n", strings.Repeat("  ", depth), path, file)
+				} else {
+					fmt.Printf("%s%s\n", strings.Repeat("  ", depth), path)
+				}
+			}
+			walk(entry.Children, path, depth+1)
+		}
+	}
+	walk(entries, "", 0)
+
+	if jsonOutput {
+		return printJSON(items)
+	}
+	return nil
+}
+
 func init() {
-	exportCmd.Flags().StringVarP(&exportFile, "file", "f", "", "Source markdown file to export")
+	exportCmd.Flags().StringArrayVarP(&exportFiles, "file", "f", nil, "Source markdown file to export (can be specified multiple times, merged in the given order)")
+	exportCmd.Flags().StringVar(&exportFileList, "file-list", "", "Manifest of source markdown files to merge in order, one path per line")
 	exportCmd.Flags().StringVarP(&exportDir, "dir", "d", "", "Source directory containing markdown files to export")
 	exportCmd.Flags().StringVarP(&siteType, "site-type", "s", "basic", "Site type (basic, mkdocs, hugo, docusaurus)")
 	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Output file path")
+	exportCmd.Flags().StringVar(&exportOutputDir, "output-dir", "", "Output directory receiving one document per section or file")
+	exportCmd.Flags().StringVar(&exportPerNavDir, "per-nav-output-dir", "", "Output directory receiving one document per top-level navigation entry, exported in parallel")
+	exportCmd.Flags().StringVar(&exportSplitBy, "split-by", "nav", "Documents written to --output-dir (nav, file)")
 	exportCmd.Flags().StringVarP(&exportTemplate, "template", "t", "", "Word template file path")
+	exportCmd.Flags().StringVar(&exportTheme, "theme", "", "Template or built-in theme (corporate, academic, minimal), see 'export templates list'")
 	exportCmd.Flags().StringVarP(&exportFormat, "format", "F", "docx", "Output format (docx, pdf, epub)")
 	exportCmd.Flags().BoolVar(&generateToc, "toc", false, "Generate table of contents")
 	exportCmd.Flags().IntVar(&shiftHeadingLevelBy, "shift-heading-level-by", 0, "Shift heading level by N")
 	exportCmd.Flags().BoolVar(&fileAsTitle, "file-as-title", false, "Use filename as section title")
 	exportCmd.Flags().IntVar(&tocDepth, "toc-depth", 3, "Depth of table of contents (default 3)")
-	exportCmd.Flags().StringVarP(&navPath, "nav-path", "n", "", "Specify the navigation path to export (e.g. 'Section1/Subsection2')")
+	exportCmd.Flags().StringVar(&exportTitle, "title", "", "Document title metadata")
+	exportCmd.Flags().StringSliceVar(&exportAuthors, "author", nil, "Author metadata (can be specified multiple times)")
+	exportCmd.Flags().StringVar(&exportLang, "lang", "", "Document language metadata, e.g. en-US")
+	exportCmd.Flags().StringVar(&exportIdentifier, "identifier", "", "EPUB identifier such as an ISBN or URN (default: random UUID)")
+	exportCmd.Flags().StringVar(&epubCoverImage, "epub-cover-image", "", "Cover image of EPUB output")
+	exportCmd.Flags().StringSliceVar(&epubEmbedFonts, "epub-embed-font", nil, "Font file embedded in EPUB output (can be specified multiple times)")
+	exportCmd.Flags().StringVar(&maxImageWidth, "max-image-width", "", "Downscale images wider than this length, e.g. 6in, 15cm or 800px")
+	exportCmd.Flags().IntVar(&imageDPI, "image-dpi", 0, "Resolution of images without resolution information (default 96)")
+	exportCmd.Flags().BoolVar(&numberHeadings, "number-headings", false, "Number headings (1., 1.1, 1.2.3) with sec- anchors instead of Pandoc's --number-sections")
+	exportCmd.Flags().StringVar(&exportGlossary, "glossary", "", "Glossary file (YAML) whose terms used in the content are appended as a glossary section")
+	exportCmd.Flags().BoolVar(&expandAcronyms, "expand-acronyms", false, "Spell out the acronyms of --glossary on first use")
+	exportCmd.Flags().StringVar(&linkBaseURL, "link-base-url", "", "URL the site is published at, relative links between pages become links to it")
+	exportCmd.Flags().StringArrayVar(&luaFilters, "lua-filter", nil, "Pandoc Lua filter to apply (can be specified multiple times)")
+	exportCmd.Flags().StringArrayVar(&pandocArgs, "pandoc-arg", nil, "Extra argument passed to Pandoc (can be specified multiple times)")
+	exportCmd.Flags().StringVar(&headerText, "header-text", "", "Text in the page header of PDF and DOCX output")
+	exportCmd.Flags().StringVar(&footerText, "footer-text", "", "Text in the page footer of PDF and DOCX output")
+	exportCmd.Flags().BoolVar(&pageNumbers, "page-numbers", false, "Number the pages of PDF and DOCX output")
+	exportCmd.Flags().StringVar(&watermark, "watermark", "", "Watermark text on every page of PDF and DOCX output, e.g. DRAFT")
+	exportCmd.Flags().IntVarP(&exportJobs, "jobs", "j", 1, "Number of documents exported at the same time with --output-dir (default: number of CPUs with --per-nav-output-dir)")
+	exportCmd.Flags().StringArrayVar(&pdfEngineOpts, "pdf-engine-opt", nil, "Option passed to the PDF engine (can be specified multiple times)")
+	exportCmd.Flags().BoolVar(&skipPDFCheck, "skip-pdf-check", false, "Do not verify that PDF bookmarks match the table of contents")
+	exportCmd.Flags().StringVar(&exportEmoji, "emoji", "", "Emoji handling of PDF output (font, twemoji, strip)")
+	exportCmd.Flags().StringVar(&exportEmojiFont, "emoji-font", "", "Font of --emoji font (default \""+exporter.DefaultEmojiFont+"\")")
+	exportCmd.Flags().StringArrayVarP(&navPaths, "nav-path", "n", nil, "Navigation path to export, e.g. 'Guide/Install' or 'API/*' (can be specified multiple times)")
+	exportCmd.Flags().StringVar(&exportDedupe, "dedupe", exporter.DedupeLinkToFirst, "Handling of files merged more than once (link-to-first, skip, duplicate)")
+	exportCmd.Flags().StringVar(&exportFrom, "from", exporter.DefaultInputFormat, "Pandoc markdown reader of the sources with extension toggles, e.g. gfm+smart or markdown")
+	exportCmd.Flags().StringVar(&exportGitRef, "git-ref", "", "Export the sources as of a git tag, branch or commit, e.g. v1.2.0")
+	exportCmd.Flags().BoolVar(&exportStamp, "stamp", false, "Add the mdctl version, git commit and generation date to the footer and document properties")
+	exportCmd.Flags().BoolVar(&listNav, "list-nav", false, "Print the navigation of the site with the paths --nav-path accepts")
+
+	registerCompletion(exportCmd, "format", cobra.FixedCompletions([]string{"docx", "pdf", "epub"}, cobra.ShellCompDirectiveNoFileComp))
+	registerCompletion(exportCmd, "site-type", cobra.FixedCompletions([]string{"basic", "mkdocs", "hugo", "docusaurus"}, cobra.ShellCompDirectiveNoFileComp))
+	registerCompletion(exportCmd, "theme", completeThemes)
+	registerCompletion(exportCmd, "emoji", cobra.FixedCompletions([]string{exporter.EmojiFont, exporter.EmojiTwemoji, exporter.EmojiStrip}, cobra.ShellCompDirectiveNoFileComp))
+	registerCompletion(exportCmd, "dedupe", cobra.FixedCompletions([]string{exporter.DedupeLinkToFirst, exporter.DedupeSkip, exporter.DedupeDuplicate}, cobra.ShellCompDirectiveNoFileComp))
+	registerCompletion(exportCmd, "from", cobra.FixedCompletions(exporter.InputFormats, cobra.ShellCompDirectiveNoFileComp))
+	registerCompletion(exportCmd, "split-by", cobra.FixedCompletions([]string{"nav", "file"}, cobra.ShellCompDirectiveNoFileComp))
 }
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestPerNavOutputDir(t *testing.T) {
+	names := []string{"per-nav-output-dir", "output-dir", "split-by", "jobs"}
+	reset := func() {
+		for _, name := range names {
+			flag := exportCmd.Flags().Lookup(name)
+			flag.Value.Set(flag.DefValue)
+			flag.Changed = false
+		}
+	}
+	defer reset()
+
+	tests := []struct {
+		name    string
+		args    []string
+		jobs    int
+		wantErr bool
+	}{
+		{"defaults", []string{"--per-nav-output-dir", "manuals"}, runtime.NumCPU(), false},
+		{"jobs", []string{"--per-nav-output-dir", "manuals", "-j", "2"}, 2, false},
+		{"split by nav", []string{"--per-nav-output-dir", "manuals", "--split-by", "nav"}, runtime.NumCPU(), false},
+		{"split by file", []string{"--per-nav-output-dir", "manuals", "--split-by", "file"}, 0, true},
+		{"output dir", []string{"--per-nav-output-dir", "manuals", "--output-dir", "out"}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset()
+			if err := exportCmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			err := applyPerNavOutputDir(exportCmd)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exportOutputDir != "manuals" || exportSplitBy != "nav" || exportJobs != tt.jobs {
+				t.Errorf("got --output-dir %q, --split-by %q, --jobs %d, want manuals, nav, %d", exportOutputDir, exportSplitBy, exportJobs, tt.jobs)
+			}
+		})
+	}
+
+	// Without the flag nothing changes
+	reset()
+	if err := applyPerNavOutputDir(exportCmd); err != nil || exportOutputDir != "" || exportJobs != 1 {
+		t.Errorf("got --output-dir %q, --jobs %d, %v", exportOutputDir, exportJobs, err)
+	}
+}
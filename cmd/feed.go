@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/sitegen"
+	"github.com/spf13/cobra"
+)
+
+var (
+	feedDir         string
+	feedBaseURL     string
+	feedOutput      string
+	feedFormat      string
+	feedTitle       string
+	feedDescription string
+	feedAuthor      string
+	feedLanguage    string
+	feedURL         string
+	feedLimit       int
+	feedFull        bool
+	feedURLStyle    string
+
+	feedCmd = &cobra.Command{
+		Use:   "feed",
+		Short: "Generate an RSS or Atom feed from markdown front matter",
+		Long: `Generate an RSS 2.0 or Atom 1.0 feed from a directory of markdown posts.
+
+Every page with a date in its front matter (date, pubDate or published)
+becomes an item, newest first. Items take their title, description (or
+summary), tags and author from the front matter, and their URL from the page
+path below --base-url like mdctl sitemap. Drafts are left out.
+
+With --full the rendered page is included, relative links and images are
+resolved against the page URL.
+
+Examples:
+  mdctl feed -d content/posts --base-url https://example.com/posts --title "My Blog"
+  mdctl feed -d content/posts --base-url https://example.com/posts --format atom -o public/atom.xml --limit 10 --full`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pages, err := sitegen.Collect(feedDir, sitegen.Options{
+				BaseURL:  feedBaseURL,
+				URLStyle: feedURLStyle,
+			})
+			if err != nil {
+				return err
+			}
+
+			opts := sitegen.FeedOptions{
+				Title:       feedTitle,
+				Description: feedDescription,
+				Link:        feedBaseURL,
+				FeedURL:     feedURL,
+				Author:      feedAuthor,
+				Language:    feedLanguage,
+				Limit:       feedLimit,
+				FullContent: feedFull,
+			}
+			if opts.Title == "" {
+				if u, err := url.Parse(feedBaseURL); err == nil {
+					opts.Title = u.Host
+				}
+			}
+			if opts.FeedURL == "" && feedOutput != "-" {
+				// The feed is assumed to be published next to the pages
+				opts.FeedURL = strings.TrimSuffix(feedBaseURL, "/") + "/" + filepath.Base(feedOutput)
+			}
+
+			feed, err := sitegen.Feed(feedFormat, pages, opts)
+			if err != nil {
+				return err
+			}
+
+			if !dryRun {
+				if feedOutput == "-" {
+					os.Stdout.Write(feed)
+					return nil
+				}
+				if err := os.WriteFile(feedOutput, feed, 0644); err != nil {
+					return fmt.Errorf("failed to write feed: %v", err)
+				}
+			}
+
+			if jsonOutput {
+				return printJSON(struct {
+					DryRun bool   `json:"dry_run"`
+					Output string `json:"output"`
+					Format string `json:"format"`
+					Bytes  int    `json:"bytes"`
+				}{DryRun: dryRun, Output: feedOutput, Format: feedFormat, Bytes: len(feed)})
+			}
+			if dryRun {
+				os.Stdout.Write(feed)
+				return nil
+			}
+			fmt.Printf("Wrote %s feed to %s\n", feedFormat, feedOutput)
+			return nil
+		},
+	}
+)
+
+func init() {
+	feedCmd.Flags().StringVarP(&feedDir, "dir", "d", ".", "Directory of the markdown posts")
+	feedCmd.Flags().StringVar(&feedBaseURL, "base-url", "", "URL the directory is published at (required)")
+	feedCmd.Flags().StringVarP(&feedOutput, "output", "o", "feed.xml", "Output file path, - for stdout")
+	feedCmd.Flags().StringVar(&feedFormat, "format", sitegen.FeedRSS, "Feed format (rss, atom)")
+	feedCmd.Flags().StringVar(&feedTitle, "title", "", "Feed title (default: host of the base URL)")
+	feedCmd.Flags().StringVar(&feedDescription, "description", "", "Feed description")
+	feedCmd.Flags().StringVar(&feedAuthor, "author", "", "Feed author, used for posts without author")
+	feedCmd.Flags().StringVar(&feedLanguage, "lang", "", "Feed language, e.g. en-US")
+	feedCmd.Flags().StringVar(&feedURL, "feed-url", "", "URL the feed is published at (default: output file name below the base URL)")
+	feedCmd.Flags().IntVar(&feedLimit, "limit", 20, "Maximum number of items (0 for all)")
+	feedCmd.Flags().BoolVar(&feedFull, "full", false, "Include the full rendered content of every post")
+	feedCmd.Flags().StringVar(&feedURLStyle, "url-style", sitegen.URLStyleDirectory, "URL style of the pages (dir, html)")
+	feedCmd.MarkFlagRequired("base-url")
+	registerCompletion(feedCmd, "format", cobra.FixedCompletions([]string{sitegen.FeedRSS, sitegen.FeedAtom}, cobra.ShellCompDirectiveNoFileComp))
+	registerCompletion(feedCmd, "url-style", cobra.FixedCompletions([]string{sitegen.URLStyleDirectory, sitegen.URLStyleHTML}, cobra.ShellCompDirectiveNoFileComp))
+
+	feedCmd.GroupID = "core"
+	rootCmd.AddCommand(feedCmd)
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/markdownfmt"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fmtFile   string
+	fmtOutput string
+
+	fmtCmd = &cobra.Command{
+		Use:   "fmt [files...]",
+		Short: "Format markdown files",
+		Long: `Format markdown files in place with the formatter of "lint --fix" and
+"translate --format": heading spacing, link syntax, spacing between CJK and
+latin text and consecutive blank lines. Directories are formatted with the
+markdown files below them.
+
+With - as the file, or -f -, the markdown is read from stdin and the
+formatted document is written to stdout. --output writes the formatted
+document of a single file elsewhere, - to stdout. --since and --staged
+only format the files changed in git.
+
+Examples:
+  mdctl fmt docs/
+  mdctl fmt --dry-run README.md docs/*.md
+  mdctl fmt --staged
+  mdctl fmt -f - < doc.md > formatted.md
+  mdctl fmt -f doc.md -o -`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if fmtFile != "" {
+				args = append(args, fmtFile)
+			}
+			selectChanged := changedOptions().Enabled()
+			if len(args) == 0 {
+				if !selectChanged {
+					return fmt.Errorf("at least one markdown file must be specified, - reads stdin")
+				}
+				args = []string{"."}
+			}
+			if fmtOutput != "" || containsString(args, stdioPath) {
+				if selectChanged {
+					return fmt.Errorf("--since and --staged cannot be used with stdin or --output")
+				}
+				if len(args) != 1 {
+					return fmt.Errorf("stdin and --output format a single document")
+				}
+				return formatStream(args[0], fmtOutput)
+			}
+
+			files, err := formatFiles(args, selectChanged)
+			if err != nil {
+				return err
+			}
+			if selectChanged && len(files) == 0 {
+				fmt.Println("No changed markdown files")
+			}
+			formatter := markdownfmt.New(true)
+			changed := []string{}
+			for _, file := range files {
+				content, err := os.ReadFile(file)
+				if err != nil {
+					return fmt.Errorf("failed to read file %s: %v", file, err)
+				}
+				formatted := formatter.Format(string(content))
+				if formatted == string(content) {
+					continue
+				}
+				changed = append(changed, file)
+				if dryRun {
+					fmt.Printf("Would format: %s\n", file)
+					continue
+				}
+				if err := fsutil.WriteFileAtomic(file, []byte(formatted), 0644); err != nil {
+					return err
+				}
+				fmt.Printf("Formatted: %s\n", file)
+			}
+
+			if jsonOutput {
+				return printJSON(struct {
+					Files   int      `json:"files"`
+					Changed []string `json:"changed"`
+					DryRun  bool     `json:"dry_run"`
+				}{len(files), changed, dryRun})
+			}
+			fmt.Printf("%d of %d files formatted\n", len(changed), len(files))
+			return nil
+		},
+	}
+)
+
+// formatStream formats a single document between stdin, stdout and files,
+// the document of stdin goes to stdout unless output is given
+func formatStream(source, output string) error {
+	if output == "" {
+		output = stdioPath
+		if source != stdioPath {
+			output = source
+		}
+	}
+	if output == stdioPath {
+		if err := reserveStdout(); err != nil {
+			return err
+		}
+	}
+
+	content, err := readInput(source)
+	if err != nil {
+		return err
+	}
+	return writeOutput(output, []byte(markdownfmt.New(true).Format(string(content))))
+}
+
+// formatFiles expands the file, glob and directory arguments of fmt into
+// markdown files, only the ones changed in git with selectChanged
+func formatFiles(args []string, selectChanged bool) ([]string, error) {
+	if selectChanged {
+		changedList, err := changedFiles(args...)
+		if err != nil {
+			return nil, err
+		}
+		var files []string
+		for _, file := range changedList {
+			if mddoc.IsMarkdown(file) {
+				files = append(files, file)
+			}
+		}
+		return files, nil
+	}
+
+	var files []string
+	for _, arg := range args {
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file pattern %s: %v", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files found matching %s", arg)
+		}
+		for _, match := range matches {
+			err := filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() && path != match && (d.Name() == ".git" || d.Name() == "node_modules") {
+					return filepath.SkipDir
+				}
+				if !d.IsDir() && (path == match || mddoc.IsMarkdown(path)) {
+					files = append(files, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to walk %s: %v", match, err)
+			}
+		}
+	}
+	return files, nil
+}
+
+func init() {
+	fmtCmd.Flags().StringVarP(&fmtFile, "file", "f", "", "Markdown file to format, - reads stdin and writes stdout")
+	fmtCmd.Flags().StringVarP(&fmtOutput, "output", "o", "", "Write the formatted document to this file instead of in place, - writes stdout")
+
+	addChangedFlags(fmtCmd)
+
+	fmtCmd.GroupID = "core"
+	rootCmd.AddCommand(fmtCmd)
+}
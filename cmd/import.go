@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/exporter"
+	"github.com/samzong/mdctl/internal/notion"
+	"github.com/spf13/cobra"
+)
+
+var (
+	notionOutput   string
+	notionSiteType string
+	docxOutput     string
+	docxImageDir   string
+
+	importCmd = &cobra.Command{
+		Use:   "import",
+		Short: "Import documents from other platforms as markdown",
+		Long: `Convert documents hosted on other platforms into a markdown tree, with images
+downloaded next to the pages and links between pages rewritten to relative links.`,
+	}
+
+	importNotionCmd = &cobra.Command{
+		Use:   "notion <export.zip|dir>",
+		Short: "Normalize a Notion markdown export",
+		Long: `Turn a Notion "Markdown & CSV" export into a standard markdown tree.
+
+Page IDs are removed from file and folder names and names are slugified, a page
+with subpages becomes the index of its folder, links follow the renames,
+notion.so links to exported pages become relative links and remaining remote
+images are downloaded. The title heading and the property lines below it move
+into front matter: dates become YYYY-MM-DD and tags become lists.
+
+Zip exports are extracted first, including the part archives of large
+workspaces. The export itself is left untouched.
+
+Examples:
+  mdctl import notion Export-1234.zip -o docs/
+  mdctl import notion notion-export/ -o content/posts -s hugo`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if notionOutput == "" {
+				return fmt.Errorf("output directory (-o) must be specified")
+			}
+
+			indexName := "index.md"
+			switch notionSiteType {
+			case "hugo":
+				indexName = "_index.md"
+			case "", "basic", "mkdocs", "docusaurus":
+			default:
+				return fmt.Errorf("unsupported site type: %s (must be basic, mkdocs, hugo or docusaurus)", notionSiteType)
+			}
+
+			stats, err := notion.Normalize(cmd.Context(), notion.Options{
+				Source:    args[0],
+				OutputDir: notionOutput,
+				IndexName: indexName,
+				DryRun:    dryRun,
+			})
+			if stats == nil {
+				return err
+			}
+			if err != nil && interrupted(cmd) {
+				err = fmt.Errorf("import interrupted after %d pages", stats.Pages)
+			}
+
+			if jsonOutput {
+				printJSON(stats)
+				return err
+			}
+			fmt.Printf("Normalized %d pages and %d files into %s (%d renamed, %d links rewritten, %d images downloaded)\n",
+				stats.Pages, stats.Files, notionOutput, stats.Renamed, stats.Links, stats.Images)
+			return err
+		},
+	}
+
+	importDocxCmd = &cobra.Command{
+		Use:     "docx <file>",
+		Aliases: []string{"odt"},
+		Short:   "Convert a Word or OpenDocument file to markdown",
+		Long: `Convert a DOCX or ODT document to GitHub-flavored markdown with Pandoc.
+
+Embedded images are extracted to the same place mdctl download stores images:
+--image-dir when given, otherwise images/ next to the markdown file. They are
+named after the document (report-image1.png) and linked relatively. The title
+and author of the document become front matter.
+
+Examples:
+  mdctl import docx report.docx -o report.md
+  mdctl import docx spec.odt -o docs/spec.md --image-dir docs/assets`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			input := args[0]
+			output := docxOutput
+			if output == "" {
+				output = strings.TrimSuffix(input, filepath.Ext(input)) + ".md"
+			}
+			if !dryRun {
+				if err := exporter.CheckPandocAvailability(); err != nil {
+					return err
+				}
+			}
+
+			result, err := exporter.ImportDocument(cmd.Context(), input, output, exporter.ImportOptions{
+				ImageDir: docxImageDir,
+				DryRun:   dryRun,
+			})
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(result)
+			}
+			if dryRun {
+				fmt.Printf("Pandoc command:\n  %s\n", strings.Join(result.Command, " "))
+				return nil
+			}
+			fmt.Printf("Imported %s to %s with %d images\n", input, output, len(result.Images))
+			return nil
+		},
+	}
+)
+
+func init() {
+	importNotionCmd.Flags().StringVarP(&notionOutput, "output", "o", "", "Output directory")
+	importNotionCmd.Flags().StringVarP(&notionSiteType, "site-type", "s", "basic", "Site type (basic, mkdocs, hugo, docusaurus), hugo names section pages _index.md")
+	importCmd.AddCommand(importNotionCmd)
+
+	importDocxCmd.Flags().StringVarP(&docxOutput, "output", "o", "", "Output markdown file (default: input name with .md)")
+	importDocxCmd.Flags().StringVar(&docxImageDir, "image-dir", "", "Directory for extracted images (default: images/ next to the output)")
+	importCmd.AddCommand(importDocxCmd)
+
+	importCmd.GroupID = "core"
+	rootCmd.AddCommand(importCmd)
+}
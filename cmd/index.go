@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/samzong/mdctl/internal/index"
+	"github.com/samzong/mdctl/internal/sitegen"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchIndexDir      string
+	searchIndexOutput   string
+	searchIndexBaseURL  string
+	searchIndexURLStyle string
+	searchIndexInclude  []string
+	searchIndexExclude  []string
+	searchIndexPush     string
+	searchIndexEndpoint string
+	searchIndexAPIKey   string
+	searchIndexName     string
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Manage the persistent file index for large repositories",
+	Long: `Build and inspect a persistent index of the markdown files in a directory.
+
+The index records each file's size, modification time, hash, front matter,
+headings, links and images in .mdctl/index.json. Commands such as download
+and upload consult it to skip unchanged files without reading them, which
+keeps repeated runs fast on repositories with tens of thousands of files.
+
+Rebuilding is incremental: only files whose size or modification time changed
+are read again.`,
+}
+
+var indexBuildCmd = &cobra.Command{
+	Use:   "build [dir]",
+	Short: "Build or update the index of a directory",
+	Example: `  mdctl index build
+  mdctl index build docs/`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := indexRoot(args)
+
+		previous, err := index.Load(root)
+		if err != nil {
+			fmt.Printf("Rebuilding index from scratch: %v\n", err)
+		}
+
+		start := time.Now()
+		idx, stats, err := index.Build(root, previous)
+		if err != nil {
+			return err
+		}
+		if err := idx.Save(); err != nil {
+			return fmt.Errorf("failed to save index: %v", err)
+		}
+
+		if jsonOutput {
+			return printJSON(struct {
+				Index string `json:"index"`
+				index.Stats
+			}{Index: index.Path(idx.Root), Stats: stats})
+		}
+
+		fmt.Printf("Indexed %d files in %v (%d added, %d updated, %d removed, %d unchanged)\n",
+			stats.Total, time.Since(start).Round(time.Millisecond), stats.Added, stats.Updated, stats.Removed, stats.Unchanged)
+		fmt.Printf("Index written to %s\n", index.Path(idx.Root))
+		return nil
+	},
+}
+
+var indexStatusCmd = &cobra.Command{
+	Use:   "status [dir]",
+	Short: "Show files changed since the index was built",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := indexRoot(args)
+
+		previous, err := index.Load(root)
+		if err != nil {
+			return err
+		}
+		if previous == nil {
+			return fmt.Errorf("no index found in %s, run 'mdctl index build' first", root)
+		}
+
+		_, stats, err := index.Build(root, previous)
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			return printJSON(struct {
+				BuiltAt time.Time `json:"built_at"`
+				index.Stats
+			}{BuiltAt: previous.BuiltAt, Stats: stats})
+		}
+
+		fmt.Printf("Index built at %s\n", previous.BuiltAt.Format(time.RFC3339))
+		fmt.Printf("  Added:     %d\n", stats.Added)
+		fmt.Printf("  Updated:   %d\n", stats.Updated)
+		fmt.Printf("  Removed:   %d\n", stats.Removed)
+		fmt.Printf("  Unchanged: %d\n", stats.Unchanged)
+		return nil
+	},
+}
+
+var indexSearchCmd = &cobra.Command{
+	Use:   "search",
+	Short: "Generate a search index of a markdown tree",
+	Long: `Extract the title, headings and plain text of every markdown file into a
+JSON search index for client-side search with lunr.js or Fuse.js, or push
+the documents to a Meilisearch or Typesense server.
+
+Each document has the fields id, url, path, title, description, headings,
+text and tags. Code blocks and raw HTML are left out of the text, and so
+are drafts and pages with noindex: true or sitemap: false. URLs are
+relative to the site root unless --base-url is given.
+
+With --push, the documents replace those with the same id in the index
+named by --index-name, which is created if it does not exist. The API key
+can also be set with the MDCTL_SEARCH_API_KEY environment variable.
+
+Examples:
+  mdctl index search -d docs/ -o search-index.json
+  mdctl index search -d docs/ --push meilisearch --endpoint http://localhost:7700 --api-key masterKey
+  mdctl index search -d docs/ --push typesense --endpoint http://localhost:8108 --index-name docs`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pages, err := sitegen.Collect(searchIndexDir, sitegen.Options{
+			BaseURL:  searchIndexBaseURL,
+			URLStyle: searchIndexURLStyle,
+			Include:  searchIndexInclude,
+			Exclude:  searchIndexExclude,
+		})
+		if err != nil {
+			return err
+		}
+		docs := sitegen.SearchDocuments(pages)
+
+		if searchIndexPush != "" {
+			apiKey := searchIndexAPIKey
+			if apiKey == "" {
+				apiKey = os.Getenv("MDCTL_SEARCH_API_KEY")
+			}
+			if !dryRun {
+				err := sitegen.PushSearchDocuments(cmd.Context(), sitegen.SearchTarget{
+					Engine:   searchIndexPush,
+					Endpoint: searchIndexEndpoint,
+					APIKey:   apiKey,
+					Index:    searchIndexName,
+				}, docs)
+				if err != nil {
+					return fmt.Errorf("failed to push search index: %v", err)
+				}
+			}
+			if jsonOutput {
+				return printJSON(struct {
+					DryRun    bool   `json:"dry_run"`
+					Engine    string `json:"engine"`
+					Index     string `json:"index"`
+					Documents int    `json:"documents"`
+				}{DryRun: dryRun, Engine: searchIndexPush, Index: searchIndexName, Documents: len(docs)})
+			}
+			if dryRun {
+				fmt.Printf("Would push %d documents to %s index %s\n", len(docs), searchIndexPush, searchIndexName)
+				return nil
+			}
+			fmt.Printf("Pushed %d documents to %s index %s\n", len(docs), searchIndexPush, searchIndexName)
+			return nil
+		}
+
+		data, err := json.MarshalIndent(docs, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+		if !dryRun {
+			if searchIndexOutput == "-" {
+				os.Stdout.Write(data)
+				return nil
+			}
+			if err := os.WriteFile(searchIndexOutput, data, 0644); err != nil {
+				return fmt.Errorf("failed to write search index: %v", err)
+			}
+		}
+
+		if jsonOutput {
+			return printJSON(struct {
+				DryRun    bool   `json:"dry_run"`
+				Output    string `json:"output"`
+				Documents int    `json:"documents"`
+			}{DryRun: dryRun, Output: searchIndexOutput, Documents: len(docs)})
+		}
+		if dryRun {
+			fmt.Printf("Would write %d documents to %s\n", len(docs), searchIndexOutput)
+			return nil
+		}
+		fmt.Printf("Wrote %d documents to %s\n", len(docs), searchIndexOutput)
+		return nil
+	},
+}
+
+// indexRoot returns the directory argument, defaulting to the current directory
+func indexRoot(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return "."
+}
+
+func init() {
+	indexCmd.AddCommand(indexBuildCmd)
+	indexCmd.AddCommand(indexStatusCmd)
+
+	indexSearchCmd.Flags().StringVarP(&searchIndexDir, "dir", "d", ".", "Directory of the markdown files")
+	indexSearchCmd.Flags().StringVarP(&searchIndexOutput, "output", "o", "search-index.json", "Output file path, - for stdout")
+	indexSearchCmd.Flags().StringVar(&searchIndexBaseURL, "base-url", "", "URL the directory is published at, URLs are root-relative without it")
+	indexSearchCmd.Flags().StringVar(&searchIndexURLStyle, "url-style", sitegen.URLStyleDirectory, "URL style of the pages (dir, html)")
+	indexSearchCmd.Flags().StringSliceVar(&searchIndexInclude, "include", nil, "Glob patterns for files to include, relative to the directory (can be specified multiple times)")
+	indexSearchCmd.Flags().StringSliceVar(&searchIndexExclude, "exclude", nil, "Glob patterns for files to exclude, relative to the directory (can be specified multiple times)")
+	indexSearchCmd.Flags().StringVar(&searchIndexPush, "push", "", "Push the documents to a search engine instead of writing a file (meilisearch, typesense)")
+	indexSearchCmd.Flags().StringVar(&searchIndexEndpoint, "endpoint", "", "URL of the search engine")
+	indexSearchCmd.Flags().StringVar(&searchIndexAPIKey, "api-key", "", "API key of the search engine")
+	indexSearchCmd.Flags().StringVar(&searchIndexName, "index-name", "docs", "Search engine index or collection to push to")
+	registerCompletion(indexSearchCmd, "url-style", cobra.FixedCompletions([]string{sitegen.URLStyleDirectory, sitegen.URLStyleHTML}, cobra.ShellCompDirectiveNoFileComp))
+	registerCompletion(indexSearchCmd, "push", cobra.FixedCompletions([]string{sitegen.SearchMeilisearch, sitegen.SearchTypesense}, cobra.ShellCompDirectiveNoFileComp))
+	indexCmd.AddCommand(indexSearchCmd)
+}
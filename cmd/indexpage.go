@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/sitegen"
+	"github.com/spf13/cobra"
+)
+
+var (
+	indexPageDir      string
+	indexPageOutput   string
+	indexPageFormat   string
+	indexPageTitle    string
+	indexPageDepth    int
+	indexPageTemplate string
+	indexPageInclude  []string
+	indexPageExclude  []string
+
+	indexPageCmd = &cobra.Command{
+		Use:   "index-page",
+		Short: "Generate a table of contents page for a directory",
+		Long: `Generate a nested index of the markdown files in a directory, grouped by
+subdirectory, with the titles from the front matter or the first heading.
+
+--format markdown writes a nested list of links relative to the output file.
+When it is written to a file, the list is placed between
+<!-- mdctl:index --> and <!-- /mdctl:index --> markers, and later runs only
+replace the part between the markers, so text around them is kept.
+
+--format mkdocs writes a nav snippet for mkdocs.yml with paths relative to
+the directory. --template renders a Go text/template instead, which receives
+.Title and .Entries; every entry has .Title, .Path, .Link and .Children.
+
+A directory's index.md, README.md or _index.md gives the directory its title
+and link. Drafts and the output file itself are left out.
+
+Examples:
+  mdctl index-page -d docs/ -o docs/README.md --title "Documentation"
+  mdctl index-page -d docs/ --format mkdocs
+  mdctl index-page -d notes/ -o notes/index.md --depth 2`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pages, err := sitegen.Collect(indexPageDir, sitegen.Options{Include: indexPageInclude, Exclude: indexPageExclude})
+			if err != nil {
+				return err
+			}
+
+			// Links are relative to the output file, stdout is treated as a
+			// file at the root
+			output := "index.md"
+			if indexPageOutput != "-" {
+				rel, err := filepath.Rel(indexPageDir, indexPageOutput)
+				if err != nil {
+					return fmt.Errorf("failed to resolve output path: %v", err)
+				}
+				output = filepath.ToSlash(rel)
+			}
+			if strings.HasPrefix(output, "../") && indexPageFormat == sitegen.IndexMarkdown {
+				return fmt.Errorf("output %s is outside of %s, links would not resolve", indexPageOutput, indexPageDir)
+			}
+
+			opts := sitegen.IndexOptions{
+				Format: indexPageFormat,
+				Title:  indexPageTitle,
+				Depth:  indexPageDepth,
+				Output: output,
+			}
+			if indexPageTemplate != "" {
+				data, err := os.ReadFile(indexPageTemplate)
+				if err != nil {
+					return fmt.Errorf("failed to read template: %v", err)
+				}
+				opts.Template = string(data)
+			}
+
+			tree := sitegen.IndexTree(pages, output)
+			index, err := sitegen.RenderIndex(tree, opts)
+			if err != nil {
+				return err
+			}
+
+			if indexPageOutput == "-" {
+				if jsonOutput {
+					return printJSON(tree)
+				}
+				os.Stdout.Write(index)
+				return nil
+			}
+
+			changed := false
+			if !dryRun {
+				markers := indexPageTemplate == "" && indexPageFormat == sitegen.IndexMarkdown
+				if changed, err = sitegen.WriteIndexFile(indexPageOutput, index, markers); err != nil {
+					return err
+				}
+			}
+
+			if jsonOutput {
+				return printJSON(struct {
+					DryRun  bool                  `json:"dry_run"`
+					Output  string                `json:"output"`
+					Changed bool                  `json:"changed"`
+					Entries []*sitegen.IndexEntry `json:"entries"`
+				}{DryRun: dryRun, Output: indexPageOutput, Changed: changed, Entries: tree})
+			}
+			switch {
+			case dryRun:
+				fmt.Printf("Would write the index of %d pages to %s\n", len(pages), indexPageOutput)
+			case changed:
+				fmt.Printf("Wrote the index of %d pages to %s\n", len(pages), indexPageOutput)
+			default:
+				fmt.Printf("Index %s is up to date\n", indexPageOutput)
+			}
+			return nil
+		},
+	}
+)
+
+func init() {
+	indexPageCmd.Flags().StringVarP(&indexPageDir, "dir", "d", ".", "Directory of the markdown files")
+	indexPageCmd.Flags().StringVarP(&indexPageOutput, "output", "o", "-", "Output file path, - for stdout")
+	indexPageCmd.Flags().StringVar(&indexPageFormat, "format", sitegen.IndexMarkdown, "Index format (markdown, mkdocs)")
+	indexPageCmd.Flags().StringVar(&indexPageTitle, "title", "", "Heading above the index")
+	indexPageCmd.Flags().IntVar(&indexPageDepth, "depth", 0, "Maximum directory depth of the index (0 for all)")
+	indexPageCmd.Flags().StringVar(&indexPageTemplate, "template", "", "Go text/template file rendering the index")
+	indexPageCmd.Flags().StringSliceVar(&indexPageInclude, "include", nil, "Glob patterns for files to include, relative to the directory (can be specified multiple times)")
+	indexPageCmd.Flags().StringSliceVar(&indexPageExclude, "exclude", nil, "Glob patterns for files to exclude, relative to the directory (can be specified multiple times)")
+	registerCompletion(indexPageCmd, "format", cobra.FixedCompletions([]string{sitegen.IndexMarkdown, sitegen.IndexMkDocs}, cobra.ShellCompDirectiveNoFileComp))
+
+	indexPageCmd.GroupID = "core"
+	rootCmd.AddCommand(indexPageCmd)
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/samzong/mdctl/internal/linkgraph"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backlinksHeading string
+	backlinksWiki    bool
+
+	linksCmd = &cobra.Command{
+		Use:   "links",
+		Short: "Analyze the links between markdown files",
+		Long: `Build an index of the wiki-links ([[note]], [[note|alias]]) and markdown links
+between the files of a directory, then print the link graph, report orphan
+pages or write a backlinks section into every file.
+
+Wiki-links resolve like Obsidian: a path relative to the directory, or a note
+name matched case-insensitively. Markdown links resolve relative to the file,
+directory links such as guide/ match guide/index.md or guide/README.md.
+
+Examples:
+  mdctl links graph vault/ --json > graph.json
+  mdctl links orphans docs/
+  mdctl links backlinks vault/ --wiki`,
+	}
+
+	linksGraphCmd = &cobra.Command{
+		Use:   "graph [dir]",
+		Short: "Print the link graph of a directory",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			g, err := linkgraph.Build(indexRoot(args))
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return printJSON(g)
+			}
+
+			for _, n := range g.Nodes {
+				fmt.Printf("%s (%d in, %d out)\n", n.Path, n.Incoming, n.Outgoing)
+			}
+			for _, e := range g.Edges {
+				if e.From != e.To {
+					fmt.Printf("  %s -> %s\n", e.From, e.To)
+				}
+			}
+			for _, e := range g.Unresolved {
+				fmt.Printf("%s:%d: unresolved %s link: %s\n", e.From, e.Line, e.Kind, e.Target)
+			}
+			fmt.Printf("%d files, %d links, %d unresolved\n", len(g.Nodes), len(g.Edges), len(g.Unresolved))
+			return nil
+		},
+	}
+
+	linksOrphansCmd = &cobra.Command{
+		Use:   "orphans [dir]",
+		Short: "Report files no other file links to",
+		Long: `Report the files no other file links to. The entry pages index.md, README.md
+and _index.md at the top of the directory are never reported.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			g, err := linkgraph.Build(indexRoot(args))
+			if err != nil {
+				return err
+			}
+			orphans := g.Orphans()
+			if jsonOutput {
+				return printJSON(orphans)
+			}
+
+			for _, n := range orphans {
+				fmt.Printf("%s (%s)\n", n.Path, n.Title)
+			}
+			fmt.Printf("%d orphan pages out of %d\n", len(orphans), len(g.Nodes))
+			return nil
+		},
+	}
+
+	linksBacklinksCmd = &cobra.Command{
+		Use:   "backlinks [dir]",
+		Short: "Write a backlinks section into every linked file",
+		Long: `Append a "## Referenced by" section listing the files that link to it to every
+file with backlinks. The section is delimited by HTML comments, replaced on
+every run and removed again from files that lost their backlinks, and its
+links are ignored when the graph is built.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			g, err := linkgraph.Build(indexRoot(args))
+			if err != nil {
+				return err
+			}
+			changes, err := g.WriteBacklinks(linkgraph.BacklinkOptions{
+				Heading: backlinksHeading,
+				Wiki:    backlinksWiki,
+				DryRun:  dryRun,
+			})
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(changes)
+			}
+			for _, c := range changes {
+				fmt.Printf("%s: %d backlinks\n", c.File, len(c.Backlinks))
+			}
+			verb := "Updated"
+			if dryRun {
+				verb = "Would update"
+			}
+			fmt.Printf("%s %d files\n", verb, len(changes))
+			return nil
+		},
+	}
+)
+
+func init() {
+	linksBacklinksCmd.Flags().StringVar(&backlinksHeading, "heading", "Referenced by", "Heading of the backlinks section")
+	linksBacklinksCmd.Flags().BoolVar(&backlinksWiki, "wiki", false, "Write [[wiki-links]] instead of relative markdown links")
+
+	linksCmd.AddCommand(linksGraphCmd)
+	linksCmd.AddCommand(linksOrphansCmd)
+	linksCmd.AddCommand(linksBacklinksCmd)
+	linksCmd.GroupID = "core"
+	rootCmd.AddCommand(linksCmd)
+}
@@ -5,21 +5,43 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
+	mdconfig "github.com/samzong/mdctl/internal/config"
 	"github.com/samzong/mdctl/internal/linter"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"github.com/samzong/mdctl/internal/schema"
+	"github.com/samzong/mdctl/internal/translator"
 	"github.com/spf13/cobra"
 )
 
 var (
-	autoFix      bool
-	configRules  []string
-	outputFormat string
-	rulesFile    string
-	enableRules  []string
-	disableRules []string
-	initConfig   bool
-	configOutput string
+	autoFix         bool
+	configRules     []string
+	outputFormat    string
+	rulesFile       string
+	enableRules     []string
+	disableRules    []string
+	initConfig      bool
+	configOutput    string
+	lintConcurrency int
+	baselineFile    string
+	saveBaseline    bool
+	maxWarnings     int
+	spellCheck      bool
+	spellLangs      []string
+	spellDicts      []string
+	spellWords      string
+	spellTerms      string
+	styleFiles      []string
+	schemaFile      string
+	explainIssues   bool
+	explainLimit    int
+	lintReflow      string
+	lintBackup      string
+	lintBackupDir   string
+	lintInputs      []string
 )
 
 var lintCmd = &cobra.Command{
@@ -40,6 +62,40 @@ Examples:
   # Lint with auto-fix
   mdctl lint --fix README.md
 
+  # Preview the fixes as a diff without writing them
+  mdctl lint --fix --dry-run docs/*.md
+
+  # Lint only the files changed since a git ref, or staged for commit
+  mdctl lint --since origin/main docs/
+  mdctl lint --fix --staged
+
+  # Lint piped content, or fix it and write the result to stdout
+  cat README.md | mdctl lint -
+  mdctl lint --fix -f - < README.md > FIXED.md
+
+  # Adopt the linter on an existing repository: record the current issues,
+  # then only fail on new errors and on more than 20 warnings
+  mdctl lint --baseline .mdctl-lint-baseline.json --update-baseline docs/
+  mdctl lint --baseline .mdctl-lint-baseline.json --max-warnings 20 docs/
+
+  # Check spelling and product name casing (GitHub, not Github)
+  mdctl lint --spell --words .mdctl-words.txt docs/
+  mdctl lint --spell --fix docs/
+
+  # Fix long lines by rewrapping paragraphs, or one sentence per line
+  mdctl lint --fix --reflow wrap docs/
+  mdctl lint --fix --reflow semantic docs/
+
+  # Apply prose style rules: banned phrases, passive voice, sentence length
+  mdctl lint --style styles/docs.yaml docs/
+
+  # Ask the configured AI model to explain issues that cannot be fixed
+  # automatically and to suggest a rewrite, shown as a diff
+  mdctl lint --explain docs/guide.md
+
+  # Validate front matter against a JSON Schema (required keys, enums, dates)
+  mdctl lint --frontmatter-schema schema.yaml docs/
+
   # Lint with custom rules configuration
   mdctl lint --config .markdownlint.json README.md
 
@@ -53,7 +109,24 @@ Examples:
   mdctl lint --init
 
   # Create a configuration file with custom name
-  mdctl lint --init --init-config my-rules.json`,
+  mdctl lint --init --init-config my-rules.json
+
+Each rule reports issues with a severity set in the configuration file, for
+example "MD013": {"severity": "warning"}. Errors fail the run, warnings only
+once there are more than --max-warnings, info never does.
+
+Long lines (MD013) are only fixed with --reflow, or "reflow" in the MD013
+configuration next to its "line_length": wrap fills paragraphs up to the line
+length, semantic starts every sentence on a new line. Code, tables, headings
+and HTML are kept, links and code spans are never broken, and CJK characters
+count as two columns.
+
+--fix only rewrites files whose content changes, replacing them atomically so
+an interrupted run never truncates a file. The original is kept as
+<file>.orig (--backup orig, the default), below --backup-dir with the same
+relative path (--backup dir) or not at all (--backup none):
+
+  mdctl lint --fix --backup dir --backup-dir .mdctl-backup docs/`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Handle config initialization
 		if initConfig {
@@ -70,13 +143,43 @@ Examples:
 			return nil
 		}
 
+		if explainIssues && autoFix {
+			return fmt.Errorf("--explain cannot be combined with --fix")
+		}
+		if !linter.ValidBackup(lintBackup) {
+			return fmt.Errorf("invalid --backup %q: use none, orig or dir", lintBackup)
+		}
+		if lintReflow != "" {
+			if err := linter.ValidateReflow(lintReflow); err != nil {
+				return err
+			}
+		}
+
+		args = append(args, lintInputs...)
+		selectChanged := changedOptions().Enabled()
 		if len(args) == 0 {
-			return fmt.Errorf("at least one markdown file must be specified")
+			if !selectChanged {
+				return fmt.Errorf("at least one markdown file must be specified")
+			}
+			args = []string{"."}
 		}
 
 		// Expand file patterns
 		var files []string
 		for _, arg := range args {
+			if selectChanged {
+				if arg == stdioPath || strings.Contains(arg, "..") {
+					return fmt.Errorf("--since and --staged need files or directories in the repository: %s", arg)
+				}
+				continue
+			}
+
+			// "-" reads the markdown from stdin
+			if arg == stdioPath {
+				files = append(files, arg)
+				continue
+			}
+
 			// Basic security validation - prevent path traversal
 			if strings.Contains(arg, "..") {
 				return fmt.Errorf("path traversal not allowed: %s", arg)
@@ -98,48 +201,148 @@ Examples:
 			}
 		}
 
+		// Only lint the files changed in git
+		if selectChanged {
+			var err error
+			if files, err = changedFiles(args...); err != nil {
+				return err
+			}
+		}
+
 		// Filter for markdown files
 		var markdownFiles []string
 		for _, file := range files {
-			if strings.HasSuffix(strings.ToLower(file), ".md") || strings.HasSuffix(strings.ToLower(file), ".markdown") {
+			if file == stdioPath || mddoc.IsMarkdown(file) {
 				markdownFiles = append(markdownFiles, file)
 			}
 		}
 
 		if len(markdownFiles) == 0 {
+			if selectChanged {
+				fmt.Println("No changed markdown files")
+				if jsonOutput {
+					return printJSON(lintReport{Files: []*linter.Result{}})
+				}
+				return nil
+			}
 			return fmt.Errorf("no markdown files found")
 		}
 
 		// Create linter configuration
 		config := &linter.Config{
 			AutoFix:      autoFix,
+			DryRun:       dryRun,
 			OutputFormat: outputFormat,
 			RulesFile:    rulesFile,
 			EnableRules:  enableRules,
 			DisableRules: disableRules,
 			Verbose:      verbose,
+			Reflow:       lintReflow,
+			Backup:       lintBackup,
+			BackupDir:    lintBackupDir,
+		}
+
+		if spellCheck {
+			spelling, err := linter.LoadSpelling(linter.SpellOptions{
+				Languages:    spellLangs,
+				Dictionaries: spellDicts,
+				WordsFile:    spellWords,
+				TermsFile:    spellTerms,
+			})
+			if err != nil {
+				return err
+			}
+			config.Spelling = spelling
+		}
+
+		styles, err := linter.LoadStyles(styleFiles)
+		if err != nil {
+			return err
+		}
+		config.Styles = styles
+
+		if schemaFile != "" {
+			if config.FrontMatter, err = schema.Load(schemaFile); err != nil {
+				return err
+			}
+		}
+
+		// Known issues are suppressed, unless the baseline is being recorded
+		if saveBaseline && baselineFile == "" {
+			return fmt.Errorf("--update-baseline requires --baseline")
+		}
+		if baselineFile != "" && !saveBaseline {
+			baseline, err := linter.LoadBaseline(baselineFile)
+			if err != nil {
+				return fmt.Errorf("%v (record one with --update-baseline)", err)
+			}
+			config.Baseline = baseline
 		}
 
 		// Create linter instance
 		mdLinter := linter.New(config)
 
+		// Explanations are only requested with --explain, linting never needs the network
+		var explainer *translator.Translator
+		if explainIssues {
+			cfg, err := mdconfig.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %v", err)
+			}
+			explainer = translator.New(cfg, false).WithContext(cmd.Context())
+		}
+
+		// Fixed stdin content goes to stdout, so reports move to stderr
+		fixStdin := autoFix && !dryRun && containsString(markdownFiles, stdioPath)
+		if fixStdin {
+			if err := reserveStdout(); err != nil {
+				return err
+			}
+		}
+
 		// Process files
 		var totalIssues int
 		var totalFixed int
-
-		for _, file := range markdownFiles {
-			if verbose {
-				fmt.Printf("Linting: %s\n", file)
+		var totalBaselined int
+		var errorCount, warningCount int
+		var results []*linter.Result
+		var allResults []*linter.Result
+		var lintErrors []fileError
+
+		outcomes := lintFiles(mdLinter, markdownFiles, fixStdin, lintConcurrency)
+		for i, file := range markdownFiles {
+			result, err := outcomes[i].Result, outcomes[i].Err
+			if file == stdioPath {
+				file = stdinName
 			}
-
-			result, err := mdLinter.LintFile(file)
 			if err != nil {
 				fmt.Printf("Error linting %s: %v\n", file, err)
+				lintErrors = append(lintErrors, fileError{File: file, Error: err.Error()})
 				continue
 			}
 
+			if explainer != nil && file != stdinName {
+				content, err := os.ReadFile(file)
+				if err != nil {
+					return fmt.Errorf("failed to read file %s: %v", file, err)
+				}
+				if err := mdLinter.Explain(result, string(content), explainer.Chat, explainLimit); err != nil {
+					return err
+				}
+			}
+
 			totalIssues += len(result.Issues)
 			totalFixed += result.FixedCount
+			totalBaselined += result.Baselined
+			errorCount += result.Count(linter.SeverityError)
+			warningCount += result.Count(linter.SeverityWarning)
+			allResults = append(allResults, result)
+
+			// Structured output is emitted once all files are linted
+			if jsonOutput {
+				results = append(results, result)
+				continue
+			}
 
 			// Display results based on output format
 			if err := displayResults(file, result, config); err != nil {
@@ -147,25 +350,126 @@ Examples:
 			}
 		}
 
+		if saveBaseline {
+			baseline := linter.NewBaseline(allResults)
+			if err := baseline.Save(baselineFile); err != nil {
+				return fmt.Errorf("failed to write baseline: %v", err)
+			}
+			fmt.Printf("Recorded %d issues in baseline %s\n", totalIssues, baselineFile)
+			return nil
+		}
+
+		// Exit with error code on errors, or too many warnings, unless fixing
+		failed := !autoFix && (errorCount > 0 || (maxWarnings >= 0 && warningCount > maxWarnings))
+
+		if jsonOutput {
+			if err := printJSON(lintReport{
+				Files:  results,
+				Errors: lintErrors,
+				Summary: lintSummary{
+					FilesProcessed: len(markdownFiles),
+					TotalIssues:    totalIssues,
+					Errors:         errorCount,
+					Warnings:       warningCount,
+					Baselined:      totalBaselined,
+					IssuesFixed:    totalFixed,
+				},
+			}); err != nil {
+				return err
+			}
+			if failed {
+				exitWith(1)
+			}
+			return nil
+		}
+
 		// Summary
 		if verbose || len(markdownFiles) > 1 {
 			fmt.Printf("\nSummary:\n")
 			fmt.Printf("  Files processed: %d\n", len(markdownFiles))
-			fmt.Printf("  Total issues: %d\n", totalIssues)
-			if autoFix {
+			fmt.Printf("  Total issues: %d (%d errors, %d warnings)\n", totalIssues, errorCount, warningCount)
+			if totalBaselined > 0 {
+				fmt.Printf("  Baselined issues: %d\n", totalBaselined)
+			}
+			if autoFix && dryRun {
+				fmt.Printf("  Issues that would be fixed: %d\n", totalFixed)
+			} else if autoFix {
 				fmt.Printf("  Issues fixed: %d\n", totalFixed)
 			}
 		}
 
-		// Exit with error code if issues found and not in fix mode
-		if totalIssues > 0 && !autoFix {
-			os.Exit(1)
+		if maxWarnings >= 0 && warningCount > maxWarnings {
+			fmt.Printf("Too many warnings: %d (max %d)\n", warningCount, maxWarnings)
+		}
+		if failed {
+			exitWith(1)
 		}
 
 		return nil
 	},
 }
 
+// lintFiles lints files concurrently, stdin included
+func lintFiles(mdLinter *linter.Linter, files []string, fixStdin bool, workers int) []linter.Outcome {
+	return linter.LintFiles(files, workers, func(file string) (*linter.Result, error) {
+		if verbose {
+			fmt.Printf("Linting: %s\n", file)
+		}
+		if file == stdioPath {
+			return lintStdin(mdLinter, fixStdin)
+		}
+		return mdLinter.LintFile(file)
+	})
+}
+
+// lintStdin lints markdown read from stdin, with fix the fixed content is
+// written to stdout instead of a file
+func lintStdin(mdLinter *linter.Linter, fix bool) (*linter.Result, error) {
+	content, err := readInput(stdioPath)
+	if err != nil {
+		return nil, err
+	}
+	if !fix {
+		return mdLinter.LintContent(stdinName, string(content))
+	}
+
+	result, fixed := mdLinter.FixContent(stdinName, string(content))
+	return result, writeOutput(stdioPath, []byte(fixed))
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// fileError records a file that could not be processed
+type fileError struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// lintSummary holds aggregate lint statistics
+type lintSummary struct {
+	FilesProcessed int `json:"files_processed"`
+	TotalIssues    int `json:"total_issues"`
+	Errors         int `json:"errors"`
+	Warnings       int `json:"warnings"`
+	Baselined      int `json:"baselined,omitempty"`
+	IssuesFixed    int `json:"issues_fixed"`
+}
+
+// lintReport is the --json output of the lint command
+type lintReport struct {
+	Files   []*linter.Result `json:"files"`
+	Errors  []fileError      `json:"errors,omitempty"`
+	Summary lintSummary      `json:"summary"`
+}
+
 func displayResults(filename string, result *linter.Result, config *linter.Config) error {
 	switch config.OutputFormat {
 	case "json":
@@ -186,22 +490,43 @@ func displayDefaultResults(filename string, result *linter.Result, config *linte
 	}
 
 	fmt.Printf("%s:\n", filename)
+	lastRewrite := ""
 	for _, issue := range result.Issues {
 		status := "✗"
 		if issue.Fixed {
 			status = "✓"
 		}
 
-		fmt.Printf("  %s Line %d: %s (%s)\n",
-			status, issue.Line, issue.Message, issue.Rule)
+		severity := ""
+		if issue.Severity != linter.SeverityError {
+			severity = " [" + issue.Severity + "]"
+		}
+		fmt.Printf("  %s Line %d%s: %s (%s)\n",
+			status, issue.Line, severity, issue.Message, issue.Rule)
 
 		if config.Verbose && issue.Context != "" {
 			fmt.Printf("    Context: %s\n", issue.Context)
 		}
+		if issue.Explanation != "" {
+			fmt.Printf("    Explanation: %s\n", issue.Explanation)
+		}
+		// Issues on the same passage share their suggested rewrite
+		if issue.Rewrite != "" && issue.Rewrite != lastRewrite {
+			fmt.Printf("    Suggested rewrite:\n")
+			for _, line := range strings.Split(strings.TrimRight(issue.Rewrite, "\n"), "\n") {
+				fmt.Printf("      %s\n", line)
+			}
+			lastRewrite = issue.Rewrite
+		}
 	}
 
 	if config.AutoFix && result.FixedCount > 0 {
-		fmt.Printf("  Fixed %d issues\n", result.FixedCount)
+		if config.DryRun {
+			fmt.Printf("  Would fix %d issues:\n", result.FixedCount)
+			fmt.Print(result.Diff)
+		} else {
+			fmt.Printf("  Fixed %d issues\n", result.FixedCount)
+		}
 	}
 
 	return nil
@@ -226,25 +551,50 @@ func displayJSONResults(filename string, result *linter.Result) error {
 func displayGitHubResults(filename string, result *linter.Result) error {
 	// GitHub Actions workflow commands format
 	for _, issue := range result.Issues {
-		level := "error"
-		if issue.Fixed {
+		level := issue.Severity
+		if issue.Fixed || level == linter.SeverityInfo {
 			level = "notice"
 		}
 
-		fmt.Printf("::%s file=%s,line=%d::%s (%s)\n",
-			level, filename, issue.Line, issue.Message, issue.Rule)
+		fmt.Printf("::%s file=%s,line=%d::%s\n",
+			level, githubProperty(filename), issue.Line, githubData(issue.Message+" ("+issue.Rule+")"))
 	}
 	return nil
 }
 
 func init() {
 	lintCmd.Flags().BoolVar(&autoFix, "fix", false, "Automatically fix issues where possible")
+	lintCmd.Flags().StringSliceVarP(&lintInputs, "file", "f", nil, "Markdown file to lint like the file arguments, - reads stdin")
 	lintCmd.Flags().StringVar(&outputFormat, "format", "default", "Output format: default, json, github")
 	lintCmd.Flags().StringVar(&rulesFile, "config", "", "Path to markdownlint configuration file")
 	lintCmd.Flags().StringSliceVar(&enableRules, "enable", []string{}, "Enable specific rules (comma-separated)")
 	lintCmd.Flags().StringSliceVar(&disableRules, "disable", []string{}, "Disable specific rules (comma-separated)")
 	lintCmd.Flags().BoolVar(&initConfig, "init", false, "Create a default .markdownlint.json configuration file")
 	lintCmd.Flags().StringVar(&configOutput, "init-config", "", "Path for the configuration file when using --init (default: .markdownlint.json)")
+	lintCmd.Flags().StringVar(&baselineFile, "baseline", "", "Baseline file of known issues that are not reported")
+	lintCmd.Flags().BoolVar(&saveBaseline, "update-baseline", false, "Record the current issues in the --baseline file")
+	lintCmd.Flags().BoolVar(&spellCheck, "spell", false, "Check spelling (SPELL001) and terminology (TERM001)")
+	lintCmd.Flags().StringSliceVar(&spellLangs, "spell-lang", nil, "Dictionary languages for --spell (default en unless --dictionary is set)")
+	lintCmd.Flags().StringSliceVar(&spellDicts, "dictionary", nil, "Additional dictionary files (word lists or Hunspell .dic)")
+	lintCmd.Flags().StringVar(&spellWords, "words", "", "Project word allowlist (default .mdctl-words.txt when present)")
+	lintCmd.Flags().StringVar(&spellTerms, "terms", "", "Terminology file, one term or \"wrong => right\" per line")
+	lintCmd.Flags().StringSliceVar(&styleFiles, "style", nil, "Prose style rule files (default .mdctl-style.yaml when present)")
+	lintCmd.Flags().StringVar(&schemaFile, "frontmatter-schema", "", "JSON Schema (JSON or YAML) the front matter must match (FM001)")
+	lintCmd.Flags().IntVar(&lintConcurrency, "concurrency", runtime.NumCPU(), "Number of files linted concurrently")
+	lintCmd.Flags().BoolVar(&explainIssues, "explain", false, "Ask the configured AI model to explain issues that cannot be fixed and suggest rewrites")
+	lintCmd.Flags().IntVar(&explainLimit, "explain-limit", 10, "Maximum number of AI requests per file with --explain (0 for no limit)")
+	lintCmd.Flags().StringVar(&lintReflow, "reflow", "", "Fix long lines by reflowing paragraphs (wrap, semantic)")
+	lintCmd.Flags().IntVar(&maxWarnings, "max-warnings", -1, "Fail when there are more warnings than this (-1 for no limit)")
+	lintCmd.Flags().StringVar(&lintBackup, "backup", linter.BackupOrig, "Backup of files rewritten by --fix: none, orig (<file>.orig) or dir (below --backup-dir)")
+	lintCmd.Flags().StringVar(&lintBackupDir, "backup-dir", linter.DefaultBackupDir, "Directory of the backups with --backup dir")
+
+	registerCompletion(lintCmd, "format", cobra.FixedCompletions([]string{"default", "json", "github"}, cobra.ShellCompDirectiveNoFileComp))
+	registerCompletion(lintCmd, "backup", cobra.FixedCompletions([]string{linter.BackupNone, linter.BackupOrig, linter.BackupDir}, cobra.ShellCompDirectiveNoFileComp))
+	registerCompletion(lintCmd, "reflow", cobra.FixedCompletions([]string{"wrap", "semantic"}, cobra.ShellCompDirectiveNoFileComp))
+	registerCompletion(lintCmd, "enable", completeRuleIDs)
+	registerCompletion(lintCmd, "disable", completeRuleIDs)
+
+	addChangedFlags(lintCmd)
 
 	lintCmd.GroupID = "core"
 }
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLintConcurrencyKeepsOrder(t *testing.T) {
+	defer func() { autoFix, dryRun, lintConcurrency = false, false, runtime.NumCPU() }()
+
+	dir := t.TempDir()
+	var files []string
+	for i := 0; i < 12; i++ {
+		file := filepath.Join(dir, fmt.Sprintf("doc%02d.md", i))
+		// Files of different sizes finish in a different order than they start
+		content := "# Title\n\n### Deep\n\n" + strings.Repeat("Line  \n\tTab\n", 12-i)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+
+	run := func(concurrency int) string {
+		t.Helper()
+		args := append([]string{"lint", "--fix", "--dry-run", "--concurrency", strconv.Itoa(concurrency)}, files...)
+		out, err := runOutput(t, "", args...)
+		if err != nil {
+			t.Fatalf("lint --concurrency %d failed: %v", concurrency, err)
+		}
+		return out
+	}
+
+	serial := run(1)
+	if !strings.Contains(serial, "MD001") || !strings.Contains(serial, "MD009") {
+		t.Fatalf("expected issues in the output:\n%s", serial)
+	}
+	last := -1
+	for _, file := range files {
+		i := strings.Index(serial, file)
+		if i <= last {
+			t.Fatalf("expected %s after the previous file in:\n%s", file, serial)
+		}
+		last = i
+	}
+	for attempt := 0; attempt < 3; attempt++ {
+		if parallel := run(8); parallel != serial {
+			t.Fatalf("parallel output differs from serial output:\n%s\n---\n%s", parallel, serial)
+		}
+	}
+}
@@ -1,13 +1,19 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/samzong/mdctl/internal/llmstxt"
 	"github.com/spf13/cobra"
 )
 
+// llmstxtUserAgent is sent when fetching sitemaps and pages
+const llmstxtUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/115.0.0.0 Safari/537.36"
+
 var (
 	includePaths []string
 	excludePaths []string
@@ -16,6 +22,15 @@ var (
 	concurrency  int
 	timeout      int
 	maxPages     int
+	templatePath string
+	llmstxtLang  string
+
+	llmstxtMinSuccessRate float64
+	llmstxtErrorReport    string
+
+	llmstxtHeaders    []string
+	llmstxtBasicAuth  string
+	llmstxtCookieFile string
 
 	llmstxtCmd = &cobra.Command{
 		Use:   "llmstxt [url]",
@@ -24,19 +39,75 @@ var (
 list of the website's pages in markdown format, perfect for training or fine-tuning 
 language models.
 
+The URL is either a sitemap (optionally gzip-compressed) or a site URL, whose
+sitemaps are discovered from the Sitemap directives of robots.txt or the common
+/sitemap.xml and /sitemap_index.xml locations.
+
 In standard mode, only title and description are extracted. In full mode (-f flag), 
 the content of each page is also extracted.
 
+Pages marked noindex (robots meta or X-Robots-Tag) are skipped, and pages whose
+canonical URL points at another page are merged into it. With --lang, pages are
+kept when their <html lang> or hreflang matches, and URLs under another
+language prefix such as /zh/ are not fetched at all.
+
 Examples:
   # Standard mode
   mdctl llmstxt https://example.com/sitemap.xml > llms.txt
 
+  # Discover the sitemap from the site root
+  mdctl llmstxt https://example.com > llms.txt
+
   # Full-content mode
-  mdctl llmstxt -f https://example.com/sitemap.xml > llms-full.txt`,
+  mdctl llmstxt -f https://example.com/sitemap.xml > llms-full.txt
+
+  # Only English pages of a multilingual site
+  mdctl llmstxt --lang en https://example.com > llms.txt
+
+  # A site behind basic auth or an SSO proxy
+  mdctl llmstxt --basic-auth "$DOCS_USER:$DOCS_PASSWORD" https://docs.internal.example.com > llms.txt
+  mdctl llmstxt -H "Authorization: Bearer $TOKEN" --cookie-file cookies.txt https://docs.internal.example.com
+
+  # Custom output format
+  mdctl llmstxt --template llms.tmpl https://example.com > llms.txt
+
+  # In CI: fail when more than 5% of the pages are missing, and keep a report
+  mdctl llmstxt --min-success-rate 0.95 --error-report llms-errors.json https://example.com -o llms.txt
+
+--header and --basic-auth are only sent to the host of the URL, not to other
+hosts the sitemap lists. --cookie-file reads a Netscape cookies.txt file, as
+exported by browser extensions or curl -c, whose cookies go to their domains.
+
+Pages that fail to fetch or parse are left out with a warning. --error-report
+writes them to a JSON file with the URL, the stage that failed (fetch or
+parse), the HTTP status and the error, and --json output lists them under
+stats.failures. With --min-success-rate the run fails without writing any
+output when a smaller share of the pages could be fetched.
+
+A template is a Go text/template file. It receives .URL, .Title, .Description,
+.Sections (each with .Name, .Title and .Pages), .Pages, .FullMode and
+.Generated, pages have .Title, .URL, .Description, .Content, .Section,
+.Published, .Modified, .Updated (modified or else published), .Authors and
+.Tags. Dates and authors come from JSON-LD, OpenGraph and meta tags.
+(.Section "docs") looks up a section by name, and the capitalize, lower, upper,
+trim, replace, truncate, default, join and date functions are available, e.g.
+{{with date "2006-01-02" .Updated}} (updated {{.}}){{end}} or
+{{join ", " .Authors}}.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			sitemapURL := args[0]
 
+			headers, err := llmstxt.ParseHeaders(llmstxtHeaders)
+			if err != nil {
+				return err
+			}
+			if llmstxtBasicAuth != "" && !strings.Contains(llmstxtBasicAuth, ":") {
+				return fmt.Errorf("invalid --basic-auth, expected user:password")
+			}
+			if llmstxtMinSuccessRate < 0 || llmstxtMinSuccessRate > 1 {
+				return fmt.Errorf("invalid --min-success-rate %v, expected a value between 0 and 1", llmstxtMinSuccessRate)
+			}
+
 			// Create a generator and configure options
 			config := llmstxt.GeneratorConfig{
 				SitemapURL:   sitemapURL,
@@ -45,18 +116,69 @@ Examples:
 				FullMode:     fullMode,
 				Concurrency:  concurrency,
 				Timeout:      timeout,
-				UserAgent:    "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/115.0.0.0 Safari/537.36",
+				UserAgent:    llmstxtUserAgent,
 				Verbose:      verbose,
 				VeryVerbose:  veryVerbose,
 				MaxPages:     maxPages,
+				Template:     templatePath,
+				Lang:         llmstxtLang,
+				Headers:      headers,
+				BasicAuth:    llmstxtBasicAuth,
+				CookieFile:   llmstxtCookieFile,
+
+				MinSuccessRate: llmstxtMinSuccessRate,
 			}
 
 			generator := llmstxt.NewGenerator(config)
 
 			// Execute generation
-			content, err := generator.Generate()
+			content, err := generator.Generate(cmd.Context())
+			if reportErr := writeLLMSTxtReport(sitemapURL, generator.Stats()); reportErr != nil && err == nil {
+				err = reportErr
+			}
+			if errors.Is(err, llmstxt.ErrLowSuccessRate) {
+				// Nothing is written, the failures show what went missing
+				if jsonOutput {
+					printJSON(struct {
+						Stats llmstxt.Stats `json:"stats"`
+						Error string        `json:"error"`
+					}{Stats: generator.Stats(), Error: err.Error()})
+					exitWith(1)
+				}
+				return fmt.Errorf("%v, no output written", err)
+			}
 			if err != nil {
-				return err
+				if !interrupted(cmd) {
+					return err
+				}
+
+				// Nothing is written, but report how far the run got
+				stats := generator.Stats()
+				if jsonOutput {
+					printJSON(struct {
+						Stats llmstxt.Stats `json:"stats"`
+						Error string        `json:"error"`
+					}{Stats: stats, Error: "generation interrupted"})
+					exitWith(1)
+				}
+				fmt.Fprintf(os.Stderr, "Fetched %d of %d pages before interruption, no output written\n",
+					stats.URLsFetched, stats.URLsFetched+stats.URLsFailed)
+				return fmt.Errorf("generation interrupted")
+			}
+
+			if jsonOutput {
+				// Write the file as usual and describe the run on stdout
+				result := struct {
+					Output  string        `json:"output,omitempty"`
+					Content string        `json:"content,omitempty"`
+					Stats   llmstxt.Stats `json:"stats"`
+				}{Output: outputPath, Stats: generator.Stats()}
+				if outputPath == "" {
+					result.Content = content
+				} else if err := writeLLMSTxt(content); err != nil {
+					return err
+				}
+				return printJSON(result)
 			}
 
 			// Output content
@@ -65,7 +187,7 @@ Examples:
 				fmt.Println(content)
 			} else {
 				// Output to file
-				return os.WriteFile(outputPath, []byte(content), 0644)
+				return writeLLMSTxt(content)
 			}
 
 			return nil
@@ -73,6 +195,45 @@ Examples:
 	}
 )
 
+// writeLLMSTxt writes the generated content to --output
+func writeLLMSTxt(content string) error {
+	if dryRun {
+		fmt.Printf("Would write %s (%d bytes)\n", outputPath, len(content))
+		return nil
+	}
+	return os.WriteFile(outputPath, []byte(content), 0644)
+}
+
+// writeLLMSTxtReport writes the pages that failed to --error-report, a JSON
+// file that is also written when all pages were fetched
+func writeLLMSTxtReport(sitemapURL string, stats llmstxt.Stats) error {
+	if llmstxtErrorReport == "" {
+		return nil
+	}
+	if dryRun {
+		fmt.Printf("Would write error report %s\n", llmstxtErrorReport)
+		return nil
+	}
+	failures := stats.Failures
+	if failures == nil {
+		failures = []llmstxt.FailedURL{}
+	}
+	data, err := json.MarshalIndent(struct {
+		URL         string              `json:"url"`
+		Fetched     int                 `json:"fetched"`
+		Failed      int                 `json:"failed"`
+		SuccessRate float64             `json:"success_rate"`
+		Failures    []llmstxt.FailedURL `json:"failures"`
+	}{sitemapURL, stats.URLsFetched, stats.URLsFailed, stats.SuccessRate(), failures}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(llmstxtErrorReport, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write error report: %w", err)
+	}
+	return nil
+}
+
 func init() {
 	llmstxtCmd.Flags().StringVarP(&outputPath, "output", "o", "", "Output file path (default: stdout)")
 	llmstxtCmd.Flags().StringSliceVarP(&includePaths, "include-path", "i", []string{}, "Glob patterns for paths to include (can be specified multiple times)")
@@ -81,6 +242,13 @@ func init() {
 	llmstxtCmd.Flags().IntVarP(&concurrency, "concurrency", "c", 5, "Number of concurrent requests")
 	llmstxtCmd.Flags().IntVar(&timeout, "timeout", 30, "Request timeout in seconds")
 	llmstxtCmd.Flags().IntVar(&maxPages, "max-pages", 0, "Maximum number of pages to process (0 for unlimited)")
+	llmstxtCmd.Flags().StringVar(&llmstxtLang, "lang", "", "Only include pages in this language, e.g. en or zh-CN")
+	llmstxtCmd.Flags().StringVar(&templatePath, "template", "", "Go text/template file controlling the output format")
+	llmstxtCmd.Flags().StringArrayVarP(&llmstxtHeaders, "header", "H", nil, "HTTP header sent with every request, e.g. 'Authorization: Bearer TOKEN' (can be specified multiple times)")
+	llmstxtCmd.Flags().StringVar(&llmstxtBasicAuth, "basic-auth", "", "Basic auth credentials as user:password")
+	llmstxtCmd.Flags().StringVar(&llmstxtCookieFile, "cookie-file", "", "Netscape cookies.txt file with the session cookies of the site")
+	llmstxtCmd.Flags().Float64Var(&llmstxtMinSuccessRate, "min-success-rate", 0, "Fail without output when fewer than this share of the pages (0 to 1) could be fetched, e.g. 0.95")
+	llmstxtCmd.Flags().StringVar(&llmstxtErrorReport, "error-report", "", "JSON file listing the pages that failed to fetch or parse")
 
 	// Add command to core group
 	llmstxtCmd.GroupID = "core"
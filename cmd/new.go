@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/scaffold"
+	"github.com/spf13/cobra"
+)
+
+var (
+	newTemplate   string
+	newDir        string
+	newAuthor     string
+	newDatePrefix bool
+	newForce      bool
+	newList       bool
+
+	newCmd = &cobra.Command{
+		Use:   "new <kind> <title>",
+		Short: "Create a markdown document from a template",
+		Long: `Create a markdown document named after its title from a template, with the
+front matter filled in: title, creation date and the author of the
+configuration (mdctl config set --key author --value "Jane Doe").
+
+The template is --template, or the template named like the kind, or the
+default template. Templates are looked up in the templates directory of the
+nearest project configuration (.mdctl.yaml, templates_dir, default
+.mdctl/templates next to it) and its parents, then in
+~/.config/mdctl/new-templates and finally among the built-in templates
+default, blog and doc. A template <name>.md is a Go template with the fields
+{{.Title}}, {{.Slug}}, {{.Kind}}, {{.Author}}, {{.Date}} and {{.DateTime}};
+{{quote .Title}} writes a value as a YAML string.
+
+The document is written to --dir, or the directory new_dirs sets for the kind
+in the project configuration, or the current directory:
+
+  templates_dir: .mdctl/templates
+  new_dirs:
+    post: content/posts
+
+Examples:
+  # content/posts/my-title.md from the blog template
+  mdctl new post "My Title" --template blog
+
+  # Jekyll style file name with the date: _posts/2024-05-01-release-notes.md
+  mdctl new post "Release Notes" --dir _posts --date-prefix
+
+  # Show the available templates
+  mdctl new --list`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if newList {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(2)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			finder, err := scaffold.NewFinder(".")
+			if err != nil {
+				return err
+			}
+
+			if newList {
+				templates, err := finder.List()
+				if err != nil {
+					return err
+				}
+				if jsonOutput {
+					return printJSON(templates)
+				}
+				for _, t := range templates {
+					fmt.Printf("%-16s %s\n", t.Name, t.Source)
+				}
+				return nil
+			}
+
+			kind, title := args[0], args[1]
+			author := newAuthor
+			if !cmd.Flags().Changed("author") {
+				cfg, err := config.LoadConfig()
+				if err != nil {
+					return fmt.Errorf("failed to load config: %v", err)
+				}
+				author = cfg.Author
+			}
+			data := scaffold.NewData(kind, title, author, time.Now())
+			if data.Slug == "" {
+				return fmt.Errorf("title %q has no letters or digits to name the file after", title)
+			}
+
+			name := finder.TemplateFor(kind, newTemplate)
+			text, source, err := finder.Find(name)
+			if err != nil {
+				return err
+			}
+			content, err := scaffold.Render(text, data)
+			if err != nil {
+				return fmt.Errorf("template %s: %v", source, err)
+			}
+
+			dir := newDir
+			if dir == "" {
+				dir = finder.Dir(kind, ".")
+			}
+			fileName := data.Slug + ".md"
+			if newDatePrefix {
+				fileName = data.Date + "-" + fileName
+			}
+			path := filepath.Join(dir, fileName)
+			if _, err := os.Stat(path); err == nil && !newForce {
+				return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+			}
+
+			if !dryRun {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					return fmt.Errorf("failed to create directory %s: %v", dir, err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					return fmt.Errorf("failed to write %s: %v", path, err)
+				}
+			}
+
+			if jsonOutput {
+				return printJSON(struct {
+					Path     string `json:"path"`
+					Template string `json:"template"`
+					Source   string `json:"source"`
+					Created  bool   `json:"created"`
+				}{path, name, source, !dryRun})
+			}
+			if dryRun {
+				fmt.Printf("Would create %s from template %s:\n\n%s", path, name, content)
+				return nil
+			}
+			fmt.Printf("Created %s from template %s\n", path, name)
+			return nil
+		},
+	}
+)
+
+func init() {
+	newCmd.Flags().StringVar(&newTemplate, "template", "", "Template name (default: the kind, or default)")
+	newCmd.Flags().StringVarP(&newDir, "dir", "d", "", "Directory to create the document in (default: new_dirs of the project configuration, or the current directory)")
+	newCmd.Flags().StringVar(&newAuthor, "author", "", "Author (default: author of the configuration)")
+	newCmd.Flags().BoolVar(&newDatePrefix, "date-prefix", false, "Start the file name with the date, e.g. 2024-05-01-my-title.md")
+	newCmd.Flags().BoolVarP(&newForce, "force", "F", false, "Overwrite an existing file")
+	newCmd.Flags().BoolVar(&newList, "list", false, "List the available templates")
+
+	newCmd.GroupID = "core"
+	rootCmd.AddCommand(newCmd)
+}
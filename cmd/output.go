@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	jsonOutput bool
+
+	// resultWriter receives structured results, it stays bound to the real
+	// stdout even when informational output is redirected in --json mode
+	resultWriter io.Writer = os.Stdout
+)
+
+// errorResult is the structured form of a command failure
+type errorResult struct {
+	Error string `json:"error"`
+}
+
+// setupOutput prepares stdout/stderr for the selected output mode
+func setupOutput(cmd *cobra.Command, args []string) {
+	if !jsonOutput {
+		return
+	}
+
+	// Keep stdout reserved for the JSON document, progress messages that
+	// packages print with fmt.Printf end up on stderr instead
+	resultWriter = os.Stdout
+	os.Stdout = os.Stderr
+
+	// Errors are reported as JSON by Execute
+	cmd.Root().SilenceErrors = true
+	cmd.Root().SilenceUsage = true
+}
+
+// printJSON writes v as an indented JSON document to the result writer
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(resultWriter)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
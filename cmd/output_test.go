@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestJSONOutput(t *testing.T) {
+	defer func() {
+		jsonOutput, dryRun, autoFix = false, false, false
+		rootCmd.SilenceErrors, rootCmd.SilenceUsage = false, false
+	}()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"index.md": "#Index\n\nSee [guide](guide.md) and [missing](missing.md).\n",
+		"guide.md": "# Guide\n\nText   \n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		keys []string // Keys of the result object
+	}{
+		{"fmt", []string{"fmt", "--json", "--dry-run", dir}, []string{"changed", "dry_run", "files"}},
+		{"lint", []string{"lint", "--json", "--fix", "--dry-run", filepath.Join(dir, "guide.md")}, []string{"files", "summary"}},
+		{"links graph", []string{"links", "graph", "--json", dir}, []string{"edges", "nodes", "root", "unresolved"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := runStream(t, "", tt.args...)
+			// Progress messages go to stderr, stdout holds a single document
+			var result map[string]json.RawMessage
+			decoder := json.NewDecoder(bytes.NewReader([]byte(out)))
+			if err := decoder.Decode(&result); err != nil {
+				t.Fatalf("stdout is not a JSON object: %v\n%s", err, out)
+			}
+			if decoder.More() {
+				t.Fatalf("expected a single JSON document on stdout:\n%s", out)
+			}
+			var keys []string
+			for key := range result {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.keys) {
+				t.Errorf("got keys %v, want %v", keys, tt.keys)
+			}
+		})
+	}
+}
+
+func TestPrintJSON(t *testing.T) {
+	defer func(w io.Writer) { resultWriter = w }(resultWriter)
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"error", errorResult{Error: "failed to read <file> & more"}, "{\n  \"error\": \"failed to read <file> & more\"\n}\n"},
+		{"empty list", []string{}, "[]\n"},
+		{"nil list", []string(nil), "null\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			resultWriter = &buf
+			if err := printJSON(tt.value); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("printJSON wrote %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/samzong/mdctl/internal/relink"
+	"github.com/spf13/cobra"
+)
+
+var (
+	relinkMap   string
+	relinkGit   bool
+	relinkSince string
+
+	relinkCmd = &cobra.Command{
+		Use:   "relink [path]",
+		Short: "Fix relative links and image paths after moving files",
+		Long: `Rewrite the relative links and images of the markdown files below a directory
+after files or directories were moved within it. Links pointing at a moved
+file get its new path, and the links of a moved file are adjusted to its new
+directory. Relative links whose target does not exist are reported and left
+as they are, the command then exits with a non-zero status.
+
+The moves come from a YAML map, paths relative to the directory, where a
+directory entry moves everything below it:
+
+  - from: guide/install.md
+    to: setup/install.md
+  - from: images
+    to: assets/img
+
+or from the renames git detects between --since (default HEAD) and the
+working tree. Stage the moves (git mv) so git can detect them.
+
+Examples:
+  # Apply a rename map
+  mdctl relink docs/ --map moves.yaml
+
+  # Fix links after git mv, showing the changes first
+  mdctl relink docs/ --git --dry-run
+
+  # Fix links for everything moved since a release
+  mdctl relink docs/ --git --since v1.2.0`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := indexRoot(args)
+
+			var moves []relink.Move
+			var err error
+			if relinkGit {
+				moves, err = relink.GitMoves(root, relinkSince)
+			} else {
+				moves, err = relink.LoadMoves(relinkMap)
+			}
+			if err != nil {
+				return err
+			}
+
+			result, err := relink.Relink(root, moves, dryRun)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				if err := printJSON(result); err != nil {
+					return err
+				}
+			} else {
+				for _, c := range result.Changes {
+					fmt.Printf("%s:%d: %s -> %s\n", c.File, c.Line, c.Old, c.New)
+				}
+				for _, u := range result.Unresolved {
+					fmt.Printf("%s:%d: unresolved link: %s\n", u.File, u.Line, u.Target)
+				}
+				verb := "Updated"
+				if dryRun {
+					verb = "Would update"
+				}
+				fmt.Printf("%s %d links for %d moves\n", verb, len(result.Changes), len(moves))
+			}
+
+			if len(result.Unresolved) > 0 {
+				exitWith(1)
+			}
+			return nil
+		},
+	}
+)
+
+func init() {
+	relinkCmd.Flags().StringVar(&relinkMap, "map", "", "YAML file mapping old paths to new ones")
+	relinkCmd.Flags().BoolVar(&relinkGit, "git", false, "Detect the moves from git renames")
+	relinkCmd.Flags().StringVar(&relinkSince, "since", "HEAD", "Git ref the renames are detected from, with --git")
+	relinkCmd.MarkFlagsOneRequired("map", "git")
+	relinkCmd.MarkFlagsMutuallyExclusive("map", "git")
+
+	relinkCmd.GroupID = "core"
+	rootCmd.AddCommand(relinkCmd)
+}
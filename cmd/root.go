@@ -1,9 +1,19 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/metrics"
+	"github.com/samzong/mdctl/internal/netutil"
 	"github.com/spf13/cobra"
 )
 
@@ -12,6 +22,15 @@ var (
 	BuildTime   = "unknown"
 	verbose     bool
 	veryVerbose bool
+	logLevel    string
+	logFormat   string
+	logFile     string
+	dryRun      bool
+	configFile  string
+
+	// The run recorded in the local metrics file
+	runCommand *cobra.Command
+	runStart   time.Time
 
 	rootCmd = &cobra.Command{
 		Use:   "mdctl",
@@ -23,12 +42,65 @@ Currently supports downloading remote images and more features to come.`,
 )
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The first Ctrl-C cancels in-flight work so commands can clean up and
+	// report partial results, a second one terminates immediately
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		signal.Stop(signals)
+		fmt.Fprintln(os.Stderr, "Interrupted, finishing in-flight work (press Ctrl-C again to abort)")
+		rootCmd.SilenceUsage = true
+		cancel()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	finishRun(err != nil)
+	logging.Close()
+	if err != nil {
+		if jsonOutput {
+			printJSON(errorResult{Error: err.Error()})
+		} else {
+			fmt.Println(err)
+		}
 		os.Exit(1)
 	}
 }
 
+// exitWith records the run in the metrics file and terminates the process
+func exitWith(code int) {
+	finishRun(code != 0)
+	os.Exit(code)
+}
+
+// startRun remembers the command and start time of the run, help,
+// completion and stats commands are not recorded
+func startRun(cmd *cobra.Command) {
+	if strings.HasPrefix(cmd.Name(), "__") || cmd.Name() == "completion" || cmd == statsCmd || cmd.Parent() == statsCmd {
+		return
+	}
+	runCommand = cmd
+	runStart = time.Now()
+}
+
+// finishRun appends the run to the metrics file when metrics are enabled
+func finishRun(failed bool) {
+	cmd := runCommand
+	runCommand = nil
+	if cmd == nil || config.ConfigDir() == "" || !config.ReadMetrics() {
+		return
+	}
+
+	name := strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" ")
+	run := metrics.Finish(name, Version, runStart, failed)
+	if err := metrics.Append(filepath.Join(config.ConfigDir(), metrics.FileName), run); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to record metrics: %v\n", err)
+	}
+}
+
 func init() {
 	// Add commands first
 	rootCmd.AddCommand(translateCmd)
@@ -38,10 +110,31 @@ func init() {
 	rootCmd.AddCommand(exportCmd)
 	rootCmd.AddCommand(llmstxtCmd)
 	rootCmd.AddCommand(lintCmd)
+	rootCmd.AddCommand(indexCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(runCmd)
 
 	// Add global flags
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 	rootCmd.PersistentFlags().BoolVar(&veryVerbose, "vv", false, "Enable very verbose output with detailed information")
+	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Emit machine-readable JSON results on stdout")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Show what would be done without changing any files")
+
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Configuration file to use instead of ~/.config/mdctl/config.json (env: MDCTL_CONFIG)")
+
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format (text, json)")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Write logs to this file instead of stderr")
+
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		setupOutput(cmd, args)
+		config.SetConfigPath(configFile)
+		startRun(cmd)
+		if err := netutil.SetProxy(config.ReadProxy()); err != nil {
+			return err
+		}
+		return setupLogging(cmd)
+	}
 
 	// Then add groups and set group IDs
 	rootCmd.AddGroup(&cobra.Group{
@@ -60,5 +153,32 @@ func init() {
 	exportCmd.GroupID = "core"
 	llmstxtCmd.GroupID = "core"
 	lintCmd.GroupID = "core"
+	indexCmd.GroupID = "core"
+	serveCmd.GroupID = "core"
+	runCmd.GroupID = "core"
 	configCmd.GroupID = "config"
 }
+
+// setupLogging configures the shared logger from the global flags
+func setupLogging(cmd *cobra.Command) error {
+	level, err := logging.ParseLevel(logLevel)
+	if err != nil {
+		return err
+	}
+
+	// -v and --vv enable debug output unless a level was chosen explicitly
+	if (verbose || veryVerbose) && !cmd.Flags().Changed("log-level") {
+		level = logging.LevelDebug
+	}
+
+	return logging.Configure(logging.Options{
+		Level:  level,
+		Format: logFormat,
+		File:   logFile,
+	})
+}
+
+// interrupted reports whether the command was cancelled by a signal
+func interrupted(cmd *cobra.Command) bool {
+	return cmd.Context() != nil && cmd.Context().Err() != nil
+}
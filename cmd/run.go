@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/samzong/mdctl/internal/pipeline"
+	"github.com/spf13/cobra"
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run <pipeline.yaml>",
+	Short: "Run a pipeline of mdctl operations",
+	Long: `Run a declarative pipeline that chains mdctl operations (download, lint,
+translate, upload, export) over one directory tree. The tree is walked once,
+every step works on the same file list and an existing index (mdctl index build)
+is shared, and a single summary is printed at the end.
+
+Example pipeline:
+
+  name: docs
+  root: docs
+  include: ["**.md"]
+  exclude: ["drafts/**"]
+  steps:
+    - run: download
+      with: {output: images}
+    - run: lint
+      with: {fix: true}
+    - name: chinese
+      run: translate
+      include: ["guide/**"]
+      when: {env: OPENAI_API_KEY}
+      with: {locale: zh, to: ../docs_zh}
+    - run: upload
+      when: {changed: true}
+      with: {storage: my-s3}
+    - run: export
+      continue_on_error: true
+      with: {output: docs.pdf, format: pdf, toc: true}
+
+Paths are relative to the pipeline file, globs are relative to root.
+A failing step stops the pipeline unless it sets continue_on_error.`,
+	Example: `  mdctl run pipeline.yaml
+  mdctl run pipeline.yaml --dry-run
+  mdctl --json run pipeline.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := pipeline.Load(args[0])
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+
+		summary, err := pipeline.Run(cmd.Context(), p, pipeline.Options{DryRun: dryRun})
+		if summary == nil {
+			return err
+		}
+		if interrupted(cmd) {
+			err = fmt.Errorf("pipeline interrupted")
+		}
+
+		if jsonOutput {
+			result := struct {
+				*pipeline.Summary
+				Error string `json:"error,omitempty"`
+			}{Summary: summary}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			if printErr := printJSON(result); printErr != nil {
+				return printErr
+			}
+			if err != nil {
+				exitWith(1)
+			}
+			return nil
+		}
+
+		printPipelineSummary(summary)
+		return err
+	},
+}
+
+// printPipelineSummary prints one line per step
+func printPipelineSummary(summary *pipeline.Summary) {
+	title := "Pipeline"
+	if summary.Pipeline != "" {
+		title = fmt.Sprintf("Pipeline %s", summary.Pipeline)
+	}
+	fmt.Printf("\n%s (%d files in %s):\n", title, summary.Files, summary.Root)
+
+	for _, step := range summary.Steps {
+		line := fmt.Sprintf("  %-8s %-20s", step.Status, step.Name)
+		if step.Status != pipeline.StatusSkipped {
+			line += fmt.Sprintf(" %4d files  %6s", step.Files, (time.Duration(step.DurationMs) * time.Millisecond).String())
+		}
+		if step.Summary != "" {
+			line += "  " + step.Summary
+		}
+		if step.Error != "" {
+			line += "  error: " + step.Error
+		}
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,525 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/api"
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/exporter"
+	"github.com/samzong/mdctl/internal/exporter/sitereader"
+	"github.com/samzong/mdctl/internal/linter"
+	"github.com/samzong/mdctl/internal/llmstxt"
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/mcp"
+	"github.com/samzong/mdctl/internal/preview"
+	"github.com/samzong/mdctl/internal/translator"
+	"github.com/samzong/mdctl/internal/uploader"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveLogger = logging.New("SERVE")
+
+	serveHost        string
+	servePort        int
+	serveAPIKeys     []string
+	serveMaxBodySize int64
+
+	previewHost     string
+	previewPort     int
+	previewSiteType string
+
+	serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Run mdctl as a long-running server",
+	}
+
+	serveMCPCmd = &cobra.Command{
+		Use:   "mcp",
+		Short: "Serve mdctl tools over the Model Context Protocol (stdio)",
+		Long: `Run a Model Context Protocol server on stdin/stdout so AI agents such as
+Claude Desktop or IDE assistants can drive markdown workflows directly.
+
+Exposed tools:
+  lint_content       Lint markdown content, optionally returning the fixed content
+  translate_file     Translate a markdown file or string catalog
+  generate_llmstxt   Generate llms.txt from a sitemap
+  export_document    Export a markdown file or directory with Pandoc
+  upload_images      Upload local images of a file or directory to cloud storage
+
+Logs are written to stderr, stdout carries only protocol messages.
+
+Example client configuration:
+  {
+    "mcpServers": {
+      "mdctl": {"command": "mdctl", "args": ["serve", "mcp"]}
+    }
+  }`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Packages print progress with fmt.Printf, keep it off the protocol
+			// stream. resultWriter is the real stdout, also in --json mode.
+			stdout := os.Stdout
+			os.Stdout = os.Stderr
+			defer func() { os.Stdout = stdout }()
+
+			server := mcp.NewServer("mdctl", Version, mcpTools())
+			err := server.Serve(cmd.Context(), os.Stdin, resultWriter)
+			if interrupted(cmd) {
+				return nil
+			}
+			return err
+		},
+	}
+)
+
+var serveHTTPCmd = &cobra.Command{
+	Use:   "http",
+	Short: "Serve lint, format, translate and llms.txt generation as a REST API",
+	Long: `Run mdctl as an HTTP service, e.g. behind a docs-bot webhook, instead of
+shelling out to the CLI for every request.
+
+Endpoints (POST, JSON bodies):
+  /v1/lint       {"content": "...", "fix": true}
+  /v1/format     {"content": "..."}
+  /v1/translate  {"content": "...", "language": "zh"}
+  /v1/llmstxt    {"sitemap_url": "https://example.com/sitemap.xml", "full": false}
+
+GET /healthz reports liveness without authentication.
+
+Requests must carry one of the configured API keys as "Authorization: Bearer <key>"
+or "X-API-Key: <key>". Keys are taken from --api-key or the comma-separated
+MDCTL_API_KEYS environment variable.`,
+	Example: `  mdctl serve http --port 8080 --api-key "$DOCS_BOT_KEY"
+  curl -H "Authorization: Bearer $DOCS_BOT_KEY" -d '{"content":"#Title"}' localhost:8080/v1/lint`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keys := serveAPIKeys
+		if env := os.Getenv("MDCTL_API_KEYS"); env != "" {
+			for _, key := range strings.Split(env, ",") {
+				if key = strings.TrimSpace(key); key != "" {
+					keys = append(keys, key)
+				}
+			}
+		}
+		if len(keys) == 0 {
+			serveLogger.Warnf("No API key configured, the API is open to anyone who can reach %s", serveHost)
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %v", err)
+		}
+
+		server := api.New(api.Options{
+			APIKeys:     keys,
+			MaxBodySize: serveMaxBodySize,
+			Config:      cfg,
+			UserAgent:   llmstxtUserAgent,
+		})
+
+		addr := net.JoinHostPort(serveHost, strconv.Itoa(servePort))
+		serveLogger.Infof("Serving API on http://%s", addr)
+		if err := server.ListenAndServe(cmd.Context(), addr); err != nil && err != http.ErrServerClosed {
+			return fmt.Errorf("API server failed: %v", err)
+		}
+		return nil
+	},
+}
+
+var servePreviewCmd = &cobra.Command{
+	Use:   "preview [dir]",
+	Short: "Serve a live-reloading HTML preview of a markdown directory",
+	Long: `Render the markdown files of a directory (default: the current directory) as
+HTML pages on localhost, to check the results of fmt, translate or link
+rewriting without building the whole site. Images and other files are served
+as they are, a directory shows its index.md or README.md, or a list of its
+files. Open pages reload when a file of the directory changes.
+
+With -s mkdocs the directory is the MkDocs project: its docs_dir is served
+and the navigation of mkdocs.yml is shown in a sidebar.
+
+Rendering is a goldmark approximation of the site (GitHub-flavored markdown
+with footnotes and heading attributes), themes and plugins are not applied.`,
+	Example: `  mdctl serve preview docs/
+  mdctl serve preview -s mkdocs --port 8001`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path: %v", err)
+		}
+
+		opts := preview.Options{Dir: dir}
+		switch previewSiteType {
+		case "basic":
+		case "mkdocs":
+			configPath, err := sitereader.FindConfigFile(dir, []string{"mkdocs.yml", "mkdocs.yaml"})
+			if err != nil {
+				return err
+			}
+			reader := &sitereader.MkDocsReader{Logger: serveLogger}
+			docsDir, _, err := reader.ReadURLSettings(dir, configPath)
+			if err != nil {
+				return err
+			}
+			opts.Dir = docsDir
+			opts.Files = []string{configPath}
+			opts.Nav = func() ([]sitereader.NavEntry, error) {
+				return reader.ReadNavigation(dir, configPath)
+			}
+		default:
+			return fmt.Errorf("unsupported site type: %s (must be basic or mkdocs)", previewSiteType)
+		}
+
+		server, err := preview.New(opts)
+		if err != nil {
+			return err
+		}
+		addr := net.JoinHostPort(previewHost, strconv.Itoa(previewPort))
+		serveLogger.Infof("Serving preview of %s on http://%s", opts.Dir, addr)
+		if err := server.ListenAndServe(cmd.Context(), addr); err != nil && err != http.ErrServerClosed {
+			return fmt.Errorf("preview server failed: %v", err)
+		}
+		return nil
+	},
+}
+
+// mcpTools returns the tools exposed by the MCP server
+func mcpTools() []mcp.Tool {
+	return []mcp.Tool{
+		{
+			Name:        "lint_content",
+			Description: "Lint markdown content against markdownlint rules. With fix, the fixed content is returned as well.",
+			InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "content": {"type": "string", "description": "Markdown content to lint"},
+    "filename": {"type": "string", "description": "Name used in results, defaults to content.md"},
+    "fix": {"type": "boolean", "description": "Return the content with fixable issues fixed"},
+    "enable_rules": {"type": "array", "items": {"type": "string"}, "description": "Only run these rules, e.g. MD001"},
+    "disable_rules": {"type": "array", "items": {"type": "string"}, "description": "Rules to skip"}
+  },
+  "required": ["content"]
+}`),
+			Handler: mcpLintContent,
+		},
+		{
+			Name:        "translate_file",
+			Description: "Translate a markdown file or YAML/TOML/JSON string catalog with the configured AI model.",
+			InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "source": {"type": "string", "description": "Source file path"},
+    "target": {"type": "string", "description": "Target file path, defaults to name_<language>.md next to the source"},
+    "language": {"type": "string", "description": "Target language code, e.g. zh, ja, de"},
+    "force": {"type": "boolean", "description": "Translate even if the target is already translated"},
+    "format": {"type": "boolean", "description": "Format markdown after translation"}
+  },
+  "required": ["source", "language"]
+}`),
+			Handler: mcpTranslateFile,
+		},
+		{
+			Name:        "generate_llmstxt",
+			Description: "Generate llms.txt content from a website sitemap.xml.",
+			InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "sitemap_url": {"type": "string", "description": "URL of the sitemap.xml, or a site URL whose sitemap is discovered"},
+    "full": {"type": "boolean", "description": "Include the content of every page"},
+    "include_paths": {"type": "array", "items": {"type": "string"}, "description": "Glob patterns of paths to include"},
+    "exclude_paths": {"type": "array", "items": {"type": "string"}, "description": "Glob patterns of paths to exclude"},
+    "max_pages": {"type": "integer", "description": "Maximum number of pages, 0 for unlimited"},
+    "lang": {"type": "string", "description": "Only include pages in this language, e.g. en"}
+  },
+  "required": ["sitemap_url"]
+}`),
+			Handler: mcpGenerateLLMsTxt,
+		},
+		{
+			Name:        "export_document",
+			Description: "Export a markdown file or directory to DOCX, PDF or EPUB with Pandoc.",
+			InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "source": {"type": "string", "description": "Markdown file or directory"},
+    "output": {"type": "string", "description": "Output file path"},
+    "format": {"type": "string", "enum": ["docx", "pdf", "epub"], "description": "Output format, defaults to docx"},
+    "site_type": {"type": "string", "description": "Site type for directories (basic, mkdocs)"},
+    "toc": {"type": "boolean", "description": "Generate a table of contents"},
+    "template": {"type": "string", "description": "Word template file path"}
+  },
+  "required": ["source", "output"]
+}`),
+			Handler: mcpExportDocument,
+		},
+		{
+			Name:        "upload_images",
+			Description: "Upload local images referenced by a markdown file or directory to cloud storage and rewrite their links.",
+			InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "source": {"type": "string", "description": "Markdown file or directory"},
+    "storage": {"type": "string", "description": "Configured storage name, defaults to the default storage"},
+    "dry_run": {"type": "boolean", "description": "Only report what would be uploaded"}
+  },
+  "required": ["source"]
+}`),
+			Handler: mcpUploadImages,
+		},
+	}
+}
+
+// mcpLintContent implements the lint_content tool
+func mcpLintContent(ctx context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		Content      string   `json:"content"`
+		Filename     string   `json:"filename"`
+		Fix          bool     `json:"fix"`
+		EnableRules  []string `json:"enable_rules"`
+		DisableRules []string `json:"disable_rules"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+	if in.Filename == "" {
+		in.Filename = "content.md"
+	}
+
+	l := linter.New(&linter.Config{
+		EnableRules:  in.EnableRules,
+		DisableRules: in.DisableRules,
+	})
+
+	if !in.Fix {
+		result, err := l.LintContent(in.Filename, in.Content)
+		if err != nil {
+			return "", err
+		}
+		return toolJSON(result)
+	}
+
+	result, fixed := l.FixContent(in.Filename, in.Content)
+	return toolJSON(struct {
+		*linter.Result
+		FixedContent string `json:"fixed_content"`
+	}{Result: result, FixedContent: fixed})
+}
+
+// mcpTranslateFile implements the translate_file tool
+func mcpTranslateFile(ctx context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		Source   string `json:"source"`
+		Target   string `json:"target"`
+		Language string `json:"language"`
+		Force    bool   `json:"force"`
+		Format   bool   `json:"format"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+	if in.Source == "" {
+		return "", fmt.Errorf("source is required")
+	}
+	if !translator.IsLanguageSupported(in.Language) {
+		return "", fmt.Errorf("unsupported language: %s (supported: %s)", in.Language, translator.GetSupportedLanguages())
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %v", err)
+	}
+
+	src, err := filepath.Abs(in.Source)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path: %v", err)
+	}
+	dst := generateTargetPath(src, in.Language)
+	if in.Target != "" {
+		if dst, err = filepath.Abs(in.Target); err != nil {
+			return "", fmt.Errorf("failed to get absolute path: %v", err)
+		}
+	}
+
+	report := &translator.Report{}
+	opts := translator.Options{Format: in.Format, Force: in.Force, Report: report}
+	if err := translator.ProcessFile(ctx, src, dst, in.Language, cfg, opts); err != nil {
+		return "", err
+	}
+	return toolJSON(report)
+}
+
+// mcpGenerateLLMsTxt implements the generate_llmstxt tool
+func mcpGenerateLLMsTxt(ctx context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		SitemapURL   string   `json:"sitemap_url"`
+		Full         bool     `json:"full"`
+		IncludePaths []string `json:"include_paths"`
+		ExcludePaths []string `json:"exclude_paths"`
+		MaxPages     int      `json:"max_pages"`
+		Lang         string   `json:"lang"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+	if in.SitemapURL == "" {
+		return "", fmt.Errorf("sitemap_url is required")
+	}
+
+	generator := llmstxt.NewGenerator(llmstxt.GeneratorConfig{
+		SitemapURL:   in.SitemapURL,
+		IncludePaths: in.IncludePaths,
+		ExcludePaths: in.ExcludePaths,
+		FullMode:     in.Full,
+		Concurrency:  5,
+		Timeout:      30,
+		UserAgent:    llmstxtUserAgent,
+		MaxPages:     in.MaxPages,
+		Lang:         in.Lang,
+	})
+	return generator.Generate(ctx)
+}
+
+// mcpExportDocument implements the export_document tool
+func mcpExportDocument(ctx context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		Source   string `json:"source"`
+		Output   string `json:"output"`
+		Format   string `json:"format"`
+		SiteType string `json:"site_type"`
+		Toc      bool   `json:"toc"`
+		Template string `json:"template"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+	if in.Source == "" || in.Output == "" {
+		return "", fmt.Errorf("source and output are required")
+	}
+	if in.Format == "" {
+		in.Format = "docx"
+	}
+	if err := exporter.CheckPandocAvailability(); err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(in.Source)
+	if err != nil {
+		return "", fmt.Errorf("failed to access source: %v", err)
+	}
+
+	options := exporter.ExportOptions{
+		Template:    in.Template,
+		GenerateToc: in.Toc,
+		Format:      in.Format,
+		SiteType:    in.SiteType,
+		TocDepth:    3,
+	}
+	exp := exporter.NewExporter()
+	if info.IsDir() {
+		err = exp.ExportDirectory(ctx, in.Source, in.Output, options)
+	} else {
+		err = exp.ExportFile(ctx, in.Source, in.Output, options)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Exported %s to %s", in.Source, in.Output), nil
+}
+
+// mcpUploadImages implements the upload_images tool
+func mcpUploadImages(ctx context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		Source  string `json:"source"`
+		Storage string `json:"storage"`
+		DryRun  bool   `json:"dry_run"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+
+	info, err := os.Stat(in.Source)
+	if err != nil {
+		return "", fmt.Errorf("failed to access source: %v", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %v", err)
+	}
+	upConfig := mcpUploaderConfig(cfg, in.Storage, in.DryRun)
+	if info.IsDir() {
+		upConfig.SourceDir = in.Source
+	} else {
+		upConfig.SourceFile = in.Source
+	}
+
+	up, err := uploader.New(upConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to create uploader: %v", err)
+	}
+	stats, err := up.Process(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to process files: %v", err)
+	}
+	return toolJSON(stats)
+}
+
+// mcpUploaderConfig returns the uploader configuration of the named storage,
+// the default one when empty. The storage is passed on as a whole, so its
+// credentials, endpoint and region are used and not those of the default.
+func mcpUploaderConfig(cfg *config.Config, storage string, dryRun bool) uploader.UploaderConfig {
+	cloudConfig := cfg.GetActiveCloudConfig(storage)
+	return uploader.UploaderConfig{
+		Provider:       cloudConfig.Provider,
+		Bucket:         cloudConfig.Bucket,
+		CustomDomain:   cloudConfig.CustomDomain,
+		PathPrefix:     cloudConfig.PathPrefix,
+		DryRun:         dryRun,
+		Concurrency:    cloudConfig.Concurrency,
+		SkipVerify:     cloudConfig.SkipVerify,
+		CACertPath:     cloudConfig.CACertPath,
+		ConflictPolicy: uploader.ConflictPolicy(cloudConfig.ConflictPolicy),
+		CacheDir:       cloudConfig.CacheDir,
+		Storage:        &cloudConfig,
+	}
+}
+
+// toolJSON formats a tool result as indented JSON text
+func toolJSON(v interface{}) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func init() {
+	serveCmd.AddCommand(serveMCPCmd)
+	serveCmd.AddCommand(serveHTTPCmd)
+	serveCmd.AddCommand(servePreviewCmd)
+
+	serveHTTPCmd.Flags().StringVar(&serveHost, "host", "127.0.0.1", "Address to listen on")
+	serveHTTPCmd.Flags().IntVar(&servePort, "port", 8080, "Port to listen on")
+	serveHTTPCmd.Flags().StringSliceVar(&serveAPIKeys, "api-key", nil, "Accepted API key (can be specified multiple times)")
+	serveHTTPCmd.Flags().Int64Var(&serveMaxBodySize, "max-body-size", api.DefaultMaxBodySize, "Maximum request body size in bytes")
+
+	servePreviewCmd.Flags().StringVar(&previewHost, "host", "127.0.0.1", "Address to listen on")
+	servePreviewCmd.Flags().IntVar(&previewPort, "port", 8000, "Port to listen on")
+	servePreviewCmd.Flags().StringVarP(&previewSiteType, "site-type", "s", "basic", "Site type (basic, mkdocs), mkdocs serves docs_dir with the navigation in a sidebar")
+	registerCompletion(servePreviewCmd, "site-type", cobra.FixedCompletions([]string{"basic", "mkdocs"}, cobra.ShellCompDirectiveNoFileComp))
+}
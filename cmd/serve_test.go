@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+func TestMCPUploaderConfigUsesNamedStorage(t *testing.T) {
+	cfg := &config.Config{
+		DefaultStorage: "main",
+		CloudStorages: map[string]config.CloudConfig{
+			"main":    {Provider: "s3", Bucket: "main-bucket", AccessKey: "main-key", SecretKey: "main-secret", Endpoint: "https://main.example.com", Region: "us-east-1"},
+			"archive": {Provider: "r2", Bucket: "archive-bucket", AccessKey: "archive-key", SecretKey: "archive-secret", Endpoint: "https://archive.example.com", Region: "auto"},
+		},
+	}
+
+	got := mcpUploaderConfig(cfg, "archive", true)
+	if got.Storage == nil {
+		t.Fatalf("expected the storage to be passed to the uploader")
+	}
+	if got.Bucket != "archive-bucket" || got.Storage.AccessKey != "archive-key" || got.Storage.SecretKey != "archive-secret" ||
+		got.Storage.Endpoint != "https://archive.example.com" || got.Storage.Region != "auto" || !got.DryRun {
+		t.Errorf("expected the archive storage, got %+v with storage %+v", got, *got.Storage)
+	}
+
+	if got := mcpUploaderConfig(cfg, "", false); got.Storage == nil || got.Storage.AccessKey != "main-key" {
+		t.Errorf("expected the default storage without a name, got %+v", got)
+	}
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/samzong/mdctl/internal/sitegen"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sitemapDir       string
+	sitemapBaseURL   string
+	sitemapOutput    string
+	sitemapInventory string
+	sitemapURLStyle  string
+	sitemapInclude   []string
+	sitemapExclude   []string
+
+	sitemapCmd = &cobra.Command{
+		Use:   "sitemap",
+		Short: "Generate sitemap.xml from a markdown tree",
+		Long: `Generate a sitemap.xml for the site built from a directory of markdown files.
+
+Every page becomes a URL below --base-url: guide/install.md is published at
+guide/install/ (or guide/install.html with --url-style html), index.md and
+README.md at their directory. The front matter can override the URL with url,
+permalink or slug. The lastmod date comes from lastmod, updated,
+last_modified or date. Drafts and pages with sitemap: false or noindex: true
+are left out.
+
+--inventory additionally writes the URLs, paths and titles as JSON.
+
+Examples:
+  mdctl sitemap -d docs/ --base-url https://docs.example.com
+  mdctl sitemap -d content/ --base-url https://example.com/blog -o public/sitemap.xml --inventory urls.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pages, err := sitegen.Collect(sitemapDir, sitegen.Options{
+				BaseURL:  sitemapBaseURL,
+				URLStyle: sitemapURLStyle,
+				Include:  sitemapInclude,
+				Exclude:  sitemapExclude,
+			})
+			if err != nil {
+				return err
+			}
+			entries := sitegen.SitemapEntries(pages)
+			sitemap, err := sitegen.Sitemap(entries)
+			if err != nil {
+				return fmt.Errorf("failed to render sitemap: %v", err)
+			}
+
+			if !dryRun {
+				if sitemapOutput == "-" {
+					os.Stdout.Write(sitemap)
+				} else if err := os.WriteFile(sitemapOutput, sitemap, 0644); err != nil {
+					return fmt.Errorf("failed to write sitemap: %v", err)
+				}
+				if sitemapInventory != "" {
+					inventory, err := json.MarshalIndent(entries, "", "  ")
+					if err != nil {
+						return err
+					}
+					if err := os.WriteFile(sitemapInventory, append(inventory, '\n'), 0644); err != nil {
+						return fmt.Errorf("failed to write inventory: %v", err)
+					}
+				}
+			}
+
+			if jsonOutput {
+				return printJSON(struct {
+					DryRun    bool                   `json:"dry_run"`
+					Output    string                 `json:"output"`
+					Inventory string                 `json:"inventory,omitempty"`
+					URLs      []sitegen.SitemapEntry `json:"urls"`
+				}{DryRun: dryRun, Output: sitemapOutput, Inventory: sitemapInventory, URLs: entries})
+			}
+			if sitemapOutput == "-" && !dryRun {
+				return nil
+			}
+			if dryRun {
+				for _, entry := range entries {
+					fmt.Printf("%s (%s)\n", entry.Loc, entry.Path)
+				}
+				fmt.Printf("Would write %d URLs to %s\n", len(entries), sitemapOutput)
+				return nil
+			}
+			fmt.Printf("Wrote %d URLs to %s\n", len(entries), sitemapOutput)
+			return nil
+		},
+	}
+)
+
+func init() {
+	sitemapCmd.Flags().StringVarP(&sitemapDir, "dir", "d", ".", "Directory of the markdown files")
+	sitemapCmd.Flags().StringVar(&sitemapBaseURL, "base-url", "", "URL the directory is published at (required)")
+	sitemapCmd.Flags().StringVarP(&sitemapOutput, "output", "o", "sitemap.xml", "Output file path, - for stdout")
+	sitemapCmd.Flags().StringVar(&sitemapInventory, "inventory", "", "Also write the URLs as a JSON inventory to this file")
+	sitemapCmd.Flags().StringVar(&sitemapURLStyle, "url-style", sitegen.URLStyleDirectory, "URL style of the pages (dir, html)")
+	sitemapCmd.Flags().StringSliceVar(&sitemapInclude, "include", nil, "Glob patterns for files to include, relative to the directory (can be specified multiple times)")
+	sitemapCmd.Flags().StringSliceVar(&sitemapExclude, "exclude", nil, "Glob patterns for files to exclude, relative to the directory (can be specified multiple times)")
+	sitemapCmd.MarkFlagRequired("base-url")
+	registerCompletion(sitemapCmd, "url-style", cobra.FixedCompletions([]string{sitegen.URLStyleDirectory, sitegen.URLStyleHTML}, cobra.ShellCompDirectiveNoFileComp))
+
+	sitemapCmd.GroupID = "core"
+	rootCmd.AddCommand(sitemapCmd)
+}
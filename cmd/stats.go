@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/metrics"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsSince   string
+	statsCommand string
+	statsLimit   int
+
+	statsCmd = &cobra.Command{
+		Use:   "stats",
+		Short: "Show local usage metrics",
+		Long: `Show the usage metrics mdctl records locally: the duration of every run, the
+files it processed, the AI tokens it used and the bytes it uploaded. Nothing
+is ever sent anywhere.
+
+Recording is opt-in:
+  mdctl config set --key metrics --value true
+
+Runs are appended to ~/.config/mdctl/metrics.jsonl, one JSON document per
+line, which can also be processed with other tools.`,
+	}
+
+	statsRunsCmd = &cobra.Command{
+		Use:   "runs",
+		Short: "List recorded runs and totals per command",
+		Long: `List the most recent recorded runs and the totals per command.
+
+Examples:
+  # Runs of the last week
+  mdctl stats runs --since 7d
+
+  # Token usage of translations since the start of the year
+  mdctl stats runs --command translate --since 2024-01-01
+
+  # Totals as JSON
+  mdctl stats runs --json | jq '.summary'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			since, err := parseSince(statsSince, time.Now())
+			if err != nil {
+				return err
+			}
+
+			path := filepath.Join(config.ConfigDir(), metrics.FileName)
+			all, err := metrics.Load(path)
+			if err != nil {
+				return err
+			}
+			var runs []metrics.Run
+			for _, run := range all {
+				if run.Start.Before(since) {
+					continue
+				}
+				if statsCommand != "" && run.Command != statsCommand && !strings.HasPrefix(run.Command, statsCommand+" ") {
+					continue
+				}
+				runs = append(runs, run)
+			}
+			summary := metrics.Summarize(runs)
+
+			if jsonOutput {
+				if runs == nil {
+					runs = []metrics.Run{}
+				}
+				return printJSON(struct {
+					Runs    []metrics.Run     `json:"runs"`
+					Summary []metrics.Summary `json:"summary"`
+				}{Runs: runs, Summary: summary})
+			}
+
+			if len(runs) == 0 {
+				if !config.ReadMetrics() {
+					fmt.Println("No runs recorded, enable metrics with: mdctl config set --key metrics --value true")
+				} else {
+					fmt.Println("No runs recorded")
+				}
+				return nil
+			}
+
+			recent := runs
+			if statsLimit > 0 && len(recent) > statsLimit {
+				recent = recent[len(recent)-statsLimit:]
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "START\tCOMMAND\tDURATION\tFILES\tTOKENS\tUPLOADED\tSTATUS")
+			for _, run := range recent {
+				status := "ok"
+				if run.Failed {
+					status = "failed"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\t%s\n", run.Start.Local().Format("2006-01-02 15:04"), run.Command,
+					formatDuration(run.DurationMS), run.Files, run.Tokens, formatBytes(run.BytesUploaded), status)
+			}
+			w.Flush()
+
+			fmt.Println()
+			w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "COMMAND\tRUNS\tFAILED\tDURATION\tFILES\tTOKENS\tUPLOADED")
+			for _, s := range summary {
+				fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%d\t%d\t%s\n", s.Command, s.Runs, s.Failed,
+					formatDuration(s.DurationMS), s.Files, s.Tokens, formatBytes(s.BytesUploaded))
+			}
+			return w.Flush()
+		},
+	}
+)
+
+// parseSince parses a date (2006-01-02) or an age such as 7d or 12h, the
+// zero time for an empty value
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value %q, expected a date (2006-01-02) or an age such as 7d or 12h", value)
+}
+
+// formatDuration formats milliseconds for tables
+func formatDuration(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond).String()
+}
+
+// formatBytes formats a byte count with binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	statsRunsCmd.Flags().StringVar(&statsSince, "since", "", "Only runs since a date (2006-01-02) or an age such as 7d or 12h")
+	statsRunsCmd.Flags().StringVar(&statsCommand, "command", "", "Only runs of a command, e.g. translate or config set")
+	statsRunsCmd.Flags().IntVarP(&statsLimit, "limit", "n", 20, "Number of recent runs to list, 0 lists all")
+
+	statsCmd.AddCommand(statsRunsCmd)
+	statsCmd.GroupID = "config"
+	rootCmd.AddCommand(statsCmd)
+}
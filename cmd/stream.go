@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	// stdioPath selects stdin as input or stdout as output
+	stdioPath = "-"
+
+	// stdinName identifies piped content in reports
+	stdinName = "<stdin>"
+)
+
+// readInput reads a file, or stdin for "-"
+func readInput(path string) ([]byte, error) {
+	if path == stdioPath {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %v", err)
+		}
+		return data, nil
+	}
+	return os.ReadFile(path)
+}
+
+// writeOutput writes data to a file, or stdout for "-"
+func writeOutput(path string, data []byte) error {
+	if path == stdioPath {
+		if _, err := resultWriter.Write(data); err != nil {
+			return fmt.Errorf("failed to write stdout: %v", err)
+		}
+		return nil
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// reserveStdout keeps stdout for the streamed document, progress and reports
+// that packages print with fmt.Printf end up on stderr instead
+func reserveStdout() error {
+	if jsonOutput {
+		return fmt.Errorf("--json cannot be combined with writing the document to stdout")
+	}
+	resultWriter = os.Stdout
+	os.Stdout = os.Stderr
+	return nil
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+// runStream runs mdctl with stdin and returns what it wrote to stdout
+func runStream(t *testing.T, stdin string, args ...string) string {
+	t.Helper()
+	out, err := runOutput(t, stdin, args...)
+	if err != nil {
+		t.Fatalf("mdctl %s failed: %v", strings.Join(args, " "), err)
+	}
+	return out
+}
+
+// runOutput runs mdctl with stdin and returns what it wrote to stdout and
+// its error
+func runOutput(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	dir := t.TempDir()
+	in := filepath.Join(dir, "stdin")
+	if err := os.WriteFile(in, []byte(stdin), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inFile, err := os.Open(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inFile.Close()
+	outFile, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outFile.Close()
+
+	stdin0, stdout0, result0 := os.Stdin, os.Stdout, resultWriter
+	os.Stdin, os.Stdout = inFile, outFile
+	defer func() { os.Stdin, os.Stdout, resultWriter = stdin0, stdout0, result0 }()
+
+	rootCmd.SetArgs(args)
+	runErr := rootCmd.Execute()
+	data, err := os.ReadFile(outFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), runErr
+}
+
+func TestFmtStream(t *testing.T) {
+	defer func() { fmtFile = "" }()
+
+	got := runStream(t, "#Title\n\n\n\nText\n", "fmt", "-f", "-")
+	if got != "# Title\n\nText\n" {
+		t.Errorf("fmt -f - wrote %q", got)
+	}
+}
+
+func TestLintFixStream(t *testing.T) {
+	defer func() { lintInputs, autoFix = nil, false }()
+
+	got := runStream(t, "# Title   \n\nText\n", "lint", "--fix", "-f", "-")
+	if got != "# Title\n\nText\n" {
+		t.Errorf("lint --fix -f - wrote %q", got)
+	}
+}
+
+func TestTranslateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": "# 标题\n\n正文\n"}}},
+		})
+	}))
+	defer server.Close()
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(config.ConfigEnv, "")
+	path := filepath.Join(t.TempDir(), "mdctl.json")
+	data, _ := json.Marshal(map[string]string{"endpoint": server.URL, "model": "test"})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { configFile, fromPath, locale = "", "", ""; config.SetConfigPath("") }()
+
+	got := runStream(t, "# Title\n\nText\n", "--config", path, "translate", "-f", "-", "-l", "zh")
+	if !strings.Contains(got, "# 标题") || !strings.Contains(got, "正文") {
+		t.Errorf("translate -f - wrote %q", got)
+	}
+}
+
+func TestDownloadStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG\r\n\x1a\n"))
+	}))
+	defer server.Close()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer func() { sourceFile, imageOutputDir = "", "" }()
+
+	got := runStream(t, "![logo]("+server.URL+"/logo.png)\n", "download", "-f", "-", "-o", "images")
+	if !strings.HasPrefix(got, "![logo](images/logo_") || strings.Contains(got, server.URL) {
+		t.Fatalf("download -f - wrote %q", got)
+	}
+	local := strings.TrimSuffix(strings.TrimPrefix(got, "![logo]("), ")\n")
+	if _, err := os.Stat(filepath.Join(dir, local)); err != nil {
+		t.Errorf("image was not downloaded: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"github.com/samzong/mdctl/internal/sitegen"
+	"github.com/samzong/mdctl/internal/translator"
+	"github.com/spf13/cobra"
+)
+
+var (
+	summarizeFile      string
+	summarizeDir       string
+	summarizeLength    int
+	summarizeLang      string
+	summarizeWrite     bool
+	summarizeField     string
+	summarizeOverwrite bool
+	summarizeInclude   []string
+	summarizeExclude   []string
+
+	summarizeCmd = &cobra.Command{
+		Use:   "summarize",
+		Short: "Summarize markdown files with the configured AI model",
+		Long: `Produce an abstract of a markdown file, or of every file in a directory, with
+the model, endpoint and usage limits configured for translate.
+
+Summaries are printed by default. With --write they are stored in the front
+matter field named by --field (description by default) instead; files that
+already have the field are skipped unless --overwrite is given.
+
+Examples:
+  mdctl summarize -f long-doc.md --length 200
+  mdctl summarize -f guide.md -l en
+  mdctl summarize -d docs/ --write --length 40
+  mdctl summarize -d docs/ --write --field summary --overwrite`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var files []string
+			if summarizeFile != "" {
+				files = []string{summarizeFile}
+			} else {
+				pages, err := sitegen.Collect(summarizeDir, sitegen.Options{Include: summarizeInclude, Exclude: summarizeExclude, Drafts: true})
+				if err != nil {
+					return err
+				}
+				for _, page := range pages {
+					files = append(files, filepath.Join(summarizeDir, filepath.FromSlash(page.Path)))
+				}
+			}
+
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %v", err)
+			}
+			t := translator.New(cfg, false).WithContext(cmd.Context())
+
+			type summaryResult struct {
+				File    string `json:"file"`
+				Summary string `json:"summary,omitempty"`
+				Status  string `json:"status"` // summarized, written or skipped
+			}
+			var results []summaryResult
+			for _, file := range files {
+				if interrupted(cmd) {
+					return cmd.Context().Err()
+				}
+				content, err := os.ReadFile(file)
+				if err != nil {
+					return fmt.Errorf("failed to read file %s: %v", file, err)
+				}
+				doc := mddoc.Split(content)
+
+				if summarizeWrite && !summarizeOverwrite && hasMetaField(doc, summarizeField) {
+					results = append(results, summaryResult{File: file, Status: "skipped"})
+					continue
+				}
+				if dryRun {
+					results = append(results, summaryResult{File: file, Status: "summarized"})
+					continue
+				}
+
+				summary, err := t.Summarize(string(content), summarizeLength, summarizeLang)
+				if err != nil {
+					return fmt.Errorf("failed to summarize %s: %v", file, err)
+				}
+				result := summaryResult{File: file, Summary: summary, Status: "summarized"}
+
+				if summarizeWrite {
+					updated, err := doc.SetMeta(summarizeField, summary)
+					if err != nil {
+						return fmt.Errorf("failed to update front matter of %s: %v", file, err)
+					}
+					if err := fsutil.WriteFileAtomic(file, updated, 0644); err != nil {
+						return err
+					}
+					result.Status = "written"
+				}
+				results = append(results, result)
+			}
+
+			if jsonOutput {
+				return printJSON(struct {
+					DryRun bool            `json:"dry_run"`
+					Files  []summaryResult `json:"files"`
+				}{DryRun: dryRun, Files: results})
+			}
+
+			written, skipped := 0, 0
+			for _, result := range results {
+				switch {
+				case dryRun && result.Status == "summarized":
+					fmt.Printf("Would summarize %s\n", result.File)
+				case result.Status == "skipped":
+					skipped++
+				case result.Status == "written":
+					written++
+				case summarizeFile != "":
+					fmt.Println(result.Summary)
+				default:
+					fmt.Printf("%s:\n  %s\n\n", result.File, result.Summary)
+				}
+			}
+			if summarizeWrite && !dryRun {
+				fmt.Printf("Wrote %d summaries to the %s field (%d files skipped)\n", written, summarizeField, skipped)
+			}
+			return nil
+		},
+	}
+)
+
+// hasMetaField reports whether the front matter of a document has a non-empty field
+func hasMetaField(doc *mddoc.Document, field string) bool {
+	node, err := doc.Meta()
+	if err != nil || node == nil || len(node.Content) == 0 {
+		return false
+	}
+	mapping := node.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == field {
+			return strings.TrimSpace(mapping.Content[i+1].Value) != ""
+		}
+	}
+	return false
+}
+
+func init() {
+	summarizeCmd.Flags().StringVarP(&summarizeFile, "file", "f", "", "Markdown file to summarize")
+	summarizeCmd.Flags().StringVarP(&summarizeDir, "dir", "d", "", "Directory of markdown files to summarize")
+	summarizeCmd.Flags().IntVar(&summarizeLength, "length", translator.DefaultSummaryLength, "Maximum length of a summary in words")
+	summarizeCmd.Flags().StringVarP(&summarizeLang, "lang", "l", "", "Language of the summaries (default: the language of each document)")
+	summarizeCmd.Flags().BoolVar(&summarizeWrite, "write", false, "Write the summaries into the front matter instead of printing them")
+	summarizeCmd.Flags().StringVar(&summarizeField, "field", "description", "Front matter field written by --write")
+	summarizeCmd.Flags().BoolVar(&summarizeOverwrite, "overwrite", false, "Replace existing front matter fields with --write")
+	summarizeCmd.Flags().StringSliceVar(&summarizeInclude, "include", nil, "Glob patterns for files to include, relative to the directory (can be specified multiple times)")
+	summarizeCmd.Flags().StringSliceVar(&summarizeExclude, "exclude", nil, "Glob patterns for files to exclude, relative to the directory (can be specified multiple times)")
+	summarizeCmd.MarkFlagsOneRequired("file", "dir")
+	summarizeCmd.MarkFlagsMutuallyExclusive("file", "dir")
+
+	summarizeCmd.GroupID = "core"
+	rootCmd.AddCommand(summarizeCmd)
+}
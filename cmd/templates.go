@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/exporter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	templateName string
+
+	templatesCmd = &cobra.Command{
+		Use:   "templates",
+		Short: "Manage export templates and themes",
+		Long: `Manage the templates selectable with mdctl export --theme.
+
+Templates are Word reference documents (.docx) for DOCX output, LaTeX
+templates (.latex or .tex) for PDF output and style sheets (.css) for EPUB
+output, stored in ~/.config/mdctl/templates. Files added under one name form a
+single template. The built-in themes corporate, academic and minimal style
+every format without any files.
+
+Examples:
+  mdctl export templates list
+  mdctl export templates add reference.docx --name acme
+  mdctl export templates fetch https://example.com/acme.css
+  mdctl export -d docs/ -o guide.docx --theme acme`,
+	}
+
+	templatesListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List stored templates and built-in themes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			templates, err := exporter.DefaultTemplateStore().List()
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				return printJSON(templates)
+			}
+			for _, t := range templates {
+				source := "user"
+				if t.Builtin {
+					source = "built-in"
+				}
+				fmt.Printf("%-16s %-9s %-16s %s\n", t.Name, source, strings.Join(t.Formats, ","), t.Description)
+			}
+			return nil
+		},
+	}
+
+	templatesAddCmd = &cobra.Command{
+		Use:   "add <file>",
+		Short: "Add a template file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := templateStore().Add(args[0], templateName)
+			if err != nil {
+				return err
+			}
+			return printTemplate(path)
+		},
+	}
+
+	templatesFetchCmd = &cobra.Command{
+		Use:   "fetch <url>",
+		Short: "Download a template file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := templateStore().Fetch(cmd.Context(), args[0], templateName)
+			if err != nil {
+				return err
+			}
+			return printTemplate(path)
+		},
+	}
+)
+
+// templateStore returns the template store, which writes nothing with --dry-run
+func templateStore() *exporter.TemplateStore {
+	store := exporter.DefaultTemplateStore()
+	store.DryRun = dryRun
+	return store
+}
+
+// printTemplate reports a stored template
+func printTemplate(path string) error {
+	if jsonOutput {
+		return printJSON(map[string]string{"path": path})
+	}
+	if dryRun {
+		fmt.Printf("Would save template to %s\n", path)
+		return nil
+	}
+	fmt.Printf("Template saved to %s\n", path)
+	return nil
+}
+
+func init() {
+	templatesAddCmd.Flags().StringVar(&templateName, "name", "", "Template name (default: file name)")
+	templatesFetchCmd.Flags().StringVar(&templateName, "name", "", "Template name (default: file name)")
+
+	templatesCmd.AddCommand(templatesListCmd, templatesAddCmd, templatesFetchCmd)
+	exportCmd.AddCommand(templatesCmd)
+}
@@ -1,11 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/samzong/mdctl/internal/clipboard"
 	"github.com/samzong/mdctl/internal/config"
 	"github.com/samzong/mdctl/internal/translator"
 	"github.com/spf13/cobra"
@@ -17,6 +19,25 @@ var (
 	locale   string
 	force    bool
 	format   bool
+	catalogs bool
+	mdx      bool
+
+	snippet       string
+	fromClipboard bool
+
+	copyAssets        bool
+	translateSiteType string
+	reviewDir         string
+	reviewFormat      string
+	resume            bool
+	continueOnError   bool
+	translateInclude  []string
+	translateExclude  []string
+	listModels        bool
+	skipModelCheck    bool
+	structureRetries  int
+	strictStructure   bool
+	bilingualMode     string
 )
 
 // Generate target file path
@@ -32,6 +53,38 @@ var translateCmd = &cobra.Command{
 	Use:   "translate",
 	Short: "Translate markdown files using AI models",
 	Long: `Translate markdown files or directories to specified language using AI models.
+YAML/TOML/JSON string catalogs (e.g. theme overrides, Hugo i18n files) are
+translated value by value, keeping keys and structure unchanged.
+
+The prompt of a target language is translate_prompts.<lang> of the
+configuration, or translate_prompt. A .mdctl.yaml file in the directory of a
+document or one of its parents overrides both for the documents below it:
+
+  translate_prompt: Translate to {TARGET_LANG} in a friendly, informal tone.
+  translate_prompts:
+    ja: Translate to Japanese using the desu/masu form.
+
+The nearest .mdctl.yaml with a prompt for the language, or a general prompt,
+wins. {TARGET_LANG} is replaced with the language code.
+
+Every translation is compared with its source: the number and levels of the
+headings and the number of code blocks, links and tables must match. A
+translation that dropped or duplicated structure is sent back to the model
+with the differences, --structure-retries times (default 1). If it still
+differs it is written and listed as flagged, or fails with --strict-structure.
+
+--bilingual keeps the source next to the translation, paragraph by paragraph:
+"alternate" shows each source paragraph before its translation, "comments"
+keeps it in an HTML comment that only editors see. Code blocks and other
+paragraphs left as they are appear once. When the translation has a different
+number of paragraphs the whole source comes first and the file is flagged.
+"mdctl translate strip" removes the kept source once the translation is
+reviewed.
+
+Directory runs first check that the endpoint accepts the API key and offers
+the configured model (GET /models), so a wrong endpoint, key or model name
+fails at once. --list-models shows the models of the endpoint and
+--skip-model-check turns the check off for endpoints without a model list.
 
 Supported AI Models:
   - OpenAI (Current)
@@ -57,20 +110,91 @@ Examples:
   mdctl translate -f README.md -l zh -m
 
   # Translate to a specific output path
-  mdctl translate -f docs -l fr -t translated_docs`,
+  mdctl translate -f docs -l fr -t translated_docs
+
+  # Translate the values of a YAML/TOML/JSON string catalog
+  mdctl translate -f i18n/en.toml -l de -t i18n/de.toml
+
+  # Copy referenced images into the target tree instead of rewriting their paths
+  mdctl translate -f docs -l zh -t translated/zh --copy-assets
+
+  # Translate into the i18n layout of a docs site
+  mdctl translate -f docs/en -l zh -s mkdocs      # docs/zh/... (folder structure)
+  mdctl translate -f content/en -l ja -s hugo     # content/ja/...
+  mdctl translate -f docs -l fr -s docusaurus     # i18n/fr/docusaurus-plugin-content-docs/current/...
+
+  # Keep going on failures, then retry only the unfinished files
+  mdctl translate -f docs -l ja -t docs_ja --continue-on-error
+  mdctl translate -f docs -l ja -t docs_ja --resume
+
+  # Write side-by-side review pages for the translated files
+  mdctl translate -f docs -l de -t docs_de --review review/
+
+  # Keep the source paragraphs in comments, then drop them after the review
+  mdctl translate -f docs -l ja -t docs_ja --bilingual comments
+  mdctl translate strip docs_ja
+
+  # Translate markdown with embedded JSX/HTML components (.mdx files always are)
+  mdctl translate -f docs/intro.md -l ja --mdx
+
+  # Translate a directory including string catalogs
+  mdctl translate -f docs -l ja -t docs_ja --catalogs
+
+  # Skip generated folders, the changelog and existing translations
+  mdctl translate -f docs -l zh --exclude 'node_modules/**' --exclude CHANGELOG.md --exclude '**_zh.md'
+
+  # Show which files would be translated and the estimated token usage
+  mdctl translate -f docs -l ja --dry-run
+
+  # Translate only the files changed since a git ref
+  mdctl translate -f docs -l ja -t docs_ja --since origin/main
+
+  # Translate a snippet or the clipboard and print the result
+  mdctl translate --text "Click **Save** to apply the changes." -l de
+  mdctl translate --clipboard -l ja
+
+  # Show the models of the configured endpoint
+  mdctl translate --list-models
+
+  # Translate piped content, "-" reads stdin and writes stdout
+  cat README.md | mdctl translate -l zh -f - > README_zh.md
+  mdctl translate -f README.md -l zh -t - | less`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.LoadConfig()
 		if err != nil {
 			return fmt.Errorf("failed to load config: %v", err)
 		}
 
+		if listModels {
+			return listAIModels(cmd.Context(), cfg)
+		}
+
 		// Validate language option
+		if locale == "" {
+			return fmt.Errorf(`required flag(s) "locales" not set`)
+		}
 		if !translator.IsLanguageSupported(locale) {
 			return fmt.Errorf("unsupported locale: %s\nSupported languages: %s",
 				locale,
 				translator.GetSupportedLanguages())
 		}
 
+		if err := translator.ValidateBilingual(bilingualMode); err != nil {
+			return err
+		}
+
+		if cmd.Flags().Changed("text") || fromClipboard {
+			return translateSnippet(cmd.Context(), cfg)
+		}
+
+		if fromPath == stdioPath || toPath == stdioPath {
+			return translateStream(cmd.Context(), cfg)
+		}
+
+		if reviewFormat != translator.ReviewHTML && reviewFormat != translator.ReviewMarkdown {
+			return fmt.Errorf("unsupported review format: %s (must be html or markdown)", reviewFormat)
+		}
+
 		// Check if source path exists
 		if _, err := os.Stat(fromPath); os.IsNotExist(err) {
 			return fmt.Errorf("source path does not exist: %s", fromPath)
@@ -82,23 +206,79 @@ Examples:
 			return fmt.Errorf("failed to get absolute path: %v", err)
 		}
 
+		opts := translator.Options{
+			Format:   format,
+			Force:    force,
+			Catalogs: catalogs,
+			MDX:      mdx,
+			DryRun:   dryRun,
+			Report:   &translator.Report{},
+
+			CopyAssets:      copyAssets,
+			Resume:          resume,
+			ContinueOnError: continueOnError,
+			Include:         translateInclude,
+			Exclude:         translateExclude,
+
+			StructureRetries: structureRetries,
+			StrictStructure:  strictStructure,
+			Bilingual:        bilingualMode,
+		}
+		if structureRetries == 0 {
+			// The options take 0 for the default number of repairs
+			opts.StructureRetries = -1
+		}
+
+		// Only translate the files changed in git
+		if changedOptions().Enabled() {
+			if opts.Files, err = changedFiles(srcAbs); err != nil {
+				return err
+			}
+			if len(opts.Files) == 0 {
+				fmt.Println("No changed files to translate")
+				return reportTranslation(cmd, opts.Report, nil)
+			}
+		}
+
 		// Check if it's a file or directory
 		fi, err := os.Stat(srcAbs)
 		if err != nil {
 			return fmt.Errorf("failed to get file info: %v", err)
 		}
 
+		if (len(translateInclude) > 0 || len(translateExclude) > 0) && !fi.IsDir() {
+			return fmt.Errorf("--include and --exclude require a source directory")
+		}
+
+		if translateSiteType != "" {
+			if !fi.IsDir() {
+				return fmt.Errorf("--site-type requires a source directory")
+			}
+			if toPath != "" {
+				return fmt.Errorf("--site-type determines the target location, it cannot be combined with --to")
+			}
+			if err := checkModel(cmd.Context(), cfg); err != nil {
+				return err
+			}
+			err = translator.ProcessSite(cmd.Context(), srcAbs, translateSiteType, locale, cfg, opts)
+			return reportTranslation(cmd, opts.Report, reviewTranslation(opts.Report, srcAbs, err))
+		}
+
 		if fi.IsDir() {
 			// If it's a directory and no target path specified, use the same directory structure
-			if toPath == "" {
-				return translator.ProcessDirectory(srcAbs, srcAbs, locale, cfg, force, format)
+			dstAbs := srcAbs
+			if toPath != "" {
+				// If target path is specified, use the specified path
+				dstAbs, err = filepath.Abs(toPath)
+				if err != nil {
+					return fmt.Errorf("failed to get absolute path: %v", err)
+				}
 			}
-			// If target path is specified, use the specified path
-			dstAbs, err := filepath.Abs(toPath)
-			if err != nil {
-				return fmt.Errorf("failed to get absolute path: %v", err)
+			if err := checkModel(cmd.Context(), cfg); err != nil {
+				return err
 			}
-			return translator.ProcessDirectory(srcAbs, dstAbs, locale, cfg, force, format)
+			err = translator.ProcessDirectory(cmd.Context(), srcAbs, dstAbs, locale, cfg, opts)
+			return reportTranslation(cmd, opts.Report, reviewTranslation(opts.Report, srcAbs, err))
 		}
 
 		// Process single file
@@ -114,17 +294,256 @@ Examples:
 			}
 		}
 
-		return translator.ProcessFile(srcAbs, dstAbs, locale, cfg, format, force)
+		err = translator.ProcessFile(cmd.Context(), srcAbs, dstAbs, locale, cfg, opts)
+		return reportTranslation(cmd, opts.Report, reviewTranslation(opts.Report, srcAbs, err))
+	},
+}
+
+var translateStripCmd = &cobra.Command{
+	Use:   "strip <file|dir>",
+	Short: "Remove the source paragraphs kept by --bilingual",
+	Long: `Remove the source paragraphs a --bilingual translation kept, in both the
+alternate and the comments mode, leaving the translation alone. A directory
+strips all its markdown files. With --dry-run the files are only listed.
+
+Examples:
+  mdctl translate strip docs_ja/
+  mdctl translate strip README_zh.md --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		results, err := translator.StripSourceFiles(args[0], dryRun)
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			return printJSON(results)
+		}
+		total := 0
+		for _, r := range results {
+			total += r.Blocks
+			fmt.Printf("%s: %d blocks\n", r.File, r.Blocks)
+		}
+		verb := "Removed"
+		if dryRun {
+			verb = "Would remove"
+		}
+		fmt.Printf("%s %d source blocks in %d files\n", verb, total, len(results))
+		return nil
 	},
 }
 
+// checkModel verifies the endpoint, API key and model before a directory
+// run, so a misconfiguration does not surface deep into it
+func checkModel(ctx context.Context, cfg *config.Config) error {
+	if dryRun || skipModelCheck {
+		return nil
+	}
+	if err := translator.New(cfg, false).WithContext(ctx).CheckModel(); err != nil {
+		return fmt.Errorf("%v\nUse --skip-model-check for endpoints without a model list", err)
+	}
+	return nil
+}
+
+// listAIModels prints the models of the configured endpoint, marking the
+// configured one
+func listAIModels(ctx context.Context, cfg *config.Config) error {
+	models, err := translator.New(cfg, false).WithContext(ctx).ListModels()
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJSON(struct {
+			Endpoint string             `json:"endpoint"`
+			Model    string             `json:"model"`
+			Models   []translator.Model `json:"models"`
+		}{cfg.OpenAIEndpointURL, cfg.ModelName, models})
+	}
+	for _, model := range models {
+		marker := "  "
+		if model.ID == cfg.ModelName {
+			marker = "* "
+		}
+		fmt.Println(marker + model.ID)
+	}
+	return nil
+}
+
+// translateStream translates a single document between stdin, stdout and
+// files, without the already-translated check
+func translateStream(ctx context.Context, cfg *config.Config) error {
+	if dryRun {
+		return fmt.Errorf("--dry-run is not supported when streaming through stdin/stdout")
+	}
+
+	dst := toPath
+	if dst == "" {
+		dst = stdioPath
+	}
+	if dst == stdioPath {
+		if err := reserveStdout(); err != nil {
+			return err
+		}
+	}
+
+	content, err := readInput(fromPath)
+	if err != nil {
+		return err
+	}
+
+	// Documents read from stdin use the project prompts of the working directory
+	source := fromPath
+	if source == stdioPath {
+		source = "."
+	}
+	t := translator.New(cfg, format).WithContext(ctx).WithMDX(mdx || translator.IsMDX(fromPath)).WithSource(source).
+		WithBilingual(bilingualMode)
+	translated, err := t.TranslateDocument(string(content), locale)
+	if err != nil {
+		return err
+	}
+	return writeOutput(dst, []byte(translated))
+}
+
+// translateSnippet translates the --text or clipboard content and prints the
+// translation, or writes it to --to
+func translateSnippet(ctx context.Context, cfg *config.Config) error {
+	if dryRun {
+		return fmt.Errorf("--dry-run is not supported for --text and --clipboard")
+	}
+	if bilingualMode != "" {
+		return fmt.Errorf("--bilingual is not supported for --text and --clipboard")
+	}
+
+	text := snippet
+	if fromClipboard {
+		var err error
+		if text, err = clipboard.Read(); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("nothing to translate")
+	}
+
+	t := translator.New(cfg, format).WithContext(ctx).WithMDX(mdx).WithSource(".")
+	translated, err := t.TranslateContent(text, locale)
+	if err != nil {
+		return err
+	}
+	translated = strings.TrimRight(translated, "\n")
+
+	if jsonOutput {
+		return printJSON(struct {
+			Language    string `json:"language"`
+			Text        string `json:"text"`
+			Translation string `json:"translation"`
+		}{locale, text, translated})
+	}
+	dst := toPath
+	if dst == "" {
+		dst = stdioPath
+	}
+	return writeOutput(dst, []byte(translated+"\n"))
+}
+
+// reviewTranslation writes the --review comparison of the translated files,
+// keeping the translation error if there was one
+func reviewTranslation(report *translator.Report, srcRoot string, err error) error {
+	if reviewDir == "" || dryRun {
+		return err
+	}
+
+	if reviewErr := translator.WriteReview(report, srcRoot, reviewDir, reviewFormat); reviewErr != nil {
+		if err == nil {
+			err = fmt.Errorf("failed to write review: %v", reviewErr)
+		}
+		return err
+	}
+	fmt.Printf("Review written to %s\n", reviewDir)
+	return err
+}
+
+// reportTranslation emits the translation report in --json mode, or a
+// summary when the run was interrupted or is a dry run
+func reportTranslation(cmd *cobra.Command, report *translator.Report, err error) error {
+	if interrupted(cmd) {
+		err = fmt.Errorf("translation interrupted")
+	}
+
+	if !jsonOutput {
+		if report.Flagged > 0 {
+			fmt.Printf("Flagged files (review the translation):\n")
+			for _, file := range report.Files {
+				if file.Warning != "" {
+					fmt.Printf("  %s: %s\n", file.Target, file.Warning)
+				}
+			}
+		}
+		if report.Failed > 0 {
+			fmt.Printf("Failed files:\n")
+			for _, file := range report.Files {
+				if file.Status == "failed" {
+					fmt.Printf("  %s: %s\n", file.Source, file.Error)
+				}
+			}
+		}
+		if interrupted(cmd) {
+			fmt.Printf("Translated %d, skipped %d, failed %d files before interruption\n",
+				report.Translated, report.Skipped, report.Failed)
+		} else if dryRun {
+			fmt.Printf("Would translate %d files (~%d tokens), skip %d, failed %d\n",
+				report.Planned, report.EstimatedTokens, report.Skipped, report.Failed)
+		}
+		return err
+	}
+
+	result := struct {
+		*translator.Report
+		Error string `json:"error,omitempty"`
+	}{Report: report}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	if printErr := printJSON(result); printErr != nil {
+		return printErr
+	}
+	if err != nil || report.Failed > 0 {
+		exitWith(1)
+	}
+	return nil
+}
+
 func init() {
-	translateCmd.Flags().StringVarP(&fromPath, "from", "f", "", "Source file or directory path")
-	translateCmd.Flags().StringVarP(&toPath, "to", "t", "", "Target file or directory path (optional, default: generate in same directory as source)")
+	translateCmd.Flags().StringVarP(&fromPath, "from", "f", "", "Source file or directory path, - reads stdin")
+	translateCmd.Flags().StringVar(&snippet, "text", "", "Translate this text and print the result instead of translating files")
+	translateCmd.Flags().BoolVar(&fromClipboard, "clipboard", false, "Translate the text on the clipboard and print the result")
+	translateCmd.Flags().StringVarP(&toPath, "to", "t", "", "Target file or directory path, - writes stdout (optional, default: generate in same directory as source, stdout for stdin)")
 	translateCmd.Flags().StringVarP(&locale, "locales", "l", "", "Target language code (e.g., zh, en, ja, ko, fr, de, es, etc.)")
 	translateCmd.Flags().BoolVarP(&force, "force", "F", false, "Force translate even if already translated")
 	translateCmd.Flags().BoolVarP(&format, "format", "m", false, "Format markdown content after translation")
+	translateCmd.Flags().BoolVar(&catalogs, "catalogs", false, "Also translate YAML/TOML/JSON string catalogs in directory mode")
+	translateCmd.Flags().BoolVar(&mdx, "mdx", false, "Protect JSX/HTML components, import/export statements and expressions (always on for .mdx files)")
+
+	translateCmd.Flags().BoolVar(&copyAssets, "copy-assets", false, "Copy referenced local images next to translations written elsewhere instead of rewriting their paths")
+	translateCmd.Flags().StringVarP(&translateSiteType, "site-type", "s", "", "Write translations into the i18n layout of a docs site (mkdocs, hugo, docusaurus)")
+	translateCmd.Flags().StringVar(&reviewDir, "review", "", "Write a side-by-side source/translation comparison of each translated file into this directory")
+	translateCmd.Flags().StringVar(&reviewFormat, "review-format", translator.ReviewHTML, "Review format: html, markdown")
+	translateCmd.Flags().BoolVar(&resume, "resume", false, "Resume the previous directory run, only translating unfinished and failed files")
+	translateCmd.Flags().BoolVar(&continueOnError, "continue-on-error", false, "Keep translating the remaining files when one fails and report all failures at the end")
+	translateCmd.Flags().StringSliceVar(&translateInclude, "include", nil, "Glob patterns for files to translate, relative to the source directory (can be specified multiple times)")
+	translateCmd.Flags().StringSliceVar(&translateExclude, "exclude", nil, "Glob patterns for files and directories to skip, relative to the source directory (can be specified multiple times)")
+	translateCmd.Flags().BoolVar(&listModels, "list-models", false, "List the models of the configured endpoint (* marks the configured model)")
+	translateCmd.Flags().BoolVar(&skipModelCheck, "skip-model-check", false, "Do not check the endpoint, API key and model before a directory run")
+	translateCmd.Flags().IntVar(&structureRetries, "structure-retries", 1, "Repair requests for translations whose headings, code blocks, links or tables differ from the source")
+	translateCmd.Flags().BoolVar(&strictStructure, "strict-structure", false, "Fail files whose structure still differs after the repairs instead of writing them flagged")
+	translateCmd.Flags().StringVar(&bilingualMode, "bilingual", "", "Keep the source paragraphs before their translations: alternate (visible) or comments (HTML comments)")
+	translateCmd.Flags().Lookup("bilingual").NoOptDefVal = translator.BilingualAlternate
+	registerCompletion(translateCmd, "bilingual", cobra.FixedCompletions([]string{translator.BilingualAlternate, translator.BilingualComments}, cobra.ShellCompDirectiveNoFileComp))
+	addChangedFlags(translateCmd)
 
-	translateCmd.MarkFlagRequired("from")
-	translateCmd.MarkFlagRequired("locales")
+	translateCmd.MarkFlagsOneRequired("from", "text", "clipboard", "list-models")
+	translateCmd.MarkFlagsMutuallyExclusive("from", "text", "clipboard", "list-models")
+	translateCmd.AddCommand(translateStripCmd)
 }
@@ -1,10 +1,17 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/storage"
+	"github.com/samzong/mdctl/internal/translator"
 	"github.com/samzong/mdctl/internal/uploader"
 	"github.com/spf13/cobra"
 )
@@ -17,15 +24,29 @@ var (
 	uploadBucket         string
 	uploadCustomDomain   string
 	uploadPathPrefix     string
-	uploadDryRun         bool
 	uploadConcurrency    int
 	uploadForceUpload    bool
 	uploadSkipVerify     bool
 	uploadCACertPath     string
 	uploadConflictPolicy string
 	uploadCacheDir       string
-	uploadIncludeExts    string
+	uploadExtensions     string
+	uploadInclude        []string
+	uploadExclude        []string
 	uploadStorageName    string
+	uploadAltText        string
+	uploadLinkStyle      string
+	uploadImageWidth     string
+	uploadAssetsKeys     []string
+	uploadProvenance     string
+
+	// Upload gc command flags
+	gcSourceFile  string
+	gcSourceDir   string
+	gcStorageName string
+	gcPathPrefix  string
+	gcCacheDir    string
+	gcYes         bool
 
 	uploadCmd = &cobra.Command{
 		Use:   "upload",
@@ -36,7 +57,39 @@ Supports multiple cloud storage providers with S3-compatible APIs.
 Examples:
   mdctl upload -d docs/
   mdctl upload -f post.md
-  mdctl upload -f post.md --storage my-s3`,
+  mdctl upload -f post.md --storage my-s3
+  mdctl upload -d docs/ --since origin/main
+  mdctl upload -d site/ --include 'content/posts/**' --exclude '**/archive/**'
+  mdctl upload -d docs/ --alt-text ai
+  mdctl upload -f article.md --link-style html --image-width 600
+  mdctl upload -f post.md --link-style reference
+  mdctl upload -d docs/ --provenance sidecar
+
+--alt-text fills the empty alt text of the images it rewrites: "filename"
+turns the file name into words, "ai" asks the configured model for a short
+description (it has to accept images) and falls back to the file name.
+
+--link-style sets how rewritten images are written, for publishing targets
+that need a particular form: "inline" keeps ![alt](url), "html" writes
+<img src="url" alt="alt"> tags, with a width attribute from --image-width
+(pixels or a percentage), and "reference" writes ![alt][image-1] and collects
+the [image-1]: url definitions at the end of the file.
+
+--provenance records the original path of every rewritten image with its
+uploaded URL, so uploads can be audited or reverted: "frontmatter" adds them
+to the mdctl_assets field of each file, "sidecar" to .mdctl-assets.json in
+the source directory. Entries of earlier runs are kept.
+
+Images are uploaded once per content: the upload cache records them by hash,
+so copies at other paths, other repositories and moved checkouts get the
+cached URL. --force uploads them again.
+
+Images referenced by front matter fields, such as the cover image of a theme,
+are uploaded and rewritten too when the fields are listed in assets_keys of
+the project configuration (.mdctl.yaml) or with --assets-key. Nested fields
+are given as dotted paths, download uses the same list:
+
+  assets_keys: [cover.image, image, thumbnail]`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if uploadSourceFile == "" && uploadSourceDir == "" {
 				return fmt.Errorf("either source file (-f) or source directory (-d) must be specified")
@@ -44,6 +97,63 @@ Examples:
 			if uploadSourceFile != "" && uploadSourceDir != "" {
 				return fmt.Errorf("cannot specify both source file (-f) and source directory (-d)")
 			}
+			switch uploadAltText {
+			case "", uploader.AltTextFilename, uploader.AltTextAI:
+			default:
+				return fmt.Errorf("unsupported alt text mode: %s (must be filename or ai)", uploadAltText)
+			}
+			if !uploader.ValidLinkStyle(uploadLinkStyle) {
+				return fmt.Errorf("unsupported link style: %s (must be inline, html or reference)", uploadLinkStyle)
+			}
+			if !uploader.ValidProvenance(uploadProvenance) {
+				return fmt.Errorf("unsupported provenance: %s (must be frontmatter or sidecar)", uploadProvenance)
+			}
+			if uploadImageWidth != "" && uploadLinkStyle != uploader.LinkStyleHTML {
+				return fmt.Errorf("--image-width requires --link-style html")
+			}
+
+			// Parse markdown extensions
+			var exts []string
+			if uploadExtensions != "" {
+				exts = strings.Split(uploadExtensions, ",")
+				for i, ext := range exts {
+					exts[i] = strings.TrimSpace(ext)
+				}
+			}
+
+			// Only process the markdown files changed in git
+			var files []string
+			if changedOptions().Enabled() {
+				source := uploadSourceFile
+				if source == "" {
+					source = uploadSourceDir
+				}
+				changedList, err := changedFiles(source)
+				if err != nil {
+					return err
+				}
+				for _, file := range changedList {
+					if hasExtension(file, exts) {
+						files = append(files, file)
+					}
+				}
+				if len(files) == 0 {
+					fmt.Println("No changed markdown files to upload")
+					if jsonOutput {
+						return printJSON(&uploader.FileStats{})
+					}
+					return nil
+				}
+			}
+
+			source := uploadSourceDir
+			if source == "" {
+				source = filepath.Dir(uploadSourceFile)
+			}
+			assetsKeys, err := assetsKeysFor(cmd, uploadAssetsKeys, source)
+			if err != nil {
+				return err
+			}
 
 			// Load configuration file first
 			cfg, err := config.LoadConfig()
@@ -68,22 +178,11 @@ Examples:
 				return fmt.Errorf("provider (-p) must be specified or set in configuration file")
 			}
 
-			if uploadBucket == "" {
+			if uploadBucket == "" && !storage.IsImageHost(strings.ToLower(uploadProvider)) {
 				return fmt.Errorf("bucket (-b) must be specified or set in configuration file")
 			}
 
-			// Set default region for S3-compatible services
-			// If region is not set or empty, set default region
-			if cloudConfig.Region == "" {
-				switch strings.ToLower(uploadProvider) {
-				case "s3":
-					// For AWS S3, default to us-east-1
-					cloudConfig.Region = "us-east-1"
-				case "r2", "minio", "b2":
-					// For S3-compatible services, region can be any value but must be provided
-					cloudConfig.Region = "auto"
-				}
-			}
+			setDefaultRegion(&cloudConfig, uploadProvider)
 
 			// If not specified in command line, get other configuration parameters
 			if uploadCustomDomain == "" {
@@ -114,15 +213,6 @@ Examples:
 				uploadCacheDir = cloudConfig.CacheDir
 			}
 
-			// Parse include extensions
-			var exts []string
-			if uploadIncludeExts != "" {
-				exts = strings.Split(uploadIncludeExts, ",")
-				for i, ext := range exts {
-					exts[i] = strings.TrimSpace(ext)
-				}
-			}
-
 			// Validate conflict policy
 			var conflictPolicy uploader.ConflictPolicy
 			switch strings.ToLower(uploadConflictPolicy) {
@@ -147,11 +237,12 @@ Examples:
 			up, err := uploader.New(uploader.UploaderConfig{
 				SourceFile:     uploadSourceFile,
 				SourceDir:      uploadSourceDir,
+				Files:          files,
 				Provider:       uploadProvider,
 				Bucket:         uploadBucket,
 				CustomDomain:   uploadCustomDomain,
 				PathPrefix:     uploadPathPrefix,
-				DryRun:         uploadDryRun,
+				DryRun:         dryRun,
 				Concurrency:    uploadConcurrency,
 				ForceUpload:    uploadForceUpload,
 				SkipVerify:     uploadSkipVerify,
@@ -159,17 +250,44 @@ Examples:
 				ConflictPolicy: conflictPolicy,
 				CacheDir:       uploadCacheDir,
 				FileExtensions: exts,
+				Include:        uploadInclude,
+				Exclude:        uploadExclude,
+				AltText:        uploadAltText,
+				LinkStyle:      uploadLinkStyle,
+				ImageWidth:     uploadImageWidth,
+				AssetsKeys:     assetsKeys,
+				Provenance:     uploadProvenance,
+				Caption:        captionImage(cmd.Context(), cfg),
+				Storage:        &cloudConfig,
 			})
 			if err != nil {
 				return fmt.Errorf("failed to create uploader: %v", err)
 			}
 
 			// Process files
-			stats, err := up.Process()
-			if err != nil {
+			stats, err := up.Process(cmd.Context())
+			if err != nil && !interrupted(cmd) {
 				return fmt.Errorf("failed to process files: %v", err)
 			}
 
+			if jsonOutput {
+				result := struct {
+					DryRun bool `json:"dry_run"`
+					*uploader.FileStats
+					Error string `json:"error,omitempty"`
+				}{DryRun: dryRun, FileStats: stats}
+				if err != nil {
+					result.Error = "upload interrupted"
+				}
+				if printErr := printJSON(result); printErr != nil {
+					return printErr
+				}
+				if err != nil {
+					exitWith(1)
+				}
+				return nil
+			}
+
 			// Print statistics
 			fmt.Printf("\nUpload Statistics:\n")
 			fmt.Printf("  Total Files Processed: %d\n", stats.ProcessedFiles)
@@ -177,27 +295,205 @@ Examples:
 			fmt.Printf("  Images Skipped: %d\n", stats.SkippedImages)
 			fmt.Printf("  Failed Uploads: %d\n", stats.FailedImages)
 			fmt.Printf("  Files Changed: %d\n", stats.ChangedFiles)
+			if uploadAltText != "" {
+				fmt.Printf("  Alt Texts Filled: %d\n", stats.FilledAltTexts)
+			}
 
+			if err != nil {
+				return fmt.Errorf("upload interrupted")
+			}
 			return nil
 		},
 	}
 )
 
+var uploadGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete uploaded images no markdown file references anymore",
+	Long: `List the images stored under the path prefix of the storage that no markdown
+file of the source references and no upload cache item records, then delete
+them after confirmation. With --dry-run the orphans are only listed.
+
+Only objects with image extensions are considered, so other files sharing the
+bucket are never deleted. The source should cover every document that uses
+the storage, images referenced from elsewhere are reported as orphans.
+
+Examples:
+  mdctl upload gc -d docs/ --dry-run
+  mdctl upload gc -d docs/ --storage my-s3 --yes`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if gcSourceFile == "" && gcSourceDir == "" {
+			return fmt.Errorf("either source file (-f) or source directory (-d) must be specified")
+		}
+		if gcSourceFile != "" && gcSourceDir != "" {
+			return fmt.Errorf("cannot specify both source file (-f) and source directory (-d)")
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %v", err)
+		}
+		cloudConfig := cfg.GetActiveCloudConfig(gcStorageName)
+		if cloudConfig.Provider == "" {
+			return fmt.Errorf("no storage configured, set one with mdctl config set")
+		}
+		if storage.IsImageHost(strings.ToLower(cloudConfig.Provider)) {
+			return fmt.Errorf("provider %s is an image host and cannot list its images", cloudConfig.Provider)
+		}
+		setDefaultRegion(&cloudConfig, cloudConfig.Provider)
+		if gcCacheDir == "" {
+			gcCacheDir = cloudConfig.CacheDir
+		}
+
+		up, err := uploader.New(uploader.UploaderConfig{
+			SourceFile: gcSourceFile,
+			SourceDir:  gcSourceDir,
+			PathPrefix: gcPathPrefix,
+			CacheDir:   gcCacheDir,
+			Storage:    &cloudConfig,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create uploader: %v", err)
+		}
+
+		result, err := up.FindOrphans(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to find orphaned images: %v", err)
+		}
+
+		if !jsonOutput {
+			for _, key := range result.Orphans {
+				fmt.Println(key)
+			}
+			fmt.Printf("%d of %d images are not referenced\n", len(result.Orphans), result.Objects)
+		}
+		if dryRun || len(result.Orphans) == 0 {
+			if jsonOutput {
+				return printJSON(result)
+			}
+			return nil
+		}
+
+		if !gcYes {
+			if jsonOutput {
+				return fmt.Errorf("--yes is required to delete images with --json")
+			}
+			fmt.Printf("Delete %d images? [y/N] ", len(result.Orphans))
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+				fmt.Println("Aborted")
+				return nil
+			}
+		}
+
+		err = up.DeleteOrphans(cmd.Context(), result)
+		if jsonOutput {
+			if printErr := printJSON(result); printErr != nil {
+				return printErr
+			}
+		} else {
+			fmt.Printf("Deleted %d images\n", len(result.Deleted))
+		}
+		if err != nil && !interrupted(cmd) {
+			return fmt.Errorf("failed to delete images: %v", err)
+		}
+		return err
+	},
+}
+
+// hasExtension reports whether a file has one of the extensions, .md and
+// .markdown when none are given
+func hasExtension(file string, exts []string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+	if len(exts) == 0 {
+		return ext == ".md" || ext == ".markdown"
+	}
+	for _, allowed := range exts {
+		if ext == "."+strings.TrimPrefix(strings.ToLower(allowed), ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// assetsKeysFor returns the front matter fields referencing images: those of
+// the --assets-key flag, or assets_keys of the nearest project configuration
+// of the source directory
+func assetsKeysFor(cmd *cobra.Command, flagKeys []string, dir string) ([]string, error) {
+	if cmd.Flags().Changed("assets-key") {
+		return flagKeys, nil
+	}
+	return config.FindAssetsKeys(dir)
+}
+
+// setDefaultRegion sets the region S3-compatible services require when none is configured
+func setDefaultRegion(cloudConfig *config.CloudConfig, provider string) {
+	if cloudConfig.Region != "" {
+		return
+	}
+	switch strings.ToLower(provider) {
+	case "s3":
+		// For AWS S3, default to us-east-1
+		cloudConfig.Region = "us-east-1"
+	case "r2", "minio":
+		// For S3-compatible services, region can be any value but must be provided
+		cloudConfig.Region = "auto"
+	}
+}
+
 func init() {
 	// Add flags
 	uploadCmd.Flags().StringVarP(&uploadSourceFile, "file", "f", "", "Source markdown file to process")
 	uploadCmd.Flags().StringVarP(&uploadSourceDir, "dir", "d", "", "Source directory containing markdown files to process")
-	uploadCmd.Flags().StringVarP(&uploadProvider, "provider", "p", "", "Cloud storage provider (s3, r2, minio)")
+	uploadCmd.Flags().StringVarP(&uploadProvider, "provider", "p", "", "Cloud storage provider (s3, r2, minio, b2, spaces, wasabi, oss, cos, kodo, imgur, smms, picgo)")
 	uploadCmd.Flags().StringVarP(&uploadBucket, "bucket", "b", "", "Cloud storage bucket name")
 	uploadCmd.Flags().StringVarP(&uploadCustomDomain, "custom-domain", "c", "", "Custom domain for generated URLs")
 	uploadCmd.Flags().StringVar(&uploadPathPrefix, "prefix", "", "Path prefix for uploaded files")
-	uploadCmd.Flags().BoolVar(&uploadDryRun, "dry-run", false, "Preview changes without uploading")
 	uploadCmd.Flags().IntVar(&uploadConcurrency, "concurrency", 5, "Number of concurrent uploads")
 	uploadCmd.Flags().BoolVarP(&uploadForceUpload, "force", "F", false, "Force upload even if file exists")
 	uploadCmd.Flags().BoolVar(&uploadSkipVerify, "skip-verify", false, "Skip SSL verification")
 	uploadCmd.Flags().StringVar(&uploadCACertPath, "ca-cert", "", "Path to CA certificate")
 	uploadCmd.Flags().StringVar(&uploadConflictPolicy, "conflict", "rename", "Conflict policy (rename, version, overwrite)")
 	uploadCmd.Flags().StringVar(&uploadCacheDir, "cache-dir", "", "Cache directory path")
-	uploadCmd.Flags().StringVar(&uploadIncludeExts, "include", "", "Comma-separated list of file extensions to include")
+	uploadCmd.Flags().StringVar(&uploadExtensions, "ext", "", "Comma-separated list of markdown file extensions (default md,markdown)")
+	uploadCmd.Flags().StringSliceVar(&uploadInclude, "include", nil, "Glob patterns for markdown files to process, relative to the directory (can be specified multiple times)")
+	uploadCmd.Flags().StringSliceVar(&uploadExclude, "exclude", nil, "Glob patterns for markdown files to skip, relative to the directory (can be specified multiple times)")
 	uploadCmd.Flags().StringVar(&uploadStorageName, "storage", "", "Storage name to use")
+	uploadCmd.Flags().StringVar(&uploadAltText, "alt-text", "", "Fill empty alt text of rewritten images from the file name or an AI caption (filename, ai)")
+	uploadCmd.Flags().StringVar(&uploadLinkStyle, "link-style", uploader.LinkStyleInline, "Style of the rewritten images (inline, html, reference)")
+	uploadCmd.Flags().StringVar(&uploadImageWidth, "image-width", "", "Width attribute of html images in pixels or percent, e.g. 600 or 80%")
+	uploadCmd.Flags().StringSliceVar(&uploadAssetsKeys, "assets-key", nil, "Front matter field referencing an image, e.g. cover or cover.image (default: assets_keys of .mdctl.yaml)")
+	uploadCmd.Flags().StringVar(&uploadProvenance, "provenance", "", "Record the original paths of rewritten images in the front matter or a sidecar file (frontmatter, sidecar)")
+	registerCompletion(uploadCmd, "storage", completeStorages)
+	registerCompletion(uploadCmd, "alt-text", cobra.FixedCompletions([]string{uploader.AltTextFilename, uploader.AltTextAI}, cobra.ShellCompDirectiveNoFileComp))
+	registerCompletion(uploadCmd, "link-style", cobra.FixedCompletions([]string{uploader.LinkStyleInline, uploader.LinkStyleHTML, uploader.LinkStyleReference}, cobra.ShellCompDirectiveNoFileComp))
+	registerCompletion(uploadCmd, "provenance", cobra.FixedCompletions([]string{uploader.ProvenanceFrontMatter, uploader.ProvenanceSidecar}, cobra.ShellCompDirectiveNoFileComp))
+	addChangedFlags(uploadCmd)
+
+	uploadGCCmd.Flags().StringVarP(&gcSourceFile, "file", "f", "", "Markdown file referencing the images")
+	uploadGCCmd.Flags().StringVarP(&gcSourceDir, "dir", "d", "", "Directory of the markdown files referencing the images")
+	uploadGCCmd.Flags().StringVar(&gcStorageName, "storage", "", "Storage name to use")
+	uploadGCCmd.Flags().StringVar(&gcPathPrefix, "prefix", "", "Path prefix to collect, defaults to the configured prefix")
+	uploadGCCmd.Flags().StringVar(&gcCacheDir, "cache-dir", "", "Cache directory path")
+	uploadGCCmd.Flags().BoolVarP(&gcYes, "yes", "y", false, "Delete without asking for confirmation")
+	registerCompletion(uploadGCCmd, "storage", completeStorages)
+	uploadCmd.AddCommand(uploadGCCmd)
+}
+
+// captionImage returns a function describing local images with the
+// configured AI model, for --alt-text ai
+func captionImage(ctx context.Context, cfg *config.Config) func(string) (string, error) {
+	t := translator.New(cfg, false).WithContext(ctx)
+	return func(path string) (string, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		// Vision models take raster images only, SVG is detected as text
+		mimeType := http.DetectContentType(data)
+		if !strings.HasPrefix(mimeType, "image/") {
+			return "", fmt.Errorf("unsupported image type %s", mimeType)
+		}
+		return t.Caption(data, mimeType)
+	}
 }
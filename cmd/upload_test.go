@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/storage"
+)
+
+// configuredStorage records the settings the recording provider was
+// configured with
+var configuredStorage config.CloudConfig
+
+// recordingProvider uploads nothing and remembers its configuration
+type recordingProvider struct{}
+
+func (recordingProvider) Upload(localPath, remotePath string, metadata map[string]string) (string, error) {
+	return "https://cdn.example.com/" + remotePath, nil
+}
+func (recordingProvider) Configure(cfg config.CloudConfig) error {
+	configuredStorage = cfg
+	return nil
+}
+func (recordingProvider) GetPublicURL(remotePath string) string {
+	return "https://cdn.example.com/" + remotePath
+}
+func (recordingProvider) ObjectExists(remotePath string) (bool, error)      { return false, nil }
+func (recordingProvider) CompareHash(remotePath, hash string) (bool, error) { return false, nil }
+func (recordingProvider) SetObjectMetadata(remotePath string, metadata map[string]string) error {
+	return nil
+}
+func (recordingProvider) GetObjectMetadata(remotePath string) (map[string]string, error) {
+	return nil, nil
+}
+
+func TestUploadUsesNamedStorage(t *testing.T) {
+	storage.RegisterProvider("recording", func() storage.Provider { return recordingProvider{} })
+	defer func() {
+		dryRun, configFile = false, ""
+		uploadSourceFile, uploadStorageName, uploadProvider, uploadBucket = "", "", "", ""
+		uploadCustomDomain, uploadPathPrefix, uploadCacheDir = "", "", ""
+		config.SetConfigPath("")
+	}()
+
+	dir := t.TempDir()
+	cfg := `{
+  "default_storage": "main",
+  "cloud_storages": {
+    "main": {"provider": "recording", "bucket": "main-bucket", "access_key": "main-key", "secret_key": "main-secret", "endpoint": "https://main.example.com", "region": "us-east-1"},
+    "archive": {"provider": "recording", "bucket": "archive-bucket", "access_key": "archive-key", "secret_key": "archive-secret", "endpoint": "https://archive.example.com", "region": "auto"}
+  }
+}`
+	for name, content := range map[string]string{
+		"config.json": cfg,
+		"post.md":     "# Post\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv(config.ConfigEnv, "")
+
+	runStream(t, "", "--config", filepath.Join(dir, "config.json"), "upload", "-f", filepath.Join(dir, "post.md"),
+		"--storage", "archive", "--cache-dir", filepath.Join(dir, "cache"), "--dry-run")
+	if configuredStorage.Bucket != "archive-bucket" || configuredStorage.AccessKey != "archive-key" || configuredStorage.SecretKey != "archive-secret" ||
+		configuredStorage.Endpoint != "https://archive.example.com" || configuredStorage.Region != "auto" {
+		t.Errorf("expected the archive storage, got %+v", configuredStorage)
+	}
+}
@@ -0,0 +1,173 @@
+// Package anchors computes heading anchors the way static site generators do
+// and keeps the links pointing at them intact.
+package anchors
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/samzong/mdctl/internal/mddoc"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Slug styles
+const (
+	// StyleGitHub lowercases, keeps letters, digits, spaces, hyphens and
+	// underscores and turns spaces into hyphens (GitHub, Docusaurus, Hugo)
+	StyleGitHub = "github"
+	// StyleMkDocs is the Python-Markdown toc slugify: ASCII only, runs of
+	// spaces and hyphens become one hyphen, duplicates get _1, _2...
+	StyleMkDocs = "mkdocs"
+)
+
+var (
+	// headingRegex matches ATX headings with an optional {#id} attribute list
+	headingRegex = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)(?:[ \t]+\{[^}]*#([\w-]+)[^}]*\})?(?:[ \t]+#+)?[ \t]*$`)
+	// closingRegex matches the optional closing sequence of an ATX heading
+	closingRegex = regexp.MustCompile(`[ \t]+#+$`)
+	// inlineLinkRegex matches inline links and images, keeping their text
+	inlineLinkRegex = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	// inlineMarkupRegex matches emphasis and code markers
+	inlineMarkupRegex = regexp.MustCompile("[*_`~]+")
+	// htmlTagRegex matches inline HTML tags
+	htmlTagRegex = regexp.MustCompile(`<[^>]+>`)
+	// mkdocsStripRegex and mkdocsHyphenRegex implement Python-Markdown's slugify
+	mkdocsStripRegex  = regexp.MustCompile(`[^\w\s-]`)
+	mkdocsHyphenRegex = regexp.MustCompile(`[-\s]+`)
+)
+
+// Heading is an ATX heading and its anchor
+type Heading struct {
+	Line     int    // Line number, starting at 1
+	Level    int    // 1 to 6
+	Text     string // Heading text without the attribute list
+	ID       string // Anchor of the heading
+	Explicit bool   // ID comes from a {#id} attribute
+}
+
+// ValidStyle reports an error for unknown slug styles
+func ValidStyle(style string) error {
+	if style != StyleGitHub && style != StyleMkDocs {
+		return fmt.Errorf("unsupported slug style: %s (must be github or mkdocs)", style)
+	}
+	return nil
+}
+
+// Slug returns the anchor a heading text gets in a slug style
+func Slug(text, style string) string {
+	text = plainText(text)
+	if style == StyleMkDocs {
+		var ascii strings.Builder
+		for _, r := range norm.NFKD.String(text) {
+			if r < unicode.MaxASCII {
+				ascii.WriteRune(r)
+			}
+		}
+		slug := strings.ToLower(strings.TrimSpace(mkdocsStripRegex.ReplaceAllString(ascii.String(), "")))
+		return mkdocsHyphenRegex.ReplaceAllString(slug, "-")
+	}
+
+	var sb strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || r == '-' || r == '_':
+			sb.WriteRune(r)
+		case r == ' ':
+			sb.WriteByte('-')
+		}
+	}
+	return sb.String()
+}
+
+// plainText strips links, inline markup and HTML from heading text
+func plainText(text string) string {
+	text = inlineLinkRegex.ReplaceAllString(text, "$1")
+	text = htmlTagRegex.ReplaceAllString(text, "")
+	return inlineMarkupRegex.ReplaceAllString(text, "")
+}
+
+// ParseHeadings returns the headings of a markdown document with their
+// anchors, duplicate slugs are numbered the way the slug style does
+func ParseHeadings(content, style string) []Heading {
+	var headings []Heading
+	seen := make(map[string]int)
+
+	forEachLine(content, func(i int, line string) {
+		m := headingRegex.FindStringSubmatch(line)
+		if m == nil {
+			return
+		}
+		h := Heading{Line: i + 1, Level: len(m[1]), Text: m[2], ID: m[3], Explicit: m[3] != ""}
+		if !h.Explicit {
+			h.ID = uniqueID(Slug(h.Text, style), style, seen)
+		} else {
+			seen[h.ID]++
+		}
+		headings = append(headings, h)
+	})
+	return headings
+}
+
+// uniqueID numbers a slug that was already used in the document
+func uniqueID(slug, style string, seen map[string]int) string {
+	id := slug
+	sep := "-"
+	if style == StyleMkDocs {
+		sep = "_"
+	}
+	for n := seen[slug]; seen[id] > 0; n++ {
+		id = fmt.Sprintf("%s%s%d", slug, sep, n)
+	}
+	seen[slug]++
+	if id != slug {
+		seen[id]++
+	}
+	return id
+}
+
+// AddIDs appends an explicit {#id} to every heading that has none, freezing
+// its current anchor so later edits of the text keep deep links working. It
+// returns the new content and the number of headings changed.
+func AddIDs(content, style string) (string, int) {
+	headings := ParseHeadings(content, style)
+	byLine := make(map[int]Heading)
+	for _, h := range headings {
+		if !h.Explicit && h.ID != "" {
+			byLine[h.Line] = h
+		}
+	}
+	if len(byLine) == 0 {
+		return content, 0
+	}
+
+	lines := strings.Split(content, "\n")
+	for line, h := range byLine {
+		// Closing hashes are dropped, attribute lists must end the line
+		text := closingRegex.ReplaceAllString(strings.TrimRight(lines[line-1], " \t\r"), "")
+		lines[line-1] = fmt.Sprintf("%s {#%s}", text, h.ID)
+	}
+	return strings.Join(lines, "\n"), len(byLine)
+}
+
+// forEachLine calls fn for every line outside fenced code blocks and front matter
+func forEachLine(content string, fn func(i int, line string)) {
+	lines := strings.Split(content, "\n")
+	start := 0
+	if len(lines) > 0 && strings.TrimRight(lines[0], "\r") == "---" {
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimRight(lines[i], "\r") == "---" {
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	var fences mddoc.Fences
+	for i := start; i < len(lines); i++ {
+		if !fences.Line(lines[i]) {
+			fn(i, lines[i])
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package anchors
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		text, style, want string
+	}{
+		{"Getting Started", StyleGitHub, "getting-started"},
+		{"What's `new` in **v1.2**?", StyleGitHub, "whats-new-in-v12"},
+		{"Foo -- Bar", StyleGitHub, "foo----bar"},
+		{"Foo -- Bar", StyleMkDocs, "foo-bar"},
+		{"Café & [Links](x.md)", StyleMkDocs, "cafe-links"},
+		{"中文 标题", StyleGitHub, "中文-标题"},
+	}
+	for _, tt := range tests {
+		if got := Slug(tt.text, tt.style); got != tt.want {
+			t.Errorf("Slug(%q, %s) = %q, want %q", tt.text, tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestParseHeadings(t *testing.T) {
+	content := "# Intro\n\n## Setup\n\n```\n## Not a heading\n```\n\n## Setup\n\n## Custom {#my-id}\n\n## C#\n"
+	want := []string{"intro", "setup", "setup-1", "my-id", "c"}
+
+	headings := ParseHeadings(content, StyleGitHub)
+	if len(headings) != len(want) {
+		t.Fatalf("got %d headings, want %d", len(headings), len(want))
+	}
+	for i, h := range headings {
+		if h.ID != want[i] {
+			t.Errorf("heading %d: got %q, want %q", i, h.ID, want[i])
+		}
+	}
+	if got := ParseHeadings("## Setup\n## Setup\n", StyleMkDocs)[1].ID; got != "setup_1" {
+		t.Errorf("mkdocs duplicate: got %q, want setup_1", got)
+	}
+
+	added, n := AddIDs(content, StyleGitHub)
+	if n != 4 || !strings.Contains(added, "## Setup {#setup-1}") || !strings.Contains(added, "## Custom {#my-id}\n") || !strings.Contains(added, "## C# {#c}") {
+		t.Errorf("AddIDs changed %d headings:\n%s", n, added)
+	}
+}
+
+func TestCheckAndRename(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"guide/install.md": "# Install\n\n## Linux\n\n## Linux\n",
+		"index.md":         "See [install](guide/install.md#installing-on-linux) and [intro](#intro).\n\n# Intro\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	problems, err := Check(dir, StyleGitHub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 2 || problems[0].Kind != "duplicate" || problems[1].Kind != "broken-link" || problems[1].Anchor != "installing-on-linux" {
+		t.Fatalf("unexpected problems: %+v", problems)
+	}
+
+	changes, err := Rename(dir, []Mapping{{File: "guide/install.md", From: "installing-on-linux", To: "linux"}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(changes))
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "index.md"))
+	if !strings.Contains(string(data), "(guide/install.md#linux)") {
+		t.Errorf("link not rewritten:\n%s", data)
+	}
+}
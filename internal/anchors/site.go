@@ -0,0 +1,294 @@
+package anchors
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"gopkg.in/yaml.v3"
+)
+
+// skipDirs are never descended into
+var skipDirs = map[string]bool{
+	".git":         true,
+	".mdctl":       true,
+	"node_modules": true,
+}
+
+// Problem is an anchor issue found by Check
+type Problem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Kind    string `json:"kind"` // duplicate or broken-link
+	Anchor  string `json:"anchor"`
+	Message string `json:"message"`
+}
+
+// Mapping maps the old anchor of a renamed heading to the new one
+type Mapping struct {
+	File string `yaml:"file" json:"file,omitempty"` // Page of the heading, relative to the root; empty matches every page
+	From string `yaml:"from" json:"from"`
+	To   string `yaml:"to" json:"to"`
+}
+
+// Change is a link rewritten by Rename
+type Change struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// FileChange is a file that had explicit IDs added
+type FileChange struct {
+	File     string `json:"file"`
+	Headings int    `json:"headings"`
+}
+
+// page is a markdown file of a site
+type page struct {
+	abs     string
+	rel     string
+	content string
+}
+
+// site is the set of markdown files below a root
+type site struct {
+	base  string
+	pages []*page
+	byAbs map[string]*page
+}
+
+// loadSite reads the markdown files below root, which may also be a single file
+func loadSite(root string) (*site, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %v", err)
+	}
+	info, err := os.Stat(absRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access %s: %v", root, err)
+	}
+
+	s := &site{base: absRoot, byAbs: make(map[string]*page)}
+	var files []string
+	if info.IsDir() {
+		err = filepath.Walk(absRoot, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path != absRoot && skipDirs[info.Name()] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if mddoc.IsMarkdown(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk %s: %v", root, err)
+		}
+	} else {
+		s.base = filepath.Dir(absRoot)
+		files = []string{absRoot}
+	}
+
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		rel, err := filepath.Rel(s.base, path)
+		if err != nil {
+			return nil, err
+		}
+		p := &page{abs: path, rel: filepath.ToSlash(rel), content: string(data)}
+		s.pages = append(s.pages, p)
+		s.byAbs[path] = p
+	}
+	return s, nil
+}
+
+// resolve returns the page and fragment a link destination of from points at,
+// the page is nil for links outside the site or to other kinds of files
+func (s *site) resolve(from *page, dest string) (*page, string) {
+	target, fragment, ok := strings.Cut(dest, "#")
+	if !ok || strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:") {
+		return nil, ""
+	}
+	if unescaped, err := url.PathUnescape(fragment); err == nil {
+		fragment = unescaped
+	}
+	if target == "" {
+		return from, fragment
+	}
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	if !mddoc.IsMarkdown(target) || filepath.IsAbs(target) {
+		return nil, ""
+	}
+	return s.byAbs[filepath.Join(filepath.Dir(from.abs), filepath.FromSlash(target))], fragment
+}
+
+// Add appends explicit {#id} attributes to the headings of the markdown files
+// below root that have none, keeping the anchor they currently get
+func Add(root, style string, dryRun bool) ([]FileChange, error) {
+	s, err := loadSite(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FileChange
+	for _, p := range s.pages {
+		content, n := AddIDs(p.content, style)
+		if n == 0 {
+			continue
+		}
+		if !dryRun {
+			if err := fsutil.WriteFileAtomic(p.abs, []byte(content), 0644); err != nil {
+				return changes, err
+			}
+		}
+		changes = append(changes, FileChange{File: p.rel, Headings: n})
+	}
+	return changes, nil
+}
+
+// Check reports headings of a page sharing a slug, whose anchors then depend
+// on their order, and links to anchors that do not exist in the target page
+func Check(root, style string) ([]Problem, error) {
+	s, err := loadSite(root)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[*page]map[string]bool)
+	var problems []Problem
+	for _, p := range s.pages {
+		ids[p] = make(map[string]bool)
+		first := make(map[string]int)
+		for _, h := range ParseHeadings(p.content, style) {
+			ids[p][h.ID] = true
+			slug := h.ID
+			if !h.Explicit {
+				slug = Slug(h.Text, style)
+			}
+			if line, ok := first[slug]; ok {
+				problems = append(problems, Problem{
+					File:    p.rel,
+					Line:    h.Line,
+					Kind:    "duplicate",
+					Anchor:  slug,
+					Message: fmt.Sprintf("heading %q has the same slug as line %d", strings.TrimSpace(h.Text), line),
+				})
+				continue
+			}
+			first[slug] = h.Line
+		}
+	}
+
+	for _, p := range s.pages {
+		for _, link := range pageLinks(mddoc.Parse([]byte(p.content))) {
+			target, fragment := s.resolve(p, link.Destination)
+			if target == nil || fragment == "" || ids[target][fragment] {
+				continue
+			}
+			problems = append(problems, Problem{
+				File:    p.rel,
+				Line:    link.Line,
+				Kind:    "broken-link",
+				Anchor:  fragment,
+				Message: fmt.Sprintf("%s has no heading with anchor #%s", target.rel, fragment),
+			})
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	return problems, nil
+}
+
+// LoadRenames reads a YAML rename mapping, a list of file, from and to entries
+func LoadRenames(path string) ([]Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rename mapping: %v", err)
+	}
+	var renames []Mapping
+	if err := yaml.Unmarshal(data, &renames); err != nil {
+		return nil, fmt.Errorf("failed to parse rename mapping %s: %v", path, err)
+	}
+	for i, r := range renames {
+		if r.From == "" || r.To == "" {
+			return nil, fmt.Errorf("rename %d in %s: from and to are required", i+1, path)
+		}
+		renames[i].From = strings.TrimPrefix(r.From, "#")
+		renames[i].To = strings.TrimPrefix(r.To, "#")
+		if r.File != "" {
+			renames[i].File = filepath.ToSlash(filepath.Clean(r.File))
+		}
+	}
+	return renames, nil
+}
+
+// Rename rewrites the fragments of links below root that point at renamed
+// headings, so deep links keep working after the heading text changed
+func Rename(root string, renames []Mapping, dryRun bool) ([]Change, error) {
+	s, err := loadSite(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for _, p := range s.pages {
+		var edits []mddoc.Edit
+		doc := mddoc.Parse([]byte(p.content))
+		for _, link := range pageLinks(doc) {
+			target, fragment := s.resolve(p, link.Destination)
+			if target == nil {
+				continue
+			}
+			for _, r := range renames {
+				if r.From != fragment || (r.File != "" && r.File != target.rel) {
+					continue
+				}
+				dest := link.Destination[:strings.Index(link.Destination, "#")+1] + r.To
+				changes = append(changes, Change{File: p.rel, Line: link.Line, Old: link.Destination, New: dest})
+				edits = append(edits, mddoc.Edit{Start: link.Start, End: link.End, Text: dest})
+				break
+			}
+		}
+
+		if len(edits) > 0 && !dryRun {
+			if err := fsutil.WriteFileAtomic(p.abs, doc.Apply(edits), 0644); err != nil {
+				return changes, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+// pageLinks returns the links of a page that may point at a heading, inline
+// links and link reference definitions outside code
+func pageLinks(doc *mddoc.Document) []mddoc.Link {
+	var links []mddoc.Link
+	for _, link := range doc.Links() {
+		if link.Kind == mddoc.InlineLink || link.Kind == mddoc.Definition {
+			links = append(links, link)
+		}
+	}
+	return links
+}
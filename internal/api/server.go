@@ -0,0 +1,282 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/linter"
+	"github.com/samzong/mdctl/internal/llmstxt"
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/markdownfmt"
+	"github.com/samzong/mdctl/internal/translator"
+)
+
+// DefaultMaxBodySize limits request bodies, matching the linter's file size limit
+const DefaultMaxBodySize = 10 * 1024 * 1024
+
+var logger = logging.New("API")
+
+// Options configures the HTTP API server
+type Options struct {
+	APIKeys     []string       // Accepted API keys, empty disables authentication
+	MaxBodySize int64          // Maximum request body size in bytes
+	Config      *config.Config // Configuration used for AI translation
+	UserAgent   string         // User agent for llms.txt fetches
+}
+
+// Server exposes mdctl operations as a REST API
+type Server struct {
+	opts Options
+}
+
+// New creates an API server
+func New(opts Options) *Server {
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = DefaultMaxBodySize
+	}
+	return &Server{opts: opts}
+}
+
+// Handler returns the HTTP handler serving all endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.Handle("/v1/lint", s.authenticated(s.handleLint))
+	mux.Handle("/v1/format", s.authenticated(s.handleFormat))
+	mux.Handle("/v1/translate", s.authenticated(s.handleTranslate))
+	mux.Handle("/v1/llmstxt", s.authenticated(s.handleLLMsTxt))
+	return mux
+}
+
+// ListenAndServe serves the API on addr until ctx is cancelled
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		logger.Infof("Shutting down API server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// authenticated wraps a POST handler with API key checks and the body size limit
+func (s *Server) authenticated(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		if len(s.opts.APIKeys) > 0 && !s.validKey(requestKey(r)) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "missing or invalid API key")
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, s.opts.MaxBodySize)
+		next(w, r)
+	})
+}
+
+// requestKey extracts the API key from the Authorization or X-API-Key header
+func requestKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// validKey compares key against the configured keys in constant time
+func (s *Server) validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	valid := false
+	for _, k := range s.opts.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// lintRequest is the body of POST /v1/lint
+type lintRequest struct {
+	Content      string   `json:"content"`
+	Filename     string   `json:"filename"`
+	Fix          bool     `json:"fix"`
+	EnableRules  []string `json:"enable_rules"`
+	DisableRules []string `json:"disable_rules"`
+}
+
+func (s *Server) handleLint(w http.ResponseWriter, r *http.Request) {
+	var req lintRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Filename == "" {
+		req.Filename = "content.md"
+	}
+
+	l := linter.New(&linter.Config{
+		EnableRules:  req.EnableRules,
+		DisableRules: req.DisableRules,
+	})
+
+	if !req.Fix {
+		result, err := l.LintContent(req.Filename, req.Content)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+
+	result, fixed := l.FixContent(req.Filename, req.Content)
+	writeJSON(w, http.StatusOK, struct {
+		*linter.Result
+		FixedContent string `json:"fixed_content"`
+	}{Result: result, FixedContent: fixed})
+}
+
+// contentRequest is the body of POST /v1/format and /v1/translate
+type contentRequest struct {
+	Content  string `json:"content"`
+	Language string `json:"language"`
+	Format   bool   `json:"format"`
+}
+
+// contentResponse returns transformed markdown
+type contentResponse struct {
+	Content string `json:"content"`
+}
+
+func (s *Server) handleFormat(w http.ResponseWriter, r *http.Request) {
+	var req contentRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	writeJSON(w, http.StatusOK, contentResponse{Content: markdownfmt.New(true).Format(req.Content)})
+}
+
+func (s *Server) handleTranslate(w http.ResponseWriter, r *http.Request) {
+	var req contentRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if !translator.IsLanguageSupported(req.Language) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unsupported language: %s (supported: %s)",
+			req.Language, translator.GetSupportedLanguages()))
+		return
+	}
+	if s.opts.Config == nil {
+		writeError(w, http.StatusServiceUnavailable, "translation is not configured")
+		return
+	}
+
+	translated, err := translator.New(s.opts.Config, req.Format).WithContext(r.Context()).TranslateContent(req.Content, req.Language)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, contentResponse{Content: translated})
+}
+
+// llmstxtRequest is the body of POST /v1/llmstxt
+type llmstxtRequest struct {
+	SitemapURL   string   `json:"sitemap_url"`
+	Full         bool     `json:"full"`
+	IncludePaths []string `json:"include_paths"`
+	ExcludePaths []string `json:"exclude_paths"`
+	MaxPages     int      `json:"max_pages"`
+	Lang         string   `json:"lang"`
+}
+
+func (s *Server) handleLLMsTxt(w http.ResponseWriter, r *http.Request) {
+	var req llmstxtRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.SitemapURL == "" {
+		writeError(w, http.StatusBadRequest, "sitemap_url is required")
+		return
+	}
+
+	generator := llmstxt.NewGenerator(llmstxt.GeneratorConfig{
+		SitemapURL:   req.SitemapURL,
+		IncludePaths: req.IncludePaths,
+		ExcludePaths: req.ExcludePaths,
+		FullMode:     req.Full,
+		Concurrency:  5,
+		Timeout:      30,
+		UserAgent:    s.opts.UserAgent,
+		MaxPages:     req.MaxPages,
+		Lang:         req.Lang,
+	})
+	content, err := generator.Generate(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Content string        `json:"content"`
+		Stats   llmstxt.Stats `json:"stats"`
+	}{Content: content, Stats: generator.Stats()})
+}
+
+// decode reads a JSON request body, writing an error response on failure
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+			return false
+		}
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return false
+	}
+	return true
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		logger.Warnf("Failed to write response: %v", err)
+	}
+}
+
+// writeError writes an error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
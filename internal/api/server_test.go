@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer_AuthAndLimits(t *testing.T) {
+	srv := httptest.NewServer(New(Options{APIKeys: []string{"secret"}, MaxBodySize: 64}).Handler())
+	defer srv.Close()
+
+	post := func(path, key, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := post("/v1/format", "", `{"content":"#A"}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without key, got %d", resp.StatusCode)
+	}
+	if resp := post("/v1/format", "wrong", `{"content":"#A"}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 with wrong key, got %d", resp.StatusCode)
+	}
+	if resp := post("/v1/format", "secret", `{"content":"#A"}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 with valid key, got %d", resp.StatusCode)
+	}
+	if resp := post("/v1/lint", "secret", `{"content":"`+strings.Repeat("a", 100)+`"}`); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for oversized body, got %d", resp.StatusCode)
+	}
+
+	resp, err := http.Get(srv.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected healthz without auth, got %d", resp.StatusCode)
+	}
+}
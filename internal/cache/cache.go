@@ -4,43 +4,71 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/samzong/mdctl/internal/fsutil"
 )
 
+// cacheVersion is the version of the cache file, version 1.0 kept the items
+// by local path and 2.0 by hash alone
+const cacheVersion = "3.0"
+
+// Target is the storage a content was uploaded to
+type Target struct {
+	Provider   string `json:"provider,omitempty"`
+	Bucket     string `json:"bucket,omitempty"`
+	PathPrefix string `json:"path_prefix,omitempty"`
+}
+
+// key returns the key of a content uploaded to the target
+func (t Target) key(hash string) string {
+	return t.Provider + ":" + path.Join(t.Bucket, strings.Trim(t.PathPrefix, "/"), hash)
+}
+
 // CacheItem represents a single cached file information
 type CacheItem struct {
-	LocalPath  string    `json:"local_path"`
+	Target
+	LocalPath  string    `json:"local_path"` // Path the content was uploaded from
 	RemotePath string    `json:"remote_path"`
 	URL        string    `json:"url"`
 	Hash       string    `json:"hash"`
 	UploadTime time.Time `json:"upload_time"`
 }
 
-// Cache manages information about uploaded files
+// Cache manages information about uploaded files. The items are kept by
+// storage and content hash, so an image is found again when it is referenced
+// from another repository or the checkout moved, but uploaded again to
+// another provider, bucket or path prefix.
 type Cache struct {
-	Items    map[string]CacheItem `json:"items"`
+	Items    map[string]CacheItem `json:"items"` // Items by target and hash, by local path when they have no hash
 	Version  string               `json:"version"`
 	CacheDir string               `json:"cache_dir,omitempty"`
 	mutex    sync.RWMutex
 }
 
+// DefaultDir returns the directory shared by all mdctl caches
+func DefaultDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		// Fallback to temp directory
+		return filepath.Join(os.TempDir(), "mdctl-cache")
+	}
+	return filepath.Join(homeDir, ".cache", "mdctl")
+}
+
 // New creates a new cache instance
 func New(cacheDir string) *Cache {
 	if cacheDir == "" {
-		homeDir, err := os.UserHomeDir()
-		if err == nil {
-			cacheDir = filepath.Join(homeDir, ".cache", "mdctl")
-		} else {
-			// Fallback to temp directory
-			cacheDir = filepath.Join(os.TempDir(), "mdctl-cache")
-		}
+		cacheDir = DefaultDir()
 	}
 
 	return &Cache{
 		Items:    make(map[string]CacheItem),
-		Version:  "1.0",
+		Version:  cacheVersion,
 		CacheDir: cacheDir,
 	}
 }
@@ -59,7 +87,7 @@ func (c *Cache) saveWithoutLock() error {
 		return fmt.Errorf("failed to marshal cache: %v", err)
 	}
 
-	if err := os.WriteFile(cacheFile, data, 0644); err != nil {
+	if err := fsutil.WriteFileAtomic(cacheFile, data, 0644); err != nil {
 		return fmt.Errorf("failed to write cache file: %v", err)
 	}
 
@@ -91,12 +119,35 @@ func (c *Cache) Load() error {
 	if err := json.Unmarshal(data, c); err != nil {
 		// If cache is corrupt, start with a fresh one
 		c.Items = make(map[string]CacheItem)
+		c.Version = cacheVersion
 		return nil
 	}
+	c.migrate()
 
 	return nil
 }
 
+// migrate keys the items of an older cache by their hash, the latest upload
+// of a content wins. Older items do not record their storage, they keep an
+// empty target and are never reused.
+func (c *Cache) migrate() {
+	if c.Version == cacheVersion {
+		return
+	}
+	items := make(map[string]CacheItem, len(c.Items))
+	for key, item := range c.Items {
+		if item.Hash != "" {
+			key = item.key(item.Hash)
+		}
+		if prev, ok := items[key]; ok && prev.UploadTime.After(item.UploadTime) {
+			continue
+		}
+		items[key] = item
+	}
+	c.Items = items
+	c.Version = cacheVersion
+}
+
 // Save persists the cache to disk
 func (c *Cache) Save() error {
 	c.mutex.Lock()
@@ -105,12 +156,17 @@ func (c *Cache) Save() error {
 	return c.saveWithoutLock() // Use the lockless version to avoid deadlock
 }
 
-// AddItem adds or updates a cache item
-func (c *Cache) AddItem(localPath, remotePath, url, hash string) {
+// AddItem adds or updates the cache item of a content uploaded to target
+func (c *Cache) AddItem(target Target, localPath, remotePath, url, hash string) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	c.Items[localPath] = CacheItem{
+	key := localPath
+	if hash != "" {
+		key = target.key(hash)
+	}
+	c.Items[key] = CacheItem{
+		Target:     target,
 		LocalPath:  localPath,
 		RemotePath: remotePath,
 		URL:        url,
@@ -119,32 +175,41 @@ func (c *Cache) AddItem(localPath, remotePath, url, hash string) {
 	}
 }
 
-// GetItem retrieves a cache item by local path
+// GetItem retrieves the latest cache item uploaded from a local path
 func (c *Cache) GetItem(localPath string) (CacheItem, bool) {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
-	item, exists := c.Items[localPath]
-	return item, exists
+	var found CacheItem
+	exists := false
+	for _, item := range c.Items {
+		if item.LocalPath == localPath && (!exists || item.UploadTime.After(found.UploadTime)) {
+			found, exists = item, true
+		}
+	}
+	return found, exists
 }
 
-// HasItemWithHash checks if an item with the same hash exists
-func (c *Cache) HasItemWithHash(hash string) (CacheItem, bool) {
+// HasItemWithHash checks if an item with the same hash was uploaded to target
+func (c *Cache) HasItemWithHash(target Target, hash string) (CacheItem, bool) {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
-	for _, item := range c.Items {
-		if item.Hash == hash {
-			return item, true
-		}
+	if hash == "" {
+		return CacheItem{}, false
 	}
-	return CacheItem{}, false
+	item, exists := c.Items[target.key(hash)]
+	return item, exists
 }
 
-// RemoveItem removes an item from the cache
+// RemoveItem removes the items uploaded from a local path
 func (c *Cache) RemoveItem(localPath string) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	delete(c.Items, localPath)
+	for key, item := range c.Items {
+		if item.LocalPath == localPath {
+			delete(c.Items, key)
+		}
+	}
 }
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheByHash(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir)
+	target := Target{Provider: "s3", Bucket: "docs", PathPrefix: "img/"}
+	c.AddItem(target, "/repo-a/img/logo.png", "logo_abcd1234.png", "https://cdn.example.com/logo_abcd1234.png", "abcd1234")
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded := New(dir)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	// The same content at another path is found by its hash
+	item, ok := loaded.HasItemWithHash(Target{Provider: "s3", Bucket: "docs", PathPrefix: "/img"}, "abcd1234")
+	if !ok || item.URL != "https://cdn.example.com/logo_abcd1234.png" || item.LocalPath != "/repo-a/img/logo.png" || item.Target != target {
+		t.Errorf("unexpected item %+v, %v", item, ok)
+	}
+	// Uploads to other storage are not reused
+	for _, other := range []Target{
+		{Provider: "r2", Bucket: "docs", PathPrefix: "img/"},
+		{Provider: "s3", Bucket: "blog", PathPrefix: "img/"},
+		{Provider: "s3", Bucket: "docs"},
+	} {
+		if _, ok := loaded.HasItemWithHash(other, "abcd1234"); ok {
+			t.Errorf("expected no item for %+v", other)
+		}
+	}
+	if _, ok := loaded.GetItem("/repo-a/img/logo.png"); !ok {
+		t.Errorf("expected the item by its local path")
+	}
+	loaded.RemoveItem("/repo-a/img/logo.png")
+	if _, ok := loaded.HasItemWithHash(target, "abcd1234"); ok {
+		t.Errorf("expected the item to be removed")
+	}
+}
+
+func TestCacheMigratesPathKeys(t *testing.T) {
+	dir := t.TempDir()
+	old := `{
+  "items": {
+    "/a/logo.png": {"local_path": "/a/logo.png", "url": "https://cdn.example.com/old.png", "hash": "h1", "upload_time": "2024-01-01T00:00:00Z"},
+    "/b/logo.png": {"local_path": "/b/logo.png", "url": "https://cdn.example.com/new.png", "hash": "h1", "upload_time": "2024-02-01T00:00:00Z"},
+    "/c/chart.png": {"local_path": "/c/chart.png", "url": "https://cdn.example.com/chart.png", "hash": "h2", "upload_time": "2024-01-01T00:00:00Z"}
+  },
+  "version": "1.0"
+}`
+	os.WriteFile(filepath.Join(dir, "upload-cache.json"), []byte(old), 0644)
+
+	c := New(dir)
+	if err := c.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if c.Version != cacheVersion || len(c.Items) != 2 {
+		t.Fatalf("expected 2 items of version %s, got %d of %s", cacheVersion, len(c.Items), c.Version)
+	}
+	if item, ok := c.HasItemWithHash(Target{}, "h1"); !ok || item.URL != "https://cdn.example.com/new.png" {
+		t.Errorf("expected the latest upload of a content, got %+v", item)
+	}
+	// Their storage is unknown, so they are not reused for uploads
+	if _, ok := c.HasItemWithHash(Target{Provider: "s3", Bucket: "docs"}, "h1"); ok {
+		t.Error("expected migrated items to be kept apart from the uploads to a storage")
+	}
+}
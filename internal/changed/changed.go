@@ -0,0 +1,83 @@
+package changed
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/gitrepo"
+)
+
+// Options selects the changes to report
+type Options struct {
+	Since  string // Files changed between this ref and the working tree
+	Staged bool   // Files staged for the next commit (compared to Since if set)
+}
+
+// Enabled reports whether a change selection was requested
+func (o Options) Enabled() bool {
+	return o.Since != "" || o.Staged
+}
+
+// Files returns the added, copied, modified and renamed files below path, which
+// is a directory or a single file inside a git work tree. Returned paths are
+// joined to path, deleted files are left out. Only files git tracks, staged
+// new files included, are compared.
+func Files(path string, opts Options) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	dir := path
+	if !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+
+	repo, err := gitrepo.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := repo.Rel(path)
+	if err != nil {
+		return nil, err
+	}
+	dirPrefix, err := repo.Rel(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// The staged files are compared to HEAD unless a ref is given
+	base, err := repo.Head()
+	if opts.Since != "" {
+		base, err = repo.Commit(opts.Since)
+	}
+	if err != nil {
+		return nil, err
+	}
+	old, err := gitrepo.TreeFiles(base, prefix)
+	if err != nil {
+		return nil, err
+	}
+	files := repo.WorktreeFiles
+	if opts.Staged {
+		files = repo.IndexFiles
+	}
+	cur, err := files(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for _, name := range gitrepo.Changed(old, cur) {
+		if dirPrefix != "." {
+			name = strings.TrimPrefix(name, dirPrefix+"/")
+		}
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		// Staged files may have been removed from the work tree since
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		changed = append(changed, file)
+	}
+	return changed, nil
+}
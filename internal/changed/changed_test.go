@@ -0,0 +1,94 @@
+package changed
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	add := func(name string) {
+		if _, err := wt.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("docs/a.md", "a\n")
+	write("docs/b.md", "b\n")
+	write("docs/d.md", "d\n")
+	write("README.md", "readme\n")
+	for _, name := range []string{"docs/a.md", "docs/b.md", "docs/d.md", "README.md"} {
+		add(name)
+	}
+	signature := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	if _, err := wt.Commit("initial", &git.CommitOptions{Author: signature}); err != nil {
+		t.Fatal(err)
+	}
+
+	write("docs/a.md", "changed\n")
+	write("README.md", "changed\n")
+	write("docs/c.md", "new\n")
+	write("docs/untracked.md", "untracked\n")
+	add("docs/c.md")
+	if err := os.Remove(filepath.Join(dir, "docs", "d.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	docs := filepath.Join(dir, "docs")
+	tests := []struct {
+		name string
+		path string
+		opts Options
+		want []string
+	}{
+		{"since head", docs, Options{Since: "HEAD"}, []string{filepath.Join(docs, "a.md"), filepath.Join(docs, "c.md")}},
+		{"staged", dir, Options{Staged: true}, []string{filepath.Join(docs, "c.md")}},
+		{"staged since head", docs, Options{Since: "HEAD", Staged: true}, []string{filepath.Join(docs, "c.md")}},
+		{"single file", filepath.Join(dir, "README.md"), Options{Since: "HEAD"}, []string{filepath.Join(dir, "README.md")}},
+		{"unchanged file", filepath.Join(docs, "b.md"), Options{Since: "HEAD"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Files(tt.path, tt.opts)
+			if err != nil {
+				t.Fatalf("Files failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Files(dir, Options{Since: "no-such-ref"}); err == nil {
+		t.Error("expected error for unknown ref")
+	}
+	if _, err := Files(dir, Options{Since: "--output=" + filepath.Join(dir, "out")}); err == nil {
+		t.Error("expected error for a ref starting with -")
+	}
+	if _, err := Files(t.TempDir(), Options{Since: "HEAD"}); err == nil {
+		t.Error("expected error outside a git repository")
+	}
+}
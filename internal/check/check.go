@@ -0,0 +1,354 @@
+// Package check runs the lint, link, front matter and image checks of a
+// documentation directory in one pass and reports their findings together,
+// as the single quality gate of a CI pipeline.
+package check
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/anchors"
+	"github.com/samzong/mdctl/internal/linkgraph"
+	"github.com/samzong/mdctl/internal/linter"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"github.com/samzong/mdctl/internal/schema"
+)
+
+// Checks run by Run
+const (
+	CheckLint        = "lint"
+	CheckLinks       = "links"
+	CheckFrontMatter = "frontmatter"
+	CheckImages      = "images"
+)
+
+// Checks lists the checks in the order they run
+var Checks = []string{CheckLint, CheckLinks, CheckFrontMatter, CheckImages}
+
+// Rules of the link and image checks, lint and front matter findings keep
+// the rule of the linter
+const (
+	RuleBrokenLink    = "LINK001"
+	RuleBrokenAnchor  = "LINK002"
+	RuleDuplicateSlug = "LINK003"
+	RuleMissingImage  = "IMG001"
+	RuleLargeImage    = "IMG002"
+)
+
+// RuleDescriptions describes the rules of the link and image checks
+var RuleDescriptions = map[string]string{
+	RuleBrokenLink:    "Links should point at existing pages",
+	RuleBrokenAnchor:  "Link fragments should match a heading of the target page",
+	RuleDuplicateSlug: "Headings of a page should have unique anchors",
+	RuleMissingImage:  "Local images should exist",
+	RuleLargeImage:    "Images should not exceed the size limit",
+}
+
+// skipDirs are never descended into
+var skipDirs = map[string]bool{
+	".git":         true,
+	".mdctl":       true,
+	"node_modules": true,
+}
+
+// Options configures a check run
+type Options struct {
+	Dir    string   // Documentation directory
+	Checks []string // Checks to run, all when empty
+
+	Lint         *linter.Config // Rules, severities and baseline of the lint check
+	Schema       *schema.Schema // Front matter schema, only the YAML syntax is checked when nil
+	SlugStyle    string         // Anchor style of the site generator, anchors.StyleGitHub when empty
+	MaxImageSize int64          // Largest image size in bytes, 0 for no limit
+	Concurrency  int            // Files linted concurrently, the number of CPUs when 0
+}
+
+// Finding is a problem reported by one of the checks
+type Finding struct {
+	Check    string `json:"check"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"` // error, warning or info
+	File     string `json:"file"`     // Path of the file below the checked directory, as given
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Summary counts the checked files and the findings
+type Summary struct {
+	Files    int            `json:"files"`
+	Errors   int            `json:"errors"`
+	Warnings int            `json:"warnings"`
+	Info     int            `json:"info"`
+	ByCheck  map[string]int `json:"by_check"`
+}
+
+// Report is the result of a check run
+type Report struct {
+	Dir      string    `json:"dir"`
+	Checks   []string  `json:"checks"`
+	Findings []Finding `json:"findings"`
+	Summary  Summary   `json:"summary"`
+	Failures []string  `json:"failures,omitempty"` // Thresholds the findings exceed, set by Evaluate
+}
+
+// ValidCheck reports whether name is one of Checks
+func ValidCheck(name string) bool {
+	for _, c := range Checks {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Run checks the markdown files below opts.Dir
+func Run(opts Options) (*Report, error) {
+	info, err := os.Stat(opts.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access directory: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", opts.Dir)
+	}
+	enabled := make(map[string]bool)
+	for _, c := range opts.Checks {
+		if !ValidCheck(c) {
+			return nil, fmt.Errorf("unknown check: %s (must be one of %s)", c, strings.Join(Checks, ", "))
+		}
+		enabled[c] = true
+	}
+	if len(enabled) == 0 {
+		for _, c := range Checks {
+			enabled[c] = true
+		}
+	}
+
+	files, err := markdownFiles(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Report{Dir: opts.Dir, Findings: []Finding{}}
+	for _, c := range Checks {
+		if enabled[c] {
+			r.Checks = append(r.Checks, c)
+		}
+	}
+	if enabled[CheckLint] || enabled[CheckFrontMatter] {
+		if err := r.lint(opts, files, enabled); err != nil {
+			return nil, err
+		}
+	}
+	if enabled[CheckLinks] {
+		if err := r.links(opts); err != nil {
+			return nil, err
+		}
+	}
+	if enabled[CheckImages] {
+		if err := r.images(opts, files); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		a, b := r.Findings[i], r.Findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	r.Summary = Summary{Files: len(files), ByCheck: make(map[string]int)}
+	for _, f := range r.Findings {
+		r.Summary.ByCheck[f.Check]++
+		switch f.Severity {
+		case linter.SeverityError:
+			r.Summary.Errors++
+		case linter.SeverityWarning:
+			r.Summary.Warnings++
+		default:
+			r.Summary.Info++
+		}
+	}
+	return r, nil
+}
+
+// lint runs the linter, front matter violations (FM001) are reported by the
+// front matter check
+func (r *Report) lint(opts Options, files []string, enabled map[string]bool) error {
+	config := linter.Config{}
+	if opts.Lint != nil {
+		config = *opts.Lint
+	}
+	config.AutoFix = false
+	config.FrontMatter = nil
+	if enabled[CheckFrontMatter] {
+		config.FrontMatter = opts.Schema
+		if config.FrontMatter == nil {
+			config.FrontMatter = &schema.Schema{}
+		}
+		switch {
+		case !enabled[CheckLint]:
+			config.EnableRules = []string{linter.FrontMatterRuleID}
+		case len(config.EnableRules) > 0:
+			config.EnableRules = append(append([]string{}, config.EnableRules...), linter.FrontMatterRuleID)
+		}
+	}
+
+	workers := opts.Concurrency
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	l := linter.New(&config)
+	outcomes := linter.LintFiles(files, workers, l.LintFile)
+	for i, file := range files {
+		result, err := outcomes[i].Result, outcomes[i].Err
+		if err != nil {
+			return fmt.Errorf("failed to lint %s: %v", file, err)
+		}
+		for _, issue := range result.Issues {
+			check := CheckLint
+			if issue.Rule == linter.FrontMatterRuleID {
+				check = CheckFrontMatter
+			}
+			if !enabled[check] {
+				continue
+			}
+			r.Findings = append(r.Findings, Finding{
+				Check:    check,
+				Rule:     issue.Rule,
+				Severity: issue.Severity,
+				File:     filepath.ToSlash(file),
+				Line:     issue.Line,
+				Column:   issue.Column,
+				Message:  issue.Message,
+			})
+		}
+	}
+	return nil
+}
+
+// links reports links to missing pages, links to missing anchors and
+// headings sharing an anchor
+func (r *Report) links(opts Options) error {
+	graph, err := linkgraph.Build(opts.Dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range graph.Unresolved {
+		r.Findings = append(r.Findings, Finding{
+			Check:    CheckLinks,
+			Rule:     RuleBrokenLink,
+			Severity: linter.SeverityError,
+			File:     r.path(e.From),
+			Line:     e.Line,
+			Message:  fmt.Sprintf("link target %s does not exist", e.Target),
+		})
+	}
+
+	style := opts.SlugStyle
+	if style == "" {
+		style = anchors.StyleGitHub
+	}
+	problems, err := anchors.Check(opts.Dir, style)
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		f := Finding{
+			Check:    CheckLinks,
+			Rule:     RuleBrokenAnchor,
+			Severity: linter.SeverityError,
+			File:     r.path(p.File),
+			Line:     p.Line,
+			Message:  p.Message,
+		}
+		if p.Kind == "duplicate" {
+			f.Rule, f.Severity = RuleDuplicateSlug, linter.SeverityWarning
+		}
+		r.Findings = append(r.Findings, f)
+	}
+	return nil
+}
+
+// images reports local images that do not exist or exceed the size limit
+func (r *Report) images(opts Options, files []string) error {
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", file, err)
+		}
+		for _, img := range mddoc.Parse(content).Images() {
+			local := localImage(opts.Dir, file, img.Destination)
+			if local == "" {
+				continue
+			}
+			finding := Finding{Check: CheckImages, File: filepath.ToSlash(file), Line: img.Line}
+			info, err := os.Stat(local)
+			switch {
+			case err != nil:
+				finding.Rule, finding.Severity = RuleMissingImage, linter.SeverityError
+				finding.Message = fmt.Sprintf("image %s does not exist", img.Destination)
+			case opts.MaxImageSize > 0 && info.Size() > opts.MaxImageSize:
+				finding.Rule, finding.Severity = RuleLargeImage, linter.SeverityWarning
+				finding.Message = fmt.Sprintf("image %s is %d bytes, more than %d", img.Destination, info.Size(), opts.MaxImageSize)
+			default:
+				continue
+			}
+			r.Findings = append(r.Findings, finding)
+		}
+	}
+	return nil
+}
+
+// path returns the path of a file given relative to the checked directory
+func (r *Report) path(rel string) string {
+	return filepath.ToSlash(filepath.Join(r.Dir, filepath.FromSlash(rel)))
+}
+
+// localImage resolves an image destination of file to a local path, root
+// relative destinations start at dir. It returns "" for remote images.
+func localImage(dir, file, dest string) string {
+	if dest == "" || strings.Contains(dest, "://") || strings.HasPrefix(dest, "//") || strings.HasPrefix(dest, "data:") {
+		return ""
+	}
+	dest, _, _ = strings.Cut(mddoc.Unescape(dest), "#")
+	dest, _, _ = strings.Cut(dest, "?")
+	if unescaped, err := url.PathUnescape(dest); err == nil {
+		dest = unescaped
+	}
+	if strings.HasPrefix(dest, "/") {
+		return filepath.Join(dir, filepath.FromSlash(path.Clean(dest)))
+	}
+	return filepath.Join(filepath.Dir(file), filepath.FromSlash(dest))
+}
+
+// markdownFiles lists the markdown files below dir
+func markdownFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if p != dir && skipDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if mddoc.IsMarkdown(p) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %v", dir, err)
+	}
+	return files, nil
+}
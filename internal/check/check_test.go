@@ -0,0 +1,134 @@
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/linter"
+	"github.com/samzong/mdctl/internal/schema"
+	"gopkg.in/yaml.v3"
+)
+
+func writeDocs(t *testing.T) string {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "guide"), 0755)
+	os.WriteFile(filepath.Join(dir, "index.md"), []byte("---\ntitle: Home\n---\n\n# Home\n\nSee [install](guide/install.md#setup) and [gone](missing.md).\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "guide", "install.md"), []byte("---\ntags: [a\n---\n\n# Install\n\n![Logo](logo.png)\n\n![Big](big.png)\n\n![Remote](https://example.com/a.png)\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "guide", "big.png"), bytes.Repeat([]byte("x"), 2048), 0644)
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := writeDocs(t)
+	var s schema.Schema
+	if err := yaml.Unmarshal([]byte("required: [title]\n"), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Run(Options{Dir: dir, Schema: &s, MaxImageSize: 1024, Lint: &linter.Config{EnableRules: []string{"MD047"}}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	found := make(map[string]Finding)
+	for _, f := range report.Findings {
+		found[f.Rule] = f
+	}
+	install := filepath.ToSlash(filepath.Join(dir, "guide", "install.md"))
+	index := filepath.ToSlash(filepath.Join(dir, "index.md"))
+	for _, want := range []Finding{
+		{Check: CheckLinks, Rule: RuleBrokenLink, Severity: linter.SeverityError, File: index, Line: 7},
+		{Check: CheckLinks, Rule: RuleBrokenAnchor, Severity: linter.SeverityError, File: index, Line: 7},
+		{Check: CheckFrontMatter, Rule: linter.FrontMatterRuleID, Severity: linter.SeverityError, File: install, Line: 1},
+		{Check: CheckImages, Rule: RuleMissingImage, Severity: linter.SeverityError, File: install, Line: 7},
+		{Check: CheckImages, Rule: RuleLargeImage, Severity: linter.SeverityWarning, File: install, Line: 9},
+	} {
+		got, ok := found[want.Rule]
+		got.Message = ""
+		if !ok || got != want {
+			t.Errorf("expected finding %+v, got %+v", want, got)
+		}
+	}
+	if len(report.Findings) != 5 {
+		t.Errorf("expected 5 findings, got %+v", report.Findings)
+	}
+	if report.Summary.Files != 2 || report.Summary.Errors != 4 || report.Summary.Warnings != 1 || report.Summary.ByCheck[CheckImages] != 2 {
+		t.Errorf("unexpected summary %+v", report.Summary)
+	}
+
+	report, err = Run(Options{Dir: dir, Checks: []string{CheckImages}})
+	if err != nil || len(report.Findings) != 1 || report.Findings[0].Rule != RuleMissingImage {
+		t.Errorf("expected only the missing image without a size limit, got %+v, %v", report, err)
+	}
+	if _, err := Run(Options{Dir: dir, Checks: []string{"spelling"}}); err == nil {
+		t.Errorf("expected an error for an unknown check")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	report := &Report{
+		Findings: []Finding{
+			{Check: CheckLint, Severity: linter.SeverityWarning},
+			{Check: CheckLint, Severity: linter.SeverityWarning},
+			{Check: CheckLinks, Severity: linter.SeverityInfo},
+		},
+		Summary: Summary{Warnings: 2, Info: 1, ByCheck: map[string]int{CheckLint: 2, CheckLinks: 1}},
+	}
+	tests := []struct {
+		thresholds Thresholds
+		failures   int
+	}{
+		{Thresholds{MaxWarnings: -1}, 0},
+		{Thresholds{FailOn: linter.SeverityWarning, MaxWarnings: -1}, 1},
+		{Thresholds{FailOn: linter.SeverityInfo, MaxWarnings: -1}, 2},
+		{Thresholds{FailOn: FailNone, MaxWarnings: 1}, 1},
+		{Thresholds{FailOn: FailNone, MaxWarnings: -1, Max: map[string]int{CheckLinks: 0, CheckLint: 2}}, 1},
+	}
+	for _, tt := range tests {
+		if failed := report.Evaluate(tt.thresholds); failed != (tt.failures > 0) || len(report.Failures) != tt.failures {
+			t.Errorf("Evaluate(%+v) = %v, %q", tt.thresholds, failed, report.Failures)
+		}
+	}
+
+	if err := (Thresholds{FailOn: "fatal"}).Validate(); err == nil {
+		t.Errorf("expected an error for an invalid fail level")
+	}
+	if err := (Thresholds{Max: map[string]int{"spelling": 1}}).Validate(); err == nil {
+		t.Errorf("expected an error for a limit of an unknown check")
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	report := &Report{Findings: []Finding{
+		{Check: CheckLinks, Rule: RuleBrokenLink, Severity: linter.SeverityError, File: "docs/index.md", Line: 3, Message: "link target a.md does not exist"},
+		{Check: CheckLint, Rule: "MD047", Severity: linter.SeverityInfo, File: "docs/a.md", Line: 9, Column: 2, Message: "no newline"},
+	}}
+	var out bytes.Buffer
+	if err := report.WriteSARIF(&out, "1.2.3"); err != nil {
+		t.Fatalf("WriteSARIF failed: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("invalid SARIF: %v\n%s", err, out.String())
+	}
+	run := log.Runs[0]
+	if log.Version != "2.1.0" || run.Tool.Driver.Version != "1.2.3" || len(run.Tool.Driver.Rules) != 2 || len(run.Results) != 2 {
+		t.Fatalf("unexpected SARIF log:\n%s", out.String())
+	}
+	first := run.Results[0]
+	if first.RuleID != RuleBrokenLink || first.Level != "error" || run.Tool.Driver.Rules[first.RuleIndex].ID != RuleBrokenLink ||
+		first.Locations[0].PhysicalLocation.ArtifactLocation.URI != "docs/index.md" || first.Locations[0].PhysicalLocation.Region.StartLine != 3 {
+		t.Errorf("unexpected result %+v", first)
+	}
+	if second := run.Results[1]; second.Level != "note" || second.Locations[0].PhysicalLocation.Region.StartColumn != 2 {
+		t.Errorf("unexpected result %+v", second)
+	}
+	if !strings.Contains(out.String(), `"shortDescription"`) {
+		t.Errorf("expected rule descriptions:\n%s", out.String())
+	}
+}
@@ -0,0 +1,84 @@
+package check
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/samzong/mdctl/internal/linter"
+)
+
+// FailNone is the FailOn level of runs that no severity fails on its own
+const FailNone = "none"
+
+// FailLevels are the accepted FailOn levels
+var FailLevels = []string{linter.SeverityError, linter.SeverityWarning, linter.SeverityInfo, FailNone}
+
+// severityRank orders the severities, higher is more severe
+var severityRank = map[string]int{
+	linter.SeverityInfo:    1,
+	linter.SeverityWarning: 2,
+	linter.SeverityError:   3,
+}
+
+// Thresholds decide whether the findings of a run fail it
+type Thresholds struct {
+	FailOn      string         // Lowest severity failing the run, linter.SeverityError when empty
+	MaxWarnings int            // Warnings allowed, negative for no limit
+	Max         map[string]int // Findings of any severity allowed per check
+}
+
+// Validate checks the fail level and the checks of the limits
+func (t Thresholds) Validate() error {
+	valid := t.FailOn == ""
+	for _, level := range FailLevels {
+		valid = valid || t.FailOn == level
+	}
+	if !valid {
+		return fmt.Errorf("invalid fail level: %s (must be error, warning, info or none)", t.FailOn)
+	}
+	for c := range t.Max {
+		if !ValidCheck(c) {
+			return fmt.Errorf("unknown check in limits: %s", c)
+		}
+	}
+	return nil
+}
+
+// Evaluate records the thresholds the findings exceed in r.Failures and
+// reports whether the run failed
+func (r *Report) Evaluate(t Thresholds) bool {
+	r.Failures = nil
+	failOn := t.FailOn
+	if failOn == "" {
+		failOn = linter.SeverityError
+	}
+
+	if rank, ok := severityRank[failOn]; ok {
+		counts := make(map[string]int)
+		for _, f := range r.Findings {
+			if severityRank[f.Severity] >= rank {
+				counts[f.Severity]++
+			}
+		}
+		for _, severity := range []string{linter.SeverityError, linter.SeverityWarning, linter.SeverityInfo} {
+			if counts[severity] > 0 {
+				r.Failures = append(r.Failures, fmt.Sprintf("%d findings with severity %s", counts[severity], severity))
+			}
+		}
+	}
+	if t.MaxWarnings >= 0 && r.Summary.Warnings > t.MaxWarnings {
+		r.Failures = append(r.Failures, fmt.Sprintf("%d warnings, more than %d", r.Summary.Warnings, t.MaxWarnings))
+	}
+
+	checks := make([]string, 0, len(t.Max))
+	for c := range t.Max {
+		checks = append(checks, c)
+	}
+	sort.Strings(checks)
+	for _, c := range checks {
+		if n := r.Summary.ByCheck[c]; n > t.Max[c] {
+			r.Failures = append(r.Failures, fmt.Sprintf("%d %s findings, more than %d", n, c, t.Max[c]))
+		}
+	}
+	return len(r.Failures) > 0
+}
@@ -0,0 +1,52 @@
+// Package clipboard reads the system clipboard through the platform tools:
+// pbpaste on macOS, PowerShell on Windows and wl-paste, xclip or xsel on
+// Linux and BSD.
+package clipboard
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// readCommands returns the commands that print the clipboard, in order of
+// preference
+func readCommands() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbpaste"}}
+	case "windows":
+		return [][]string{{"powershell", "-NoProfile", "-Command", "Get-Clipboard -Raw"}}
+	}
+	var commands [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append(commands, []string{"wl-paste", "--no-newline"})
+	}
+	return append(commands,
+		[]string{"xclip", "-selection", "clipboard", "-out"},
+		[]string{"xsel", "--clipboard", "--output"},
+	)
+}
+
+// Read returns the text on the clipboard
+func Read() (string, error) {
+	var tried []string
+	for _, command := range readCommands() {
+		if _, err := exec.LookPath(command[0]); err != nil {
+			tried = append(tried, command[0])
+			continue
+		}
+		out, err := exec.Command(command[0], command[1:]...).Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to read clipboard with %s: %v", command[0], err)
+		}
+		text := string(out)
+		if runtime.GOOS == "windows" {
+			text = strings.ReplaceAll(text, "\r\n", "\n")
+		}
+		return text, nil
+	}
+	return "", fmt.Errorf("no clipboard tool found (install one of: %s)", strings.Join(tried, ", "))
+}
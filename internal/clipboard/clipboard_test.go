@@ -0,0 +1,62 @@
+package clipboard
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("fake clipboard tools are shell scripts for Linux and BSD")
+	}
+
+	tests := []struct {
+		name    string
+		wayland bool
+		tools   map[string]string // Tool name -> shell script body
+		want    string
+		wantErr string
+	}{
+		{"xclip", false, map[string]string{"xclip": "printf 'from xclip'"}, "from xclip", ""},
+		{"xsel fallback", false, map[string]string{"xsel": "printf 'from xsel'"}, "from xsel", ""},
+		{"wayland first", true, map[string]string{"wl-paste": "printf 'from wl-paste'", "xclip": "printf 'from xclip'"}, "from wl-paste", ""},
+		{"no wayland session", false, map[string]string{"wl-paste": "printf 'from wl-paste'", "xclip": "printf 'from xclip'"}, "from xclip", ""},
+		{"multiple lines", false, map[string]string{"xclip": "printf 'line 1\\nline 2\\n'"}, "line 1\nline 2\n", ""},
+		{"tool fails", false, map[string]string{"xclip": "exit 1", "xsel": "printf 'from xsel'"}, "", "failed to read clipboard with xclip"},
+		{"no tool", false, nil, "", "no clipboard tool found (install one of: xclip, xsel)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, body := range tt.tools {
+				script := "#!/bin/sh\n" + body + "\n"
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Setenv("PATH", dir)
+			if tt.wayland {
+				t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+			} else {
+				t.Setenv("WAYLAND_DISPLAY", "")
+			}
+
+			got, err := Read()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Read() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
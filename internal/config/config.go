@@ -23,17 +23,38 @@ type CloudConfig struct {
 	CACertPath     string            `json:"ca_cert_path,omitempty"`
 	ConflictPolicy string            `json:"conflict_policy"`
 	CacheDir       string            `json:"cache_dir,omitempty"`
+	// Multipart uploads, object settings and server-side encryption of S3
+	// compatible providers
+	MultipartThreshold int    `json:"multipart_threshold,omitempty"` // MiB, files above it are uploaded in parts
+	StorageClass       string `json:"storage_class,omitempty"`
+	ACL                string `json:"acl,omitempty"`
+	SSE                string `json:"sse,omitempty"` // SSE-S3 (AES256) or SSE-KMS (aws:kms)
+	SSEKMSKeyID        string `json:"sse_kms_key_id,omitempty"`
 }
 
 type Config struct {
 	TranslatePrompt   string                 `json:"translate_prompt"`
+	TranslatePrompts  map[string]string      `json:"translate_prompts,omitempty"` // Prompts by target language code, TranslatePrompt for other languages
 	OpenAIEndpointURL string                 `json:"endpoint"`
 	OpenAIAPIKey      string                 `json:"api_key"`
 	ModelName         string                 `json:"model"`
+	EmbeddingModel    string                 `json:"embedding_model,omitempty"`
 	Temperature       float64                `json:"temperature"`
 	TopP              float64                `json:"top_p"`
 	CloudStorages     map[string]CloudConfig `json:"cloud_storages,omitempty"`
 	DefaultStorage    string                 `json:"default_storage,omitempty"`
+	AIRequestsPerMin  int                    `json:"ai_requests_per_minute,omitempty"`
+	AITokensPerMin    int                    `json:"ai_tokens_per_minute,omitempty"`
+	AIDailyTokenQuota int                    `json:"ai_daily_token_quota,omitempty"`
+	AITimeout         int                    `json:"ai_timeout,omitempty"`      // Seconds per AI request, 120 when 0
+	AIMaxRetries      int                    `json:"ai_max_retries,omitempty"`  // Retries of failed AI requests, 3 when 0, none when negative
+	AIStream          bool                   `json:"ai_stream,omitempty"`       // Stream AI replies, the timeout then applies between chunks
+	Proxy             string                 `json:"proxy,omitempty"`           // Proxy URL of all HTTP requests, HTTP_PROXY and HTTPS_PROXY when empty
+	SecretsBackend    string                 `json:"secrets_backend,omitempty"` // Where saved secrets go: keyring or passphrase, the file when empty
+	Metrics           bool                   `json:"metrics,omitempty"`         // Record local usage metrics of every run
+	Author            string                 `json:"author,omitempty"`          // Author of documents created with mdctl new
+
+	secrets map[string]secretRef // Secrets decrypted by LoadConfig
 }
 
 var DefaultCloudConfig = CloudConfig{
@@ -53,26 +74,117 @@ var DefaultConfig = Config{
 	OpenAIEndpointURL: "https://api.openai.com/v1",
 	OpenAIAPIKey:      "",
 	ModelName:         "gpt-3.5-turbo",
+	EmbeddingModel:    "text-embedding-3-small",
 	Temperature:       0.0,
 	TopP:              1.0,
 	CloudStorages:     make(map[string]CloudConfig),
 }
 
-func GetConfigPath() string {
+// ConfigEnv names the environment variable selecting another configuration file
+const ConfigEnv = "MDCTL_CONFIG"
+
+// pathOverride is the configuration file chosen with SetConfigPath
+var pathOverride string
+
+// SetConfigPath makes LoadConfig and SaveConfig use another configuration
+// file, the empty path restores the default lookup
+func SetConfigPath(path string) {
+	pathOverride = path
+}
+
+// customConfigPath returns the file chosen with SetConfigPath or MDCTL_CONFIG,
+// empty when the default file is used
+func customConfigPath() string {
+	if pathOverride != "" {
+		return pathOverride
+	}
+	return os.Getenv(ConfigEnv)
+}
+
+// ConfigDir returns the directory of the default configuration file, which
+// also holds templates and dictionaries
+func ConfigDir() string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return ""
 	}
-	return filepath.Join(homeDir, ".config", "mdctl", "config.json")
+	return filepath.Join(homeDir, ".config", "mdctl")
+}
+
+// GetConfigPath returns the configuration file: the one chosen with
+// SetConfigPath, then MDCTL_CONFIG, then ~/.config/mdctl/config.json
+func GetConfigPath() string {
+	if path := customConfigPath(); path != "" {
+		return path
+	}
+	if dir := ConfigDir(); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	return ""
+}
+
+// ReadProxy returns the proxy of the configuration file, without creating or
+// repairing the file like LoadConfig
+func ReadProxy() string {
+	data, err := os.ReadFile(GetConfigPath())
+	if err != nil {
+		return ""
+	}
+	var cfg struct {
+		Proxy string `json:"proxy"`
+	}
+	if json.Unmarshal(data, &cfg) != nil {
+		return ""
+	}
+	return cfg.Proxy
 }
 
+// ReadMetrics reports whether local usage metrics are enabled, reading the
+// configuration file like ReadProxy
+func ReadMetrics() bool {
+	data, err := os.ReadFile(GetConfigPath())
+	if err != nil {
+		return false
+	}
+	var cfg struct {
+		Metrics bool `json:"metrics"`
+	}
+	if json.Unmarshal(data, &cfg) != nil {
+		return false
+	}
+	return cfg.Metrics
+}
+
+// LoadConfig reads the configuration file. The default file is created when
+// it does not exist, a file chosen with --config or MDCTL_CONFIG has to exist.
 func LoadConfig() (*Config, error) {
+	return loadConfig(false)
+}
+
+// LoadConfigForUpdate is LoadConfig for commands that save the configuration:
+// a chosen file that does not exist yet starts from the defaults and is
+// created by SaveConfig
+func LoadConfigForUpdate() (*Config, error) {
+	return loadConfig(true)
+}
+
+func loadConfig(update bool) (*Config, error) {
 	configPath := GetConfigPath()
 	if configPath == "" {
 		return &DefaultConfig, nil
 	}
 
+	// Custom files may be mounted read-only, they are never created or
+	// replaced here
+	custom := customConfigPath() != ""
+
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		if custom {
+			if update {
+				return &DefaultConfig, nil
+			}
+			return &DefaultConfig, fmt.Errorf("config file %s does not exist", configPath)
+		}
 		if err := SaveConfig(&DefaultConfig); err != nil {
 			return &DefaultConfig, fmt.Errorf("failed to create default config: %v", err)
 		}
@@ -86,6 +198,9 @@ func LoadConfig() (*Config, error) {
 
 	var config Config
 	if err := json.Unmarshal(data, &config); err != nil {
+		if custom {
+			return &DefaultConfig, fmt.Errorf("invalid config file %s: %v", configPath, err)
+		}
 		os.Remove(configPath)
 		if err := SaveConfig(&DefaultConfig); err != nil {
 			return &DefaultConfig, fmt.Errorf("failed to create new config after invalid file: %v", err)
@@ -93,6 +208,8 @@ func LoadConfig() (*Config, error) {
 		return &DefaultConfig, fmt.Errorf("invalid config file (recreated with defaults): %v", err)
 	}
 
+	config.openSecrets()
+
 	if config.TranslatePrompt == "" {
 		config.TranslatePrompt = DefaultConfig.TranslatePrompt
 	}
@@ -143,18 +260,30 @@ func SaveConfig(config *Config) error {
 		return fmt.Errorf("failed to create config directory: %v", err)
 	}
 
-	data, err := json.MarshalIndent(config, "", "  ")
+	sealed, err := config.sealedCopy()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(sealed, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %v", err)
 	}
 
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
+	if err := os.WriteFile(configPath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write config file: %v", err)
 	}
 
 	return nil
 }
 
+// PromptFor returns the translation prompt of a target language code
+func (c *Config) PromptFor(lang string) string {
+	if prompt := c.TranslatePrompts[lang]; prompt != "" {
+		return prompt
+	}
+	return c.TranslatePrompt
+}
+
 // ApplyCloudConfig applies platform-specific settings to the cloud configuration
 func (c *Config) ApplyCloudConfig() {
 	// Ensure CloudStorages is non-nil
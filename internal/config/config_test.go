@@ -0,0 +1,100 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetConfigPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(ConfigEnv, "")
+	defer SetConfigPath("")
+
+	if got, want := GetConfigPath(), filepath.Join(home, ".config", "mdctl", "config.json"); got != want {
+		t.Errorf("default path = %q, want %q", got, want)
+	}
+	t.Setenv(ConfigEnv, "/etc/mdctl/env.json")
+	if got := GetConfigPath(); got != "/etc/mdctl/env.json" {
+		t.Errorf("path with %s = %q", ConfigEnv, got)
+	}
+	// --config takes precedence over the environment
+	SetConfigPath("/tmp/flag.json")
+	if got := GetConfigPath(); got != "/tmp/flag.json" {
+		t.Errorf("path with SetConfigPath = %q", got)
+	}
+}
+
+func TestLoadMissingCustomConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer SetConfigPath("")
+
+	for _, viaEnv := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "missing.json")
+		SetConfigPath("")
+		t.Setenv(ConfigEnv, "")
+		if viaEnv {
+			t.Setenv(ConfigEnv, path)
+		} else {
+			SetConfigPath(path)
+		}
+
+		if _, err := LoadConfig(); err == nil {
+			t.Errorf("expected an error for the missing file %s (env %v)", path, viaEnv)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("LoadConfig created %s", path)
+		}
+
+		// config set starts from the defaults and creates the file
+		cfg, err := LoadConfigForUpdate()
+		if err != nil || cfg.ModelName != DefaultConfig.ModelName {
+			t.Fatalf("LoadConfigForUpdate = %+v, %v", cfg, err)
+		}
+		if err := SaveConfig(cfg); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadConfig(); err != nil {
+			t.Errorf("LoadConfig after saving failed: %v", err)
+		}
+	}
+}
+
+func TestLoadConfigKeepsCustomFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(ConfigEnv, "")
+	defer SetConfigPath("")
+
+	// An invalid custom file is reported and left alone
+	path := filepath.Join(t.TempDir(), "broken.json")
+	os.WriteFile(path, []byte("{not json"), 0400)
+	SetConfigPath(path)
+	if _, err := LoadConfig(); err == nil {
+		t.Errorf("expected an error for an invalid custom file")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{not json" {
+		t.Errorf("invalid custom file was replaced: %q", data)
+	}
+
+	// A valid one is read without being written, defaults are not filled in
+	os.Chmod(path, 0600)
+	os.WriteFile(path, []byte(`{"model": "gpt-4o"}`), 0400)
+	cfg, err := LoadConfig()
+	if err != nil || cfg.ModelName != "gpt-4o" || cfg.TranslatePrompt != DefaultConfig.TranslatePrompt {
+		t.Errorf("LoadConfig = %+v, %v", cfg, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"model": "gpt-4o"}` {
+		t.Errorf("custom file was rewritten: %q", data)
+	}
+
+	// The default file, unlike custom ones, is created when missing
+	SetConfigPath("")
+	if _, err := LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".config", "mdctl", "config.json")); err != nil {
+		t.Errorf("default config was not created: %v", err)
+	}
+}
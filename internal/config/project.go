@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfigFile is the name of project configuration files. They override
+// settings for the files of their directory and its subdirectories.
+const ProjectConfigFile = ".mdctl.yaml"
+
+// ProjectConfig holds the settings a project configuration file overrides
+type ProjectConfig struct {
+	TranslatePrompt  string            `yaml:"translate_prompt"`
+	TranslatePrompts map[string]string `yaml:"translate_prompts"` // Prompts by target language code
+	TemplatesDir     string            `yaml:"templates_dir"`     // Templates of mdctl new, relative to the file
+	NewDirs          map[string]string `yaml:"new_dirs"`          // Directories mdctl new writes to by kind, relative to the file
+	AssetsKeys       []string          `yaml:"assets_keys"`       // Front matter fields referencing images, e.g. cover.image
+}
+
+// LoadProjectConfig reads the project configuration file of dir, nil when
+// there is none
+func LoadProjectConfig(dir string) (*ProjectConfig, error) {
+	path := filepath.Join(dir, ProjectConfigFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	var project ProjectConfig
+	if err := yaml.Unmarshal(data, &project); err != nil {
+		return nil, fmt.Errorf("invalid project config %s: %v", path, err)
+	}
+	return &project, nil
+}
+
+// FindAssetsKeys returns the front matter fields referencing images of the
+// files below dir, set by the nearest project configuration from dir upwards
+// that sets assets_keys. It returns nil when none does.
+func FindAssetsKeys(dir string) ([]string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		project, err := LoadProjectConfig(dir)
+		if err != nil {
+			return nil, err
+		}
+		if project != nil && len(project.AssetsKeys) > 0 {
+			return project.AssetsKeys, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// PromptFor returns the translation prompt of a target language, the
+// general prompt when the language has none
+func (p *ProjectConfig) PromptFor(lang string) string {
+	if prompt := p.TranslatePrompts[lang]; prompt != "" {
+		return prompt
+	}
+	return p.TranslatePrompt
+}
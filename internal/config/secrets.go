@@ -0,0 +1,343 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/samzong/mdctl/internal/logging"
+)
+
+var logger = logging.New("CONFIG")
+
+// Backends keeping the secrets of the configuration out of the file
+const (
+	SecretsPlain      = "plain"      // Stored in the file as they are
+	SecretsKeyring    = "keyring"    // OS keychain: macOS Keychain or the Secret Service (secret-tool)
+	SecretsPassphrase = "passphrase" // age encryption with the passphrase in MDCTL_PASSPHRASE
+)
+
+// PassphraseEnv names the environment variable holding the passphrase of
+// encrypted secrets
+const PassphraseEnv = "MDCTL_PASSPHRASE"
+
+const (
+	keyringPrefix   = "keyring:"
+	encryptedPrefix = "age:"
+	keyringService  = "mdctl"
+)
+
+// scryptWorkFactor is the log2 of the scrypt work of encrypted secrets,
+// lowered in tests
+var scryptWorkFactor = 18
+
+// keyringOS selects the keychain tool, replaced in tests
+var keyringOS = runtime.GOOS
+
+// runKeyring runs a keychain tool with stdin and returns its output,
+// replaced in tests
+var runKeyring = func(stdin string, name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s failed: %s", name, msg)
+		}
+		return "", fmt.Errorf("%s failed: %v", name, err)
+	}
+	return stdout.String(), nil
+}
+
+// secretRef is a secret as stored in the file and its decrypted value, or
+// why it could not be decrypted
+type secretRef struct {
+	stored string
+	plain  string
+	err    error
+}
+
+// secretField is a sensitive value of the configuration
+type secretField struct {
+	key string // Key of "mdctl config set"
+	get func() string
+	set func(string)
+}
+
+// ValidSecretsBackend checks the name of a secrets backend
+func ValidSecretsBackend(backend string) error {
+	switch backend {
+	case SecretsPlain, SecretsKeyring, SecretsPassphrase:
+		return nil
+	}
+	return fmt.Errorf("invalid secrets backend: %s (must be plain, keyring or passphrase)", backend)
+}
+
+// secretFields returns the API key and the secret keys of the cloud storages
+func (c *Config) secretFields() []secretField {
+	fields := []secretField{{
+		key: "api_key",
+		get: func() string { return c.OpenAIAPIKey },
+		set: func(v string) { c.OpenAIAPIKey = v },
+	}}
+
+	names := make([]string, 0, len(c.CloudStorages))
+	for name := range c.CloudStorages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		name := name
+		fields = append(fields, secretField{
+			key: "cloud_storages." + name + ".secret_key",
+			get: func() string { return c.CloudStorages[name].SecretKey },
+			set: func(v string) {
+				storage := c.CloudStorages[name]
+				storage.SecretKey = v
+				c.CloudStorages[name] = storage
+			},
+		})
+	}
+	return fields
+}
+
+// openSecrets replaces the keyring references and encrypted values read from
+// the file by the secrets, remembering them so unchanged secrets are saved
+// the way they were stored. A secret that cannot be opened, e.g. without
+// MDCTL_PASSPHRASE, is left empty with a warning, so that commands not
+// using it still run, and is saved unchanged.
+func (c *Config) openSecrets() {
+	c.secrets = make(map[string]secretRef)
+	for _, field := range c.secretFields() {
+		stored := field.get()
+		if !isSealed(stored) {
+			continue
+		}
+		plain, err := openSecret(stored)
+		if err != nil {
+			logger.Warnf("Failed to decrypt %s, it is left empty: %v", field.key, err)
+		}
+		field.set(plain)
+		c.secrets[field.key] = secretRef{stored: stored, plain: plain, err: err}
+	}
+}
+
+// sealedCopy returns the configuration as it is written to the file: secrets
+// are stored with the secrets backend, unchanged ones as they were loaded
+func (c *Config) sealedCopy() (*Config, error) {
+	sealed := *c
+	sealed.CloudStorages = make(map[string]CloudConfig, len(c.CloudStorages))
+	for name, storage := range c.CloudStorages {
+		sealed.CloudStorages[name] = storage
+	}
+
+	for _, field := range sealed.secretFields() {
+		value := field.get()
+		if ref, ok := c.secrets[field.key]; ok && ref.plain == value {
+			field.set(ref.stored)
+			continue
+		}
+		if value == "" || isSealed(value) {
+			continue
+		}
+		stored, err := sealSecret(c.SecretsBackend, field.key, value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s: %v", field.key, err)
+		}
+		field.set(stored)
+		if stored != value {
+			if c.secrets == nil {
+				c.secrets = make(map[string]secretRef)
+			}
+			c.secrets[field.key] = secretRef{stored: stored, plain: value}
+		}
+	}
+	return &sealed, nil
+}
+
+// MigrateSecrets moves the secrets to another backend when the configuration
+// is saved next and returns their number
+func (c *Config) MigrateSecrets(backend string) (int, error) {
+	if err := ValidSecretsBackend(backend); err != nil {
+		return 0, err
+	}
+	for _, field := range c.secretFields() {
+		if ref := c.secrets[field.key]; ref.err != nil {
+			return 0, fmt.Errorf("cannot migrate %s: %v", field.key, ref.err)
+		}
+	}
+	c.SecretsBackend = backend
+	if backend == SecretsPlain {
+		c.SecretsBackend = ""
+	}
+	// Forget how the secrets were stored so all of them are written again
+	c.secrets = nil
+
+	count := 0
+	for _, field := range c.secretFields() {
+		if field.get() != "" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// isSealed reports whether a stored value is a keyring reference or encrypted
+func isSealed(value string) bool {
+	return strings.HasPrefix(value, keyringPrefix) || strings.HasPrefix(value, encryptedPrefix)
+}
+
+// openSecret returns the secret of a keyring reference or an encrypted value
+func openSecret(stored string) (string, error) {
+	if account, ok := strings.CutPrefix(stored, keyringPrefix); ok {
+		return keyringGet(account)
+	}
+	return decrypt(strings.TrimPrefix(stored, encryptedPrefix))
+}
+
+// sealSecret stores a secret with a backend and returns the value written to
+// the file in its place
+func sealSecret(backend, key, secret string) (string, error) {
+	switch backend {
+	case "", SecretsPlain:
+		return secret, nil
+	case SecretsKeyring:
+		account := keyringAccount(key)
+		if err := keyringSet(account, secret); err != nil {
+			return "", err
+		}
+		return keyringPrefix + account, nil
+	case SecretsPassphrase:
+		encrypted, err := encrypt(secret)
+		if err != nil {
+			return "", err
+		}
+		return encryptedPrefix + encrypted, nil
+	}
+	return "", ValidSecretsBackend(backend)
+}
+
+// keyringAccount names the keychain entry of a secret, entries of files
+// selected with --config or MDCTL_CONFIG are kept apart from the default one
+func keyringAccount(key string) string {
+	if path := customConfigPath(); path != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		return path + ":" + key
+	}
+	return key
+}
+
+// keyringGet reads a secret from the OS keychain
+func keyringGet(account string) (string, error) {
+	var out string
+	var err error
+	switch keyringOS {
+	case "darwin":
+		out, err = runKeyring("", "security", "find-generic-password", "-s", keyringService, "-a", account, "-w")
+	case "windows":
+		return "", fmt.Errorf("the keyring backend is not supported on Windows, use the passphrase backend")
+	default:
+		out, err = runKeyring("", "secret-tool", "lookup", "service", keyringService, "account", account)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(out, "\r\n"), nil
+}
+
+// keyringSet writes a secret to the OS keychain, replacing an existing entry.
+// The secret goes to the tools on stdin, never on their command line where
+// other local users could read it: macOS security reads the command from
+// stdin in interactive mode.
+func keyringSet(account, secret string) error {
+	var err error
+	switch keyringOS {
+	case "darwin":
+		command := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+			securityQuote(keyringService), securityQuote(account), securityQuote(secret))
+		_, err = runKeyring(command, "security", "-i")
+	case "windows":
+		return fmt.Errorf("the keyring backend is not supported on Windows, use the passphrase backend")
+	default:
+		_, err = runKeyring(secret, "secret-tool", "store", "--label=mdctl "+account, "service", keyringService, "account", account)
+	}
+	return err
+}
+
+// securityQuote quotes an argument of a security interactive mode command
+func securityQuote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+}
+
+// passphrase returns the passphrase of encrypted secrets
+func passphrase() (string, error) {
+	value := os.Getenv(PassphraseEnv)
+	if value == "" {
+		return "", fmt.Errorf("set %s to the passphrase of the encrypted secrets", PassphraseEnv)
+	}
+	return value, nil
+}
+
+// encrypt encrypts a secret to the passphrase with age, the result is the
+// base64 of the age file
+func encrypt(secret string) (string, error) {
+	pass, err := passphrase()
+	if err != nil {
+		return "", err
+	}
+	recipient, err := age.NewScryptRecipient(pass)
+	if err != nil {
+		return "", err
+	}
+	recipient.SetWorkFactor(scryptWorkFactor)
+
+	var out bytes.Buffer
+	w, err := age.Encrypt(&out, recipient)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.WriteString(w, secret); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(out.Bytes()), nil
+}
+
+// decrypt reverses encrypt
+func decrypt(encoded string) (string, error) {
+	pass, err := passphrase()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %v", err)
+	}
+	identity, err := age.NewScryptIdentity(pass)
+	if err != nil {
+		return "", err
+	}
+	r, err := age.Decrypt(bytes.NewReader(data), identity)
+	if err != nil {
+		return "", fmt.Errorf("wrong passphrase or corrupted value: %v", err)
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("corrupted value: %v", err)
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPassphraseSecrets(t *testing.T) {
+	defer func(orig int) { scryptWorkFactor = orig }(scryptWorkFactor)
+	scryptWorkFactor = 10
+	path := filepath.Join(t.TempDir(), "config.json")
+	SetConfigPath(path)
+	defer SetConfigPath("")
+	t.Setenv(PassphraseEnv, "correct horse")
+
+	cfg := DefaultConfig
+	cfg.CloudStorages = map[string]CloudConfig{"r2": {Provider: "r2", SecretKey: "s3cret"}}
+	cfg.OpenAIAPIKey = "sk-test"
+	if n, err := cfg.MigrateSecrets(SecretsPassphrase); err != nil || n != 2 {
+		t.Fatalf("MigrateSecrets = %d, %v", n, err)
+	}
+	if err := SaveConfig(&cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-test") || strings.Contains(string(data), "s3cret") || !strings.Contains(string(data), `"api_key": "age:`) {
+		t.Fatalf("secrets written in plain text:\n%s", data)
+	}
+
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.OpenAIAPIKey != "sk-test" || loaded.CloudStorages["r2"].SecretKey != "s3cret" {
+		t.Errorf("decrypted secrets = %q, %q", loaded.OpenAIAPIKey, loaded.CloudStorages["r2"].SecretKey)
+	}
+
+	// Unchanged secrets are saved as they were stored
+	loaded.ModelName = "other"
+	if err := SaveConfig(loaded); err != nil {
+		t.Fatal(err)
+	}
+	again, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(again), loaded.secrets["api_key"].stored) {
+		t.Errorf("api key was encrypted again:\n%s", again)
+	}
+
+	// Without the passphrase the config still loads, the secrets are empty
+	// and saved as they were stored
+	for _, pass := range []string{"wrong", ""} {
+		t.Setenv(PassphraseEnv, pass)
+		locked, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("LoadConfig with passphrase %q failed: %v", pass, err)
+		}
+		if locked.OpenAIAPIKey != "" || locked.CloudStorages["r2"].SecretKey != "" || locked.ModelName != "other" {
+			t.Errorf("expected empty secrets, got %q, %q", locked.OpenAIAPIKey, locked.CloudStorages["r2"].SecretKey)
+		}
+		if _, err := locked.MigrateSecrets(SecretsPlain); err == nil {
+			t.Errorf("expected migrating secrets that cannot be decrypted to fail")
+		}
+		locked.ModelName = "third"
+		if err := SaveConfig(locked); err != nil {
+			t.Fatal(err)
+		}
+		saved, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(saved), loaded.secrets["api_key"].stored) || !strings.Contains(string(saved), loaded.secrets["cloud_storages.r2.secret_key"].stored) {
+			t.Errorf("secrets lost when saving without the passphrase:\n%s", saved)
+		}
+		locked.ModelName = "other"
+		SaveConfig(locked)
+	}
+}
+
+func TestKeyringSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	SetConfigPath(path)
+	defer SetConfigPath("")
+
+	store := make(map[string]string)
+	defer func(orig func(string, string, ...string) (string, error)) { runKeyring = orig }(runKeyring)
+	runKeyring = func(stdin string, name string, args ...string) (string, error) {
+		account := ""
+		for i, arg := range args {
+			if (arg == "account" || arg == "-a") && i+1 < len(args) {
+				account = args[i+1]
+			}
+		}
+		if args[0] == "store" {
+			store[account] = stdin
+			return "", nil
+		}
+		return store[account] + "\n", nil
+	}
+
+	cfg := DefaultConfig
+	cfg.CloudStorages = nil
+	cfg.OpenAIAPIKey = "sk-test"
+	cfg.MigrateSecrets(SecretsKeyring)
+	if err := SaveConfig(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"api_key": "keyring:`+path+`:api_key"`) {
+		t.Errorf("config does not reference the keyring:\n%s", data)
+	}
+
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.OpenAIAPIKey != "sk-test" {
+		t.Errorf("api key = %q, want sk-test", loaded.OpenAIAPIKey)
+	}
+}
+
+func TestKeyringSetKeepsSecretOffCommandLine(t *testing.T) {
+	defer func(orig func(string, string, ...string) (string, error)) { runKeyring = orig }(runKeyring)
+	defer func(orig string) { keyringOS = orig }(keyringOS)
+
+	secret := "it's s3cret"
+	for _, goos := range []string{"darwin", "linux"} {
+		keyringOS = goos
+		var gotStdin string
+		runKeyring = func(stdin string, name string, args ...string) (string, error) {
+			if strings.Contains(strings.Join(args, " "), "s3cret") {
+				t.Errorf("%s: secret on the command line of %s %v", goos, name, args)
+			}
+			gotStdin = stdin
+			return "", nil
+		}
+		if err := keyringSet("api_key", secret); err != nil {
+			t.Fatal(err)
+		}
+		want := secret
+		if goos == "darwin" {
+			want = `add-generic-password -U -s 'mdctl' -a 'api_key' -w 'it'"'"'s s3cret'` + "\n"
+		}
+		if gotStdin != want {
+			t.Errorf("%s: stdin = %q, want %q", goos, gotStdin, want)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package confluence
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/netutil"
+)
+
+// logger reports import and publish progress
+var logger = logging.New("CONFLUENCE")
+
+// pageLimit is the number of pages requested per REST call
+const pageLimit = 50
+
+// Client talks to the Confluence REST API
+type Client struct {
+	BaseURL    string // Site URL including the context path, e.g. https://example.atlassian.net/wiki
+	User       string // Account email for Confluence Cloud, empty to send Token as a bearer token
+	Token      string // API token (Cloud) or personal access token (Server/Data Center)
+	HTTPClient *http.Client
+}
+
+// Page is a Confluence page with its storage-format body
+type Page struct {
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	Version   Version    `json:"version"`
+	Ancestors []Ancestor `json:"ancestors"`
+	Body      struct {
+		Storage struct {
+			Value string `json:"value"`
+		} `json:"storage"`
+	} `json:"body"`
+}
+
+// Version is the version of a page
+type Version struct {
+	Number int `json:"number"`
+}
+
+// Ancestor is a parent page in the page tree, ordered from the root
+type Ancestor struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// Attachment is a file attached to a page
+type Attachment struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Links struct {
+		Download string `json:"download"`
+	} `json:"_links"`
+}
+
+// NewClient returns a client for a Confluence site
+func NewClient(baseURL, user, token string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		User:       user,
+		Token:      token,
+		HTTPClient: netutil.NewClient(60 * time.Second),
+	}
+}
+
+// SpacePages returns all pages of a space
+func (c *Client) SpacePages(ctx context.Context, space string) ([]Page, error) {
+	query := url.Values{"spaceKey": {space}, "type": {"page"}}
+	return c.listPages(ctx, "/rest/api/content", query)
+}
+
+// PageTree returns a page and all of its descendants
+func (c *Client) PageTree(ctx context.Context, id string) ([]Page, error) {
+	var root Page
+	query := url.Values{"expand": {"body.storage,ancestors,version"}}
+	if err := c.do(ctx, http.MethodGet, "/rest/api/content/"+id, query, nil, &root); err != nil {
+		return nil, err
+	}
+
+	descendants, err := c.listPages(ctx, "/rest/api/content/"+id+"/descendant/page", url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	return append([]Page{root}, descendants...), nil
+}
+
+// listPages follows the pagination of a content listing
+func (c *Client) listPages(ctx context.Context, path string, query url.Values) ([]Page, error) {
+	query.Set("expand", "body.storage,ancestors,version")
+	query.Set("limit", fmt.Sprint(pageLimit))
+
+	var pages []Page
+	for start := 0; ; start += pageLimit {
+		query.Set("start", fmt.Sprint(start))
+		var result struct {
+			Results []Page `json:"results"`
+			Size    int    `json:"size"`
+		}
+		if err := c.do(ctx, http.MethodGet, path, query, nil, &result); err != nil {
+			return nil, err
+		}
+		pages = append(pages, result.Results...)
+		if result.Size < pageLimit {
+			return pages, nil
+		}
+	}
+}
+
+// Attachments returns the files attached to a page
+func (c *Client) Attachments(ctx context.Context, pageID string) ([]Attachment, error) {
+	var attachments []Attachment
+	query := url.Values{"limit": {fmt.Sprint(pageLimit)}}
+	for start := 0; ; start += pageLimit {
+		query.Set("start", fmt.Sprint(start))
+		var result struct {
+			Results []Attachment `json:"results"`
+			Size    int          `json:"size"`
+		}
+		if err := c.do(ctx, http.MethodGet, "/rest/api/content/"+pageID+"/child/attachment", query, nil, &result); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, result.Results...)
+		if result.Size < pageLimit {
+			return attachments, nil
+		}
+	}
+}
+
+// Download writes the content of an attachment download link to w
+func (c *Client) Download(ctx context.Context, link string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+link, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to download %s: %v", link, err)
+	}
+	return nil
+}
+
+// FindPage returns the page with a title in a space, or nil if there is none
+func (c *Client) FindPage(ctx context.Context, space, title string) (*Page, error) {
+	var result struct {
+		Results []Page `json:"results"`
+	}
+	query := url.Values{"spaceKey": {space}, "title": {title}, "expand": {"version"}}
+	if err := c.do(ctx, http.MethodGet, "/rest/api/content", query, nil, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Results) == 0 {
+		return nil, nil
+	}
+	return &result.Results[0], nil
+}
+
+// GetPage returns a page with its version
+func (c *Client) GetPage(ctx context.Context, id string) (*Page, error) {
+	var page Page
+	if err := c.do(ctx, http.MethodGet, "/rest/api/content/"+id, url.Values{"expand": {"version"}}, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// CreatePage creates a page from a storage-format body below parentID,
+// or at the top of the space when parentID is empty
+func (c *Client) CreatePage(ctx context.Context, space, parentID, title, body string) (*Page, error) {
+	content := pageContent(title, body)
+	content["space"] = map[string]string{"key": space}
+	if parentID != "" {
+		content["ancestors"] = []map[string]string{{"id": parentID}}
+	}
+
+	var page Page
+	if err := c.do(ctx, http.MethodPost, "/rest/api/content", nil, content, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// UpdatePage replaces the title and body of a page as a new version
+func (c *Client) UpdatePage(ctx context.Context, page *Page, title, body string) error {
+	content := pageContent(title, body)
+	content["version"] = Version{Number: page.Version.Number + 1}
+	return c.do(ctx, http.MethodPut, "/rest/api/content/"+page.ID, nil, content, nil)
+}
+
+// pageContent is the request body shared by page creation and updates
+func pageContent(title, body string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":  "page",
+		"title": title,
+		"body": map[string]interface{}{
+			"storage": map[string]string{"value": body, "representation": "storage"},
+		},
+	}
+}
+
+// UploadAttachment attaches a file to a page, replacing an attachment with the same name
+func (c *Client) UploadAttachment(ctx context.Context, pageID, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open attachment: %v", err)
+	}
+	defer file.Close()
+
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, err := form.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return fmt.Errorf("failed to read attachment %s: %v", path, err)
+	}
+	form.WriteField("minorEdit", "true")
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.BaseURL+"/rest/api/content/"+pageID+"/child/attachment", &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("X-Atlassian-Token", "nocheck")
+
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a JSON request and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	endpoint := c.BaseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %v", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response of %s: %v", path, err)
+	}
+	return nil
+}
+
+// send authenticates a request and turns error statuses into errors
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if c.User != "" {
+		req.SetBasicAuth(c.User, c.Token)
+	} else if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	logger.Debugf("%s %s", req.Method, req.URL)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("confluence request failed: %v", err)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("confluence returned %s for %s %s: %s", resp.Status, req.Method, req.URL.Path, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
@@ -0,0 +1,248 @@
+package confluence
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/processor"
+	"gopkg.in/yaml.v3"
+)
+
+// ImportOptions controls which pages are imported and where
+type ImportOptions struct {
+	Space     string // Space key, every page of the space is imported
+	PageID    string // Root page, imports the page and its descendants instead of a space
+	OutputDir string
+	DryRun    bool
+}
+
+// ImportStats describes an import run
+type ImportStats struct {
+	Pages       int      `json:"pages"`
+	Attachments int      `json:"attachments"`
+	Images      int      `json:"images"` // Remote images downloaded
+	Failed      int      `json:"failed"`
+	Files       []string `json:"files"`
+}
+
+// pageFile is an imported page and the markdown file it is written to
+type pageFile struct {
+	page *Page
+	slug string
+	path string
+}
+
+// frontMatter records where an imported page came from, Publish uses it to
+// update the same page
+type frontMatter struct {
+	Title   string `yaml:"title"`
+	ID      string `yaml:"confluence_id,omitempty"`
+	Version int    `yaml:"confluence_version,omitempty"`
+}
+
+// Import converts the pages of a space, or of a page tree, into a markdown
+// tree mirroring the page hierarchy. Pages with children become index.md of
+// a directory, attached images are downloaded next to the pages.
+func Import(ctx context.Context, client *Client, opts ImportOptions) (*ImportStats, error) {
+	var pages []Page
+	var err error
+	switch {
+	case opts.PageID != "":
+		pages, err = client.PageTree(ctx, opts.PageID)
+	case opts.Space != "":
+		pages, err = client.SpacePages(ctx, opts.Space)
+	default:
+		return nil, fmt.Errorf("either a space or a root page must be specified")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pages: %v", err)
+	}
+	logger.Infof("Found %d pages", len(pages))
+
+	files := planFiles(pages, opts.OutputDir)
+	byTitle := make(map[string]*pageFile)
+	for _, file := range files {
+		byTitle[file.page.Title] = file
+	}
+
+	stats := &ImportStats{}
+	for _, file := range files {
+		if ctx.Err() != nil {
+			return stats, ctx.Err()
+		}
+		if err := importPage(ctx, client, file, byTitle, opts.DryRun, stats); err != nil {
+			logger.Warnf("Failed to import %q: %v", file.page.Title, err)
+			stats.Failed++
+			continue
+		}
+		stats.Pages++
+		stats.Files = append(stats.Files, file.path)
+	}
+
+	if stats.Failed > 0 {
+		return stats, fmt.Errorf("%d pages failed to import", stats.Failed)
+	}
+	return stats, nil
+}
+
+// planFiles assigns a markdown file to every page following the page tree
+func planFiles(pages []Page, outputDir string) []*pageFile {
+	included := make(map[string]bool)
+	for _, page := range pages {
+		included[page.ID] = true
+	}
+
+	// Only ancestors that are imported as well become directories
+	parents := make(map[string][]string)
+	hasChildren := make(map[string]bool)
+	for _, page := range pages {
+		for _, ancestor := range page.Ancestors {
+			if included[ancestor.ID] {
+				parents[page.ID] = append(parents[page.ID], ancestor.ID)
+			}
+		}
+		if p := parents[page.ID]; len(p) > 0 {
+			hasChildren[p[len(p)-1]] = true
+		}
+	}
+
+	slugs := make(map[string]string)
+	taken := make(map[string]bool)
+	for _, page := range pages {
+		parent := ""
+		if p := parents[page.ID]; len(p) > 0 {
+			parent = p[len(p)-1]
+		}
+		slug := fsutil.Slugify(page.Title)
+		if slug == "" || taken[parent+"/"+slug] {
+			slug = strings.Trim(slug+"-"+page.ID, "-")
+		}
+		taken[parent+"/"+slug] = true
+		slugs[page.ID] = slug
+	}
+
+	var files []*pageFile
+	for i := range pages {
+		page := &pages[i]
+		var dir []string
+		for _, id := range parents[page.ID] {
+			dir = append(dir, slugs[id])
+		}
+		path := filepath.Join(append([]string{outputDir}, dir...)...)
+		if hasChildren[page.ID] {
+			path = filepath.Join(path, slugs[page.ID], "index.md")
+		} else {
+			path = filepath.Join(path, slugs[page.ID]+".md")
+		}
+		files = append(files, &pageFile{page: page, slug: slugs[page.ID], path: path})
+	}
+	return files
+}
+
+// importPage converts one page and downloads its images
+func importPage(ctx context.Context, client *Client, file *pageFile, byTitle map[string]*pageFile, dryRun bool, stats *ImportStats) error {
+	dir := filepath.Dir(file.path)
+	attachmentDir := filepath.Join("images", file.slug)
+	referenced := make(map[string]bool)
+
+	resolver := Resolver{
+		Attachment: func(filename string) string {
+			referenced[filename] = true
+			return linkPath(filepath.Join(attachmentDir, filename))
+		},
+		Page: func(title string) string {
+			target, ok := byTitle[title]
+			if !ok {
+				return ""
+			}
+			rel, err := filepath.Rel(dir, target.path)
+			if err != nil {
+				return ""
+			}
+			return linkPath(rel)
+		},
+	}
+
+	body, err := ToMarkdown(file.page.Body.Storage.Value, resolver)
+	if err != nil {
+		return err
+	}
+
+	header, err := yaml.Marshal(frontMatter{Title: file.page.Title, ID: file.page.ID, Version: file.page.Version.Number})
+	if err != nil {
+		return fmt.Errorf("failed to marshal front matter: %v", err)
+	}
+	content := fmt.Sprintf("---\n%s---\n\n%s", header, body)
+
+	if dryRun {
+		logger.Infof("Would write %q to %s (%d attachments)", file.page.Title, file.path, len(referenced))
+		return nil
+	}
+	logger.Infof("Importing %q to %s", file.page.Title, file.path)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+	if len(referenced) > 0 {
+		n, err := downloadAttachments(ctx, client, file.page.ID, filepath.Join(dir, attachmentDir), referenced)
+		stats.Attachments += n
+		if err != nil {
+			return err
+		}
+	}
+
+	// External images are downloaded the same way as `mdctl download` does
+	if strings.Contains(content, "](http") {
+		p := processor.New("", "", "")
+		if content, err = p.ProcessContent(content, file.path); err != nil {
+			return err
+		}
+		stats.Images += p.Stats.DownloadedImages
+	}
+
+	return fsutil.WriteFileAtomic(file.path, []byte(content), 0644)
+}
+
+// downloadAttachments saves the referenced attachments of a page into dir
+func downloadAttachments(ctx context.Context, client *Client, pageID, dir string, referenced map[string]bool) (int, error) {
+	attachments, err := client.Attachments(ctx, pageID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list attachments: %v", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create attachment directory: %v", err)
+	}
+
+	downloaded := 0
+	for _, attachment := range attachments {
+		if !referenced[attachment.Title] {
+			continue
+		}
+		delete(referenced, attachment.Title)
+
+		out, err := os.Create(filepath.Join(dir, filepath.Base(attachment.Title)))
+		if err != nil {
+			return downloaded, fmt.Errorf("failed to create attachment: %v", err)
+		}
+		err = client.Download(ctx, attachment.Links.Download, out)
+		out.Close()
+		if err != nil {
+			return downloaded, err
+		}
+		downloaded++
+	}
+
+	for filename := range referenced {
+		logger.Warnf("Attachment %s of page %s not found", filename, pageID)
+	}
+	return downloaded, nil
+}
+
+// linkPath formats a relative file path as a markdown link destination
+func linkPath(path string) string {
+	return strings.ReplaceAll(filepath.ToSlash(path), " ", "%20")
+}
@@ -0,0 +1,89 @@
+package confluence
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	pages := []map[string]interface{}{
+		{
+			"id": "1", "title": "Home", "version": map[string]int{"number": 3},
+			"ancestors": []interface{}{},
+			"body": map[string]interface{}{"storage": map[string]string{
+				"value": `<p>Start with <ac:link><ri:page ri:content-title="Getting Started" /></ac:link></p>`,
+			}},
+		},
+		{
+			"id": "2", "title": "Getting Started", "version": map[string]int{"number": 1},
+			"ancestors": []map[string]string{{"id": "1", "title": "Home"}},
+			"body": map[string]interface{}{"storage": map[string]string{
+				"value": `<p><ac:image><ri:attachment ri:filename="shot.png" /></ac:image></p><p>Back <ac:link><ri:page ri:content-title="Home" /></ac:link></p>`,
+			}},
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/wiki/rest/api/content", func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "me@example.com" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("spaceKey") != "DOCS" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": pages, "size": len(pages)})
+	})
+	mux.HandleFunc("/wiki/rest/api/content/2/child/attachment", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{
+				{"id": "a1", "title": "shot.png", "_links": map[string]string{"download": "/download/attachments/2/shot.png"}},
+				{"id": "a2", "title": "unused.pdf", "_links": map[string]string{"download": "/download/attachments/2/unused.pdf"}},
+			},
+			"size": 2,
+		})
+	})
+	mux.HandleFunc("/wiki/download/attachments/2/shot.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("png"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	dir := t.TempDir()
+	client := NewClient(server.URL+"/wiki/", "me@example.com", "secret")
+	stats, err := Import(context.Background(), client, ImportOptions{Space: "DOCS", OutputDir: dir})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if stats.Pages != 2 || stats.Attachments != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	home, err := os.ReadFile(filepath.Join(dir, "home", "index.md"))
+	if err != nil {
+		t.Fatalf("home page not written: %v", err)
+	}
+	if !strings.Contains(string(home), "confluence_id: \"1\"") || !strings.Contains(string(home), "[Getting Started](getting-started.md)") {
+		t.Errorf("unexpected home page:\n%s", home)
+	}
+
+	guide, err := os.ReadFile(filepath.Join(dir, "home", "getting-started.md"))
+	if err != nil {
+		t.Fatalf("child page not written: %v", err)
+	}
+	if !strings.Contains(string(guide), "![](images/getting-started/shot.png)") || !strings.Contains(string(guide), "[Home](index.md)") {
+		t.Errorf("unexpected child page:\n%s", guide)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "home", "images", "getting-started", "shot.png")); err != nil || string(data) != "png" {
+		t.Errorf("attachment not downloaded: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "home", "images", "getting-started", "unused.pdf")); err == nil {
+		t.Errorf("unreferenced attachment downloaded")
+	}
+}
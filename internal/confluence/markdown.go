@@ -0,0 +1,431 @@
+package confluence
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+var (
+	// cdataRegex matches the CDATA sections macros wrap plain text bodies in
+	cdataRegex = regexp.MustCompile(`(?s)<!\[CDATA\[(.*?)\]\]>`)
+	// selfClosingRegex matches self-closing Confluence elements, which the
+	// HTML parser would otherwise leave open
+	selfClosingRegex = regexp.MustCompile(`<((?:ac|ri):[\w-]+)([^<>]*?)\s*/>`)
+	// spaceRegex matches runs of whitespace collapsed in inline text
+	spaceRegex = regexp.MustCompile(`\s+`)
+)
+
+// Resolver maps Confluence references to markdown link destinations
+type Resolver struct {
+	// Attachment returns the destination of a file attached to the page
+	Attachment func(filename string) string
+	// Page returns the destination of another page by title, empty if the
+	// page is not part of the import
+	Page func(title string) string
+}
+
+// ToMarkdown converts a page body in Confluence storage format to markdown
+func ToMarkdown(storage string, resolver Resolver) (string, error) {
+	storage = cdataRegex.ReplaceAllStringFunc(storage, func(match string) string {
+		return html.EscapeString(cdataRegex.FindStringSubmatch(match)[1])
+	})
+	storage = selfClosingRegex.ReplaceAllString(storage, "<$1$2></$1>")
+
+	doc, err := xhtml.Parse(strings.NewReader("<html><body>" + storage + "</body></html>"))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse storage format: %v", err)
+	}
+	body := findElement(doc, "body")
+	if body == nil {
+		return "", nil
+	}
+
+	c := &converter{resolver: resolver}
+	return strings.TrimSpace(c.blocks(body, "\n\n")) + "\n", nil
+}
+
+// converter renders the elements of a storage-format document
+type converter struct {
+	resolver Resolver
+}
+
+// blockElements start a new markdown block
+var blockElements = map[string]bool{
+	"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "pre": true, "blockquote": true, "table": true, "hr": true,
+	"div": true, "section": true, "ac:structured-macro": true, "ac:task-list": true,
+	"ac:layout": true, "ac:layout-section": true, "ac:layout-cell": true, "ac:rich-text-body": true,
+}
+
+// blocks renders the children of n as blocks joined by sep, consecutive
+// inline content forms a paragraph
+func (c *converter) blocks(n *xhtml.Node, sep string) string {
+	var out []string
+	var inline strings.Builder
+
+	flush := func() {
+		if text := trimLines(inline.String()); text != "" {
+			out = append(out, text)
+		}
+		inline.Reset()
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == xhtml.ElementNode && blockElements[child.Data] {
+			flush()
+			if block := c.block(child); strings.TrimSpace(block) != "" {
+				out = append(out, block)
+			}
+			continue
+		}
+		inline.WriteString(c.inline(child))
+	}
+	flush()
+
+	return strings.Join(out, sep)
+}
+
+// block renders a block element
+func (c *converter) block(n *xhtml.Node) string {
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(n.Data[1] - '0')
+		return strings.Repeat("#", level) + " " + trimLines(c.inlineChildren(n))
+	case "ul":
+		return c.list(n, false)
+	case "ol":
+		return c.list(n, true)
+	case "pre":
+		return fence(textContent(n), "")
+	case "blockquote":
+		return quote(c.blocks(n, "\n\n"))
+	case "table":
+		return c.table(n)
+	case "hr":
+		return "---"
+	case "ac:structured-macro":
+		return c.macro(n)
+	case "ac:task-list":
+		return c.tasks(n)
+	default:
+		return c.blocks(n, "\n\n")
+	}
+}
+
+// inline renders a node inside a paragraph
+func (c *converter) inline(n *xhtml.Node) string {
+	switch n.Type {
+	case xhtml.TextNode:
+		return spaceRegex.ReplaceAllString(n.Data, " ")
+	case xhtml.ElementNode:
+	default:
+		return ""
+	}
+
+	switch n.Data {
+	case "strong", "b":
+		return wrap(c.inlineChildren(n), "**")
+	case "em", "i":
+		return wrap(c.inlineChildren(n), "*")
+	case "s", "del":
+		return wrap(c.inlineChildren(n), "~~")
+	case "code":
+		return "`" + textContent(n) + "`"
+	case "br":
+		return "  \n"
+	case "a":
+		return fmt.Sprintf("[%s](%s)", strings.TrimSpace(c.inlineChildren(n)), attr(n, "href"))
+	case "img":
+		return fmt.Sprintf("![%s](%s)", attr(n, "alt"), attr(n, "src"))
+	case "ac:image":
+		return c.image(n)
+	case "ac:link":
+		return c.link(n)
+	case "ac:parameter", "ac:placeholder", "ac:emoticon":
+		return ""
+	case "ac:structured-macro":
+		return c.macro(n)
+	}
+	return c.inlineChildren(n)
+}
+
+// inlineChildren renders the children of n as inline content
+func (c *converter) inlineChildren(n *xhtml.Node) string {
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(c.inline(child))
+	}
+	return sb.String()
+}
+
+// image renders an attached or external image
+func (c *converter) image(n *xhtml.Node) string {
+	alt := attr(n, "ac:alt")
+	if ref := findElement(n, "ri:attachment"); ref != nil {
+		return fmt.Sprintf("![%s](%s)", alt, c.attachment(attr(ref, "ri:filename")))
+	}
+	if ref := findElement(n, "ri:url"); ref != nil {
+		return fmt.Sprintf("![%s](%s)", alt, attr(ref, "ri:value"))
+	}
+	return ""
+}
+
+// link renders a link to a page, an attachment or an anchor
+func (c *converter) link(n *xhtml.Node) string {
+	var text string
+	if body := findElement(n, "ac:plain-text-link-body"); body != nil {
+		text = textContent(body)
+	} else if body := findElement(n, "ac:link-body"); body != nil {
+		text = c.inlineChildren(body)
+	}
+	text = strings.TrimSpace(text)
+
+	dest := ""
+	if ref := findElement(n, "ri:page"); ref != nil {
+		title := attr(ref, "ri:content-title")
+		if text == "" {
+			text = title
+		}
+		if c.resolver.Page != nil {
+			dest = c.resolver.Page(title)
+		}
+	} else if ref := findElement(n, "ri:attachment"); ref != nil {
+		filename := attr(ref, "ri:filename")
+		if text == "" {
+			text = filename
+		}
+		dest = c.attachment(filename)
+	}
+	if anchor := attr(n, "ac:anchor"); anchor != "" {
+		dest += "#" + anchor
+	}
+
+	if dest == "" {
+		return text
+	}
+	return fmt.Sprintf("[%s](%s)", text, dest)
+}
+
+// attachment resolves the destination of an attached file
+func (c *converter) attachment(filename string) string {
+	if c.resolver.Attachment == nil {
+		return filename
+	}
+	return c.resolver.Attachment(filename)
+}
+
+// macro renders the structured macros that have a markdown equivalent
+func (c *converter) macro(n *xhtml.Node) string {
+	name := attr(n, "ac:name")
+	switch name {
+	case "code", "noformat":
+		body := ""
+		if plain := findElement(n, "ac:plain-text-body"); plain != nil {
+			body = textContent(plain)
+		}
+		return fence(body, parameter(n, "language"))
+	case "info", "note", "warning", "tip", "panel":
+		label := parameter(n, "title")
+		if label == "" {
+			label = strings.ToUpper(name[:1]) + name[1:]
+		}
+		return quote("**" + label + "**\n\n" + c.richBody(n))
+	case "expand":
+		title := parameter(n, "title")
+		if title == "" {
+			title = "Details"
+		}
+		return fmt.Sprintf("<details>\n<summary>%s</summary>\n\n%s\n\n</details>", html.EscapeString(title), c.richBody(n))
+	case "toc", "children", "anchor", "pagetree":
+		return ""
+	}
+	return c.richBody(n)
+}
+
+// richBody renders the rich text body of a macro
+func (c *converter) richBody(n *xhtml.Node) string {
+	if body := findElement(n, "ac:rich-text-body"); body != nil {
+		return c.blocks(body, "\n\n")
+	}
+	return ""
+}
+
+// list renders a bulleted or numbered list, nested lists are indented
+func (c *converter) list(n *xhtml.Node, ordered bool) string {
+	var items []string
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != xhtml.ElementNode || li.Data != "li" {
+			continue
+		}
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", len(items)+1)
+		}
+		items = append(items, marker+indent(c.blocks(li, "\n"), len(marker)))
+	}
+	return strings.Join(items, "\n")
+}
+
+// tasks renders a task list as a GFM checklist
+func (c *converter) tasks(n *xhtml.Node) string {
+	var items []string
+	for task := n.FirstChild; task != nil; task = task.NextSibling {
+		if task.Type != xhtml.ElementNode || task.Data != "ac:task" {
+			continue
+		}
+		check := " "
+		if status := findElement(task, "ac:task-status"); status != nil && strings.TrimSpace(textContent(status)) == "complete" {
+			check = "x"
+		}
+		body := ""
+		if b := findElement(task, "ac:task-body"); b != nil {
+			body = trimLines(c.inlineChildren(b))
+		}
+		items = append(items, fmt.Sprintf("- [%s] %s", check, body))
+	}
+	return strings.Join(items, "\n")
+}
+
+// table renders a table as a GFM table, the first row being the header
+func (c *converter) table(n *xhtml.Node) string {
+	var rows [][]string
+	columns := 0
+	walk(n, func(node *xhtml.Node) bool {
+		if node.Type != xhtml.ElementNode || node.Data != "tr" {
+			return true
+		}
+		var row []string
+		for cell := node.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.Type == xhtml.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+				text := c.blocks(cell, "\n")
+				text = strings.ReplaceAll(text, "|", "\\|")
+				row = append(row, strings.ReplaceAll(text, "\n", "<br>"))
+			}
+		}
+		if len(row) > columns {
+			columns = len(row)
+		}
+		rows = append(rows, row)
+		return false
+	})
+	if len(rows) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			sb.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// parameter returns the value of a macro parameter
+func parameter(n *xhtml.Node, name string) string {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == xhtml.ElementNode && child.Data == "ac:parameter" && attr(child, "ac:name") == name {
+			return strings.TrimSpace(textContent(child))
+		}
+	}
+	return ""
+}
+
+// fence renders code as a fenced code block
+func fence(code, lang string) string {
+	marker := "```"
+	for strings.Contains(code, marker) {
+		marker += "`"
+	}
+	return marker + lang + "\n" + strings.Trim(code, "\n") + "\n" + marker
+}
+
+// quote prefixes every line of a block with "> "
+func quote(block string) string {
+	lines := strings.Split(block, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("> "+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indent indents all lines of a list item after the first
+func indent(block string, width int) string {
+	return strings.ReplaceAll(block, "\n", "\n"+strings.Repeat(" ", width))
+}
+
+// wrap surrounds inline text with a marker, keeping surrounding spaces outside
+func wrap(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	lead := text[:strings.Index(text, trimmed)]
+	trail := text[len(lead)+len(trimmed):]
+	return lead + marker + trimmed + marker + trail
+}
+
+// trimLines trims the whitespace around the lines of a paragraph, keeping hard line breaks
+func trimLines(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		line = strings.TrimLeft(line, " ")
+		if !strings.HasSuffix(line, "  ") || i == len(lines)-1 {
+			line = strings.TrimRight(line, " ")
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// textContent returns the text of a node and its descendants
+func textContent(n *xhtml.Node) string {
+	var sb strings.Builder
+	walk(n, func(node *xhtml.Node) bool {
+		if node.Type == xhtml.TextNode {
+			sb.WriteString(node.Data)
+		}
+		return true
+	})
+	return sb.String()
+}
+
+// findElement returns the first descendant element with a tag name
+func findElement(n *xhtml.Node, tag string) *xhtml.Node {
+	var found *xhtml.Node
+	walk(n, func(node *xhtml.Node) bool {
+		if found == nil && node != n && node.Type == xhtml.ElementNode && node.Data == tag {
+			found = node
+		}
+		return found == nil
+	})
+	return found
+}
+
+// walk visits n and its descendants, children are skipped when fn returns false
+func walk(n *xhtml.Node, fn func(*xhtml.Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		walk(child, fn)
+	}
+}
+
+// attr returns the value of an attribute
+func attr(n *xhtml.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
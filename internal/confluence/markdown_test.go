@@ -0,0 +1,45 @@
+package confluence
+
+import (
+	"testing"
+)
+
+func TestToMarkdown(t *testing.T) {
+	storage := `<h1>Intro</h1><p>Hello <strong>world </strong>and <em>you</em>.<br/>Next</p>
+<ul><li><p>one</p><ul><li>nested</li></ul></li><li>two</li></ul>
+<ac:structured-macro ac:name="code"><ac:parameter ac:name="language">go</ac:parameter><ac:plain-text-body><![CDATA[fmt.Println("<hi>")
+]]></ac:plain-text-body></ac:structured-macro>
+<p><ac:image ac:alt="diagram"><ri:attachment ri:filename="arch.png" /></ac:image></p>
+<p>See <ac:link><ri:page ri:content-title="Setup" /><ac:plain-text-link-body><![CDATA[the setup]]></ac:plain-text-link-body></ac:link> and <ac:link><ri:page ri:content-title="Elsewhere" /></ac:link>.</p>
+<table><tbody><tr><th>A</th><th>B</th></tr><tr><td><p>1|x</p></td><td>2</td></tr></tbody></table>
+<ac:structured-macro ac:name="info"><ac:rich-text-body><p>Be careful</p></ac:rich-text-body></ac:structured-macro>
+<ac:structured-macro ac:name="toc" />
+<ac:task-list><ac:task><ac:task-id>1</ac:task-id><ac:task-status>complete</ac:task-status><ac:task-body>done it</ac:task-body></ac:task></ac:task-list>`
+
+	resolver := Resolver{
+		Attachment: func(filename string) string { return "images/intro/" + filename },
+		Page: func(title string) string {
+			if title == "Setup" {
+				return "setup.md"
+			}
+			return ""
+		},
+	}
+	got, err := ToMarkdown(storage, resolver)
+	if err != nil {
+		t.Fatalf("ToMarkdown failed: %v", err)
+	}
+
+	want := "# Intro\n\n" +
+		"Hello **world** and *you*.  \nNext\n\n" +
+		"- one\n  - nested\n- two\n\n" +
+		"```go\nfmt.Println(\"<hi>\")\n```\n\n" +
+		"![diagram](images/intro/arch.png)\n\n" +
+		"See [the setup](setup.md) and Elsewhere.\n\n" +
+		"| A | B |\n| --- | --- |\n| 1\\|x | 2 |\n\n" +
+		"> **Info**\n>\n> Be careful\n\n" +
+		"- [x] done it\n"
+	if got != want {
+		t.Errorf("unexpected markdown:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,265 @@
+package confluence
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// imgRegex matches the images of pandoc HTML output
+	imgRegex = regexp.MustCompile(`<img\s([^>]*?)\s*/?>`)
+	// attrRegex matches the attributes of an HTML element
+	attrRegex = regexp.MustCompile(`([\w-]+)="([^"]*)"`)
+	// preRegex matches the code blocks of pandoc HTML output
+	preRegex = regexp.MustCompile(`(?s)<pre(?: class="([^"]*)")?><code>(.*?)</code></pre>`)
+	// anchorRegex matches the links of pandoc HTML output
+	anchorRegex = regexp.MustCompile(`(?s)<a href="([^"]*)"[^>]*>(.*?)</a>`)
+	// headingRegex matches the first ATX heading of a document
+	headingRegex = regexp.MustCompile(`(?m)^#[ \t]+(.+?)[ \t#]*$`)
+)
+
+// PublishOptions controls where markdown files are published
+type PublishOptions struct {
+	Space    string // Space key the pages are created in
+	ParentID string // Page the new pages are created below, empty for the top of the space
+	DryRun   bool
+}
+
+// PublishedPage is a markdown file published as a page
+type PublishedPage struct {
+	File   string `json:"file"`
+	ID     string `json:"id,omitempty"`
+	Title  string `json:"title"`
+	Action string `json:"action"` // created or updated
+}
+
+// PublishStats describes a publish run
+type PublishStats struct {
+	Created     int             `json:"created"`
+	Updated     int             `json:"updated"`
+	Attachments int             `json:"attachments"`
+	Pages       []PublishedPage `json:"pages"`
+}
+
+// document is a markdown file about to be published
+type document struct {
+	path  string
+	title string
+	id    string // Page ID from the confluence_id front matter
+	body  string
+}
+
+// Publish converts markdown files to storage format with pandoc and creates
+// or updates one page per file. Pages imported by Import are updated in place,
+// others are matched by title within the space. Local images are uploaded as
+// attachments and links between the files become page links.
+func Publish(ctx context.Context, client *Client, files []string, opts PublishOptions) (*PublishStats, error) {
+	docs := make([]*document, 0, len(files))
+	titles := make(map[string]string)
+	for _, file := range files {
+		doc, err := readDocument(file)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+		if abs, err := filepath.Abs(file); err == nil {
+			titles[abs] = doc.title
+		}
+	}
+
+	stats := &PublishStats{}
+	for _, doc := range docs {
+		if ctx.Err() != nil {
+			return stats, ctx.Err()
+		}
+
+		rendered, err := pandocHTML(ctx, doc.body)
+		if err != nil {
+			return stats, fmt.Errorf("failed to convert %s: %v", doc.path, err)
+		}
+		storage, images := storageFromHTML(rendered, filepath.Dir(doc.path), titles)
+
+		if opts.DryRun {
+			logger.Infof("Would publish %s as %q with %d attachments", doc.path, doc.title, len(images))
+			stats.Pages = append(stats.Pages, PublishedPage{File: doc.path, ID: doc.id, Title: doc.title, Action: "dry-run"})
+			continue
+		}
+
+		published, err := publishPage(ctx, client, doc, storage, opts)
+		if err != nil {
+			return stats, fmt.Errorf("failed to publish %s: %v", doc.path, err)
+		}
+		if published.Action == "created" {
+			stats.Created++
+		} else {
+			stats.Updated++
+		}
+		stats.Pages = append(stats.Pages, *published)
+
+		for _, image := range images {
+			if err := client.UploadAttachment(ctx, published.ID, image); err != nil {
+				return stats, err
+			}
+			stats.Attachments++
+		}
+	}
+	return stats, nil
+}
+
+// publishPage creates the page of a document or updates its existing page
+func publishPage(ctx context.Context, client *Client, doc *document, storage string, opts PublishOptions) (*PublishedPage, error) {
+	var existing *Page
+	var err error
+	if doc.id != "" {
+		existing, err = client.GetPage(ctx, doc.id)
+	} else {
+		existing, err = client.FindPage(ctx, opts.Space, doc.title)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		logger.Infof("Updating page %q (%s)", doc.title, existing.ID)
+		if err := client.UpdatePage(ctx, existing, doc.title, storage); err != nil {
+			return nil, err
+		}
+		return &PublishedPage{File: doc.path, ID: existing.ID, Title: doc.title, Action: "updated"}, nil
+	}
+
+	logger.Infof("Creating page %q", doc.title)
+	page, err := client.CreatePage(ctx, opts.Space, opts.ParentID, doc.title, storage)
+	if err != nil {
+		return nil, err
+	}
+	return &PublishedPage{File: doc.path, ID: page.ID, Title: doc.title, Action: "created"}, nil
+}
+
+// readDocument reads a markdown file and determines its page title from the
+// front matter, the first heading or the file name
+func readDocument(path string) (*document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	doc := &document{path: path, body: string(data)}
+
+	if strings.HasPrefix(doc.body, "---\n") {
+		if parts := strings.SplitN(doc.body[4:], "\n---\n", 2); len(parts) == 2 {
+			var meta frontMatter
+			if err := yaml.Unmarshal([]byte(parts[0]), &meta); err != nil {
+				return nil, fmt.Errorf("failed to parse front matter of %s: %v", path, err)
+			}
+			doc.title, doc.id, doc.body = meta.Title, meta.ID, parts[1]
+		}
+	}
+
+	if doc.title == "" {
+		// The heading becomes the page title, so it is not repeated in the body
+		if loc := headingRegex.FindStringSubmatchIndex(doc.body); loc != nil {
+			doc.title = doc.body[loc[2]:loc[3]]
+			doc.body = doc.body[:loc[0]] + doc.body[loc[1]:]
+		} else {
+			doc.title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+	}
+	return doc, nil
+}
+
+// pandocHTML renders markdown to HTML with pandoc
+func pandocHTML(ctx context.Context, markdown string) (string, error) {
+	cmd := exec.CommandContext(ctx, "pandoc", "-f", "gfm", "-t", "html", "--wrap=none", "--no-highlight")
+	cmd.Stdin = strings.NewReader(markdown)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("pandoc failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// storageFromHTML turns pandoc HTML into storage format: code blocks become
+// code macros, images become attachments or external images, and links to
+// published files become page links. It returns the local images to upload.
+func storageFromHTML(content, dir string, titles map[string]string) (string, []string) {
+	content = preRegex.ReplaceAllStringFunc(content, func(match string) string {
+		sub := preRegex.FindStringSubmatch(match)
+		lang := ""
+		for _, class := range strings.Fields(sub[1]) {
+			if class != "sourceCode" {
+				lang = class
+			}
+		}
+		code := strings.ReplaceAll(html.UnescapeString(sub[2]), "]]>", "]]]]><![CDATA[>")
+
+		var sb strings.Builder
+		sb.WriteString(`<ac:structured-macro ac:name="code">`)
+		if lang != "" {
+			sb.WriteString(`<ac:parameter ac:name="language">` + html.EscapeString(lang) + `</ac:parameter>`)
+		}
+		sb.WriteString(`<ac:plain-text-body><![CDATA[` + code + `]]></ac:plain-text-body></ac:structured-macro>`)
+		return sb.String()
+	})
+
+	var images []string
+	seen := make(map[string]bool)
+	content = imgRegex.ReplaceAllStringFunc(content, func(match string) string {
+		attrs := make(map[string]string)
+		for _, a := range attrRegex.FindAllStringSubmatch(imgRegex.FindStringSubmatch(match)[1], -1) {
+			attrs[a[1]] = a[2]
+		}
+		src := html.UnescapeString(attrs["src"])
+		image := `<ac:image ac:alt="` + attrs["alt"] + `">`
+
+		if u, err := url.Parse(src); err != nil || u.Scheme != "" || src == "" {
+			return image + `<ri:url ri:value="` + html.EscapeString(src) + `" /></ac:image>`
+		}
+		if unescaped, err := url.PathUnescape(src); err == nil {
+			src = unescaped
+		}
+		path := filepath.Join(dir, filepath.FromSlash(src))
+		if !seen[path] {
+			seen[path] = true
+			images = append(images, path)
+		}
+		return image + `<ri:attachment ri:filename="` + html.EscapeString(filepath.Base(path)) + `" /></ac:image>`
+	})
+
+	content = anchorRegex.ReplaceAllStringFunc(content, func(match string) string {
+		sub := anchorRegex.FindStringSubmatch(match)
+		target, anchor, _ := strings.Cut(html.UnescapeString(sub[1]), "#")
+		if target == "" || strings.Contains(target, "://") {
+			return match
+		}
+		if unescaped, err := url.PathUnescape(target); err == nil {
+			target = unescaped
+		}
+		abs, err := filepath.Abs(filepath.Join(dir, filepath.FromSlash(target)))
+		if err != nil {
+			return match
+		}
+		title, ok := titles[abs]
+		if !ok {
+			return match
+		}
+
+		link := `<ac:link`
+		if anchor != "" {
+			link += ` ac:anchor="` + html.EscapeString(anchor) + `"`
+		}
+		return link + `><ri:page ri:content-title="` + html.EscapeString(title) + `" /><ac:link-body>` + sub[2] + `</ac:link-body></ac:link>`
+	})
+
+	return content, images
+}
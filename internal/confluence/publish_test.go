@@ -0,0 +1,60 @@
+package confluence
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStorageFromHTML(t *testing.T) {
+	dir := t.TempDir()
+	guide, _ := filepath.Abs(filepath.Join(dir, "guide.md"))
+	titles := map[string]string{guide: "User Guide"}
+
+	content := `<p><img src="img/a%20b.png" alt="shot" /> <img src="https://example.com/x.png" alt="" /></p>
+<pre class="go"><code>if a &lt; b &amp;&amp; s == &quot;]]&gt;&quot; {}
+</code></pre>
+<p>See <a href="guide.md#install">the <em>guide</em></a> or <a href="https://example.com">site</a>.</p>`
+
+	storage, images := storageFromHTML(content, dir, titles)
+
+	for _, want := range []string{
+		`<ac:image ac:alt="shot"><ri:attachment ri:filename="a b.png" /></ac:image>`,
+		`<ac:image ac:alt=""><ri:url ri:value="https://example.com/x.png" /></ac:image>`,
+		`<ac:parameter ac:name="language">go</ac:parameter><ac:plain-text-body><![CDATA[if a < b && s == "]]]]><![CDATA[>" {}`,
+		`<ac:link ac:anchor="install"><ri:page ri:content-title="User Guide" /><ac:link-body>the <em>guide</em></ac:link-body></ac:link>`,
+		`<a href="https://example.com">site</a>`,
+	} {
+		if !strings.Contains(storage, want) {
+			t.Errorf("expected %s in:\n%s", want, storage)
+		}
+	}
+	if len(images) != 1 || images[0] != filepath.Join(dir, "img", "a b.png") {
+		t.Errorf("unexpected images: %v", images)
+	}
+}
+
+func TestReadDocument(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"imported.md": "---\ntitle: Imported\nconfluence_id: \"42\"\n---\n\nBody\n",
+		"heading.md":  "Intro\n\n# Install Guide\n\nSteps\n",
+		"plain.md":    "Just text\n",
+	} {
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+
+	doc, _ := readDocument(filepath.Join(dir, "imported.md"))
+	if doc.title != "Imported" || doc.id != "42" || doc.body != "\nBody\n" {
+		t.Errorf("unexpected document from front matter: %+v", doc)
+	}
+	doc, _ = readDocument(filepath.Join(dir, "heading.md"))
+	if doc.title != "Install Guide" || strings.Contains(doc.body, "# Install") {
+		t.Errorf("unexpected document from heading: %+v", doc)
+	}
+	doc, _ = readDocument(filepath.Join(dir, "plain.md"))
+	if doc.title != "plain" {
+		t.Errorf("unexpected title from file name: %q", doc.title)
+	}
+}
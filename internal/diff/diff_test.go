@@ -0,0 +1,28 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n"
+	b := "one\ntwo\nthree\nFOUR\nfive\nsix\nseven\neight\n"
+
+	want := `--- a.md
++++ b.md
+@@ -1,7 +1,7 @@
+ one
+ two
+ three
+-four
++FOUR
+ five
+ six
+ seven
+`
+	if got := Unified("a.md", "b.md", a, b); got != want {
+		t.Errorf("unexpected diff:\n%s", got)
+	}
+
+	if got := Unified("a.md", "b.md", a, a); got != "" {
+		t.Errorf("expected no diff for equal input, got:\n%s", got)
+	}
+}
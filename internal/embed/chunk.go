@@ -0,0 +1,173 @@
+// Package embed splits markdown into chunks by headings, computes their
+// embeddings with an OpenAI-compatible endpoint and writes them to JSONL
+// files or vector stores for retrieval-augmented generation
+package embed
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/mddoc"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultMaxChars is the default size limit of a chunk
+const DefaultMaxChars = 2000
+
+// Metadata describes where a chunk comes from
+type Metadata struct {
+	Path    string   `json:"path"`              // Relative to the root, with forward slashes
+	Title   string   `json:"title"`             // Title of the document
+	Heading string   `json:"heading,omitempty"` // Heading of the section, empty before the first heading
+	Section []string `json:"section,omitempty"` // Headings from the top of the document down to the section
+	Line    int      `json:"line"`              // Line the chunk starts at
+	Index   int      `json:"index"`             // Position of the chunk within the document
+}
+
+// Chunk is a piece of a document embedded on its own
+type Chunk struct {
+	ID       string    `json:"id"`
+	Text     string    `json:"text"`
+	Metadata Metadata  `json:"metadata"`
+	Vector   []float32 `json:"vector,omitempty"`
+}
+
+// Split splits a markdown document into one chunk per heading section.
+// Sections longer than maxChars are split further at blank lines outside
+// code blocks. rel names the document in the chunk IDs and metadata.
+func Split(rel string, content []byte, maxChars int) []Chunk {
+	if maxChars <= 0 {
+		maxChars = DefaultMaxChars
+	}
+	doc := mddoc.Parse(content)
+	headings := doc.Headings()
+
+	title := documentTitle(doc, headings)
+	if title == "" {
+		title = strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+	}
+
+	// Section boundaries are the headings, the text before the first heading
+	// is a section of its own
+	type section struct {
+		start   int
+		body    int // Offset after the heading
+		heading string
+		trail   []string
+	}
+	sections := []section{{start: doc.BodyStart, body: doc.BodyStart}}
+	var trail []mddoc.Heading
+	for _, h := range headings {
+		for len(trail) > 0 && trail[len(trail)-1].Level >= h.Level {
+			trail = trail[:len(trail)-1]
+		}
+		trail = append(trail, h)
+		texts := make([]string, len(trail))
+		for i, t := range trail {
+			texts[i] = t.Text
+		}
+		sections = append(sections, section{start: h.Start, body: h.End, heading: h.Text, trail: texts})
+	}
+
+	var chunks []Chunk
+	for i, s := range sections {
+		end := len(content)
+		if i+1 < len(sections) {
+			end = sections[i+1].start
+		}
+		// A heading without content carries no information of its own
+		if s.body >= end || strings.TrimSpace(string(content[s.body:end])) == "" {
+			continue
+		}
+		for _, piece := range splitText(string(content[s.start:end]), s.start, maxChars) {
+			text := strings.TrimSpace(piece.text)
+			if text == "" {
+				continue
+			}
+			chunks = append(chunks, Chunk{
+				ID:   fmt.Sprintf("%s#%d", rel, len(chunks)),
+				Text: text,
+				Metadata: Metadata{
+					Path:    rel,
+					Title:   title,
+					Heading: s.heading,
+					Section: s.trail,
+					Line:    doc.Line(piece.offset + leadingSpace(piece.text)),
+					Index:   len(chunks),
+				},
+			})
+		}
+	}
+	return chunks
+}
+
+// piece is a part of a section and its offset in the document
+type piece struct {
+	text   string
+	offset int
+}
+
+// splitText splits text into pieces of at most maxChars at blank lines that
+// are not inside fenced code blocks. Paragraphs longer than maxChars stay whole.
+func splitText(text string, offset, maxChars int) []piece {
+	if len(text) <= maxChars {
+		return []piece{{text, offset}}
+	}
+
+	// Blocks are the runs of lines between blank lines outside code fences
+	var blocks []piece
+	start, pos := 0, 0
+	fence := ""
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		case trimmed == "" && pos > start:
+			blocks = append(blocks, piece{text[start:pos], offset + start})
+			start = pos
+		}
+		pos += len(line)
+	}
+	if start < len(text) {
+		blocks = append(blocks, piece{text[start:], offset + start})
+	}
+
+	var pieces []piece
+	for _, block := range blocks {
+		if n := len(pieces); n > 0 && len(pieces[n-1].text)+len(block.text) <= maxChars {
+			pieces[n-1].text += block.text
+			continue
+		}
+		pieces = append(pieces, block)
+	}
+	return pieces
+}
+
+// leadingSpace returns the length of the whitespace a text starts with
+func leadingSpace(text string) int {
+	return len(text) - len(strings.TrimLeft(text, " \t\r\n"))
+}
+
+// documentTitle returns the title of the front matter or the first level 1 heading
+func documentTitle(doc *mddoc.Document, headings []mddoc.Heading) string {
+	if doc.FrontMatter != nil {
+		var meta struct {
+			Title string `yaml:"title"`
+		}
+		if yaml.Unmarshal(doc.FrontMatter, &meta) == nil && meta.Title != "" {
+			return meta.Title
+		}
+	}
+	for _, h := range headings {
+		if h.Level == 1 {
+			return h.Text
+		}
+	}
+	return ""
+}
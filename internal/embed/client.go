@@ -0,0 +1,159 @@
+package embed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/netutil"
+	"github.com/samzong/mdctl/internal/throttle"
+)
+
+// DefaultBatchSize is the number of chunks sent in one embeddings request
+const DefaultBatchSize = 64
+
+var logger = logging.New("EMBED")
+
+// Client computes embeddings with the /embeddings endpoint of an
+// OpenAI-compatible API
+type Client struct {
+	Endpoint   string // Base URL of the API, e.g. https://api.openai.com/v1
+	APIKey     string
+	Model      string
+	BatchSize  int
+	HTTPClient *http.Client
+
+	limiter *throttle.Limiter
+}
+
+// NewClient creates a client from the AI settings of the configuration, the
+// requests draw from the same usage limits as translations
+func NewClient(cfg *config.Config, model string) *Client {
+	if model == "" {
+		model = cfg.EmbeddingModel
+	}
+	if model == "" {
+		model = config.DefaultConfig.EmbeddingModel
+	}
+	return &Client{
+		Endpoint:   strings.TrimSuffix(cfg.OpenAIEndpointURL, "/"),
+		APIKey:     cfg.OpenAIAPIKey,
+		Model:      model,
+		BatchSize:  DefaultBatchSize,
+		HTTPClient: netutil.NewClient(0),
+		limiter: throttle.Shared(throttle.Limits{
+			RequestsPerMinute: cfg.AIRequestsPerMin,
+			TokensPerMinute:   cfg.AITokensPerMin,
+			DailyTokenQuota:   cfg.AIDailyTokenQuota,
+		}),
+	}
+}
+
+type embeddingsRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// Embed sets the vectors of the chunks, sending them in batches
+func (c *Client) Embed(ctx context.Context, chunks []Chunk) error {
+	size := c.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	for start := 0; start < len(chunks); start += size {
+		end := start + size
+		if end > len(chunks) {
+			end = len(chunks)
+		}
+		logger.Debugf("Embedding chunks %d-%d of %d", start+1, end, len(chunks))
+		if err := c.embedBatch(ctx, chunks[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// embedBatch sets the vectors of the chunks with a single request
+func (c *Client) embedBatch(ctx context.Context, chunks []Chunk) error {
+	input := make([]string, len(chunks))
+	estimate := 0
+	for i, chunk := range chunks {
+		input[i] = chunk.Text
+		estimate += throttle.EstimateTokens(chunk.Text)
+	}
+	data, err := json.Marshal(embeddingsRequest{Model: c.Model, Input: input})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	if c.limiter != nil {
+		if err := c.limiter.Wait(ctx, estimate); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint+"/embeddings", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("embeddings request failed with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var response embeddingsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("failed to parse response: %v\nResponse body: %s", err, string(body))
+	}
+
+	if c.limiter != nil {
+		used := response.Usage.TotalTokens
+		if used == 0 {
+			used = estimate
+		}
+		if err := c.limiter.Record(used); err != nil {
+			logger.Warnf("Failed to record AI usage: %v", err)
+		}
+	}
+
+	if len(response.Data) != len(chunks) {
+		return fmt.Errorf("expected %d embeddings, got %d", len(chunks), len(response.Data))
+	}
+	for i, item := range response.Data {
+		index := item.Index
+		if index < 0 || index >= len(chunks) {
+			index = i
+		}
+		chunks[index].Vector = item.Embedding
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package embed
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	content := "---\ntitle: Guide\n---\nIntro text.\n\n# Guide\n\n## Install\n\nRun it.\n\n```sh\n## not a heading\n```\n\n## Empty\n\n### Deep\n\nDeep text.\n"
+	chunks := Split("docs/guide.md", []byte(content), 0)
+
+	var got []string
+	for _, chunk := range chunks {
+		got = append(got, chunk.Metadata.Heading+"|"+strings.Join(chunk.Metadata.Section, ">"))
+	}
+	want := []string{"|", "Install|Guide>Install", "Deep|Guide>Empty>Deep"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected chunks: %v", got)
+	}
+	if chunks[0].Text != "Intro text." || chunks[0].Metadata.Line != 4 || chunks[0].Metadata.Title != "Guide" {
+		t.Errorf("unexpected first chunk: %+v", chunks[0])
+	}
+	if !strings.Contains(chunks[1].Text, "## not a heading") || chunks[1].ID != "docs/guide.md#1" {
+		t.Errorf("expected the code block in the install chunk: %+v", chunks[1])
+	}
+}
+
+func TestSplitLongSection(t *testing.T) {
+	paragraph := strings.Repeat("word ", 20) + "\n\n"
+	content := "# Long\n\n" + strings.Repeat(paragraph, 10) + "```\n" + strings.Repeat("code\n\n", 30) + "```\n"
+	chunks := Split("long.md", []byte(content), 250)
+	if len(chunks) < 5 {
+		t.Fatalf("expected the section to be split, got %d chunks", len(chunks))
+	}
+	last := chunks[len(chunks)-1].Text
+	if !strings.HasPrefix(last, "```") || !strings.HasSuffix(last, "```") {
+		t.Errorf("expected the code block to stay whole: %q", last)
+	}
+}
+
+func TestClientEmbed(t *testing.T) {
+	var batches int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var req embeddingsRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		batches++
+		var resp embeddingsResponse
+		// Results come back in reverse order, the index puts them in place
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			resp.Data = append(resp.Data, struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}{i, []float32{float32(len(req.Input[i]))}})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	chunks := []Chunk{{Text: "a"}, {Text: "bb"}, {Text: "ccc"}}
+	client := &Client{Endpoint: server.URL + "/v1", APIKey: "key", Model: "test", BatchSize: 2, HTTPClient: server.Client()}
+	if err := client.Embed(context.Background(), chunks); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if batches != 2 {
+		t.Errorf("expected 2 requests, got %d", batches)
+	}
+	for i, chunk := range chunks {
+		if len(chunk.Vector) != 1 || int(chunk.Vector[0]) != i+1 {
+			t.Errorf("unexpected vector of chunk %d: %v", i, chunk.Vector)
+		}
+	}
+}
+
+func TestSQLiteWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "docs.db")
+	store := &SQLite{Path: path, Table: `my "chunks"`}
+	chunks := []Chunk{
+		{ID: "a.md#0", Text: "It's here'); DROP TABLE x; --", Vector: []float32{0.5, 1}, Metadata: Metadata{Path: "a.md", Title: "A", Section: []string{"A", "Install"}, Line: 1}},
+		{ID: "a.md#1", Text: "Second", Vector: []float32{1}, Metadata: Metadata{Path: "a.md", Title: "A", Line: 5}},
+		{ID: "b.md#0", Text: "Other", Vector: []float32{0}, Metadata: Metadata{Path: "b.md", Title: "B", Line: 1}},
+	}
+	if err := store.Write(context.Background(), chunks); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	// a.md got shorter, its second chunk is removed and b.md is kept
+	if err := store.Write(context.Background(), chunks[:1]); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT id, section, line, text, embedding FROM "my ""chunks""" ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var id, section, text, embedding string
+		var line int
+		if err := rows.Scan(&id, &section, &line, &text, &embedding); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%s|%s|%d|%s|%s", id, section, line, text, embedding))
+	}
+	want := []string{"a.md#0|A > Install|1|It's here'); DROP TABLE x; --|[0.5,1]", "b.md#0||1|Other|[0]"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected rows:\n%s", strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,226 @@
+package embed
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/netutil"
+	_ "modernc.org/sqlite" // Registers the sqlite driver
+)
+
+// Vector stores chunks can be written to
+const (
+	StoreChroma = "chroma"
+	StoreSQLite = "sqlite"
+)
+
+// WriteJSONL writes one chunk per line with its text, metadata and vector
+func WriteJSONL(w io.Writer, chunks []Chunk) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, chunk := range chunks {
+		if err := enc.Encode(chunk); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// chunkPaths returns the documents the chunks come from, sorted
+func chunkPaths(chunks []Chunk) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, chunk := range chunks {
+		if !seen[chunk.Metadata.Path] {
+			seen[chunk.Metadata.Path] = true
+			paths = append(paths, chunk.Metadata.Path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Chroma writes chunks to a collection of a Chroma server
+type Chroma struct {
+	URL        string // Base URL of the server, e.g. http://localhost:8000
+	Tenant     string // default_tenant when empty
+	Database   string // default_database when empty
+	Collection string
+	HTTPClient *http.Client
+}
+
+// Write replaces the chunks of the documents in the collection, which is
+// created if it does not exist
+func (c *Chroma) Write(ctx context.Context, chunks []Chunk) error {
+	if c.Collection == "" {
+		return fmt.Errorf("no chroma collection given")
+	}
+	tenant, database := c.Tenant, c.Database
+	if tenant == "" {
+		tenant = "default_tenant"
+	}
+	if database == "" {
+		database = "default_database"
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = netutil.NewClient(0)
+	}
+	base := fmt.Sprintf("%s/api/v2/tenants/%s/databases/%s/collections",
+		strings.TrimSuffix(c.URL, "/"), url.PathEscape(tenant), url.PathEscape(database))
+
+	var collection struct {
+		ID string `json:"id"`
+	}
+	err := c.post(ctx, base, map[string]interface{}{
+		"name":          c.Collection,
+		"get_or_create": true,
+		"metadata":      map[string]string{"hnsw:space": "cosine"},
+	}, &collection)
+	if err != nil {
+		return fmt.Errorf("failed to open collection %s: %v", c.Collection, err)
+	}
+	base += "/" + url.PathEscape(collection.ID)
+
+	// Chunks of earlier versions of the documents are removed first, so
+	// documents that got shorter leave no stale chunks behind
+	err = c.post(ctx, base+"/delete", map[string]interface{}{
+		"where": map[string]interface{}{"path": map[string]interface{}{"$in": chunkPaths(chunks)}},
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete previous chunks: %v", err)
+	}
+
+	for start := 0; start < len(chunks); start += DefaultBatchSize {
+		end := start + DefaultBatchSize
+		if end > len(chunks) {
+			end = len(chunks)
+		}
+		batch := chunks[start:end]
+		ids := make([]string, len(batch))
+		embeddings := make([][]float32, len(batch))
+		documents := make([]string, len(batch))
+		metadatas := make([]map[string]interface{}, len(batch))
+		for i, chunk := range batch {
+			ids[i] = chunk.ID
+			embeddings[i] = chunk.Vector
+			documents[i] = chunk.Text
+			// Chroma metadata values are scalars
+			metadatas[i] = map[string]interface{}{
+				"path":    chunk.Metadata.Path,
+				"title":   chunk.Metadata.Title,
+				"heading": chunk.Metadata.Heading,
+				"section": strings.Join(chunk.Metadata.Section, " > "),
+				"line":    chunk.Metadata.Line,
+				"index":   chunk.Metadata.Index,
+			}
+		}
+		err := c.post(ctx, base+"/upsert", map[string]interface{}{
+			"ids":        ids,
+			"embeddings": embeddings,
+			"documents":  documents,
+			"metadatas":  metadatas,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to upsert chunks: %v", err)
+		}
+	}
+	return nil
+}
+
+// post sends a JSON request to the server and decodes the reply into out
+func (c *Chroma) post(ctx context.Context, endpoint string, in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("chroma request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("chroma returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// SQLite writes chunks to a table of a SQLite database. Vectors are stored as
+// JSON arrays, which the sqlite-vec functions such as vec_distance_cosine
+// accept as they are.
+type SQLite struct {
+	Path  string // Database file, created if it does not exist
+	Table string // chunks when empty
+}
+
+// Write replaces the chunks of the documents in the table, which is created
+// if it does not exist
+func (s *SQLite) Write(ctx context.Context, chunks []Chunk) error {
+	table := s.Table
+	if table == "" {
+		table = "chunks"
+	}
+	// Identifiers cannot be bound, the table name is quoted instead
+	table = `"` + strings.ReplaceAll(table, `"`, `""`) + `"`
+
+	db, err := sql.Open("sqlite", s.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", s.Path, err)
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", s.Path, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+table+
+		" (id TEXT PRIMARY KEY, path TEXT NOT NULL, title TEXT, heading TEXT, section TEXT, line INTEGER, text TEXT NOT NULL, embedding TEXT NOT NULL)"); err != nil {
+		return fmt.Errorf("failed to create table %s: %v", table, err)
+	}
+	// Chunks of earlier versions of the documents are removed first, so
+	// documents that got shorter leave no stale chunks behind
+	for _, p := range chunkPaths(chunks) {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE path = ?", p); err != nil {
+			return fmt.Errorf("failed to delete previous chunks: %v", err)
+		}
+	}
+	insert, err := tx.PrepareContext(ctx, "INSERT OR REPLACE INTO "+table+" VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to insert chunks: %v", err)
+	}
+	defer insert.Close()
+	for _, chunk := range chunks {
+		vector, err := json.Marshal(chunk.Vector)
+		if err != nil {
+			return err
+		}
+		_, err = insert.ExecContext(ctx, chunk.ID, chunk.Metadata.Path, chunk.Metadata.Title, chunk.Metadata.Heading,
+			strings.Join(chunk.Metadata.Section, " > "), chunk.Metadata.Line, chunk.Text, string(vector))
+		if err != nil {
+			return fmt.Errorf("failed to insert chunk %s: %v", chunk.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to write %s: %v", s.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/mddoc"
+)
+
+var (
+	// LaTeX classes Pandoc starts at \chapter instead of \section
+	chapterClasses = map[string]bool{"book": true, "report": true, "memoir": true, "scrbook": true, "scrreprt": true}
+	// Heading attributes leaving a heading out of the table of contents
+	unlistedRegex = regexp.MustCompile(`\{[^}]*\.unlisted[^}]*\}\s*$`)
+	streamRegex   = regexp.MustCompile(`stream\r?\n`)
+)
+
+// bookmarkDepth returns the LaTeX section depth the PDF bookmarks go down to
+// for a table of contents depth, and the deepest heading level LaTeX has a
+// sectioning command for. Pandoc maps heading level 1 to \part or \chapter
+// when --top-level-division or a book class asks for it.
+func bookmarkDepth(args []string, tocDepth int) (int, int) {
+	offset := 0
+	for i, arg := range args {
+		value := arg
+		if arg == "--top-level-division" && i+1 < len(args) {
+			value = "--top-level-division=" + args[i+1]
+		}
+		switch {
+		case value == "--top-level-division=part":
+			offset = 2
+		case value == "--top-level-division=chapter":
+			offset = 1
+		case value == "--top-level-division=section":
+			offset = 0
+		case strings.HasPrefix(value, "documentclass=") || strings.Contains(value, "=documentclass="):
+			class := value[strings.LastIndex(value, "=")+1:]
+			if chapterClasses[class] {
+				offset = 1
+			}
+		}
+	}
+	return tocDepth - offset, 5 + offset
+}
+
+// pdfBookmarkArgs returns the Pandoc arguments that keep heading bookmarks
+// down to the table of contents depth in PDF output, followed by the options
+// for the PDF engine
+func pdfBookmarkArgs(args []string, options ExportOptions) []string {
+	depth, _ := bookmarkDepth(args, tocDepthOf(options))
+	result := []string{
+		"-V", "hyperrefoptions=bookmarks=true",
+		"-V", fmt.Sprintf("hyperrefoptions=bookmarksdepth=%d", depth),
+	}
+	for _, opt := range options.PDFEngineOpts {
+		result = append(result, "--pdf-engine-opt="+opt)
+	}
+	return result
+}
+
+// tocDepthOf returns the table of contents depth of an export, 3 when unset
+func tocDepthOf(options ExportOptions) int {
+	if options.TocDepth > 0 {
+		return options.TocDepth
+	}
+	return 3
+}
+
+// expectedBookmarks counts the headings of the Pandoc input that get a PDF
+// bookmark: those down to the table of contents depth after the level shift,
+// without .unlisted headings
+func expectedBookmarks(input string, args []string, options ExportOptions) (int, error) {
+	content, err := os.ReadFile(input)
+	if err != nil {
+		return 0, err
+	}
+	_, deepest := bookmarkDepth(args, 0)
+	maxLevel := tocDepthOf(options)
+	if maxLevel > deepest {
+		maxLevel = deepest
+	}
+
+	count := 0
+	for _, heading := range mddoc.Parse(content).Headings() {
+		level := heading.Level + options.ShiftHeadingLevelBy
+		if level < 1 || level > maxLevel || unlistedRegex.MatchString(heading.Text) {
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// verifyPDFBookmarks fails when the bookmarks of a PDF do not match the
+// headings of the table of contents, which happens when a template, theme or
+// filter drops hyperref
+func verifyPDFBookmarks(pdf, input string, args []string, options ExportOptions) error {
+	expected, err := expectedBookmarks(input, args, options)
+	if err != nil {
+		return fmt.Errorf("failed to read headings: %s", err)
+	}
+	data, err := os.ReadFile(pdf)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF: %s", err)
+	}
+	got, err := CountPDFBookmarks(data)
+	if err != nil {
+		return err
+	}
+	if got != expected {
+		return fmt.Errorf("PDF has %d bookmarks but the table of contents has %d entries, check that the template keeps hyperref and the headings (skip this check with --skip-pdf-check)", got, expected)
+	}
+	return nil
+}
+
+// CountPDFBookmarks returns the number of outline items of a PDF, including
+// those in compressed object streams
+func CountPDFBookmarks(data []byte) (int, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return 0, fmt.Errorf("not a PDF file")
+	}
+
+	// Stream contents are cut out of the scanned text, object streams are
+	// added decompressed since they hold the dictionaries of PDF 1.5 files
+	var text bytes.Buffer
+	rest := data
+	for {
+		loc := streamRegex.FindIndex(rest)
+		if loc == nil {
+			text.Write(rest)
+			break
+		}
+		end := bytes.Index(rest[loc[1]:], []byte("endstream"))
+		if end < 0 {
+			text.Write(rest)
+			break
+		}
+		header := rest[:loc[0]]
+		body := rest[loc[1] : loc[1]+end]
+		text.Write(header)
+		text.WriteByte('\n')
+
+		dict := header[bytes.LastIndex(header, []byte("obj"))+1:]
+		if bytes.Contains(dict, []byte("/ObjStm")) {
+			if bytes.Contains(dict, []byte("/FlateDecode")) {
+				r, err := zlib.NewReader(bytes.NewReader(body))
+				if err != nil {
+					return 0, fmt.Errorf("failed to read object stream: %s", err)
+				}
+				decoded, err := io.ReadAll(r)
+				if err != nil && err != io.ErrUnexpectedEOF {
+					return 0, fmt.Errorf("failed to read object stream: %s", err)
+				}
+				body = decoded
+			}
+			text.Write(body)
+			text.WriteByte('\n')
+		}
+		rest = rest[loc[1]+end+len("endstream"):]
+	}
+
+	count := 0
+	for _, keys := range topLevelDicts(text.Bytes()) {
+		if keys["/Title"] && keys["/Parent"] {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// topLevelDicts returns the names used as keys or values directly inside the
+// outermost dictionaries of PDF syntax, skipping strings and comments
+func topLevelDicts(data []byte) []map[string]bool {
+	var dicts []map[string]bool
+	var current map[string]bool
+	depth := 0
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; {
+		case c == '%':
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			nesting := 0
+			for ; i < len(data); i++ {
+				if data[i] == '\\' {
+					i++
+				} else if data[i] == '(' {
+					nesting++
+				} else if data[i] == ')' {
+					if nesting--; nesting == 0 {
+						break
+					}
+				}
+			}
+		case c == '<' && i+1 < len(data) && data[i+1] == '<':
+			i++
+			if depth++; depth == 1 {
+				current = map[string]bool{}
+			}
+		case c == '>' && i+1 < len(data) && data[i+1] == '>':
+			i++
+			if depth > 0 {
+				if depth--; depth == 0 {
+					dicts = append(dicts, current)
+				}
+			}
+		case c == '<':
+			for i < len(data) && data[i] != '>' {
+				i++
+			}
+		case c == '/' && depth == 1:
+			j := i + 1
+			for j < len(data) && !bytes.ContainsAny(data[j:j+1], " \t\r\n/<>[]()%{}") {
+				j++
+			}
+			current[string(data[i:j])] = true
+			i = j - 1
+		}
+	}
+	return dicts
+}
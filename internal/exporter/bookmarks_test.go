@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCountPDFBookmarks(t *testing.T) {
+	plain := "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n" +
+		"1 0 obj\n<< /Type /Catalog /Outlines 2 0 R /Pages 3 0 R >>\nendobj\n" +
+		"2 0 obj\n<< /Type /Outlines /First 4 0 R /Last 5 0 R /Count 2 >>\nendobj\n" +
+		"3 0 obj\n<< /Type /Pages /Kids [] /Count 0 >>\nendobj\n" +
+		"4 0 obj\n<< /Title (Intro \\(draft\\) >>) /Parent 2 0 R /Next 5 0 R /Dest [6 0 R /XYZ 0 0 null] >>\nendobj\n" +
+		"5 0 obj\n<< /Title <FEFF0041> /Parent 2 0 R /Prev 4 0 R /A << /S /GoTo /D (section.2) >> >>\nendobj\n" +
+		"7 0 obj\n<< /Title (Document) /Producer (xdvipdfmx) >>\nendobj\n" +
+		"8 0 obj\n<< /Length 10 >>\nstream\n<< /Title /Parent >>\nendstream\nendobj\n%%EOF\n"
+	count, err := CountPDFBookmarks([]byte(plain))
+	if err != nil {
+		t.Fatalf("CountPDFBookmarks failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 bookmarks, got %d", count)
+	}
+
+	// PDF 1.5 files keep the outline items in compressed object streams
+	var objects bytes.Buffer
+	w := zlib.NewWriter(&objects)
+	w.Write([]byte("4 0 5 60 << /Title (One) /Parent 2 0 R /Next 5 0 R >> << /Title (Two) /Parent 2 0 R /Prev 4 0 R >> << /Type /Page /Parent 3 0 R >>"))
+	w.Close()
+	compressed := fmt.Sprintf("%%PDF-1.5\n9 0 obj\n<< /Type /ObjStm /N 2 /First 9 /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream\nendobj\n%%%%EOF\n", objects.Len(), objects.String())
+	if count, err := CountPDFBookmarks([]byte(compressed)); err != nil || count != 2 {
+		t.Errorf("expected 2 bookmarks in the object stream, got %d (%v)", count, err)
+	}
+
+	if _, err := CountPDFBookmarks([]byte("PK\x03\x04")); err == nil {
+		t.Error("expected an error for a file that is not a PDF")
+	}
+}
+
+func TestExpectedBookmarks(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "input.md")
+	content := "# One\n\n## Two {.unlisted}\n\n### Three\n\n#### Four\n\n```\n# not a heading\n```\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args    []string
+		options ExportOptions
+		want    int
+	}{
+		{nil, ExportOptions{}, 2},
+		{nil, ExportOptions{TocDepth: 4}, 3},
+		{nil, ExportOptions{ShiftHeadingLevelBy: 1}, 1},
+		{nil, ExportOptions{ShiftHeadingLevelBy: -1, TocDepth: 2}, 1},
+	}
+	for _, tt := range tests {
+		got, err := expectedBookmarks(input, tt.args, tt.options)
+		if err != nil {
+			t.Fatalf("expectedBookmarks failed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("expectedBookmarks(%v, %+v) = %d, want %d", tt.args, tt.options, got, tt.want)
+		}
+	}
+}
+
+func TestPDFBookmarkArgs(t *testing.T) {
+	args := strings.Join(pdfBookmarkArgs(nil, ExportOptions{TocDepth: 2, PDFEngineOpts: []string{"-shell-escape"}}), " ")
+	if !strings.Contains(args, "hyperrefoptions=bookmarksdepth=2") || !strings.HasSuffix(args, "--pdf-engine-opt=-shell-escape") {
+		t.Errorf("unexpected arguments: %s", args)
+	}
+
+	// Book classes start at \chapter, which is LaTeX depth 0
+	args = strings.Join(pdfBookmarkArgs([]string{"-V", "documentclass=book"}, ExportOptions{}), " ")
+	if !strings.Contains(args, "bookmarksdepth=2") {
+		t.Errorf("unexpected arguments for a book class: %s", args)
+	}
+	args = strings.Join(pdfBookmarkArgs([]string{"--top-level-division", "part"}, ExportOptions{}), " ")
+	if !strings.Contains(args, "bookmarksdepth=1") {
+		t.Errorf("unexpected arguments for parts: %s", args)
+	}
+}
@@ -0,0 +1,273 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Part names of the header and footer added to DOCX output, chosen so they
+// never collide with parts copied from a reference document
+const (
+	docxHeaderPart = "word/mdctl-header.xml"
+	docxFooterPart = "word/mdctl-footer.xml"
+	docxHeaderRel  = "rIdMdctlHeader"
+	docxFooterRel  = "rIdMdctlFooter"
+)
+
+var (
+	docxHeaderRefRegex   = regexp.MustCompile(`<w:headerReference [^>]*/>`)
+	docxFooterRefRegex   = regexp.MustCompile(`<w:footerReference [^>]*/>`)
+	docxSectPrRegex      = regexp.MustCompile(`<w:sectPr(\s[^>]*)?>`)
+	docxEmptySectPrRegex = regexp.MustCompile(`<w:sectPr(\s[^>]*)?/>`)
+)
+
+// hasPageDecorations reports whether headers, footers, page numbers, a
+// watermark or a build stamp are requested
+func hasPageDecorations(options ExportOptions) bool {
+	return options.HeaderText != "" || hasFooter(options) || options.Watermark != ""
+}
+
+// hasFooter reports whether the footer has text, page numbers or a build stamp
+func hasFooter(options ExportOptions) bool {
+	return options.FooterText != "" || options.PageNumbers || options.Stamp != nil
+}
+
+// latexDecorationArgs returns the Pandoc variables that add the page
+// decorations to PDF output through the LaTeX preamble
+func latexDecorationArgs(options ExportOptions) []string {
+	var preamble []string
+	if options.HeaderText != "" || hasFooter(options) {
+		preamble = append(preamble,
+			`\usepackage{fancyhdr}`,
+			`\pagestyle{fancy}`,
+			`\fancyhf{}`,
+			`\renewcommand{\headrulewidth}{0pt}`)
+		if options.HeaderText != "" {
+			preamble = append(preamble, `\fancyhead[C]{`+escapeLaTeX(options.HeaderText)+`}`)
+		}
+		if options.FooterText != "" {
+			preamble = append(preamble, `\fancyfoot[C]{`+escapeLaTeX(options.FooterText)+`}`)
+		}
+		if options.PageNumbers {
+			// Page numbers move aside when they share the footer with text
+			position := "C"
+			if options.FooterText != "" {
+				position = "R"
+			}
+			preamble = append(preamble, `\fancyfoot[`+position+`]{\thepage}`)
+		}
+		if options.Stamp != nil {
+			preamble = append(preamble, `\fancyfoot[L]{\scriptsize `+escapeLaTeX(options.Stamp.String())+`}`)
+		}
+		// Title pages use the plain style, which gets the same decorations
+		preamble = append(preamble, `\makeatletter\let\ps@plain\ps@fancy\makeatother`)
+	}
+	if options.Watermark != "" {
+		preamble = append(preamble,
+			`\usepackage{draftwatermark}`,
+			`\SetWatermarkText{`+escapeLaTeX(options.Watermark)+`}`,
+			`\SetWatermarkColor[gray]{0.85}`,
+			`\SetWatermarkScale{1}`)
+	}
+	if len(preamble) == 0 {
+		return nil
+	}
+	return []string{"-V", "header-includes=" + strings.Join(preamble, "\n")}
+}
+
+// escapeLaTeX escapes the characters LaTeX treats specially
+func escapeLaTeX(text string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\textbackslash{}`,
+		`{`, `\{`,
+		`}`, `\}`,
+		`$`, `\$`,
+		`&`, `\&`,
+		`#`, `\#`,
+		`%`, `\%`,
+		`_`, `\_`,
+		`^`, `\textasciicircum{}`,
+		`~`, `\textasciitilde{}`,
+	)
+	return replacer.Replace(text)
+}
+
+// decorateDocx adds the header, footer, page numbers and watermark to a DOCX
+// file written by Pandoc. Pandoc only copies headers and footers from a
+// reference document, so they are added to its output instead, which works
+// with any template or theme. Existing headers or footers are replaced.
+func decorateDocx(path string, options ExportOptions) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer r.Close()
+
+	addHeader := options.HeaderText != "" || options.Watermark != ""
+	addFooter := hasFooter(options)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+
+		switch f.Name {
+		case "word/document.xml":
+			data = []byte(docxAddSectionReferences(string(data), addHeader, addFooter))
+		case "word/_rels/document.xml.rels":
+			var rels strings.Builder
+			if addHeader {
+				fmt.Fprintf(&rels, `<Relationship Id="%s" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/header" Target="%s"/>`,
+					docxHeaderRel, strings.TrimPrefix(docxHeaderPart, "word/"))
+			}
+			if addFooter {
+				fmt.Fprintf(&rels, `<Relationship Id="%s" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/footer" Target="%s"/>`,
+					docxFooterRel, strings.TrimPrefix(docxFooterPart, "word/"))
+			}
+			data = []byte(strings.Replace(string(data), "</Relationships>", rels.String()+"</Relationships>", 1))
+		case "[Content_Types].xml":
+			var types strings.Builder
+			if addHeader {
+				fmt.Fprintf(&types, `<Override PartName="/%s" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml"/>`, docxHeaderPart)
+			}
+			if addFooter {
+				fmt.Fprintf(&types, `<Override PartName="/%s" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.footer+xml"/>`, docxFooterPart)
+			}
+			data = []byte(strings.Replace(string(data), "</Types>", types.String()+"</Types>", 1))
+		}
+
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.Name, Method: f.Method, Modified: f.Modified})
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	parts := map[string]string{}
+	if addHeader {
+		parts[docxHeaderPart] = docxHeaderXML(options.HeaderText, options.Watermark)
+	}
+	if addFooter {
+		stamp := ""
+		if options.Stamp != nil {
+			stamp = options.Stamp.String()
+		}
+		parts[docxFooterPart] = docxFooterXML(options.FooterText, stamp, options.PageNumbers)
+	}
+	for _, name := range []string{docxHeaderPart, docxFooterPart} {
+		content, ok := parts[name]
+		if !ok {
+			continue
+		}
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	r.Close()
+
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// docxAddSectionReferences points the section properties of the document at
+// the added header and footer
+func docxAddSectionReferences(document string, addHeader, addFooter bool) string {
+	var refs strings.Builder
+	if addHeader {
+		document = docxHeaderRefRegex.ReplaceAllString(document, "")
+		fmt.Fprintf(&refs, `<w:headerReference w:type="default" r:id="%s"/>`, docxHeaderRel)
+	}
+	if addFooter {
+		document = docxFooterRefRegex.ReplaceAllString(document, "")
+		fmt.Fprintf(&refs, `<w:footerReference w:type="default" r:id="%s"/>`, docxFooterRel)
+	}
+
+	if !strings.Contains(document, `xmlns:r=`) {
+		document = strings.Replace(document, "<w:document ",
+			`<w:document xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" `, 1)
+	}
+
+	document = docxEmptySectPrRegex.ReplaceAllString(document, "<w:sectPr$1></w:sectPr>")
+
+	// References come first in the final section properties of the body,
+	// earlier ones belong to section breaks inside paragraphs
+	if locs := docxSectPrRegex.FindAllStringIndex(document, -1); len(locs) > 0 {
+		end := locs[len(locs)-1][1]
+		return document[:end] + refs.String() + document[end:]
+	}
+	return strings.Replace(document, "</w:body>", "<w:sectPr>"+refs.String()+"</w:sectPr></w:body>", 1)
+}
+
+// docxHeaderXML returns a header part with the centered header text and the watermark
+func docxHeaderXML(text, watermark string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:hdr xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:v="urn:schemas-microsoft-com:vml" xmlns:o="urn:schemas-microsoft-com:office:office">`)
+	b.WriteString(`<w:p><w:pPr><w:jc w:val="center"/></w:pPr>`)
+	if text != "" {
+		fmt.Fprintf(&b, `<w:r><w:t xml:space="preserve">%s</w:t></w:r>`, xmlEscape(text))
+	}
+	if watermark != "" {
+		// The watermark is a rotated VML text shape anchored behind the page
+		// content, the same construct Word writes for its own watermarks
+		fmt.Fprintf(&b, `<w:r><w:pict>`+
+			`<v:shapetype id="_x0000_t136" coordsize="21600,21600" o:spt="136" adj="10800" path="m@7,l@8,m@5,21600l@6,21600e">`+
+			`<v:path textpathok="t" o:connecttype="custom"/><v:textpath on="t" fitshape="t"/><o:lock v:ext="edit" text="t" shapetype="t"/></v:shapetype>`+
+			`<v:shape id="MdctlWatermark" type="#_x0000_t136" style="position:absolute;margin-left:0;margin-top:0;width:468pt;height:117pt;rotation:315;z-index:-251657216;`+
+			`mso-position-horizontal:center;mso-position-horizontal-relative:margin;mso-position-vertical:center;mso-position-vertical-relative:margin" `+
+			`o:allowincell="f" fillcolor="silver" stroked="f"><v:fill opacity=".5"/><v:textpath style="font-family:&quot;Calibri&quot;;font-size:1pt" string="%s"/></v:shape>`+
+			`</w:pict></w:r>`, xmlEscape(watermark))
+	}
+	b.WriteString(`</w:p></w:hdr>`)
+	return b.String()
+}
+
+// docxFooterXML returns a footer part with the centered footer text and page
+// number, followed by the build stamp in small print
+func docxFooterXML(text, stamp string, pageNumbers bool) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:ftr xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">`)
+	if text != "" {
+		fmt.Fprintf(&b, `<w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:r><w:t xml:space="preserve">%s</w:t></w:r></w:p>`, xmlEscape(text))
+	}
+	if pageNumbers {
+		b.WriteString(`<w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:fldSimple w:instr=" PAGE "><w:r><w:t>1</w:t></w:r></w:fldSimple></w:p>`)
+	}
+	if stamp != "" {
+		fmt.Fprintf(&b, `<w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:r><w:rPr><w:sz w:val="14"/></w:rPr><w:t xml:space="preserve">%s</w:t></w:r></w:p>`, xmlEscape(stamp))
+	}
+	b.WriteString(`</w:ftr>`)
+	return b.String()
+}
+
+// xmlEscape escapes text for use in XML content and attributes
+func xmlEscape(text string) string {
+	var b strings.Builder
+	if err := xml.EscapeText(&b, []byte(text)); err != nil {
+		return text
+	}
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package exporter
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLatexDecorationArgs(t *testing.T) {
+	if args := latexDecorationArgs(ExportOptions{}); args != nil {
+		t.Errorf("expected no arguments without decorations, got %v", args)
+	}
+
+	args := latexDecorationArgs(ExportOptions{HeaderText: "R&D 100%", FooterText: "Internal", PageNumbers: true, Watermark: "DRAFT"})
+	if len(args) != 2 || args[0] != "-V" {
+		t.Fatalf("expected a single header-includes variable, got %v", args)
+	}
+	for _, want := range []string{`\fancyhead[C]{R\&D 100\%}`, `\fancyfoot[C]{Internal}`, `\fancyfoot[R]{\thepage}`, `\SetWatermarkText{DRAFT}`} {
+		if !strings.Contains(args[1], want) {
+			t.Errorf("expected %q in %q", want, args[1])
+		}
+	}
+}
+
+func TestDecorateDocx(t *testing.T) {
+	data, err := builtinThemes["minimal"].referenceDocx()
+	if err != nil {
+		t.Fatalf("referenceDocx failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "out.docx")
+	os.WriteFile(path, data, 0644)
+
+	options := ExportOptions{HeaderText: "Confidential <A&B>", PageNumbers: true, Watermark: "DRAFT"}
+	if err := decorateDocx(path, options); err != nil {
+		t.Fatalf("decorateDocx failed: %v", err)
+	}
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("output is not a zip: %v", err)
+	}
+	defer r.Close()
+	parts := map[string]string{}
+	for _, f := range r.File {
+		rc, _ := f.Open()
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(content)
+	}
+
+	if !strings.Contains(parts[docxHeaderPart], "Confidential &lt;A&amp;B&gt;") || !strings.Contains(parts[docxHeaderPart], `string="DRAFT"`) {
+		t.Errorf("unexpected header: %s", parts[docxHeaderPart])
+	}
+	if !strings.Contains(parts[docxFooterPart], `w:instr=" PAGE "`) {
+		t.Errorf("expected a page number field in the footer: %s", parts[docxFooterPart])
+	}
+	document := parts["word/document.xml"]
+	if !strings.Contains(document, `<w:sectPr><w:headerReference w:type="default" r:id="rIdMdctlHeader"/><w:footerReference`) {
+		t.Errorf("expected header and footer references in the section properties: %s", document)
+	}
+	if !strings.Contains(document, `xmlns:r=`) {
+		t.Error("expected the relationships namespace in the document")
+	}
+	if !strings.Contains(parts["word/_rels/document.xml.rels"], `Target="mdctl-footer.xml"`) {
+		t.Error("expected a footer relationship")
+	}
+	if !strings.Contains(parts["[Content_Types].xml"], `/word/mdctl-header.xml`) {
+		t.Error("expected a header content type")
+	}
+}
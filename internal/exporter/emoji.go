@@ -0,0 +1,283 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/samzong/mdctl/internal/cache"
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"github.com/samzong/mdctl/internal/netutil"
+	"github.com/yuin/goldmark/ast"
+)
+
+// Emoji handling modes of PDF export, emoji are kept as they are when empty
+const (
+	EmojiFont    = "font"    // Set emoji in a separate font
+	EmojiTwemoji = "twemoji" // Replace emoji with Twemoji images
+	EmojiStrip   = "strip"   // Remove emoji
+)
+
+// DefaultEmojiFont is the font of the font mode. xelatex cannot render color
+// fonts, so it is a monochrome one.
+const DefaultEmojiFont = "Noto Emoji"
+
+// twemojiBaseURL is where the Twemoji PNG images are downloaded from
+var twemojiBaseURL = "https://cdn.jsdelivr.net/gh/jdecked/twemoji@15.1.0/assets/72x72/"
+
+// emojiPresentation holds the characters below U+1F000 shown as emoji
+// without a variation selector
+var emojiPresentation = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x231a, Hi: 0x231b, Stride: 1},
+		{Lo: 0x23e9, Hi: 0x23ec, Stride: 1},
+		{Lo: 0x23f0, Hi: 0x23f3, Stride: 3},
+		{Lo: 0x25fd, Hi: 0x25fe, Stride: 1},
+		{Lo: 0x2614, Hi: 0x2615, Stride: 1},
+		{Lo: 0x2648, Hi: 0x2653, Stride: 1},
+		{Lo: 0x267f, Hi: 0x267f, Stride: 1},
+		{Lo: 0x2693, Hi: 0x2693, Stride: 1},
+		{Lo: 0x26a1, Hi: 0x26a1, Stride: 1},
+		{Lo: 0x26aa, Hi: 0x26ab, Stride: 1},
+		{Lo: 0x26bd, Hi: 0x26be, Stride: 1},
+		{Lo: 0x26c4, Hi: 0x26c5, Stride: 1},
+		{Lo: 0x26ce, Hi: 0x26ce, Stride: 1},
+		{Lo: 0x26d4, Hi: 0x26d4, Stride: 1},
+		{Lo: 0x26ea, Hi: 0x26ea, Stride: 1},
+		{Lo: 0x26f2, Hi: 0x26f3, Stride: 1},
+		{Lo: 0x26f5, Hi: 0x26f5, Stride: 1},
+		{Lo: 0x26fa, Hi: 0x26fa, Stride: 1},
+		{Lo: 0x26fd, Hi: 0x26fd, Stride: 1},
+		{Lo: 0x2705, Hi: 0x2705, Stride: 1},
+		{Lo: 0x270a, Hi: 0x270b, Stride: 1},
+		{Lo: 0x2728, Hi: 0x2728, Stride: 1},
+		{Lo: 0x274c, Hi: 0x274e, Stride: 2},
+		{Lo: 0x2753, Hi: 0x2755, Stride: 1},
+		{Lo: 0x2757, Hi: 0x2757, Stride: 1},
+		{Lo: 0x2795, Hi: 0x2797, Stride: 1},
+		{Lo: 0x27b0, Hi: 0x27b0, Stride: 1},
+		{Lo: 0x27bf, Hi: 0x27bf, Stride: 1},
+		{Lo: 0x2b1b, Hi: 0x2b1c, Stride: 1},
+		{Lo: 0x2b50, Hi: 0x2b50, Stride: 1},
+		{Lo: 0x2b55, Hi: 0x2b55, Stride: 1},
+	},
+}
+
+// isEmojiBase reports whether r is shown as emoji on its own
+func isEmojiBase(r rune) bool {
+	return (r >= 0x1f000 && r <= 0x1faff) || unicode.Is(emojiPresentation, r)
+}
+
+// isEmojiSymbol reports whether r is a symbol shown as text unless a
+// variation selector asks for emoji
+func isEmojiSymbol(r rune) bool {
+	return r == 0xa9 || r == 0xae || (r >= 0x2000 && r <= 0x2bff) ||
+		r == 0x3030 || r == 0x303d || r == 0x3297 || r == 0x3299
+}
+
+// isRegionalIndicator reports whether r is one of the letters of flags
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+// emojiSpans returns the byte ranges of the emoji sequences of a text,
+// including their skin tones, variation selectors and ZWJ sequences
+func emojiSpans(s string) [][2]int {
+	var spans [][2]int
+	for i := 0; i < len(s); {
+		if end := emojiEnd(s, i); end > i {
+			spans = append(spans, [2]int{i, end})
+			i = end
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return spans
+}
+
+// emojiEnd returns the end of the emoji sequence starting at i, i when none
+// starts there
+func emojiEnd(s string, i int) int {
+	r, size := utf8.DecodeRuneInString(s[i:])
+	next, nextSize := utf8.DecodeRuneInString(s[i+size:])
+	end := i + size
+
+	switch {
+	case (r >= '0' && r <= '9') || r == '#' || r == '*':
+		// Keycaps
+		if next == 0xfe0f {
+			end += nextSize
+			next, _ = utf8.DecodeRuneInString(s[end:])
+		}
+		if next != 0x20e3 {
+			return i
+		}
+	case isRegionalIndicator(r):
+		// Flags are pairs of regional indicators
+		if !isRegionalIndicator(next) {
+			return i
+		}
+		return end + nextSize
+	case isEmojiBase(r):
+	case isEmojiSymbol(r) && next == 0xfe0f:
+	default:
+		return i
+	}
+
+	for end < len(s) {
+		r, size := utf8.DecodeRuneInString(s[end:])
+		switch {
+		case r == 0xfe0f, r == 0x20e3, r >= 0x1f3fb && r <= 0x1f3ff, r >= 0xe0020 && r <= 0xe007f:
+			end += size
+		case r == 0x200d:
+			joined, joinedSize := utf8.DecodeRuneInString(s[end+size:])
+			if !isEmojiBase(joined) && !isEmojiSymbol(joined) {
+				return end
+			}
+			end += size + joinedSize
+		default:
+			return end
+		}
+	}
+	return end
+}
+
+// twemojiName returns the file name of an emoji sequence in Twemoji, the
+// variation selector is only kept in ZWJ sequences
+func twemojiName(emoji string) string {
+	keepSelector := strings.ContainsRune(emoji, 0x200d)
+	var parts []string
+	for _, r := range emoji {
+		if r == 0xfe0f && !keepSelector {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%x", r))
+	}
+	return strings.Join(parts, "-") + ".png"
+}
+
+// twemojiFetcher downloads Twemoji images into a cache directory
+type twemojiFetcher struct {
+	dir    string
+	client *http.Client
+}
+
+// fetch returns the path of the image of an emoji, downloading it unless it
+// is cached
+func (f *twemojiFetcher) fetch(emoji string) (string, error) {
+	file := filepath.Join(f.dir, twemojiName(emoji))
+	if _, err := os.Stat(fsutil.LongPath(file)); err == nil {
+		return file, nil
+	}
+
+	resp, err := f.client.Get(twemojiBaseURL + twemojiName(emoji))
+	if err != nil {
+		return "", fmt.Errorf("failed to download Twemoji image: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download Twemoji image %s: %s", twemojiName(emoji), resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to download Twemoji image: %s", err)
+	}
+	if err := os.MkdirAll(fsutil.LongPath(f.dir), 0755); err != nil {
+		return "", err
+	}
+	if err := fsutil.WriteFileAtomic(file, data, 0644); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// emojiArgs returns the Pandoc arguments an emoji mode needs
+func emojiArgs(options ExportOptions) []string {
+	if options.Emoji != EmojiFont {
+		return nil
+	}
+	font := options.EmojiFont
+	if font == "" {
+		font = DefaultEmojiFont
+	}
+	return []string{"-V", `header-includes=\newfontfamily\mdctlemojifont{` + font + `}`}
+}
+
+// replaceEmoji applies an emoji mode to a markdown document. Emoji in code,
+// raw HTML, image descriptions and autolinks are left alone. In the Twemoji
+// mode, emoji whose image cannot be downloaded are kept.
+func replaceEmoji(content []byte, mode string, logger *logging.Logger) ([]byte, error) {
+	var fetcher *twemojiFetcher
+	switch mode {
+	case "":
+		return content, nil
+	case EmojiFont, EmojiStrip:
+	case EmojiTwemoji:
+		fetcher = &twemojiFetcher{
+			dir:    filepath.Join(cache.DefaultDir(), "twemoji"),
+			client: netutil.NewClient(30 * time.Second),
+		}
+	default:
+		return nil, fmt.Errorf("unsupported emoji mode: %s (must be font, twemoji or strip)", mode)
+	}
+
+	doc := mddoc.Parse(content)
+	var edits []mddoc.Edit
+	failed := map[string]bool{}
+	ast.Walk(doc.Root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch v := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock, *ast.RawHTML, *ast.CodeSpan, *ast.Image, *ast.AutoLink:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			if !entering {
+				break
+			}
+			text := string(v.Segment.Value(doc.Source))
+			for _, span := range emojiSpans(text) {
+				emoji := text[span[0]:span[1]]
+				edit := mddoc.Edit{Start: v.Segment.Start + span[0], End: v.Segment.Start + span[1]}
+				switch mode {
+				case EmojiFont:
+					edit.Text = "`{\\mdctlemojifont " + emoji + "}`{=latex}"
+				case EmojiTwemoji:
+					if failed[emoji] {
+						continue
+					}
+					image, err := fetcher.fetch(emoji)
+					if err != nil {
+						logger.Warnf("Keeping emoji %s: %s", emoji, err)
+						failed[emoji] = true
+						continue
+					}
+					// The empty description keeps Pandoc from making a figure
+					edit.Text = "![](<" + filepath.ToSlash(image) + ">){height=1em}"
+				}
+				edits = append(edits, edit)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return doc.Apply(edits), nil
+}
+
+// applyEmojiMode rewrites the emoji of a sanitized copy for PDF output
+func applyEmojiMode(file, mode string, logger *logging.Logger) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	replaced, err := replaceEmoji(content, mode, logger)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, replaced, 0644)
+}
@@ -0,0 +1,126 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/logging"
+)
+
+func TestEmojiSpans(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Ship it 🚀 now", []string{"🚀"}},
+		{"Thumbs 👍🏽 and family 👨‍👩‍👧", []string{"👍🏽", "👨‍👩‍👧"}},
+		{"Flag 🇩🇪 and keycap 1️⃣", []string{"🇩🇪", "1️⃣"}},
+		{"Done ✅, warning ⚠️, heart ❤️", []string{"✅", "⚠️", "❤️"}},
+		// Symbols without a variation selector, digits and CJK stay text
+		{"© 2024 → ★ 中文 — 42", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, span := range emojiSpans(tt.text) {
+			got = append(got, tt.text[span[0]:span[1]])
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("emojiSpans(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTwemojiName(t *testing.T) {
+	tests := map[string]string{
+		"🚀":    "1f680.png",
+		"❤️":   "2764.png",
+		"👍🏽":   "1f44d-1f3fd.png",
+		"1️⃣":  "31-20e3.png",
+		"🏳️‍🌈": "1f3f3-fe0f-200d-1f308.png",
+		"🇩🇪":   "1f1e9-1f1ea.png",
+	}
+	for emoji, want := range tests {
+		if got := twemojiName(emoji); got != want {
+			t.Errorf("twemojiName(%q) = %s, want %s", emoji, got, want)
+		}
+	}
+}
+
+func TestReplaceEmoji(t *testing.T) {
+	content := "# Release 🎉\n\nShip it 🚀 with `echo 🚀` and ![🚀](rocket.png).\n\n```\nrocket 🚀\n```\n\n<div>🚀</div>\n"
+	logger := logging.New("TEST")
+
+	got, err := replaceEmoji([]byte(content), EmojiStrip, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Release \n\nShip it  with `echo 🚀` and ![🚀](rocket.png).\n\n```\nrocket 🚀\n```\n\n<div>🚀</div>\n"
+	if string(got) != want {
+		t.Errorf("strip mode:\n%s\nwant:\n%s", got, want)
+	}
+
+	got, err = replaceEmoji([]byte(content), EmojiFont, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "Ship it `{\\mdctlemojifont 🚀}`{=latex} with `echo 🚀`") {
+		t.Errorf("font mode did not wrap the emoji:\n%s", got)
+	}
+	if strings.Count(string(got), "mdctlemojifont") != 2 {
+		t.Errorf("font mode changed emoji in code or HTML:\n%s", got)
+	}
+
+	if _, err := replaceEmoji([]byte(content), "color", logger); err == nil {
+		t.Error("expected an error for an unsupported mode")
+	}
+}
+
+func TestReplaceEmojiTwemoji(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/1f680.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("png"))
+	}))
+	defer server.Close()
+	defer func(url string) { twemojiBaseURL = url }(twemojiBaseURL)
+	twemojiBaseURL = server.URL + "/"
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	content := []byte("Launch 🚀🚀 ⌛\n")
+	got, err := replaceEmoji(content, EmojiTwemoji, logging.New("TEST"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := filepath.ToSlash(filepath.Join(home, ".cache", "mdctl", "twemoji", "1f680.png"))
+	want := "Launch ![](<" + image + ">){height=1em}![](<" + image + ">){height=1em} ⌛\n"
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if data, err := os.ReadFile(filepath.FromSlash(image)); err != nil || string(data) != "png" {
+		t.Errorf("image not cached: %v", err)
+	}
+	// The cached image is not downloaded again, the missing one once
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestEmojiArgs(t *testing.T) {
+	if args := emojiArgs(ExportOptions{Emoji: EmojiStrip}); args != nil {
+		t.Errorf("strip mode needs no arguments, got %v", args)
+	}
+	args := emojiArgs(ExportOptions{Emoji: EmojiFont, EmojiFont: "Symbola"})
+	if len(args) != 2 || args[1] != `header-includes=\newfontfamily\mdctlemojifont{Symbola}` {
+		t.Errorf("unexpected font arguments: %v", args)
+	}
+}
@@ -1,58 +1,111 @@
 package exporter
 
 import (
+	"context"
 	"fmt"
-	"io"
-	"log"
 	"os"
 	"path/filepath"
-	"sort"
-	"strings"
 
 	"github.com/samzong/mdctl/internal/exporter/sitereader"
+	"github.com/samzong/mdctl/internal/logging"
 )
 
 // ExportOptions defines export options
 type ExportOptions struct {
-	Template            string      // Word template file path
-	GenerateToc         bool        // Whether to generate table of contents
-	ShiftHeadingLevelBy int         // Heading level offset
-	FileAsTitle         bool        // Whether to use filename as section title
-	Format              string      // Output format (docx, pdf, epub)
-	SiteType            string      // Site type (mkdocs, hugo, docusaurus)
-	Verbose             bool        // Whether to enable verbose logging
-	Logger              *log.Logger // Logger
-	SourceDirs          []string    // List of source directories for processing image paths
-	TocDepth            int         // Table of contents depth, default is 3
-	NavPath             string      // Specified navigation path to export
+	Template            string          // Word template file path
+	GenerateToc         bool            // Whether to generate table of contents
+	ShiftHeadingLevelBy int             // Heading level offset
+	FileAsTitle         bool            // Whether to use filename as section title
+	Format              string          // Output format (docx, pdf, epub)
+	SiteType            string          // Site type (mkdocs, hugo, docusaurus)
+	Verbose             bool            // Whether to enable verbose logging
+	Logger              *logging.Logger // Logger
+	SourceDirs          []string        // List of source directories for processing image paths
+	TocDepth            int             // Table of contents depth, default is 3
+	NavPaths            []string        // Navigation paths to export, wildcards allowed
+	DryRun              bool            // Only record the files and Pandoc command, do not run it
+	Plan                *ExportPlan     // Receives the export plan in dry-run mode when set
+	Title               string          // Document title metadata
+	Authors             []string        // Author metadata
+	Language            string          // Language metadata, e.g. en-US
+	Identifier          string          // EPUB identifier such as an ISBN or URN, Pandoc generates a UUID when empty
+	CoverImage          string          // EPUB cover image
+	EmbedFonts          []string        // Font files embedded in EPUB output
+	ChapterLevel        int             // Heading level EPUB chapters are split at, detected from the input when 0
+	MaxImageWidth       string          // Downscale and constrain images wider than this, e.g. 6in or 15cm
+	ImageDPI            int             // Resolution of images without resolution information, Pandoc's default when 0
+	LuaFilters          []string        // Pandoc Lua filters, applied in order
+	PandocArgs          []string        // Extra arguments appended to the Pandoc command
+	Theme               string          // Stored template or built-in theme styling the output
+	HeaderText          string          // Text centered in the page header of PDF and DOCX output
+	FooterText          string          // Text centered in the page footer of PDF and DOCX output
+	PageNumbers         bool            // Number the pages in the footer of PDF and DOCX output
+	Watermark           string          // Diagonal watermark text behind the pages of PDF and DOCX output
+	PDFEngineOpts       []string        // Options passed to the PDF engine
+	SkipPDFCheck        bool            // Do not verify that PDF bookmarks match the table of contents
+	Jobs                int             // Documents of a split export exported at the same time, 1 when not positive
+	Emoji               string          // Emoji handling of PDF output (EmojiFont, EmojiTwemoji, EmojiStrip), kept as they are when empty
+	EmojiFont           string          // Font of EmojiFont mode, DefaultEmojiFont when empty
+	Dedupe              string          // Handling of files merged more than once (DedupeLinkToFirst, DedupeSkip, DedupeDuplicate)
+	Stamp               *BuildStamp     // Generation info added to the footer and document properties when set
+	LinkBaseURL         string          // URL the site is published at, relative links between pages become absolute links below it when set
+	LinkRoot            string          // Site directory the links are resolved in, the input directory when empty
+	NumberHeadings      bool            // Number headings (1., 1.1, 1.2.3) with sec- anchors while merging instead of leaving it to Pandoc
+	Glossary            string          // Glossary file whose terms used in the content are appended as a glossary section
+	ExpandAcronyms      bool            // Spell out the acronyms of the glossary on first use
+	InputFormat         string          // Pandoc reader of the sources with optional extension toggles, e.g. gfm+smart, DefaultInputFormat when empty
+	Extensions          []string        // Extension toggles of the reader applied last, e.g. +smart or -footnotes
 }
 
+// siteLinks returns the converter of relative page links, nil when no link
+// base URL is set
+func (options ExportOptions) siteLinks(files []string) (*SiteLinks, error) {
+	if options.LinkBaseURL == "" {
+		return nil, nil
+	}
+	root := options.LinkRoot
+	if root == "" {
+		root = commonDir(files)
+	}
+	return NewSiteLinks(options.LinkBaseURL, options.SiteType, root)
+}
+
+// ExportPlan describes what a dry-run export would do
+type ExportPlan struct {
+	Files   []string `json:"files"`   // Input files in merge order
+	Command []string `json:"command"` // Pandoc command line
+	// Plans of the separate documents of a split export, whose Files lists
+	// the input files of all of them
+	Documents []ExportPlan `json:"documents,omitempty"`
+}
+
+// mergedPlaceholder stands for the merged temporary file in dry-run plans
+const mergedPlaceholder = "<merged markdown>"
+
 // Exporter defines exporter interface
 type Exporter interface {
-	Export(input string, output string, options ExportOptions) error
+	Export(ctx context.Context, input string, output string, options ExportOptions) error
 }
 
 // DefaultExporter is the default exporter implementation
 type DefaultExporter struct {
 	pandocPath string
-	logger     *log.Logger
+	logger     *logging.Logger
 }
 
 // NewExporter creates a new exporter
 func NewExporter() *DefaultExporter {
 	return &DefaultExporter{
 		pandocPath: "pandoc", // Default to pandoc in system PATH
-		logger:     log.New(os.Stdout, "[EXPORTER] ", log.LstdFlags),
+		logger:     logging.New("EXPORTER"),
 	}
 }
 
 // ExportFile exports a single Markdown file
-func (e *DefaultExporter) ExportFile(input, output string, options ExportOptions) error {
+func (e *DefaultExporter) ExportFile(ctx context.Context, input, output string, options ExportOptions) error {
 	// Set logger
 	if options.Logger != nil {
 		e.logger = options.Logger
-	} else if !options.Verbose {
-		e.logger = log.New(io.Discard, "", 0)
 	}
 
 	e.logger.Printf("Exporting file: %s -> %s", input, output)
@@ -66,11 +119,13 @@ func (e *DefaultExporter) ExportFile(input, output string, options ExportOptions
 
 	// Create output directory (if it doesn't exist)
 	outputDir := filepath.Dir(output)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		e.logger.Printf("Error: failed to create output directory: %s", err)
-		return fmt.Errorf("failed to create output directory: %s", err)
+	if !options.DryRun {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			e.logger.Printf("Error: failed to create output directory: %s", err)
+			return fmt.Errorf("failed to create output directory: %s", err)
+		}
+		e.logger.Printf("Output directory created/verified: %s", outputDir)
 	}
-	e.logger.Printf("Output directory created/verified: %s", outputDir)
 
 	// Add source directory to SourceDirs
 	sourceDir := filepath.Dir(input)
@@ -91,13 +146,62 @@ func (e *DefaultExporter) ExportFile(input, output string, options ExportOptions
 	}
 	e.logger.Printf("Added source directory to resource paths: %s", sourceDir)
 
+	if options.Plan != nil {
+		options.Plan.Files = []string{input}
+	}
+
+	// Oversized images are resized, page links converted, headings numbered
+	// and the glossary added in a merged copy of the file
+	if (options.MaxImageWidth != "" || options.LinkBaseURL != "" || options.NumberHeadings || options.Glossary != "") && !options.DryRun {
+		merger := &Merger{Logger: e.logger, Verbose: options.Verbose, NumberHeadings: options.NumberHeadings, ExpandAcronyms: options.ExpandAcronyms}
+		if options.Glossary != "" {
+			glossary, err := LoadGlossary(options.Glossary)
+			if err != nil {
+				return err
+			}
+			merger.Glossary = glossary
+		}
+		if options.MaxImageWidth != "" {
+			images, err := NewImageResizer(options.MaxImageWidth, options.ImageDPI, e.logger)
+			if err != nil {
+				return err
+			}
+			defer images.Close()
+			merger.Images = images
+		}
+		links, err := options.siteLinks([]string{input})
+		if err != nil {
+			return err
+		}
+		merger.Links = links
+
+		tempFile, err := os.CreateTemp("", "mdctl-resized-*.md")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary file: %s", err)
+		}
+		tempFile.Close()
+		defer os.Remove(tempFile.Name())
+
+		if err := merger.Merge([]string{input}, tempFile.Name()); err != nil {
+			return fmt.Errorf("failed to prepare %s: %s", input, err)
+		}
+		input = tempFile.Name()
+	}
+
+	// A single document is split into chapters at its top heading level
+	if options.Format == "epub" && options.ChapterLevel == 0 {
+		if content, err := os.ReadFile(input); err == nil {
+			options.ChapterLevel = TopHeadingLevel(string(content))
+		}
+	}
+
 	// Use Pandoc to export
 	e.logger.Println("Starting Pandoc export process...")
 	pandocExporter := &PandocExporter{
 		PandocPath: e.pandocPath,
 		Logger:     e.logger,
 	}
-	err := pandocExporter.Export(input, output, options)
+	err := pandocExporter.Export(ctx, input, output, options)
 	if err != nil {
 		e.logger.Printf("Pandoc export failed: %s", err)
 		return err
@@ -108,12 +212,10 @@ func (e *DefaultExporter) ExportFile(input, output string, options ExportOptions
 }
 
 // ExportDirectory exports Markdown files in a directory
-func (e *DefaultExporter) ExportDirectory(inputDir, output string, options ExportOptions) error {
+func (e *DefaultExporter) ExportDirectory(ctx context.Context, inputDir, output string, options ExportOptions) error {
 	// Set logger
 	if options.Logger != nil {
 		e.logger = options.Logger
-	} else if !options.Verbose {
-		e.logger = log.New(io.Discard, "", 0)
 	}
 
 	e.logger.Printf("Exporting directory: %s -> %s", inputDir, output)
@@ -127,11 +229,13 @@ func (e *DefaultExporter) ExportDirectory(inputDir, output string, options Expor
 
 	// Create output directory (if it doesn't exist)
 	outputDir := filepath.Dir(output)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		e.logger.Printf("Error: failed to create output directory: %s", err)
-		return fmt.Errorf("failed to create output directory: %s", err)
+	if !options.DryRun {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			e.logger.Printf("Error: failed to create output directory: %s", err)
+			return fmt.Errorf("failed to create output directory: %s", err)
+		}
+		e.logger.Printf("Output directory created/verified: %s", outputDir)
 	}
-	e.logger.Printf("Output directory created/verified: %s", outputDir)
 
 	// Initialize SourceDirs (if nil)
 	if options.SourceDirs == nil {
@@ -150,6 +254,9 @@ func (e *DefaultExporter) ExportDirectory(inputDir, output string, options Expor
 		}
 	}
 	e.logger.Printf("Added input directory to resource paths: %s", inputDir)
+	if options.LinkRoot == "" {
+		options.LinkRoot = inputDir
+	}
 
 	// Depending on site type, choose different processing
 	var files []string
@@ -173,7 +280,7 @@ func (e *DefaultExporter) ExportDirectory(inputDir, output string, options Expor
 		e.logger.Printf("Directory confirmed as %s site", options.SiteType)
 
 		e.logger.Println("Reading site structure...")
-		files, err = reader.ReadStructure(inputDir, "", options.NavPath)
+		files, err = reader.ReadStructure(inputDir, "", options.NavPaths)
 		if err != nil {
 			e.logger.Printf("Error reading site structure: %s", err)
 			return err
@@ -181,7 +288,7 @@ func (e *DefaultExporter) ExportDirectory(inputDir, output string, options Expor
 		e.logger.Printf("Found %d files in site structure", len(files))
 	} else {
 		// Basic directory mode: sort files by name
-		e.logger.Println("Using basic directory mode, sorting files by name")
+		e.logger.Println("Using basic directory mode, ordering files by _order.yaml, front matter weight and name")
 		files, err = GetMarkdownFilesInDir(inputDir)
 		if err != nil {
 			e.logger.Printf("Error getting markdown files: %s", err)
@@ -195,10 +302,43 @@ func (e *DefaultExporter) ExportDirectory(inputDir, output string, options Expor
 		return fmt.Errorf("no markdown files found in directory: %s", inputDir)
 	}
 
+	return e.ExportFiles(ctx, files, output, options)
+}
+
+// ExportFiles merges the given Markdown files in order and exports them as one document
+func (e *DefaultExporter) ExportFiles(ctx context.Context, files []string, output string, options ExportOptions) error {
+	// Set logger
+	if options.Logger != nil {
+		e.logger = options.Logger
+	}
+
+	if len(files) == 0 {
+		return fmt.Errorf("no markdown files to export")
+	}
+
+	// Create output directory (if it doesn't exist)
+	if !options.DryRun {
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			e.logger.Printf("Error: failed to create output directory: %s", err)
+			return fmt.Errorf("failed to create output directory: %s", err)
+		}
+	}
+
 	// If there's only one file, export directly
 	if len(files) == 1 {
 		e.logger.Printf("Only one file found, exporting directly: %s", files[0])
-		return e.ExportFile(files[0], output, options)
+		return e.ExportFile(ctx, files[0], output, options)
+	}
+
+	if options.DryRun {
+		if options.Plan != nil {
+			options.Plan.Files = files
+		}
+		pandocExporter := &PandocExporter{
+			PandocPath: e.pandocPath,
+			Logger:     e.logger,
+		}
+		return pandocExporter.Export(ctx, mergedPlaceholder, output, options)
 	}
 
 	// Merge multiple files
@@ -206,10 +346,34 @@ func (e *DefaultExporter) ExportDirectory(inputDir, output string, options Expor
 	merger := &Merger{
 		ShiftHeadingLevelBy: options.ShiftHeadingLevelBy,
 		FileAsTitle:         options.FileAsTitle,
+		ChapterPerFile:      options.Format == "epub",
 		Logger:              e.logger,
 		SourceDirs:          make([]string, 0),
 		Verbose:             options.Verbose,
+		Dedupe:              options.Dedupe,
+		NumberHeadings:      options.NumberHeadings,
+		ExpandAcronyms:      options.ExpandAcronyms,
 	}
+	if options.Glossary != "" {
+		glossary, err := LoadGlossary(options.Glossary)
+		if err != nil {
+			return err
+		}
+		merger.Glossary = glossary
+	}
+	if options.MaxImageWidth != "" {
+		images, err := NewImageResizer(options.MaxImageWidth, options.ImageDPI, e.logger)
+		if err != nil {
+			return err
+		}
+		defer images.Close()
+		merger.Images = images
+	}
+	links, err := options.siteLinks(files)
+	if err != nil {
+		return err
+	}
+	merger.Links = links
 
 	// Create temporary file
 	e.logger.Println("Creating temporary file for merged content...")
@@ -230,6 +394,9 @@ func (e *DefaultExporter) ExportDirectory(inputDir, output string, options Expor
 		return fmt.Errorf("failed to merge files: %s", err)
 	}
 	e.logger.Println("Files merged successfully")
+	if options.ChapterLevel == 0 {
+		options.ChapterLevel = merger.ChapterLevel
+	}
 
 	// Add merger collected source directories to options
 	if merger.SourceDirs != nil && len(merger.SourceDirs) > 0 {
@@ -256,7 +423,7 @@ func (e *DefaultExporter) ExportDirectory(inputDir, output string, options Expor
 		PandocPath: e.pandocPath,
 		Logger:     e.logger,
 	}
-	err = pandocExporter.Export(tempFilePath, output, options)
+	err = pandocExporter.Export(ctx, tempFilePath, output, options)
 	if err != nil {
 		e.logger.Printf("Pandoc export failed: %s", err)
 		return err
@@ -274,7 +441,9 @@ type SiteReader interface {
 	ReadStructure(dir string, configPath string) ([]string, error)
 }
 
-// GetMarkdownFilesInDir gets all Markdown files in a directory and sorts them by filename
+// GetMarkdownFilesInDir gets all Markdown files in a directory in export
+// order: by the _order.yaml files of the directories, then by the weight or
+// order front matter of the files and then by filename
 func GetMarkdownFilesInDir(dir string) ([]string, error) {
 	// Check if directory exists
 	info, err := os.Stat(dir)
@@ -285,27 +454,5 @@ func GetMarkdownFilesInDir(dir string) ([]string, error) {
 		return nil, fmt.Errorf("%s is not a directory", dir)
 	}
 
-	// Recursively find all Markdown files
-	var files []string
-	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			ext := strings.ToLower(filepath.Ext(path))
-			if ext == ".md" || ext == ".markdown" {
-				files = append(files, path)
-			}
-		}
-		return nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to walk directory %s: %s", dir, err)
-	}
-
-	// Sort by filename
-	sort.Strings(files)
-
-	return files, nil
+	return orderedMarkdownFiles(dir)
 }
@@ -0,0 +1,42 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadFileList reads a manifest of markdown files to export in order, one
+// path per line. Blank lines and lines starting with # are skipped, relative
+// paths are relative to the directory of the manifest.
+func ReadFileList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file list: %s", err)
+	}
+	defer f.Close()
+
+	var files []string
+	dir := filepath.Dir(path)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		file := filepath.FromSlash(line)
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		files = append(files, file)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file list %s: %s", path, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("file list %s names no files", path)
+	}
+	return files, nil
+}
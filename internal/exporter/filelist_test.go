@@ -0,0 +1,42 @@
+package exporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadFileList(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.md", "b.md", "c.md"} {
+		os.WriteFile(filepath.Join(dir, name), []byte("# "+name+"\n"), 0644)
+	}
+	manifest := filepath.Join(dir, "manifest.txt")
+	os.WriteFile(manifest, []byte("# Release notes first\nc.md\n\n  a.md\nb.md\n"), 0644)
+
+	files, err := ReadFileList(manifest)
+	if err != nil {
+		t.Fatalf("ReadFileList failed: %v", err)
+	}
+	want := []string{filepath.Join(dir, "c.md"), filepath.Join(dir, "a.md"), filepath.Join(dir, "b.md")}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("got %v, want %v", files, want)
+	}
+
+	// The given order is kept through the merge
+	plan := &ExportPlan{}
+	err = NewExporter().ExportFiles(context.Background(), files, filepath.Join(dir, "out.docx"), ExportOptions{Format: "docx", DryRun: true, Plan: plan})
+	if err != nil {
+		t.Fatalf("ExportFiles failed: %v", err)
+	}
+	if !reflect.DeepEqual(plan.Files, want) {
+		t.Errorf("plan files %v, want %v", plan.Files, want)
+	}
+
+	os.WriteFile(manifest, []byte("# nothing\n"), 0644)
+	if _, err := ReadFileList(manifest); err == nil {
+		t.Error("expected an error for an empty file list")
+	}
+}
@@ -0,0 +1,222 @@
+package exporter
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/samzong/mdctl/internal/mddoc"
+	"github.com/yuin/goldmark/ast"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultGlossaryTitle is the heading of the glossary section
+const DefaultGlossaryTitle = "Glossary"
+
+// Glossary is a glossary file of defined terms:
+//
+//	title: Glossary
+//	terms:
+//	  - term: API
+//	    expansion: Application Programming Interface
+//	    definition: Rules by which programs talk to each other.
+//	    aliases: [APIs]
+type Glossary struct {
+	Title string         `yaml:"title"`
+	Terms []GlossaryTerm `yaml:"terms"`
+}
+
+// GlossaryTerm is a defined term of a Glossary
+type GlossaryTerm struct {
+	Term       string   `yaml:"term"`
+	Expansion  string   `yaml:"expansion"`  // Spelled out form of an acronym
+	Definition string   `yaml:"definition"` // Markdown
+	Aliases    []string `yaml:"aliases"`    // Other forms counting as a use, e.g. plurals
+}
+
+// LoadGlossary reads and validates a glossary file
+func LoadGlossary(path string) (*Glossary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read glossary: %v", err)
+	}
+	var g Glossary
+	if err := yaml.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("failed to parse glossary %s: %v", path, err)
+	}
+	for i, t := range g.Terms {
+		if strings.TrimSpace(t.Term) == "" {
+			return nil, fmt.Errorf("glossary %s: term %d has no name", path, i+1)
+		}
+		if t.Expansion == "" && t.Definition == "" {
+			return nil, fmt.Errorf("glossary %s: term %s has neither expansion nor definition", path, t.Term)
+		}
+	}
+	if g.Title == "" {
+		g.Title = DefaultGlossaryTitle
+	}
+	return &g, nil
+}
+
+// termMatcher finds the uses of a term and its aliases in text
+type termMatcher struct {
+	term  *GlossaryTerm
+	regex *regexp.Regexp
+}
+
+// matcher returns the matcher of a term. Acronyms, terms without lowercase
+// letters, match case-sensitively, other terms in any case.
+func (t *GlossaryTerm) matcher() termMatcher {
+	forms := append([]string{t.Term}, t.Aliases...)
+	// Longer forms first, so that "APIs" is not matched as "API"
+	sort.SliceStable(forms, func(i, j int) bool { return len(forms[i]) > len(forms[j]) })
+	quoted := make([]string, len(forms))
+	for i, form := range forms {
+		quoted[i] = regexp.QuoteMeta(form)
+	}
+	pattern := strings.Join(quoted, "|")
+	if t.Term != strings.ToUpper(t.Term) {
+		pattern = "(?i)" + pattern
+	}
+	return termMatcher{term: t, regex: regexp.MustCompile(pattern)}
+}
+
+// find returns the byte ranges of the uses in text. A use must not continue
+// a word: an ASCII letter, digit or underscore may not touch it on a side
+// where the use is ASCII, so that API is not found in RAPID while terms in
+// scripts without spaces between words are found.
+func (m termMatcher) find(text string) [][]int {
+	var found [][]int
+	for _, loc := range m.regex.FindAllStringIndex(text, -1) {
+		before, _ := utf8.DecodeLastRuneInString(text[:loc[0]])
+		first, _ := utf8.DecodeRuneInString(text[loc[0]:loc[1]])
+		last, _ := utf8.DecodeLastRuneInString(text[loc[0]:loc[1]])
+		after, _ := utf8.DecodeRuneInString(text[loc[1]:])
+		if (wordRune(first) && wordRune(before)) || (wordRune(last) && wordRune(after)) {
+			continue
+		}
+		found = append(found, loc)
+	}
+	return found
+}
+
+// wordRune reports whether r is an ASCII letter, digit or underscore
+func wordRune(r rune) bool {
+	return r < utf8.RuneSelf && (r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// Apply appends a glossary section of the terms used in content, at the top
+// heading level and excluded from heading numbering. With expand, the first
+// use of every acronym outside headings is spelled out as "Application
+// Programming Interface (API)" unless the expansion comes before it or the
+// use is part of a longer expanded term. Terms are not looked for in code,
+// HTML and images. It also returns the number of terms used.
+func (g *Glossary) Apply(content string, expand bool) (string, int) {
+	matchers := make([]termMatcher, len(g.Terms))
+	for i := range g.Terms {
+		matchers[i] = g.Terms[i].matcher()
+	}
+
+	doc := mddoc.Parse([]byte(content))
+	used := make(map[*GlossaryTerm]bool)
+	expanded := make(map[*GlossaryTerm]bool)
+	var edits []mddoc.Edit
+	var before strings.Builder // Text preceding the current node
+	heading := 0
+	ast.Walk(doc.Root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch n := n.(type) {
+		case *ast.CodeSpan, *ast.CodeBlock, *ast.FencedCodeBlock, *ast.HTMLBlock, *ast.RawHTML, *ast.AutoLink, *ast.Image:
+			return ast.WalkSkipChildren, nil
+		case *ast.Heading:
+			if entering {
+				heading++
+			} else {
+				heading--
+			}
+		case *ast.Text:
+			if !entering {
+				break
+			}
+			text := string(n.Segment.Value(doc.Source))
+			for _, m := range matchers {
+				uses := m.find(text)
+				if len(uses) == 0 {
+					continue
+				}
+				used[m.term] = true
+				if !expand || heading > 0 || m.term.Expansion == "" || expanded[m.term] {
+					continue
+				}
+				expanded[m.term] = true
+				preceding := before.String() + text[:uses[0][0]]
+				if strings.Contains(strings.ToLower(preceding), strings.ToLower(m.term.Expansion)) {
+					continue
+				}
+				start, end := n.Segment.Start+uses[0][0], n.Segment.Start+uses[0][1]
+				if overlaps(edits, start, end) {
+					continue
+				}
+				edits = append(edits,
+					mddoc.Edit{Start: start, End: start, Text: m.term.Expansion + " ("},
+					mddoc.Edit{Start: end, End: end, Text: ")"})
+			}
+			before.WriteString(text)
+			before.WriteByte(' ')
+		}
+		return ast.WalkContinue, nil
+	})
+	if len(used) == 0 {
+		return content, 0
+	}
+
+	var terms []*GlossaryTerm
+	for term := range used {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		a, b := strings.ToLower(terms[i].Term), strings.ToLower(terms[j].Term)
+		if a != b {
+			return a < b
+		}
+		return terms[i].Term < terms[j].Term
+	})
+
+	var b strings.Builder
+	b.Write(doc.Apply(edits))
+	fmt.Fprintf(&b, "\n\n%s %s {#glossary .unnumbered}\n", strings.Repeat("#", TopHeadingLevel(content)), g.Title)
+	for _, t := range terms {
+		fmt.Fprintf(&b, "\n%s\n", t.entry())
+	}
+	return b.String(), len(terms)
+}
+
+// overlaps reports whether the range from start to end overlaps the range of
+// an expansion, whose edits are the opening and closing insertions in turn
+func overlaps(edits []mddoc.Edit, start, end int) bool {
+	for i := 0; i+1 < len(edits); i += 2 {
+		if start <= edits[i+1].Start && edits[i].Start <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// entry returns the glossary paragraph of a term, e.g. "**API**
+// (Application Programming Interface): Rules by which programs talk."
+func (t *GlossaryTerm) entry() string {
+	entry := "**" + t.Term + "**"
+	definition := strings.TrimSpace(t.Definition)
+	switch {
+	case t.Expansion != "" && definition != "":
+		entry += " (" + t.Expansion + "): " + definition
+	case t.Expansion != "":
+		entry += ": " + t.Expansion
+	default:
+		entry += ": " + definition
+	}
+	return entry
+}
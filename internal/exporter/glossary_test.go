@@ -0,0 +1,57 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGlossaryApply(t *testing.T) {
+	g := &Glossary{Title: "Glossary", Terms: []GlossaryTerm{
+		{Term: "API", Expansion: "Application Programming Interface", Definition: "Rules by which programs talk.", Aliases: []string{"APIs"}},
+		{Term: "SLA", Expansion: "Service Level Agreement"},
+		{Term: "tenant", Definition: "An isolated workspace."},
+		{Term: "CDN", Expansion: "Content Delivery Network"},
+		{Term: "RPC", Expansion: "Remote Procedure Call"},
+	}}
+	content := "## API Guide\n\nCall the APIs, not RAPID.\nEach Tenant has an API key.\n\n" +
+		"The Service Level Agreement (SLA) applies.\n\n`RPC` in code and ![CDN](cdn.png).\n\n## Usage\n\nMore API.\n"
+
+	got, used := g.Apply(content, true)
+	want := "## API Guide\n\nCall the Application Programming Interface (APIs), not RAPID.\nEach Tenant has an API key.\n\n" +
+		"The Service Level Agreement (SLA) applies.\n\n`RPC` in code and ![CDN](cdn.png).\n\n## Usage\n\nMore API.\n" +
+		"\n\n## Glossary {#glossary .unnumbered}\n" +
+		"\n**API** (Application Programming Interface): Rules by which programs talk.\n" +
+		"\n**SLA**: Service Level Agreement\n" +
+		"\n**tenant**: An isolated workspace.\n"
+	if got != want || used != 3 {
+		t.Errorf("Apply() = %d\n%s\nwant 3\n%s", used, got, want)
+	}
+
+	got, _ = g.Apply(content, false)
+	if !strings.HasPrefix(got, content) {
+		t.Errorf("expected the content unchanged without expansion, got\n%s", got)
+	}
+	if got, used := g.Apply("Nothing defined here.\n", true); got != "Nothing defined here.\n" || used != 0 {
+		t.Errorf("expected no glossary without used terms, got %d\n%s", used, got)
+	}
+}
+
+func TestLoadGlossary(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "glossary.yaml")
+	os.WriteFile(path, []byte("terms:\n  - term: API\n    expansion: Application Programming Interface\n"), 0644)
+	g, err := LoadGlossary(path)
+	if err != nil || g.Title != DefaultGlossaryTitle || len(g.Terms) != 1 {
+		t.Fatalf("LoadGlossary() = %+v, %v", g, err)
+	}
+
+	os.WriteFile(path, []byte("terms:\n  - term: API\n"), 0644)
+	if _, err := LoadGlossary(path); err == nil {
+		t.Errorf("expected an error for a term without expansion or definition")
+	}
+	if _, err := LoadGlossary(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
@@ -1,77 +1,35 @@
 package exporter
 
 import (
-	"bufio"
 	"fmt"
-	"regexp"
 	"strings"
-)
 
-var (
-	// Match ATX-style headings (those starting with #)
-	atxHeadingRegex = regexp.MustCompile(`^(#{1,6})\s+(.+)$`)
-	// Match Setext-style headings (underline style)
-	setextHeading1Regex = regexp.MustCompile(`^=+\s*$`)
-	setextHeading2Regex = regexp.MustCompile(`^-+\s*$`)
+	"github.com/samzong/mdctl/internal/mddoc"
+	"github.com/yuin/goldmark/ast"
 )
 
-// ShiftHeadings Adjust heading levels in Markdown text
+// ShiftHeadings Adjust heading levels in Markdown text, setext headings
+// become ATX headings and headings beyond level 6 become bold text
 func ShiftHeadings(content string, shiftBy int) string {
 	if shiftBy == 0 {
 		return content
 	}
 
-	scanner := bufio.NewScanner(strings.NewReader(content))
-	var result []string
-	var prevLine string
-	var isPrevLineHeading bool
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// Handle ATX-style headings
-		if matches := atxHeadingRegex.FindStringSubmatch(line); matches != nil {
-			level := len(matches[1]) + shiftBy
-			heading := matches[2]
-
-			if level <= 6 {
-				// Still valid heading level
-				result = append(result, fmt.Sprintf("%s %s", strings.Repeat("#", level), heading))
-			} else {
-				// Exceeded max heading level, convert to bold text
-				result = append(result, fmt.Sprintf("**%s**", heading))
-			}
-			isPrevLineHeading = false
-		} else if setextHeading1Regex.MatchString(line) && prevLine != "" {
-			// Handle Setext-style level 1 headings
-			level := 1 + shiftBy
-			if level <= 6 {
-				result[len(result)-1] = fmt.Sprintf("%s %s", strings.Repeat("#", level), prevLine)
-			} else {
-				result[len(result)-1] = fmt.Sprintf("**%s**", prevLine)
-			}
-			isPrevLineHeading = true
-		} else if setextHeading2Regex.MatchString(line) && prevLine != "" {
-			// Handle Setext-style level 2 headings
-			level := 2 + shiftBy
-			if level <= 6 {
-				result[len(result)-1] = fmt.Sprintf("%s %s", strings.Repeat("#", level), prevLine)
-			} else {
-				result[len(result)-1] = fmt.Sprintf("**%s**", prevLine)
-			}
-			isPrevLineHeading = true
-		} else {
-			// Ordinary line
-			result = append(result, line)
-			isPrevLineHeading = false
+	doc := mddoc.Parse([]byte(content))
+	var edits []mddoc.Edit
+	for _, h := range doc.Headings() {
+		level := h.Level + shiftBy
+		if level < 1 {
+			level = 1
 		}
-
-		if !isPrevLineHeading {
-			prevLine = line
+		text := fmt.Sprintf("%s %s", strings.Repeat("#", level), h.Text)
+		if level > 6 {
+			// Exceeded max heading level, convert to bold text
+			text = fmt.Sprintf("**%s**", h.Text)
 		}
+		edits = append(edits, mddoc.Edit{Start: h.Start, End: h.End, Text: text})
 	}
-
-	return strings.Join(result, "\n")
+	return string(doc.Apply(edits))
 }
 
 // AddTitleFromFilename Add heading from filename
@@ -97,3 +55,85 @@ func AddTitleFromFilename(content, filename string, level int) string {
 
 	return titleLine + content
 }
+
+// FirstHeadingLevel returns the level of the heading a document starts
+// with, or 0 if its first block is not a heading
+func FirstHeadingLevel(content string) int {
+	doc := mddoc.Parse([]byte(content))
+	if first, ok := doc.Root.FirstChild().(*ast.Heading); ok {
+		return first.Level
+	}
+	return 0
+}
+
+// TopHeadingLevel returns the smallest heading level outside code blocks, or
+// 1 if the document has no headings
+func TopHeadingLevel(content string) int {
+	top := 0
+	for _, h := range mddoc.Parse([]byte(content)).Headings() {
+		if top == 0 || h.Level < top {
+			top = h.Level
+		}
+	}
+	if top == 0 {
+		return 1
+	}
+	return top
+}
+
+// NumberHeadings prefixes headings with hierarchical section numbers (1.,
+// 1.1, 1.2.3) counted from the top heading level and marks each with a
+// deterministic sec-1-2-3 anchor. The anchor is an empty span, so the
+// identifier Pandoc derives from the heading text, which skips the leading
+// number, stays valid. Headings marked {-} or {.unnumbered} are left alone.
+func NumberHeadings(content string) string {
+	doc := mddoc.Parse([]byte(content))
+	headings := doc.Headings()
+	if len(headings) == 0 {
+		return content
+	}
+	top := TopHeadingLevel(content)
+
+	counters := make([]int, 7-top)
+	var edits []mddoc.Edit
+	for _, h := range headings {
+		if unnumberedHeading(h.Text) {
+			continue
+		}
+		depth := h.Level - top
+		counters[depth]++
+		for i := depth + 1; i < len(counters); i++ {
+			counters[i] = 0
+		}
+
+		parts := make([]string, depth+1)
+		for i := range parts {
+			parts[i] = fmt.Sprint(counters[i])
+		}
+		number := strings.Join(parts, ".")
+		if depth == 0 {
+			number += "."
+		}
+		text := fmt.Sprintf("%s []{#sec-%s}%s %s", strings.Repeat("#", h.Level), strings.Join(parts, "-"), number, h.Text)
+		edits = append(edits, mddoc.Edit{Start: h.Start, End: h.End, Text: text})
+	}
+	return string(doc.Apply(edits))
+}
+
+// unnumberedHeading reports whether a heading has Pandoc's unnumbered class
+func unnumberedHeading(text string) bool {
+	text = strings.TrimSpace(text)
+	if !strings.HasSuffix(text, "}") {
+		return false
+	}
+	start := strings.LastIndex(text, "{")
+	if start < 0 {
+		return false
+	}
+	for _, attr := range strings.Fields(text[start+1 : len(text)-1]) {
+		if attr == "-" || attr == ".unnumbered" {
+			return true
+		}
+	}
+	return false
+}
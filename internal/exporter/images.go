@@ -0,0 +1,223 @@
+package exporter
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // Register GIF decoding
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/mddoc"
+)
+
+// DefaultImageDPI is the resolution Pandoc assumes for images without
+// resolution information
+const DefaultImageDPI = 96
+
+// lengthRegex matches a length such as 6in, 15.5cm or 800px
+var lengthRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(in|cm|mm|pt|px)?$`)
+
+// ImageResizer downscales local images that are wider than the maximum display
+// width, so Word and LaTeX do not render screenshots beyond the page margins.
+// Resized copies are written to a temporary directory removed by Close.
+type ImageResizer struct {
+	MaxWidth string // Maximum display width as given, e.g. 6in
+	DPI      int    // Resolution used to convert the width to pixels
+	Logger   *logging.Logger
+
+	maxPixels int
+	dir       string
+	resized   map[string]string // Source image to resized copy, empty if kept
+}
+
+// NewImageResizer creates a resizer for a maximum width such as 6in, 15cm or
+// 800px, a DPI of 0 uses DefaultImageDPI
+func NewImageResizer(maxWidth string, dpi int, logger *logging.Logger) (*ImageResizer, error) {
+	if dpi <= 0 {
+		dpi = DefaultImageDPI
+	}
+	pixels, err := widthInPixels(maxWidth, dpi)
+	if err != nil {
+		return nil, err
+	}
+	if logger == nil {
+		logger = logging.New("IMAGE")
+	}
+	return &ImageResizer{
+		MaxWidth:  maxWidth,
+		DPI:       dpi,
+		Logger:    logger,
+		maxPixels: pixels,
+		resized:   make(map[string]string),
+	}, nil
+}
+
+// widthInPixels converts a length to pixels at the given resolution, plain
+// numbers are pixels
+func widthInPixels(length string, dpi int) (int, error) {
+	m := lengthRegex.FindStringSubmatch(strings.TrimSpace(strings.ToLower(length)))
+	if m == nil {
+		return 0, fmt.Errorf("invalid image width %q (use a length such as 6in, 15cm or 800px)", length)
+	}
+	value, _ := strconv.ParseFloat(m[1], 64)
+	inches := map[string]float64{"in": 1, "cm": 1 / 2.54, "mm": 1 / 25.4, "pt": 1.0 / 72}
+	pixels := value
+	if factor, ok := inches[m[2]]; ok {
+		pixels = value * factor * float64(dpi)
+	}
+	if pixels < 1 {
+		return 0, fmt.Errorf("image width %q is too small", length)
+	}
+	return int(pixels + 0.5), nil
+}
+
+// Rewrite resizes the local images referenced by content, paths are resolved
+// against baseDir. Resized images get the maximum width as attribute unless
+// they already have attributes.
+func (r *ImageResizer) Rewrite(content, baseDir string) string {
+	doc := mddoc.Parse([]byte(content))
+	var edits []mddoc.Edit
+	for _, img := range doc.Images() {
+		if strings.Contains(img.Destination, "://") || strings.HasPrefix(img.Destination, "data:") {
+			continue
+		}
+
+		path := mddoc.Unescape(img.Destination)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		resized, err := r.Resize(path)
+		if err != nil {
+			r.Logger.Printf("Keeping image %s: %s", img.Destination, err)
+			continue
+		}
+		if resized == "" {
+			continue
+		}
+
+		edits = append(edits, mddoc.Edit{Start: img.Start, End: img.End, Text: filepath.ToSlash(resized)})
+		if img.After >= len(content) || content[img.After] != '{' {
+			edits = append(edits, mddoc.Edit{Start: img.After, End: img.After, Text: "{width=" + r.MaxWidth + "}"})
+		}
+	}
+	return string(doc.Apply(edits))
+}
+
+// Resize returns a downscaled copy of an image wider than the maximum width,
+// or an empty path if the image fits or is not a PNG, JPEG or GIF
+func (r *ImageResizer) Resize(path string) (string, error) {
+	if resized, ok := r.resized[path]; ok {
+		return resized, nil
+	}
+
+	f, err := os.Open(fsutil.LongPath(path))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	config, format, err := image.DecodeConfig(f)
+	if err != nil || config.Width <= r.maxPixels {
+		r.resized[path] = ""
+		return "", nil
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return "", err
+	}
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	height := config.Height * r.maxPixels / config.Width
+	if height < 1 {
+		height = 1
+	}
+	scaled := downscale(img, r.maxPixels, height)
+
+	if r.dir == "" {
+		if r.dir, err = os.MkdirTemp("", "mdctl-images-*"); err != nil {
+			return "", fmt.Errorf("failed to create image directory: %v", err)
+		}
+	}
+	ext := ".png"
+	if format == "jpeg" {
+		ext = ".jpg"
+	}
+	name := fmt.Sprintf("%d-%s%s", len(r.resized), strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), ext)
+	resized := filepath.Join(r.dir, name)
+
+	out, err := os.Create(resized)
+	if err != nil {
+		return "", fmt.Errorf("failed to write resized image: %v", err)
+	}
+	if format == "jpeg" {
+		err = jpeg.Encode(out, scaled, &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(out, scaled)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to encode resized image: %v", err)
+	}
+
+	r.Logger.Printf("Downscaled image %s from %dpx to %dpx wide", path, config.Width, r.maxPixels)
+	r.resized[path] = resized
+	return resized, nil
+}
+
+// Close removes the resized images
+func (r *ImageResizer) Close() error {
+	if r.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(r.dir)
+}
+
+// downscale shrinks an image with a box filter, every target pixel is the
+// average of the source pixels it covers
+func downscale(src image.Image, width, height int) *image.RGBA {
+	b := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*b.Dy()/height, (y+1)*b.Dy()/height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*b.Dx()/width, (x+1)*b.Dx()/width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride+x0*4 : sy*rgba.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			offset := y*dst.Stride + x*4
+			for c := 0; c < 4; c++ {
+				dst.Pix[offset+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
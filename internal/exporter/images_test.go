@@ -0,0 +1,80 @@
+package exporter
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWidthInPixels(t *testing.T) {
+	tests := []struct {
+		length string
+		dpi    int
+		want   int
+	}{
+		{"6in", 96, 576},
+		{"2.54cm", 100, 100},
+		{"800px", 300, 800},
+		{"640", 96, 640},
+	}
+	for _, tt := range tests {
+		got, err := widthInPixels(tt.length, tt.dpi)
+		if err != nil || got != tt.want {
+			t.Errorf("widthInPixels(%q, %d) = %d, %v, want %d", tt.length, tt.dpi, got, err, tt.want)
+		}
+	}
+	if _, err := widthInPixels("wide", 96); err == nil {
+		t.Error("expected an error for an invalid width")
+	}
+}
+
+func TestImageResizerRewrite(t *testing.T) {
+	dir := t.TempDir()
+	writePNG := func(name string, width, height int) {
+		img := image.NewRGBA(image.Rect(0, 0, width, height))
+		for i := range img.Pix {
+			img.Pix[i] = 200
+		}
+		img.Set(0, 0, color.RGBA{0, 0, 0, 255})
+		f, _ := os.Create(filepath.Join(dir, name))
+		png.Encode(f, img)
+		f.Close()
+	}
+	writePNG("wide.png", 400, 100)
+	writePNG("small.png", 50, 50)
+
+	images, err := NewImageResizer("2in", 100, nil)
+	if err != nil {
+		t.Fatalf("NewImageResizer failed: %v", err)
+	}
+	defer images.Close()
+
+	content := "![Wide](wide.png)\n![Small](small.png)\n![Sized](wide.png){width=50%}\n![Web](https://example.com/a.png)\n"
+	got := images.Rewrite(content, dir)
+	lines := strings.Split(got, "\n")
+
+	if !strings.HasSuffix(lines[0], "{width=2in}") || strings.Contains(lines[0], "(wide.png)") {
+		t.Errorf("expected a resized image with width, got %q", lines[0])
+	}
+	if lines[1] != "![Small](small.png)" || lines[3] != "![Web](https://example.com/a.png)" {
+		t.Errorf("expected small and web images to be kept, got %q and %q", lines[1], lines[3])
+	}
+	if !strings.HasSuffix(lines[2], "{width=50%}") {
+		t.Errorf("expected existing attributes to be kept, got %q", lines[2])
+	}
+
+	resized, _ := images.Resize(filepath.Join(dir, "wide.png"))
+	f, err := os.Open(resized)
+	if err != nil {
+		t.Fatalf("resized image missing: %v", err)
+	}
+	defer f.Close()
+	config, _, err := image.DecodeConfig(f)
+	if err != nil || config.Width != 200 || config.Height != 50 {
+		t.Errorf("expected a 200x50 image, got %dx%d (%v)", config.Width, config.Height, err)
+	}
+}
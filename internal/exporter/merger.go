@@ -4,37 +4,60 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"unicode/utf8"
 
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"github.com/samzong/mdctl/internal/metrics"
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/transform"
 )
 
+// Handling of files merged more than once, e.g. listed in several sections
+// of a navigation
+const (
+	DedupeLinkToFirst = "link-to-first" // Replace repeats with a link to the first copy
+	DedupeSkip        = "skip"          // Leave repeats out
+	DedupeDuplicate   = "duplicate"     // Merge every copy
+)
+
 // Merger Merge multiple Markdown files
 type Merger struct {
 	ShiftHeadingLevelBy int
 	FileAsTitle         bool
-	Logger              *log.Logger
+	// Start every file with a heading at a common level, so that EPUB
+	// chapters are split at file boundaries
+	ChapterPerFile bool
+	// Heading level the files start at, set by Merge when ChapterPerFile is enabled
+	ChapterLevel int
+	Logger       *logging.Logger
 	// Store all source directories, used to set Pandoc's resource paths
 	SourceDirs []string
 	// Whether to enable verbose logging
 	Verbose bool
+	// Downscales images wider than the maximum width when set
+	Images *ImageResizer
+	// Handling of repeated files, DedupeLinkToFirst when empty
+	Dedupe string
+	// Converts relative links between pages to links to the published site when set
+	Links *SiteLinks
+	// Number the headings of the merged content and give them sec- anchors
+	NumberHeadings bool
+	// Appends a section of the glossary terms the merged content uses when set
+	Glossary *Glossary
+	// Spell out acronyms of the glossary on first use
+	ExpandAcronyms bool
 }
 
 // Merge Merge multiple Markdown files into a single target file
 func (m *Merger) Merge(sources []string, target string) error {
 	// If no logger is provided, create a default one
 	if m.Logger == nil {
-		if m.Verbose {
-			m.Logger = log.New(os.Stdout, "[MERGER] ", log.LstdFlags)
-		} else {
-			m.Logger = log.New(io.Discard, "", 0)
-		}
+		m.Logger = logging.New("MERGER")
 	}
 
 	if len(sources) == 0 {
@@ -43,7 +66,10 @@ func (m *Merger) Merge(sources []string, target string) error {
 	}
 
 	m.Logger.Printf("Merging %d files into: %s", len(sources), target)
-	var mergedContent strings.Builder
+	metrics.AddFiles(len(sources))
+	contents := make([]string, 0, len(sources))
+	merged := make([]string, 0, len(sources)) // Source of each content
+	firstIndex := make(map[string]int)        // Content index of the first copy of each file
 
 	// Initialize source directory list
 	m.SourceDirs = make([]string, 0, len(sources))
@@ -53,6 +79,24 @@ func (m *Merger) Merge(sources []string, target string) error {
 	for i, source := range sources {
 		m.Logger.Printf("Processing file %d/%d: %s", i+1, len(sources), source)
 
+		key := source
+		if abs, err := filepath.Abs(source); err == nil {
+			key = abs
+		}
+		if first, ok := firstIndex[key]; ok && m.Dedupe != DedupeDuplicate {
+			if m.Dedupe == DedupeSkip {
+				m.Logger.Printf("Skipping repeated file: %s", source)
+				continue
+			}
+			m.Logger.Printf("Linking repeated file to its first copy: %s", source)
+			var anchor, title string
+			contents[first], anchor, title = anchorContent(contents[first], fmt.Sprintf("mdctl-file-%d", first+1), source)
+			contents = append(contents, fmt.Sprintf("See [%s](#%s).", title, anchor))
+			merged = append(merged, source)
+			continue
+		}
+		firstIndex[key] = len(contents)
+
 		// Get source file's directory and add to list (deduplication)
 		sourceDir := filepath.Dir(source)
 		if !sourceDirsMap[sourceDir] {
@@ -61,7 +105,7 @@ func (m *Merger) Merge(sources []string, target string) error {
 		}
 
 		// Read file content
-		content, err := os.ReadFile(source)
+		content, err := os.ReadFile(fsutil.LongPath(source))
 		if err != nil {
 			m.Logger.Printf("Error reading file %s: %s", source, err)
 			return fmt.Errorf("failed to read file %s: %s", source, err)
@@ -86,7 +130,12 @@ func (m *Merger) Merge(sources []string, target string) error {
 
 		// Remove YAML front matter
 		m.Logger.Println("Removing YAML front matter...")
-		processedContent = removeYAMLFrontMatter(processedContent)
+		processedContent = mddoc.StripFrontMatter(processedContent)
+
+		if m.Links != nil {
+			m.Logger.Println("Converting page links to site URLs...")
+			processedContent = m.Links.Rewrite(processedContent, source)
+		}
 
 		// Process image paths
 		m.Logger.Println("Processing image paths...")
@@ -96,6 +145,11 @@ func (m *Merger) Merge(sources []string, target string) error {
 			return fmt.Errorf("failed to process image paths: %s", err)
 		}
 
+		if m.Images != nil {
+			m.Logger.Println("Resizing oversized images...")
+			processedContent = m.Images.Rewrite(processedContent, ".")
+		}
+
 		// Adjust heading levels
 		if m.ShiftHeadingLevelBy != 0 {
 			m.Logger.Printf("Shifting heading levels by %d", m.ShiftHeadingLevelBy)
@@ -109,18 +163,27 @@ func (m *Merger) Merge(sources []string, target string) error {
 			processedContent = AddTitleFromFilename(processedContent, filename, 1+m.ShiftHeadingLevelBy)
 		}
 
-		// Add to merged content
-		m.Logger.Printf("Adding processed content to merged result (length: %d bytes)", len(processedContent))
-		mergedContent.WriteString(processedContent)
+		contents = append(contents, processedContent)
+		merged = append(merged, source)
+	}
 
-		// If not the last file, add separator
-		if i < len(sources)-1 {
-			mergedContent.WriteString("\n\n")
-		}
+	if m.ChapterPerFile {
+		m.startChapters(merged, contents)
 	}
 
 	// Final content
-	finalContent := mergedContent.String()
+	finalContent := strings.Join(contents, "\n\n")
+
+	if m.Glossary != nil {
+		var used int
+		finalContent, used = m.Glossary.Apply(finalContent, m.ExpandAcronyms)
+		m.Logger.Printf("Added glossary of %d used terms", used)
+	}
+
+	if m.NumberHeadings {
+		m.Logger.Println("Numbering headings...")
+		finalContent = NumberHeadings(finalContent)
+	}
 
 	// Check again for any YAML-related issues
 	m.Logger.Println("Sanitizing final content...")
@@ -138,15 +201,64 @@ func (m *Merger) Merge(sources []string, target string) error {
 	return nil
 }
 
+// anchorContent marks the start of a merged file with an anchor and returns
+// the content, the anchor and the title of the file. The anchor is an empty
+// span inside the first heading, which keeps the identifier Pandoc derives
+// from the heading, or before the content when it has no heading.
+func anchorContent(content, id, source string) (string, string, string) {
+	span := "[]{#" + id + "}"
+	headings := mddoc.Parse([]byte(content)).Headings()
+	if len(headings) == 0 {
+		if !strings.HasPrefix(content, span) {
+			content = span + "\n\n" + content
+		}
+		return content, id, fileTitle(source)
+	}
+
+	h := headings[0]
+	if strings.HasPrefix(h.Text, span) {
+		// Anchored for an earlier repeat
+		return content, id, strings.TrimPrefix(h.Text, span)
+	}
+	textStart := h.Start
+	if !h.Setext {
+		textStart += len(content[h.Start:]) - len(strings.TrimLeft(content[h.Start:], "#"))
+		textStart += len(content[textStart:]) - len(strings.TrimLeft(content[textStart:], " \t"))
+	}
+	return content[:textStart] + span + content[textStart:], id, h.Text
+}
+
+// fileTitle returns the name of a file without its extension
+func fileTitle(source string) string {
+	return strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+}
+
+// startChapters makes every file start with a heading at the top level the
+// files start at, files without such a heading get their file name as title
+func (m *Merger) startChapters(sources, contents []string) {
+	m.ChapterLevel = 0
+	for _, content := range contents {
+		if level := FirstHeadingLevel(content); level > 0 && (m.ChapterLevel == 0 || level < m.ChapterLevel) {
+			m.ChapterLevel = level
+		}
+	}
+	if m.ChapterLevel == 0 {
+		m.ChapterLevel = 1
+	}
+
+	for i, content := range contents {
+		if FirstHeadingLevel(content) != m.ChapterLevel {
+			m.Logger.Printf("Starting chapter with file name title: %s", sources[i])
+			contents[i] = AddTitleFromFilename(content, filepath.Base(sources[i]), m.ChapterLevel)
+		}
+	}
+}
+
 // processImagePaths Process image paths in Markdown, converting relative paths to paths relative to the command execution location
-func processImagePaths(content, sourcePath string, logger *log.Logger, verbose bool) (string, error) {
+func processImagePaths(content, sourcePath string, logger *logging.Logger, verbose bool) (string, error) {
 	// If no logger is provided, create a default one
 	if logger == nil {
-		if verbose {
-			logger = log.New(os.Stdout, "[IMAGE] ", log.LstdFlags)
-		} else {
-			logger = log.New(io.Discard, "", 0)
-		}
+		logger = logging.New("IMAGE")
 	}
 
 	// Get source file's directory
@@ -173,21 +285,11 @@ func processImagePaths(content, sourcePath string, logger *log.Logger, verbose b
 		logger.Printf("Source file's directory absolute path = %s", absSourceDir)
 	}
 
-	// Match Markdown image syntax: ![alt](path)
-	imageRegex := regexp.MustCompile(`!\[(.*?)\]\((.*?)\)`)
-
-	// Replace all image paths
-	processedContent := imageRegex.ReplaceAllStringFunc(content, func(match string) string {
-		// Extract image path
-		submatches := imageRegex.FindStringSubmatch(match)
-		if len(submatches) < 3 {
-			return match // If match is incorrect, keep as-is
-		}
-
-		altText := submatches[1]
-		imagePath := submatches[2]
+	// Replace all local image paths, images in code are not part of the document
+	processedContent, _ := mddoc.Parse([]byte(content)).ReplaceImages(func(img mddoc.Image) (string, bool) {
+		imagePath := mddoc.Unescape(img.Destination)
 		if verbose {
-			logger.Printf("Found image: alt = %s, path = %s", altText, imagePath)
+			logger.Printf("Found image: path = %s", imagePath)
 		}
 
 		// If image is a web image (starts with http:// or https://), keep as-is
@@ -195,7 +297,7 @@ func processImagePaths(content, sourcePath string, logger *log.Logger, verbose b
 			if verbose {
 				logger.Printf("Keeping web image path: %s", imagePath)
 			}
-			return match
+			return "", false
 		}
 
 		// Parse image's absolute path
@@ -234,11 +336,11 @@ func processImagePaths(content, sourcePath string, logger *log.Logger, verbose b
 					if verbose {
 						logger.Printf("Image does not exist in alternative path: %s", alternativePath)
 					}
-					return match
+					return "", false
 				}
 			} else {
 				// Image not found, keep as-is
-				return match
+				return "", false
 			}
 		}
 
@@ -248,25 +350,17 @@ func processImagePaths(content, sourcePath string, logger *log.Logger, verbose b
 			if verbose {
 				logger.Printf("Unable to calculate relative path, keeping original path: %s, error: %v", imagePath, err)
 			}
-			return match
+			return "", false
 		}
 
 		// Update image reference with path relative to current working directory
-		newRef := fmt.Sprintf("![%s](%s)", altText, relPath)
 		if verbose {
-			logger.Printf("Updating image reference: %s -> %s", match, newRef)
+			logger.Printf("Updating image reference: %s -> %s", img.Destination, relPath)
 		}
-		return newRef
+		return relPath, true
 	})
 
-	return processedContent, nil
-}
-
-// removeYAMLFrontMatter Remove YAML front matter
-func removeYAMLFrontMatter(content string) string {
-	// Match YAML front matter
-	yamlFrontMatterRegex := regexp.MustCompile(`(?s)^---\s*\n(.*?)\n---\s*\n`)
-	return yamlFrontMatterRegex.ReplaceAllString(content, "")
+	return string(processedContent), nil
 }
 
 // sanitizeContent Clean content, removing content that may cause Pandoc parsing errors
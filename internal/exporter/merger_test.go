@@ -0,0 +1,151 @@
+package exporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeChapterPerFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"01-intro.md":   "## Introduction\n\nHello\n",
+		"02-install.md": "Some text without heading\n\n### Step\n",
+		"03-usage.md":   "---\ntitle: Usage\n---\n\n## Usage\n\n```\n# not a heading\n```\n",
+	}
+	var sources []string
+	for _, name := range []string{"01-intro.md", "02-install.md", "03-usage.md"} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(files[name]), 0644)
+		sources = append(sources, path)
+	}
+
+	target := filepath.Join(dir, "merged.md")
+	merger := &Merger{ChapterPerFile: true}
+	if err := merger.Merge(sources, target); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if merger.ChapterLevel != 2 {
+		t.Errorf("expected chapter level 2, got %d", merger.ChapterLevel)
+	}
+
+	merged, _ := os.ReadFile(target)
+	if !strings.Contains(string(merged), "## 02 Install\n\nSome text without heading") {
+		t.Errorf("expected file name chapter for the file without heading:\n%s", merged)
+	}
+	if strings.Count(string(merged), "\n## ")+1 != 3 || strings.Contains(string(merged), "01 Intro") {
+		t.Errorf("expected one chapter per file:\n%s", merged)
+	}
+}
+
+func TestExportEPUBPlan(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "book.md")
+	os.WriteFile(input, []byte("## One\n\n### Sub\n\n## Two\n"), 0644)
+
+	plan := &ExportPlan{}
+	err := NewExporter().ExportFile(context.Background(), input, filepath.Join(dir, "book.epub"), ExportOptions{
+		Format:     "epub",
+		TocDepth:   2,
+		Title:      "Book",
+		Authors:    []string{"A", "B"},
+		Language:   "en-US",
+		CoverImage: "cover.png",
+		DryRun:     true,
+		Plan:       plan,
+	})
+	if err != nil {
+		t.Fatalf("ExportFile failed: %v", err)
+	}
+
+	command := strings.Join(plan.Command, " ")
+	cover, _ := filepath.Abs("cover.png")
+	for _, want := range []string{
+		"--epub-chapter-level=2",
+		"--toc-depth 2",
+		"--metadata title=Book",
+		"--metadata author=A --metadata author=B",
+		"--metadata lang=en-US",
+		"--epub-cover-image " + cover,
+	} {
+		if !strings.Contains(command, want) {
+			t.Errorf("expected %q in command: %s", want, command)
+		}
+	}
+}
+
+func TestExportPassthroughPlan(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "doc.md")
+	os.WriteFile(input, []byte("# Doc\n"), 0644)
+
+	plan := &ExportPlan{}
+	err := NewExporter().ExportFile(context.Background(), input, filepath.Join(dir, "doc.docx"), ExportOptions{
+		Format:     "docx",
+		LuaFilters: []string{"acronyms.lua"},
+		PandocArgs: []string{"--number-sections", "-M", "draft=true"},
+		DryRun:     true,
+		Plan:       plan,
+	})
+	if err != nil {
+		t.Fatalf("ExportFile failed: %v", err)
+	}
+
+	filter, _ := filepath.Abs("acronyms.lua")
+	command := strings.Join(plan.Command, " ")
+	if !strings.HasSuffix(command, "--lua-filter "+filter+" --number-sections -M draft=true") {
+		t.Errorf("expected filters and extra arguments at the end: %s", command)
+	}
+}
+
+func TestMergeDedupe(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"intro.md":   "# Introduction\n\nHello\n",
+		"shared.md":  "## Shared Setup\n\nSteps\n",
+		"snippet.md": "Just text\n",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	path := func(name string) string { return filepath.Join(dir, name) }
+	sources := []string{path("intro.md"), path("shared.md"), path("snippet.md"), path("shared.md"), path("snippet.md"), path("shared.md")}
+
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{"", "# Introduction\n\nHello\n\n\n## []{#mdctl-file-2}Shared Setup\n\nSteps\n\n\n[]{#mdctl-file-3}\n\nJust text\n\n\n" +
+			"See [Shared Setup](#mdctl-file-2).\n\nSee [snippet](#mdctl-file-3).\n\nSee [Shared Setup](#mdctl-file-2)."},
+		{DedupeSkip, "# Introduction\n\nHello\n\n\n## Shared Setup\n\nSteps\n\n\nJust text\n"},
+		{DedupeDuplicate, ""},
+	}
+	for _, tt := range tests {
+		target := filepath.Join(dir, "merged.md")
+		if err := (&Merger{Dedupe: tt.mode}).Merge(sources, target); err != nil {
+			t.Fatalf("Merge failed: %v", err)
+		}
+		merged, _ := os.ReadFile(target)
+		if tt.mode == DedupeDuplicate {
+			if strings.Count(string(merged), "## Shared Setup") != 3 || strings.Contains(string(merged), "mdctl-file") {
+				t.Errorf("duplicate mode changed the repeats:\n%s", merged)
+			}
+			continue
+		}
+		if string(merged) != tt.want {
+			t.Errorf("mode %q:\n%q\nwant:\n%q", tt.mode, merged, tt.want)
+		}
+	}
+}
+
+func TestNumberHeadings(t *testing.T) {
+	content := "## Intro\n\n### Setup\n\n```\n## not a heading\n```\n\n### Usage\n\n" +
+		"Options\n-------\n\n#### Flags {#flags}\n\n## Appendix {-}\n\n## License\n"
+	want := "## []{#sec-1}1. Intro\n\n### []{#sec-1-1}1.1 Setup\n\n```\n## not a heading\n```\n\n### []{#sec-1-2}1.2 Usage\n\n" +
+		"## []{#sec-2}2. Options\n\n#### []{#sec-2-0-1}2.0.1 Flags {#flags}\n\n## Appendix {-}\n\n## []{#sec-3}3. License\n"
+	if got := NumberHeadings(content); got != want {
+		t.Errorf("NumberHeadings:\n%q\nwant:\n%q", got, want)
+	}
+}
@@ -0,0 +1,154 @@
+package exporter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/mddoc"
+	"gopkg.in/yaml.v3"
+)
+
+// OrderFile lists the files and subdirectories of a directory to export
+// first, a YAML list of their names
+const OrderFile = "_order.yaml"
+
+// orderedEntry is a markdown file or a directory containing markdown files
+type orderedEntry struct {
+	name   string
+	key    string // Sort key, directories end with a slash to sort like their paths
+	weight *float64
+	files  []string
+}
+
+// orderedMarkdownFiles returns the markdown files below dir in export order:
+// the entries the OrderFile of a directory lists come first, the others
+// follow by the weight or order of their front matter (for directories that
+// of their index page) and then by name
+func orderedMarkdownFiles(dir string) ([]string, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory %s: %s", dir, err)
+	}
+
+	var entries []orderedEntry
+	for _, d := range dirEntries {
+		name := d.Name()
+		path := filepath.Join(dir, name)
+		if d.IsDir() {
+			files, err := orderedMarkdownFiles(path)
+			if err != nil {
+				return nil, err
+			}
+			if len(files) == 0 {
+				continue
+			}
+			entries = append(entries, orderedEntry{name: name, key: name + "/", weight: dirWeight(path), files: files})
+			continue
+		}
+		if !mddoc.IsMarkdown(name) {
+			continue
+		}
+		weight, err := fileWeight(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, orderedEntry{name: name, key: name, weight: weight, files: []string{path}})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		wi, wj := entries[i].weight, entries[j].weight
+		if (wi == nil) != (wj == nil) {
+			return wi != nil
+		}
+		if wi != nil && *wi != *wj {
+			return *wi < *wj
+		}
+		return entries[i].key < entries[j].key
+	})
+
+	order, err := readOrderFile(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	listed := make(map[string]bool)
+	for _, item := range order {
+		for _, entry := range entries {
+			if listed[entry.name] || !orderItemMatches(item, entry.name) {
+				continue
+			}
+			listed[entry.name] = true
+			files = append(files, entry.files...)
+			break
+		}
+	}
+	for _, entry := range entries {
+		if !listed[entry.name] {
+			files = append(files, entry.files...)
+		}
+	}
+	return files, nil
+}
+
+// readOrderFile returns the entry names of the OrderFile of a directory, nil
+// without one
+func readOrderFile(dir string) ([]string, error) {
+	path := filepath.Join(dir, OrderFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", path, err)
+	}
+	var order []string
+	if err := yaml.Unmarshal(data, &order); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+	return order, nil
+}
+
+// orderItemMatches reports whether an OrderFile item names an entry, the
+// extension of files may be left out
+func orderItemMatches(item, name string) bool {
+	item = strings.TrimSuffix(strings.TrimSpace(item), "/")
+	return item == name || item == strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// fileWeight returns the weight or order of the front matter of a file, nil
+// when it sets neither
+func fileWeight(path string) (*float64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %s", path, err)
+	}
+	frontMatter, _ := mddoc.SplitFrontMatter(string(content))
+	if frontMatter == "" {
+		return nil, nil
+	}
+	var meta struct {
+		Weight *float64 `yaml:"weight"`
+		Order  *float64 `yaml:"order"`
+	}
+	// Front matter that does not parse leaves the file unweighted
+	if yaml.Unmarshal([]byte(frontMatter), &meta) != nil {
+		return nil, nil
+	}
+	if meta.Weight != nil {
+		return meta.Weight, nil
+	}
+	return meta.Order, nil
+}
+
+// dirWeight returns the weight of the index page of a directory
+func dirWeight(dir string) *float64 {
+	for _, name := range []string{"_index.md", "index.md", "README.md"} {
+		if weight, err := fileWeight(filepath.Join(dir, name)); err == nil && weight != nil {
+			return weight
+		}
+	}
+	return nil
+}
@@ -1,31 +1,30 @@
 package exporter
 
 import (
+	"context"
 	"fmt"
-	"io"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strings"
+
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/mddoc"
 )
 
 // PandocExporter Use Pandoc to export Markdown files
 type PandocExporter struct {
 	PandocPath string
-	Logger     *log.Logger
+	Logger     *logging.Logger
 }
 
 // Export Use Pandoc to export Markdown files
-func (e *PandocExporter) Export(input, output string, options ExportOptions) error {
+func (e *PandocExporter) Export(ctx context.Context, input, output string, options ExportOptions) error {
 	// If no logger is provided, create a default one
 	if e.Logger == nil {
-		if options.Verbose {
-			e.Logger = log.New(os.Stdout, "[PANDOC] ", log.LstdFlags)
-		} else {
-			e.Logger = log.New(io.Discard, "", 0)
-		}
+		e.Logger = logging.New("PANDOC")
 	}
 
 	e.Logger.Printf("Starting Pandoc export: %s -> %s", input, output)
@@ -38,21 +37,51 @@ func (e *PandocExporter) Export(input, output string, options ExportOptions) err
 	}
 	e.Logger.Printf("Using absolute output path: %s", absOutput)
 
-	// Create a temporary file for sanitized content
-	e.Logger.Println("Creating sanitized copy of input file...")
-	tempFile, err := createSanitizedCopy(input, e.Logger)
+	from, err := inputFormat(options)
 	if err != nil {
-		e.Logger.Printf("Failed to create sanitized copy: %s", err)
-		return fmt.Errorf("failed to create sanitized copy: %s", err)
+		return err
+	}
+	e.Logger.Printf("Reading input as: %s", from)
+
+	// A dry run only builds the command, so it refers to the real paths
+	tempFile, partialOutput := input, absOutput
+	if !options.DryRun {
+		// Create a temporary file for sanitized content
+		e.Logger.Println("Creating sanitized copy of input file...")
+		tempFile, err = createSanitizedCopy(input, e.Logger)
+		if err != nil {
+			e.Logger.Printf("Failed to create sanitized copy: %s", err)
+			return fmt.Errorf("failed to create sanitized copy: %s", err)
+		}
+		defer os.Remove(tempFile)
+		e.Logger.Printf("Sanitized copy created: %s", tempFile)
+
+		// xelatex has no glyphs for emoji in the main fonts
+		if options.Format == "pdf" && options.Emoji != "" {
+			e.Logger.Printf("Handling emoji with mode: %s", options.Emoji)
+			if err := applyEmojiMode(tempFile, options.Emoji, e.Logger); err != nil {
+				return fmt.Errorf("failed to handle emoji: %s", err)
+			}
+		}
+
+		// Pandoc writes to a partial file that replaces the output only on success,
+		// so an interrupted export never leaves a truncated document behind
+		ext := filepath.Ext(absOutput)
+		partial, err := os.CreateTemp(filepath.Dir(fsutil.LongPath(absOutput)), "."+strings.TrimSuffix(filepath.Base(absOutput), ext)+".*"+ext)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %s", err)
+		}
+		partialOutput = partial.Name()
+		partial.Close()
+		defer os.Remove(partialOutput)
 	}
-	defer os.Remove(tempFile)
-	e.Logger.Printf("Sanitized copy created: %s", tempFile)
 
 	// Build Pandoc command arguments
 	e.Logger.Println("Building Pandoc command arguments...")
 	args := []string{
 		tempFile,
-		"-o", absOutput,
+		"--from", from,
+		"-o", partialOutput,
 		"--standalone",
 		"--pdf-engine=xelatex",
 		"-V", "mainfont=SimSun", // Use SimSun as the main font
@@ -89,17 +118,41 @@ func (e *PandocExporter) Export(input, output string, options ExportOptions) err
 		}
 	}
 
-	// Add all resource paths to Pandoc arguments
+	// Add all resource paths to Pandoc arguments, sorted for a stable command line
+	paths := make([]string, 0, len(resourcePaths))
 	for path := range resourcePaths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
 		args = append(args, "--resource-path", path)
 	}
 
+	// Images without resolution information are sized at this DPI
+	if options.ImageDPI > 0 {
+		args = append(args, fmt.Sprintf("--dpi=%d", options.ImageDPI))
+	}
+
 	// Add template parameter
 	if options.Template != "" {
 		e.Logger.Printf("Using template: %s", options.Template)
 		args = append(args, "--reference-doc", options.Template)
 	}
 
+	// Add document metadata
+	if options.Title != "" {
+		args = append(args, "--metadata", "title="+options.Title)
+	}
+	for _, author := range options.Authors {
+		args = append(args, "--metadata", "author="+author)
+	}
+	if options.Language != "" {
+		args = append(args, "--metadata", "lang="+options.Language)
+	}
+	if options.Identifier != "" {
+		args = append(args, "--metadata", "identifier="+options.Identifier)
+	}
+
 	// Add directory parameter
 	if options.GenerateToc {
 		e.Logger.Println("Generating table of contents")
@@ -131,17 +184,87 @@ func (e *PandocExporter) Export(input, output string, options ExportOptions) err
 	case "epub":
 		// EPUB format specific parameters
 		e.Logger.Println("Adding EPUB-specific parameters")
-		args = append(args, "--epub-chapter-level=1")
+		chapterLevel := options.ChapterLevel
+		if chapterLevel <= 0 {
+			chapterLevel = 1
+		}
+		args = append(args, fmt.Sprintf("--epub-chapter-level=%d", chapterLevel))
+
+		// The navigation document always exists, its depth follows --toc-depth
+		if !options.GenerateToc && options.TocDepth > 0 {
+			args = append(args, "--toc-depth", fmt.Sprintf("%d", options.TocDepth))
+		}
+		// Pandoc runs in the input directory, so files given relative to the
+		// working directory are passed as absolute paths
+		if options.CoverImage != "" {
+			args = append(args, "--epub-cover-image", absPath(options.CoverImage))
+		}
+		for _, font := range options.EmbedFonts {
+			args = append(args, "--epub-embed-font", absPath(font))
+		}
+	}
+
+	// Themes follow the format defaults they override
+	if options.Theme != "" {
+		e.Logger.Printf("Using theme: %s", options.Theme)
+		themeArgs, err := DefaultTemplateStore().PandocArgs(options.Theme, options.Format, options.DryRun)
+		if err != nil {
+			return err
+		}
+		args = append(args, themeArgs...)
+	}
+
+	// Page decorations of PDF output are set in the LaTeX preamble
+	if options.Format == "pdf" && hasPageDecorations(options) {
+		e.Logger.Println("Adding header, footer, page number and watermark settings")
+		args = append(args, latexDecorationArgs(options)...)
+	}
+
+	// The build stamp goes into the document properties
+	if options.Stamp != nil {
+		e.Logger.Printf("Stamping the output: %s", options.Stamp)
+		args = append(args, stampArgs(options)...)
+	}
+
+	// The emoji font is declared in the LaTeX preamble
+	if options.Format == "pdf" {
+		args = append(args, emojiArgs(options)...)
+	}
+
+	// Heading bookmarks follow the table of contents depth of the document
+	// class the theme and extra arguments select
+	if options.Format == "pdf" {
+		args = append(args, pdfBookmarkArgs(append(args, options.PandocArgs...), options)...)
+	}
+
+	// Lua filters and raw arguments come last, so they can override the defaults
+	for _, filter := range options.LuaFilters {
+		args = append(args, "--lua-filter", absPath(filter))
+	}
+	if len(options.PandocArgs) > 0 {
+		e.Logger.Printf("Adding extra Pandoc arguments: %s", strings.Join(options.PandocArgs, " "))
+		args = append(args, options.PandocArgs...)
+	}
+
+	if options.DryRun {
+		if options.Plan != nil {
+			options.Plan.Command = append([]string{e.PandocPath}, args...)
+		}
+		return nil
 	}
 
 	// Execute Pandoc command
 	e.Logger.Printf("Executing Pandoc command: %s %s", e.PandocPath, strings.Join(args, " "))
-	cmd := exec.Command(e.PandocPath, args...)
+	cmd := exec.CommandContext(ctx, e.PandocPath, args...)
 
 	// Set working directory to input file directory, which helps Pandoc find relative paths for images
 	cmd.Dir = inputDir
 
 	outputBytes, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		e.Logger.Println("Pandoc export interrupted, output left untouched")
+		return ctx.Err()
+	}
 	if err != nil {
 		// If execution fails, try to look at input file content for debugging
 		e.Logger.Printf("Pandoc execution failed: %s", err)
@@ -163,19 +286,47 @@ func (e *PandocExporter) Export(input, output string, options ExportOptions) err
 			err, string(outputBytes), strings.Join(cmd.Args, " "))
 	}
 
+	// DOCX headers and footers are added to the document Pandoc wrote
+	if options.Format == "docx" && hasPageDecorations(options) {
+		e.Logger.Println("Adding header, footer, page numbers and watermark to DOCX output")
+		if err := decorateDocx(partialOutput, options); err != nil {
+			return fmt.Errorf("failed to add page decorations: %s", err)
+		}
+	}
+
+	// A PDF whose bookmarks got lost is not written
+	if options.Format == "pdf" && !options.SkipPDFCheck {
+		e.Logger.Println("Verifying PDF bookmarks against the table of contents")
+		if err := verifyPDFBookmarks(partialOutput, tempFile, args, options); err != nil {
+			return fmt.Errorf("PDF verification of %s failed: %s", output, err)
+		}
+	}
+
+	if err := os.Rename(partialOutput, fsutil.LongPath(absOutput)); err != nil {
+		return fmt.Errorf("failed to write output file: %s", err)
+	}
+
 	e.Logger.Printf("Pandoc export completed successfully: %s", output)
 	return nil
 }
 
+// absPath returns the absolute form of path, or path itself if it cannot be resolved
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
 // createSanitizedCopy Create a sanitized temporary file copy
-func createSanitizedCopy(inputFile string, logger *log.Logger) (string, error) {
+func createSanitizedCopy(inputFile string, logger *logging.Logger) (string, error) {
 	if logger == nil {
-		logger = log.New(io.Discard, "", 0)
+		logger = logging.New("PANDOC")
 	}
 
 	// Read input file content
 	logger.Printf("Reading input file: %s", inputFile)
-	content, err := os.ReadFile(inputFile)
+	content, err := os.ReadFile(fsutil.LongPath(inputFile))
 	if err != nil {
 		return "", fmt.Errorf("failed to read input file: %s", err)
 	}
@@ -185,10 +336,9 @@ func createSanitizedCopy(inputFile string, logger *log.Logger) (string, error) {
 
 	// Remove YAML front matter
 	logger.Println("Removing YAML front matter...")
-	yamlFrontMatterRegex := regexp.MustCompile(`(?s)^---\s*\n(.*?)\n---\s*\n`)
-	if yamlFrontMatterRegex.MatchString(contentStr) {
+	if _, body := mddoc.SplitFrontMatter(contentStr); body != contentStr {
 		logger.Println("YAML front matter found, removing it")
-		contentStr = yamlFrontMatterRegex.ReplaceAllString(contentStr, "")
+		contentStr = body
 	}
 
 	// Fix lines that may cause YAML parsing errors
@@ -220,57 +370,29 @@ func createSanitizedCopy(inputFile string, logger *log.Logger) (string, error) {
 
 	logger.Printf("Fixed %d lines with potential YAML issues", fixedLines)
 
-	// Create a temporary file
-	tempDir := os.TempDir()
-	tempFilePath := filepath.Join(tempDir, "mdctl-sanitized-"+filepath.Base(inputFile))
+	// Create a temporary file with a unique name, so concurrent exports of
+	// files with the same name do not overwrite each other, and keep the
+	// extension Pandoc detects the input format from
+	tempFile, err := os.CreateTemp("", "mdctl-sanitized-*"+filepath.Ext(inputFile))
+	if err != nil {
+		return "", err
+	}
+	tempFilePath := tempFile.Name()
 
 	// Write sanitized content to temporary file
 	logger.Printf("Writing sanitized content to temporary file: %s", tempFilePath)
-	err = os.WriteFile(tempFilePath, []byte(strings.Join(cleanedLines, "\n")), 0644)
+	_, err = tempFile.WriteString(strings.Join(cleanedLines, "\n"))
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
+		os.Remove(tempFilePath)
 		return "", err
 	}
 
 	return tempFilePath, nil
 }
 
-// preprocessInputFile Preprocess input file, removing content that may cause Pandoc parsing errors
-func preprocessInputFile(filePath string) error {
-	// Read file content
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
-
-	contentStr := string(content)
-
-	// Check for unconventional YAML front matter
-	yamlFrontMatterRegex := regexp.MustCompile(`(?s)^---\s*\n(.*?)\n---\s*\n`)
-	if yamlFrontMatterRegex.MatchString(contentStr) {
-		// Extract YAML front matter content
-		matches := yamlFrontMatterRegex.FindStringSubmatch(contentStr)
-		if len(matches) > 1 {
-			yamlContent := matches[1]
-
-			// Check if YAML content has formatting issues
-			if strings.Contains(yamlContent, "\n-") && !strings.Contains(yamlContent, "\n- ") {
-				// Fix formatting issue: ensure there's a space after the dash
-				fixedYaml := strings.ReplaceAll(yamlContent, "\n-", "\n- ")
-				fixedContent := strings.Replace(contentStr, yamlContent, fixedYaml, 1)
-
-				// Write back to file
-				return os.WriteFile(filePath, []byte(fixedContent), 0644)
-			}
-		}
-
-		// If YAML format has other issues, remove entire front matter
-		processedContent := yamlFrontMatterRegex.ReplaceAllString(contentStr, "")
-		return os.WriteFile(filePath, []byte(processedContent), 0644)
-	}
-
-	return nil
-}
-
 // CheckPandocAvailability Check if Pandoc is available
 func CheckPandocAvailability() error {
 	cmd := exec.Command("pandoc", "--version")
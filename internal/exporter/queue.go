@@ -0,0 +1,65 @@
+package exporter
+
+import (
+	"context"
+	"sync"
+)
+
+// Queue runs export jobs on a bounded number of workers. Every export works
+// on temporary files of its own, so concurrent jobs, and concurrent mdctl
+// processes, never overwrite each other's files.
+type Queue struct {
+	Workers int // Number of jobs running at the same time, 1 when not positive
+}
+
+// Run runs the jobs 0 to n-1 in order and waits for them. The first failure
+// cancels the context of the running jobs and keeps the remaining ones from
+// starting. It reports which jobs completed and returns the first error.
+func (q Queue) Run(ctx context.Context, n int, job func(ctx context.Context, i int) error) ([]bool, error) {
+	workers := q.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make([]bool, n)
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	work := make(chan int)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				err := ctx.Err()
+				if err == nil {
+					err = job(ctx, i)
+				}
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+				} else {
+					done[i] = true
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	return done, firstErr
+}
@@ -0,0 +1,93 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueueRun(t *testing.T) {
+	var running, peak int32
+	var mu sync.Mutex
+	var order []int
+	done, err := Queue{Workers: 3}.Run(context.Background(), 10, func(ctx context.Context, i int) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		mu.Lock()
+		order = append(order, i)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if peak > 3 {
+		t.Errorf("expected at most 3 concurrent jobs, got %d", peak)
+	}
+	for i, ok := range done {
+		if !ok {
+			t.Errorf("job %d did not complete", i)
+		}
+	}
+	if len(order) != 10 {
+		t.Errorf("expected 10 jobs to run, got %d", len(order))
+	}
+}
+
+func TestQueueRunStopsAtFirstFailure(t *testing.T) {
+	failure := errors.New("pandoc failed")
+	done, err := Queue{}.Run(context.Background(), 5, func(ctx context.Context, i int) error {
+		if i == 2 {
+			return failure
+		}
+		return nil
+	})
+	if err != failure {
+		t.Fatalf("expected the failure, got %v", err)
+	}
+	want := []bool{true, true, false, false, false}
+	for i := range want {
+		if done[i] != want[i] {
+			t.Errorf("job %d: done = %v, want %v", i, done[i], want[i])
+		}
+	}
+}
+
+func TestCreateSanitizedCopyUniqueNames(t *testing.T) {
+	dir := t.TempDir()
+	var copies []string
+	for i, sub := range []string{"a", "b"} {
+		os.MkdirAll(filepath.Join(dir, sub), 0755)
+		input := filepath.Join(dir, sub, "index.md")
+		os.WriteFile(input, []byte("# Page "+sub+"\n"), 0644)
+
+		sanitized, err := createSanitizedCopy(input, nil)
+		if err != nil {
+			t.Fatalf("createSanitizedCopy failed: %v", err)
+		}
+		defer os.Remove(sanitized)
+		if filepath.Ext(sanitized) != ".md" {
+			t.Errorf("copy %s lost the extension", sanitized)
+		}
+		copies = append(copies, sanitized)
+		if i == 1 && copies[0] == copies[1] {
+			t.Fatalf("files with the same name share the copy %s", sanitized)
+		}
+	}
+	data, _ := os.ReadFile(copies[0])
+	if string(data) != "# Page a\n" {
+		t.Errorf("first copy was overwritten: %q", data)
+	}
+}
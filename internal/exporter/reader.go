@@ -0,0 +1,109 @@
+package exporter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultInputFormat is the Pandoc reader of the markdown sources
+const DefaultInputFormat = "gfm"
+
+// InputFormats are the Pandoc markdown readers an export accepts
+var InputFormats = []string{"gfm", "commonmark", "commonmark_x", "markdown", "markdown_strict", "markdown_phpextra", "markdown_mmd"}
+
+// requiredExtensions are switched on for the gfm and commonmark readers
+// unless the input format toggles them: footnotes, heading attributes such as
+// {.unlisted}, and the anchor spans and raw LaTeX mdctl adds while merging.
+// Pandoc's markdown and commonmark_x have them already.
+var requiredExtensions = []string{"footnotes", "attributes", "bracketed_spans", "raw_attribute"}
+
+// extensionRegex matches an extension toggle of a reader, e.g. +footnotes
+var extensionRegex = regexp.MustCompile(`^[+-][a-z0-9_]+$`)
+
+// ParseInputFormat splits a Pandoc reader such as gfm+footnotes-smart into
+// the format and its extension toggles
+func ParseInputFormat(spec string) (string, []string, error) {
+	end := strings.IndexAny(spec, "+-")
+	if end < 0 {
+		end = len(spec)
+	}
+	format := spec[:end]
+	if !validInputFormat(format) {
+		return "", nil, fmt.Errorf("unsupported input format: %s (must be one of %s)", format, strings.Join(InputFormats, ", "))
+	}
+	var toggles []string
+	for rest := spec[end:]; rest != ""; {
+		next := strings.IndexAny(rest[1:], "+-") + 1
+		if next == 0 {
+			next = len(rest)
+		}
+		toggle, err := extensionToggle(rest[:next])
+		if err != nil {
+			return "", nil, err
+		}
+		toggles = append(toggles, toggle)
+		rest = rest[next:]
+	}
+	return format, toggles, nil
+}
+
+// validInputFormat reports whether format is one of InputFormats
+func validInputFormat(format string) bool {
+	for _, f := range InputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// extensionToggle returns a toggle as +name or -name, a bare name switches
+// the extension on
+func extensionToggle(toggle string) (string, error) {
+	if toggle != "" && toggle[0] != '+' && toggle[0] != '-' {
+		toggle = "+" + toggle
+	}
+	if !extensionRegex.MatchString(toggle) {
+		return "", fmt.Errorf("invalid Pandoc extension toggle: %q (must be +name or -name)", toggle)
+	}
+	return toggle, nil
+}
+
+// inputFormat returns the Pandoc reader of an export: the input format,
+// DefaultInputFormat when empty, with the required extensions it does not
+// toggle itself and the extension toggles of the options, which come last
+// and win
+func inputFormat(options ExportOptions) (string, error) {
+	spec := options.InputFormat
+	if spec == "" {
+		spec = DefaultInputFormat
+	}
+	format, toggles, err := ParseInputFormat(spec)
+	if err != nil {
+		return "", err
+	}
+	for _, ext := range options.Extensions {
+		toggle, err := extensionToggle(ext)
+		if err != nil {
+			return "", err
+		}
+		toggles = append(toggles, toggle)
+	}
+
+	toggled := make(map[string]bool)
+	for _, toggle := range toggles {
+		toggled[toggle[1:]] = true
+	}
+	var b strings.Builder
+	b.WriteString(format)
+	for _, ext := range requiredExtensions {
+		if (format == "gfm" || format == "commonmark") && !toggled[ext] {
+			b.WriteString("+" + ext)
+		}
+	}
+	for _, toggle := range toggles {
+		b.WriteString(toggle)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,70 @@
+package exporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInputFormat(t *testing.T) {
+	tests := []struct {
+		options ExportOptions
+		want    string
+	}{
+		{ExportOptions{}, "gfm+footnotes+attributes+bracketed_spans+raw_attribute"},
+		{ExportOptions{InputFormat: "gfm-footnotes+smart"}, "gfm+attributes+bracketed_spans+raw_attribute-footnotes+smart"},
+		{ExportOptions{InputFormat: "commonmark", Extensions: []string{"task_lists", "-raw_attribute"}}, "commonmark+footnotes+attributes+bracketed_spans+task_lists-raw_attribute"},
+		{ExportOptions{InputFormat: "markdown-smart"}, "markdown-smart"},
+		{ExportOptions{InputFormat: "commonmark_x", Extensions: []string{"+east_asian_line_breaks"}}, "commonmark_x+east_asian_line_breaks"},
+	}
+	for _, tt := range tests {
+		got, err := inputFormat(tt.options)
+		if err != nil || got != tt.want {
+			t.Errorf("inputFormat(%q, %q) = %q, %v, want %q", tt.options.InputFormat, tt.options.Extensions, got, err, tt.want)
+		}
+	}
+
+	for _, options := range []ExportOptions{
+		{InputFormat: "html"},
+		{InputFormat: "+footnotes"},
+		{InputFormat: "gfm+"},
+		{InputFormat: "gfm+Foot notes"},
+		{Extensions: []string{"-"}},
+	} {
+		if got, err := inputFormat(options); err == nil {
+			t.Errorf("expected an error for %q, %q, got %q", options.InputFormat, options.Extensions, got)
+		}
+	}
+}
+
+func TestExportInputFormatPlan(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "doc.md")
+	os.WriteFile(input, []byte("# Doc\n\n- [x] done\n\nText[^1]\n\n[^1]: Note\n"), 0644)
+
+	plan := &ExportPlan{}
+	err := NewExporter().ExportFile(context.Background(), input, filepath.Join(dir, "doc.docx"), ExportOptions{
+		Format:      "docx",
+		InputFormat: "gfm+smart",
+		DryRun:      true,
+		Plan:        plan,
+	})
+	if err != nil {
+		t.Fatalf("ExportFile failed: %v", err)
+	}
+	if command := strings.Join(plan.Command, " "); !strings.Contains(command, " --from gfm+footnotes+attributes+bracketed_spans+raw_attribute+smart ") {
+		t.Errorf("expected the pinned reader in command: %s", command)
+	}
+
+	err = NewExporter().ExportFile(context.Background(), input, filepath.Join(dir, "doc.docx"), ExportOptions{
+		Format:      "docx",
+		InputFormat: "rst",
+		DryRun:      true,
+		Plan:        &ExportPlan{},
+	})
+	if err == nil || !strings.Contains(err.Error(), "unsupported input format: rst") {
+		t.Errorf("expected an unsupported input format error, got %v", err)
+	}
+}
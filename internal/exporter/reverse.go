@@ -0,0 +1,148 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/processor"
+)
+
+// importFormats are the document formats Pandoc converts back to markdown
+var importFormats = map[string]string{
+	".docx": "docx",
+	".odt":  "odt",
+}
+
+// ImportOptions defines how a document is converted to markdown
+type ImportOptions struct {
+	ImageDir string          // Directory for extracted media, default images/ next to the output
+	DryRun   bool            // Only build the Pandoc command, do not run it
+	Logger   *logging.Logger // Logger
+}
+
+// ImportResult describes a document converted to markdown
+type ImportResult struct {
+	Input   string   `json:"input"`
+	Output  string   `json:"output"`
+	Images  []string `json:"images"`
+	Command []string `json:"command"`
+}
+
+// ImportDocument converts a DOCX or ODT document to GitHub-flavored markdown
+// with Pandoc. Embedded media is extracted into the same image directory
+// mdctl download uses and linked relative to the output, document metadata
+// such as title and author becomes front matter.
+func ImportDocument(ctx context.Context, input, output string, opts ImportOptions) (*ImportResult, error) {
+	if opts.Logger == nil {
+		opts.Logger = logging.New("IMPORT")
+	}
+
+	format, ok := importFormats[strings.ToLower(filepath.Ext(input))]
+	if !ok {
+		return nil, fmt.Errorf("unsupported document format: %s (must be .docx or .odt)", filepath.Ext(input))
+	}
+	absOutput, err := filepath.Abs(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for output: %v", err)
+	}
+	imgDir := processor.New("", "", opts.ImageDir).ImageDir(absOutput)
+
+	result := &ImportResult{Input: input, Output: output}
+	args := []string{
+		input,
+		"-f", format,
+		"-t", "gfm+yaml_metadata_block",
+		"--standalone",
+		"--wrap=none",
+	}
+
+	if opts.DryRun {
+		result.Command = append([]string{"pandoc"}, append(args, "--extract-media", imgDir, "-o", output)...)
+		return result, nil
+	}
+
+	// Media is extracted to a scratch directory first, Pandoc nests it in
+	// media/ and names it image1.png, image2.png... for every document
+	if err := os.MkdirAll(filepath.Dir(absOutput), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %v", err)
+	}
+	mediaDir, err := os.MkdirTemp(filepath.Dir(absOutput), ".mdctl-media-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create media directory: %v", err)
+	}
+	defer os.RemoveAll(mediaDir)
+	args = append(args, "--extract-media", mediaDir)
+	result.Command = append([]string{"pandoc"}, args...)
+
+	opts.Logger.Printf("Executing Pandoc command: pandoc %s", strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, "pandoc", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	markdown, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("pandoc conversion failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	prefix := fsutil.Slugify(strings.TrimSuffix(filepath.Base(absOutput), filepath.Ext(absOutput)))
+	content, images, err := relocateMedia(string(markdown), mediaDir, imgDir, filepath.Dir(absOutput), prefix)
+	if err != nil {
+		return nil, err
+	}
+	result.Images = images
+
+	if err := fsutil.WriteFileAtomic(absOutput, []byte(content), 0644); err != nil {
+		return nil, err
+	}
+	opts.Logger.Printf("Imported %s to %s with %d images", input, output, len(images))
+	return result, nil
+}
+
+// relocateMedia moves the media Pandoc extracted into imgDir, prefixing the
+// names with the document name, and rewrites the references in the markdown
+// relative to outputDir
+func relocateMedia(content, mediaDir, imgDir, outputDir, prefix string) (string, []string, error) {
+	var extracted []string
+	err := filepath.Walk(mediaDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			extracted = append(extracted, path)
+		}
+		return err
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list extracted media: %v", err)
+	}
+	if len(extracted) == 0 {
+		return content, nil, nil
+	}
+	if err := os.MkdirAll(imgDir, 0755); err != nil {
+		return "", nil, fmt.Errorf("failed to create image directory %s: %v", imgDir, err)
+	}
+
+	// Longer paths first so media/image10.png is not rewritten as media/image1.png
+	sort.Slice(extracted, func(i, j int) bool { return len(extracted[i]) > len(extracted[j]) })
+
+	var images []string
+	for _, path := range extracted {
+		dst := filepath.Join(imgDir, prefix+"-"+filepath.Base(path))
+		if err := os.Rename(path, dst); err != nil {
+			return "", nil, fmt.Errorf("failed to move %s: %v", path, err)
+		}
+		rel, err := filepath.Rel(outputDir, dst)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to relativize %s: %v", dst, err)
+		}
+		content = strings.ReplaceAll(content, filepath.ToSlash(path), filepath.ToSlash(rel))
+		content = strings.ReplaceAll(content, path, filepath.ToSlash(rel))
+		images = append(images, dst)
+	}
+	sort.Strings(images)
+	return content, images, nil
+}
@@ -0,0 +1,34 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRelocateMedia(t *testing.T) {
+	dir := t.TempDir()
+	mediaDir := filepath.Join(dir, ".media")
+	os.MkdirAll(filepath.Join(mediaDir, "media"), 0755)
+	for _, name := range []string{"image1.png", "image10.png"} {
+		os.WriteFile(filepath.Join(mediaDir, "media", name), []byte(name), 0644)
+	}
+
+	content := "![](" + filepath.ToSlash(mediaDir) + "/media/image1.png)\n" +
+		"<img src=\"" + filepath.ToSlash(mediaDir) + "/media/image10.png\" style=\"width:2in\" />\n"
+	got, images, err := relocateMedia(content, mediaDir, filepath.Join(dir, "images"), dir, "report")
+	if err != nil {
+		t.Fatalf("relocateMedia failed: %v", err)
+	}
+
+	want := "![](images/report-image1.png)\n<img src=\"images/report-image10.png\" style=\"width:2in\" />\n"
+	if got != want {
+		t.Errorf("unexpected content:\n%s\nwant:\n%s", got, want)
+	}
+	if len(images) != 2 {
+		t.Fatalf("expected 2 images, got %v", images)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "images", "report-image10.png")); err != nil || string(data) != "image10.png" {
+		t.Errorf("image not moved: %v", err)
+	}
+}
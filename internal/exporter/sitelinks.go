@@ -0,0 +1,191 @@
+package exporter
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/exporter/sitereader"
+	"github.com/samzong/mdctl/internal/mddoc"
+)
+
+// URL styles of the site generators
+const (
+	linkStyleDirectory  = "dir"        // guide/install.md is published at guide/install/
+	linkStyleHTML       = "html"       // guide/install.md is published at guide/install.html
+	linkStyleDocusaurus = "docusaurus" // docs/guide/install.md is published at docs/guide/install
+)
+
+var (
+	// numberPrefixRegex matches the number prefixes Docusaurus strips from file names
+	numberPrefixRegex = regexp.MustCompile(`^\d+\s*[-_.]\s*`)
+)
+
+// SiteLinks converts relative links between the pages of a site into
+// absolute links to the published site, so they keep working in exported
+// documents
+type SiteLinks struct {
+	base   *url.URL
+	root   string // Directory the URLs of the pages are relative to
+	prefix string // Path of root below the base URL
+	style  string
+	lower  bool // Paths are lowercased, like Hugo does by default
+}
+
+// NewSiteLinks returns the links of a site of the given type in dir published
+// at baseURL: MkDocs pages of the docs directory at directory URLs (or .html
+// files without use_directory_urls), Hugo pages of the content directory at
+// lowercased pretty URLs, Docusaurus docs below docs/ without extension and
+// other sites at directory URLs
+func NewSiteLinks(baseURL, siteType, dir string) (*SiteLinks, error) {
+	base, err := ParseLinkBaseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	l := &SiteLinks{base: base, root: root, style: linkStyleDirectory}
+
+	switch siteType {
+	case "mkdocs":
+		docsDir, directoryURLs, err := (&sitereader.MkDocsReader{}).ReadURLSettings(dir, "")
+		if err != nil {
+			return nil, err
+		}
+		if l.root, err = filepath.Abs(docsDir); err != nil {
+			return nil, err
+		}
+		if !directoryURLs {
+			l.style = linkStyleHTML
+		}
+	case "hugo":
+		contentDir := filepath.Join(root, "content")
+		if dir, err := (&sitereader.HugoReader{}).ReadContentDir(root, ""); err == nil {
+			contentDir = dir
+		}
+		if info, err := os.Stat(contentDir); err == nil && info.IsDir() {
+			l.root = contentDir
+		}
+		l.lower = true
+	case "docusaurus":
+		if info, err := os.Stat(filepath.Join(root, "docs")); err == nil && info.IsDir() {
+			l.root = filepath.Join(root, "docs")
+			l.prefix = "docs/"
+		}
+		l.style = linkStyleDocusaurus
+	}
+	return l, nil
+}
+
+// ParseLinkBaseURL parses the URL a site is published at, which must be absolute
+func ParseLinkBaseURL(baseURL string) (*url.URL, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid link base URL: %s (must be an absolute URL such as https://docs.example.com)", baseURL)
+	}
+	return base, nil
+}
+
+// Rewrite converts the relative links to pages of the site in the content of
+// source, links in code, to other files and outside the site are kept
+func (l *SiteLinks) Rewrite(content, source string) string {
+	sourceDir, err := filepath.Abs(filepath.Dir(source))
+	if err != nil {
+		return content
+	}
+
+	out, _ := mddoc.Parse([]byte(content)).ReplaceLinks(func(link mddoc.Link) (string, bool) {
+		if link.Kind != mddoc.InlineLink && link.Kind != mddoc.Definition {
+			return "", false
+		}
+		return l.linkURL(sourceDir, link.Destination)
+	})
+	return string(out)
+}
+
+// linkURL returns the absolute URL of a link destination written in dir,
+// false when it does not point at a page of the site
+func (l *SiteLinks) linkURL(dir, dest string) (string, bool) {
+	target, suffix := dest, ""
+	if i := strings.IndexAny(target, "?#"); i >= 0 {
+		target, suffix = target[:i], target[i:]
+	}
+	if target == "" || strings.HasPrefix(target, "/") || strings.Contains(target, ":") {
+		return "", false
+	}
+	unescaped, err := url.PathUnescape(target)
+	if err != nil {
+		return "", false
+	}
+
+	file := filepath.Join(dir, filepath.FromSlash(unescaped))
+	isDir := strings.HasSuffix(target, "/")
+	if info, err := os.Stat(file); err == nil && info.IsDir() {
+		isDir = true
+	}
+	if !isDir && !mddoc.IsMarkdown(file) && !mddoc.IsMDX(file) {
+		return "", false
+	}
+	rel, err := filepath.Rel(l.root, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	u := *l.base
+	u.Path = strings.TrimSuffix(l.base.Path, "/") + "/" + l.pagePath(filepath.ToSlash(rel), isDir)
+	return u.String() + suffix, true
+}
+
+// pagePath returns the URL path of a page or directory relative to the root
+func (l *SiteLinks) pagePath(rel string, isDir bool) string {
+	if rel == "." {
+		rel = ""
+	}
+	dir, name := "", ""
+	if isDir {
+		dir = strings.TrimSuffix(rel, "/")
+		if dir != "" {
+			dir += "/"
+		}
+	} else {
+		dir, name = path.Split(rel)
+		name = strings.TrimSuffix(name, path.Ext(name))
+		if strings.EqualFold(name, "index") || strings.EqualFold(name, "readme") || name == "_index" {
+			name = ""
+		}
+	}
+
+	if l.style == linkStyleDocusaurus {
+		parts := strings.Split(strings.TrimSuffix(dir, "/"), "/")
+		for i, part := range parts {
+			parts[i] = numberPrefixRegex.ReplaceAllString(part, "")
+		}
+		dir = strings.Join(parts, "/")
+		if dir != "" {
+			dir += "/"
+		}
+		name = numberPrefixRegex.ReplaceAllString(name, "")
+	}
+
+	var p string
+	switch {
+	case name == "":
+		p = dir
+	case l.style == linkStyleHTML:
+		p = dir + name + ".html"
+	case l.style == linkStyleDocusaurus:
+		p = dir + name
+	default:
+		p = dir + name + "/"
+	}
+	if l.lower {
+		p = strings.ToLower(p)
+	}
+	return l.prefix + p
+}
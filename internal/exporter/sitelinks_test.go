@@ -0,0 +1,98 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeSiteFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSiteLinksRewrite(t *testing.T) {
+	tests := []struct {
+		name     string
+		siteType string
+		files    map[string]string
+		source   string
+		content  string
+		want     string
+	}{
+		{
+			name:     "mkdocs directory urls",
+			siteType: "mkdocs",
+			files:    map[string]string{"mkdocs.yml": "site_name: Test\n", "docs/index.md": "", "docs/guide/install.md": "", "docs/guide/index.md": ""},
+			source:   "docs/index.md",
+			content:  "See [install](guide/install.md#linux) and [guide](guide/).",
+			want:     "See [install](https://docs.example.com/guide/install/#linux) and [guide](https://docs.example.com/guide/).",
+		},
+		{
+			name:     "mkdocs html urls",
+			siteType: "mkdocs",
+			files:    map[string]string{"mkdocs.yml": "site_name: Test\nuse_directory_urls: false\n", "docs/guide/install.md": "", "docs/guide/index.md": ""},
+			source:   "docs/guide/install.md",
+			content:  "[home](index.md)\n\n[ref]: ./install.md \"Install\"",
+			want:     "[home](https://docs.example.com/guide/)\n\n[ref]: https://docs.example.com/guide/install.html \"Install\"",
+		},
+		{
+			name:     "hugo pretty urls",
+			siteType: "hugo",
+			files:    map[string]string{"content/Posts/First-Post.md": "", "content/Posts/_index.md": ""},
+			source:   "content/Posts/_index.md",
+			content:  "[first](First-Post.md)",
+			want:     "[first](https://docs.example.com/posts/first-post/)",
+		},
+		{
+			name:     "docusaurus",
+			siteType: "docusaurus",
+			files:    map[string]string{"docs/01-intro.md": "", "docs/02-guide/03-setup.mdx": ""},
+			source:   "docs/01-intro.md",
+			content:  "[setup](02-guide/03-setup.mdx)",
+			want:     "[setup](https://docs.example.com/docs/guide/setup)",
+		},
+		{
+			name:     "kept links",
+			siteType: "",
+			files:    map[string]string{"a.md": "", "b.md": "", "diagram.png": ""},
+			source:   "a.md",
+			content:  "![diagram](diagram.png) [top](#top) [ext](https://example.com/b.md) [missing](../outside.md)\n```\n[b](b.md)\n```\n[b](b.md)",
+			want:     "![diagram](diagram.png) [top](#top) [ext](https://example.com/b.md) [missing](../outside.md)\n```\n[b](b.md)\n```\n[b](https://docs.example.com/b/)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeSiteFiles(t, dir, tt.files)
+
+			links, err := NewSiteLinks("https://docs.example.com", tt.siteType, dir)
+			if err != nil {
+				t.Fatalf("NewSiteLinks failed: %v", err)
+			}
+			if got := links.Rewrite(tt.content, filepath.Join(dir, tt.source)); got != tt.want {
+				t.Errorf("Rewrite =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLinkBaseURL(t *testing.T) {
+	for _, value := range []string{"docs.example.com", "/docs", ""} {
+		if _, err := ParseLinkBaseURL(value); err == nil {
+			t.Errorf("ParseLinkBaseURL(%q) succeeded, want an error", value)
+		}
+	}
+	if _, err := ParseLinkBaseURL("https://example.com/docs/"); err != nil {
+		t.Errorf("ParseLinkBaseURL failed: %v", err)
+	}
+}
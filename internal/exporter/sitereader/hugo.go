@@ -0,0 +1,278 @@
+package sitereader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"gopkg.in/yaml.v3"
+)
+
+// hugoConfigNames are the configuration files of a Hugo site, newest first
+var hugoConfigNames = []string{
+	"hugo.toml", "hugo.yaml", "hugo.yml", "hugo.json",
+	"config.toml", "config.yaml", "config.yml", "config.json",
+	"config/_default/hugo.toml", "config/_default/hugo.yaml", "config/_default/config.toml", "config/_default/config.yaml",
+}
+
+var (
+	// tomlContentDirRegex matches the contentDir setting of a TOML configuration
+	tomlContentDirRegex = regexp.MustCompile(`(?m)^\s*contentDir\s*=\s*["']([^"'\n]+)["']`)
+	// tomlTableRegex matches a table header, the settings before the first are top-level
+	tomlTableRegex = regexp.MustCompile(`(?m)^\s*\[`)
+)
+
+// HugoReader reads the navigation of a Hugo site from its content directory:
+// every directory is a section, titled and ordered by the front matter of its
+// _index.md, every page is ordered by its own front matter
+type HugoReader struct {
+	Logger *logging.Logger
+}
+
+// hugoMeta is the front matter Hugo titles and orders pages by
+type hugoMeta struct {
+	Title     string `yaml:"title"`
+	LinkTitle string `yaml:"linkTitle"`
+	Weight    int    `yaml:"weight"`
+	Draft     bool   `yaml:"draft"`
+}
+
+// hugoEntry is a navigation entry with its sort keys
+type hugoEntry struct {
+	NavEntry
+	weight int
+	name   string
+}
+
+func (r *HugoReader) Detect(dir string) bool {
+	if r.Logger == nil {
+		r.Logger = logging.New("SITE-READER")
+	}
+
+	configPath, err := FindConfigFile(dir, hugoConfigNames)
+	if err != nil {
+		r.Logger.Printf("No Hugo configuration file found in %s", dir)
+		return false
+	}
+	r.Logger.Printf("Found Hugo configuration file: %s", configPath)
+	return true
+}
+
+func (r *HugoReader) ReadStructure(dir string, configPath string, navPaths []string) ([]string, error) {
+	if r.Logger == nil {
+		r.Logger = logging.New("SITE-READER")
+	}
+
+	r.Logger.Printf("Reading Hugo site structure from: %s", dir)
+	entries, err := r.ReadNavigation(dir, configPath)
+	if err != nil {
+		return nil, err
+	}
+	files, err := navFiles(entries, navPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Logger.Printf("Found %d files in navigation", len(files))
+	return files, nil
+}
+
+func (r *HugoReader) ReadSections(dir string, configPath string) ([]Section, error) {
+	if r.Logger == nil {
+		r.Logger = logging.New("SITE-READER")
+	}
+
+	r.Logger.Printf("Reading Hugo top-level sections from: %s", dir)
+	entries, err := r.ReadNavigation(dir, configPath)
+	if err != nil {
+		return nil, err
+	}
+	sections := navSections(entries)
+
+	r.Logger.Printf("Found %d top-level sections in navigation", len(sections))
+	return sections, nil
+}
+
+// ReadNavigation returns the home page of the content directory followed by
+// its sections and pages. Sections start with their _index.md, a directory
+// with an index.md is a page (a leaf bundle). Entries are ordered like Hugo
+// orders pages by default: by weight, those without one last, then by title
+// and file name. Drafts and empty sections are left out.
+func (r *HugoReader) ReadNavigation(dir string, configPath string) ([]NavEntry, error) {
+	if r.Logger == nil {
+		r.Logger = logging.New("SITE-READER")
+	}
+
+	contentDir, err := r.ReadContentDir(dir, configPath)
+	if err != nil {
+		return nil, err
+	}
+	r.Logger.Printf("Using content directory: %s", contentDir)
+
+	entries, err := readHugoDir(contentDir)
+	if err != nil {
+		return nil, err
+	}
+	home := filepath.Join(contentDir, "_index.md")
+	if meta, ok := readHugoMeta(home); ok && !meta.Draft {
+		entries = append([]NavEntry{{Title: meta.title("Home"), File: home}}, entries...)
+	}
+	return entries, nil
+}
+
+// ReadContentDir returns the content directory of the site, the contentDir
+// of its configuration or content
+func (r *HugoReader) ReadContentDir(dir string, configPath string) (string, error) {
+	if r.Logger == nil {
+		r.Logger = logging.New("SITE-READER")
+	}
+
+	if configPath == "" {
+		var err error
+		configPath, err = FindConfigFile(dir, hugoConfigNames)
+		if err != nil {
+			return "", fmt.Errorf("failed to find Hugo config file: %s", err)
+		}
+	}
+	r.Logger.Printf("Using config file: %s", configPath)
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Hugo config file: %s", err)
+	}
+	var config struct {
+		ContentDir string `yaml:"contentDir" json:"contentDir"`
+	}
+	switch filepath.Ext(configPath) {
+	case ".toml":
+		if i := tomlTableRegex.FindIndex(data); i != nil {
+			data = data[:i[0]]
+		}
+		if m := tomlContentDirRegex.FindSubmatch(data); m != nil {
+			config.ContentDir = string(m[1])
+		}
+	case ".json":
+		err = json.Unmarshal(data, &config)
+	default:
+		err = yaml.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to parse Hugo config file: %s", err)
+	}
+
+	if config.ContentDir == "" {
+		config.ContentDir = "content"
+	}
+	return filepath.Join(dir, config.ContentDir), nil
+}
+
+// readHugoDir returns the entries of a directory of the content in
+// navigation order
+func readHugoDir(dir string) ([]NavEntry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory %s: %s", dir, err)
+	}
+
+	var entries []hugoEntry
+	for _, d := range dirEntries {
+		name := d.Name()
+		path := filepath.Join(dir, name)
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		if d.IsDir() {
+			// A leaf bundle is a page, its other files are resources
+			bundle := filepath.Join(path, "index.md")
+			if meta, ok := readHugoMeta(bundle); ok {
+				if !meta.Draft {
+					entries = append(entries, hugoEntry{NavEntry{Title: meta.title(name), File: bundle}, meta.Weight, name})
+				}
+				continue
+			}
+
+			index := filepath.Join(path, "_index.md")
+			meta, hasIndex := readHugoMeta(index)
+			if meta.Draft {
+				continue
+			}
+			children, err := readHugoDir(path)
+			if err != nil {
+				return nil, err
+			}
+			title := meta.title(name)
+			if hasIndex {
+				children = append([]NavEntry{{Title: title, File: index}}, children...)
+			}
+			if len(children) == 0 {
+				continue
+			}
+			entries = append(entries, hugoEntry{NavEntry{Title: title, Children: children}, meta.Weight, name})
+			continue
+		}
+
+		if !mddoc.IsMarkdown(name) || name == "_index.md" {
+			continue
+		}
+		meta, _ := readHugoMeta(path)
+		if meta.Draft {
+			continue
+		}
+		title := meta.title(strings.TrimSuffix(name, filepath.Ext(name)))
+		entries = append(entries, hugoEntry{NavEntry{Title: title, File: path}, meta.Weight, name})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		wi, wj := entries[i].weight, entries[j].weight
+		if (wi == 0) != (wj == 0) {
+			return wi != 0
+		}
+		if wi != wj {
+			return wi < wj
+		}
+		if entries[i].Title != entries[j].Title {
+			return entries[i].Title < entries[j].Title
+		}
+		return entries[i].name < entries[j].name
+	})
+
+	result := make([]NavEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry.NavEntry)
+	}
+	return result, nil
+}
+
+// readHugoMeta reads the front matter of a page, false when the page does
+// not exist. Front matter that does not parse is left empty.
+func readHugoMeta(file string) (hugoMeta, bool) {
+	var meta hugoMeta
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return meta, false
+	}
+	if frontMatter, _ := mddoc.SplitFrontMatter(string(content)); frontMatter != "" {
+		if yaml.Unmarshal([]byte(frontMatter), &meta) != nil {
+			meta = hugoMeta{}
+		}
+	}
+	return meta, true
+}
+
+// title returns the navigation title of a page, fallback without one
+func (m hugoMeta) title(fallback string) string {
+	if title := strings.TrimSpace(m.LinkTitle); title != "" {
+		return title
+	}
+	if title := strings.TrimSpace(m.Title); title != "" {
+		return title
+	}
+	return fallback
+}
@@ -0,0 +1,88 @@
+package sitereader
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHugoNavigation(t *testing.T) {
+	dir := writeSite(t, map[string]string{
+		"hugo.toml":                      "title = \"Test\"\ncontentDir = \"pages\"\n\n[params]\ncontentDir = \"other\"\n",
+		"pages/_index.md":                "---\ntitle: Welcome\n---\n# Home",
+		"pages/about.md":                 "# About",
+		"pages/guide/_index.md":          "---\ntitle: User Guide\nweight: 1\n---\n# Guide",
+		"pages/guide/usage.md":           "---\ntitle: Usage\nweight: 2\n---\n# Usage",
+		"pages/guide/setup.md":           "---\ntitle: Setup\nlinkTitle: Install\nweight: 1\n---\n# Setup",
+		"pages/guide/draft.md":           "---\ndraft: true\n---\n# Draft",
+		"pages/api/reference.md":         "# Reference",
+		"pages/api/bundle/index.md":      "---\ntitle: Bundle\n---\n# Bundle",
+		"pages/api/bundle/resource.md":   "# Resource of the bundle",
+		"pages/drafts/_index.md":         "---\ndraft: true\n---\n# Drafts",
+		"pages/drafts/wip.md":            "# Work in progress",
+		"pages/.hidden/page.md":          "# Hidden",
+		"content/ignored.md":             "# Not the content directory",
+		"pages/guide/images/diagram.svg": "<svg/>",
+	})
+
+	r := &HugoReader{}
+	if !r.Detect(dir) {
+		t.Fatal("expected hugo.toml to be detected")
+	}
+	sections, err := r.ReadSections(dir, "")
+	if err != nil {
+		t.Fatalf("ReadSections failed: %v", err)
+	}
+	pages := filepath.Join(dir, "pages")
+	var got []string
+	for _, section := range sections {
+		var files []string
+		for _, file := range section.Files {
+			rel, _ := filepath.Rel(pages, file)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		got = append(got, section.Title+": "+strings.Join(files, ","))
+	}
+	want := []string{
+		"Welcome: _index.md",
+		"User Guide: guide/_index.md,guide/setup.md,guide/usage.md",
+		"about: about.md",
+		"api: api/bundle/index.md,api/reference.md",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sections:\n got %q\nwant %q", got, want)
+	}
+
+	files, err := r.ReadStructure(dir, "", []string{"User Guide/Install"})
+	if err != nil {
+		t.Fatalf("ReadStructure failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != filepath.Join(pages, "guide", "setup.md") {
+		t.Errorf("expected the linkTitle to select setup.md, got %v", files)
+	}
+
+	// Without contentDir the content directory is content
+	dir = writeSite(t, map[string]string{
+		"config.yaml":          "title: Test\n",
+		"content/docs/a.md":    "# A",
+		"content/docs/b.md":    "# B",
+		"content/_index.md":    "# Home",
+		"content/changelog.md": "# Changelog",
+	})
+	sections, err = r.ReadSections(dir, "")
+	if err != nil {
+		t.Fatalf("ReadSections failed: %v", err)
+	}
+	var titles []string
+	for _, section := range sections {
+		titles = append(titles, section.Title)
+	}
+	if strings.Join(titles, ",") != "Home,changelog,docs" {
+		t.Errorf("unexpected sections %q", titles)
+	}
+
+	if (&HugoReader{}).Detect(t.TempDir()) {
+		t.Error("expected a directory without configuration not to be a Hugo site")
+	}
+}
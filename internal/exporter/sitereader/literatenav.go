@@ -0,0 +1,156 @@
+package sitereader
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// literateNavItemRegex matches a list item of a navigation file
+	literateNavItemRegex = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	// literateNavLinkRegex matches the link of a list item
+	literateNavLinkRegex = regexp.MustCompile(`^\[([^\]]*)\]\(<?([^)>\s]*)>?\)`)
+)
+
+// literateNavItem is a list item of a navigation file
+type literateNavItem struct {
+	title    string
+	target   string
+	indent   int
+	children []*literateNavItem
+}
+
+// pluginOptions returns the options of a configured plugin and whether it is configured
+func pluginOptions(config map[string]interface{}, name string) (map[string]interface{}, bool) {
+	plugins, _ := config["plugins"].([]interface{})
+	for _, plugin := range plugins {
+		switch v := plugin.(type) {
+		case string:
+			if v == name {
+				return map[string]interface{}{}, true
+			}
+		case map[string]interface{}:
+			if options, ok := v[name]; ok {
+				opts, _ := options.(map[string]interface{})
+				return opts, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// literateNav returns the navigation the literate-nav plugin reads from the
+// navigation file of the docs directory, nil when the plugin is not used or
+// the file does not exist. The result has the structure of the nav setting.
+func literateNav(config map[string]interface{}, docsDir string) interface{} {
+	options, ok := pluginOptions(config, "literate-nav")
+	if !ok {
+		return nil
+	}
+	navFile := "SUMMARY.md"
+	if name, ok := options["nav_file"].(string); ok && name != "" {
+		navFile = name
+	}
+	if nav := readLiterateNav(docsDir, "", navFile); nav != nil {
+		return nav
+	}
+	return nil
+}
+
+// readLiterateNav parses the navigation file of a directory relative to the
+// docs directory, links are made relative to the docs directory
+func readLiterateNav(docsDir, relDir, navFile string) []interface{} {
+	content, err := os.ReadFile(filepath.Join(docsDir, relDir, navFile))
+	if err != nil {
+		return nil
+	}
+
+	root := &literateNavItem{indent: -1}
+	stack := []*literateNavItem{root}
+	for _, line := range strings.Split(string(content), "\n") {
+		m := literateNavItemRegex.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil {
+			continue
+		}
+		item := &literateNavItem{indent: len(strings.ReplaceAll(m[1], "\t", "    ")), title: strings.TrimSpace(m[2])}
+		if link := literateNavLinkRegex.FindStringSubmatch(item.title); link != nil {
+			item.title, item.target = strings.TrimSpace(link[1]), link[2]
+		} else if strings.HasSuffix(item.title, ".md") || strings.HasSuffix(item.title, "/") {
+			item.title, item.target = "", item.title
+		}
+
+		for len(stack) > 1 && stack[len(stack)-1].indent >= item.indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, item)
+		stack = append(stack, item)
+	}
+	return literateNavItems(root.children, docsDir, relDir, navFile)
+}
+
+// literateNavItems converts list items into navigation entries
+func literateNavItems(items []*literateNavItem, docsDir, relDir, navFile string) []interface{} {
+	var nav []interface{}
+	for _, item := range items {
+		var value []interface{}
+		target := item.target
+		if target != "" && !strings.Contains(target, "://") {
+			target = path.Join(relDir, target)
+			if strings.HasSuffix(item.target, "/") {
+				// A directory brings its own navigation file or its pages
+				value = readLiterateNav(docsDir, target, navFile)
+				if value == nil {
+					value = literateNavDir(docsDir, target)
+				}
+			} else {
+				value = append(value, target)
+			}
+		}
+		value = append(value, literateNavItems(item.children, docsDir, relDir, navFile)...)
+		if len(value) == 0 {
+			continue
+		}
+
+		switch {
+		case item.title == "" && len(value) == 1:
+			nav = append(nav, value[0])
+		case item.title == "":
+			nav = append(nav, value...)
+		case len(value) == 1:
+			nav = append(nav, map[string]interface{}{item.title: value[0]})
+		default:
+			nav = append(nav, map[string]interface{}{item.title: value})
+		}
+	}
+	return nav
+}
+
+// literateNavDir returns the pages of a directory without navigation file in name order
+func literateNavDir(docsDir, relDir string) []interface{} {
+	entries, err := os.ReadDir(filepath.Join(docsDir, relDir))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(strings.ToLower(entry.Name()), ".md") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		if isIndexPage(names[i]) != isIndexPage(names[j]) {
+			return isIndexPage(names[i])
+		}
+		return names[i] < names[j]
+	})
+	nav := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		nav = append(nav, path.Join(relDir, name))
+	}
+	return nav
+}
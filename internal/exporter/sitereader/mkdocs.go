@@ -2,18 +2,16 @@ package sitereader
 
 import (
 	"fmt"
-	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 
-	"gopkg.in/yaml.v3"
+	"github.com/samzong/mdctl/internal/logging"
 )
 
 type MkDocsReader struct {
-	Logger *log.Logger
+	Logger *logging.Logger
 }
 
 type MkDocsConfig struct {
@@ -25,7 +23,7 @@ type MkDocsConfig struct {
 func (r *MkDocsReader) Detect(dir string) bool {
 	// Setting up the Logger
 	if r.Logger == nil {
-		r.Logger = log.New(io.Discard, "", 0)
+		r.Logger = logging.New("SITE-READER")
 	}
 
 	// Check if mkdocs.yml file exists
@@ -43,17 +41,83 @@ func (r *MkDocsReader) Detect(dir string) bool {
 	return true
 }
 
-func (r *MkDocsReader) ReadStructure(dir string, configPath string, navPath string) ([]string, error) {
+func (r *MkDocsReader) ReadStructure(dir string, configPath string, navPaths []string) ([]string, error) {
 	// Setting up the Logger
 	if r.Logger == nil {
-		r.Logger = log.New(io.Discard, "", 0)
+		r.Logger = logging.New("SITE-READER")
 	}
 
 	r.Logger.Printf("Reading MkDocs site structure from: %s", dir)
-	if navPath != "" {
-		r.Logger.Printf("Filtering by navigation path: %s", navPath)
+	if len(navPaths) > 0 {
+		r.Logger.Printf("Filtering by navigation paths: %s", strings.Join(navPaths, ", "))
 	}
 
+	entries, err := r.ReadNavigation(dir, configPath)
+	if err != nil {
+		return nil, err
+	}
+	files, err := navFiles(entries, navPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Logger.Printf("Found %d files in navigation", len(files))
+	return files, nil
+}
+
+func (r *MkDocsReader) ReadSections(dir string, configPath string) ([]Section, error) {
+	// Setting up the Logger
+	if r.Logger == nil {
+		r.Logger = logging.New("SITE-READER")
+	}
+
+	r.Logger.Printf("Reading MkDocs top-level sections from: %s", dir)
+
+	entries, err := r.ReadNavigation(dir, configPath)
+	if err != nil {
+		return nil, err
+	}
+	sections := navSections(entries)
+
+	r.Logger.Printf("Found %d top-level sections in navigation", len(sections))
+	return sections, nil
+}
+
+// ReadURLSettings returns the docs directory of the site and whether pages
+// are published at directory URLs (use_directory_urls, true by default)
+func (r *MkDocsReader) ReadURLSettings(dir string, configPath string) (string, bool, error) {
+	if r.Logger == nil {
+		r.Logger = logging.New("SITE-READER")
+	}
+
+	site, err := r.readNavigation(dir, configPath)
+	if err != nil {
+		return "", false, err
+	}
+	return site.docsDir, site.directoryURLs, nil
+}
+
+func (r *MkDocsReader) ReadNavigation(dir string, configPath string) ([]NavEntry, error) {
+	// Setting up the Logger
+	if r.Logger == nil {
+		r.Logger = logging.New("SITE-READER")
+	}
+
+	site, err := r.readNavigation(dir, configPath)
+	if err != nil {
+		return nil, err
+	}
+	if site.nav == nil {
+		// If no navigation config, derive it from the files like MkDocs
+		r.Logger.Println("No navigation configuration found, deriving it from the markdown files")
+		return site.derivedNavigation()
+	}
+	return site.navEntries(site.nav), nil
+}
+
+// readNavigation reads the navigation of the site, its docs directory and the
+// settings excluding pages, the navigation is nil when the configuration has none
+func (r *MkDocsReader) readNavigation(dir string, configPath string) (*mkdocsSite, error) {
 	// Find config file
 	if configPath == "" {
 		configNames := []string{"mkdocs.yml", "mkdocs.yaml"}
@@ -83,25 +147,15 @@ func (r *MkDocsReader) ReadStructure(dir string, configPath string, navPath stri
 	docsDir = filepath.Join(dir, docsDir)
 	r.Logger.Printf("Using docs directory: %s", docsDir)
 
-	// Parse navigation structure
-	var nav interface{}
-	if navValue, ok := config["nav"]; ok {
-		nav = navValue
-	} else {
-		// If no navigation config, try to find all Markdown files
-		r.Logger.Println("No navigation configuration found, searching for all markdown files")
-		return getAllMarkdownFiles(docsDir)
-	}
-
-	// Parse navigation structure, get file list
-	files, err := parseNavigation(nav, docsDir, navPath)
-	if err != nil {
-		r.Logger.Printf("Failed to parse navigation: %s", err)
-		return nil, fmt.Errorf("failed to parse navigation: %s", err)
+	// Plugins such as literate-nav provide the navigation instead of the configuration
+	if config["nav"] == nil {
+		if nav := literateNav(config, docsDir); nav != nil {
+			r.Logger.Println("Using navigation of the literate-nav plugin")
+			config["nav"] = nav
+		}
 	}
 
-	r.Logger.Printf("Found %d files in navigation", len(files))
-	return files, nil
+	return newMkDocsSite(config, docsDir), nil
 }
 
 // readAndMergeConfig Read and merge MkDocs config file, handling INHERIT directive
@@ -116,8 +170,8 @@ func (r *MkDocsReader) readAndMergeConfig(configPath string, baseDir string) (ma
 	}
 
 	// Parse config file
-	var config map[string]interface{}
-	if err := yaml.Unmarshal(configData, &config); err != nil {
+	config, err := decodeMkDocsYAML(configData)
+	if err != nil {
 		r.Logger.Printf("Failed to parse MkDocs config file: %s", err)
 		return nil, fmt.Errorf("failed to parse MkDocs config file: %s", err)
 	}
@@ -207,96 +261,3 @@ func preprocessMarkdownFile(filePath string) error {
 
 	return nil
 }
-
-// parseNavigation Parse MkDocs navigation structure
-func parseNavigation(nav interface{}, docsDir string, navPath string) ([]string, error) {
-	var files []string
-
-	switch v := nav.(type) {
-	case []interface{}:
-		// Navigation is a list
-		for _, item := range v {
-			itemFiles, err := parseNavigation(item, docsDir, navPath)
-			if err != nil {
-				return nil, err
-			}
-			files = append(files, itemFiles...)
-		}
-	case map[string]interface{}:
-		// Navigation is a map
-		for title, value := range v {
-			// If nav path is specified, check if current node title matches
-			if navPath != "" {
-				// Support simple path matching, e.g. "Section1/Subsection2"
-				navParts := strings.Split(navPath, "/")
-				if strings.TrimSpace(title) == strings.TrimSpace(navParts[0]) {
-					// If it's a multi-level path, continue matching the next level
-					if len(navParts) > 1 {
-						subNavPath := strings.Join(navParts[1:], "/")
-						itemFiles, err := parseNavigation(value, docsDir, subNavPath)
-						if err != nil {
-							return nil, err
-						}
-						files = append(files, itemFiles...)
-						continue
-					} else {
-						// If it's a single-level path and matches, only handle this node
-						itemFiles, err := parseNavigation(value, docsDir, "")
-						if err != nil {
-							return nil, err
-						}
-						files = append(files, itemFiles...)
-						continue
-					}
-				} else {
-					// Title doesn't match, skip this node
-					continue
-				}
-			}
-
-			// If no nav path is specified or already matched the path, handle normally
-			itemFiles, err := parseNavigation(value, docsDir, "")
-			if err != nil {
-				return nil, err
-			}
-			files = append(files, itemFiles...)
-		}
-	case string:
-		// Navigation item is a file path
-		if strings.HasSuffix(v, ".md") {
-			filePath := filepath.Join(docsDir, v)
-			if _, err := os.Stat(filePath); err == nil {
-				// If no nav path is specified or already handled in nav path filtering, add file
-				if navPath == "" {
-					files = append(files, filePath)
-				}
-			}
-		}
-	}
-
-	return files, nil
-}
-
-// getAllMarkdownFiles Get all Markdown files in a directory
-func getAllMarkdownFiles(dir string) ([]string, error) {
-	var files []string
-
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			ext := strings.ToLower(filepath.Ext(path))
-			if ext == ".md" || ext == ".markdown" {
-				files = append(files, path)
-			}
-		}
-		return nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to walk directory %s: %s", dir, err)
-	}
-
-	return files, nil
-}
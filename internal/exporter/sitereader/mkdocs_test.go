@@ -0,0 +1,213 @@
+package sitereader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSite writes the files of a site below a temporary directory
+func writeSite(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// relFiles returns files relative to the docs directory of a site
+func relFiles(dir string, files []string) string {
+	var rels []string
+	for _, file := range files {
+		rel, _ := filepath.Rel(filepath.Join(dir, "docs"), file)
+		rels = append(rels, filepath.ToSlash(rel))
+	}
+	return strings.Join(rels, ",")
+}
+
+func TestMkDocsDerivedNavigation(t *testing.T) {
+	dir := writeSite(t, map[string]string{
+		"mkdocs.yml":              "site_name: Test\nnot_in_nav: |\n  /orphan.md\nexclude_docs: |\n  *.tmp.md\n",
+		"docs/index.md":           "# Home",
+		"docs/about.md":           "# About",
+		"docs/orphan.md":          "# Orphan",
+		"docs/scratch.tmp.md":     "# Scratch",
+		"docs/.pages":             "nav:\n  - guide\n  - ...\n",
+		"docs/guide/.pages":       "title: User Guide\nnav:\n  - setup.md\n  - Usage: usage.md\n",
+		"docs/guide/usage.md":     "# Usage",
+		"docs/guide/setup.md":     "# Setup",
+		"docs/guide/draft.md":     "---\ndraft: true\n---\n# Draft",
+		"docs/hidden/.pages":      "hide: true\n",
+		"docs/hidden/secret.md":   "# Secret",
+		"docs/.github/readme.md":  "# Dot directory",
+		"docs/templates/page.md":  "# Template",
+		"docs/blog/posts/2024.md": "# Post",
+	})
+
+	r := &MkDocsReader{}
+	files, err := r.ReadStructure(dir, "", nil)
+	if err != nil {
+		t.Fatalf("ReadStructure failed: %v", err)
+	}
+	if got, want := relFiles(dir, files), "guide/setup.md,guide/usage.md,index.md,about.md,blog/posts/2024.md"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	sections, err := r.ReadSections(dir, "")
+	if err != nil {
+		t.Fatalf("ReadSections failed: %v", err)
+	}
+	var titles []string
+	for _, section := range sections {
+		titles = append(titles, section.Title)
+	}
+	if got := strings.Join(titles, ","); got != "User Guide,index,about,blog" {
+		t.Errorf("unexpected sections: %s", got)
+	}
+}
+
+func TestMkDocsNavSkipsDrafts(t *testing.T) {
+	dir := writeSite(t, map[string]string{
+		"mkdocs.yml":    "site_name: Test\nnav:\n  - index.md\n  - Guide:\n    - draft.md\n    - guide.md\n  - old.md\nexclude_docs: |\n  old.md\n",
+		"docs/index.md": "# Home",
+		"docs/draft.md": "---\ntitle: Draft\ndraft: true\n---\n# Draft",
+		"docs/guide.md": "# Guide",
+		"docs/old.md":   "# Old",
+		"docs/other.md": "# Other",
+	})
+
+	files, err := (&MkDocsReader{}).ReadStructure(dir, "", nil)
+	if err != nil {
+		t.Fatalf("ReadStructure failed: %v", err)
+	}
+	if got := relFiles(dir, files); got != "index.md,guide.md" {
+		t.Errorf("unexpected files: %s", got)
+	}
+}
+
+func TestMatchDocsPatterns(t *testing.T) {
+	patterns := []string{"drafts/", "*.tmp.md", "/api/**", "!api/keep.md"}
+	tests := map[string]bool{
+		"drafts/a.md":     true,
+		"sub/drafts/b.md": true,
+		"drafts.md":       false,
+		"x/y.tmp.md":      true,
+		"api/ref.md":      true,
+		"api/keep.md":     false,
+		"sub/api/ref.md":  false,
+	}
+	for rel, want := range tests {
+		if got := matchDocsPatterns(patterns, rel); got != want {
+			t.Errorf("%s: expected %v, got %v", rel, want, got)
+		}
+	}
+}
+
+func TestMkDocsCustomTags(t *testing.T) {
+	t.Setenv("MDCTL_TEST_DOCS", "content")
+	config, err := decodeMkDocsYAML([]byte(`site_name: !ENV [MDCTL_TEST_UNSET, "Fallback"]
+docs_dir: !ENV MDCTL_TEST_DOCS
+strict: !ENV [MDCTL_TEST_UNSET, true]
+theme:
+  custom_dir: !relative $config_dir/overrides
+markdown_extensions:
+  - pymdownx.emoji:
+      emoji_index: !!python/name:material.extensions.emoji.twemoji
+  - toc:
+      slugify: !!python/object/apply:pymdownx.slugs.slugify
+        kwds:
+          case: lower
+`))
+	if err != nil {
+		t.Fatalf("decodeMkDocsYAML failed: %v", err)
+	}
+	if config["site_name"] != "Fallback" || config["docs_dir"] != "content" || config["strict"] != true {
+		t.Errorf("unexpected !ENV values: %v", config)
+	}
+	if config["theme"].(map[string]interface{})["custom_dir"] != "$config_dir/overrides" {
+		t.Errorf("unexpected !relative value: %v", config["theme"])
+	}
+}
+
+func TestMkDocsLiterateNav(t *testing.T) {
+	dir := writeSite(t, map[string]string{
+		"mkdocs.yml":            "site_name: Test\nplugins:\n  - search\n  - literate-nav:\n      nav_file: NAV.md\n",
+		"docs/NAV.md":           "* [Home](index.md)\n* Guide\n    * [Setup](guide/setup.md)\n    * [Usage](guide/usage.md)\n* [Reference](reference/)\n",
+		"docs/index.md":         "# Home",
+		"docs/guide/setup.md":   "# Setup",
+		"docs/guide/usage.md":   "# Usage",
+		"docs/reference/NAV.md": "- [API](api.md)\n",
+		"docs/reference/api.md": "# API",
+		"docs/reference/cli.md": "# CLI",
+	})
+
+	r := &MkDocsReader{}
+	files, err := r.ReadStructure(dir, "", []string{"Guide"})
+	if err != nil {
+		t.Fatalf("ReadStructure failed: %v", err)
+	}
+	if got := relFiles(dir, files); got != "guide/setup.md,guide/usage.md" {
+		t.Errorf("unexpected files: %s", got)
+	}
+
+	sections, err := r.ReadSections(dir, "")
+	if err != nil {
+		t.Fatalf("ReadSections failed: %v", err)
+	}
+	if len(sections) != 3 || sections[2].Title != "Reference" || relFiles(dir, sections[2].Files) != "reference/api.md" {
+		t.Errorf("unexpected sections: %v", sections)
+	}
+}
+
+func TestSelectNav(t *testing.T) {
+	dir := writeSite(t, map[string]string{
+		"mkdocs.yml":            "site_name: Test\nnav:\n  - index.md\n  - User Guide:\n    - Install: guide/install.md\n    - Usage: guide/usage.md\n  - API:\n    - REST: api/rest.md\n    - Go: api/go.md\n",
+		"docs/index.md":         "# Home",
+		"docs/guide/install.md": "# Install",
+		"docs/guide/usage.md":   "# Usage",
+		"docs/api/rest.md":      "# REST",
+		"docs/api/go.md":        "# Go",
+	})
+
+	r := &MkDocsReader{}
+	entries, err := r.ReadNavigation(dir, "")
+	if err != nil {
+		t.Fatalf("ReadNavigation failed: %v", err)
+	}
+	if len(entries) != 3 || entries[0].Title != "index" || entries[1].Title != "User Guide" || len(entries[2].Children) != 2 {
+		t.Fatalf("unexpected navigation: %+v", entries)
+	}
+
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{[]string{"User Guide/*", "API/*"}, "guide/install.md,guide/usage.md,api/rest.md,api/go.md"},
+		{[]string{"API/Go", "User Guide/Usage"}, "guide/usage.md,api/go.md"},
+		{[]string{"User Guide", "User Guide/Install"}, "guide/install.md,guide/usage.md"},
+		{[]string{"*"}, "index.md,guide/install.md,guide/usage.md,api/rest.md,api/go.md"},
+		{[]string{" API / R* "}, "api/rest.md"},
+	}
+	for _, tt := range tests {
+		files, err := SelectNav(entries, tt.paths)
+		if err != nil {
+			t.Errorf("%q: %v", tt.paths, err)
+			continue
+		}
+		if got := relFiles(dir, files); got != tt.want {
+			t.Errorf("%q: expected %s, got %s", tt.paths, tt.want, got)
+		}
+	}
+
+	if _, err := SelectNav(entries, []string{"API/*", "Tutorials"}); err == nil || !strings.Contains(err.Error(), "Tutorials") {
+		t.Errorf("expected an error for a path matching nothing, got %v", err)
+	}
+	if _, err := SelectNav(entries, []string{"API/["}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
@@ -0,0 +1,83 @@
+package sitereader
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// standardTags are the YAML tags decoded as usual, all other tags are custom
+var standardTags = map[string]bool{
+	"!!str": true, "!!int": true, "!!float": true, "!!bool": true, "!!null": true,
+	"!!map": true, "!!seq": true, "!!binary": true, "!!timestamp": true, "!!merge": true,
+}
+
+// decodeMkDocsYAML decodes an MkDocs configuration the way MkDocs loads it:
+// !ENV tags are replaced by the value of their environment variable, and
+// other custom tags such as !relative or !!python/name, which only MkDocs and
+// its plugins can resolve, are dropped so their values decode as plain YAML
+func decodeMkDocsYAML(data []byte) (map[string]interface{}, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if root.Kind == 0 {
+		// An empty file
+		return map[string]interface{}{}, nil
+	}
+	resolveTags(&root)
+
+	var config map[string]interface{}
+	if err := root.Decode(&config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// resolveTags resolves the custom tags of a node and its children
+func resolveTags(node *yaml.Node) {
+	if node.Tag == "!ENV" {
+		resolveEnv(node)
+		return
+	}
+	if node.Tag != "" && !standardTags[node.Tag] {
+		node.Tag = ""
+	}
+	for _, child := range node.Content {
+		resolveTags(child)
+	}
+}
+
+// resolveEnv replaces an !ENV node by the value of its environment variable.
+// A scalar names one variable; in a sequence the first defined variable wins
+// and the last item is the default, as in pyyaml-env-tag. Values are parsed
+// as YAML scalars, so true and 8000 keep their types.
+func resolveEnv(node *yaml.Node) {
+	var names []string
+	var fallback *yaml.Node
+	switch node.Kind {
+	case yaml.ScalarNode:
+		names = []string{node.Value}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			names = append(names, item.Value)
+		}
+		if len(node.Content) > 1 {
+			fallback = node.Content[len(node.Content)-1]
+			names = names[:len(names)-1]
+		}
+	}
+
+	for _, name := range names {
+		if value, ok := os.LookupEnv(name); ok {
+			*node = yaml.Node{Kind: yaml.ScalarNode, Value: value, Line: node.Line, Column: node.Column}
+			return
+		}
+	}
+	if fallback != nil {
+		resolveTags(fallback)
+		*node = *fallback
+		return
+	}
+	*node = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Line: node.Line, Column: node.Column}
+}
@@ -0,0 +1,139 @@
+package sitereader
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// navEntries converts the nav of a MkDocs configuration into entries. Links,
+// missing and unpublished pages and the sections left empty are dropped,
+// pages listed without a title are titled after their file name.
+func (s *mkdocsSite) navEntries(nav interface{}) []NavEntry {
+	var entries []NavEntry
+	switch v := nav.(type) {
+	case []interface{}:
+		for _, item := range v {
+			entries = append(entries, s.navEntries(item)...)
+		}
+	case map[string]interface{}:
+		for title, value := range v {
+			title = strings.TrimSpace(title)
+			if file, ok := value.(string); ok {
+				if page, ok := s.navPage(file); ok {
+					page.Title = title
+					entries = append(entries, page)
+				}
+				continue
+			}
+			if children := s.navEntries(value); len(children) > 0 {
+				entries = append(entries, NavEntry{Title: title, Children: children})
+			}
+		}
+	case string:
+		if page, ok := s.navPage(v); ok {
+			entries = append(entries, page)
+		}
+	}
+	return entries
+}
+
+// navPage returns the entry of a page of the nav, false when it is not a
+// published markdown file of the docs directory
+func (s *mkdocsSite) navPage(file string) (NavEntry, bool) {
+	if !strings.HasSuffix(file, ".md") {
+		return NavEntry{}, false
+	}
+	filePath := filepath.Join(s.docsDir, file)
+	if _, err := os.Stat(filePath); err != nil || !s.published(filePath) {
+		return NavEntry{}, false
+	}
+	title := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	return NavEntry{Title: title, File: filePath}, true
+}
+
+// navFiles returns the pages of the entries matching the navigation paths,
+// all pages without paths
+func navFiles(entries []NavEntry, navPaths []string) ([]string, error) {
+	if len(navPaths) > 0 {
+		return SelectNav(entries, navPaths)
+	}
+	var files []string
+	for _, entry := range entries {
+		files = append(files, entry.files()...)
+	}
+	return files, nil
+}
+
+// navSections returns a section per top-level entry
+func navSections(entries []NavEntry) []Section {
+	sections := make([]Section, 0, len(entries))
+	for _, entry := range entries {
+		sections = append(sections, Section{Title: entry.Title, Files: entry.files()})
+	}
+	return sections
+}
+
+// SelectNav returns the pages of the entries matching one of the navigation
+// paths, in navigation order. A path lists the titles from the top level down
+// separated by slashes, e.g. "User Guide/Install", every level may use the
+// wildcards of path.Match, e.g. "API/*". A matching section selects all pages
+// below it, which are not selected again by other paths, while pages the
+// navigation lists several times are kept. Every path has to match an entry.
+func SelectNav(entries []NavEntry, navPaths []string) ([]string, error) {
+	patterns := make([][]string, len(navPaths))
+	for i, navPath := range navPaths {
+		for _, part := range strings.Split(navPath, "/") {
+			part = strings.TrimSpace(part)
+			if _, err := path.Match(part, ""); err != nil {
+				return nil, fmt.Errorf("invalid navigation path %q: %s", navPath, err)
+			}
+			patterns[i] = append(patterns[i], part)
+		}
+	}
+
+	var files []string
+	matched := make([]bool, len(navPaths))
+	var walk func(entries []NavEntry, titles []string, inSelected bool)
+	walk = func(entries []NavEntry, titles []string, inSelected bool) {
+		for _, entry := range entries {
+			entryTitles := append(titles[:len(titles):len(titles)], strings.TrimSpace(entry.Title))
+			selected := false
+			for i, pattern := range patterns {
+				if matchNavPath(pattern, entryTitles) {
+					matched[i] = true
+					selected = true
+				}
+			}
+			if selected && !inSelected {
+				files = append(files, entry.files()...)
+			}
+			// Entries below a selected one may match other paths
+			walk(entry.Children, entryTitles, inSelected || selected)
+		}
+	}
+	walk(entries, nil, false)
+
+	for i, ok := range matched {
+		if !ok {
+			return nil, fmt.Errorf("navigation path %q matches no entry, use --list-nav to show the available paths", navPaths[i])
+		}
+	}
+	return files, nil
+}
+
+// matchNavPath reports whether the titles of an entry and its parents match
+// a navigation path split into levels
+func matchNavPath(pattern, titles []string) bool {
+	if len(pattern) != len(titles) {
+		return false
+	}
+	for i := range pattern {
+		if ok, _ := path.Match(pattern[i], titles[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
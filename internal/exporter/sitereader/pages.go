@@ -0,0 +1,319 @@
+package sitereader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gobwas/glob"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultExcludeDocs are the files MkDocs leaves out of every site
+var defaultExcludeDocs = []string{".*", "/templates/"}
+
+// mkdocsSite is the part of a MkDocs configuration that selects the pages
+// of the published site
+type mkdocsSite struct {
+	nav      interface{}
+	docsDir  string
+	exclude  []string // exclude_docs and draft_docs patterns, never exported
+	notInNav []string // not_in_nav patterns, left out when the navigation is derived from the files
+
+	directoryURLs bool // Pages are published at guide/install/ instead of guide/install.html
+}
+
+// newMkDocsSite reads the page selection settings of a configuration
+func newMkDocsSite(config map[string]interface{}, docsDir string) *mkdocsSite {
+	site := &mkdocsSite{nav: config["nav"], docsDir: docsDir}
+	site.exclude = append(site.exclude, defaultExcludeDocs...)
+	site.exclude = append(site.exclude, docsPatterns(config["exclude_docs"])...)
+	site.exclude = append(site.exclude, docsPatterns(config["draft_docs"])...)
+	site.notInNav = docsPatterns(config["not_in_nav"])
+	site.directoryURLs = true
+	if value, ok := config["use_directory_urls"].(bool); ok {
+		site.directoryURLs = value
+	}
+	return site
+}
+
+// published reports whether a page is part of the published site: it is not
+// excluded by exclude_docs or draft_docs and not marked draft: true
+func (s *mkdocsSite) published(file string) bool {
+	rel, err := filepath.Rel(s.docsDir, file)
+	if err != nil {
+		rel = file
+	}
+	if matchDocsPatterns(s.exclude, filepath.ToSlash(rel)) {
+		return false
+	}
+	return !isDraft(file)
+}
+
+// NavEntry is a page or a section of the navigation of a site
+type NavEntry struct {
+	Title    string     `json:"title"`
+	File     string     `json:"file,omitempty"` // Page file, empty for sections
+	Children []NavEntry `json:"children,omitempty"`
+}
+
+// files returns the pages of an entry in navigation order
+func (e NavEntry) files() []string {
+	if e.File != "" {
+		return []string{e.File}
+	}
+	var files []string
+	for _, child := range e.Children {
+		files = append(files, child.files()...)
+	}
+	return files
+}
+
+// pagesFile is an awesome-pages .pages file, which orders, titles and hides
+// the entries of its directory
+type pagesFile struct {
+	Title   string        `yaml:"title"`
+	Nav     []interface{} `yaml:"nav"`
+	Arrange []interface{} `yaml:"arrange"` // Older name of nav
+	Order   string        `yaml:"order"`
+	Hide    bool          `yaml:"hide"`
+}
+
+// derivedNavigation builds the navigation MkDocs derives from the files of
+// the docs directory when the configuration has none. Pages not in the nav
+// and unpublished pages are left out, .pages files order the directories.
+func (s *mkdocsSite) derivedNavigation() ([]NavEntry, error) {
+	if _, err := os.Stat(s.docsDir); err != nil {
+		return nil, fmt.Errorf("failed to walk directory %s: %s", s.docsDir, err)
+	}
+	return s.readPagesDir(s.docsDir)
+}
+
+// readPagesDir returns the entries of a directory in navigation order
+func (s *mkdocsSite) readPagesDir(dir string) ([]NavEntry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory %s: %s", dir, err)
+	}
+
+	var pages pagesFile
+	if data, err := os.ReadFile(filepath.Join(dir, ".pages")); err == nil {
+		if err := yaml.Unmarshal(data, &pages); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", filepath.Join(dir, ".pages"), err)
+		}
+	}
+	if pages.Hide && dir != s.docsDir {
+		return nil, nil
+	}
+
+	// Every visible page and non-empty section of the directory, by name
+	entries := make(map[string]NavEntry)
+	var names []string
+	for _, d := range dirEntries {
+		name := d.Name()
+		path := filepath.Join(dir, name)
+		rel, _ := filepath.Rel(s.docsDir, path)
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if matchDocsPatterns(s.exclude, rel+"/") {
+				continue
+			}
+			sub, err := s.readPagesDir(path)
+			if err != nil {
+				return nil, err
+			}
+			if len(sub) == 0 {
+				continue
+			}
+			entries[name] = NavEntry{Title: sectionTitle(path, name), Children: sub}
+		} else {
+			if !mddoc.IsMarkdown(name) {
+				continue
+			}
+			if matchDocsPatterns(s.notInNav, rel) || !s.published(path) {
+				continue
+			}
+			entries[name] = NavEntry{Title: strings.TrimSuffix(name, filepath.Ext(name)), File: path}
+		}
+		names = append(names, name)
+	}
+
+	// Index pages come first, as in MkDocs
+	sort.SliceStable(names, func(i, j int) bool {
+		if isIndexPage(names[i]) != isIndexPage(names[j]) {
+			return isIndexPage(names[i])
+		}
+		if strings.EqualFold(pages.Order, "desc") {
+			return names[i] > names[j]
+		}
+		return names[i] < names[j]
+	})
+
+	nav := pages.Nav
+	if nav == nil {
+		nav = pages.Arrange
+	}
+	if nav == nil {
+		result := make([]NavEntry, 0, len(names))
+		for _, name := range names {
+			result = append(result, entries[name])
+		}
+		return result, nil
+	}
+
+	// Entries listed in nav take their place, "..." stands for the rest
+	listed := make(map[string]bool)
+	for _, item := range nav {
+		name, _ := pagesNavItem(item)
+		if _, ok := entries[name]; ok {
+			listed[name] = true
+		}
+	}
+	var result []NavEntry
+	for _, item := range nav {
+		name, title := pagesNavItem(item)
+		if name == "..." || strings.HasPrefix(name, "... ") {
+			for _, rest := range names {
+				if !listed[rest] {
+					result = append(result, entries[rest])
+				}
+			}
+			continue
+		}
+		entry, ok := entries[name]
+		if !ok {
+			// Links and missing files have no pages to export
+			continue
+		}
+		if title != "" {
+			entry.Title = title
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// pagesNavItem returns the entry name and custom title of a .pages nav item
+func pagesNavItem(item interface{}) (string, string) {
+	switch v := item.(type) {
+	case string:
+		return strings.TrimSpace(v), ""
+	case map[string]interface{}:
+		for title, value := range v {
+			if name, ok := value.(string); ok {
+				return strings.TrimSpace(name), title
+			}
+		}
+	}
+	return "", ""
+}
+
+// sectionTitle returns the title of a directory, set by its .pages file or
+// derived from its name
+func sectionTitle(dir, name string) string {
+	var pages pagesFile
+	if data, err := os.ReadFile(filepath.Join(dir, ".pages")); err == nil {
+		if yaml.Unmarshal(data, &pages) == nil && pages.Title != "" {
+			return pages.Title
+		}
+	}
+	return name
+}
+
+// isIndexPage reports whether a file is the index page of its directory
+func isIndexPage(name string) bool {
+	lower := strings.ToLower(name)
+	return lower == "index.md" || lower == "readme.md"
+}
+
+// isDraft reports whether a page has draft: true in its front matter
+func isDraft(file string) bool {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	frontMatter, _ := mddoc.SplitFrontMatter(string(content))
+	if frontMatter == "" {
+		return false
+	}
+	var meta struct {
+		Draft bool `yaml:"draft"`
+	}
+	if err := yaml.Unmarshal([]byte(frontMatter), &meta); err != nil {
+		return false
+	}
+	return meta.Draft
+}
+
+// docsPatterns returns the gitignore-style patterns of a configuration value,
+// a multi-line string or a list
+func docsPatterns(value interface{}) []string {
+	var patterns []string
+	switch v := value.(type) {
+	case string:
+		patterns = strings.Split(v, "\n")
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				patterns = append(patterns, s)
+			}
+		}
+	}
+	return patterns
+}
+
+// matchDocsPatterns reports whether a path relative to the docs directory
+// matches gitignore-style patterns, the last matching pattern decides and
+// patterns starting with ! include files again. Directories end with a slash.
+func matchDocsPatterns(patterns []string, rel string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		negate := strings.HasPrefix(pattern, "!")
+		if negate {
+			pattern = pattern[1:]
+		}
+		if matchDocsPattern(pattern, rel) {
+			matched = !negate
+		}
+	}
+	return matched
+}
+
+// matchDocsPattern matches a single gitignore-style pattern against a path
+// and its parent directories. Patterns with a slash other than a trailing one
+// are anchored at the docs directory, others match any path element.
+func matchDocsPattern(pattern, rel string) bool {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	g, err := glob.Compile(pattern, '/')
+	if err != nil {
+		return false
+	}
+
+	isDir := strings.HasSuffix(rel, "/")
+	parts := strings.Split(strings.TrimSuffix(rel, "/"), "/")
+	for i := 1; i <= len(parts); i++ {
+		// The last element is only a directory if the path is one
+		if dirOnly && i == len(parts) && !isDir {
+			break
+		}
+		if anchored {
+			if g.Match(strings.Join(parts[:i], "/")) {
+				return true
+			}
+		} else if g.Match(parts[i-1]) {
+			return true
+		}
+	}
+	return false
+}
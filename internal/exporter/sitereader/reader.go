@@ -2,10 +2,11 @@ package sitereader
 
 import (
 	"fmt"
-	"io"
-	"log"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/logging"
 )
 
 // SiteReader Define Site Reader Interface
@@ -14,19 +15,37 @@ type SiteReader interface {
 	Detect(dir string) bool
 
 	// Read site structure, return sorted list of files
-	// navPath parameter is used to specify the navigation path to export, empty to export all
-	ReadStructure(dir string, configPath string, navPath string) ([]string, error)
+	// navPaths select the navigation paths to export, see SelectNav, empty to export all
+	ReadStructure(dir string, configPath string, navPaths []string) ([]string, error)
+
+	// Read the top-level navigation entries of the site and their files
+	ReadSections(dir string, configPath string) ([]Section, error)
+
+	// Read the navigation tree of the site
+	ReadNavigation(dir string, configPath string) ([]NavEntry, error)
+}
+
+// Section is a top-level navigation entry and the files below it in order
+type Section struct {
+	Title string
+	Files []string
+}
+
+// FileSections returns one section per file, titled after the file name
+func FileSections(files []string) []Section {
+	sections := make([]Section, 0, len(files))
+	for _, file := range files {
+		title := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		sections = append(sections, Section{Title: title, Files: []string{file}})
+	}
+	return sections
 }
 
 // GetSiteReader Return the appropriate reader based on site type
-func GetSiteReader(siteType string, verbose bool, logger *log.Logger) (SiteReader, error) {
+func GetSiteReader(siteType string, verbose bool, logger *logging.Logger) (SiteReader, error) {
 	// If no logger is provided, create a default one
 	if logger == nil {
-		if verbose {
-			logger = log.New(os.Stdout, "[SITE-READER] ", log.LstdFlags)
-		} else {
-			logger = log.New(io.Discard, "", 0)
-		}
+		logger = logging.New("SITE-READER")
 	}
 
 	logger.Printf("Creating site reader for type: %s", siteType)
@@ -36,8 +55,8 @@ func GetSiteReader(siteType string, verbose bool, logger *log.Logger) (SiteReade
 		logger.Println("Using MkDocs site reader")
 		return &MkDocsReader{Logger: logger}, nil
 	case "hugo":
-		logger.Println("Hugo site type is not yet implemented")
-		return nil, fmt.Errorf("hugo site type is not yet implemented")
+		logger.Println("Using Hugo site reader")
+		return &HugoReader{Logger: logger}, nil
 	case "docusaurus":
 		logger.Println("Docusaurus site type is not yet implemented")
 		return nil, fmt.Errorf("docusaurus site type is not yet implemented")
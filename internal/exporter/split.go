@@ -0,0 +1,190 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/anchors"
+	"github.com/samzong/mdctl/internal/exporter/sitereader"
+)
+
+// Split modes of ExportDirectoryToDir
+const (
+	SplitByNav  = "nav"  // One document per top-level navigation entry
+	SplitByFile = "file" // One document per source file
+)
+
+// ExportDirectoryToDir exports the Markdown files of a directory as separate
+// documents into outputDir instead of one merged file. Split by nav, every
+// top-level navigation entry of a site, or every top-level file and
+// subdirectory of a basic directory, becomes a document named after it. Split
+// by file, every source file becomes a document at the same relative path.
+// It returns the written documents in order.
+func (e *DefaultExporter) ExportDirectoryToDir(ctx context.Context, inputDir, outputDir, splitBy string, options ExportOptions) ([]string, error) {
+	// Set logger
+	if options.Logger != nil {
+		e.logger = options.Logger
+	}
+
+	if splitBy != SplitByNav && splitBy != SplitByFile {
+		return nil, fmt.Errorf("unsupported split mode: %s (must be nav or file)", splitBy)
+	}
+	if _, err := os.Stat(inputDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("input directory does not exist: %s", inputDir)
+	}
+
+	if options.LinkRoot == "" {
+		options.LinkRoot = inputDir
+	}
+
+	sections, err := e.readSections(inputDir, splitBy, options)
+	if err != nil {
+		return nil, err
+	}
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("no markdown files found in directory: %s", inputDir)
+	}
+	e.logger.Printf("Exporting %d documents to: %s", len(sections), outputDir)
+
+	ext := "." + options.Format
+	var outputs []string
+	if splitBy == SplitByFile {
+		var files []string
+		for _, section := range sections {
+			files = append(files, section.Files...)
+		}
+		root := commonDir(files)
+		for _, file := range files {
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				rel = filepath.Base(file)
+			}
+			outputs = append(outputs, filepath.Join(outputDir, strings.TrimSuffix(rel, filepath.Ext(rel))+ext))
+		}
+		sections = sitereader.FileSections(files)
+	} else {
+		used := make(map[string]int)
+		for _, section := range sections {
+			name := anchors.Slug(section.Title, anchors.StyleGitHub)
+			if name == "" {
+				name = "section"
+			}
+			// Sections with the same title get numbered names
+			used[name]++
+			if used[name] > 1 {
+				name = fmt.Sprintf("%s-%d", name, used[name])
+			}
+			outputs = append(outputs, filepath.Join(outputDir, name+ext))
+		}
+	}
+
+	// Documents are exported by --jobs workers, each with an exporter of its
+	// own, and reported in order
+	plans := make([]*ExportPlan, len(sections))
+	exported, err := Queue{Workers: options.Jobs}.Run(ctx, len(sections), func(ctx context.Context, i int) error {
+		section := sections[i]
+		e.logger.Printf("Exporting document %d/%d: %s -> %s", i+1, len(sections), section.Title, outputs[i])
+
+		sectionOptions := options
+		if options.Plan != nil {
+			plans[i] = &ExportPlan{}
+			sectionOptions.Plan = plans[i]
+		}
+		job := &DefaultExporter{pandocPath: e.pandocPath, logger: e.logger}
+		if err := job.ExportFiles(ctx, section.Files, outputs[i], sectionOptions); err != nil {
+			return fmt.Errorf("failed to export %s: %w", section.Title, err)
+		}
+		return nil
+	})
+
+	var written []string
+	for i, ok := range exported {
+		if !ok {
+			continue
+		}
+		written = append(written, outputs[i])
+		if plan := options.Plan; plan != nil {
+			plan.Files = append(plan.Files, plans[i].Files...)
+			plan.Documents = append(plan.Documents, *plans[i])
+		}
+	}
+	return written, err
+}
+
+// readSections returns the groups of files that become one document each
+func (e *DefaultExporter) readSections(inputDir, splitBy string, options ExportOptions) ([]sitereader.Section, error) {
+	if options.SiteType != "" && options.SiteType != "basic" {
+		reader, err := sitereader.GetSiteReader(options.SiteType, options.Verbose, e.logger)
+		if err != nil {
+			return nil, err
+		}
+		if !reader.Detect(inputDir) {
+			return nil, fmt.Errorf("directory %s does not appear to be a %s site", inputDir, options.SiteType)
+		}
+		if splitBy == SplitByFile || len(options.NavPaths) > 0 {
+			// A navigation path selects files, which are then exported one by one
+			files, err := reader.ReadStructure(inputDir, "", options.NavPaths)
+			if err != nil {
+				return nil, err
+			}
+			return sitereader.FileSections(files), nil
+		}
+		return reader.ReadSections(inputDir, "")
+	}
+
+	files, err := GetMarkdownFilesInDir(inputDir)
+	if err != nil {
+		return nil, err
+	}
+	if splitBy == SplitByFile {
+		return sitereader.FileSections(files), nil
+	}
+
+	// Top-level files and directories of a basic directory are its sections,
+	// files keep the export order within a directory
+	var sections []sitereader.Section
+	index := make(map[string]int)
+	for _, file := range files {
+		rel, err := filepath.Rel(inputDir, file)
+		if err != nil {
+			return nil, err
+		}
+		parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+		if len(parts) == 1 {
+			sections = append(sections, sitereader.FileSections([]string{file})...)
+			continue
+		}
+		if i, ok := index[parts[0]]; ok {
+			sections[i].Files = append(sections[i].Files, file)
+			continue
+		}
+		index[parts[0]] = len(sections)
+		sections = append(sections, sitereader.Section{Title: parts[0], Files: []string{file}})
+	}
+	return sections, nil
+}
+
+// commonDir returns the deepest directory containing all files
+func commonDir(files []string) string {
+	if len(files) == 0 {
+		return "."
+	}
+	dir := filepath.Dir(files[0])
+	for _, file := range files[1:] {
+		for {
+			rel, err := filepath.Rel(dir, file)
+			if err == nil && !strings.HasPrefix(rel, "..") {
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				return dir
+			}
+			dir = parent
+		}
+	}
+	return dir
+}
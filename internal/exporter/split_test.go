@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportDirectoryToDirPlan(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"index.md", "guide/install.md", "guide/usage.md", "api/ref.md"} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("# "+name+"\n"), 0644)
+	}
+	out := filepath.Join(dir, "out")
+
+	tests := []struct {
+		splitBy string
+		want    []string
+	}{
+		{SplitByNav, []string{"api.pdf", "guide.pdf", "index.pdf"}},
+		{SplitByFile, []string{"api/ref.pdf", "guide/install.pdf", "guide/usage.pdf", "index.pdf"}},
+	}
+	for _, tt := range tests {
+		plan := &ExportPlan{}
+		options := ExportOptions{Format: "pdf", DryRun: true, Plan: plan}
+		outputs, err := NewExporter().ExportDirectoryToDir(context.Background(), dir, out, tt.splitBy, options)
+		if err != nil {
+			t.Fatalf("%s: ExportDirectoryToDir failed: %v", tt.splitBy, err)
+		}
+
+		var got []string
+		for _, output := range outputs {
+			rel, _ := filepath.Rel(out, output)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: unexpected outputs %v, want %v", tt.splitBy, got, tt.want)
+		}
+		if len(plan.Documents) != len(tt.want) || len(plan.Files) != 4 {
+			t.Errorf("%s: expected a plan per document covering all files, got %+v", tt.splitBy, plan)
+		}
+	}
+
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Error("expected a dry run not to create the output directory")
+	}
+}
+
+func TestGetMarkdownFilesInDirOrder(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.md":               "# A\n",
+		"b.md":               "---\nweight: 2\n---\n# B\n",
+		"c.md":               "---\norder: 1\n---\n# C\n",
+		"guide/_index.md":    "---\nweight: 3\n---\n",
+		"guide/install.md":   "# Install\n",
+		"guide/usage.md":     "# Usage\n",
+		"guide/_order.yaml":  "- usage\n- missing.md\n",
+		"ref/api.md":         "# API\n",
+		"ref/notes.txt":      "not markdown\n",
+		"z.md":               "---\ntitle: [broken\n---\n",
+		"_order.yaml":        "- ref/\n",
+		"assets/diagram.png": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+
+	got, err := GetMarkdownFilesInDir(dir)
+	if err != nil {
+		t.Fatalf("GetMarkdownFilesInDir failed: %v", err)
+	}
+	var rels []string
+	for _, file := range got {
+		rel, _ := filepath.Rel(dir, file)
+		rels = append(rels, filepath.ToSlash(rel))
+	}
+	want := "ref/api.md,c.md,b.md,guide/usage.md,guide/_index.md,guide/install.md,a.md,z.md"
+	if strings.Join(rels, ",") != want {
+		t.Errorf("order = %s, want %s", strings.Join(rels, ","), want)
+	}
+}
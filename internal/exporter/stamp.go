@@ -0,0 +1,68 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BuildStamp identifies how and from what an export was generated
+type BuildStamp struct {
+	Version string    // mdctl version
+	Commit  string    // Commit of the docs repository, empty outside git
+	Dirty   bool      // The sources have uncommitted changes
+	Date    time.Time // Generation date
+}
+
+// commit returns the abbreviated commit, marked when the tree is dirty
+func (s *BuildStamp) commit() string {
+	commit := s.Commit
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	if commit != "" && s.Dirty {
+		commit += "-dirty"
+	}
+	return commit
+}
+
+// String returns the footer line, e.g. "Generated by mdctl 1.4.0 from
+// 3f9c2a1b7d0e on 2026-10-16"
+func (s *BuildStamp) String() string {
+	var b strings.Builder
+	b.WriteString("Generated by mdctl")
+	if s.Version != "" {
+		b.WriteString(" " + s.Version)
+	}
+	if commit := s.commit(); commit != "" {
+		b.WriteString(" from " + commit)
+	}
+	if !s.Date.IsZero() {
+		b.WriteString(" on " + s.Date.Format("2006-01-02"))
+	}
+	return b.String()
+}
+
+// stampArgs returns the Pandoc metadata carrying the stamp, which DOCX output
+// stores as custom document properties. PDF output also gets the stamp in
+// its creator and subject properties.
+func stampArgs(options ExportOptions) []string {
+	stamp := options.Stamp
+	if stamp == nil {
+		return nil
+	}
+	args := []string{"-M", "mdctl-version=" + stamp.Version}
+	if commit := stamp.commit(); commit != "" {
+		args = append(args, "-M", "mdctl-commit="+commit)
+	}
+	if !stamp.Date.IsZero() {
+		args = append(args, "-M", "mdctl-generated="+stamp.Date.Format(time.RFC3339))
+	}
+	if options.Format == "pdf" {
+		// hyperref is loaded after the header includes of some templates, so
+		// the properties are set when the document starts
+		args = append(args, "-V", fmt.Sprintf(`header-includes=\AtBeginDocument{\hypersetup{pdfcreator={mdctl %s},pdfsubject={%s}}}`,
+			escapeLaTeX(stamp.Version), escapeLaTeX(stamp.String())))
+	}
+	return args
+}
@@ -0,0 +1,43 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildStamp(t *testing.T) {
+	stamp := &BuildStamp{
+		Version: "1.4.0",
+		Commit:  "3f9c2a1b7d0e5f6a7b8c9d0e1f2a3b4c5d6e7f80",
+		Dirty:   true,
+		Date:    time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC),
+	}
+	if got, want := stamp.String(), "Generated by mdctl 1.4.0 from 3f9c2a1b7d0e-dirty on 2026-10-16"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got, want := (&BuildStamp{Version: "dev"}).String(), "Generated by mdctl dev"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	args := strings.Join(stampArgs(ExportOptions{Format: "pdf", Stamp: stamp}), " ")
+	for _, want := range []string{"mdctl-version=1.4.0", "mdctl-commit=3f9c2a1b7d0e-dirty", "mdctl-generated=2026-10-16T09:30:00Z", `pdfcreator={mdctl 1.4.0}`} {
+		if !strings.Contains(args, want) {
+			t.Errorf("expected %q in %q", want, args)
+		}
+	}
+	if args := stampArgs(ExportOptions{Format: "docx", Stamp: stamp}); strings.Contains(strings.Join(args, " "), "hypersetup") {
+		t.Errorf("unexpected PDF properties in DOCX arguments: %v", args)
+	}
+	if args := stampArgs(ExportOptions{Format: "pdf"}); args != nil {
+		t.Errorf("expected no arguments without a stamp, got %v", args)
+	}
+
+	decorations := latexDecorationArgs(ExportOptions{Stamp: stamp})
+	if len(decorations) != 2 || !strings.Contains(decorations[1], `\fancyfoot[L]{\scriptsize Generated by mdctl 1.4.0`) {
+		t.Errorf("expected the stamp in the footer, got %v", decorations)
+	}
+	if footer := docxFooterXML("", stamp.String(), false); !strings.Contains(footer, "Generated by mdctl 1.4.0") {
+		t.Errorf("expected the stamp in the DOCX footer: %s", footer)
+	}
+}
@@ -0,0 +1,200 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/netutil"
+)
+
+// templateFormats maps template file extensions to the output format they style
+var templateFormats = map[string]string{
+	".docx":  "docx", // Word reference document
+	".latex": "pdf",  // LaTeX template
+	".css":   "epub", // Style sheet
+}
+
+// templateNameRegex matches valid template names
+var templateNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// TemplateInfo describes a template selectable with --theme
+type TemplateInfo struct {
+	Name        string   `json:"name"`
+	Formats     []string `json:"formats"`
+	Builtin     bool     `json:"builtin"`
+	Description string   `json:"description,omitempty"`
+}
+
+// TemplateStore keeps user templates as <name>.docx, <name>.latex and
+// <name>.css files in a directory
+type TemplateStore struct {
+	Dir    string
+	DryRun bool // Add and Fetch only return the path they would write, Fetch downloads nothing
+}
+
+// DefaultTemplateStore returns the store in the mdctl configuration directory
+func DefaultTemplateStore() *TemplateStore {
+	return &TemplateStore{Dir: filepath.Join(config.ConfigDir(), "templates")}
+}
+
+// List returns the built-in themes and the user templates sorted by name, a
+// user template hides the built-in theme of the same name
+func (s *TemplateStore) List() ([]TemplateInfo, error) {
+	templates := make(map[string]*TemplateInfo)
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read template directory: %v", err)
+	}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		format, ok := templateFormats[ext]
+		if entry.IsDir() || !ok {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ext)
+		if templates[name] == nil {
+			templates[name] = &TemplateInfo{Name: name}
+		}
+		templates[name].Formats = append(templates[name].Formats, format)
+	}
+	for _, theme := range BuiltinThemes() {
+		if templates[theme.Name] == nil {
+			templates[theme.Name] = &TemplateInfo{
+				Name:        theme.Name,
+				Formats:     []string{"docx", "epub", "pdf"},
+				Builtin:     true,
+				Description: theme.Description,
+			}
+		}
+	}
+
+	list := make([]TemplateInfo, 0, len(templates))
+	for _, info := range templates {
+		sort.Strings(info.Formats)
+		list = append(list, *info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Add copies a template file into the store, the name defaults to the file
+// name. It returns the stored path.
+func (s *TemplateStore) Add(file, name string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read template: %v", err)
+	}
+	return s.save(file, name, data)
+}
+
+// Fetch downloads a template into the store, the name defaults to the file
+// name of the URL. It returns the stored path.
+func (s *TemplateStore) Fetch(ctx context.Context, rawURL, name string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", fmt.Errorf("invalid template URL: %s", rawURL)
+	}
+	if s.DryRun {
+		return s.save(path.Base(u.Path), name, nil)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+	client := netutil.NewClient(60 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download template: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download template: %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to download template: %v", err)
+	}
+	return s.save(path.Base(u.Path), name, data)
+}
+
+// save writes template data under a name, the kind is taken from the
+// extension of file
+func (s *TemplateStore) save(file, name string, data []byte) (string, error) {
+	ext := strings.ToLower(filepath.Ext(file))
+	if ext == ".tex" {
+		ext = ".latex"
+	}
+	if _, ok := templateFormats[ext]; !ok {
+		return "", fmt.Errorf("unsupported template %s (use .docx, .latex, .tex or .css)", file)
+	}
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+	if !templateNameRegex.MatchString(name) {
+		return "", fmt.Errorf("invalid template name %q", name)
+	}
+
+	target := filepath.Join(s.Dir, name+ext)
+	if s.DryRun {
+		return target, nil
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create template directory: %v", err)
+	}
+	if err := os.WriteFile(target, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to save template: %v", err)
+	}
+	return target, nil
+}
+
+// PandocArgs returns the Pandoc arguments that apply a template or built-in
+// theme to an output format. Built-in themes are generated into the store's
+// theme cache unless dryRun is set.
+func (s *TemplateStore) PandocArgs(name, format string, dryRun bool) ([]string, error) {
+	for ext, f := range templateFormats {
+		if f != format {
+			continue
+		}
+		file := filepath.Join(s.Dir, name+ext)
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		switch ext {
+		case ".docx":
+			return []string{"--reference-doc", file}, nil
+		case ".latex":
+			return []string{"--template", file}, nil
+		default:
+			return []string{"--css", file}, nil
+		}
+	}
+
+	theme, ok := builtinThemes[name]
+	if !ok {
+		if _, err := s.find(name); err == nil {
+			return nil, fmt.Errorf("template %s has no %s template", name, format)
+		}
+		return nil, fmt.Errorf("unknown theme %s, see mdctl export templates list", name)
+	}
+	return theme.pandocArgs(format, filepath.Join(s.Dir, ".themes"), dryRun)
+}
+
+// find returns the stored files of a template
+func (s *TemplateStore) find(name string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.Dir, name+".*"))
+	if err != nil || len(files) == 0 {
+		return nil, fmt.Errorf("template %s not found", name)
+	}
+	return files, nil
+}
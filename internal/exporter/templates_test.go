@@ -0,0 +1,69 @@
+package exporter
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplateStore(t *testing.T) {
+	dir := t.TempDir()
+	store := &TemplateStore{Dir: filepath.Join(dir, "templates")}
+
+	src := filepath.Join(dir, "acme.tex")
+	os.WriteFile(src, []byte("$body$"), 0644)
+	if _, err := store.Add(src, ""); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, err := store.Add(src, "../escape"); err == nil {
+		t.Error("expected an error for an invalid name")
+	}
+
+	list, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	var names []string
+	for _, info := range list {
+		names = append(names, info.Name)
+	}
+	if strings.Join(names, ",") != "academic,acme,corporate,minimal" {
+		t.Errorf("unexpected templates: %v", names)
+	}
+
+	args, err := store.PandocArgs("acme", "pdf", false)
+	if err != nil || len(args) != 2 || args[0] != "--template" {
+		t.Errorf("expected the LaTeX template, got %v (%v)", args, err)
+	}
+	if _, err := store.PandocArgs("acme", "docx", false); err == nil {
+		t.Error("expected an error for a missing format")
+	}
+	if _, err := store.PandocArgs("unknown", "docx", false); err == nil {
+		t.Error("expected an error for an unknown theme")
+	}
+}
+
+func TestBuiltinThemeReferenceDocx(t *testing.T) {
+	store := &TemplateStore{Dir: t.TempDir()}
+	args, err := store.PandocArgs("corporate", "docx", false)
+	if err != nil {
+		t.Fatalf("PandocArgs failed: %v", err)
+	}
+
+	r, err := zip.OpenReader(args[1])
+	if err != nil {
+		t.Fatalf("reference document is not a zip: %v", err)
+	}
+	defer r.Close()
+	found := false
+	for _, f := range r.File {
+		if f.Name == "word/styles.xml" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected word/styles.xml in the reference document")
+	}
+}
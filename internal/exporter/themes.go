@@ -0,0 +1,221 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Theme is a built-in export style. Reference documents and style sheets are
+// generated from it, so no binary templates ship with mdctl.
+type Theme struct {
+	Name         string
+	Description  string
+	BodyFont     string  // Font family of body text
+	HeadingFont  string  // Font family of titles and headings
+	FontSize     int     // Body font size in points, 10 to 12
+	LineSpacing  float64 // Line height as a multiple of the font size
+	HeadingColor string  // Hex RGB color of titles and headings
+	LinkColor    string  // Hex RGB color of hyperlinks
+	LaTeXColor   string  // dvipsnames color of hyperlinks in PDF output
+	Margin       float64 // Page margin in inches
+}
+
+// builtinThemes are the themes selectable with --theme without adding templates
+var builtinThemes = map[string]Theme{
+	"corporate": {
+		Name:         "corporate",
+		Description:  "Sans-serif business documents with blue headings",
+		BodyFont:     "Calibri",
+		HeadingFont:  "Calibri Light",
+		FontSize:     11,
+		LineSpacing:  1.15,
+		HeadingColor: "1F4E79",
+		LinkColor:    "0563C1",
+		LaTeXColor:   "NavyBlue",
+		Margin:       1,
+	},
+	"academic": {
+		Name:         "academic",
+		Description:  "Serif papers with generous line spacing",
+		BodyFont:     "Times New Roman",
+		HeadingFont:  "Times New Roman",
+		FontSize:     12,
+		LineSpacing:  1.5,
+		HeadingColor: "000000",
+		LinkColor:    "000000",
+		LaTeXColor:   "Black",
+		Margin:       1,
+	},
+	"minimal": {
+		Name:         "minimal",
+		Description:  "Compact sans-serif layout with narrow margins",
+		BodyFont:     "Arial",
+		HeadingFont:  "Arial",
+		FontSize:     10,
+		LineSpacing:  1.2,
+		HeadingColor: "333333",
+		LinkColor:    "333333",
+		LaTeXColor:   "Gray",
+		Margin:       0.75,
+	},
+}
+
+// BuiltinThemes returns the built-in themes sorted by name
+func BuiltinThemes() []Theme {
+	themes := make([]Theme, 0, len(builtinThemes))
+	for _, theme := range builtinThemes {
+		themes = append(themes, theme)
+	}
+	sort.Slice(themes, func(i, j int) bool { return themes[i].Name < themes[j].Name })
+	return themes
+}
+
+// pandocArgs returns the Pandoc arguments that apply the theme to a format.
+// Generated files are written to dir unless dryRun is set.
+func (t Theme) pandocArgs(format, dir string, dryRun bool) ([]string, error) {
+	switch format {
+	case "docx":
+		path := filepath.Join(dir, t.Name+".docx")
+		if !dryRun {
+			if err := t.writeFile(path, t.referenceDocx); err != nil {
+				return nil, err
+			}
+		}
+		return []string{"--reference-doc", path}, nil
+	case "epub":
+		path := filepath.Join(dir, t.Name+".css")
+		if !dryRun {
+			if err := t.writeFile(path, func() ([]byte, error) { return []byte(t.css()), nil }); err != nil {
+				return nil, err
+			}
+		}
+		return []string{"--css", path}, nil
+	case "pdf":
+		return []string{
+			"-V", fmt.Sprintf("fontsize=%dpt", t.FontSize),
+			"-V", fmt.Sprintf("linestretch=%g", t.LineSpacing),
+			"-V", fmt.Sprintf("geometry=margin=%gin", t.Margin),
+			"-V", "colorlinks=true",
+			"-V", "linkcolor=" + t.LaTeXColor,
+			"-V", "urlcolor=" + t.LaTeXColor,
+		}, nil
+	}
+	return nil, fmt.Errorf("theme %s does not support format %s", t.Name, format)
+}
+
+// writeFile writes a generated file
+func (t Theme) writeFile(path string, generate func() ([]byte, error)) error {
+	data, err := generate()
+	if err != nil {
+		return fmt.Errorf("failed to generate theme %s: %v", t.Name, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create theme directory: %v", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// css returns the EPUB style sheet of the theme
+func (t Theme) css() string {
+	return fmt.Sprintf(`body { font-family: %q, sans-serif; font-size: %dpt; line-height: %g; }
+h1, h2, h3, h4, h5, h6, .title { font-family: %q, sans-serif; color: #%s; }
+a { color: #%s; }
+code, pre { font-family: monospace; font-size: 0.9em; }
+pre { padding: 0.5em; background: #f5f5f5; white-space: pre-wrap; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.5em; }
+img { max-width: 100%%; }
+`, t.BodyFont, t.FontSize, t.LineSpacing, t.HeadingFont, t.HeadingColor, t.LinkColor)
+}
+
+// referenceDocx builds a minimal reference document. Pandoc takes the styles
+// and page setup from it and the remaining parts from its default.
+func (t Theme) referenceDocx() ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRels},
+		{"word/_rels/document.xml.rels", docxDocumentRels},
+		{"word/document.xml", t.documentXML()},
+		{"word/styles.xml", t.stylesXML()},
+	}
+	for _, part := range parts {
+		w, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// documentXML returns an empty document with the page setup of the theme
+func (t Theme) documentXML() string {
+	margin := int(t.Margin * 1440) // Twentieths of a point
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body><w:p/>`+
+		`<w:sectPr><w:pgSz w:w="12240" w:h="15840"/><w:pgMar w:top="%d" w:right="%d" w:bottom="%d" w:left="%d" w:header="720" w:footer="720" w:gutter="0"/></w:sectPr>`+
+		`</w:body></w:document>`, margin, margin, margin, margin)
+}
+
+// stylesXML returns the paragraph and character styles of the theme
+func (t Theme) stylesXML() string {
+	fonts := func(font string) string {
+		return fmt.Sprintf(`<w:rFonts w:ascii=%q w:hAnsi=%q w:eastAsia=%q w:cs=%q/>`, font, font, font, font)
+	}
+	size := t.FontSize * 2 // Half points
+	line := int(t.LineSpacing * 240)
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">`)
+	fmt.Fprintf(&b, `<w:docDefaults><w:rPrDefault><w:rPr>%s<w:sz w:val="%d"/><w:szCs w:val="%d"/></w:rPr></w:rPrDefault>`+
+		`<w:pPrDefault><w:pPr><w:spacing w:after="160" w:line="%d" w:lineRule="auto"/></w:pPr></w:pPrDefault></w:docDefaults>`,
+		fonts(t.BodyFont), size, size, line)
+	b.WriteString(`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:qFormat/></w:style>`)
+	b.WriteString(`<w:style w:type="paragraph" w:styleId="BodyText"><w:name w:val="Body Text"/><w:basedOn w:val="Normal"/><w:qFormat/></w:style>`)
+	b.WriteString(`<w:style w:type="paragraph" w:styleId="FirstParagraph"><w:name w:val="First Paragraph"/><w:basedOn w:val="BodyText"/><w:next w:val="BodyText"/><w:qFormat/></w:style>`)
+	b.WriteString(`<w:style w:type="paragraph" w:styleId="Compact"><w:name w:val="Compact"/><w:basedOn w:val="BodyText"/><w:qFormat/><w:pPr><w:spacing w:before="36" w:after="36"/></w:pPr></w:style>`)
+	fmt.Fprintf(&b, `<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:next w:val="BodyText"/><w:qFormat/>`+
+		`<w:pPr><w:keepNext/><w:spacing w:before="480" w:after="240"/><w:jc w:val="center"/></w:pPr><w:rPr>%s<w:b/><w:color w:val="%s"/><w:sz w:val="%d"/><w:szCs w:val="%d"/></w:rPr></w:style>`,
+		fonts(t.HeadingFont), t.HeadingColor, size*2+8, size*2+8)
+	for level, scale := range []int{16, 10, 6, 4} {
+		fmt.Fprintf(&b, `<w:style w:type="paragraph" w:styleId="Heading%d"><w:name w:val="heading %d"/><w:basedOn w:val="Normal"/><w:next w:val="BodyText"/><w:uiPriority w:val="9"/><w:qFormat/>`+
+			`<w:pPr><w:keepNext/><w:keepLines/><w:spacing w:before="%d" w:after="80"/><w:outlineLvl w:val="%d"/></w:pPr><w:rPr>%s<w:b/><w:color w:val="%s"/><w:sz w:val="%d"/><w:szCs w:val="%d"/></w:rPr></w:style>`,
+			level+1, level+1, 360-level*60, level, fonts(t.HeadingFont), t.HeadingColor, size+scale, size+scale)
+	}
+	fmt.Fprintf(&b, `<w:style w:type="character" w:styleId="Hyperlink"><w:name w:val="Hyperlink"/><w:rPr><w:color w:val="%s"/><w:u w:val="single"/></w:rPr></w:style>`, t.LinkColor)
+	b.WriteString(`</w:styles>`)
+	return b.String()
+}
+
+const (
+	docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+		`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>` +
+		`</Types>`
+
+	docxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+		`</Relationships>`
+
+	docxDocumentRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+)
@@ -0,0 +1,87 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// slugRegex matches the characters replaced in file names derived from titles
+var slugRegex = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// Slugify turns a title into a lowercase file name, runs of characters other
+// than letters and digits become a single hyphen
+func Slugify(title string) string {
+	return strings.Trim(slugRegex.ReplaceAllString(strings.ToLower(title), "-"), "-")
+}
+
+// WriteFileAtomic writes data to a temporary file next to path and renames it
+// into place, so an interrupted write never leaves a half-written file behind
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	target := LongPath(path)
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write temporary file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to close temporary file: %v", err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to set file permissions: %v", err)
+	}
+	if err := os.Rename(tmpPath, target); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %v", path, err)
+	}
+
+	return nil
+}
+
+var (
+	// lockRetry is how often a held lock file is tried again
+	lockRetry = 10 * time.Millisecond
+	// lockTimeout is how long LockFile waits for a lock held by another process
+	lockTimeout = 30 * time.Second
+	// lockStale is the age of a lock file left behind by a crashed process
+	lockStale = 10 * time.Second
+)
+
+// LockFile takes an exclusive lock on path shared between processes by
+// creating path.lock, waiting while another process holds it. A lock file
+// older than lockStale is taken over. The returned function releases the
+// lock; hold it only for short read-modify-write cycles.
+func LockFile(path string) (func(), error) {
+	lockPath := LongPath(path + ".lock")
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock %s: %v", path, err)
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for the lock %s", lockPath)
+		}
+		time.Sleep(lockRetry)
+	}
+}
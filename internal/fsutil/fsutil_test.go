@@ -0,0 +1,39 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	defer func(timeout, stale time.Duration) { lockTimeout, lockStale = timeout, stale }(lockTimeout, lockStale)
+	lockTimeout, lockStale = 50*time.Millisecond, time.Hour
+
+	path := filepath.Join(t.TempDir(), "usage.json")
+	unlock, err := LockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LockFile(path); err == nil {
+		t.Fatal("expected a held lock to time out")
+	}
+	unlock()
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Fatalf("lock file not removed: %v", err)
+	}
+
+	// A lock file left by a crashed process is taken over once stale
+	if _, err := LockFile(path); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Minute)
+	os.Chtimes(path+".lock", old, old)
+	lockStale = time.Second
+	unlock, err = LockFile(path)
+	if err != nil {
+		t.Fatalf("expected the stale lock to be taken over: %v", err)
+	}
+	unlock()
+}
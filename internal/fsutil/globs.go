@@ -0,0 +1,30 @@
+package fsutil
+
+import (
+	"fmt"
+
+	"github.com/gobwas/glob"
+)
+
+// CompileGlobs compiles file globs, "*" stops at slashes and "**" does not
+func CompileGlobs(patterns []string) ([]glob.Glob, error) {
+	var matchers []glob.Glob
+	for _, pattern := range patterns {
+		matcher, err := glob.Compile(pattern, '/')
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
+
+// MatchAny reports whether any of the globs matches a slash-separated path
+func MatchAny(matchers []glob.Glob, path string) bool {
+	for _, m := range matchers {
+		if m.Match(path) {
+			return true
+		}
+	}
+	return false
+}
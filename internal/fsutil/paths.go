@@ -0,0 +1,47 @@
+package fsutil
+
+import (
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// maxPath is the length from which Windows needs the extended-length form of
+// a path, 248 rather than 260 since directories must leave room for a file
+// name
+const maxPath = 248
+
+// RemotePath joins the elements of an object key or URL path with forward
+// slashes, backslashes of Windows paths included
+func RemotePath(elem ...string) string {
+	for i, e := range elem {
+		elem[i] = strings.ReplaceAll(e, `\`, "/")
+	}
+	return path.Join(elem...)
+}
+
+// LongPath returns a local path that can be opened when it is longer than
+// the Windows path limit, the \\?\ extended-length form of its absolute path.
+// Other paths and systems get the path unchanged.
+func LongPath(p string) string {
+	return longPath(p, runtime.GOOS)
+}
+
+func longPath(p, goos string) string {
+	if goos != "windows" || len(p) < maxPath || strings.HasPrefix(p, `\\?\`) {
+		return p
+	}
+	// Extended-length paths are not normalized by Windows, so they must be
+	// absolute and clean
+	if goos == runtime.GOOS {
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+	}
+	p = strings.ReplaceAll(p, "/", `\`)
+	if strings.HasPrefix(p, `\\`) {
+		return `\\?\UNC\` + p[2:]
+	}
+	return `\\?\` + p
+}
@@ -0,0 +1,62 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemotePath(t *testing.T) {
+	tests := []struct {
+		elem []string
+		want string
+	}{
+		{[]string{"images", "logo.png"}, "images/logo.png"},
+		{[]string{`images\2024`, "logo.png"}, "images/2024/logo.png"},
+		{[]string{"prefix/", `docs\img\a.png`}, "prefix/docs/img/a.png"},
+		{[]string{"", "a.png"}, "a.png"},
+	}
+	for _, tt := range tests {
+		if got := RemotePath(tt.elem...); got != tt.want {
+			t.Errorf("RemotePath(%q) = %q, want %q", tt.elem, got, tt.want)
+		}
+	}
+}
+
+func TestLongPath(t *testing.T) {
+	long := `C:\docs\` + strings.Repeat(`section\`, 40) + "page.md"
+	if got := longPath(long, "windows"); got != `\\?\`+long {
+		t.Errorf("unexpected drive path: %s", got)
+	}
+	unc := `\\server\share\` + strings.Repeat("a/", 130) + "page.md"
+	if got := longPath(unc, "windows"); !strings.HasPrefix(got, `\\?\UNC\server\share\a\a\`) || strings.Contains(got, "/") {
+		t.Errorf("unexpected UNC path: %s", got)
+	}
+	if got := longPath(`\\?\`+long, "windows"); got != `\\?\`+long {
+		t.Errorf("extended-length paths should stay unchanged: %s", got)
+	}
+	if got := longPath(`C:\docs\page.md`, "windows"); got != `C:\docs\page.md` {
+		t.Errorf("short paths should stay unchanged: %s", got)
+	}
+	if got := longPath(long, "linux"); got != long {
+		t.Errorf("paths on other systems should stay unchanged: %s", got)
+	}
+}
+
+func TestWriteFileAtomicLongPath(t *testing.T) {
+	dir := t.TempDir()
+	for len(dir) < 300 {
+		dir = filepath.Join(dir, strings.Repeat("d", 50))
+	}
+	if err := os.MkdirAll(LongPath(dir), 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	file := filepath.Join(dir, "page.md")
+	if err := WriteFileAtomic(file, []byte("# Page\n"), 0644); err != nil {
+		t.Fatalf("WriteFileAtomic failed: %v", err)
+	}
+	if data, err := os.ReadFile(LongPath(file)); err != nil || string(data) != "# Page\n" {
+		t.Errorf("unexpected content %q (%v)", data, err)
+	}
+}
@@ -0,0 +1,92 @@
+// Package gitref extracts the files of a git repository as of a ref
+package gitref
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/samzong/mdctl/internal/gitrepo"
+)
+
+// Snapshot is a temporary copy of a path of a repository at a commit
+type Snapshot struct {
+	Dir    string // Root of the copy
+	Path   string // The requested path inside the copy
+	Commit string // Full hash of the commit
+}
+
+// Checkout copies path, a file or directory of a git repository, as of ref
+// (a tag, branch or commit) into a temporary directory at the same place
+// relative to the top level. The rest of the tree is not copied. The working
+// tree and the repository are left untouched. Close removes the copy.
+func Checkout(path, ref string) (*Snapshot, error) {
+	repo, err := gitrepo.Open(dirOf(path))
+	if err != nil {
+		return nil, err
+	}
+	rel, err := repo.Rel(path)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.Commit(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.MkdirTemp("", "mdctl-gitref-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	snapshot := &Snapshot{Dir: tmp, Path: filepath.Join(tmp, filepath.FromSlash(rel)), Commit: commit.Hash.String()}
+
+	// Only the requested path is written
+	if err := gitrepo.WriteFiles(commit, rel, tmp); err != nil {
+		snapshot.Close()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s does not exist at %s", rel, ref)
+		}
+		return nil, fmt.Errorf("failed to extract %s: %v", ref, err)
+	}
+	return snapshot, nil
+}
+
+// Head returns the commit checked out in the repository containing path and
+// whether path has uncommitted changes
+func Head(path string) (string, bool, error) {
+	repo, err := gitrepo.Open(dirOf(path))
+	if err != nil {
+		return "", false, err
+	}
+	rel, err := repo.Rel(path)
+	if err != nil {
+		return "", false, err
+	}
+	commit, err := repo.Head()
+	if err != nil {
+		return "", false, err
+	}
+	if commit == nil {
+		return "", false, fmt.Errorf("no commit checked out in %s", repo.Root)
+	}
+	dirty, err := repo.Dirty(rel)
+	if err != nil {
+		return "", false, err
+	}
+	return commit.Hash.String(), dirty, nil
+}
+
+// Close removes the copy
+func (s *Snapshot) Close() error {
+	return os.RemoveAll(s.Dir)
+}
+
+// dirOf returns path if it is a directory and its parent otherwise
+func dirOf(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return path
+	}
+	return filepath.Dir(path)
+}
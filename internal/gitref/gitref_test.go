@@ -0,0 +1,126 @@
+package gitref
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestCheckout(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := func(message string) {
+		t.Helper()
+		if err := wt.AddGlob("."); err != nil {
+			t.Fatal(err)
+		}
+		signature := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+		hash, err := wt.Commit(message, &git.CommitOptions{Author: signature})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.CreateTag(message, hash, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("docs/index.md", "v1\n")
+	write("docs/guide/setup.md", "setup\n")
+	write("README.md", "readme\n")
+	commit("v1.0.0")
+	write("docs/index.md", "v2\n")
+	write("docs/new.md", "new\n")
+	commit("v2.0.0")
+
+	head, dirty, err := Head(filepath.Join(dir, "docs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(head) < 40 || dirty {
+		t.Errorf("Head() = %q, %v, want a full hash of a clean tree", head, dirty)
+	}
+
+	// Changes outside the path do not count
+	write("README.md", "uncommitted\n")
+	if _, dirty, err := Head(filepath.Join(dir, "docs")); err != nil || dirty {
+		t.Errorf("Head() dirty = %v, %v, want false", dirty, err)
+	}
+	write("docs/index.md", "uncommitted\n")
+	if _, dirty, err := Head(filepath.Join(dir, "docs")); err != nil || !dirty {
+		t.Errorf("Head() dirty = %v, %v, want true", dirty, err)
+	}
+
+	snapshot, err := Checkout(filepath.Join(dir, "docs"), "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(snapshot.Path, "index.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "v1\n" {
+		t.Errorf("index.md = %q, want %q", content, "v1\n")
+	}
+	if content, _ := os.ReadFile(filepath.Join(snapshot.Path, "guide", "setup.md")); string(content) != "setup\n" {
+		t.Errorf("guide/setup.md = %q, want %q", content, "setup\n")
+	}
+	if _, err := os.Stat(filepath.Join(snapshot.Path, "new.md")); !os.IsNotExist(err) {
+		t.Errorf("new.md exists in the snapshot of v1.0.0")
+	}
+	// Only the requested path is copied
+	if _, err := os.Stat(filepath.Join(snapshot.Dir, "README.md")); !os.IsNotExist(err) {
+		t.Errorf("README.md outside docs exists in the snapshot")
+	}
+	if len(snapshot.Commit) < 40 {
+		t.Errorf("Commit = %q, want a full hash", snapshot.Commit)
+	}
+
+	// The working tree is untouched
+	if content, _ := os.ReadFile(filepath.Join(dir, "docs", "index.md")); string(content) != "uncommitted\n" {
+		t.Errorf("working tree changed: %q", content)
+	}
+
+	if err := snapshot.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(snapshot.Dir); !os.IsNotExist(err) {
+		t.Errorf("snapshot directory not removed")
+	}
+
+	if _, err := Checkout(filepath.Join(dir, "docs"), "v9.9.9"); err == nil {
+		t.Error("expected an error for an unknown ref")
+	}
+	if _, err := Checkout(filepath.Join(dir, "docs", "new.md"), "v1.0.0"); err == nil {
+		t.Error("expected an error for a path missing at the ref")
+	}
+
+	// A single file
+	snapshot, err = Checkout(filepath.Join(dir, "README.md"), "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Close()
+	if content, _ := os.ReadFile(snapshot.Path); string(content) != "readme\n" {
+		t.Errorf("README.md = %q, want %q", content, "readme\n")
+	}
+}
@@ -0,0 +1,383 @@
+// Package gitrepo reads commits, the index and the work tree of git
+// repositories with go-git, so no git binary has to be installed
+package gitrepo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// Repo is a git repository with a work tree
+type Repo struct {
+	Root string // Top level of the work tree
+
+	repo *git.Repository
+}
+
+// Open opens the repository whose work tree contains path
+func Open(path string) (*Repo, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := git.PlainOpenWithOptions(abs, &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+	if err != nil {
+		if errors.Is(err, git.ErrRepositoryNotExists) {
+			return nil, fmt.Errorf("%s is not inside a git repository", path)
+		}
+		return nil, fmt.Errorf("failed to open git repository: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git work tree: %v", err)
+	}
+	return &Repo{Root: wt.Filesystem.Root(), repo: repo}, nil
+}
+
+// Rel returns path relative to the top level with forward slashes, "." for
+// the top level itself
+func (r *Repo) Rel(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(r.Root, abs)
+	if err != nil || !filepath.IsLocal(rel) && rel != "." {
+		return "", fmt.Errorf("%s is not inside the git repository %s", path, r.Root)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Commit resolves a ref, e.g. a branch, tag, commit or HEAD~1, to a commit
+func (r *Repo) Commit(ref string) (*object.Commit, error) {
+	hash, err := r.repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, fmt.Errorf("unknown git ref: %s", ref)
+	}
+	commit, err := r.repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %v", ref, err)
+	}
+	return commit, nil
+}
+
+// Head returns the commit checked out, nil before the first commit
+func (r *Repo) Head() (*object.Commit, error) {
+	ref, err := r.repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD: %v", err)
+	}
+	commit, err := r.repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %v", ref.Hash(), err)
+	}
+	return commit, nil
+}
+
+// TreeFiles returns the blob hashes of the files below prefix in the tree
+// of commit, by path relative to the top level. A nil commit has no files.
+func TreeFiles(commit *object.Commit, prefix string) (map[string]plumbing.Hash, error) {
+	files := make(map[string]plumbing.Hash)
+	if commit == nil {
+		return files, nil
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of %s: %v", commit.Hash, err)
+	}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if Below(f.Name, prefix) {
+			files[f.Name] = f.Hash
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of %s: %v", commit.Hash, err)
+	}
+	return files, nil
+}
+
+// IndexFiles returns the blob hashes of the files below prefix staged in the
+// index, by path relative to the top level
+func (r *Repo) IndexFiles(prefix string) (map[string]plumbing.Hash, error) {
+	idx, err := r.repo.Storer.Index()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git index: %v", err)
+	}
+	files := make(map[string]plumbing.Hash)
+	for _, entry := range idx.Entries {
+		if entry.Mode != filemode.Submodule && !entry.IntentToAdd && Below(entry.Name, prefix) {
+			files[entry.Name] = entry.Hash
+		}
+	}
+	return files, nil
+}
+
+// WorktreeFiles returns the blob hashes of the tracked files below prefix as
+// they are in the work tree, by path relative to the top level. Files that
+// were removed from the work tree are left out.
+func (r *Repo) WorktreeFiles(prefix string) (map[string]plumbing.Hash, error) {
+	idx, err := r.repo.Storer.Index()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git index: %v", err)
+	}
+	files := make(map[string]plumbing.Hash)
+	for _, entry := range idx.Entries {
+		if entry.Mode == filemode.Submodule || !Below(entry.Name, prefix) {
+			continue
+		}
+		hash, ok, err := r.hashFile(entry.Name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			files[entry.Name] = hash
+		}
+	}
+	return files, nil
+}
+
+// hashFile returns the blob hash of a file of the work tree, false when it
+// does not exist
+func (r *Repo) hashFile(name string) (plumbing.Hash, bool, error) {
+	info, err := os.Lstat(filepath.Join(r.Root, filepath.FromSlash(name)))
+	if err != nil || info.IsDir() {
+		return plumbing.ZeroHash, false, nil
+	}
+	data, err := r.readFile(name)
+	if err != nil {
+		return plumbing.ZeroHash, false, err
+	}
+	return plumbing.ComputeHash(plumbing.BlobObject, data), true, nil
+}
+
+// readFile returns the blob content of a file of the work tree, symlinks are
+// read as their target like git stores them
+func (r *Repo) readFile(name string) ([]byte, error) {
+	path := filepath.Join(r.Root, filepath.FromSlash(name))
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		return []byte(filepath.ToSlash(target)), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return data, nil
+}
+
+// Renames returns the tracked files below prefix that were renamed between
+// commit and the work tree as old and new paths relative to the top level,
+// sorted by the old path. Renames are detected by content like git diff -M.
+func (r *Repo) Renames(commit *object.Commit, prefix string) ([][2]string, error) {
+	old, err := TreeFiles(commit, prefix)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := r.WorktreeFiles(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes object.Changes
+	if len(old) > 0 {
+		tree, err := commit.Tree()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tree of %s: %v", commit.Hash, err)
+		}
+		for name, hash := range old {
+			if _, ok := cur[name]; !ok {
+				entry := object.TreeEntry{Name: name, Mode: filemode.Regular, Hash: hash}
+				changes = append(changes, &object.Change{From: object.ChangeEntry{Name: name, Tree: tree, TreeEntry: entry}})
+			}
+		}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	// The detector reads the new files as blobs, they are kept in memory
+	// instead of writing objects into the repository
+	storage := memory.NewStorage()
+	added := &object.Tree{}
+	for name, hash := range cur {
+		if _, ok := old[name]; ok {
+			continue
+		}
+		data, err := r.readFile(name)
+		if err != nil {
+			return nil, err
+		}
+		blob := storage.NewEncodedObject()
+		blob.SetType(plumbing.BlobObject)
+		writer, err := blob.Writer()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		writer.Close()
+		if _, err := storage.SetEncodedObject(blob); err != nil {
+			return nil, err
+		}
+		added.Entries = append(added.Entries, object.TreeEntry{Name: name, Mode: filemode.Regular, Hash: hash})
+	}
+	encoded := storage.NewEncodedObject()
+	if err := added.Encode(encoded); err != nil {
+		return nil, err
+	}
+	if added, err = object.DecodeTree(storage, encoded); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range added.Entries {
+		changes = append(changes, &object.Change{To: object.ChangeEntry{Name: entry.Name, Tree: added, TreeEntry: entry}})
+	}
+
+	// git diff -M considers files renamed from 50% similarity
+	changes, err = object.DetectRenames(changes, &object.DiffTreeOptions{DetectRenames: true, RenameScore: 50})
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect renames: %v", err)
+	}
+	var renames [][2]string
+	for _, change := range changes {
+		if change.From.Name != "" && change.To.Name != "" {
+			renames = append(renames, [2]string{change.From.Name, change.To.Name})
+		}
+	}
+	sort.Slice(renames, func(i, j int) bool { return renames[i][0] < renames[j][0] })
+	return renames, nil
+}
+
+// Dirty reports whether the files below prefix have changes that are not
+// committed, untracked files included
+func (r *Repo) Dirty(prefix string) (bool, error) {
+	wt, err := r.repo.Worktree()
+	if err != nil {
+		return false, fmt.Errorf("failed to open git work tree: %v", err)
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return false, fmt.Errorf("failed to read git status: %v", err)
+	}
+	for name, s := range status {
+		if Below(name, prefix) && (s.Staging != git.Unmodified || s.Worktree != git.Unmodified) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// WriteFiles writes the files below prefix in the tree of commit below dir,
+// at their paths relative to the top level. The error wraps fs.ErrNotExist
+// when prefix is not in the tree.
+func WriteFiles(commit *object.Commit, prefix, dir string) error {
+	tree, err := commit.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to read tree of %s: %v", commit.Hash, err)
+	}
+	if prefix != "." {
+		entry, err := tree.FindEntry(prefix)
+		if err != nil {
+			return fmt.Errorf("%s: %w", prefix, fs.ErrNotExist)
+		}
+		if entry.Mode != filemode.Dir {
+			file, err := tree.TreeEntryFile(entry)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %v", prefix, err)
+			}
+			return writeFile(file, prefix, dir)
+		}
+		if tree, err = tree.Tree(prefix); err != nil {
+			return fmt.Errorf("failed to read %s: %v", prefix, err)
+		}
+		if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(prefix)), 0755); err != nil {
+			return err
+		}
+	}
+	return tree.Files().ForEach(func(f *object.File) error {
+		return writeFile(f, path.Join(prefix, f.Name), dir)
+	})
+}
+
+// writeFile writes a file of a tree to name below dir
+func writeFile(f *object.File, name, dir string) error {
+	name = filepath.FromSlash(name)
+	if !filepath.IsLocal(name) {
+		return fmt.Errorf("invalid path in tree: %s", f.Name)
+	}
+	target := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	reader, err := f.Reader()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", name, err)
+	}
+	defer reader.Close()
+	if f.Mode == filemode.Symlink {
+		link, err := io.ReadAll(reader)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", name, err)
+		}
+		return os.Symlink(string(link), target)
+	}
+
+	perm := os.FileMode(0644)
+	if f.Mode == filemode.Executable {
+		perm = 0755
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Changed returns the paths of files whose hash in cur differs from old,
+// sorted. Files only in old, which were deleted, are left out.
+func Changed(old, cur map[string]plumbing.Hash) []string {
+	var names []string
+	for name, hash := range cur {
+		if old[name] != hash {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Below reports whether name is prefix or a path below it, every path is
+// below "."
+func Below(name, prefix string) bool {
+	return prefix == "." || name == prefix || strings.HasPrefix(name, prefix+"/")
+}
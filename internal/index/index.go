@@ -0,0 +1,323 @@
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Dir is the directory, relative to the indexed root, that holds the index
+	Dir = ".mdctl"
+	// fileName is the index file inside Dir
+	fileName = "index.json"
+	// version is bumped whenever the entry format changes
+	version = "1"
+)
+
+var (
+	logger = logging.New("INDEX")
+
+	// skipDirs are never descended into while building an index
+	skipDirs = map[string]bool{
+		Dir:            true,
+		".git":         true,
+		"node_modules": true,
+	}
+)
+
+// Heading is a single ATX heading of a file
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+}
+
+// Entry holds what is known about a single markdown file
+type Entry struct {
+	Path        string                 `json:"path"`
+	Size        int64                  `json:"size"`
+	ModTime     time.Time              `json:"mod_time"`
+	Hash        string                 `json:"hash"`
+	FrontMatter map[string]interface{} `json:"front_matter,omitempty"`
+	Headings    []Heading              `json:"headings,omitempty"`
+	Links       []string               `json:"links,omitempty"`
+	Images      []string               `json:"images,omitempty"`
+}
+
+// Index is a persistent snapshot of the markdown files below a root directory
+type Index struct {
+	Version string            `json:"version"`
+	Root    string            `json:"root"`
+	BuiltAt time.Time         `json:"built_at"`
+	Files   map[string]*Entry `json:"files"`
+}
+
+// Stats describes how an index changed while building it
+type Stats struct {
+	Total     int `json:"total"`
+	Added     int `json:"added"`
+	Updated   int `json:"updated"`
+	Removed   int `json:"removed"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Path returns the index file location for a root directory
+func Path(root string) string {
+	return filepath.Join(root, Dir, fileName)
+}
+
+// Load reads the index of root, returning nil if none has been built
+func Load(root string) (*Index, error) {
+	data, err := os.ReadFile(Path(root))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %v", err)
+	}
+
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("failed to parse index: %v", err)
+	}
+	if idx.Version != version {
+		// Indexes written by other versions are rebuilt from scratch
+		return nil, nil
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	idx.Root = absRoot
+	if idx.Files == nil {
+		idx.Files = make(map[string]*Entry)
+	}
+
+	return &idx, nil
+}
+
+// Find loads the index of dir or of its closest ancestor that has one,
+// returning nil if there is none
+func Find(dir string) (*Index, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, err := os.Stat(Path(absDir)); err == nil {
+			return Load(absDir)
+		}
+		parent := filepath.Dir(absDir)
+		if parent == absDir {
+			return nil, nil
+		}
+		absDir = parent
+	}
+}
+
+// Build indexes all markdown files below root. Entries of previous whose size
+// and modification time are unchanged are reused without reading the file.
+func Build(root string, previous *Index) (*Index, Stats, error) {
+	var stats Stats
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, stats, fmt.Errorf("failed to get absolute path: %v", err)
+	}
+
+	idx := &Index{
+		Version: version,
+		Root:    absRoot,
+		BuiltAt: time.Now(),
+		Files:   make(map[string]*Entry),
+	}
+
+	var pending []string
+	err = filepath.Walk(absRoot, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != absRoot && skipDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !mddoc.IsMarkdown(path) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(absRoot, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if previous != nil {
+			if entry, ok := previous.Files[rel]; ok && entry.matches(info) {
+				idx.Files[rel] = entry
+				stats.Unchanged++
+				return nil
+			}
+		}
+		pending = append(pending, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, stats, fmt.Errorf("failed to walk %s: %v", root, err)
+	}
+
+	logger.Debugf("Indexing %d new or changed files", len(pending))
+
+	// Parse changed files concurrently, large repositories are dominated by I/O
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	work := make(chan string)
+
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rel := range work {
+				entry, err := parseFile(filepath.Join(absRoot, filepath.FromSlash(rel)), rel)
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					idx.Files[rel] = entry
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, rel := range pending {
+		work <- rel
+	}
+	close(work)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, stats, firstErr
+	}
+
+	for _, rel := range pending {
+		if previous != nil && previous.Files[rel] != nil {
+			stats.Updated++
+		} else {
+			stats.Added++
+		}
+	}
+	if previous != nil {
+		for rel := range previous.Files {
+			if _, ok := idx.Files[rel]; !ok {
+				stats.Removed++
+			}
+		}
+	}
+	stats.Total = len(idx.Files)
+
+	return idx, stats, nil
+}
+
+// Save writes the index below its root directory
+func (idx *Index) Save() error {
+	path := Path(idx.Root)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %v", err)
+	}
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("failed to marshal index: %v", err)
+	}
+
+	return fsutil.WriteFileAtomic(path, data, 0644)
+}
+
+// Lookup returns the entry for a file if it is indexed and has not changed since
+func (idx *Index) Lookup(path string) (*Entry, bool) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, false
+	}
+	rel, err := filepath.Rel(idx.Root, absPath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return nil, false
+	}
+
+	entry, ok := idx.Files[filepath.ToSlash(rel)]
+	if !ok {
+		return nil, false
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil || !entry.matches(info) {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// matches reports whether the file still looks like the indexed one
+func (e *Entry) matches(info os.FileInfo) bool {
+	return e.Size == info.Size() && e.ModTime.Equal(info.ModTime())
+}
+
+// parseFile reads a markdown file and extracts its entry
+func parseFile(path, rel string) (*Entry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %v", path, err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	entry := &Entry{
+		Path:    rel,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Hash:    fmt.Sprintf("%x", sha256.Sum256(content)),
+	}
+
+	doc := mddoc.Parse(content)
+	if doc.FrontMatter != nil {
+		var frontMatter map[string]interface{}
+		if err := yaml.Unmarshal(doc.FrontMatter, &frontMatter); err != nil {
+			logger.Warnf("Invalid front matter in %s: %v", path, err)
+		} else {
+			entry.FrontMatter = frontMatter
+		}
+	}
+
+	for _, h := range doc.Headings() {
+		entry.Headings = append(entry.Headings, Heading{Level: h.Level, Text: h.Text})
+	}
+	for _, link := range doc.Links() {
+		switch link.Kind {
+		case mddoc.InlineLink:
+			entry.Links = append(entry.Links, link.Destination)
+		case mddoc.InlineImage:
+			entry.Images = append(entry.Images, link.Destination)
+		}
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,77 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuild_Incremental(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("guide/intro.md", "---\ntitle: Intro\n---\n# Intro\n\nSee [setup](setup.md).\n\n```\n# not a heading\n```\n\n![arch](img/arch.png)\n")
+	write("README.md", "# Readme\n")
+	write("node_modules/pkg/README.md", "# Ignored\n")
+
+	idx, stats, err := Build(root, nil)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if stats.Total != 2 || stats.Added != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	entry := idx.Files["guide/intro.md"]
+	if entry == nil {
+		t.Fatalf("guide/intro.md not indexed: %v", idx.Files)
+	}
+	if entry.FrontMatter["title"] != "Intro" {
+		t.Errorf("unexpected front matter: %v", entry.FrontMatter)
+	}
+	if len(entry.Headings) != 1 || entry.Headings[0].Text != "Intro" {
+		t.Errorf("unexpected headings: %v", entry.Headings)
+	}
+	if len(entry.Links) != 1 || entry.Links[0] != "setup.md" {
+		t.Errorf("unexpected links: %v", entry.Links)
+	}
+	if len(entry.Images) != 1 || entry.Images[0] != "img/arch.png" {
+		t.Errorf("unexpected images: %v", entry.Images)
+	}
+
+	if err := idx.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := Load(root)
+	if err != nil || loaded == nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	// Change one file and remove another
+	write("README.md", "# Readme\n\nUpdated.\n")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(root, "README.md"), future, future)
+	os.Remove(filepath.Join(root, "guide/intro.md"))
+	write("guide/setup.md", "# Setup\n")
+
+	_, stats, err = Build(root, loaded)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if stats.Added != 1 || stats.Updated != 1 || stats.Removed != 1 || stats.Unchanged != 0 {
+		t.Errorf("unexpected incremental stats: %+v", stats)
+	}
+
+	if _, ok := loaded.Lookup(filepath.Join(root, "README.md")); ok {
+		t.Errorf("expected modified file to be reported as stale")
+	}
+}
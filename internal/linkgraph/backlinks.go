@@ -0,0 +1,102 @@
+package linkgraph
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/fsutil"
+)
+
+// Markers delimiting the generated backlinks section, it is replaced on
+// every run and ignored while building the graph
+const (
+	startMarker = "<!-- mdctl:backlinks -->"
+	endMarker   = "<!-- /mdctl:backlinks -->"
+)
+
+// BacklinkOptions controls the generated backlinks sections
+type BacklinkOptions struct {
+	Heading string // Section heading, default "Referenced by"
+	Wiki    bool   // Write [[wiki-links]] instead of relative markdown links
+	DryRun  bool
+}
+
+// BacklinkChange is a file whose backlinks section was written or removed
+type BacklinkChange struct {
+	File      string   `json:"file"`
+	Backlinks []string `json:"backlinks"`
+}
+
+// WriteBacklinks appends a backlinks section listing the referring files to
+// every file that has backlinks, and removes stale sections from files that
+// no longer have any. Files whose section is unchanged are not written.
+func (g *Graph) WriteBacklinks(opts BacklinkOptions) ([]BacklinkChange, error) {
+	if opts.Heading == "" {
+		opts.Heading = "Referenced by"
+	}
+
+	var changes []BacklinkChange
+	for _, n := range g.Nodes {
+		abs := filepath.Join(g.Root, filepath.FromSlash(n.Path))
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return changes, fmt.Errorf("failed to read %s: %v", n.Path, err)
+		}
+		original := string(data)
+
+		sources := g.Backlinks(n.Path)
+		content := strings.TrimRight(RemoveBacklinks(original), "\n") + "\n"
+		if len(sources) > 0 {
+			content += "\n" + g.section(n.Path, sources, opts) + "\n"
+		}
+		if content == original || (len(sources) == 0 && !strings.Contains(original, startMarker)) {
+			continue
+		}
+
+		if !opts.DryRun {
+			if err := fsutil.WriteFileAtomic(abs, []byte(content), 0644); err != nil {
+				return changes, err
+			}
+		}
+		changes = append(changes, BacklinkChange{File: n.Path, Backlinks: sources})
+	}
+	return changes, nil
+}
+
+// section renders the backlinks section of a file
+func (g *Graph) section(file string, sources []string, opts BacklinkOptions) string {
+	var sb strings.Builder
+	sb.WriteString(startMarker + "\n")
+	sb.WriteString("## " + opts.Heading + "\n\n")
+	for _, source := range sources {
+		if opts.Wiki {
+			sb.WriteString("- [[" + strings.TrimSuffix(source, path.Ext(source)) + "]]\n")
+			continue
+		}
+		rel, err := filepath.Rel(filepath.Dir(filepath.FromSlash(file)), filepath.FromSlash(source))
+		if err != nil {
+			rel = source
+		}
+		title := g.byPath[source].Title
+		sb.WriteString(fmt.Sprintf("- [%s](%s)\n", title, strings.ReplaceAll(filepath.ToSlash(rel), " ", "%20")))
+	}
+	sb.WriteString(endMarker)
+	return sb.String()
+}
+
+// RemoveBacklinks strips a generated backlinks section from content
+func RemoveBacklinks(content string) string {
+	start := strings.Index(content, startMarker)
+	if start < 0 {
+		return content
+	}
+	end := strings.Index(content[start:], endMarker)
+	if end < 0 {
+		return content
+	}
+	end += start + len(endMarker)
+	return strings.TrimRight(content[:start], "\n") + "\n" + strings.TrimLeft(content[end:], "\n")
+}
@@ -0,0 +1,297 @@
+// Package linkgraph indexes the wiki-links and markdown links between the
+// markdown files of a directory.
+package linkgraph
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/mddoc"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// wikiLinkRegex matches [[target]], [[target#heading]] and [[target|alias]]
+	wikiLinkRegex = regexp.MustCompile(`(!?)\[\[([^\]|#]*)(?:#[^\]|]*)?(?:\|[^\]]*)?\]\]`)
+	// headingRegex matches the first level heading
+	headingRegex = regexp.MustCompile(`^#[ \t]+(.+?)[ \t#]*$`)
+	// skipDirs are never descended into
+	skipDirs = map[string]bool{
+		".git":         true,
+		".mdctl":       true,
+		".obsidian":    true,
+		"node_modules": true,
+	}
+)
+
+// Link kinds
+const (
+	KindMarkdown = "markdown"
+	KindWiki     = "wiki"
+)
+
+// Node is a markdown file of the graph
+type Node struct {
+	Path     string `json:"path"` // Slash-separated, relative to the root
+	Title    string `json:"title"`
+	Outgoing int    `json:"outgoing"`
+	Incoming int    `json:"incoming"`
+}
+
+// Edge is a link from one file to another
+type Edge struct {
+	From   string `json:"from"`
+	To     string `json:"to"` // Target file, or the raw destination for unresolved links
+	Line   int    `json:"line"`
+	Kind   string `json:"kind"`
+	Target string `json:"target"` // Link destination as written
+}
+
+// Graph is the link graph of a directory
+type Graph struct {
+	Root       string `json:"root"`
+	Nodes      []Node `json:"nodes"`
+	Edges      []Edge `json:"edges"`
+	Unresolved []Edge `json:"unresolved"`
+
+	byPath   map[string]*Node
+	byName   map[string][]string // Lowercase base name without extension to paths
+	contents map[string]string
+}
+
+// Build reads the markdown files below root and resolves the links between
+// them. Generated backlinks sections are ignored so they never count as links.
+func Build(root string) (*Graph, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %v", err)
+	}
+
+	g := &Graph{
+		Root:     absRoot,
+		byPath:   make(map[string]*Node),
+		byName:   make(map[string][]string),
+		contents: make(map[string]string),
+	}
+
+	var files []string
+	err = filepath.Walk(absRoot, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if p != absRoot && skipDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if mddoc.IsMarkdown(p) {
+			rel, err := filepath.Rel(absRoot, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %v", root, err)
+	}
+	sort.Strings(files)
+
+	g.Nodes = make([]Node, len(files))
+	for i, rel := range files {
+		data, err := os.ReadFile(filepath.Join(absRoot, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", rel, err)
+		}
+		content := RemoveBacklinks(string(data))
+		g.contents[rel] = content
+		g.Nodes[i] = Node{Path: rel, Title: title(rel, content)}
+		g.byPath[rel] = &g.Nodes[i]
+		name := strings.ToLower(strings.TrimSuffix(path.Base(rel), path.Ext(rel)))
+		g.byName[name] = append(g.byName[name], rel)
+	}
+
+	for _, rel := range files {
+		g.parseLinks(rel)
+	}
+	return g, nil
+}
+
+// parseLinks records the links of a file outside code. Markdown links come
+// from the AST, wiki links, which CommonMark does not know, from the lines
+// outside fenced code blocks.
+func (g *Graph) parseLinks(from string) {
+	var links []mddoc.Link
+	for _, link := range mddoc.Parse([]byte(g.contents[from])).Links() {
+		if link.Kind == mddoc.InlineLink {
+			links = append(links, link)
+		}
+	}
+
+	var fences mddoc.Fences
+	for i, line := range strings.Split(g.contents[from], "\n") {
+		for len(links) > 0 && links[0].Line == i+1 {
+			if to, ok := g.resolveMarkdown(from, links[0].Destination); ok {
+				g.addEdge(Edge{From: from, To: to, Line: i + 1, Kind: KindMarkdown, Target: links[0].Destination}, to != "")
+			}
+			links = links[1:]
+		}
+		if fences.Line(line) {
+			continue
+		}
+		for _, m := range wikiLinkRegex.FindAllStringSubmatch(line, -1) {
+			target := strings.TrimSpace(m[2])
+			if target == "" {
+				continue // [[#heading]] links within the same page
+			}
+			to := g.resolveWiki(target)
+			if to == "" && m[1] == "!" && path.Ext(target) != "" && !mddoc.IsMarkdown(target) {
+				continue // Embedded attachments are not pages
+			}
+			g.addEdge(Edge{From: from, To: to, Line: i + 1, Kind: KindWiki, Target: target}, to != "")
+		}
+	}
+}
+
+// addEdge records a resolved edge or an unresolved link
+func (g *Graph) addEdge(e Edge, resolved bool) {
+	if !resolved {
+		e.To = e.Target
+		g.Unresolved = append(g.Unresolved, e)
+		return
+	}
+	g.Edges = append(g.Edges, e)
+	if e.From != e.To {
+		g.byPath[e.From].Outgoing++
+		g.byPath[e.To].Incoming++
+	}
+}
+
+// resolveMarkdown returns the file a markdown link of from points at. ok is
+// false for links that are not meant to point at a page of the site, such as
+// URLs, same-page anchors and other file types.
+func (g *Graph) resolveMarkdown(from, dest string) (string, bool) {
+	target, _, _ := strings.Cut(dest, "#")
+	target, _, _ = strings.Cut(target, "?")
+	if target == "" || strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:") {
+		return "", false
+	}
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+
+	var base string
+	if strings.HasPrefix(target, "/") {
+		base = path.Clean(strings.TrimPrefix(target, "/"))
+	} else {
+		base = path.Join(path.Dir(from), target)
+	}
+
+	ext := path.Ext(base)
+	if ext != "" && !mddoc.IsMarkdown(base) {
+		return "", false
+	}
+	// Directory style links used by MkDocs and Hugo
+	for _, candidate := range []string{base, base + ".md", base + "/index.md", base + "/README.md", base + "/_index.md"} {
+		if _, ok := g.byPath[candidate]; ok {
+			return candidate, true
+		}
+	}
+	return "", true
+}
+
+// resolveWiki returns the file a wiki-link points at: a path relative to the
+// root, or a note name matched case-insensitively, preferring the shortest path
+func (g *Graph) resolveWiki(target string) string {
+	clean := strings.TrimPrefix(path.Clean(target), "/")
+	for _, candidate := range []string{clean, clean + ".md"} {
+		if _, ok := g.byPath[candidate]; ok {
+			return candidate
+		}
+	}
+
+	name := strings.ToLower(path.Base(clean))
+	if mddoc.IsMarkdown(name) {
+		name = strings.TrimSuffix(name, path.Ext(name))
+	}
+	matches := g.byName[name]
+	if len(matches) == 0 {
+		return ""
+	}
+	best := matches[0]
+	for _, m := range matches[1:] {
+		if len(m) < len(best) {
+			best = m
+		}
+	}
+	return best
+}
+
+// Backlinks returns the files linking to a file, sorted and without duplicates
+func (g *Graph) Backlinks(file string) []string {
+	seen := make(map[string]bool)
+	var sources []string
+	for _, e := range g.Edges {
+		if e.To == file && e.From != file && !seen[e.From] {
+			seen[e.From] = true
+			sources = append(sources, e.From)
+		}
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// Orphans returns the files no other file links to. Entry pages, index.md,
+// README.md and _index.md at the root, are never reported.
+func (g *Graph) Orphans() []Node {
+	var orphans []Node
+	for _, n := range g.Nodes {
+		if n.Incoming > 0 {
+			continue
+		}
+		switch strings.ToLower(n.Path) {
+		case "index.md", "readme.md", "_index.md":
+			continue
+		}
+		orphans = append(orphans, n)
+	}
+	return orphans
+}
+
+// Node returns the node of a file
+func (g *Graph) Node(file string) (Node, bool) {
+	n, ok := g.byPath[file]
+	if !ok {
+		return Node{}, false
+	}
+	return *n, true
+}
+
+// title returns the title front matter, the first level heading or the file name
+func title(rel, content string) string {
+	if strings.HasPrefix(content, "---\n") {
+		if parts := strings.SplitN(content[4:], "\n---", 2); len(parts) == 2 {
+			var meta struct {
+				Title string `yaml:"title"`
+			}
+			if yaml.Unmarshal([]byte(parts[0]), &meta) == nil && meta.Title != "" {
+				return meta.Title
+			}
+			content = parts[1]
+		}
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if m := headingRegex.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+	}
+	return strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+}
@@ -0,0 +1,79 @@
+package linkgraph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGraph(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"index.md":            "# Home\n\nSee [guide](guide/) and [[Setup|setting up]].\n\n```\n[[Orphan]]\n```\n",
+		"guide/index.md":      "# Guide\n\nBack to [home](../index.md#top), [[missing]] and ![[diagram.png]].\n",
+		"guide/setup.md":      "---\ntitle: Setting Up\n---\n\nRead [the guide](./index.md) first.\n",
+		"notes/orphan.md":     "# Orphan\n\nLinks to [[guide/setup]] and [site](https://example.com).\n",
+		"notes/deep/Setup.md": "# Another setup\n",
+	})
+
+	g, err := Build(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := g.Backlinks("guide/setup.md"); strings.Join(got, ",") != "index.md,notes/orphan.md" {
+		t.Errorf("backlinks of guide/setup.md = %v", got)
+	}
+	if got := g.Backlinks("guide/index.md"); strings.Join(got, ",") != "guide/setup.md,index.md" {
+		t.Errorf("backlinks of guide/index.md = %v", got)
+	}
+	if len(g.Unresolved) != 1 || g.Unresolved[0].Target != "missing" {
+		t.Errorf("unresolved = %+v", g.Unresolved)
+	}
+
+	var orphans []string
+	for _, n := range g.Orphans() {
+		orphans = append(orphans, n.Path)
+	}
+	if strings.Join(orphans, ",") != "notes/deep/Setup.md,notes/orphan.md" {
+		t.Errorf("orphans = %v", orphans)
+	}
+
+	changes, err := g.WriteBacklinks(BacklinkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("got %d changed files, want 3", len(changes))
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "guide", "setup.md"))
+	if !strings.Contains(string(data), "## Referenced by\n\n- [Home](../index.md)\n- [Orphan](../notes/orphan.md)\n") {
+		t.Errorf("unexpected backlinks section:\n%s", data)
+	}
+
+	// Generated sections do not count as links and are stable across runs
+	g, err = Build(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := g.Backlinks("notes/orphan.md"); len(got) != 0 {
+		t.Errorf("backlinks section counted as links: %v", got)
+	}
+	if changes, _ := g.WriteBacklinks(BacklinkOptions{}); len(changes) != 0 {
+		t.Errorf("second run changed %d files", len(changes))
+	}
+}
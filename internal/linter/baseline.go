@@ -0,0 +1,137 @@
+package linter
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// baselineVersion is the format version of baseline files
+const baselineVersion = 1
+
+// Baseline records the issues that existed when a linter was adopted, so
+// that only new violations are reported. Issues are identified by file, rule
+// and the content of their line, which keeps them matched when lines move.
+type Baseline struct {
+	Version int             `json:"version"`
+	Issues  []BaselineEntry `json:"issues"`
+
+	known map[string]map[string]int // File to issue key to count
+}
+
+// BaselineEntry is a known issue, Count identical issues on identical lines
+type BaselineEntry struct {
+	File  string `json:"file"`
+	Rule  string `json:"rule"`
+	Hash  string `json:"hash"`
+	Count int    `json:"count"`
+}
+
+// NewBaseline records the issues of lint results
+func NewBaseline(results []*Result) *Baseline {
+	counts := make(map[BaselineEntry]int)
+	for _, result := range results {
+		for _, issue := range result.Issues {
+			entry := BaselineEntry{File: baselinePath(result.Filename), Rule: issue.Rule, Hash: lineHash(issue.text)}
+			counts[entry]++
+		}
+	}
+
+	b := &Baseline{Version: baselineVersion, Issues: []BaselineEntry{}}
+	for entry, count := range counts {
+		entry.Count = count
+		b.Issues = append(b.Issues, entry)
+	}
+	sort.Slice(b.Issues, func(i, j int) bool {
+		a, c := b.Issues[i], b.Issues[j]
+		if a.File != c.File {
+			return a.File < c.File
+		}
+		if a.Rule != c.Rule {
+			return a.Rule < c.Rule
+		}
+		return a.Hash < c.Hash
+	})
+	b.index()
+	return b
+}
+
+// LoadBaseline reads a baseline file
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %v", err)
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %v", path, err)
+	}
+	if b.Version != baselineVersion {
+		return nil, fmt.Errorf("unsupported baseline version %d in %s", b.Version, path)
+	}
+	b.index()
+	return &b, nil
+}
+
+// Save writes the baseline to a file
+func (b *Baseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal baseline: %v", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Filter removes the known issues from a result and counts them as
+// baselined. A nil baseline keeps every issue.
+func (b *Baseline) Filter(result *Result) {
+	if b == nil {
+		return
+	}
+	known := b.known[baselinePath(result.Filename)]
+	if len(known) == 0 {
+		return
+	}
+
+	remaining := make(map[string]int, len(known))
+	for key, count := range known {
+		remaining[key] = count
+	}
+	issues := result.Issues[:0]
+	for _, issue := range result.Issues {
+		key := issue.Rule + ":" + lineHash(issue.text)
+		if remaining[key] > 0 {
+			remaining[key]--
+			result.Baselined++
+			continue
+		}
+		issues = append(issues, issue)
+	}
+	result.Issues = issues
+}
+
+// index builds the lookup table of known issues
+func (b *Baseline) index() {
+	b.known = make(map[string]map[string]int)
+	for _, entry := range b.Issues {
+		if b.known[entry.File] == nil {
+			b.known[entry.File] = make(map[string]int)
+		}
+		b.known[entry.File][entry.Rule+":"+entry.Hash] += entry.Count
+	}
+}
+
+// baselinePath normalizes a file name for baseline lookups
+func baselinePath(filename string) string {
+	return filepath.ToSlash(filepath.Clean(filename))
+}
+
+// lineHash identifies the content of a line, ignoring surrounding whitespace
+func lineHash(line string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(line)))
+	return fmt.Sprintf("%x", sum[:8])
+}
@@ -2,8 +2,11 @@ package linter
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/samzong/mdctl/internal/markdownfmt"
 )
 
 // ConfigFile represents a markdownlint configuration file
@@ -25,7 +28,11 @@ type ConfigFile struct {
 	MD019 *RuleConfig `json:"MD019,omitempty"`
 	MD023 *RuleConfig `json:"MD023,omitempty"`
 	MD032 *RuleConfig `json:"MD032,omitempty"`
+	MD042 *RuleConfig `json:"MD042,omitempty"`
+	MD044 *RuleConfig `json:"MD044,omitempty"`
+	MD045 *RuleConfig `json:"MD045,omitempty"`
 	MD047 *RuleConfig `json:"MD047,omitempty"`
+	MD052 *RuleConfig `json:"MD052,omitempty"`
 }
 
 // RuleConfig represents configuration for a specific rule
@@ -33,8 +40,18 @@ type RuleConfig struct {
 	// Whether the rule is enabled
 	Enabled *bool `json:"enabled,omitempty"`
 
+	// Severity of the rule's issues: error, warning or info
+	Severity string `json:"severity,omitempty"`
+
 	// Rule-specific options
 	Options map[string]interface{} `json:"options,omitempty"`
+
+	// Proper names with their correct capitalization (MD044)
+	Names []string `json:"names,omitempty"`
+
+	// Maximum line length and paragraph reflow mode of the fix (MD013)
+	LineLength int    `json:"line_length,omitempty"`
+	Reflow     string `json:"reflow,omitempty"`
 }
 
 // LoadConfigFile loads configuration from a file
@@ -74,16 +91,52 @@ func (c *ConfigFile) ApplyToRuleSet(rs *RuleSet) {
 		"MD019": c.MD019,
 		"MD023": c.MD023,
 		"MD032": c.MD032,
+		"MD042": c.MD042,
+		"MD044": c.MD044,
+		"MD045": c.MD045,
 		"MD047": c.MD047,
+		"MD052": c.MD052,
 	}
 
 	for ruleID, ruleConfig := range ruleConfigs {
-		if ruleConfig != nil && ruleConfig.Enabled != nil {
+		if ruleConfig == nil {
+			continue
+		}
+		if ruleConfig.Enabled != nil {
 			if rule, exists := rs.rules[ruleID]; exists {
 				rule.SetEnabled(*ruleConfig.Enabled)
 			}
 		}
+		if rule, ok := rs.rules[ruleID].(*MD044); ok && len(ruleConfig.Names) > 0 {
+			rule.SetNames(ruleConfig.Names)
+		}
+		if rule, ok := rs.rules[ruleID].(*MD013); ok {
+			if ruleConfig.LineLength > 0 {
+				rule.lineLength = ruleConfig.LineLength
+			}
+			if ruleConfig.Reflow != "" {
+				if err := ValidateReflow(ruleConfig.Reflow); err != nil {
+					logger.Warnf("Ignoring reflow of %s: %v", ruleID, err)
+				} else {
+					rule.reflow = ruleConfig.Reflow
+				}
+			}
+		}
+		if ruleConfig.Severity != "" {
+			if err := rs.SetSeverity(ruleID, ruleConfig.Severity); err != nil {
+				logger.Warnf("Ignoring severity of %s: %v", ruleID, err)
+			}
+		}
+	}
+}
+
+// ValidateReflow checks a paragraph reflow mode
+func ValidateReflow(mode string) error {
+	switch mode {
+	case markdownfmt.ReflowWrap, markdownfmt.ReflowSemantic:
+		return nil
 	}
+	return fmt.Errorf("unknown reflow mode %q (must be wrap or semantic)", mode)
 }
 
 // findConfigFile looks for common markdownlint config files
@@ -129,7 +182,11 @@ func CreateDefaultConfig(filename string) error {
 		MD019:   &RuleConfig{Enabled: boolPtr(true)},
 		MD023:   &RuleConfig{Enabled: boolPtr(true)},
 		MD032:   &RuleConfig{Enabled: boolPtr(true)},
+		MD042:   &RuleConfig{Enabled: boolPtr(true)},
+		MD044:   &RuleConfig{Enabled: boolPtr(true)},
+		MD045:   &RuleConfig{Enabled: boolPtr(true)},
 		MD047:   &RuleConfig{Enabled: boolPtr(true)},
+		MD052:   &RuleConfig{Enabled: boolPtr(true)},
 	}
 
 	data, err := json.MarshalIndent(config, "", "  ")
@@ -0,0 +1,140 @@
+package linter
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/diff"
+)
+
+// Ask sends a system prompt and content to a chat model and returns its reply
+type Ask func(systemPrompt, content string) (string, error)
+
+// explainPrompt asks for an explanation of lint issues and a rewrite of the
+// passage they point at
+const explainPrompt = `You review markdown documents for a linter. You get lint issues, the outline of the document and the passage the issues point at.
+Explain in one or two sentences why the issues matter, and rewrite the passage so they are resolved without changing its meaning, links, code or formatting elsewhere.
+Long lines are wrapped or split into shorter sentences, headings get the level that fits the outline.
+Reply ONLY with a JSON object: {"explanation": "...", "rewrite": "..."}, where rewrite is the complete replacement of the passage.`
+
+var headingLineRegex = regexp.MustCompile(`^ {0,3}#{1,6}(\s|$)`)
+
+// passage is a range of lines, starting at 1, and the issues pointing into it
+type passage struct {
+	start, end int
+	issues     []*Issue
+}
+
+// Explain asks a model to explain the issues of a result that cannot be
+// fixed automatically and to propose a rewrite of the passage around them,
+// shown as a diff against content. Issues on the same paragraph share one
+// request, at most limit requests are sent, all passages when limit is 0.
+func (l *Linter) Explain(result *Result, content string, ask Ask, limit int) error {
+	lines := strings.Split(content, "\n")
+
+	var passages []*passage
+	for _, issue := range result.Issues {
+		if issue.Fixed || issue.Line < 1 || issue.Line > len(lines) || l.fixer.rules[issue.Rule] != nil {
+			continue
+		}
+		start, end := passageBounds(lines, issue.Line)
+		if n := len(passages); n > 0 && passages[n-1].start == start {
+			passages[n-1].issues = append(passages[n-1].issues, issue)
+			continue
+		}
+		passages = append(passages, &passage{start: start, end: end, issues: []*Issue{issue}})
+	}
+	if limit > 0 && len(passages) > limit {
+		passages = passages[:limit]
+	}
+
+	outline := documentOutline(lines)
+	for _, p := range passages {
+		text := strings.Join(lines[p.start-1:p.end], "\n")
+
+		var request strings.Builder
+		request.WriteString("Issues:\n")
+		for _, issue := range p.issues {
+			fmt.Fprintf(&request, "- Line %d: %s (%s)\n", issue.Line, issue.Message, issue.Rule)
+		}
+		if outline != "" {
+			fmt.Fprintf(&request, "\nOutline:\n%s\n", outline)
+		}
+		fmt.Fprintf(&request, "\nPassage (lines %d-%d):\n%s\n", p.start, p.end, text)
+
+		reply, err := ask(explainPrompt, request.String())
+		if err != nil {
+			return fmt.Errorf("failed to explain issues of %s: %v", result.Filename, err)
+		}
+		var answer struct {
+			Explanation string `json:"explanation"`
+			Rewrite     string `json:"rewrite"`
+		}
+		if err := json.Unmarshal([]byte(jsonObject(reply)), &answer); err != nil {
+			return fmt.Errorf("failed to parse explanation: %v\nResponse: %s", err, reply)
+		}
+
+		rewritten := make([]string, 0, len(lines))
+		rewritten = append(rewritten, lines[:p.start-1]...)
+		rewritten = append(rewritten, strings.Split(strings.TrimRight(answer.Rewrite, "\n"), "\n")...)
+		rewritten = append(rewritten, lines[p.end:]...)
+		suggestion := ""
+		if strings.TrimSpace(answer.Rewrite) != "" {
+			suggestion = diff.Unified(result.Filename, result.Filename+" (suggested)", content, strings.Join(rewritten, "\n"))
+		}
+		for _, issue := range p.issues {
+			issue.Explanation = strings.TrimSpace(answer.Explanation)
+			issue.Rewrite = suggestion
+		}
+	}
+	return nil
+}
+
+// passageBounds returns the lines of the paragraph around a line, headings
+// are passages of their own
+func passageBounds(lines []string, line int) (int, int) {
+	if headingLineRegex.MatchString(lines[line-1]) || strings.TrimSpace(lines[line-1]) == "" {
+		return line, line
+	}
+	start, end := line, line
+	for start > 1 && strings.TrimSpace(lines[start-2]) != "" && !headingLineRegex.MatchString(lines[start-2]) {
+		start--
+	}
+	for end < len(lines) && strings.TrimSpace(lines[end]) != "" && !headingLineRegex.MatchString(lines[end]) {
+		end++
+	}
+	return start, end
+}
+
+// documentOutline returns the ATX headings of a document with their lines,
+// code blocks are skipped
+func documentOutline(lines []string) string {
+	var outline []string
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		case headingLineRegex.MatchString(line):
+			outline = append(outline, fmt.Sprintf("%d: %s", i+1, trimmed))
+		}
+	}
+	return strings.Join(outline, "\n")
+}
+
+// jsonObject strips code fences and surrounding text from a model reply
+func jsonObject(reply string) string {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start == -1 || end < start {
+		return reply
+	}
+	return reply[start : end+1]
+}
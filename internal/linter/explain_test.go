@@ -0,0 +1,57 @@
+package linter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	long := strings.Repeat("word ", 30)
+	content := "# Title\n\n" + long + "\n" + long + "\n\n### Deep\n\nText  \n"
+	l := New(&Config{EnableRules: []string{"MD001", "MD009", "MD013"}})
+	result, err := l.LintContent("doc.md", content)
+	if err != nil {
+		t.Fatalf("LintContent failed: %v", err)
+	}
+
+	var requests []string
+	ask := func(system, request string) (string, error) {
+		requests = append(requests, request)
+		if strings.Contains(request, "MD001") {
+			return "```json\n{\"explanation\": \"Skips a level.\", \"rewrite\": \"## Deep\"}\n```", nil
+		}
+		return `{"explanation": "Too long.", "rewrite": "Short line.\nAnother one."}`, nil
+	}
+	if err := l.Explain(result, content, ask, 0); err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+
+	// The two long lines of a paragraph share one request, MD009 is fixable
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d:\n%s", len(requests), strings.Join(requests, "\n---\n"))
+	}
+	if request := requests[1]; !strings.Contains(request, "Passage (lines 3-4)") || !strings.Contains(request, "6: ### Deep") {
+		t.Errorf("unexpected request: %s", request)
+	}
+	for _, issue := range result.Issues {
+		switch issue.Rule {
+		case "MD013":
+			if issue.Explanation != "Too long." || !strings.Contains(issue.Rewrite, "+Short line.\n+Another one.") {
+				t.Errorf("unexpected MD013 explanation: %+v", issue)
+			}
+		case "MD001":
+			if !strings.Contains(issue.Rewrite, "-### Deep\n+## Deep") || !strings.Contains(issue.Rewrite, "@@ -3,") {
+				t.Errorf("unexpected MD001 rewrite: %s", issue.Rewrite)
+			}
+		case "MD009":
+			if issue.Explanation != "" {
+				t.Errorf("expected no explanation of a fixable issue: %+v", issue)
+			}
+		}
+	}
+
+	requests = nil
+	if err := l.Explain(result, content, ask, 1); err != nil || len(requests) != 1 {
+		t.Errorf("expected the limit to cap the requests, got %d (%v)", len(requests), err)
+	}
+}
@@ -2,6 +2,7 @@ package linter
 
 import (
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -24,6 +25,7 @@ func NewFixer() *Fixer {
 	f.rules["MD019"] = f.fixMultipleSpacesAfterHash
 	f.rules["MD023"] = f.fixHeadingIndentation
 	f.rules["MD032"] = f.fixListSpacing
+	f.rules["MD045"] = f.fixImageAltText
 	f.rules["MD047"] = f.fixFileEndNewline
 
 	return f
@@ -176,6 +178,8 @@ func (f *Fixer) fixFileEndNewline(lines []string) ([]string, int) {
 		return lines, 0
 	}
 
+	n, last := len(lines), lines[len(lines)-1]
+
 	// Remove trailing empty lines
 	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
 		lines = lines[:len(lines)-1]
@@ -184,5 +188,41 @@ func (f *Fixer) fixFileEndNewline(lines []string) ([]string, int) {
 	// Add single empty line at the end
 	lines = append(lines, "")
 
+	// Lines before the new last one are unchanged, so the file already ended
+	// with a single newline when the count and the last line are the same
+	if len(lines) == n && last == "" {
+		return lines, 0
+	}
 	return lines, 1
 }
+
+// fixImageAltText adds alt text derived from the file name to images without
+// alt text
+func (f *Fixer) fixImageAltText(lines []string) ([]string, int) {
+	fixed := 0
+	definitions := refDefinitions(lines)
+	prose := proseLines(lines)
+
+	for i := range lines {
+		links := append(inlineLinks(lines[i], prose[i]), referenceLinks(lines[i], prose[i])...)
+		// Later images are fixed first, so offsets of earlier ones stay valid
+		sort.Slice(links, func(a, b int) bool { return links[a].start > links[b].start })
+		for _, link := range links {
+			if !link.image || strings.TrimSpace(link.text) != "" {
+				continue
+			}
+			dest := link.target
+			if link.end > link.textEnd+1 && lines[i][link.textEnd+1] == '[' {
+				dest = definitions[normalizeLabel(link.target)]
+			}
+			alt := altFromPath(dest)
+			if alt == "" {
+				continue
+			}
+			lines[i] = lines[i][:link.start+2] + alt + lines[i][link.textEnd:]
+			fixed++
+		}
+	}
+
+	return lines, fixed
+}
@@ -0,0 +1,43 @@
+package linter
+
+import (
+	"strings"
+
+	"github.com/samzong/mdctl/internal/mddoc"
+	"github.com/samzong/mdctl/internal/schema"
+)
+
+// FrontMatterRuleID is the rule reporting front matter schema violations
+const FrontMatterRuleID = "FM001"
+
+// FrontMatterRule validates the front matter of a file against a JSON Schema
+type FrontMatterRule struct {
+	BaseRule
+	schema *schema.Schema
+}
+
+func (r *FrontMatterRule) Check(lines []string) []*Issue {
+	doc := mddoc.Split([]byte(strings.Join(lines, "\n")))
+	if doc.Unclosed {
+		return []*Issue{{Line: 1, Rule: r.ID(), Message: "Front matter is not closed"}}
+	}
+	document, err := doc.Meta()
+	if err != nil {
+		return []*Issue{{Line: 1, Rule: r.ID(), Message: "Invalid front matter: " + err.Error()}}
+	}
+
+	var issues []*Issue
+	for _, v := range r.schema.Validate(document) {
+		// Front matter starts on the second line of the file
+		line := v.Line + 1
+		if document == nil {
+			line = 1
+		}
+		issue := &Issue{Line: line, Rule: r.ID(), Message: "Front matter: " + v.Message}
+		if line <= len(lines) {
+			issue.Context = lines[line-1]
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
@@ -0,0 +1,201 @@
+package linter
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/mddoc"
+)
+
+var (
+	// inlineLinkRegex matches inline links and images with their text and
+	// destination, titles are skipped
+	inlineLinkRegex = regexp.MustCompile(`(!?)\[((?:[^\[\]\\]|\\.)*)\]\(\s*(<[^>]*>|[^\s)]*)(?:\s+(?:"[^"]*"|'[^']*'|\([^)]*\)))?\s*\)`)
+	// refLinkRegex matches full and collapsed reference links and images
+	refLinkRegex = regexp.MustCompile(`(!?)\[((?:[^\[\]\\]|\\.)*)\]\[((?:[^\[\]\\]|\\.)*)\]`)
+	// refDefLabelRegex matches a link reference definition and its label
+	refDefLabelRegex = regexp.MustCompile(`^\s{0,3}\[((?:[^\[\]\\]|\\.)+)\]:\s*(<[^>]*>|\S+)`)
+	// altSeparatorRegex matches the separators of words in file names
+	altSeparatorRegex = regexp.MustCompile(`[-_.+\s]+`)
+)
+
+// linkMatch is an inline or reference link or image of a line
+type linkMatch struct {
+	start, end int
+	image      bool
+	text       string
+	target     string // Destination of inline links, label of reference links
+	textEnd    int    // Offset of the bracket closing the text
+}
+
+// inlineLinks returns the inline links and images of a line that are prose,
+// not code or HTML
+func inlineLinks(line, prose string) []linkMatch {
+	return findLinks(inlineLinkRegex, line, prose)
+}
+
+// referenceLinks returns the full and collapsed reference links and images
+// of a line that are prose, the target is the label they refer to
+func referenceLinks(line, prose string) []linkMatch {
+	links := findLinks(refLinkRegex, line, prose)
+	for i := range links {
+		if links[i].target == "" {
+			links[i].target = links[i].text
+		}
+	}
+	return links
+}
+
+// findLinks returns the matches of a link regular expression whose first
+// character survived proseLines, links in code spans and escaped brackets
+// are skipped
+func findLinks(re *regexp.Regexp, line, prose string) []linkMatch {
+	var links []linkMatch
+	for _, m := range re.FindAllStringSubmatchIndex(line, -1) {
+		start := m[0]
+		if start >= len(prose) || prose[start] != line[start] || (start > 0 && line[start-1] == '\\') {
+			continue
+		}
+		links = append(links, linkMatch{
+			start:   start,
+			end:     m[1],
+			image:   m[3] > m[2],
+			text:    line[m[4]:m[5]],
+			target:  strings.Trim(line[m[6]:m[7]], "<>"),
+			textEnd: m[5],
+		})
+	}
+	return links
+}
+
+// refDefinitions returns the destinations of the link reference
+// definitions of a document by normalized label, definitions in code blocks
+// do not count
+func refDefinitions(lines []string) map[string]string {
+	definitions := make(map[string]string)
+	var fences mddoc.Fences
+	for _, line := range lines {
+		if fences.Line(line) {
+			continue
+		}
+		if m := refDefLabelRegex.FindStringSubmatch(line); m != nil {
+			label := normalizeLabel(m[1])
+			if _, exists := definitions[label]; !exists {
+				definitions[label] = strings.Trim(m[2], "<>")
+			}
+		}
+	}
+	return definitions
+}
+
+// normalizeLabel folds the case and whitespace of a reference label, as
+// CommonMark matches labels
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// altFromPath derives alt text from the file name of an image destination,
+// e.g. "Architecture diagram" from img/architecture-diagram.png
+func altFromPath(dest string) string {
+	if i := strings.IndexAny(dest, "?#"); i >= 0 {
+		dest = dest[:i]
+	}
+	if unescaped, err := url.PathUnescape(dest); err == nil {
+		dest = unescaped
+	}
+	name := path.Base(strings.ReplaceAll(dest, "\\", "/"))
+	name = strings.TrimSuffix(name, path.Ext(name))
+	alt := strings.TrimSpace(altSeparatorRegex.ReplaceAllString(name, " "))
+	if alt == "" || alt == "/" {
+		return ""
+	}
+	alt = strings.NewReplacer("[", `\[`, "]", `\]`).Replace(alt)
+	return strings.ToUpper(alt[:1]) + alt[1:]
+}
+
+// MD042: No empty links
+type MD042 struct {
+	BaseRule
+}
+
+func (r *MD042) Check(lines []string) []*Issue {
+	var issues []*Issue
+
+	for i, line := range proseLines(lines) {
+		for _, link := range inlineLinks(lines[i], line) {
+			if link.image || (link.target != "" && link.target != "#") {
+				continue
+			}
+			issues = append(issues, &Issue{
+				Line:    i + 1,
+				Column:  link.start + 1,
+				Rule:    r.ID(),
+				Message: "No empty links",
+				Context: lines[i][link.start:link.end],
+			})
+		}
+	}
+
+	return issues
+}
+
+// MD045: Images should have alternate text (alt text)
+type MD045 struct {
+	BaseRule
+}
+
+func (r *MD045) Check(lines []string) []*Issue {
+	var issues []*Issue
+
+	for i, line := range proseLines(lines) {
+		links := append(inlineLinks(lines[i], line), referenceLinks(lines[i], line)...)
+		for _, link := range links {
+			if !link.image || strings.TrimSpace(link.text) != "" {
+				continue
+			}
+			issues = append(issues, &Issue{
+				Line:    i + 1,
+				Column:  link.start + 1,
+				Rule:    r.ID(),
+				Message: "Images should have alternate text (alt text)",
+				Context: lines[i][link.start:link.end],
+			})
+		}
+	}
+
+	return issues
+}
+
+// MD052: Reference links and images should use a label that is defined
+type MD052 struct {
+	BaseRule
+}
+
+func (r *MD052) Check(lines []string) []*Issue {
+	var issues []*Issue
+	definitions := refDefinitions(lines)
+
+	for i, line := range proseLines(lines) {
+		for _, link := range referenceLinks(lines[i], line) {
+			label := normalizeLabel(link.target)
+			if label == "" || strings.HasPrefix(label, "^") {
+				continue
+			}
+			if _, defined := definitions[label]; defined {
+				continue
+			}
+			issues = append(issues, &Issue{
+				Line:    i + 1,
+				Column:  link.start + 1,
+				Rule:    r.ID(),
+				Message: fmt.Sprintf("Reference links and images should use a label that is defined: %q", link.target),
+				Context: lines[i][link.start:link.end],
+			})
+		}
+	}
+
+	return issues
+}
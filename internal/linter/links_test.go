@@ -0,0 +1,72 @@
+package linter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLinkRules(t *testing.T) {
+	content := strings.Join([]string{
+		"# Links",
+		"",
+		"An [empty link]() and [anchor](#), a [real one](https://example.com) and `[code]()`.",
+		"",
+		"![](img/architecture-diagram.png) ![Logo](logo.svg) ![ ][shot] ![][missing]",
+		"",
+		"See [the guide][guide], [Guide][] and [nowhere][none], but not \\[escaped][none].",
+		"",
+		"```",
+		"[in code][none] ![](code.png)",
+		"[none]: https://example.com",
+		"```",
+		"",
+		"[guide]: https://example.com/guide",
+		"[shot]: <images/screen_shot%201.png>",
+		"",
+	}, "\n")
+
+	l := New(&Config{EnableRules: []string{"MD042", "MD045", "MD052"}})
+	result, err := l.LintContent("a.md", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, issue := range result.Issues {
+		got = append(got, issue.Rule+":"+issue.Context)
+	}
+	want := []string{
+		"MD042:[empty link]()",
+		"MD042:[anchor](#)",
+		"MD045:![](img/architecture-diagram.png)",
+		"MD045:![ ][shot]",
+		"MD045:![][missing]",
+		"MD052:![][missing]",
+		"MD052:[nowhere][none]",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	_, fixed := l.FixContent("a.md", content)
+	if !strings.Contains(fixed, "![Architecture diagram](img/architecture-diagram.png) ![Logo](logo.svg) ![Screen shot 1][shot] ![][missing]") {
+		t.Errorf("alt text not added:\n%s", fixed)
+	}
+	if !strings.Contains(fixed, "[in code][none] ![](code.png)") {
+		t.Errorf("code block changed:\n%s", fixed)
+	}
+}
+
+func TestAltFromPath(t *testing.T) {
+	tests := map[string]string{
+		"img/architecture-diagram.png":               "Architecture diagram",
+		"https://example.com/a/Build_Status.svg?x=1": "Build Status",
+		"screen%20shot.PNG":                          "Screen shot",
+		"":                                           "",
+	}
+	for dest, want := range tests {
+		if got := altFromPath(dest); got != want {
+			t.Errorf("altFromPath(%q) = %q, want %q", dest, got, want)
+		}
+	}
+}
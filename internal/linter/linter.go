@@ -2,31 +2,76 @@ package linter
 
 import (
 	"fmt"
-	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
+	"github.com/samzong/mdctl/internal/diff"
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/logging"
 	"github.com/samzong/mdctl/internal/markdownfmt"
+	"github.com/samzong/mdctl/internal/metrics"
+	"github.com/samzong/mdctl/internal/schema"
 )
 
+// logger reports linter configuration problems
+var logger = logging.New("LINT")
+
+// Backup policies for files rewritten by AutoFix
+const (
+	BackupNone = "none" // No backup
+	BackupOrig = "orig" // <file>.orig next to the file, the default
+	BackupDir  = "dir"  // A copy below BackupDir, keeping the file's path
+)
+
+// DefaultBackupDir is where BackupDir keeps copies unless configured
+const DefaultBackupDir = ".mdctl-backup"
+
+// ValidBackup reports whether policy is a backup policy, empty for the default
+func ValidBackup(policy string) bool {
+	switch policy {
+	case "", BackupNone, BackupOrig, BackupDir:
+		return true
+	}
+	return false
+}
+
 // Config holds the linter configuration
 type Config struct {
 	AutoFix      bool
+	DryRun       bool   // With AutoFix, compute fixes as a diff without writing files
+	Backup       string // Backup policy of fixed files, BackupOrig when empty
+	BackupDir    string // Directory of BackupDir copies, DefaultBackupDir when empty
 	OutputFormat string
 	RulesFile    string
 	EnableRules  []string
 	DisableRules []string
 	Verbose      bool
+	Reflow       string         // Paragraph reflow mode fixing MD013, overrides the rules file
+	Baseline     *Baseline      // Known issues that are not reported
+	Spelling     *Spelling      // Enables the spelling and terminology rules
+	Styles       []StyleRule    // Prose style rules
+	FrontMatter  *schema.Schema // Schema the front matter of every file must match
 }
 
 // Issue represents a linting issue
 type Issue struct {
-	Line    int    `json:"line"`
-	Column  int    `json:"column,omitempty"`
-	Rule    string `json:"rule"`
-	Message string `json:"message"`
-	Context string `json:"context,omitempty"`
-	Fixed   bool   `json:"fixed,omitempty"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column,omitempty"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Context  string `json:"context,omitempty"`
+	Fixed    bool   `json:"fixed,omitempty"`
+
+	Suggestion string `json:"suggestion,omitempty"` // Replacement for the offending text
+
+	// Set by Explain for issues that cannot be fixed automatically
+	Explanation string `json:"explanation,omitempty"`
+	Rewrite     string `json:"rewrite,omitempty"` // Suggested rewrite of the passage as a unified diff
+
+	text string // Source line, identifies the issue in a baseline
 }
 
 // Result holds the linting results for a file
@@ -34,6 +79,19 @@ type Result struct {
 	Filename   string   `json:"filename"`
 	Issues     []*Issue `json:"issues"`
 	FixedCount int      `json:"fixed_count"`
+	Baselined  int      `json:"baselined,omitempty"` // Issues suppressed by the baseline
+	Diff       string   `json:"diff,omitempty"`      // Fixes that would be applied in dry-run mode
+}
+
+// Count returns the number of issues of a severity
+func (r *Result) Count(severity string) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			n++
+		}
+	}
+	return n
 }
 
 // Linter performs markdown linting
@@ -47,13 +105,39 @@ type Linter struct {
 // New creates a new linter instance
 func New(config *Config) *Linter {
 	rules := NewRuleSet()
+	fixer := NewFixer()
+
+	// The spelling rule pack is optional, it needs dictionaries
+	if config.Spelling != nil {
+		rules.addRule(&SpellRule{BaseRule: BaseRule{id: SpellRuleID, description: optionalRules[SpellRuleID], enabled: true}, spelling: config.Spelling})
+		rules.addRule(&TermRule{BaseRule: BaseRule{id: TermRuleID, description: optionalRules[TermRuleID], enabled: true}, spelling: config.Spelling})
+		fixer.rules[TermRuleID] = config.Spelling.fixTerms
+	}
+	if config.FrontMatter != nil {
+		rules.addRule(&FrontMatterRule{BaseRule: BaseRule{id: FrontMatterRuleID, description: optionalRules[FrontMatterRuleID], enabled: true}, schema: config.FrontMatter})
+	}
+	if names, ok := rules.rules["MD044"].(*MD044); ok {
+		fixer.rules["MD044"] = names.fix
+	}
+	for _, style := range config.Styles {
+		check := newStyleCheck(style)
+		rules.addRule(check)
+		severity := style.Severity
+		if severity == "" {
+			severity = SeverityWarning
+		}
+		rules.severities[check.ID()] = severity
+		if style.Type == StyleSubstitution {
+			fixer.rules[check.ID()] = check.fix
+		}
+	}
 
 	// Load configuration file if specified
 	if config.RulesFile != "" {
 		if configFile, err := LoadConfigFile(config.RulesFile); err == nil {
 			configFile.ApplyToRuleSet(rules)
 		} else if config.Verbose {
-			fmt.Printf("Warning: Could not load rules file %s: %v\n", config.RulesFile, err)
+			logger.Warnf("Could not load rules file %s: %v", config.RulesFile, err)
 		}
 	} else {
 		// Try to find and load default config file
@@ -71,16 +155,64 @@ func New(config *Config) *Linter {
 		rules.Disable(config.DisableRules)
 	}
 
+	// Long lines are only fixed when a reflow mode is chosen
+	if lineLength, ok := rules.rules["MD013"].(*MD013); ok {
+		if config.Reflow != "" {
+			lineLength.reflow = config.Reflow
+		}
+		if lineLength.reflow != "" {
+			fixer.rules["MD013"] = lineLength.fix
+		}
+	}
+
 	return &Linter{
 		config:    config,
 		rules:     rules,
 		formatter: markdownfmt.New(true), // Enable formatter for auto-fix
-		fixer:     NewFixer(),
+		fixer:     fixer,
+	}
+}
+
+// Outcome is the result of linting one file
+type Outcome struct {
+	Result *Result
+	Err    error
+}
+
+// LintFiles calls lint for files on up to workers goroutines, rules keep no
+// state between files. The outcomes are in the order of files so reports do
+// not depend on scheduling.
+func LintFiles(files []string, workers int, lint func(file string) (*Result, error)) []Outcome {
+	outcomes := make([]Outcome, len(files))
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(files) {
+		workers = len(files)
 	}
+
+	var wg sync.WaitGroup
+	work := make(chan int)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				outcomes[i].Result, outcomes[i].Err = lint(files[i])
+			}
+		}()
+	}
+	for i := range files {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	return outcomes
 }
 
 // LintFile lints a single markdown file
 func (l *Linter) LintFile(filename string) (*Result, error) {
+	metrics.AddFiles(1)
 	// Check file size limit (10MB)
 	const maxFileSize = 10 * 1024 * 1024
 	if info, err := os.Stat(filename); err == nil {
@@ -99,6 +231,16 @@ func (l *Linter) LintFile(filename string) (*Result, error) {
 
 // LintContent lints markdown content
 func (l *Linter) LintContent(filename, content string) (*Result, error) {
+	result, err := l.lintContent(filename, content)
+	if err != nil {
+		return nil, err
+	}
+	l.config.Baseline.Filter(result)
+	return result, nil
+}
+
+// lintContent lints markdown content and applies fixes when requested
+func (l *Linter) lintContent(filename, content string) (*Result, error) {
 	result := &Result{
 		Filename: filename,
 		Issues:   []*Issue{},
@@ -111,20 +253,32 @@ func (l *Linter) LintContent(filename, content string) (*Result, error) {
 		issues := rule.Check(lines)
 		result.Issues = append(result.Issues, issues...)
 	}
+	l.annotate(result.Issues, lines)
 
 	// Apply auto-fix if requested
 	if l.config.AutoFix && len(result.Issues) > 0 {
 		fixedContent, fixedCount := l.applyFixes(content, result.Issues)
+		if fixedContent == content {
+			unmarkFixed(result.Issues)
+			return result, nil
+		}
 		result.FixedCount = fixedCount
 
+		// In dry-run mode only report what would change
+		if l.config.DryRun {
+			result.Diff = diff.Unified(filename, filename+" (fixed)", content, fixedContent)
+			return result, nil
+		}
+
 		// Write fixed content back to file with backup
 		if fixedCount > 0 {
 			// Create backup before modifying the file
-			if err := l.createBackup(filename); err != nil {
+			if err := l.backup(filename, content); err != nil {
 				return nil, fmt.Errorf("failed to create backup: %v", err)
 			}
 
-			if err := os.WriteFile(filename, []byte(fixedContent), 0644); err != nil {
+			// A fix interrupted halfway leaves the original file in place
+			if err := fsutil.WriteFileAtomic(filename, []byte(fixedContent), fileMode(filename)); err != nil {
 				return nil, fmt.Errorf("failed to write fixed content: %v", err)
 			}
 
@@ -140,6 +294,59 @@ func (l *Linter) LintContent(filename, content string) (*Result, error) {
 	return result, nil
 }
 
+// FixContent lints markdown content and returns it with all fixes applied,
+// without touching any file
+func (l *Linter) FixContent(filename, content string) (*Result, string) {
+	result := &Result{
+		Filename: filename,
+		Issues:   []*Issue{},
+	}
+
+	lines := strings.Split(content, "\n")
+	for _, rule := range l.rules.GetEnabledRules() {
+		result.Issues = append(result.Issues, rule.Check(lines)...)
+	}
+	l.annotate(result.Issues, lines)
+	defer l.config.Baseline.Filter(result)
+	if len(result.Issues) == 0 {
+		return result, content
+	}
+
+	fixedContent, fixedCount := l.applyFixes(content, result.Issues)
+	if fixedContent == content {
+		unmarkFixed(result.Issues)
+		return result, content
+	}
+	result.FixedCount = fixedCount
+	if fixedCount > 0 {
+		for _, issue := range result.Issues {
+			if issue.Rule != "MD013" {
+				issue.Fixed = true
+			}
+		}
+	}
+
+	return result, fixedContent
+}
+
+// unmarkFixed clears the fixed flag the fixer sets on issues whose fixes
+// left the content unchanged
+func unmarkFixed(issues []*Issue) {
+	for _, issue := range issues {
+		issue.Fixed = false
+	}
+}
+
+// annotate sets the severity and source line of issues
+func (l *Linter) annotate(issues []*Issue, lines []string) {
+	for _, issue := range issues {
+		issue.Severity = l.rules.Severity(issue.Rule)
+		if issue.Line > 0 && issue.Line <= len(lines) {
+			issue.text = lines[issue.Line-1]
+		}
+	}
+}
+
 // applyFixes applies automatic fixes to the content
 func (l *Linter) applyFixes(content string, issues []*Issue) (string, int) {
 	// Use the dedicated fixer for rule-specific fixes
@@ -156,31 +363,54 @@ func (l *Linter) applyFixes(content string, issues []*Issue) (string, int) {
 	return finalContent, fixedCount
 }
 
-// createBackup creates a backup of the file before modification
-func (l *Linter) createBackup(filename string) error {
-	backupFilename := filename + ".orig"
-
-	// Open source file
-	src, err := os.Open(filename)
-	if err != nil {
-		return fmt.Errorf("failed to open source file: %v", err)
+// backup saves the original content of a file before it is fixed, as the
+// backup policy says
+func (l *Linter) backup(filename, content string) error {
+	var backupFilename string
+	switch l.config.Backup {
+	case BackupNone:
+		return nil
+	case BackupDir:
+		dir := l.config.BackupDir
+		if dir == "" {
+			dir = DefaultBackupDir
+		}
+		backupFilename = filepath.Join(dir, backupPath(filename))
+		if err := os.MkdirAll(filepath.Dir(backupFilename), 0755); err != nil {
+			return fmt.Errorf("failed to create backup directory: %v", err)
+		}
+	default:
+		backupFilename = filename + ".orig"
 	}
-	defer src.Close()
 
-	// Create backup file
-	dst, err := os.Create(backupFilename)
-	if err != nil {
-		return fmt.Errorf("failed to create backup file: %v", err)
+	if err := fsutil.WriteFileAtomic(backupFilename, []byte(content), fileMode(filename)); err != nil {
+		return fmt.Errorf("failed to write backup file: %v", err)
 	}
-	defer dst.Close()
+	return nil
+}
 
-	// Copy content
-	_, err = io.Copy(dst, src)
+// backupPath returns the path of a file inside the backup directory: its path
+// relative to the working directory, or its absolute path without the volume
+// for files outside of it
+func backupPath(filename string) string {
+	abs, err := filepath.Abs(filename)
 	if err != nil {
-		return fmt.Errorf("failed to copy content to backup: %v", err)
+		return filepath.Clean(filename)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return rel
+		}
 	}
+	return strings.TrimLeft(strings.TrimPrefix(abs, filepath.VolumeName(abs)), `/\`)
+}
 
-	return nil
+// fileMode returns the permissions of an existing file, so fixes keep them
+func fileMode(filename string) os.FileMode {
+	if info, err := os.Stat(filename); err == nil {
+		return info.Mode().Perm()
+	}
+	return 0644
 }
 
 // countFixableIssues counts how many issues can be automatically fixed
@@ -192,6 +422,8 @@ func (l *Linter) countFixableIssues(issues []*Issue) int {
 		"MD018": true, // No space after hash on atx style heading
 		"MD019": true, // Multiple spaces after hash on atx style heading
 		"MD023": true, // Headings must start at the beginning of the line
+		"MD044": true, // Proper names should have the correct capitalization
+		"MD045": true, // Images should have alternate text (alt text)
 		"MD047": true, // Files should end with a single newline character
 	}
 
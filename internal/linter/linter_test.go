@@ -2,6 +2,8 @@ package linter
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -213,3 +215,137 @@ func TestLinter_BackupCreation(t *testing.T) {
 		t.Errorf("Backup content doesn't match original.\nExpected: %q\nGot: %q", originalContent, string(backupContent))
 	}
 }
+
+func TestLinter_Baseline(t *testing.T) {
+	original := "# Title  \n\nOld trailing spaces.  \n"
+	result, err := New(&Config{}).LintContent("docs/a.md", original)
+	if err != nil {
+		t.Fatalf("LintContent failed: %v", err)
+	}
+	baseline := NewBaseline([]*Result{result})
+
+	// Known issues stay suppressed when lines move, new ones are reported
+	changed := "Intro\n\n# Title  \n\nOld trailing spaces.  \nNew trailing spaces.  \n"
+	result, err = New(&Config{Baseline: baseline}).LintContent("./docs/a.md", changed)
+	if err != nil {
+		t.Fatalf("LintContent failed: %v", err)
+	}
+	var md009 []*Issue
+	for _, issue := range result.Issues {
+		if issue.Rule == "MD009" {
+			md009 = append(md009, issue)
+		}
+	}
+	if len(md009) != 1 || md009[0].Line != 6 {
+		t.Errorf("expected only the new MD009 issue on line 6, got %+v", md009)
+	}
+	if result.Baselined != 2 {
+		t.Errorf("expected 2 baselined issues, got %d", result.Baselined)
+	}
+}
+
+func TestLinter_Severity(t *testing.T) {
+	l := New(&Config{EnableRules: []string{"MD009", "MD047"}})
+	if err := l.rules.SetSeverity("MD009", SeverityWarning); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.rules.SetSeverity("MD009", "fatal"); err == nil {
+		t.Error("expected an error for an unknown severity")
+	}
+
+	result, err := l.LintContent("a.md", "# Title  \n\nText")
+	if err != nil {
+		t.Fatalf("LintContent failed: %v", err)
+	}
+	if result.Count(SeverityWarning) != 1 || result.Count(SeverityError) != 1 {
+		t.Errorf("unexpected severities: %+v", result.Issues)
+	}
+}
+
+func TestLinter_Reflow(t *testing.T) {
+	content := "# Title\n\n" + strings.Repeat("word ", 30) + "end.\n"
+
+	l := New(&Config{EnableRules: []string{"MD013"}})
+	result, fixed := l.FixContent("a.md", content)
+	if len(result.Issues) != 1 || fixed != content {
+		t.Fatalf("long lines must not be fixed without a reflow mode: %d issues\n%s", len(result.Issues), fixed)
+	}
+
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, ".markdownlint.json")
+	os.WriteFile(configFile, []byte(`{"MD013": {"line_length": 60, "reflow": "wrap"}}`), 0644)
+	l = New(&Config{RulesFile: configFile, EnableRules: []string{"MD013"}})
+	result, fixed = l.FixContent("a.md", content)
+	if result.FixedCount != 1 {
+		t.Errorf("expected 1 fixed line, got %d", result.FixedCount)
+	}
+	for _, line := range strings.Split(fixed, "\n") {
+		if len(line) > 60 {
+			t.Errorf("line longer than 60 characters after reflow: %q", line)
+		}
+	}
+	if issues, _ := l.LintContent("a.md", fixed); len(issues.Issues) != 0 {
+		t.Errorf("reflowed content still has issues: %+v", issues.Issues)
+	}
+}
+
+func TestLinter_FixNoOp(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "clean.md")
+	content := "# Title\n\nContent.\n"
+	os.WriteFile(path, []byte(content), 0644)
+
+	// MD047 is fixable but the file already ends with a single newline
+	result, err := New(&Config{AutoFix: true, EnableRules: []string{"MD047", "MD009"}}).LintFile(path)
+	if err != nil {
+		t.Fatalf("LintFile failed: %v", err)
+	}
+	if result.FixedCount != 0 {
+		t.Errorf("expected no fixes, got %d", result.FixedCount)
+	}
+	if _, err := os.Stat(path + ".orig"); !os.IsNotExist(err) {
+		t.Error("expected no backup for an unchanged file")
+	}
+
+	fixer := NewFixer()
+	if _, fixed := fixer.fixFileEndNewline(strings.Split(content, "\n")); fixed != 0 {
+		t.Errorf("MD047 counted %d fixes on a file ending with a newline", fixed)
+	}
+	if _, fixed := fixer.fixFileEndNewline(strings.Split("# Title\n\n\n", "\n")); fixed != 1 {
+		t.Errorf("MD047 counted %d fixes on a file ending with blank lines", fixed)
+	}
+}
+
+func TestLinter_BackupPolicies(t *testing.T) {
+	original := "# Title  \n\nContent with trailing spaces.  \n"
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	os.Mkdir("docs", 0755)
+	path := filepath.Join("docs", "doc.md")
+
+	for _, policy := range []string{BackupNone, BackupDir} {
+		os.WriteFile(path, []byte(original), 0600)
+		result, err := New(&Config{AutoFix: true, Backup: policy, BackupDir: "backups"}).LintFile(path)
+		if err != nil {
+			t.Fatalf("%s: LintFile failed: %v", policy, err)
+		}
+		if result.FixedCount == 0 {
+			t.Fatalf("%s: expected fixes", policy)
+		}
+		if _, err := os.Stat(path + ".orig"); !os.IsNotExist(err) {
+			t.Errorf("%s: unexpected .orig backup", policy)
+		}
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("%s: expected the fixed file to keep its permissions, got %v %v", policy, info, err)
+		}
+	}
+
+	backup, err := os.ReadFile(filepath.Join("backups", "docs", "doc.md"))
+	if err != nil || string(backup) != original {
+		t.Errorf("expected the original below the backup directory, got %q %v", backup, err)
+	}
+}
@@ -0,0 +1,49 @@
+package linter
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/mddoc"
+)
+
+var (
+	// codeSpanRegex matches inline code spans
+	codeSpanRegex = regexp.MustCompile("`+[^`]*`+")
+	// linkDestRegex matches link and image destinations, the text is kept
+	linkDestRegex = regexp.MustCompile(`\]\([^)]*\)`)
+	// refDefRegex matches link reference definitions
+	refDefRegex = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s*\S+.*$`)
+	// urlRegex matches bare URLs and e-mail addresses
+	urlRegex = regexp.MustCompile(`(?:https?://|www\.)\S+|\S+@\S+\.\w+`)
+	// htmlRegex matches HTML tags and comments
+	htmlRegex = regexp.MustCompile(`<!--.*?-->|</?[A-Za-z][^>]*>`)
+)
+
+// proseLines returns the lines of a document with everything that is not
+// prose blanked out: front matter, code blocks, code spans, URLs, link
+// destinations and HTML. Blanked text is replaced by spaces, so byte offsets
+// in the returned lines are offsets in the original lines.
+func proseLines(lines []string) []string {
+	prose := make([]string, len(lines))
+	start := mddoc.Split([]byte(strings.Join(lines, "\n"))).BodyLine - 1
+
+	var fences mddoc.Fences
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		if fences.Line(line) || refDefRegex.MatchString(line) {
+			continue
+		}
+
+		for _, re := range []*regexp.Regexp{codeSpanRegex, linkDestRegex, htmlRegex, urlRegex} {
+			line = re.ReplaceAllStringFunc(line, blank)
+		}
+		prose[i] = line
+	}
+	return prose
+}
+
+// blank replaces text with spaces of the same byte length
+func blank(s string) string {
+	return strings.Repeat(" ", len(s))
+}
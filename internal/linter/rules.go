@@ -2,9 +2,24 @@ package linter
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
+
+	"github.com/samzong/mdctl/internal/markdownfmt"
+)
+
+var (
+	// noSpaceHeadingRegex matches atx headings without a space after the hashes
+	noSpaceHeadingRegex = regexp.MustCompile(`^#+[^# ]`)
+	// multiSpaceHeadingRegex matches atx headings with several spaces after the hashes
+	multiSpaceHeadingRegex = regexp.MustCompile(`^#+  +`)
+	// indentedHeadingRegex matches indented headings
+	indentedHeadingRegex = regexp.MustCompile(`^ +#`)
+	// listItemRegex matches unordered list items
+	listItemRegex = regexp.MustCompile(`^[*+-] `)
 )
 
 // Rule represents a markdown linting rule
@@ -28,15 +43,24 @@ func (r *BaseRule) Description() string     { return r.description }
 func (r *BaseRule) Enabled() bool           { return r.enabled }
 func (r *BaseRule) SetEnabled(enabled bool) { r.enabled = enabled }
 
+// Severity levels of issues
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
 // RuleSet manages a collection of linting rules
 type RuleSet struct {
-	rules map[string]Rule
+	rules      map[string]Rule
+	severities map[string]string
 }
 
 // NewRuleSet creates a new rule set with default rules
 func NewRuleSet() *RuleSet {
 	rs := &RuleSet{
-		rules: make(map[string]Rule),
+		rules:      make(map[string]Rule),
+		severities: make(map[string]string),
 	}
 
 	// Add default rules
@@ -50,16 +74,43 @@ func NewRuleSet() *RuleSet {
 	rs.addRule(&MD019{BaseRule: BaseRule{id: "MD019", description: "Multiple spaces after hash on atx style heading", enabled: true}})
 	rs.addRule(&MD023{BaseRule: BaseRule{id: "MD023", description: "Headings must start at the beginning of the line", enabled: true}})
 	rs.addRule(&MD032{BaseRule: BaseRule{id: "MD032", description: "Lists should be surrounded by blank lines", enabled: true}})
+	rs.addRule(&MD042{BaseRule: BaseRule{id: "MD042", description: "No empty links", enabled: true}})
+	rs.addRule(&MD044{BaseRule: BaseRule{id: "MD044", description: "Proper names should have the correct capitalization", enabled: true}})
+	rs.addRule(&MD045{BaseRule: BaseRule{id: "MD045", description: "Images should have alternate text (alt text)", enabled: true}})
 	rs.addRule(&MD047{BaseRule: BaseRule{id: "MD047", description: "Files should end with a single newline character", enabled: true}})
 
+	rs.addRule(&MD052{BaseRule: BaseRule{id: "MD052", description: "Reference links and images should use a label that is defined", enabled: true}})
+
 	return rs
 }
 
+// optionalRules describes the rules that are only added when their rule
+// pack is configured
+var optionalRules = map[string]string{
+	SpellRuleID:       "Spelling",
+	TermRuleID:        "Terminology and product name casing",
+	FrontMatterRuleID: "Front matter should match the schema",
+}
+
+// RuleDescriptions returns the description of every built-in rule by ID,
+// including the optional rule packs but not style rules loaded from files
+func RuleDescriptions() map[string]string {
+	descriptions := make(map[string]string)
+	for id, rule := range NewRuleSet().rules {
+		descriptions[id] = rule.Description()
+	}
+	for id, description := range optionalRules {
+		descriptions[id] = description
+	}
+	return descriptions
+}
+
 func (rs *RuleSet) addRule(rule Rule) {
 	rs.rules[rule.ID()] = rule
 }
 
-// GetEnabledRules returns all enabled rules
+// GetEnabledRules returns all enabled rules ordered by ID, so the issues of
+// a file are reported in the same order on every run
 func (rs *RuleSet) GetEnabledRules() []Rule {
 	var enabled []Rule
 	for _, rule := range rs.rules {
@@ -67,6 +118,7 @@ func (rs *RuleSet) GetEnabledRules() []Rule {
 			enabled = append(enabled, rule)
 		}
 	}
+	sort.Slice(enabled, func(i, j int) bool { return enabled[i].ID() < enabled[j].ID() })
 	return enabled
 }
 
@@ -85,6 +137,28 @@ func (rs *RuleSet) EnableOnly(ruleIDs []string) {
 	}
 }
 
+// SetSeverity sets the severity of the issues a rule reports
+func (rs *RuleSet) SetSeverity(ruleID, severity string) error {
+	switch severity {
+	case SeverityError, SeverityWarning, SeverityInfo:
+	default:
+		return fmt.Errorf("unknown severity %q (must be error, warning or info)", severity)
+	}
+	if _, exists := rs.rules[ruleID]; !exists {
+		return fmt.Errorf("unknown rule %s", ruleID)
+	}
+	rs.severities[ruleID] = severity
+	return nil
+}
+
+// Severity returns the severity of a rule, error unless configured otherwise
+func (rs *RuleSet) Severity(ruleID string) string {
+	if severity, ok := rs.severities[ruleID]; ok {
+		return severity
+	}
+	return SeverityError
+}
+
 // Disable disables the specified rules
 func (rs *RuleSet) Disable(ruleIDs []string) {
 	for _, id := range ruleIDs {
@@ -117,6 +191,11 @@ func (rs *RuleSet) LoadFromFile(filename string) error {
 				if enabled, ok := v["enabled"].(bool); ok {
 					rule.SetEnabled(enabled)
 				}
+				if severity, ok := v["severity"].(string); ok {
+					if err := rs.SetSeverity(ruleID, severity); err != nil {
+						return err
+					}
+				}
 			}
 		}
 	}
@@ -265,14 +344,25 @@ func (r *MD012) Check(lines []string) []*Issue {
 // MD013: Line length
 type MD013 struct {
 	BaseRule
+	lineLength int    // Maximum display width, markdownfmt.DefaultWidth when 0
+	reflow     string // Reflow mode of the fix, lines are not fixed when empty
+}
+
+// maxLength returns the configured line length
+func (r *MD013) maxLength() int {
+	if r.lineLength > 0 {
+		return r.lineLength
+	}
+	return markdownfmt.DefaultWidth
 }
 
 func (r *MD013) Check(lines []string) []*Issue {
 	var issues []*Issue
-	maxLength := 80 // Default line length limit
+	maxLength := r.maxLength()
 
 	for i, line := range lines {
-		if len(line) > maxLength {
+		// CJK characters take two columns
+		if markdownfmt.DisplayWidth(line) > maxLength {
 			issues = append(issues, &Issue{
 				Line:    i + 1,
 				Rule:    r.ID(),
@@ -285,23 +375,25 @@ func (r *MD013) Check(lines []string) []*Issue {
 	return issues
 }
 
+// fix reflows paragraphs to the line length, it returns the number of lines
+// that are no longer too long
+func (r *MD013) fix(lines []string) ([]string, int) {
+	before := len(r.Check(lines))
+	reflowed := strings.Split(markdownfmt.Reflow(strings.Join(lines, "\n"), markdownfmt.ReflowOptions{Mode: r.reflow, Width: r.maxLength()}), "\n")
+	return reflowed, max(before-len(r.Check(reflowed)), 0)
+}
+
 // MD018: No space after hash on atx style heading
 type MD018 struct {
 	BaseRule
-	pattern *regexp.Regexp
 }
 
 func (r *MD018) Check(lines []string) []*Issue {
 	var issues []*Issue
 
-	// Initialize pattern if not already done
-	if r.pattern == nil {
-		r.pattern = regexp.MustCompile(`^#+[^# ]`)
-	}
-
 	for i, line := range lines {
 		line = strings.TrimSpace(line)
-		if r.pattern.MatchString(line) {
+		if noSpaceHeadingRegex.MatchString(line) {
 			issues = append(issues, &Issue{
 				Line:    i + 1,
 				Rule:    r.ID(),
@@ -317,20 +409,14 @@ func (r *MD018) Check(lines []string) []*Issue {
 // MD019: Multiple spaces after hash on atx style heading
 type MD019 struct {
 	BaseRule
-	pattern *regexp.Regexp
 }
 
 func (r *MD019) Check(lines []string) []*Issue {
 	var issues []*Issue
 
-	// Initialize pattern if not already done
-	if r.pattern == nil {
-		r.pattern = regexp.MustCompile(`^#+  +`)
-	}
-
 	for i, line := range lines {
 		line = strings.TrimSpace(line)
-		if r.pattern.MatchString(line) {
+		if multiSpaceHeadingRegex.MatchString(line) {
 			issues = append(issues, &Issue{
 				Line:    i + 1,
 				Rule:    r.ID(),
@@ -346,19 +432,13 @@ func (r *MD019) Check(lines []string) []*Issue {
 // MD023: Headings must start at the beginning of the line
 type MD023 struct {
 	BaseRule
-	pattern *regexp.Regexp
 }
 
 func (r *MD023) Check(lines []string) []*Issue {
 	var issues []*Issue
 
-	// Initialize pattern if not already done
-	if r.pattern == nil {
-		r.pattern = regexp.MustCompile(`^ +#`)
-	}
-
 	for i, line := range lines {
-		if r.pattern.MatchString(line) {
+		if indentedHeadingRegex.MatchString(line) {
 			issues = append(issues, &Issue{
 				Line:    i + 1,
 				Rule:    r.ID(),
@@ -374,25 +454,19 @@ func (r *MD023) Check(lines []string) []*Issue {
 // MD032: Lists should be surrounded by blank lines
 type MD032 struct {
 	BaseRule
-	pattern *regexp.Regexp
 }
 
 func (r *MD032) Check(lines []string) []*Issue {
 	var issues []*Issue
 
-	// Initialize pattern if not already done
-	if r.pattern == nil {
-		r.pattern = regexp.MustCompile(`^[*+-] `)
-	}
-
 	for i, line := range lines {
 		line = strings.TrimSpace(line)
-		if r.pattern.MatchString(line) {
+		if listItemRegex.MatchString(line) {
 			// Check if previous line is not blank (and not start of file)
 			// and previous line is not also a list item
 			if i > 0 {
 				prevLine := strings.TrimSpace(lines[i-1])
-				if prevLine != "" && !r.pattern.MatchString(prevLine) {
+				if prevLine != "" && !listItemRegex.MatchString(prevLine) {
 					issues = append(issues, &Issue{
 						Line:    i + 1,
 						Rule:    r.ID(),
@@ -407,7 +481,7 @@ func (r *MD032) Check(lines []string) []*Issue {
 				nextLine := strings.TrimSpace(lines[i+1])
 				// Only check if next line exists and is not empty
 				if nextLine != "" {
-					nextIsListItem := r.pattern.MatchString(nextLine)
+					nextIsListItem := listItemRegex.MatchString(nextLine)
 					// If next line is not a list item, this is the end of the list
 					if !nextIsListItem {
 						issues = append(issues, &Issue{
@@ -458,3 +532,66 @@ func (r *MD047) Check(lines []string) []*Issue {
 
 	return issues
 }
+
+// MD044: Proper names should have the correct capitalization
+type MD044 struct {
+	BaseRule
+	names   map[string]string // Lowercase name to its correct form
+	pattern *regexp.Regexp
+}
+
+// SetNames sets the proper names the rule checks, from the names option of
+// the configuration file
+func (r *MD044) SetNames(names []string) {
+	r.names = make(map[string]string)
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			r.names[strings.ToLower(name)] = name
+		}
+	}
+	r.pattern = nil
+	if len(r.names) > 0 {
+		r.pattern = termRegex(r.names)
+	}
+}
+
+func (r *MD044) Check(lines []string) []*Issue {
+	var issues []*Issue
+	if r.pattern == nil {
+		return issues
+	}
+
+	// Code, URLs and link destinations keep their spelling
+	for i, line := range proseLines(lines) {
+		for _, m := range findTerms(r.pattern, r.names, line) {
+			issues = append(issues, &Issue{
+				Line:       i + 1,
+				Column:     m.start + 1,
+				Rule:       r.ID(),
+				Message:    fmt.Sprintf("Proper names should have the correct capitalization, expected %q instead of %q", m.replacement, line[m.start:m.end]),
+				Context:    lines[i],
+				Suggestion: m.replacement,
+			})
+		}
+	}
+
+	return issues
+}
+
+// fix rewrites proper names to their correct capitalization
+func (r *MD044) fix(lines []string) ([]string, int) {
+	fixed := 0
+	if r.pattern == nil {
+		return lines, fixed
+	}
+	prose := proseLines(lines)
+	for i := range lines {
+		matches := findTerms(r.pattern, r.names, prose[i])
+		for j := len(matches) - 1; j >= 0; j-- {
+			m := matches[j]
+			lines[i] = lines[i][:m.start] + m.replacement + lines[i][m.end:]
+			fixed++
+		}
+	}
+	return lines, fixed
+}
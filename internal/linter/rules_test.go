@@ -1,6 +1,11 @@
 package linter
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -130,38 +135,26 @@ func TestMD032_ListBlankLines(t *testing.T) {
 
 func TestRegexPrecompilation(t *testing.T) {
 	tests := []struct {
-		name string
-		rule Rule
+		name    string
+		rule    Rule
+		pattern *regexp.Regexp
+		line    string // Line the rule reports
 	}{
-		{"MD018", &MD018{BaseRule: BaseRule{id: "MD018", enabled: true}}},
-		{"MD019", &MD019{BaseRule: BaseRule{id: "MD019", enabled: true}}},
-		{"MD023", &MD023{BaseRule: BaseRule{id: "MD023", enabled: true}}},
-		{"MD032", &MD032{BaseRule: BaseRule{id: "MD032", enabled: true}}},
+		{"MD018", &MD018{BaseRule: BaseRule{id: "MD018", enabled: true}}, noSpaceHeadingRegex, "#Test"},
+		{"MD019", &MD019{BaseRule: BaseRule{id: "MD019", enabled: true}}, multiSpaceHeadingRegex, "#  Test"},
+		{"MD023", &MD023{BaseRule: BaseRule{id: "MD023", enabled: true}}, indentedHeadingRegex, "  # Test"},
+		{"MD032", &MD032{BaseRule: BaseRule{id: "MD032", enabled: true}}, listItemRegex, "- Item"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Call Check method to trigger regex compilation
-			_ = tt.rule.Check([]string{"# Test", "Content"})
-
-			// Check that pattern was compiled for rules that have patterns
-			switch rule := tt.rule.(type) {
-			case *MD018:
-				if rule.pattern == nil {
-					t.Error("MD018 pattern was not compiled")
-				}
-			case *MD019:
-				if rule.pattern == nil {
-					t.Error("MD019 pattern was not compiled")
-				}
-			case *MD023:
-				if rule.pattern == nil {
-					t.Error("MD023 pattern was not compiled")
-				}
-			case *MD032:
-				if rule.pattern == nil {
-					t.Error("MD032 pattern was not compiled")
-				}
+			// Patterns are compiled with the package, rules share them
+			// when files are checked concurrently
+			if tt.pattern == nil {
+				t.Fatalf("%s pattern was not compiled", tt.name)
+			}
+			if issues := tt.rule.Check([]string{"Content", tt.line, "Content"}); len(issues) == 0 {
+				t.Errorf("%s did not report %q", tt.name, tt.line)
 			}
 		})
 	}
@@ -249,3 +242,35 @@ func TestMD023_HeadingAtStartOfLine(t *testing.T) {
 		})
 	}
 }
+
+func TestMD044_ProperNames(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, ".markdownlint.json")
+	os.WriteFile(configFile, []byte(`{"MD044": {"names": ["Kubernetes", "macOS", "JavaScript"]}}`), 0644)
+
+	l := New(&Config{RulesFile: configFile, EnableRules: []string{"MD044"}})
+	content := "# Running kubernetes on MacOS\n\nWrite javascript, not `javascript` or https://kubernetes.io/docs.\n\n```\nkubernetes\n```\n"
+	result, err := l.LintContent("a.md", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, issue := range result.Issues {
+		got = append(got, fmt.Sprintf("%d:%d:%s", issue.Line, issue.Column, issue.Suggestion))
+	}
+	if strings.Join(got, ",") != "1:11:Kubernetes,1:25:macOS,3:7:JavaScript" {
+		t.Errorf("unexpected issues: %v", got)
+	}
+
+	_, fixed := l.FixContent("a.md", content)
+	want := "# Running Kubernetes on macOS\n\nWrite JavaScript, not `javascript` or https://kubernetes.io/docs.\n\n```\nkubernetes\n```\n"
+	if fixed != want {
+		t.Errorf("unexpected fix:\n%s", fixed)
+	}
+
+	// Without configured names the rule reports nothing
+	if issues := (&MD044{}).Check([]string{"kubernetes"}); len(issues) != 0 {
+		t.Errorf("expected no issues without names, got %d", len(issues))
+	}
+}
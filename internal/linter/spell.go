@@ -0,0 +1,415 @@
+package linter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rule IDs of the spelling rule pack
+const (
+	SpellRuleID = "SPELL001"
+	TermRuleID  = "TERM001"
+)
+
+// DefaultWordsFile is the project word allowlist used when present
+const DefaultWordsFile = ".mdctl-words.txt"
+
+var (
+	// wordRegex matches words, including contractions
+	wordRegex = regexp.MustCompile(`\p{L}+(?:['’]\p{L}+)*`)
+	// defaultTerms are product names and their correct casing
+	defaultTerms = []string{
+		"GitHub", "GitLab", "Bitbucket", "JavaScript", "TypeScript", "CoffeeScript",
+		"Node.js", "Vue.js", "Next.js", "macOS", "iOS", "iPadOS", "watchOS", "tvOS",
+		"iPhone", "iPad", "Xcode", "PostgreSQL", "MySQL", "MariaDB", "SQLite",
+		"MongoDB", "Redis", "Kubernetes", "OpenShift", "PyPI", "npm", "pnpm",
+		"YouTube", "LinkedIn", "WordPress", "PowerShell", "OAuth", "GraphQL",
+		"WebAssembly", "WebSocket", "OpenAI", "LaTeX", "YAML", "JSON", "TOML",
+		"Wi-Fi", "VS Code", "IntelliJ", "DynamoDB", "CloudFront", "BigQuery",
+		"e-mail => email", "web site => website",
+	}
+)
+
+// SpellOptions selects the dictionaries and word lists of the spelling rules
+type SpellOptions struct {
+	Languages    []string // Dictionary languages such as en or de_DE, default en without Dictionaries
+	Dictionaries []string // Additional dictionary files
+	WordsFile    string   // Project allowlist, one word per line
+	TermsFile    string   // Terminology, one term or "wrong => right" per line
+}
+
+// Spelling holds the dictionaries of the spelling and terminology rules,
+// it is read-only once loaded and shared between files
+type Spelling struct {
+	words   map[string]bool
+	allowed map[string]bool
+	terms   map[string]string // Lowercase term to correct form
+	termRe  *regexp.Regexp
+}
+
+// LoadSpelling loads the dictionaries of the configured languages, the
+// project allowlist and the terminology. Dictionaries are plain word lists
+// (<lang>.txt) or Hunspell .dic files looked up in .mdctl/dictionaries,
+// ~/.config/mdctl/dictionaries and the system Hunspell directories.
+func LoadSpelling(opts SpellOptions) (*Spelling, error) {
+	s := &Spelling{
+		words:   make(map[string]bool),
+		allowed: make(map[string]bool),
+		terms:   make(map[string]string),
+	}
+
+	if len(opts.Languages) == 0 && len(opts.Dictionaries) == 0 {
+		opts.Languages = []string{"en"}
+	}
+	for _, lang := range opts.Languages {
+		path := findDictionary(lang)
+		if path == "" {
+			return nil, fmt.Errorf("no dictionary found for language %s, install a Hunspell dictionary or add .mdctl/dictionaries/%s.txt", lang, lang)
+		}
+		if err := loadWords(path, s.words); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range opts.Dictionaries {
+		if err := loadWords(path, s.words); err != nil {
+			return nil, err
+		}
+	}
+
+	wordsFile := opts.WordsFile
+	if wordsFile == "" {
+		if _, err := os.Stat(DefaultWordsFile); err == nil {
+			wordsFile = DefaultWordsFile
+		}
+	}
+	if wordsFile != "" {
+		if err := loadWords(wordsFile, s.allowed); err != nil {
+			return nil, err
+		}
+	}
+
+	terms := defaultTerms
+	if opts.TermsFile != "" {
+		extra, err := readList(opts.TermsFile)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(append([]string{}, terms...), extra...)
+	}
+	s.addTerms(terms)
+	return s, nil
+}
+
+// findDictionary returns the dictionary file of a language
+func findDictionary(lang string) string {
+	variants := []string{lang}
+	switch lang {
+	case "en":
+		variants = append(variants, "en_US", "en_GB")
+	case "de", "fr", "es", "it", "pt", "nl":
+		variants = append(variants, lang+"_"+strings.ToUpper(lang))
+	}
+
+	var dirs []string
+	dirs = append(dirs, filepath.Join(".mdctl", "dictionaries"))
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".config", "mdctl", "dictionaries"))
+	}
+	dirs = append(dirs, "/usr/share/hunspell", "/usr/share/myspell", "/usr/share/myspell/dicts", "/Library/Spelling")
+
+	for _, dir := range dirs {
+		for _, variant := range variants {
+			for _, ext := range []string{".txt", ".dic"} {
+				path := filepath.Join(dir, variant+ext)
+				if _, err := os.Stat(path); err == nil {
+					return path
+				}
+			}
+		}
+	}
+	if strings.HasPrefix(lang, "en") {
+		if _, err := os.Stat("/usr/share/dict/words"); err == nil {
+			return "/usr/share/dict/words"
+		}
+	}
+	return ""
+}
+
+// loadWords adds the words of a word list or Hunspell .dic file to words
+func loadWords(path string, words map[string]bool) error {
+	list, err := readList(path)
+	if err != nil {
+		return err
+	}
+	for i, word := range list {
+		// Hunspell dictionaries start with the word count and mark affixes after /
+		if i == 0 && strings.HasSuffix(path, ".dic") && strings.Trim(word, "0123456789") == "" {
+			continue
+		}
+		if idx := strings.IndexByte(word, '/'); idx > 0 {
+			word = word[:idx]
+		}
+		words[word] = true
+	}
+	return nil
+}
+
+// readList reads the non-empty lines of a file, # starts a comment
+func readList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open word list: %v", err)
+	}
+	defer f.Close()
+
+	var list []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list = append(list, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return list, nil
+}
+
+// addTerms registers terms and builds the regular expression matching them
+func (s *Spelling) addTerms(terms []string) {
+	for _, term := range terms {
+		wrong, right, ok := strings.Cut(term, "=>")
+		if !ok {
+			right = term
+		}
+		wrong, right = strings.TrimSpace(wrong), strings.TrimSpace(right)
+		s.terms[strings.ToLower(wrong)] = right
+		s.allowed[right] = true
+	}
+
+	s.termRe = termRegex(s.terms)
+}
+
+// termRegex builds the regular expression matching the lowercase keys of
+// terms as whole words in any case
+func termRegex(terms map[string]string) *regexp.Regexp {
+	keys := make([]string, 0, len(terms))
+	for key := range terms {
+		keys = append(keys, regexp.QuoteMeta(key))
+	}
+	// Longer terms first so "vs code" wins over "code"
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}_./@-])(` + strings.Join(keys, "|") + `)(?:$|[^\p{L}\p{N}_/@-])`)
+}
+
+// known reports whether a word is spelled correctly
+func (s *Spelling) known(word string) bool {
+	word = strings.TrimSuffix(strings.TrimSuffix(word, "'s"), "’s")
+	word = strings.ReplaceAll(word, "’", "'")
+	lower := strings.ToLower(word)
+	// Misspelled terms are reported by the terminology rule
+	_, term := s.terms[lower]
+	return term || s.words[word] || s.words[lower] || s.allowed[word] || s.allowed[lower]
+}
+
+// suggest returns dictionary words one edit away from word
+func (s *Spelling) suggest(word string) []string {
+	lower := []rune(strings.ToLower(word))
+	candidates := make(map[string]bool)
+	try := func(r []rune) {
+		if w := string(r); s.words[w] {
+			candidates[w] = true
+		}
+	}
+
+	for i := 0; i <= len(lower); i++ {
+		if i < len(lower) {
+			try(append(append([]rune{}, lower[:i]...), lower[i+1:]...)) // Deletion
+		}
+		if i+1 < len(lower) {
+			swapped := append([]rune{}, lower...)
+			swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+			try(swapped) // Transposition
+		}
+		for c := 'a'; c <= 'z'; c++ {
+			if i < len(lower) {
+				replaced := append([]rune{}, lower...)
+				replaced[i] = c
+				try(replaced) // Substitution
+			}
+			try(append(append(append([]rune{}, lower[:i]...), c), lower[i:]...)) // Insertion
+		}
+	}
+
+	suggestions := make([]string, 0, len(candidates))
+	for c := range candidates {
+		if r, _ := utf8.DecodeRuneInString(word); unicode.IsUpper(r) {
+			c = strings.ToUpper(c[:1]) + c[1:]
+		}
+		suggestions = append(suggestions, c)
+	}
+	sort.Strings(suggestions)
+	if len(suggestions) > 3 {
+		suggestions = suggestions[:3]
+	}
+	return suggestions
+}
+
+// skipWord reports whether a token is not a plain prose word: identifiers,
+// acronyms, file names and words in other scripts are not spell-checked
+func skipWord(line string, start, end int) bool {
+	word := line[start:end]
+	if utf8.RuneCountInString(word) < 2 {
+		return true
+	}
+	for i, r := range word {
+		if !unicode.Is(unicode.Latin, r) && r != '\'' && r != '’' {
+			return true
+		}
+		// camelCase, PascalCase inside words and ACRONYMS
+		if i > 0 && unicode.IsUpper(r) {
+			return true
+		}
+	}
+	if start > 0 && strings.ContainsRune("_./\\@#$%&0123456789", rune(line[start-1])) {
+		return true
+	}
+	if end < len(line) {
+		next := line[end]
+		if strings.ContainsRune("_/\\@0123456789", rune(next)) {
+			return true
+		}
+		if next == '.' && end+1 < len(line) && unicode.IsLetter(rune(line[end+1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// SpellRule reports words missing from the dictionaries and allowlist
+type SpellRule struct {
+	BaseRule
+	spelling *Spelling
+}
+
+func (r *SpellRule) Check(lines []string) []*Issue {
+	var issues []*Issue
+	for i, line := range proseLines(lines) {
+		for _, loc := range wordRegex.FindAllStringIndex(line, -1) {
+			if skipWord(line, loc[0], loc[1]) {
+				continue
+			}
+			word := line[loc[0]:loc[1]]
+			if r.spelling.known(word) {
+				continue
+			}
+
+			issue := &Issue{
+				Line:    i + 1,
+				Column:  loc[0] + 1,
+				Rule:    r.ID(),
+				Message: fmt.Sprintf("Unknown word %q", word),
+				Context: lines[i],
+			}
+			if suggestions := r.spelling.suggest(word); len(suggestions) > 0 {
+				issue.Suggestion = suggestions[0]
+				issue.Message += fmt.Sprintf(", did you mean %s?", strings.Join(suggestions, ", "))
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// TermRule reports product names and terms written with the wrong casing or
+// spelling, e.g. Github instead of GitHub
+type TermRule struct {
+	BaseRule
+	spelling *Spelling
+}
+
+func (r *TermRule) Check(lines []string) []*Issue {
+	var issues []*Issue
+	for i, line := range proseLines(lines) {
+		for _, m := range r.spelling.termMatches(line) {
+			issues = append(issues, &Issue{
+				Line:       i + 1,
+				Column:     m.start + 1,
+				Rule:       r.ID(),
+				Message:    fmt.Sprintf("Use %q instead of %q", m.replacement, line[m.start:m.end]),
+				Context:    lines[i],
+				Suggestion: m.replacement,
+			})
+		}
+	}
+	return issues
+}
+
+// termMatch is a term written incorrectly
+type termMatch struct {
+	start, end  int
+	replacement string
+}
+
+// termMatches finds the incorrectly written terms of a prose line
+func (s *Spelling) termMatches(line string) []termMatch {
+	return findTerms(s.termRe, s.terms, line)
+}
+
+// findTerms finds the terms matched by re that are not written like their
+// correct form in terms
+func findTerms(re *regexp.Regexp, terms map[string]string, line string) []termMatch {
+	var matches []termMatch
+	for offset := 0; offset < len(line); {
+		loc := re.FindStringSubmatchIndex(line[offset:])
+		if loc == nil {
+			break
+		}
+		start, end := offset+loc[2], offset+loc[3]
+		offset = end
+
+		text := line[start:end]
+		right := terms[strings.ToLower(text)]
+		if text == right {
+			continue
+		}
+		// Replacements keep a capital at the start of a sentence
+		if r, _ := utf8.DecodeRuneInString(text); unicode.IsUpper(r) && strings.ToLower(right) == right {
+			right = strings.ToUpper(right[:1]) + right[1:]
+			if text == right {
+				continue
+			}
+		}
+		matches = append(matches, termMatch{start: start, end: end, replacement: right})
+	}
+	return matches
+}
+
+// fixTerms rewrites incorrectly written terms
+func (s *Spelling) fixTerms(lines []string) ([]string, int) {
+	fixed := 0
+	prose := proseLines(lines)
+	for i := range lines {
+		matches := s.termMatches(prose[i])
+		for j := len(matches) - 1; j >= 0; j-- {
+			m := matches[j]
+			lines[i] = lines[i][:m.start] + m.replacement + lines[i][m.end:]
+			fixed++
+		}
+	}
+	return lines, fixed
+}
@@ -0,0 +1,41 @@
+package linter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSpelling(t *testing.T) {
+	dir := t.TempDir()
+	dict := filepath.Join(dir, "en_US.dic")
+	words := filepath.Join(dir, "words.txt")
+	os.WriteFile(dict, []byte("12\nthe/S\nquick\nbrown\nfox\nover\nuse\ncode\nand\nsee\n"), 0644)
+	os.WriteFile(words, []byte("# project words\nmdctl\n"), 0644)
+
+	spelling, err := LoadSpelling(SpellOptions{Dictionaries: []string{dict}, WordsFile: words})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := New(&Config{EnableRules: []string{SpellRuleID, TermRuleID}, Spelling: spelling})
+	content := "# The quick brwon fox\n\nUse mdctl over `teh` code and Github, see https://github.com/x.\n\n```\nteh\n```\n"
+	result, err := l.LintContent("a.md", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, issue := range result.Issues {
+		got = append(got, issue.Rule+":"+issue.Suggestion)
+	}
+	if strings.Join(got, ",") != "SPELL001:brown,TERM001:GitHub" {
+		t.Errorf("unexpected issues: %v", got)
+	}
+
+	_, fixed := l.FixContent("a.md", content)
+	if !strings.Contains(fixed, "code and GitHub, see https://github.com/x.") {
+		t.Errorf("terminology not fixed:\n%s", fixed)
+	}
+}
@@ -0,0 +1,295 @@
+package linter
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Style rule types
+const (
+	StyleExistence      = "existence"       // Report any of the tokens
+	StyleSubstitution   = "substitution"    // Report and replace the keys of swap
+	StylePassive        = "passive"         // Report passive voice
+	StyleSentenceLength = "sentence-length" // Report sentences with more than max words
+)
+
+// DefaultStyleFiles are loaded when present and no style file is given
+var DefaultStyleFiles = []string{".mdctl-style.yaml", ".mdctl-style.yml"}
+
+var (
+	// passiveRegex matches a form of "to be" followed by a past participle
+	passiveRegex = regexp.MustCompile(`(?i)\b(?:am|is|are|was|were|be|been|being)\s+(?:\w+ly\s+)?(\w+ed|` +
+		`begun|broken|built|chosen|done|drawn|driven|eaten|fallen|forgotten|found|given|gone|hidden|held|kept|` +
+		`known|led|left|lost|made|meant|paid|put|read|run|said|seen|sent|set|shown|sold|spent|spoken|taken|` +
+		`taught|thought|told|understood|won|written)\b`)
+	// sentenceEndRegex matches the end of a sentence
+	sentenceEndRegex = regexp.MustCompile(`[.!?]+(?:\s|$)`)
+	// blockStartRegex matches lines that start a new block: headings, list items, quotes and tables
+	blockStartRegex = regexp.MustCompile(`^\s*(?:#{1,6}\s|[-*+]\s|\d+[.)]\s|>|\|)`)
+)
+
+// StyleFile is a YAML file of prose style rules, similar to Vale styles:
+//
+//	rules:
+//	  - name: Weasel
+//	    type: existence
+//	    message: "Avoid weasel words: %s"
+//	    severity: warning
+//	    tokens: [very, quite, simply, obviously]
+//	  - name: Wordiness
+//	    type: substitution
+//	    swap: {utilize: use, "in order to": to}
+//	  - name: Passive
+//	    type: passive
+//	  - name: SentenceLength
+//	    type: sentence-length
+//	    max: 30
+type StyleFile struct {
+	Rules []StyleRule `yaml:"rules"`
+}
+
+// StyleRule defines one prose style rule
+type StyleRule struct {
+	Name       string            `yaml:"name"`
+	Type       string            `yaml:"type"`
+	Message    string            `yaml:"message"`  // %s is replaced by the matched text
+	Severity   string            `yaml:"severity"` // error, warning or info, default warning
+	Tokens     []string          `yaml:"tokens"`   // Words or phrases for existence rules
+	Swap       map[string]string `yaml:"swap"`     // Replacements for substitution rules
+	Max        int               `yaml:"max"`      // Maximum words for sentence-length rules
+	IgnoreCase *bool             `yaml:"ignorecase"`
+}
+
+// ID returns the rule ID the style rule reports its issues under
+func (r StyleRule) ID() string {
+	return "Style." + r.Name
+}
+
+// LoadStyles reads style files, without files the default style file of
+// the current directory is used when present
+func LoadStyles(paths []string) ([]StyleRule, error) {
+	if len(paths) == 0 {
+		for _, path := range DefaultStyleFiles {
+			if _, err := os.Stat(path); err == nil {
+				paths = []string{path}
+				break
+			}
+		}
+	}
+
+	var rules []StyleRule
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read style file: %v", err)
+		}
+		var file StyleFile
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse style file %s: %v", path, err)
+		}
+		for i, rule := range file.Rules {
+			if err := rule.validate(); err != nil {
+				return nil, fmt.Errorf("style file %s, rule %d: %v", path, i+1, err)
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// validate checks that a style rule has what its type needs
+func (r StyleRule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch r.Type {
+	case StyleExistence:
+		if len(r.Tokens) == 0 {
+			return fmt.Errorf("%s: existence rules need tokens", r.Name)
+		}
+	case StyleSubstitution:
+		if len(r.Swap) == 0 {
+			return fmt.Errorf("%s: substitution rules need swap", r.Name)
+		}
+	case StyleSentenceLength:
+		if r.Max <= 0 {
+			return fmt.Errorf("%s: sentence-length rules need max", r.Name)
+		}
+	case StylePassive:
+	default:
+		return fmt.Errorf("%s: unknown type %q (must be existence, substitution, passive or sentence-length)", r.Name, r.Type)
+	}
+	switch r.Severity {
+	case "", SeverityError, SeverityWarning, SeverityInfo:
+	default:
+		return fmt.Errorf("%s: unknown severity %q", r.Name, r.Severity)
+	}
+	return nil
+}
+
+// styleCheck is a style rule run by the linter
+type styleCheck struct {
+	BaseRule
+	def     StyleRule
+	pattern *regexp.Regexp
+	swap    map[string]string // Lowercase when the rule ignores case
+}
+
+// newStyleCheck compiles a style rule
+func newStyleCheck(def StyleRule) *styleCheck {
+	c := &styleCheck{
+		BaseRule: BaseRule{id: def.ID(), description: def.Type + " style rule", enabled: true},
+		def:      def,
+	}
+	ignoreCase := def.IgnoreCase == nil || *def.IgnoreCase
+
+	var tokens []string
+	switch def.Type {
+	case StyleExistence:
+		tokens = def.Tokens
+	case StyleSubstitution:
+		c.swap = make(map[string]string)
+		for from, to := range def.Swap {
+			tokens = append(tokens, from)
+			if ignoreCase {
+				from = strings.ToLower(from)
+			}
+			c.swap[from] = to
+		}
+	case StylePassive:
+		c.pattern = passiveRegex
+		return c
+	default:
+		return c
+	}
+
+	quoted := make([]string, len(tokens))
+	for i, token := range tokens {
+		quoted[i] = regexp.QuoteMeta(token)
+	}
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	flags := ""
+	if ignoreCase {
+		flags = "(?i)"
+	}
+	c.pattern = regexp.MustCompile(flags + `\b(?:` + strings.Join(quoted, "|") + `)\b`)
+	return c
+}
+
+func (c *styleCheck) Check(lines []string) []*Issue {
+	if c.def.Type == StyleSentenceLength {
+		return c.checkSentences(lines)
+	}
+
+	var issues []*Issue
+	for i, line := range proseLines(lines) {
+		for _, loc := range c.pattern.FindAllStringIndex(line, -1) {
+			text := line[loc[0]:loc[1]]
+			issue := &Issue{
+				Line:    i + 1,
+				Column:  loc[0] + 1,
+				Rule:    c.ID(),
+				Message: c.message(text),
+				Context: lines[i],
+			}
+			if c.def.Type == StyleSubstitution {
+				issue.Suggestion = c.replacement(text)
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// checkSentences reports sentences longer than the maximum word count,
+// sentences may span the lines of a paragraph
+func (c *styleCheck) checkSentences(lines []string) []*Issue {
+	var issues []*Issue
+	words, startLine := 0, 0
+	flush := func() {
+		if words > c.def.Max {
+			issues = append(issues, &Issue{
+				Line:    startLine + 1,
+				Rule:    c.ID(),
+				Message: c.message(fmt.Sprintf("%d words", words)),
+				Context: lines[startLine],
+			})
+		}
+		words = 0
+	}
+
+	for i, line := range proseLines(lines) {
+		if strings.TrimSpace(line) == "" || blockStartRegex.MatchString(line) {
+			flush()
+		}
+		rest := line
+		for rest != "" {
+			end := len(rest)
+			loc := sentenceEndRegex.FindStringIndex(rest)
+			if loc != nil {
+				end = loc[1]
+			}
+			if n := len(wordRegex.FindAllString(rest[:end], -1)); n > 0 {
+				if words == 0 {
+					startLine = i
+				}
+				words += n
+			}
+			if loc == nil {
+				break
+			}
+			flush()
+			rest = rest[end:]
+		}
+	}
+	flush()
+	return issues
+}
+
+// message formats the issue message of a match
+func (c *styleCheck) message(text string) string {
+	if c.def.Message != "" {
+		if strings.Contains(c.def.Message, "%s") {
+			return fmt.Sprintf(c.def.Message, text)
+		}
+		return c.def.Message
+	}
+	switch c.def.Type {
+	case StyleSubstitution:
+		return fmt.Sprintf("Use %q instead of %q", c.replacement(text), text)
+	case StylePassive:
+		return fmt.Sprintf("%q may be passive voice", text)
+	case StyleSentenceLength:
+		return fmt.Sprintf("Sentence too long (%s, max %d)", text, c.def.Max)
+	default:
+		return fmt.Sprintf("Avoid %q", text)
+	}
+}
+
+// replacement returns the substitution of a matched text
+func (c *styleCheck) replacement(text string) string {
+	if to, ok := c.swap[text]; ok {
+		return to
+	}
+	return c.swap[strings.ToLower(text)]
+}
+
+// fix applies the substitutions of a substitution rule
+func (c *styleCheck) fix(lines []string) ([]string, int) {
+	fixed := 0
+	prose := proseLines(lines)
+	for i := range lines {
+		locs := c.pattern.FindAllStringIndex(prose[i], -1)
+		for j := len(locs) - 1; j >= 0; j-- {
+			start, end := locs[j][0], locs[j][1]
+			lines[i] = lines[i][:start] + c.replacement(lines[i][start:end]) + lines[i][end:]
+			fixed++
+		}
+	}
+	return lines, fixed
+}
@@ -0,0 +1,66 @@
+package linter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStyleRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "style.yaml")
+	os.WriteFile(path, []byte(`rules:
+  - name: Weasel
+    type: existence
+    message: "Avoid weasel words: %s"
+    tokens: [very, simply]
+  - name: Wordiness
+    type: substitution
+    severity: error
+    swap: {utilize: use, "in order to": to}
+  - name: Passive
+    type: passive
+    severity: info
+  - name: SentenceLength
+    type: sentence-length
+    max: 12
+`), 0644)
+
+	styles, err := LoadStyles([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := New(&Config{EnableRules: []string{"Style.Weasel", "Style.Wordiness", "Style.Passive", "Style.SentenceLength"}, Styles: styles})
+
+	content := "# Title\n\nYou simply utilize the tool in order to convert files.\nThe file was written by the tool and then it is copied to the\noutput directory where everyone can read it later on.\n\nThe `very` code stays.\n"
+	result, err := l.LintContent("a.md", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, issue := range result.Issues {
+		got = append(got, issue.Rule+"@"+issue.Severity)
+	}
+	want := "Style.Passive@info,Style.Passive@info,Style.SentenceLength@warning,Style.Weasel@warning,Style.Wordiness@error,Style.Wordiness@error"
+	if strings.Join(got, ",") != want {
+		t.Errorf("got %v\nwant %s", got, want)
+	}
+
+	_, fixed := l.FixContent("a.md", content)
+	if !strings.Contains(fixed, "You simply use the tool to convert files.") {
+		t.Errorf("substitutions not applied:\n%s", fixed)
+	}
+
+	if _, err := LoadStyles([]string{writeStyle(t, "rules:\n  - name: X\n    type: regex\n")}); err == nil {
+		t.Error("expected an error for an unknown rule type")
+	}
+}
+
+func writeStyle(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "style.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,130 @@
+package llmstxt
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samzong/mdctl/internal/netutil"
+	"golang.org/x/net/publicsuffix"
+)
+
+// ParseHeaders parses "Name: value" header flags
+func ParseHeaders(values []string) (http.Header, error) {
+	header := make(http.Header)
+	for _, value := range values {
+		name, v, ok := strings.Cut(value, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid header %q, expected \"Name: value\"", value)
+		}
+		header.Add(name, strings.TrimSpace(v))
+	}
+	return header, nil
+}
+
+// loadCookieFile reads a Netscape cookies.txt file, as exported by browser
+// extensions and curl -c, into a cookie jar. Expired cookies are dropped.
+func loadCookieFile(path string) (http.CookieJar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookie file: %w", err)
+	}
+	defer file.Close()
+
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		if httpOnly {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// domain, include subdomains, path, secure, expiry, name, value
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			return nil, fmt.Errorf("invalid cookie file %s, line %d: expected 7 tab-separated fields", path, lineNo)
+		}
+		expiry, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cookie file %s, line %d: invalid expiry %q", path, lineNo, fields[4])
+		}
+		cookie := &http.Cookie{
+			Name:     fields[5],
+			Value:    strings.Join(fields[6:], "\t"),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+		}
+		if expiry > 0 {
+			cookie.Expires = time.Unix(expiry, 0)
+			if cookie.Expires.Before(now) {
+				continue
+			}
+		}
+
+		host := strings.TrimPrefix(fields[0], ".")
+		// Cookies without the subdomain flag only go to their exact host
+		if strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = host
+		}
+		scheme := "http"
+		if cookie.Secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: host, Path: "/"}, []*http.Cookie{cookie})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cookie file: %w", err)
+	}
+	return jar, nil
+}
+
+// newClient returns the HTTP client of sitemap and page requests, sending
+// the cookies of the cookie file
+func (g *Generator) newClient() *http.Client {
+	client := netutil.NewClient(time.Duration(g.config.Timeout) * time.Second)
+	if g.jar != nil {
+		client.Jar = g.jar
+	}
+	return client
+}
+
+// newRequest builds a GET request with the User-Agent. The configured headers
+// and basic auth credentials are only sent to the host of the site, so they
+// do not leak to other hosts a sitemap lists.
+func (g *Generator) newRequest(ctx context.Context, rawURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", g.config.UserAgent)
+
+	if site, err := url.Parse(g.config.SitemapURL); err != nil || !strings.EqualFold(site.Host, req.URL.Host) {
+		return req, nil
+	}
+	for name, values := range g.config.Headers {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	if g.config.BasicAuth != "" {
+		user, password, _ := strings.Cut(g.config.BasicAuth, ":")
+		req.SetBasicAuth(user, password)
+	}
+	return req, nil
+}
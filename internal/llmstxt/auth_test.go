@@ -0,0 +1,101 @@
+package llmstxt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestGenerate_Credentials(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]string{}
+	record := func(name string, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		cookie, _ := r.Cookie("session")
+		value := ""
+		if cookie != nil {
+			value = cookie.Value
+		}
+		mu.Lock()
+		seen[name+r.URL.Path] = fmt.Sprintf("%s:%s|%s|%s", user, password, r.Header.Get("X-Api-Key"), value)
+		mu.Unlock()
+	}
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record("other", r)
+		fmt.Fprint(w, "<html><head><title>Other</title></head></html>")
+	}))
+	defer other.Close()
+	var site *httptest.Server
+	site = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record("site", r)
+		if user, _, ok := r.BasicAuth(); !ok || user != "ci" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/sitemap.xml" {
+			fmt.Fprintf(w, `<urlset><url><loc>%s/page</loc></url><url><loc>%s/page</loc></url></urlset>`, site.URL, other.URL)
+			return
+		}
+		fmt.Fprint(w, "<html><head><title>Page</title></head></html>")
+	}))
+	defer site.Close()
+
+	host := strings.Split(strings.TrimPrefix(site.URL, "http://"), ":")[0]
+	cookies := filepath.Join(t.TempDir(), "cookies.txt")
+	content := "# Netscape HTTP Cookie File\n" +
+		"#HttpOnly_" + host + "\tFALSE\t/\tFALSE\t0\tsession\tabc\n" +
+		host + "\tFALSE\t/\tFALSE\t1\texpired\tx\n"
+	if err := os.WriteFile(cookies, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	headers, err := ParseHeaders([]string{"X-Api-Key: secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGenerator(GeneratorConfig{
+		SitemapURL:  site.URL + "/sitemap.xml",
+		Concurrency: 2,
+		Timeout:     5,
+		Headers:     headers,
+		BasicAuth:   "ci:pass",
+		CookieFile:  cookies,
+	})
+	if _, err := g.Generate(context.Background()); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if got := seen["site/page"]; got != "ci:pass|secret|abc" {
+		t.Errorf("site page request: got %q", got)
+	}
+	// Cookies follow their domain, which ignores ports, headers and basic
+	// auth stay with the site
+	if got := seen["other/page"]; got != ":||abc" {
+		t.Errorf("credentials leaked to another host: %q", got)
+	}
+	if g.Stats().URLsFetched != 2 {
+		t.Errorf("expected 2 pages, got %+v", g.Stats())
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	header, err := ParseHeaders([]string{"Authorization: Bearer token", "x-team:  docs "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Get("Authorization") != "Bearer token" || header.Get("X-Team") != "docs" {
+		t.Errorf("unexpected headers: %v", header)
+	}
+	for _, value := range []string{"no colon", ": empty name", "Bad Name: x"} {
+		if _, err := ParseHeaders([]string{value}); err == nil {
+			t.Errorf("expected an error for %q", value)
+		}
+	}
+}
@@ -24,6 +24,10 @@ func (g *Generator) extractPageInfo(urlStr string, resp *http.Response) (PageInf
 		return pageInfo, err
 	}
 
+	// Language, canonical URL and robots directives decide whether the page is listed
+	extractPageMeta(&pageInfo, doc, resp.Header)
+	extractPageDetails(&pageInfo, doc, resp.Header)
+
 	// Extract title
 	pageInfo.Title = extractTitle(doc)
 	if g.config.VeryVerbose {
@@ -1,19 +1,46 @@
 package llmstxt
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 )
 
+// Stages a page can fail at
+const (
+	StageFetch = "fetch" // The request failed or returned an error status
+	StageParse = "parse" // The response could not be parsed
+)
+
+// FailedURL is a page that is missing from the output
+type FailedURL struct {
+	URL    string `json:"url"`
+	Stage  string `json:"stage"`
+	Status int    `json:"status,omitempty"` // HTTP status, when the server answered
+	Error  string `json:"error"`
+}
+
+// pageError is the error of a page with the stage it failed at
+type pageError struct {
+	stage  string
+	status int
+	err    error
+}
+
+func (e *pageError) Error() string { return e.err.Error() }
+func (e *pageError) Unwrap() error { return e.err }
+
 // Fetch pages concurrently using a worker pool
-func (g *Generator) fetchPages(urls []string) ([]PageInfo, error) {
+func (g *Generator) fetchPages(ctx context.Context, urls []string) ([]PageInfo, error) {
 	g.logger.Printf("Starting to fetch %d pages with concurrency %d", len(urls), g.config.Concurrency)
 
 	// Create result and error channels
 	resultChan := make(chan PageInfo, len(urls))
-	errorChan := make(chan error, len(urls))
+	errorChan := make(chan FailedURL, len(urls))
 
 	// Create work channel, controlling concurrency
 	workChan := make(chan string, len(urls))
@@ -25,10 +52,23 @@ func (g *Generator) fetchPages(urls []string) ([]PageInfo, error) {
 		go func() {
 			defer wg.Done()
 			for urlStr := range workChan {
-				pageInfo, err := g.fetchPageContent(urlStr)
+				// Drain the remaining URLs without fetching once interrupted
+				if ctx.Err() != nil {
+					continue
+				}
+				pageInfo, err := g.fetchPageContent(ctx, urlStr)
 				if err != nil {
-					g.logger.Printf("Warning: failed to fetch page %s: %v", urlStr, err)
-					errorChan <- fmt.Errorf("failed to fetch page %s: %w", urlStr, err)
+					g.logger.Debugf("Failed to fetch page %s: %v", urlStr, err)
+					// Requests aborted by an interruption are no failures of the page
+					if errors.Is(err, context.Canceled) {
+						continue
+					}
+					failure := FailedURL{URL: urlStr, Stage: StageFetch, Error: err.Error()}
+					var pe *pageError
+					if errors.As(err, &pe) {
+						failure.Stage, failure.Status = pe.stage, pe.status
+					}
+					errorChan <- failure
 					continue
 				}
 				resultChan <- pageInfo
@@ -54,10 +94,13 @@ func (g *Generator) fetchPages(urls []string) ([]PageInfo, error) {
 		g.logger.Printf("Fetched page: %s", result.URL)
 	}
 
-	// Check for errors (don't interrupt processing, just log warnings)
-	for err := range errorChan {
-		g.logger.Printf("Warning: %v", err)
+	// Record errors (don't interrupt processing, just log warnings)
+	g.stats.Failures = nil
+	for failure := range errorChan {
+		g.logger.Warnf("failed to fetch page %s: %s", failure.URL, failure.Error)
+		g.stats.Failures = append(g.stats.Failures, failure)
 	}
+	sort.Slice(g.stats.Failures, func(i, j int) bool { return g.stats.Failures[i].URL < g.stats.Failures[j].URL })
 
 	g.logger.Printf("Successfully fetched %d/%d pages", len(results), len(urls))
 
@@ -65,37 +108,32 @@ func (g *Generator) fetchPages(urls []string) ([]PageInfo, error) {
 }
 
 // Get the content of a single page
-func (g *Generator) fetchPageContent(urlStr string) (PageInfo, error) {
+func (g *Generator) fetchPageContent(ctx context.Context, urlStr string) (PageInfo, error) {
 	// Set HTTP client
-	client := &http.Client{
-		Timeout: time.Duration(g.config.Timeout) * time.Second,
-	}
+	client := g.newClient()
 
-	// Build request
-	req, err := http.NewRequest("GET", urlStr, nil)
+	// Build request with User-Agent and credentials
+	req, err := g.newRequest(ctx, urlStr)
 	if err != nil {
-		return PageInfo{}, fmt.Errorf("failed to create request: %w", err)
+		return PageInfo{}, err
 	}
 
-	// Set User-Agent
-	req.Header.Set("User-Agent", g.config.UserAgent)
-
 	// Send request
 	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
-		return PageInfo{}, fmt.Errorf("failed to fetch page: %w", err)
+		return PageInfo{}, &pageError{stage: StageFetch, err: fmt.Errorf("failed to fetch page: %w", err)}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return PageInfo{}, fmt.Errorf("failed to fetch page, status code: %d", resp.StatusCode)
+		return PageInfo{}, &pageError{stage: StageFetch, status: resp.StatusCode, err: fmt.Errorf("failed to fetch page, status code: %d", resp.StatusCode)}
 	}
 
 	// Extract page information
 	pageInfo, err := g.extractPageInfo(urlStr, resp)
 	if err != nil {
-		return PageInfo{}, fmt.Errorf("failed to extract page info: %w", err)
+		return PageInfo{}, &pageError{stage: StageParse, err: fmt.Errorf("failed to extract page info: %w", err)}
 	}
 
 	// Record timing information
@@ -0,0 +1,51 @@
+package llmstxt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGenerateFailures(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<urlset><url><loc>%[1]s/a</loc></url><url><loc>%[1]s/b</loc></url><url><loc>%[1]s/c</loc></url></urlset>`, server.URL)
+		case "/a", "/c":
+			fmt.Fprint(w, "<html><head><title>Page</title></head><body>Text</body></html>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	config := GeneratorConfig{SitemapURL: server.URL + "/sitemap.xml", Concurrency: 2, Timeout: 5}
+	g := NewGenerator(config)
+	if _, err := g.Generate(context.Background()); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	stats := g.Stats()
+	if len(stats.Failures) != 1 {
+		t.Fatalf("expected one failure, got %+v", stats.Failures)
+	}
+	failure := stats.Failures[0]
+	if failure.URL != server.URL+"/b" || failure.Stage != StageFetch || failure.Status != http.StatusNotFound {
+		t.Errorf("unexpected failure %+v", failure)
+	}
+	if rate := stats.SuccessRate(); rate < 0.66 || rate > 0.67 {
+		t.Errorf("expected a success rate of 2/3, got %v", rate)
+	}
+
+	config.MinSuccessRate = 0.95
+	g = NewGenerator(config)
+	if _, err := g.Generate(context.Background()); !errors.Is(err, ErrLowSuccessRate) {
+		t.Errorf("expected ErrLowSuccessRate, got %v", err)
+	}
+	if len(g.Stats().Failures) != 1 {
+		t.Errorf("expected the failures of a failed run, got %+v", g.Stats().Failures)
+	}
+}
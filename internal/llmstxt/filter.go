@@ -0,0 +1,172 @@
+package llmstxt
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// languageCodes are the ISO 639-1 codes recognized as language prefixes in URL paths
+var languageCodes = map[string]bool{
+	"ar": true, "bg": true, "bn": true, "cs": true, "da": true, "de": true, "el": true,
+	"en": true, "es": true, "et": true, "fa": true, "fi": true, "fr": true, "he": true,
+	"hi": true, "hr": true, "hu": true, "id": true, "it": true, "ja": true, "ko": true,
+	"lt": true, "lv": true, "ms": true, "nb": true, "nl": true, "no": true, "pl": true,
+	"pt": true, "ro": true, "ru": true, "sk": true, "sl": true, "sr": true, "sv": true,
+	"th": true, "tr": true, "uk": true, "vi": true, "zh": true,
+}
+
+// normalizeLang lowercases a language tag and uses '-' as separator
+func normalizeLang(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"))
+}
+
+// matchLang reports whether a page language matches the wanted one, a
+// language matches its regional variants in both directions (zh, zh-cn)
+func matchLang(pageLang, want string) bool {
+	pageLang, want = normalizeLang(pageLang), normalizeLang(want)
+	return pageLang == want || strings.HasPrefix(pageLang, want+"-") || strings.HasPrefix(want, pageLang+"-")
+}
+
+// urlLang returns the language prefix of a URL path such as /zh-cn/docs, if any
+func urlLang(urlStr string) string {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return ""
+	}
+	segment, _, _ := strings.Cut(strings.Trim(parsedURL.Path, "/"), "/")
+	segment = normalizeLang(segment)
+	base, region, hasRegion := strings.Cut(segment, "-")
+	if !languageCodes[base] || (hasRegion && (len(region) < 2 || len(region) > 4)) {
+		return ""
+	}
+	return segment
+}
+
+// stripLangPrefix removes a language prefix from a URL path, so sections of
+// localized sites are derived from the path below it
+func stripLangPrefix(urlStr string) string {
+	lang := urlLang(urlStr)
+	if lang == "" {
+		return urlStr
+	}
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return urlStr
+	}
+	path := strings.TrimPrefix(parsedURL.Path, "/")
+	parsedURL.Path = "/" + strings.TrimPrefix(path[len(lang):], "/")
+	return parsedURL.String()
+}
+
+// extractPageMeta reads the language, canonical URL and indexing directives of a page
+func extractPageMeta(pageInfo *PageInfo, doc *goquery.Document, header http.Header) {
+	pageInfo.Lang, _ = doc.Find("html").First().Attr("lang")
+
+	// Fall back to the hreflang alternate pointing at the page itself
+	if pageInfo.Lang == "" {
+		doc.Find("link[rel='alternate'][hreflang]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+			href, _ := s.Attr("href")
+			if sameURL(resolveURL(pageInfo.URL, href), pageInfo.URL) {
+				pageInfo.Lang, _ = s.Attr("hreflang")
+				return false
+			}
+			return true
+		})
+	}
+
+	if href, ok := doc.Find("link[rel='canonical']").First().Attr("href"); ok && strings.TrimSpace(href) != "" {
+		pageInfo.Canonical = resolveURL(pageInfo.URL, strings.TrimSpace(href))
+	}
+
+	directives := strings.Join(header.Values("X-Robots-Tag"), ",")
+	doc.Find("meta[name='robots'], meta[name='googlebot']").Each(func(i int, s *goquery.Selection) {
+		content, _ := s.Attr("content")
+		directives += "," + content
+	})
+	for _, directive := range strings.Split(directives, ",") {
+		if d := strings.ToLower(strings.TrimSpace(directive)); d == "noindex" || d == "none" {
+			pageInfo.NoIndex = true
+		}
+	}
+}
+
+// filterPages drops noindex pages, pages in other languages and duplicates of
+// the same canonical URL, returning the kept pages and the number dropped
+func (g *Generator) filterPages(pages []PageInfo) ([]PageInfo, int) {
+	// Pages fetched at their canonical URL win over their duplicates
+	isCanonical := func(p PageInfo) bool { return p.Canonical == "" || sameURL(p.Canonical, p.URL) }
+	sort.SliceStable(pages, func(i, j int) bool {
+		return isCanonical(pages[i]) && !isCanonical(pages[j])
+	})
+
+	seen := make(map[string]bool)
+	var kept []PageInfo
+
+	for _, page := range pages {
+		if page.NoIndex {
+			g.logger.Debugf("Skipping %s (noindex)", page.URL)
+			continue
+		}
+		if g.config.Lang != "" && page.Lang != "" && !matchLang(page.Lang, g.config.Lang) {
+			g.logger.Debugf("Skipping %s (language %s)", page.URL, page.Lang)
+			continue
+		}
+
+		// Pages are listed under their canonical URL, once
+		if !isCanonical(page) {
+			g.logger.Debugf("Using canonical URL %s for %s", page.Canonical, page.URL)
+			page.URL = page.Canonical
+			page.Section = parseSection(page.URL)
+		}
+		key := strings.TrimSuffix(page.URL, "/")
+		if seen[key] {
+			g.logger.Debugf("Skipping %s (duplicate of canonical URL)", page.URL)
+			continue
+		}
+		seen[key] = true
+
+		if g.config.Lang != "" {
+			page.Section = parseSection(stripLangPrefix(page.URL))
+		}
+		kept = append(kept, page)
+	}
+
+	return kept, len(pages) - len(kept)
+}
+
+// filterLangURLs drops URLs whose path starts with the prefix of another language
+func (g *Generator) filterLangURLs(urls []string) []string {
+	if g.config.Lang == "" {
+		return urls
+	}
+	var filtered []string
+	for _, u := range urls {
+		if lang := urlLang(u); lang != "" && !matchLang(lang, g.config.Lang) {
+			continue
+		}
+		filtered = append(filtered, u)
+	}
+	return filtered
+}
+
+// resolveURL resolves href against the page URL
+func resolveURL(base, href string) string {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return baseURL.ResolveReference(ref).String()
+}
+
+// sameURL compares URLs ignoring a trailing slash
+func sameURL(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
@@ -0,0 +1,82 @@
+package llmstxt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGenerate_LanguageAndCanonicalFiltering(t *testing.T) {
+	var server *httptest.Server
+	pages := map[string]string{
+		"/":             `<html lang="en"><title>Home</title></html>`,
+		"/docs/a":       `<html lang="en"><title>A</title></html>`,
+		"/docs/a?ref=x": `<html lang="en"><head><link rel="canonical" href="/docs/a"><title>A copy</title></head></html>`,
+		"/docs/b":       `<html lang="de"><title>B auf Deutsch</title></html>`,
+		"/docs/c":       `<html><head><link rel="alternate" hreflang="fr" href="%s/docs/c"></head><title>C</title></html>`,
+		"/docs/hidden":  `<html lang="en"><head><meta name="robots" content="noindex, follow"></head><title>Hidden</title></html>`,
+		"/zh/docs/a":    `<html lang="zh"><title>A 中文</title></html>`,
+	}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sitemap.xml" {
+			fmt.Fprint(w, "<urlset>")
+			for path := range pages {
+				fmt.Fprintf(w, "<url><loc>%s%s</loc></url>", server.URL, path)
+			}
+			fmt.Fprint(w, "</urlset>")
+			return
+		}
+		key := r.URL.Path
+		if r.URL.RawQuery != "" {
+			key += "?" + r.URL.RawQuery
+		}
+		page, ok := pages[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, page, server.URL)
+	}))
+	defer server.Close()
+
+	g := NewGenerator(GeneratorConfig{SitemapURL: server.URL + "/sitemap.xml", Concurrency: 2, Timeout: 5, Lang: "en"})
+	content, err := g.Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	for _, title := range []string{"# Home", "[A]("} {
+		if !strings.Contains(content, title) {
+			t.Errorf("expected %q in output:\n%s", title, content)
+		}
+	}
+	for _, title := range []string{"A copy", "B auf", "[C]", "Hidden", "中文"} {
+		if strings.Contains(content, title) {
+			t.Errorf("unexpected %q in output:\n%s", title, content)
+		}
+	}
+	if stats := g.Stats(); stats.URLsSkipped != 4 || stats.URLsFetched != 6 {
+		t.Errorf("expected 4 pages skipped of 6, got %+v", stats)
+	}
+}
+
+func TestURLLang(t *testing.T) {
+	for u, want := range map[string]string{
+		"https://example.com/zh/docs":    "zh",
+		"https://example.com/zh-CN/docs": "zh-cn",
+		"https://example.com/pt_BR/":     "pt-br",
+		"https://example.com/docs/zh":    "",
+		"https://example.com/api/":       "",
+		"https://example.com/":           "",
+	} {
+		if got := urlLang(u); got != want {
+			t.Errorf("urlLang(%q) = %q, want %q", u, got, want)
+		}
+	}
+	if got := stripLangPrefix("https://example.com/zh-cn/docs/a"); got != "https://example.com/docs/a" {
+		t.Errorf("unexpected stripped URL %q", got)
+	}
+}
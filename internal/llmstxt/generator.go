@@ -1,12 +1,15 @@
 package llmstxt
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io"
-	"log"
-	"os"
+	"net/http"
 	"sort"
+	"text/template"
 	"time"
+
+	"github.com/samzong/mdctl/internal/logging"
 )
 
 // GeneratorConfig contains the configuration required to generate llms.txt
@@ -19,8 +22,20 @@ type GeneratorConfig struct {
 	Timeout      int
 	UserAgent    string
 	Verbose      bool
-	VeryVerbose  bool // More detailed log output
-	MaxPages     int  // Maximum number of pages to process, 0 means no limit
+	VeryVerbose  bool   // More detailed log output
+	MaxPages     int    // Maximum number of pages to process, 0 means no limit
+	Template     string // Path of a text/template file replacing the default format
+	Lang         string // Only keep pages in this language
+	// Fail when fewer than this share of the pages (0 to 1) could be fetched,
+	// 0 means no threshold
+	MinSuccessRate float64
+
+	// Credentials of sites behind basic auth or an SSO proxy. Headers and
+	// BasicAuth ("user:password") are only sent to the host of SitemapURL,
+	// the cookies of CookieFile (Netscape cookies.txt) to their domains.
+	Headers    http.Header
+	BasicAuth  string
+	CookieFile string
 }
 
 // PageInfo stores page information
@@ -30,46 +45,104 @@ type PageInfo struct {
 	Description string
 	Content     string // Page content, only filled in full mode
 	Section     string // First segment of URL path as section
+	Lang        string // Language from <html lang> or the hreflang alternate of the page
+	Canonical   string // Canonical URL, if the page declares one
+	NoIndex     bool   // Page asks not to be indexed
+
+	// Read from JSON-LD, OpenGraph and meta tags, dates are zero when unknown
+	Published time.Time
+	Modified  time.Time
+	Authors   []string
+	Tags      []string
+}
+
+// Updated returns the modified date of the page, or the published date when
+// it has none
+func (p PageInfo) Updated() time.Time {
+	if p.Modified.IsZero() {
+		return p.Published
+	}
+	return p.Modified
+}
+
+// Stats holds statistics about a generation run
+type Stats struct {
+	URLsFound   int `json:"urls_found"`
+	URLsFetched int `json:"urls_fetched"`
+	URLsFailed  int `json:"urls_failed"`
+	URLsSkipped int `json:"urls_skipped"` // Fetched but dropped as noindex, other language or canonical duplicate
+	Sections    int `json:"sections"`
+
+	Failures []FailedURL `json:"failures,omitempty"` // Pages that could not be fetched or parsed, by URL
+}
+
+// ErrLowSuccessRate is returned when fewer pages than MinSuccessRate asks for
+// could be fetched
+var ErrLowSuccessRate = errors.New("too many pages failed")
+
+// SuccessRate returns the share of the pages to fetch that were fetched, 1
+// when there were none
+func (s Stats) SuccessRate() float64 {
+	total := s.URLsFetched + s.URLsFailed
+	if total == 0 {
+		return 1
+	}
+	return float64(s.URLsFetched) / float64(total)
 }
 
 // Generator is the llms.txt generator
 type Generator struct {
 	config GeneratorConfig
-	logger *log.Logger
+	logger *logging.Logger
+	stats  Stats
+	jar    http.CookieJar // Cookies of CookieFile
 }
 
 // NewGenerator creates a new generator instance
 func NewGenerator(config GeneratorConfig) *Generator {
-	var logger *log.Logger
-	if config.Verbose || config.VeryVerbose {
-		logger = log.New(os.Stdout, "[LLMSTXT] ", log.LstdFlags)
-	} else {
-		logger = log.New(io.Discard, "", 0)
-	}
-
 	return &Generator{
 		config: config,
-		logger: logger,
+		logger: logging.New("LLMSTXT"),
 	}
 }
 
-// Generate performs the generation process and returns the generated content
-func (g *Generator) Generate() (string, error) {
+// Generate performs the generation process and returns the generated content.
+// When ctx is cancelled it stops fetching and returns ctx.Err(), Stats still
+// describes the partial run.
+func (g *Generator) Generate(ctx context.Context) (string, error) {
 	startTime := time.Now()
 	g.logger.Printf("Starting generation for sitemap: %s", g.config.SitemapURL)
 	if g.config.FullMode {
 		g.logger.Println("Full-content mode enabled")
 	}
 
+	// Fail on template errors before fetching anything
+	var tmpl *template.Template
+	if g.config.Template != "" {
+		var err error
+		if tmpl, err = loadTemplate(g.config.Template); err != nil {
+			return "", err
+		}
+	}
+
+	if g.config.CookieFile != "" {
+		jar, err := loadCookieFile(g.config.CookieFile)
+		if err != nil {
+			return "", err
+		}
+		g.jar = jar
+	}
+
 	// 1. Parse sitemap.xml to get URL list
-	urls, err := g.parseSitemap()
+	urls, err := g.parseSitemap(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse sitemap: %w", err)
 	}
 	g.logger.Printf("Found %d URLs in sitemap", len(urls))
+	g.stats.URLsFound = len(urls)
 
 	// 2. Filter URLs (based on include/exclude mode)
-	urls = g.filterURLs(urls)
+	urls = g.filterLangURLs(g.filterURLs(urls))
 	g.logger.Printf("%d URLs after filtering", len(urls))
 
 	// 2.1. Apply max page limit
@@ -79,22 +152,54 @@ func (g *Generator) Generate() (string, error) {
 	}
 
 	// 3. Create worker pool and get page info
-	pages, err := g.fetchPages(urls)
+	pages, err := g.fetchPages(ctx, urls)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch pages: %w", err)
 	}
 
+	g.stats.URLsFetched = len(pages)
+	g.stats.URLsFailed = len(urls) - len(pages)
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	// A run missing too many pages would produce a hollow llms.txt
+	if rate := g.stats.SuccessRate(); rate < g.config.MinSuccessRate {
+		return "", fmt.Errorf("%w: fetched %d of %d pages (%.1f%%), below the minimum success rate of %.1f%%",
+			ErrLowSuccessRate, g.stats.URLsFetched, g.stats.URLsFetched+g.stats.URLsFailed, rate*100, g.config.MinSuccessRate*100)
+	}
+
+	// 3.1. Drop noindex pages, other languages and canonical duplicates
+	pages, g.stats.URLsSkipped = g.filterPages(pages)
+	if g.stats.URLsSkipped > 0 {
+		g.logger.Printf("Skipped %d pages (noindex, language or canonical duplicates)", g.stats.URLsSkipped)
+	}
+
 	// 4. Group pages by section
 	sections := g.groupBySections(pages)
+	g.stats.Sections = len(sections)
 
 	// 5. Format to Markdown content
-	content := g.formatContent(sections)
+	var content string
+	if tmpl != nil {
+		if content, err = g.renderTemplate(tmpl, sections); err != nil {
+			return "", err
+		}
+	} else {
+		content = g.formatContent(sections)
+	}
 
 	elapsedTime := time.Since(startTime).Round(time.Millisecond)
 	g.logger.Printf("Generation completed successfully in %v", elapsedTime)
 	return content, nil
 }
 
+// Stats returns statistics about the last generation run
+func (g *Generator) Stats() Stats {
+	return g.stats
+}
+
 // Group pages by section
 func (g *Generator) groupBySections(pages []PageInfo) map[string][]PageInfo {
 	sections := make(map[string][]PageInfo)
@@ -0,0 +1,204 @@
+package llmstxt
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// dateLayouts are the date formats found in JSON-LD and meta tags
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	time.RFC1123,
+	time.RFC1123Z,
+}
+
+// parseDate parses a date of page metadata, the zero time when it has none
+// of the known formats
+func parseDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// pageDetails is the freshness and authorship information of one source
+type pageDetails struct {
+	published, modified string
+	authors, tags       []string
+}
+
+// extractPageDetails reads the published and modified dates, authors and
+// tags of a page. JSON-LD (schema.org) wins over OpenGraph, which wins over
+// plain meta tags, the Last-Modified header is the last resort for the
+// modified date.
+func extractPageDetails(pageInfo *PageInfo, doc *goquery.Document, header http.Header) {
+	sources := []pageDetails{jsonLDDetails(doc), openGraphDetails(doc), metaDetails(doc)}
+	for _, source := range sources {
+		if pageInfo.Published.IsZero() {
+			pageInfo.Published = parseDate(source.published)
+		}
+		if pageInfo.Modified.IsZero() {
+			pageInfo.Modified = parseDate(source.modified)
+		}
+		if len(pageInfo.Authors) == 0 {
+			pageInfo.Authors = uniqueValues(source.authors)
+		}
+		if len(pageInfo.Tags) == 0 {
+			pageInfo.Tags = uniqueValues(source.tags)
+		}
+	}
+	if pageInfo.Modified.IsZero() {
+		pageInfo.Modified = parseDate(header.Get("Last-Modified"))
+	}
+}
+
+// jsonLDDetails reads the schema.org data of the JSON-LD scripts of a page,
+// the first object with a value decides
+func jsonLDDetails(doc *goquery.Document) pageDetails {
+	var objects []map[string]interface{}
+	doc.Find("script[type='application/ld+json']").Each(func(i int, s *goquery.Selection) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(s.Text()), &data); err == nil {
+			objects = append(objects, jsonLDObjects(data)...)
+		}
+	})
+
+	var details pageDetails
+	for _, object := range objects {
+		if details.published == "" {
+			details.published, _ = object["datePublished"].(string)
+		}
+		if details.modified == "" {
+			details.modified, _ = object["dateModified"].(string)
+		}
+		if len(details.authors) == 0 {
+			details.authors = jsonLDNames(object["author"])
+		}
+		if len(details.tags) == 0 {
+			details.tags = jsonLDKeywords(object["keywords"])
+		}
+	}
+	return details
+}
+
+// jsonLDObjects returns the objects of a JSON-LD document, including those of
+// top-level arrays and @graph lists
+func jsonLDObjects(data interface{}) []map[string]interface{} {
+	var objects []map[string]interface{}
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			objects = append(objects, jsonLDObjects(item)...)
+		}
+	case map[string]interface{}:
+		objects = append(objects, v)
+		if graph, ok := v["@graph"]; ok {
+			objects = append(objects, jsonLDObjects(graph)...)
+		}
+	}
+	return objects
+}
+
+// jsonLDNames returns the names of a schema.org author, a string, a Person
+// or Organization, or a list of them
+func jsonLDNames(value interface{}) []string {
+	var names []string
+	switch v := value.(type) {
+	case string:
+		names = append(names, v)
+	case map[string]interface{}:
+		if name, ok := v["name"].(string); ok {
+			names = append(names, name)
+		}
+	case []interface{}:
+		for _, item := range v {
+			names = append(names, jsonLDNames(item)...)
+		}
+	}
+	return names
+}
+
+// jsonLDKeywords returns schema.org keywords, a comma-separated string or a list
+func jsonLDKeywords(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Split(v, ",")
+	case []interface{}:
+		var keywords []string
+		for _, item := range v {
+			if keyword, ok := item.(string); ok {
+				keywords = append(keywords, keyword)
+			}
+		}
+		return keywords
+	}
+	return nil
+}
+
+// openGraphDetails reads the article properties of OpenGraph
+func openGraphDetails(doc *goquery.Document) pageDetails {
+	details := pageDetails{
+		published: metaContent(doc, "meta[property='article:published_time']"),
+		modified:  metaContent(doc, "meta[property='article:modified_time'], meta[property='og:updated_time']"),
+	}
+	doc.Find("meta[property='article:author']").Each(func(i int, s *goquery.Selection) {
+		// Profile URLs are no names
+		if author, _ := s.Attr("content"); !strings.Contains(author, "://") {
+			details.authors = append(details.authors, author)
+		}
+	})
+	doc.Find("meta[property='article:tag']").Each(func(i int, s *goquery.Selection) {
+		tag, _ := s.Attr("content")
+		details.tags = append(details.tags, tag)
+	})
+	return details
+}
+
+// metaDetails reads the author, keywords and Dublin Core dates of meta tags
+func metaDetails(doc *goquery.Document) pageDetails {
+	details := pageDetails{
+		published: metaContent(doc, "meta[name='date'], meta[name='dcterms.created'], meta[name='DC.date.created'], meta[itemprop='datePublished']"),
+		modified:  metaContent(doc, "meta[name='last-modified'], meta[name='dcterms.modified'], meta[name='DC.date.modified'], meta[itemprop='dateModified']"),
+	}
+	doc.Find("meta[name='author']").Each(func(i int, s *goquery.Selection) {
+		author, _ := s.Attr("content")
+		details.authors = append(details.authors, author)
+	})
+	if keywords := metaContent(doc, "meta[name='keywords']"); keywords != "" {
+		details.tags = strings.Split(keywords, ",")
+	}
+	return details
+}
+
+// metaContent returns the content of the first element matching a selector
+func metaContent(doc *goquery.Document, selector string) string {
+	content, _ := doc.Find(selector).First().Attr("content")
+	return strings.TrimSpace(content)
+}
+
+// uniqueValues trims values and drops empty and repeated ones, keeping order
+func uniqueValues(values []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[strings.ToLower(value)] {
+			continue
+		}
+		seen[strings.ToLower(value)] = true
+		result = append(result, value)
+	}
+	return result
+}
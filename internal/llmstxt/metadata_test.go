@@ -0,0 +1,110 @@
+package llmstxt
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestExtractPageDetails(t *testing.T) {
+	tests := []struct {
+		name      string
+		html      string
+		header    http.Header
+		published string
+		modified  string
+		authors   string
+		tags      string
+	}{
+		{
+			name: "json-ld graph wins",
+			html: `<script type="application/ld+json">{"@context":"https://schema.org","@graph":[
+				{"@type":"WebSite","name":"Docs"},
+				{"@type":"TechArticle","datePublished":"2024-03-01T10:00:00+02:00","dateModified":"2024-05-02",
+				 "author":[{"@type":"Person","name":"Ada"},"Grace"],"keywords":"install, cli"}]}</script>
+				<meta property="article:published_time" content="2020-01-01">
+				<meta name="author" content="Someone Else">`,
+			published: "2024-03-01", modified: "2024-05-02", authors: "Ada|Grace", tags: "install|cli",
+		},
+		{
+			name: "opengraph and meta tags",
+			html: `<meta property="article:published_time" content="2023-07-04T08:00:00Z">
+				<meta property="og:updated_time" content="2023-08-01T08:00:00Z">
+				<meta property="article:author" content="https://example.com/ada">
+				<meta property="article:tag" content="Go"><meta property="article:tag" content="go">
+				<meta name="author" content="Ada Lovelace">
+				<meta name="keywords" content="ignored">`,
+			published: "2023-07-04", modified: "2023-08-01", authors: "Ada Lovelace", tags: "Go",
+		},
+		{
+			name:     "last-modified header",
+			html:     `<meta name="keywords" content="a, b,, a">`,
+			header:   http.Header{"Last-Modified": {"Wed, 21 Oct 2015 07:28:00 GMT"}},
+			modified: "2015-10-21", tags: "a|b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><head>" + tt.html + "</head></html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+			var page PageInfo
+			extractPageDetails(&page, doc, header)
+
+			if got := formatDate("2006-01-02", page.Published); got != tt.published {
+				t.Errorf("published: got %q, want %q", got, tt.published)
+			}
+			if got := formatDate("2006-01-02", page.Modified); got != tt.modified {
+				t.Errorf("modified: got %q, want %q", got, tt.modified)
+			}
+			if got := strings.Join(page.Authors, "|"); got != tt.authors {
+				t.Errorf("authors: got %q, want %q", got, tt.authors)
+			}
+			if got := strings.Join(page.Tags, "|"); got != tt.tags {
+				t.Errorf("tags: got %q, want %q", got, tt.tags)
+			}
+		})
+	}
+}
+
+func TestRenderTemplate_Details(t *testing.T) {
+	published := parseDate("2024-01-15")
+	sections := map[string][]PageInfo{
+		"docs": {
+			{Title: "Install", URL: "https://example.com/docs/install", Published: published, Modified: parseDate("2024-06-30T12:00:00Z"), Authors: []string{"Ada", "Grace"}},
+			{Title: "Usage", URL: "https://example.com/docs/usage/more", Published: published},
+			{Title: "FAQ", URL: "https://example.com/docs/faq/all/of/it"},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "llms.tmpl")
+	content := `{{range .Pages}}- [{{.Title}}]({{.URL}}){{with date "2006-01-02" .Updated}} (updated {{.}}){{end}}{{with .Authors}} by {{join ", " .}}{{end}}
+{{end}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := loadTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewGenerator(GeneratorConfig{}).renderTemplate(tmpl, sections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "- [Install](https://example.com/docs/install) (updated 2024-06-30) by Ada, Grace\n" +
+		"- [Usage](https://example.com/docs/usage/more) (updated 2024-01-15)\n" +
+		"- [FAQ](https://example.com/docs/faq/all/of/it)\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -1,12 +1,15 @@
 package llmstxt
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
-	"time"
 
 	"github.com/gobwas/glob"
 )
@@ -31,23 +34,145 @@ type SitemapIndex struct {
 	} `xml:"sitemap"`
 }
 
-// Parse sitemap.xml file and return all URLs
-func (g *Generator) parseSitemap() ([]string, error) {
-	g.logger.Printf("Parsing sitemap from %s", g.config.SitemapURL)
+// commonSitemapPaths are tried when a site root has no Sitemap directive in robots.txt
+var commonSitemapPaths = []string{"/sitemap.xml", "/sitemap_index.xml"}
 
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Parse the sitemaps of the configured URL and return all page URLs. The URL
+// is either a sitemap or a site root whose sitemaps are discovered.
+func (g *Generator) parseSitemap(ctx context.Context) ([]string, error) {
 	// Set HTTP client
-	client := &http.Client{
-		Timeout: time.Duration(g.config.Timeout) * time.Second,
+	client := g.newClient()
+
+	if isSitemapURL(g.config.SitemapURL) {
+		g.logger.Printf("Parsing sitemap from %s", g.config.SitemapURL)
+		body, err := g.fetch(ctx, client, g.config.SitemapURL)
+		if err != nil {
+			return nil, err
+		}
+		return g.parseSitemapBody(ctx, client, body, true)
+	}
+
+	sitemaps, err := g.discoverSitemaps(ctx, client, g.config.SitemapURL)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var urls []string
+	for _, sitemap := range sitemaps {
+		g.logger.Printf("Parsing sitemap from %s", sitemap.url)
+		sitemapURLs, err := g.parseSitemapBody(ctx, client, sitemap.body, false)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sitemap.url, err)
+		}
+		for _, u := range sitemapURLs {
+			if !seen[u] {
+				seen[u] = true
+				urls = append(urls, u)
+			}
+		}
+	}
+	return urls, nil
+}
+
+// discoveredSitemap is a sitemap found below a site root
+type discoveredSitemap struct {
+	url  string
+	body []byte
+}
+
+// discoverSitemaps finds the sitemaps of a site from the Sitemap directives of
+// robots.txt, falling back to the common sitemap locations
+func (g *Generator) discoverSitemaps(ctx context.Context, client *http.Client, siteURL string) ([]discoveredSitemap, error) {
+	base, err := url.Parse(siteURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid URL: %s", siteURL)
+	}
+	origin := base.Scheme + "://" + base.Host
+	g.logger.Printf("Discovering sitemaps of %s", siteURL)
+
+	var candidates []string
+	if body, err := g.fetch(ctx, client, origin+"/robots.txt"); err == nil {
+		candidates = parseRobotsSitemaps(string(body))
+		g.logger.Printf("Found %d sitemaps in robots.txt", len(candidates))
+	} else if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	fromRobots := len(candidates) > 0
+	if !fromRobots {
+		prefix := strings.TrimSuffix(origin+base.Path, "/")
+		for _, path := range commonSitemapPaths {
+			candidates = append(candidates, prefix+path)
+			if prefix != origin {
+				candidates = append(candidates, origin+path)
+			}
+		}
+	}
+
+	var sitemaps []discoveredSitemap
+	for _, candidate := range candidates {
+		body, err := g.fetch(ctx, client, candidate)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			g.logger.Debugf("No sitemap at %s: %v", candidate, err)
+			continue
+		}
+		sitemaps = append(sitemaps, discoveredSitemap{url: candidate, body: body})
+
+		// Common locations usually point at the same pages, the first one wins
+		if !fromRobots {
+			break
+		}
+	}
+
+	if len(sitemaps) == 0 {
+		return nil, fmt.Errorf("no sitemap found for %s (tried robots.txt and %s)", siteURL, strings.Join(commonSitemapPaths, ", "))
+	}
+	return sitemaps, nil
+}
+
+// parseRobotsSitemaps returns the Sitemap directives of a robots.txt
+func parseRobotsSitemaps(robots string) []string {
+	var sitemaps []string
+	for _, line := range splitLines(robots) {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "sitemap") {
+			if value = strings.TrimSpace(value); value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+		}
 	}
+	return sitemaps
+}
 
-	// Build request
-	req, err := http.NewRequest("GET", g.config.SitemapURL, nil)
+// isSitemapURL reports whether a URL points at a sitemap rather than a site root
+func isSitemapURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return true
+	}
+	path := strings.ToLower(u.Path)
+	for _, ext := range []string{".xml", ".xml.gz", ".gz", ".txt"} {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
 	}
+	return false
+}
 
-	// Set User-Agent
-	req.Header.Set("User-Agent", g.config.UserAgent)
+// fetch downloads a sitemap or robots.txt, decompressing gzip content
+func (g *Generator) fetch(ctx context.Context, client *http.Client, rawURL string) ([]byte, error) {
+	// Build request with User-Agent and credentials
+	req, err := g.newRequest(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
 
 	// Send request
 	resp, err := client.Do(req)
@@ -66,6 +191,24 @@ func (g *Generator) parseSitemap() ([]string, error) {
 		return nil, fmt.Errorf("failed to read sitemap content: %w", err)
 	}
 
+	// Compressed sitemaps (sitemap.xml.gz) are served as plain gzip files
+	if bytes.HasPrefix(body, gzipMagic) {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap: %w", err)
+		}
+		defer reader.Close()
+		if body, err = io.ReadAll(reader); err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap: %w", err)
+		}
+	}
+
+	return body, nil
+}
+
+// parseSitemapBody returns the URLs of a sitemap or sitemap index. Plain text
+// sitemaps (one URL per line) are only accepted if allowText is set.
+func (g *Generator) parseSitemapBody(ctx context.Context, client *http.Client, body []byte, allowText bool) ([]string, error) {
 	// Try to parse as standard sitemap
 	var sitemap Sitemap
 	if err := xml.Unmarshal(body, &sitemap); err == nil && len(sitemap.URLs) > 0 {
@@ -77,12 +220,12 @@ func (g *Generator) parseSitemap() ([]string, error) {
 	var sitemapIndex SitemapIndex
 	if err := xml.Unmarshal(body, &sitemapIndex); err == nil && len(sitemapIndex.Sitemaps) > 0 {
 		g.logger.Println("Parsed sitemap index, fetching child sitemaps")
-		return g.fetchSitemapIndex(sitemapIndex, client)
+		return g.fetchSitemapIndex(ctx, sitemapIndex, client)
 	}
 
 	// If all parsing fails, try to handle as text sitemap (one URL per line)
 	lines := string(body)
-	if len(lines) > 0 {
+	if allowText && len(lines) > 0 {
 		g.logger.Println("Parsing as text sitemap")
 		return g.parseTextSitemap(lines), nil
 	}
@@ -102,45 +245,29 @@ func (g *Generator) extractURLsFromSitemap(sitemap Sitemap) []string {
 }
 
 // Get all child sitemap URLs from sitemap index
-func (g *Generator) fetchSitemapIndex(index SitemapIndex, client *http.Client) ([]string, error) {
+func (g *Generator) fetchSitemapIndex(ctx context.Context, index SitemapIndex, client *http.Client) ([]string, error) {
 	var allURLs []string
 
 	for _, sitemapEntry := range index.Sitemaps {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		if sitemapEntry.Loc == "" {
 			continue
 		}
 
 		g.logger.Printf("Fetching child sitemap: %s", sitemapEntry.Loc)
 
-		// Build request
-		req, err := http.NewRequest("GET", sitemapEntry.Loc, nil)
-		if err != nil {
-			g.logger.Printf("Warning: failed to create request for child sitemap %s: %v", sitemapEntry.Loc, err)
-			continue
-		}
-
-		// Set User-Agent
-		req.Header.Set("User-Agent", g.config.UserAgent)
-
-		// Send request
-		resp, err := client.Do(req)
-		if err != nil {
-			g.logger.Printf("Warning: failed to fetch child sitemap %s: %v", sitemapEntry.Loc, err)
-			continue
-		}
-
-		// Read response body
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
+		body, err := g.fetch(ctx, client, sitemapEntry.Loc)
 		if err != nil {
-			g.logger.Printf("Warning: failed to read child sitemap %s: %v", sitemapEntry.Loc, err)
+			g.logger.Warnf("Failed to fetch child sitemap %s: %v", sitemapEntry.Loc, err)
 			continue
 		}
 
 		// Parse child sitemap
 		var childSitemap Sitemap
 		if err := xml.Unmarshal(body, &childSitemap); err != nil {
-			g.logger.Printf("Warning: failed to parse child sitemap %s: %v", sitemapEntry.Loc, err)
+			g.logger.Warnf("Failed to parse child sitemap %s: %v", sitemapEntry.Loc, err)
 			continue
 		}
 
@@ -179,7 +306,7 @@ func (g *Generator) filterURLs(urls []string) []string {
 	for _, pattern := range g.config.IncludePaths {
 		matcher, err := glob.Compile(pattern)
 		if err != nil {
-			g.logger.Printf("Warning: invalid include pattern '%s': %v", pattern, err)
+			g.logger.Warnf("Invalid include pattern '%s': %v", pattern, err)
 			continue
 		}
 		includeMatchers = append(includeMatchers, matcher)
@@ -188,7 +315,7 @@ func (g *Generator) filterURLs(urls []string) []string {
 	for _, pattern := range g.config.ExcludePaths {
 		matcher, err := glob.Compile(pattern)
 		if err != nil {
-			g.logger.Printf("Warning: invalid exclude pattern '%s': %v", pattern, err)
+			g.logger.Warnf("Invalid exclude pattern '%s': %v", pattern, err)
 			continue
 		}
 		excludeMatchers = append(excludeMatchers, matcher)
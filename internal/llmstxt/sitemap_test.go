@@ -0,0 +1,74 @@
+package llmstxt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseSitemap_Discovery(t *testing.T) {
+	gzipped := func(s string) []byte {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write([]byte(s))
+		w.Close()
+		return buf.Bytes()
+	}
+
+	var server *httptest.Server
+	withRobots := true
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			if !withRobots {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintf(w, "User-agent: *\nDisallow: /private\nsitemap: %s/index.xml.gz\n", server.URL)
+		case "/index.xml.gz":
+			w.Write(gzipped(fmt.Sprintf(`<sitemapindex><sitemap><loc>%s/pages.xml.gz</loc></sitemap></sitemapindex>`, server.URL)))
+		case "/pages.xml.gz":
+			w.Write(gzipped(fmt.Sprintf(`<urlset><url><loc>%s/a</loc></url><url><loc>%s/b</loc></url></urlset>`, server.URL, server.URL)))
+		case "/sitemap_index.xml":
+			fmt.Fprintf(w, `<urlset><url><loc>%s/c</loc></url></urlset>`, server.URL)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		url        string
+		withRobots bool
+		want       []string
+	}{
+		{"robots sitemap directive", server.URL, true, []string{server.URL + "/a", server.URL + "/b"}},
+		{"common path fallback", server.URL + "/", false, []string{server.URL + "/c"}},
+		{"explicit gzip sitemap", server.URL + "/pages.xml.gz", false, []string{server.URL + "/a", server.URL + "/b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withRobots = tt.withRobots
+			g := NewGenerator(GeneratorConfig{SitemapURL: tt.url, Timeout: 5})
+			got, err := g.parseSitemap(context.Background())
+			if err != nil {
+				t.Fatalf("parseSitemap failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	withRobots = false
+	g := NewGenerator(GeneratorConfig{SitemapURL: server.URL + "/docs", Timeout: 5})
+	if _, err := g.parseSitemap(context.Background()); err != nil {
+		t.Errorf("expected origin fallback for a sub path, got %v", err)
+	}
+}
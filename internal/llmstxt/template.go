@@ -0,0 +1,115 @@
+package llmstxt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateData is the data passed to custom output templates
+type TemplateData struct {
+	URL         string     // Sitemap or site URL the document was generated from
+	Title       string     // Title of the root page
+	Description string     // Description of the root page
+	Sections    []Section  // Sections in default order, without the root page
+	Pages       []PageInfo // All pages except the root page, in section order
+	FullMode    bool       // Whether page content was extracted
+	Generated   time.Time
+}
+
+// Section groups the pages below the same first URL path segment
+type Section struct {
+	Name  string // First URL path segment
+	Title string // Capitalized name
+	Pages []PageInfo
+}
+
+// templateFuncs are available in custom output templates
+var templateFuncs = template.FuncMap{
+	"capitalize": capitalizeString,
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"trim":       strings.TrimSpace,
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"truncate":   func(maxLen int, s string) string { return truncateString(s, maxLen) },
+	"default": func(fallback, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	},
+	"join": func(sep string, values []string) string { return strings.Join(values, sep) },
+	"date": formatDate,
+}
+
+// formatDate formats a time with a Go layout such as 2006-01-02, unknown
+// dates give an empty string
+func formatDate(layout string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// loadTemplate parses a custom output template file
+func loadTemplate(path string) (*template.Template, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %v", err)
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// templateData collects the sections in the order of the default format
+func (g *Generator) templateData(sections map[string][]PageInfo) TemplateData {
+	data := TemplateData{
+		URL:       g.config.SitemapURL,
+		FullMode:  g.config.FullMode,
+		Generated: time.Now(),
+	}
+	if rootPages, ok := sections["ROOT"]; ok && len(rootPages) > 0 {
+		data.Title = rootPages[0].Title
+		data.Description = rootPages[0].Description
+	}
+
+	for _, name := range g.getSortedSections(sections) {
+		if name == "ROOT" {
+			continue
+		}
+		data.Sections = append(data.Sections, Section{
+			Name:  name,
+			Title: capitalizeString(name),
+			Pages: sections[name],
+		})
+		data.Pages = append(data.Pages, sections[name]...)
+	}
+	return data
+}
+
+// Section returns the section with the given name, so templates can order
+// sections explicitly
+func (d TemplateData) Section(name string) *Section {
+	for i := range d.Sections {
+		if d.Sections[i].Name == name {
+			return &d.Sections[i]
+		}
+	}
+	return nil
+}
+
+// renderTemplate formats the sections with a custom output template
+func (g *Generator) renderTemplate(tmpl *template.Template, sections map[string][]PageInfo) (string, error) {
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, g.templateData(sections)); err != nil {
+		return "", fmt.Errorf("failed to render template: %v", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,76 @@
+package llmstxt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// defaultTemplate reproduces the built-in format, it is the starting point
+// documented in the README
+const defaultTemplate = `# {{.Title}}
+
+> {{.Description}}
+
+{{range .Sections}}## {{.Title}}
+
+{{range .Pages}}- [{{.Title}}]({{.URL}}): {{.Description}}
+{{if and $.FullMode .Content}}
+{{.Content}}
+{{end}}
+{{end}}{{end}}`
+
+func TestRenderTemplate(t *testing.T) {
+	sections := map[string][]PageInfo{
+		"ROOT": {{Title: "Example", URL: "https://example.com/", Description: "Example site"}},
+		"docs": {
+			{Title: "Install", URL: "https://example.com/docs/install", Description: "How to install", Content: "Run it."},
+			{Title: "Usage", URL: "https://example.com/docs/usage/more", Description: "How to use"},
+		},
+		"blog": {{Title: "News", URL: "https://example.com/blog/news", Description: "Latest news"}},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		fullMode bool
+		want     string
+	}{
+		{"default equivalent", defaultTemplate, false, ""},
+		{"default equivalent full mode", defaultTemplate, true, ""},
+		{
+			name: "custom order and links",
+			template: `# {{.Title | upper}}
+{{with .Section "docs"}}{{range .Pages}}* {{.URL}} {{.Description | truncate 6}}
+{{end}}{{end}}{{with .Section "missing"}}never{{end}}{{(.Section "blog").Title}}`,
+			want: "# EXAMPLE\n* https://example.com/docs/install How to...\n* https://example.com/docs/usage/more How to...\nBlog",
+		},
+	}
+
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "llms.tmpl")
+			if err := os.WriteFile(path, []byte(tt.template), 0644); err != nil {
+				t.Fatal(err)
+			}
+			tmpl, err := loadTemplate(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			g := NewGenerator(GeneratorConfig{FullMode: tt.fullMode})
+			got, err := g.renderTemplate(tmpl, sections)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.want
+			if want == "" {
+				want = g.formatContent(sections)
+			}
+			if got != want {
+				t.Errorf("got:\n%q\nwant:\n%q", got, want)
+			}
+		})
+	}
+}
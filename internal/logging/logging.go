@@ -0,0 +1,209 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level defines the severity of a log message
+type Level int
+
+const (
+	// LevelDebug is for detailed diagnostic output, shown with -v
+	LevelDebug Level = iota
+	// LevelInfo is for regular progress messages
+	LevelInfo
+	// LevelWarn is for recoverable problems
+	LevelWarn
+	// LevelError is for failures
+	LevelError
+)
+
+// String returns the lowercase name of the level
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+}
+
+// ParseLevel converts a level name into a Level
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", name)
+	}
+}
+
+// Options configures the shared log output
+type Options struct {
+	Level  Level  // Minimum level that is written
+	Format string // Output format: text or json
+	File   string // Optional log file, stderr is used when empty
+}
+
+var (
+	mu       sync.Mutex
+	output   io.Writer = os.Stderr
+	logFile  *os.File
+	minLevel = LevelInfo
+	format   = "text"
+)
+
+// Configure applies options to every logger, including ones created earlier
+func Configure(opts Options) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	switch opts.Format {
+	case "", "text":
+		format = "text"
+	case "json":
+		format = "json"
+	default:
+		return fmt.Errorf("invalid log format: %s (must be text or json)", opts.Format)
+	}
+
+	if logFile != nil {
+		logFile.Close()
+		logFile = nil
+	}
+	output = os.Stderr
+
+	if opts.File != "" {
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %v", err)
+		}
+		logFile = f
+		output = f
+	}
+
+	minLevel = opts.Level
+	return nil
+}
+
+// Close releases the log file, if one is open
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if logFile == nil {
+		return nil
+	}
+	err := logFile.Close()
+	logFile = nil
+	output = os.Stderr
+	return err
+}
+
+// Logger writes leveled messages tagged with a component prefix
+type Logger struct {
+	prefix string
+}
+
+// New creates a logger for the given component, e.g. "EXPORT"
+func New(prefix string) *Logger {
+	return &Logger{prefix: prefix}
+}
+
+// Enabled reports whether messages at the given level are written
+func (l *Logger) Enabled(level Level) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return level >= minLevel
+}
+
+// Debugf logs a diagnostic message
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.log(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+// Infof logs a progress message
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.log(LevelInfo, fmt.Sprintf(format, args...))
+}
+
+// Warnf logs a recoverable problem
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.log(LevelWarn, fmt.Sprintf(format, args...))
+}
+
+// Errorf logs a failure
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.log(LevelError, fmt.Sprintf(format, args...))
+}
+
+// Printf logs a diagnostic message, matching the log.Logger method set
+func (l *Logger) Printf(format string, args ...interface{}) {
+	l.log(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+// Println logs a diagnostic message, matching the log.Logger method set
+func (l *Logger) Println(args ...interface{}) {
+	l.log(LevelDebug, strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+}
+
+// log formats and writes a single message
+func (l *Logger) log(level Level, msg string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if level < minLevel {
+		return
+	}
+
+	now := time.Now()
+	if format == "json" {
+		data, err := json.Marshal(struct {
+			Time      string `json:"time"`
+			Level     string `json:"level"`
+			Component string `json:"component,omitempty"`
+			Message   string `json:"msg"`
+		}{
+			Time:      now.Format(time.RFC3339),
+			Level:     level.String(),
+			Component: strings.ToLower(l.prefix),
+			Message:   msg,
+		})
+		if err == nil {
+			output.Write(append(data, '\n'))
+		}
+		return
+	}
+
+	var line string
+	switch level {
+	case LevelDebug:
+		// Diagnostic lines keep the timestamped, prefixed layout of the old verbose loggers
+		line = fmt.Sprintf("%s [%s] %s", now.Format("2006/01/02 15:04:05"), l.prefix, msg)
+	case LevelWarn:
+		line = "Warning: " + msg
+	case LevelError:
+		line = "Error: " + msg
+	default:
+		line = msg
+	}
+	fmt.Fprintln(output, line)
+}
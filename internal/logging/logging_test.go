@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Level
+		wantErr bool
+	}{
+		{"debug", LevelDebug, false},
+		{"info", LevelInfo, false},
+		{"", LevelInfo, false},
+		{"warn", LevelWarn, false},
+		{" Warning ", LevelWarn, false},
+		{"ERROR", LevelError, false},
+		{"trace", LevelInfo, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLevel(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defer Configure(Options{Level: LevelInfo})
+
+	tests := []struct {
+		name    string
+		format  string
+		level   Level
+		want    []string // Lines written by Debugf, Infof, Warnf and Errorf
+		wantErr bool
+	}{
+		{"default", "", LevelInfo, []string{"info", "Warning: warn", "Error: error"}, false},
+		{"text", "text", LevelWarn, []string{"Warning: warn", "Error: error"}, false},
+		{"debug", "text", LevelDebug, []string{"[TEST] debug", "info", "Warning: warn", "Error: error"}, false},
+		{"json", "json", LevelWarn, []string{`"level":"warn","component":"test","msg":"warn"`, `"level":"error","component":"test","msg":"error"`}, false},
+		{"invalid", "xml", LevelInfo, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "mdctl.log")
+			err := Configure(Options{Level: tt.level, Format: tt.format, File: file})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			logger := New("TEST")
+			logger.Debugf("debug")
+			logger.Infof("info")
+			logger.Warnf("warn")
+			logger.Errorf("error")
+			if err := Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("expected %d lines, got:\n%s", len(tt.want), data)
+			}
+			for i, line := range lines {
+				if !strings.Contains(line, tt.want[i]) {
+					t.Errorf("line %d = %q, want it to contain %q", i, line, tt.want[i])
+				}
+				if tt.format == "json" && !json.Valid([]byte(line)) {
+					t.Errorf("line %d is not JSON: %s", i, line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,384 @@
+package markdownfmt
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/samzong/mdctl/internal/mddoc"
+)
+
+// Reflow modes
+const (
+	ReflowWrap     = "wrap"     // Fill every line up to the width
+	ReflowSemantic = "semantic" // Start every sentence on a new line, wrapping at the width
+)
+
+// DefaultWidth is the line width paragraphs are wrapped at
+const DefaultWidth = 80
+
+// ReflowOptions controls the reflow of paragraphs
+type ReflowOptions struct {
+	Mode  string // ReflowWrap (default) or ReflowSemantic
+	Width int    // Maximum display width of a line, DefaultWidth when 0
+}
+
+var (
+	reflowFenceRegex = regexp.MustCompile("^\\s*(?:>\\s?)*\\s*(```|~~~)")
+	quotePrefixRegex = regexp.MustCompile(`^(?:\s{0,3}>\s?)+`)
+	listMarkerRegex  = regexp.MustCompile(`^\s*(?:[*+-]|\d{1,9}[.)])(?:\s+\[[ xX]\])?\s+`)
+	// blockLineRegex matches lines that are never part of a paragraph:
+	// headings, HTML, tables, math, admonitions, template tags and link
+	// reference or footnote definitions
+	blockLineRegex  = regexp.MustCompile(`^\s{0,3}(?:#|<|\||\$\$|:::|!!!|\{[{%]|\[[^\]]+\]:)`)
+	thematicRegex   = regexp.MustCompile(`^\s{0,3}(?:(?:\*\s*){3,}|(?:-\s*){3,}|(?:_\s*){3,})$`)
+	setextRegex     = regexp.MustCompile(`^\s{0,3}(?:=+|-+)\s*$`)
+	tableDelimRegex = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$`)
+	// inlineLinkAtRegex matches a link or image at the start of the text,
+	// which is kept on one line
+	inlineLinkAtRegex  = regexp.MustCompile(`^!?\[(?:[^\[\]\\]|\\.)*\](?:\([^)]*\)|\[(?:[^\[\]\\]|\\.)*\])?`)
+	orderedMarkerRegex = regexp.MustCompile(`^\d{1,9}[.)]$`)
+)
+
+// Reflow rewraps the paragraphs of a document, including those of list
+// items and block quotes. Code, tables, headings, HTML and front matter are
+// kept as they are, inline code, links and HTML tags are never broken, and
+// hard line breaks stay in place. CJK text is measured at double width and
+// broken between characters.
+func Reflow(content string, opts ReflowOptions) string {
+	if opts.Width <= 0 {
+		opts.Width = DefaultWidth
+	}
+
+	lines := strings.Split(content, "\n")
+	start := mddoc.Split([]byte(content)).BodyLine - 1
+	result := append([]string{}, lines[:start]...)
+
+	fence := ""
+	for i := start; i < len(lines); {
+		line := lines[i]
+		if m := reflowFenceRegex.FindStringSubmatch(line); m != nil {
+			if fence == "" {
+				fence = m[1]
+			} else if m[1] == fence {
+				fence = ""
+			}
+			result = append(result, line)
+			i++
+			continue
+		}
+		if fence != "" || !startsParagraph(lines, i) {
+			result = append(result, line)
+			i++
+			continue
+		}
+
+		end := i + 1
+		for end < len(lines) && continuesParagraph(lines[i], lines[end]) {
+			end++
+		}
+		paragraph := lines[i:end]
+		// Setext headings and tables are not paragraphs
+		if (end < len(lines) && setextRegex.MatchString(stripQuote(lines[end]))) || isTable(paragraph) {
+			result = append(result, paragraph...)
+		} else {
+			result = append(result, reflowParagraph(paragraph, opts)...)
+		}
+		i = end
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// startsParagraph reports whether a paragraph starts at line i. Indented
+// lines after a blank line are left alone, they may be code.
+func startsParagraph(lines []string, i int) bool {
+	text := stripQuote(lines[i])
+	if strings.TrimSpace(text) == "" || isBlockLine(text) {
+		return false
+	}
+	if listMarkerRegex.MatchString(text) {
+		return strings.TrimSpace(listMarkerRegex.ReplaceAllString(text, "")) != ""
+	}
+	indent := len(text) - len(strings.TrimLeft(text, " \t"))
+	return indent < 4 && !strings.HasPrefix(text, "\t")
+}
+
+// continuesParagraph reports whether line continues the paragraph started
+// by first
+func continuesParagraph(first, line string) bool {
+	if quotePrefix(first) != quotePrefix(line) {
+		return false
+	}
+	text := stripQuote(line)
+	return strings.TrimSpace(text) != "" && !isBlockLine(text) &&
+		!listMarkerRegex.MatchString(text) && !reflowFenceRegex.MatchString(line)
+}
+
+// isBlockLine reports whether a line without its quote prefix is never part
+// of a paragraph
+func isBlockLine(text string) bool {
+	return blockLineRegex.MatchString(text) || thematicRegex.MatchString(text)
+}
+
+// isTable reports whether paragraph lines are a table without leading pipes
+func isTable(lines []string) bool {
+	for _, line := range lines {
+		text := stripQuote(line)
+		if strings.Contains(text, "|") && tableDelimRegex.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// quotePrefix returns the block quote markers at the start of a line
+func quotePrefix(line string) string {
+	return strings.ReplaceAll(quotePrefixRegex.FindString(line), " ", "")
+}
+
+// stripQuote removes the block quote markers at the start of a line
+func stripQuote(line string) string {
+	return line[len(quotePrefixRegex.FindString(line)):]
+}
+
+// reflowParagraph rewraps the lines of a paragraph
+func reflowParagraph(lines []string, opts ReflowOptions) []string {
+	quote := quotePrefixRegex.FindString(lines[0])
+	marker := listMarkerRegex.FindString(lines[0][len(quote):])
+	if marker == "" {
+		text := lines[0][len(quote):]
+		marker = text[:len(text)-len(strings.TrimLeft(text, " "))]
+	}
+	if quote != "" && !strings.HasSuffix(quote, " ") {
+		quote += " "
+	}
+	firstPrefix := quote + marker
+	nextPrefix := quote + strings.Repeat(" ", DisplayWidth(marker))
+
+	var result []string
+	var atoms []atom
+	for i, line := range lines {
+		content := stripQuote(line)
+		if i == 0 {
+			content = content[len(marker):]
+		}
+		// A backslash or two spaces at the end of a line other than the
+		// last are a hard line break
+		hardBreak := ""
+		if i < len(lines)-1 {
+			switch trimmed := strings.TrimRight(content, " \t"); {
+			case strings.HasSuffix(trimmed, "\\") && !strings.HasSuffix(trimmed, "\\\\"):
+				hardBreak, content = "\\", strings.TrimSuffix(trimmed, "\\")
+			case strings.HasSuffix(content, "  "):
+				hardBreak = "  "
+			}
+		}
+
+		lineAtoms := tokenize(strings.TrimSpace(content))
+		if len(lineAtoms) > 0 && len(atoms) > 0 {
+			// Line breaks between CJK characters are not spaces
+			last, _ := utf8.DecodeLastRuneInString(atoms[len(atoms)-1].text)
+			next, _ := utf8.DecodeRuneInString(lineAtoms[0].text)
+			lineAtoms[0].space = !(isCJK(last) && isCJK(next))
+		}
+		atoms = append(atoms, lineAtoms...)
+
+		if hardBreak != "" || i == len(lines)-1 {
+			filled := fill(atoms, opts, DisplayWidth(firstPrefix), DisplayWidth(nextPrefix), len(result) == 0)
+			if len(filled) == 0 {
+				filled = []string{""}
+			}
+			for _, l := range filled {
+				if len(result) == 0 {
+					result = append(result, firstPrefix+l)
+				} else {
+					result = append(result, nextPrefix+l)
+				}
+			}
+			result[len(result)-1] += hardBreak
+			atoms = nil
+		}
+	}
+	return result
+}
+
+// atom is text a line is never broken in
+type atom struct {
+	text  string
+	space bool // Separated from the previous atom by a space
+}
+
+// tokenize splits text into atoms at spaces and between CJK characters.
+// Code spans, links, images, autolinks and HTML tags are single atoms.
+func tokenize(text string) []atom {
+	var atoms []atom
+	var current strings.Builder
+	space := false
+	flush := func() {
+		if current.Len() > 0 {
+			atoms = append(atoms, atom{text: current.String(), space: space})
+			current.Reset()
+			space = false
+		}
+	}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if unicode.IsSpace(r) {
+			flush()
+			if len(atoms) > 0 {
+				space = true
+			}
+			i += size
+			continue
+		}
+
+		end := i + size
+		switch {
+		case r == '\\' && end < len(text):
+			_, escaped := utf8.DecodeRuneInString(text[end:])
+			end += escaped
+		case r == '`':
+			ticks := len(text[i:]) - len(strings.TrimLeft(text[i:], "`"))
+			if close := strings.Index(text[i+ticks:], text[i:i+ticks]); close >= 0 {
+				end = i + ticks + close + ticks
+			} else {
+				end = i + ticks
+			}
+		case r == '[' || (r == '!' && strings.HasPrefix(text[end:], "[")):
+			if loc := inlineLinkAtRegex.FindStringIndex(text[i:]); loc != nil {
+				end = i + loc[1]
+			}
+		case r == '<' && end < len(text) && (isASCIILetter(text[end]) || text[end] == '/' || text[end] == '!'):
+			if close := strings.IndexByte(text[i:], '>'); close > 0 {
+				end = i + close + 1
+			}
+		}
+
+		// CJK text breaks between characters, except before closing and
+		// after opening punctuation
+		if current.Len() > 0 {
+			prev, _ := utf8.DecodeLastRuneInString(current.String())
+			if (isCJK(prev) || isCJK(r)) && !strings.ContainsRune(noBreakBefore, r) && !strings.ContainsRune(noBreakAfter, prev) {
+				flush()
+			}
+		}
+		current.WriteString(text[i:end])
+		i = end
+	}
+	flush()
+	return atoms
+}
+
+// isASCIILetter reports whether c is an ASCII letter
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Punctuation a line must not start or end with
+const (
+	noBreakBefore = "，。、；：？！）」』》〉】〕…—·,.;:?!)]}%'\"”’"
+	noBreakAfter  = "（「『《〈【〔([{“‘"
+)
+
+// fill arranges atoms into lines no wider than the width. Atoms wider than
+// a line get a line of their own, atoms that would start a block are kept
+// on the previous line. The first line is narrowed by firstIndent when
+// first is set, all others by nextIndent.
+func fill(atoms []atom, opts ReflowOptions, firstIndent, nextIndent int, first bool) []string {
+	var lines []string
+	var current strings.Builder
+	width := 0
+	limit := func() int {
+		if first && len(lines) == 0 {
+			return opts.Width - firstIndent
+		}
+		return opts.Width - nextIndent
+	}
+	flush := func() {
+		if current.Len() > 0 {
+			lines = append(lines, current.String())
+			current.Reset()
+			width = 0
+		}
+	}
+
+	for i, a := range atoms {
+		sep := ""
+		if a.space && current.Len() > 0 {
+			sep = " "
+		}
+		w := DisplayWidth(a.text)
+		if current.Len() > 0 && width+len(sep)+w > limit() && !startsBlock(a.text) {
+			flush()
+			sep = ""
+		}
+		current.WriteString(sep + a.text)
+		width += len(sep) + w
+
+		if opts.Mode == ReflowSemantic && i+1 < len(atoms) && !startsBlock(atoms[i+1].text) {
+			if end, cjk := endsSentence(a.text); end && (cjk || atoms[i+1].space) {
+				flush()
+			}
+		}
+	}
+	flush()
+	return lines
+}
+
+// startsBlock reports whether a line starting with text would start a list
+// item, heading, block quote, table, fence or setext underline
+func startsBlock(text string) bool {
+	switch text {
+	case "-", "+", "*", ">":
+		return true
+	}
+	return orderedMarkerRegex.MatchString(text) || setextRegex.MatchString(text) ||
+		strings.HasPrefix(text, "#") || strings.HasPrefix(text, ">") || strings.HasPrefix(text, "|") ||
+		strings.HasPrefix(text, "```") || strings.HasPrefix(text, "~~~") || strings.HasPrefix(text, "<")
+}
+
+// abbreviations end with a period but not a sentence
+var abbreviations = map[string]bool{"e.g.": true, "i.e.": true, "vs.": true, "cf.": true, "Mr.": true, "Mrs.": true, "Dr.": true, "No.": true}
+
+// endsSentence reports whether an atom ends a sentence, and whether with CJK
+// punctuation, which is not followed by a space
+func endsSentence(text string) (bool, bool) {
+	if abbreviations[text] {
+		return false, false
+	}
+	trimmed := strings.TrimRight(text, "\"')]*_”’」』）")
+	r, _ := utf8.DecodeLastRuneInString(trimmed)
+	switch r {
+	case '.', '!', '?':
+		return true, false
+	case '。', '！', '？':
+		return true, true
+	}
+	return false, false
+}
+
+// isCJK reports whether a rune is a Chinese or Japanese character or CJK
+// punctuation, between which lines can break
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff01 && r <= 0xff60)
+}
+
+// DisplayWidth returns the width of text in a monospace terminal or editor:
+// CJK and other wide characters count twice, combining marks not at all
+func DisplayWidth(text string) int {
+	width := 0
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Mn, r) || r == 0x200d || r == 0xfe0f:
+		case isCJK(r) || unicode.Is(unicode.Hangul, r) || (r >= 0xffe0 && r <= 0xffe6) || (r >= 0x1f300 && r <= 0x1faff):
+			width += 2
+		default:
+			width++
+		}
+	}
+	return width
+}
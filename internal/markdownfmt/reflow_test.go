@@ -0,0 +1,90 @@
+package markdownfmt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReflowWrap(t *testing.T) {
+	content := strings.Join([]string{
+		"---",
+		"title: A front matter line that is much longer than the width of forty",
+		"---",
+		"# A heading that is much longer than the width of forty columns",
+		"",
+		"A paragraph with `a code span` and a [link with text](https://example.com/a)",
+		"that is wrapped. The count is - 3 or 1.",
+		"",
+		"- A list item that is long enough to wrap onto the next line",
+		"> A quote that is long enough to wrap onto the next line too",
+		"",
+		"| a | b |",
+		"|---|---|",
+		"",
+		"```",
+		"code that is much longer than the width of forty columns is kept",
+		"```",
+		"",
+		"Hard break  ",
+		"kept",
+		"",
+	}, "\n")
+	want := strings.Join([]string{
+		"---",
+		"title: A front matter line that is much longer than the width of forty",
+		"---",
+		"# A heading that is much longer than the width of forty columns",
+		"",
+		"A paragraph with `a code span` and a",
+		"[link with text](https://example.com/a)",
+		"that is wrapped. The count is - 3 or 1.",
+		"",
+		"- A list item that is long enough to",
+		"  wrap onto the next line",
+		"> A quote that is long enough to wrap",
+		"> onto the next line too",
+		"",
+		"| a | b |",
+		"|---|---|",
+		"",
+		"```",
+		"code that is much longer than the width of forty columns is kept",
+		"```",
+		"",
+		"Hard break  ",
+		"kept",
+		"",
+	}, "\n")
+
+	if got := Reflow(content, ReflowOptions{Width: 40}); got != want {
+		t.Errorf("unexpected reflow:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestReflowSemantic(t *testing.T) {
+	content := "First sentence. Second one, e.g. with an abbreviation! Third?\n"
+	want := "First sentence.\nSecond one, e.g. with an abbreviation!\nThird?\n"
+	if got := Reflow(content, ReflowOptions{Mode: ReflowSemantic}); got != want {
+		t.Errorf("unexpected reflow:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestReflowCJK(t *testing.T) {
+	content := "这是一个很长的中文段落，用于测试中文换行\n是否正确处理。English words 和中文。\n"
+	got := Reflow(content, ReflowOptions{Width: 20})
+	for _, line := range strings.Split(got, "\n") {
+		if DisplayWidth(line) > 20 {
+			t.Errorf("line wider than 20 columns: %q", line)
+		}
+		if strings.HasPrefix(line, "，") || strings.HasPrefix(line, "。") {
+			t.Errorf("line starts with closing punctuation: %q", line)
+		}
+	}
+	// Line breaks between CJK characters do not become spaces
+	if strings.Contains(strings.ReplaceAll(got, "\n", ""), "换行 是否") {
+		t.Errorf("space inserted between CJK characters:\n%s", got)
+	}
+	if DisplayWidth("中文ab") != 6 {
+		t.Errorf("expected CJK characters to be two columns wide")
+	}
+}
@@ -0,0 +1,260 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/samzong/mdctl/internal/logging"
+)
+
+// ProtocolVersion is the MCP revision implemented by the server
+const ProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+var logger = logging.New("MCP")
+
+// Handler runs a tool with its JSON arguments and returns the text result
+type Handler func(ctx context.Context, args json.RawMessage) (string, error)
+
+// Tool is a capability exposed to MCP clients
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+	Handler     Handler         `json:"-"`
+}
+
+// Server serves tools over the MCP stdio transport, one JSON-RPC message per line
+type Server struct {
+	name    string
+	version string
+	tools   []Tool
+
+	writeMu  sync.Mutex
+	cancelMu sync.Mutex
+	inFlight map[string]context.CancelFunc
+}
+
+// request is an incoming JSON-RPC request or notification
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is an outgoing JSON-RPC response
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// content is a single item of a tool result
+type content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// callResult is the result of tools/call
+type callResult struct {
+	Content []content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// NewServer creates a server exposing the given tools
+func NewServer(name, version string, tools []Tool) *Server {
+	return &Server{
+		name:     name,
+		version:  version,
+		tools:    tools,
+		inFlight: make(map[string]context.CancelFunc),
+	}
+}
+
+// Serve reads requests from r and writes responses to w until r is closed or
+// ctx is cancelled. Tool calls run concurrently so a long translation does not
+// block other requests.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+
+	go func() {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				lines <- line
+			}
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			return err
+		case line := <-lines:
+			var req request
+			if err := json.Unmarshal(line, &req); err != nil {
+				s.write(w, response{JSONRPC: "2.0", ID: json.RawMessage("null"),
+					Error: &rpcError{Code: codeParseError, Message: fmt.Sprintf("parse error: %v", err)}})
+				continue
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.handle(ctx, w, req)
+			}()
+		}
+	}
+}
+
+// handle dispatches a single request and writes its response
+func (s *Server) handle(ctx context.Context, w io.Writer, req request) {
+	// Notifications carry no id and never get a response
+	if len(req.ID) == 0 {
+		if req.Method == "notifications/cancelled" {
+			var params struct {
+				RequestID json.RawMessage `json:"requestId"`
+			}
+			if err := json.Unmarshal(req.Params, &params); err == nil {
+				s.cancel(string(params.RequestID))
+			}
+		}
+		return
+	}
+
+	resp := response{JSONRPC: "2.0", ID: req.ID}
+	if req.JSONRPC != "2.0" {
+		resp.Error = &rpcError{Code: codeInvalidRequest, Message: "invalid JSON-RPC version"}
+		s.write(w, resp)
+		return
+	}
+
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+		version := params.ProtocolVersion
+		if version == "" {
+			version = ProtocolVersion
+		}
+		resp.Result = map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": s.name, "version": s.version},
+		}
+	case "ping":
+		resp.Result = struct{}{}
+	case "tools/list":
+		resp.Result = map[string]interface{}{"tools": s.tools}
+	case "tools/call":
+		result, rpcErr := s.call(ctx, req)
+		resp.Result, resp.Error = result, rpcErr
+	default:
+		resp.Error = &rpcError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+
+	s.write(w, resp)
+}
+
+// call runs a tool, reporting tool failures in the result so the client can see them
+func (s *Server) call(ctx context.Context, req request) (interface{}, *rpcError) {
+	var params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+	}
+
+	var tool *Tool
+	for i := range s.tools {
+		if s.tools[i].Name == params.Name {
+			tool = &s.tools[i]
+			break
+		}
+	}
+	if tool == nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "unknown tool: " + params.Name}
+	}
+	if len(params.Arguments) == 0 {
+		params.Arguments = json.RawMessage("{}")
+	}
+
+	callCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.cancelMu.Lock()
+	s.inFlight[string(req.ID)] = cancel
+	s.cancelMu.Unlock()
+	defer func() {
+		s.cancelMu.Lock()
+		delete(s.inFlight, string(req.ID))
+		s.cancelMu.Unlock()
+	}()
+
+	logger.Debugf("Calling tool %s", tool.Name)
+	text, err := tool.Handler(callCtx, params.Arguments)
+	if err != nil {
+		logger.Debugf("Tool %s failed: %v", tool.Name, err)
+		return callResult{Content: []content{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+	}
+
+	return callResult{Content: []content{{Type: "text", Text: text}}}, nil
+}
+
+// cancel stops an in-flight tool call
+func (s *Server) cancel(id string) {
+	s.cancelMu.Lock()
+	defer s.cancelMu.Unlock()
+	if cancel, ok := s.inFlight[id]; ok {
+		cancel()
+	}
+}
+
+// write sends a response as a single line
+func (s *Server) write(w io.Writer, resp response) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		logger.Errorf("Failed to marshal response: %v", err)
+		return
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		logger.Errorf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	tools := []Tool{
+		{
+			Name:        "echo",
+			Description: "Echo the text argument",
+			InputSchema: json.RawMessage(`{"type":"object"}`),
+			Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+				var in struct {
+					Text string `json:"text"`
+				}
+				if err := json.Unmarshal(args, &in); err != nil {
+					return "", err
+				}
+				if in.Text == "" {
+					return "", errors.New("text is required")
+				}
+				return in.Text, nil
+			},
+		},
+	}
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"echo","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"unknown"}`,
+	}, "\n") + "\n"
+
+	var out bytes.Buffer
+	server := NewServer("mdctl", "test", tools)
+	if err := server.Serve(context.Background(), strings.NewReader(input), &out); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	// Responses may arrive in any order
+	responses := make(map[string]map[string]interface{})
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var resp map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", line, err)
+		}
+		id, _ := json.Marshal(resp["id"])
+		responses[string(id)] = resp
+	}
+	if len(responses) != 5 {
+		t.Fatalf("expected 5 responses, got %d:\n%s", len(responses), out.String())
+	}
+
+	serverInfo := responses["1"]["result"].(map[string]interface{})["serverInfo"].(map[string]interface{})
+	if serverInfo["name"] != "mdctl" {
+		t.Errorf("unexpected server info: %v", serverInfo)
+	}
+
+	listed := responses["2"]["result"].(map[string]interface{})["tools"].([]interface{})
+	if len(listed) != 1 || listed[0].(map[string]interface{})["name"] != "echo" {
+		t.Errorf("unexpected tools: %v", listed)
+	}
+
+	ok := responses["3"]["result"].(map[string]interface{})
+	if ok["isError"] != nil || ok["content"].([]interface{})[0].(map[string]interface{})["text"] != "hi" {
+		t.Errorf("unexpected call result: %v", ok)
+	}
+
+	failed := responses["4"]["result"].(map[string]interface{})
+	if failed["isError"] != true {
+		t.Errorf("expected tool error, got %v", failed)
+	}
+
+	if code := responses["5"]["error"].(map[string]interface{})["code"]; code != float64(codeMethodNotFound) {
+		t.Errorf("expected method not found, got %v", code)
+	}
+}
@@ -0,0 +1,177 @@
+package mddoc
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MetaAsset is a front matter value that references an asset, such as the
+// cover image of a post
+type MetaAsset struct {
+	Key        string // Field as given to MetaAssets, e.g. cover.image
+	Value      string
+	Start, End int  // Byte range of the value in the source, quotes included
+	Quote      byte // Quote of the value, 0 for plain values
+	Line       int
+}
+
+// MetaAssets returns the string values of front matter fields, named by
+// top-level keys or dotted paths into mappings such as cover.image. A list
+// gives an asset per string item. Values spanning lines are left out.
+func (d *Document) MetaAssets(keys []string) []MetaAsset {
+	if len(keys) == 0 {
+		return nil
+	}
+	node, err := d.Meta()
+	if err != nil || node == nil || len(node.Content) == 0 || node.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var assets []MetaAsset
+	seen := make(map[int]bool)
+	for _, key := range keys {
+		value := lookupMeta(node.Content[0], strings.Split(key, "."))
+		if value == nil {
+			continue
+		}
+		items := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			items = value.Content
+		}
+		for _, item := range items {
+			asset, ok := d.locateScalar(item)
+			if !ok || seen[asset.Start] {
+				continue
+			}
+			seen[asset.Start] = true
+			asset.Key = key
+			assets = append(assets, asset)
+		}
+	}
+	sort.Slice(assets, func(i, j int) bool { return assets[i].Start < assets[j].Start })
+	return assets
+}
+
+// ReplaceMetaAssets returns the source with front matter asset values
+// replaced, replace returns the new value or false to keep one. It also
+// returns the number of replaced values.
+func (d *Document) ReplaceMetaAssets(keys []string, replace func(MetaAsset) (string, bool)) ([]byte, int) {
+	var edits []Edit
+	for _, asset := range d.MetaAssets(keys) {
+		if value, ok := replace(asset); ok && value != asset.Value {
+			edits = append(edits, asset.Edit(value))
+		}
+	}
+	return d.Apply(edits), len(edits)
+}
+
+// Edit returns the edit replacing the value of an asset, quoted like the
+// original or, for plain values, when YAML needs it
+func (a MetaAsset) Edit(value string) Edit {
+	text := value
+	switch {
+	case a.Quote == '\'':
+		text = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	case a.Quote == '"' || !plainYAML(value):
+		text = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+	}
+	return Edit{Start: a.Start, End: a.End, Text: text}
+}
+
+// lookupMeta returns the value of a key path in a mapping, nil when missing
+func lookupMeta(mapping *yaml.Node, path []string) *yaml.Node {
+	for _, key := range path {
+		if mapping == nil || mapping.Kind != yaml.MappingNode {
+			return nil
+		}
+		var value *yaml.Node
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			if mapping.Content[i].Value == key {
+				value = mapping.Content[i+1]
+				break
+			}
+		}
+		mapping = value
+	}
+	return mapping
+}
+
+// locateScalar finds a single-line string value of the front matter in the
+// source
+func (d *Document) locateScalar(node *yaml.Node) (MetaAsset, bool) {
+	if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!str" {
+		return MetaAsset{}, false
+	}
+	// Line 1 of the front matter is line 2 of the source
+	if node.Line < 1 || node.Line >= len(d.lineStarts) || d.lineStarts[node.Line] >= d.BodyStart {
+		return MetaAsset{}, false
+	}
+	lineStart := d.lineStarts[node.Line]
+	line := d.line(node.Line)
+
+	// Columns count characters
+	offset := 0
+	for col := 1; col < node.Column && offset < len(line); col++ {
+		_, size := utf8.DecodeRuneInString(line[offset:])
+		offset += size
+	}
+	rest := line[offset:]
+	asset := MetaAsset{Value: node.Value, Start: lineStart + offset, Line: node.Line + 1}
+
+	switch node.Style {
+	case 0:
+		if !strings.HasPrefix(rest, node.Value) {
+			return MetaAsset{}, false
+		}
+		asset.End = asset.Start + len(node.Value)
+	case yaml.DoubleQuotedStyle, yaml.SingleQuotedStyle:
+		end := closingQuote(rest)
+		if end < 0 {
+			return MetaAsset{}, false
+		}
+		asset.Quote = rest[0]
+		asset.End = asset.Start + end + 1
+	default:
+		return MetaAsset{}, false
+	}
+	return asset, true
+}
+
+// closingQuote returns the offset of the quote closing the quoted value s
+// starts with, -1 when it is not on the same line
+func closingQuote(s string) int {
+	if s == "" {
+		return -1
+	}
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case quote == '\'' && s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// plainYAML reports whether a value can be written as a plain YAML scalar
+// and read back as the same string
+func plainYAML(value string) bool {
+	if value == "" || strings.TrimSpace(value) != value || strings.ContainsAny(value, "\n\t") ||
+		strings.Contains(value, ": ") || strings.Contains(value, " #") || strings.HasSuffix(value, ":") ||
+		strings.ContainsAny(value[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return false
+	}
+	var decoded interface{}
+	if err := yaml.Unmarshal([]byte(value), &decoded); err != nil {
+		return false
+	}
+	s, ok := decoded.(string)
+	return ok && s == value
+}
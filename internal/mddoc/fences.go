@@ -0,0 +1,38 @@
+package mddoc
+
+import "strings"
+
+// Fences tracks the fenced code blocks of a document line by line, for code
+// working on lines rather than the AST. A fence is a line of at least three
+// backticks or tildes, indented or not; a block is closed by a fence of the
+// same character at least as long and without info string, an unclosed
+// block runs to the end of the document. The zero value starts outside code.
+type Fences struct {
+	char byte // Fence character of the open block, 0 outside code
+	size int
+}
+
+// Line reports whether the next line of the document is part of a fenced
+// code block, its opening and closing fences included
+func (f *Fences) Line(line string) bool {
+	trimmed := strings.TrimLeft(line, " \t")
+	n := 0
+	if trimmed != "" && (trimmed[0] == '`' || trimmed[0] == '~') {
+		for n < len(trimmed) && trimmed[n] == trimmed[0] {
+			n++
+		}
+	}
+
+	if f.char == 0 {
+		// The info string of a backtick fence cannot contain backticks
+		if n < 3 || (trimmed[0] == '`' && strings.Contains(trimmed[n:], "`")) {
+			return false
+		}
+		f.char, f.size = trimmed[0], n
+		return true
+	}
+	if n >= f.size && trimmed[0] == f.char && strings.TrimSpace(trimmed[n:]) == "" {
+		f.char, f.size = 0, 0
+	}
+	return true
+}
@@ -0,0 +1,18 @@
+package mddoc
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// IsMarkdown reports whether a file is a markdown document by its extension,
+// .md or .markdown in any case
+func IsMarkdown(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// IsMDX reports whether a file is an MDX document, markdown with JSX
+func IsMDX(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".mdx")
+}
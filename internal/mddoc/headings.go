@@ -0,0 +1,59 @@
+package mddoc
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// Heading is an ATX or setext heading of a document
+type Heading struct {
+	Level      int
+	Text       string // Heading content as written, setext lines joined by spaces
+	Line       int
+	Setext     bool
+	Start, End int // Byte range from the opening # or the text to the end of the heading, underline included
+}
+
+// Headings returns the headings with content in document order, headings
+// in code blocks are not part of the AST
+func (d *Document) Headings() []Heading {
+	var headings []Heading
+	ast.Walk(d.Root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		h, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		lines := h.Lines()
+		if lines.Len() == 0 {
+			return ast.WalkSkipChildren, nil
+		}
+
+		first, last := lines.At(0), lines.At(lines.Len()-1)
+		parts := make([]string, lines.Len())
+		for i := 0; i < lines.Len(); i++ {
+			segment := lines.At(i)
+			parts[i] = strings.TrimSpace(string(segment.Value(d.Source)))
+		}
+		heading := Heading{
+			Level: h.Level,
+			Text:  strings.Join(parts, " "),
+			Line:  d.Line(first.Start),
+			Start: first.Start,
+		}
+
+		lineStart, _ := d.lineBounds(first.Start)
+		_, lineEnd := d.lineBounds(last.Start)
+		if marker := strings.IndexByte(string(d.Source[lineStart:first.Start]), '#'); marker >= 0 {
+			heading.Start = lineStart + marker
+			heading.End = lineEnd
+		} else {
+			// The underline is the line after the text
+			heading.Setext = true
+			_, heading.End = d.lineBounds(lineEnd + 1)
+		}
+		headings = append(headings, heading)
+		return ast.WalkSkipChildren, nil
+	})
+	return headings
+}
@@ -0,0 +1,221 @@
+package mddoc
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// Image is an inline image of a document
+type Image struct {
+	Destination string // Destination as written, without angle brackets
+	Title       string
+	Alt         string // Alt text as written, between the brackets
+	Start, End  int    // Byte range of the destination in the source
+	AltStart    int    // Byte range of the alt text in the source
+	AltEnd      int
+	After       int // Offset right after the closing parenthesis
+	Line        int
+}
+
+// Images returns the inline images in document order. Images in code
+// blocks and code spans are not part of the AST, reference style images are
+// left out as their destination is shared with other references.
+func (d *Document) Images() []Image {
+	var images []Image
+	cursor := d.BodyStart
+	ast.Walk(d.Root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		img, ok := n.(*ast.Image)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		if image, ok := d.locateImage(img, cursor); ok {
+			images = append(images, image)
+			cursor = image.After
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	return images
+}
+
+// ReplaceImages returns the source with the destinations of images replaced,
+// replace returns the new destination or false to keep an image. It also
+// returns the number of replaced images.
+func (d *Document) ReplaceImages(replace func(Image) (string, bool)) ([]byte, int) {
+	var edits []Edit
+	for _, img := range d.Images() {
+		if dest, ok := replace(img); ok && dest != img.Destination {
+			edits = append(edits, Edit{Start: img.Start, End: img.End, Text: dest})
+		}
+	}
+	return d.Apply(edits), len(edits)
+}
+
+// locateImage finds the destination of an image in the source, searching no
+// earlier than cursor
+func (d *Document) locateImage(img *ast.Image, cursor int) (Image, bool) {
+	source := d.Source
+	var open, altStart int
+	if stop := lastTextStop(img); stop >= 0 {
+		// The alt text ends right before the closing bracket
+		from := max(stop, cursor)
+		if open = bytes.IndexByte(source[from:], ']'); open < 0 {
+			return Image{}, false
+		}
+		open += from + 1
+		// The alt text starts after the "![" before its first text
+		first := firstTextStart(img)
+		if altStart = bytes.LastIndex(source[:first], []byte("![")); altStart < 0 {
+			return Image{}, false
+		}
+		altStart += 2
+	} else {
+		// Without alt text the image starts after its previous sibling
+		from := cursor
+		if prev := img.PreviousSibling(); prev != nil {
+			from = max(from, lastTextStop(prev))
+		} else if block := enclosingBlock(img); block != nil && block.Lines().Len() > 0 {
+			from = max(from, block.Lines().At(0).Start)
+		}
+		if open = bytes.Index(source[from:], []byte("![]")); open < 0 {
+			return Image{}, false
+		}
+		open += from + 3
+		altStart = open - 1
+	}
+	start, end, after, ok := scanDestination(source, open, img.Destination, img.Title)
+	if !ok {
+		return Image{}, false
+	}
+
+	return Image{
+		Destination: string(img.Destination),
+		Title:       string(img.Title),
+		Alt:         string(source[altStart : open-1]),
+		Start:       start,
+		End:         end,
+		AltStart:    altStart,
+		AltEnd:      open - 1,
+		After:       after,
+		Line:        d.Line(start),
+	}, true
+}
+
+// scanDestination reads the "(destination title)" of an inline link or image
+// at open and checks it against the parsed destination. It returns the byte
+// range of the destination, without angle brackets, and the offset right
+// after the closing parenthesis.
+func scanDestination(source []byte, open int, dest, title []byte) (int, int, int, bool) {
+	if open >= len(source) || source[open] != '(' {
+		return 0, 0, 0, false
+	}
+
+	i := skipSpaces(source, open+1)
+	start, end := i, i
+	if i < len(source) && source[i] == '<' {
+		start++
+		if end = scanTo(source, start, '>'); end >= len(source) {
+			return 0, 0, 0, false
+		}
+		i = end + 1
+	} else {
+		opened := 0
+		for ; end < len(source); end++ {
+			c := source[end]
+			if c == '\\' && end+1 < len(source) && util.IsPunct(source[end+1]) {
+				end++
+			} else if c == '(' {
+				opened++
+			} else if c == ')' {
+				if opened--; opened < 0 {
+					break
+				}
+			} else if util.IsSpace(c) {
+				break
+			}
+		}
+		i = end
+	}
+	if !bytes.Equal(source[start:end], dest) {
+		return 0, 0, 0, false
+	}
+
+	// Skip the title to find the closing parenthesis
+	i = skipSpaces(source, i)
+	if len(title) > 0 && i < len(source) {
+		closer := source[i]
+		if closer == '(' {
+			closer = ')'
+		}
+		if i = scanTo(source, i+1, closer); i >= len(source) {
+			return 0, 0, 0, false
+		}
+		i = skipSpaces(source, i+1)
+	}
+	if i >= len(source) || source[i] != ')' {
+		return 0, 0, 0, false
+	}
+	return start, end, i + 1, true
+}
+
+// Unescape removes the backslash escapes of a destination
+func Unescape(dest string) string {
+	return string(util.UnescapePunctuations([]byte(dest)))
+}
+
+// scanTo returns the offset of the first unescaped c from i on
+func scanTo(source []byte, i int, c byte) int {
+	for ; i < len(source) && source[i] != c; i++ {
+		if source[i] == '\\' && i+1 < len(source) && util.IsPunct(source[i+1]) {
+			i++
+		}
+	}
+	return i
+}
+
+// skipSpaces returns the offset of the first non-space byte from i on
+func skipSpaces(source []byte, i int) int {
+	for i < len(source) && util.IsSpace(source[i]) {
+		i++
+	}
+	return i
+}
+
+// lastTextStop returns the end of the last text within a node, -1 if the node
+// contains no text
+func lastTextStop(n ast.Node) int {
+	if t, ok := n.(*ast.Text); ok {
+		return t.Segment.Stop
+	}
+	for c := n.LastChild(); c != nil; c = c.PreviousSibling() {
+		if stop := lastTextStop(c); stop >= 0 {
+			return stop
+		}
+	}
+	return -1
+}
+
+// firstTextStart returns the start of the first text within a node, -1 if
+// the node contains no text
+func firstTextStart(n ast.Node) int {
+	if t, ok := n.(*ast.Text); ok {
+		return t.Segment.Start
+	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if start := firstTextStart(c); start >= 0 {
+			return start
+		}
+	}
+	return -1
+}
+
+// enclosingBlock returns the block an inline node belongs to
+func enclosingBlock(n ast.Node) ast.Node {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if p.Type() == ast.TypeBlock {
+			return p
+		}
+	}
+	return nil
+}
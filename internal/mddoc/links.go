@@ -0,0 +1,196 @@
+package mddoc
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// LinkKind is the syntax a link destination is written in
+type LinkKind int
+
+const (
+	InlineLink    LinkKind = iota // [text](dest)
+	InlineImage                   // ![alt](dest)
+	Definition                    // [label]: dest
+	HTMLAttribute                 // src="dest" or href="dest" of raw HTML
+)
+
+// Link is a link destination of a document
+type Link struct {
+	Destination string // As written, without angle brackets
+	Kind        LinkKind
+	Angled      bool // The destination is written in angle brackets
+	Start, End  int  // Byte range of the destination in the source
+	Line        int
+}
+
+var (
+	// definitionRegex matches a line starting with a link reference
+	// definition, group 1 is the label, group 2 or 3 the destination
+	definitionRegex = regexp.MustCompile(`^(?:[ \t]*> ?)*[ ]{0,3}\[((?:[^\[\]\\]|\\.)+)\]:[ \t]*(?:<([^>\n]*)>|(\S+))`)
+	// attributeRegex matches the src and href attributes of HTML, group 1 or
+	// 2 is the value
+	attributeRegex = regexp.MustCompile(`\s(?:src|href)\s*=\s*(?:"([^"\n]+)"|'([^'\n]+)')`)
+)
+
+// Links returns the link destinations in document order: of inline links
+// and images, link reference definitions and the src and href attributes of
+// HTML. Links in code blocks and code spans are not part of the AST,
+// reference links are left out as their destination is the one of their
+// definition. Definitions spanning lines are left out.
+func (d *Document) Links() []Link {
+	var links []Link
+	cursor := d.BodyStart
+	ast.Walk(d.Root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch n := n.(type) {
+		case *ast.Image:
+			if !entering {
+				break
+			}
+			if img, ok := d.locateImage(n, cursor); ok {
+				links = append(links, d.newLink(InlineImage, img.Start, img.End))
+				cursor = img.After
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.Link:
+			// Located when leaving, past the images of its text
+			if entering {
+				break
+			}
+			if start, end, after, ok := d.locateLink(n, cursor); ok {
+				links = append(links, d.newLink(InlineLink, start, end))
+				cursor = after
+			}
+		case *ast.HTMLBlock:
+			if !entering {
+				break
+			}
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				links = d.appendAttributes(links, lines.At(i))
+			}
+			if n.HasClosure() {
+				links = d.appendAttributes(links, n.ClosureLine)
+			}
+		case *ast.RawHTML:
+			if !entering {
+				break
+			}
+			for i := 0; i < n.Segments.Len(); i++ {
+				links = d.appendAttributes(links, n.Segments.At(i))
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	links = append(links, d.definitions()...)
+	sort.SliceStable(links, func(i, j int) bool { return links[i].Start < links[j].Start })
+	return links
+}
+
+// ReplaceLinks returns the source with link destinations replaced, replace
+// returns the new destination or false to keep a link. It also returns the
+// number of replaced links.
+func (d *Document) ReplaceLinks(replace func(Link) (string, bool)) ([]byte, int) {
+	var edits []Edit
+	for _, link := range d.Links() {
+		if dest, ok := replace(link); ok && dest != link.Destination {
+			edits = append(edits, Edit{Start: link.Start, End: link.End, Text: dest})
+		}
+	}
+	return d.Apply(edits), len(edits)
+}
+
+// newLink returns the link whose destination is the byte range from start to end
+func (d *Document) newLink(kind LinkKind, start, end int) Link {
+	return Link{
+		Destination: string(d.Source[start:end]),
+		Kind:        kind,
+		Angled:      kind != HTMLAttribute && start > 0 && d.Source[start-1] == '<',
+		Start:       start,
+		End:         end,
+		Line:        d.Line(start),
+	}
+}
+
+// locateLink finds the destination of an inline link in the source,
+// searching no earlier than cursor. It returns the byte range of the
+// destination and the offset right after the closing parenthesis.
+func (d *Document) locateLink(link *ast.Link, cursor int) (int, int, int, bool) {
+	source := d.Source
+	from := max(cursor, lastTextStop(link))
+	if !link.HasChildren() {
+		// Without text the link starts after its previous sibling
+		if prev := link.PreviousSibling(); prev != nil {
+			from = max(from, lastTextStop(prev))
+		} else if block := enclosingBlock(link); block != nil && block.Lines().Len() > 0 {
+			from = max(from, block.Lines().At(0).Start)
+		}
+		i := bytes.Index(source[from:], []byte("[]"))
+		if i < 0 {
+			return 0, 0, 0, false
+		}
+		from += i + 1
+	}
+	closing := bytes.IndexByte(source[from:], ']')
+	if closing < 0 {
+		return 0, 0, 0, false
+	}
+	return scanDestination(source, from+closing+1, link.Destination, link.Title)
+}
+
+// appendAttributes appends the src and href attributes of an HTML segment
+func (d *Document) appendAttributes(links []Link, segment text.Segment) []Link {
+	for _, m := range attributeRegex.FindAllSubmatchIndex(segment.Value(d.Source), -1) {
+		start, end := m[2], m[3]
+		if start < 0 {
+			start, end = m[4], m[5]
+		}
+		links = append(links, d.newLink(HTMLAttribute, segment.Start+start, segment.Start+end))
+	}
+	return links
+}
+
+// definitions returns the destinations of link reference definitions. The
+// parser drops definitions from the AST, they are the lines that belong to
+// no block and start with the definition of a label the parser collected.
+func (d *Document) definitions() []Link {
+	if d.context == nil || len(d.context.References()) == 0 {
+		return nil
+	}
+	covered := make(map[int]bool)
+	ast.Walk(d.Root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.Type() == ast.TypeBlock {
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				covered[d.Line(lines.At(i).Start)] = true
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	var links []Link
+	for i := d.BodyLine - 1; i < len(d.lineStarts); i++ {
+		if covered[i+1] {
+			continue
+		}
+		line := d.line(i)
+		m := definitionRegex.FindStringSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+		if _, ok := d.context.Reference(util.ToLinkReference([]byte(line[m[2]:m[3]]))); !ok {
+			continue
+		}
+		start, end := m[4], m[5]
+		if start < 0 {
+			start, end = m[6], m[7]
+		}
+		links = append(links, d.newLink(Definition, d.lineStarts[i]+start, d.lineStarts[i]+end))
+	}
+	return links
+}
@@ -0,0 +1,169 @@
+// Package mddoc is the markdown document model shared by the commands: a
+// document is split into its front matter and body, the body is parsed into
+// a goldmark AST whose segments are offsets into the original source, and
+// changes are applied as edits of byte ranges, so text that is not edited
+// keeps its formatting.
+package mddoc
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a parsed markdown document
+type Document struct {
+	Source      []byte   // Complete document
+	FrontMatter []byte   // YAML between the --- delimiters, nil without front matter
+	Unclosed    bool     // The document opens front matter that is never closed
+	BodyStart   int      // Offset of the body in Source
+	BodyLine    int      // Line the body starts at, starting at 1
+	Root        ast.Node // AST of the body, segments are offsets into Source
+
+	lineStarts []int
+	context    parser.Context // Link reference definitions of the body
+}
+
+// Parse parses a markdown document. Front matter starts with a --- line at
+// the beginning of the document and ends with a --- or ... line.
+func Parse(source []byte) *Document {
+	d := Split(source)
+
+	// The front matter is blanked instead of cut off, so that offsets in
+	// the AST are offsets in the source
+	masked := source
+	if d.BodyStart > 0 {
+		masked = make([]byte, len(source))
+		copy(masked, source)
+		for i := 0; i < d.BodyStart; i++ {
+			if masked[i] != '\n' {
+				masked[i] = ' '
+			}
+		}
+	}
+	d.context = parser.NewContext()
+	d.Root = goldmark.DefaultParser().Parse(text.NewReader(masked), parser.WithContext(d.context))
+	return d
+}
+
+// Split indexes the lines of a document and locates its front matter
+// without parsing the body, Root is nil
+func Split(source []byte) *Document {
+	d := &Document{Source: source, BodyLine: 1}
+	d.lineStarts = []int{0}
+	for i, c := range source {
+		if c == '\n' {
+			d.lineStarts = append(d.lineStarts, i+1)
+		}
+	}
+	d.splitFrontMatter()
+	return d
+}
+
+// splitFrontMatter locates the front matter
+func (d *Document) splitFrontMatter() {
+	if len(d.lineStarts) < 2 || d.line(0) != "---" {
+		return
+	}
+	for i := 1; i < len(d.lineStarts); i++ {
+		if line := d.line(i); line == "---" || line == "..." {
+			d.FrontMatter = d.Source[d.lineStarts[1]:d.lineStarts[i]]
+			d.BodyStart = len(d.Source)
+			if i+1 < len(d.lineStarts) {
+				d.BodyStart = d.lineStarts[i+1]
+			}
+			d.BodyLine = i + 2
+			return
+		}
+	}
+	d.Unclosed = true
+}
+
+// line returns a line without its line ending and trailing whitespace,
+// starting at 0
+func (d *Document) line(i int) string {
+	end := len(d.Source)
+	if i+1 < len(d.lineStarts) {
+		end = d.lineStarts[i+1]
+	}
+	return strings.TrimRight(string(d.Source[d.lineStarts[i]:end]), " \t\r\n")
+}
+
+// Body returns the document without its front matter
+func (d *Document) Body() []byte {
+	return d.Source[d.BodyStart:]
+}
+
+// Meta parses the front matter, it returns nil without front matter
+func (d *Document) Meta() (*yaml.Node, error) {
+	if d.FrontMatter == nil {
+		return nil, nil
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(d.FrontMatter, &node); err != nil {
+		return nil, err
+	}
+	if node.Kind == 0 {
+		return nil, nil
+	}
+	return &node, nil
+}
+
+// Line returns the line of a source offset, starting at 1
+func (d *Document) Line(offset int) int {
+	return sort.Search(len(d.lineStarts), func(i int) bool { return d.lineStarts[i] > offset })
+}
+
+// lineBounds returns the start and end of the line containing offset, the
+// end excludes the line ending
+func (d *Document) lineBounds(offset int) (int, int) {
+	i := d.Line(offset) - 1
+	end := len(d.Source)
+	if i+1 < len(d.lineStarts) {
+		end = d.lineStarts[i+1] - 1
+	}
+	if end > d.lineStarts[i] && d.Source[end-1] == '\r' {
+		end--
+	}
+	return d.lineStarts[i], end
+}
+
+// Edit replaces the bytes from Start to End of the source with Text
+type Edit struct {
+	Start, End int
+	Text       string
+}
+
+// Apply returns the source with edits applied. Edits must not overlap, they
+// are applied in order of their start.
+func (d *Document) Apply(edits []Edit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].Start < edits[j].Start })
+	var out bytes.Buffer
+	last := 0
+	for _, e := range edits {
+		out.Write(d.Source[last:e.Start])
+		out.WriteString(e.Text)
+		last = e.End
+	}
+	out.Write(d.Source[last:])
+	return out.Bytes()
+}
+
+// SplitFrontMatter separates the front matter YAML from the body of a
+// document, the front matter is empty without front matter
+func SplitFrontMatter(content string) (string, string) {
+	d := Split([]byte(content))
+	return string(d.FrontMatter), string(d.Body())
+}
+
+// StripFrontMatter returns a document without its front matter
+func StripFrontMatter(content string) string {
+	_, body := SplitFrontMatter(content)
+	return body
+}
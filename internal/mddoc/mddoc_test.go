@@ -0,0 +1,245 @@
+package mddoc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFrontMatter(t *testing.T) {
+	d := Parse([]byte("---\ntitle: Guide\n---\n# Guide\n"))
+	if string(d.FrontMatter) != "title: Guide\n" || string(d.Body()) != "# Guide\n" || d.BodyLine != 4 {
+		t.Errorf("unexpected split: %q %q line %d", d.FrontMatter, d.Body(), d.BodyLine)
+	}
+	meta, err := d.Meta()
+	if err != nil || meta == nil {
+		t.Fatalf("Meta failed: %v", err)
+	}
+
+	if d := Parse([]byte("---\ntitle: x\n")); !d.Unclosed || d.FrontMatter != nil {
+		t.Error("expected unclosed front matter")
+	}
+	if fm, body := SplitFrontMatter("# No front matter\n\n---\n"); fm != "" || body != "# No front matter\n\n---\n" {
+		t.Errorf("unexpected split without front matter: %q %q", fm, body)
+	}
+}
+
+func TestImages(t *testing.T) {
+	source := strings.Join([]string{
+		"---",
+		"image: ![fm](fm.png)",
+		"---",
+		`![a](img/a.png "Title") and ![](b.png)`,
+		"",
+		"```",
+		"![code](code.png)",
+		"```",
+		"",
+		"Inline `![span](span.png)` code, ![c](<my image.png>) and ![d\\]](d\\(1\\).png).",
+		"",
+		"[![badge](badge.svg)](https://example.com) ![ref][r] ![e](e.png){width=50%}",
+		"",
+		"[r]: ref.png",
+	}, "\n")
+
+	d := Parse([]byte(source))
+	var dests, alts []string
+	for _, img := range d.Images() {
+		if got := source[img.Start:img.End]; got != img.Destination {
+			t.Errorf("range %d-%d is %q, want %q", img.Start, img.End, got, img.Destination)
+		}
+		if got := source[img.AltStart:img.AltEnd]; got != img.Alt {
+			t.Errorf("alt range %d-%d is %q, want %q", img.AltStart, img.AltEnd, got, img.Alt)
+		}
+		dests = append(dests, img.Destination)
+		alts = append(alts, img.Alt)
+	}
+	want := []string{"img/a.png", "b.png", "my image.png", `d\(1\).png`, "badge.svg", "e.png"}
+	if strings.Join(dests, "|") != strings.Join(want, "|") {
+		t.Errorf("got images %q, want %q", dests, want)
+	}
+	wantAlts := []string{"a", "", "c", `d\]`, "badge", "e"}
+	if strings.Join(alts, "|") != strings.Join(wantAlts, "|") {
+		t.Errorf("got alt texts %q, want %q", alts, wantAlts)
+	}
+	if alt := Parse([]byte("![*bold* `x`](x.png)")).Images()[0].Alt; alt != "*bold* `x`" {
+		t.Errorf("got alt text %q with inline markup", alt)
+	}
+
+	images := d.Images()
+	if images[0].Title != "Title" || images[0].Line != 4 || source[images[0].After-1] != ')' {
+		t.Errorf("unexpected first image: %+v", images[0])
+	}
+	if last := images[len(images)-1]; !strings.HasPrefix(source[last.After:], "{width=50%}") {
+		t.Errorf("expected the attributes after the last image, got %q", source[last.After:])
+	}
+}
+
+func TestReplaceImages(t *testing.T) {
+	d := Parse([]byte("![a](a.png \"Title\")\n\n    ![a](a.png)\n\n![b](<b.png>)\n"))
+	out, n := d.ReplaceImages(func(img Image) (string, bool) {
+		return "https://cdn.example.com/" + img.Destination, true
+	})
+	want := "![a](https://cdn.example.com/a.png \"Title\")\n\n    ![a](a.png)\n\n![b](<https://cdn.example.com/b.png>)\n"
+	if n != 2 || string(out) != want {
+		t.Errorf("got %d replacements:\n%s\nwant:\n%s", n, out, want)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	source := strings.Join([]string{
+		"---",
+		"link: [fm](fm.md)",
+		"---",
+		`See [a](a.md "Title"), [](empty.md) and [b \] c](<my b.md>).`,
+		"",
+		"````",
+		"```",
+		"[fenced](fenced.md)",
+		"```",
+		"````",
+		"",
+		"    [indented](indented.md)",
+		"",
+		"Inline `[span](span.md)` and [![badge](badge.svg)](ci.md) [ref][r] [x](x.md)",
+		"",
+		`<img src="html.png" width="100"> and <a href='raw.md'>raw</a>`,
+		"",
+		"[r]: ./ref.md",
+		"> [q]: <quoted ref.md>",
+		"[unused]: text that is no definition",
+	}, "\n")
+
+	d := Parse([]byte(source))
+	var got []string
+	for _, link := range d.Links() {
+		if source[link.Start:link.End] != link.Destination {
+			t.Errorf("range %d-%d is %q, want %q", link.Start, link.End, source[link.Start:link.End], link.Destination)
+		}
+		got = append(got, fmt.Sprintf("%d:%d:%s", link.Kind, link.Line, link.Destination))
+	}
+	want := []string{
+		"0:4:a.md", "0:4:empty.md", "0:4:my b.md",
+		"1:14:badge.svg", "0:14:ci.md", "0:14:x.md",
+		"3:16:html.png", "3:16:raw.md",
+		"2:18:./ref.md", "2:19:quoted ref.md",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got links\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	out, n := d.ReplaceLinks(func(link Link) (string, bool) {
+		return "new/" + link.Destination, link.Kind == Definition
+	})
+	if n != 2 || !strings.Contains(string(out), "[r]: new/./ref.md\n> [q]: <new/quoted ref.md>\n") {
+		t.Errorf("ReplaceLinks replaced %d:\n%s", n, out)
+	}
+}
+
+func TestHeadings(t *testing.T) {
+	source := "# One #\n\nTwo\n===\n\n```\n# code\n```\n\n> ## Quoted\n\nThree\nlines\n---\n"
+	d := Parse([]byte(source))
+	headings := d.Headings()
+	if len(headings) != 4 {
+		t.Fatalf("expected 4 headings, got %+v", headings)
+	}
+
+	want := []struct {
+		level int
+		text  string
+		raw   string
+	}{
+		{1, "One", "# One #"},
+		{1, "Two", "Two\n==="},
+		{2, "Quoted", "## Quoted"},
+		{2, "Three lines", "Three\nlines\n---"},
+	}
+	for i, w := range want {
+		h := headings[i]
+		if h.Level != w.level || h.Text != w.text || source[h.Start:h.End] != w.raw {
+			t.Errorf("heading %d: got level %d %q %q, want %d %q %q", i, h.Level, h.Text, source[h.Start:h.End], w.level, w.text, w.raw)
+		}
+	}
+}
+
+func TestSetMeta(t *testing.T) {
+	d := Split([]byte("---\ntitle: Guide # keep\ntags: [a]\n---\n# Guide\n"))
+	out, err := d.SetMeta("description", "A short: summary")
+	if err != nil {
+		t.Fatalf("SetMeta failed: %v", err)
+	}
+	want := "---\ntitle: Guide # keep\ntags: [a]\ndescription: 'A short: summary'\n---\n# Guide\n"
+	if string(out) != want {
+		t.Errorf("unexpected document:\n%s", out)
+	}
+
+	out, _ = Split(out).SetMeta("title", "Other")
+	if !strings.HasPrefix(string(out), "---\ntitle: Other\ntags: [a]\n") {
+		t.Errorf("expected the title to be replaced in place:\n%s", out)
+	}
+
+	out, _ = Split([]byte("# Plain\n")).SetMeta("description", "x")
+	if string(out) != "---\ndescription: x\n---\n# Plain\n" {
+		t.Errorf("expected front matter to be added:\n%s", out)
+	}
+	if _, err := Split([]byte("---\ntitle: x\n")).SetMeta("a", "b"); err == nil {
+		t.Error("expected an error for unclosed front matter")
+	}
+}
+
+func TestMetaAssets(t *testing.T) {
+	source := "---\ntitle: Post\ncover:\n  image: \"img/cover.png\"\n  alt: Cover\nthumbnail: 'thumb''s.png'\nimages:\n  - a.png\n  - https://example.com/b.png\ndraft: true\n---\n![x](c.png)\n"
+	d := Split([]byte(source))
+	assets := d.MetaAssets([]string{"cover.image", "thumbnail", "images", "draft", "missing"})
+	var values []string
+	for _, a := range assets {
+		values = append(values, a.Key+"="+a.Value+"="+source[a.Start:a.End])
+	}
+	want := []string{
+		`cover.image=img/cover.png="img/cover.png"`,
+		`thumbnail=thumb's.png='thumb''s.png'`,
+		"images=a.png=a.png",
+		"images=https://example.com/b.png=https://example.com/b.png",
+	}
+	if strings.Join(values, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got\n%s\nwant\n%s", strings.Join(values, "\n"), strings.Join(want, "\n"))
+	}
+
+	out, n := d.ReplaceMetaAssets([]string{"cover.image", "thumbnail", "images"}, func(a MetaAsset) (string, bool) {
+		if strings.HasPrefix(a.Value, "http") {
+			return "", false
+		}
+		return "https://cdn.example.com/" + a.Value + "?v=1 #x", true
+	})
+	wantOut := "---\ntitle: Post\ncover:\n  image: \"https://cdn.example.com/img/cover.png?v=1 #x\"\n  alt: Cover\nthumbnail: 'https://cdn.example.com/thumb''s.png?v=1 #x'\nimages:\n  - \"https://cdn.example.com/a.png?v=1 #x\"\n  - https://example.com/b.png\ndraft: true\n---\n![x](c.png)\n"
+	if n != 3 || string(out) != wantOut {
+		t.Errorf("ReplaceMetaAssets replaced %d:\n%s", n, out)
+	}
+	if edit := (MetaAsset{Start: 1, End: 2}).Edit("https://cdn.example.com/a.png"); edit.Text != "https://cdn.example.com/a.png" {
+		t.Errorf("expected a plain value, got %s", edit.Text)
+	}
+}
+
+func TestFences(t *testing.T) {
+	lines := []string{
+		"text",
+		"````md",
+		"```",
+		"inner",
+		"```",
+		"````",
+		"``inline`` text",
+		"  ~~~",
+		"~~~ not closing",
+		"~~~~",
+		"```js `x`",
+		"after",
+	}
+	want := []bool{false, true, true, true, true, true, false, true, true, true, false, false}
+	var fences Fences
+	for i, line := range lines {
+		if got := fences.Line(line); got != want[i] {
+			t.Errorf("line %d %q: got %v, want %v", i+1, line, got, want[i])
+		}
+	}
+}
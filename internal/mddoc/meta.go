@@ -0,0 +1,66 @@
+package mddoc
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetMeta returns the document with a top-level front matter field set to
+// value, adding front matter to documents without. The other fields keep
+// their order and comments.
+func (d *Document) SetMeta(key string, value interface{}) ([]byte, error) {
+	if d.Unclosed {
+		return nil, fmt.Errorf("front matter is not closed")
+	}
+	node, err := d.Meta()
+	if err != nil {
+		return nil, fmt.Errorf("invalid front matter: %v", err)
+	}
+	if node == nil {
+		node = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	mapping := node.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("front matter is not a mapping")
+	}
+
+	var valueNode yaml.Node
+	if err := valueNode.Encode(value); err != nil {
+		return nil, err
+	}
+	replaced := false
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = &valueNode
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, &valueNode)
+	}
+
+	var meta bytes.Buffer
+	enc := yaml.NewEncoder(&meta)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	enc.Close()
+
+	var out bytes.Buffer
+	if d.FrontMatter == nil {
+		out.WriteString("---\n")
+		out.Write(meta.Bytes())
+		out.WriteString("---\n")
+		out.Write(d.Source)
+		return out.Bytes(), nil
+	}
+	start := d.lineStarts[1]
+	out.Write(d.Source[:start])
+	out.Write(meta.Bytes())
+	out.Write(d.Source[start+len(d.FrontMatter):])
+	return out.Bytes(), nil
+}
@@ -0,0 +1,151 @@
+// Package metrics records usage metrics of mdctl runs in a local file,
+// nothing is ever sent anywhere
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// FileName is the metrics file inside the mdctl configuration directory
+const FileName = "metrics.jsonl"
+
+// Run is the record of one command run
+type Run struct {
+	Command       string    `json:"command"`
+	Version       string    `json:"version,omitempty"`
+	Start         time.Time `json:"start"`
+	DurationMS    int64     `json:"duration_ms"`
+	Files         int64     `json:"files,omitempty"`          // Files processed
+	Tokens        int64     `json:"tokens,omitempty"`         // AI tokens used
+	BytesUploaded int64     `json:"bytes_uploaded,omitempty"` // Bytes sent to cloud storage
+	Failed        bool      `json:"failed,omitempty"`
+}
+
+// Summary adds up the runs of a command
+type Summary struct {
+	Command       string `json:"command"`
+	Runs          int    `json:"runs"`
+	Failed        int    `json:"failed"`
+	DurationMS    int64  `json:"duration_ms"`
+	Files         int64  `json:"files"`
+	Tokens        int64  `json:"tokens"`
+	BytesUploaded int64  `json:"bytes_uploaded"`
+}
+
+// Counters of the current process
+var files, tokens, bytesUploaded atomic.Int64
+
+// AddFiles counts processed files
+func AddFiles(n int) {
+	files.Add(int64(n))
+}
+
+// AddTokens counts used AI tokens
+func AddTokens(n int) {
+	tokens.Add(int64(n))
+}
+
+// AddBytesUploaded counts bytes uploaded to cloud storage
+func AddBytesUploaded(n int64) {
+	bytesUploaded.Add(n)
+}
+
+// Finish returns the record of a run started at start with the counters of
+// the process
+func Finish(command, version string, start time.Time, failed bool) Run {
+	return Run{
+		Command:       command,
+		Version:       version,
+		Start:         start,
+		DurationMS:    time.Since(start).Milliseconds(),
+		Files:         files.Load(),
+		Tokens:        tokens.Load(),
+		BytesUploaded: bytesUploaded.Load(),
+		Failed:        failed,
+	}
+}
+
+// Append adds a run to the metrics file, one JSON document per line
+func Append(path string, run Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create metrics directory: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open metrics file: %v", err)
+	}
+	// A single write keeps lines of concurrent runs apart
+	_, err = file.Write(append(data, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write metrics file: %v", err)
+	}
+	return nil
+}
+
+// Load reads the runs of the metrics file, oldest first. A missing file has
+// no runs, lines that cannot be parsed are skipped.
+func Load(path string) ([]Run, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metrics file: %v", err)
+	}
+	defer file.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var run Run
+		if json.Unmarshal(scanner.Bytes(), &run) == nil && run.Command != "" {
+			runs = append(runs, run)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read metrics file: %v", err)
+	}
+	return runs, nil
+}
+
+// Summarize adds up runs by command, sorted by command
+func Summarize(runs []Run) []Summary {
+	byCommand := make(map[string]*Summary)
+	for _, run := range runs {
+		s, ok := byCommand[run.Command]
+		if !ok {
+			s = &Summary{Command: run.Command}
+			byCommand[run.Command] = s
+		}
+		s.Runs++
+		if run.Failed {
+			s.Failed++
+		}
+		s.DurationMS += run.DurationMS
+		s.Files += run.Files
+		s.Tokens += run.Tokens
+		s.BytesUploaded += run.BytesUploaded
+	}
+
+	summaries := make([]Summary, 0, len(byCommand))
+	for _, s := range byCommand {
+		summaries = append(summaries, *s)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Command < summaries[j].Command })
+	return summaries
+}
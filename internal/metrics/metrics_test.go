@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAppendLoadSummarize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats", FileName)
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	runs := []Run{
+		{Command: "translate", Start: start, DurationMS: 1500, Files: 3, Tokens: 1200},
+		{Command: "upload", Start: start.Add(time.Hour), DurationMS: 800, Files: 2, BytesUploaded: 4096},
+		{Command: "translate", Start: start.Add(2 * time.Hour), DurationMS: 500, Files: 1, Tokens: 300, Failed: true},
+	}
+	for _, run := range runs {
+		if err := Append(path, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Broken lines, e.g. of an interrupted write, are skipped
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("{\"command\": \"tr\n")
+	file.Close()
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, runs) {
+		t.Fatalf("Load = %+v, want %+v", loaded, runs)
+	}
+
+	want := []Summary{
+		{Command: "translate", Runs: 2, Failed: 1, DurationMS: 2000, Files: 4, Tokens: 1500},
+		{Command: "upload", Runs: 1, DurationMS: 800, Files: 2, BytesUploaded: 4096},
+	}
+	if got := Summarize(loaded); !reflect.DeepEqual(got, want) {
+		t.Errorf("Summarize = %+v, want %+v", got, want)
+	}
+
+	if runs, err := Load(filepath.Join(t.TempDir(), "missing.jsonl")); err != nil || runs != nil {
+		t.Errorf("Load of a missing file = %v, %v", runs, err)
+	}
+}
+
+func TestFinish(t *testing.T) {
+	AddFiles(2)
+	AddTokens(100)
+	AddBytesUploaded(512)
+
+	run := Finish("upload", "v1.0.0", time.Now().Add(-time.Second), false)
+	if run.Files != 2 || run.Tokens != 100 || run.BytesUploaded != 512 || run.DurationMS < 1000 {
+		t.Errorf("Finish = %+v", run)
+	}
+}
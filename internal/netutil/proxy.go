@@ -0,0 +1,64 @@
+// Package netutil holds the HTTP transport shared by all clients of mdctl
+package netutil
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+var (
+	// proxyFunc picks the proxy of a request, from the environment unless
+	// SetProxy configured one
+	proxyFunc atomic.Value // func(*url.URL) (*url.URL, error)
+
+	transportOnce sync.Once
+	transport     *http.Transport
+)
+
+func init() {
+	proxyFunc.Store(httpproxy.FromEnvironment().ProxyFunc())
+}
+
+// SetProxy sends all requests through a proxy URL such as
+// http://proxy.example.com:3128, except those to hosts in NO_PROXY. An
+// empty URL uses HTTP_PROXY, HTTPS_PROXY and NO_PROXY from the environment.
+func SetProxy(proxy string) error {
+	env := httpproxy.FromEnvironment()
+	if proxy != "" {
+		u, err := url.Parse(proxy)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid proxy URL: %s", proxy)
+		}
+		env.HTTPProxy, env.HTTPSProxy = proxy, proxy
+	}
+	proxyFunc.Store(env.ProxyFunc())
+	return nil
+}
+
+// Proxy returns the proxy of a request, for use as http.Transport.Proxy
+func Proxy(req *http.Request) (*url.URL, error) {
+	return proxyFunc.Load().(func(*url.URL) (*url.URL, error))(req.URL)
+}
+
+// Transport returns the shared transport, the default transport of net/http
+// with the configured proxy. Clients that need TLS settings of their own use
+// a clone of it.
+func Transport() *http.Transport {
+	transportOnce.Do(func() {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = Proxy
+	})
+	return transport
+}
+
+// NewClient returns an HTTP client on the shared transport, a timeout of 0
+// means none
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: Transport()}
+}
@@ -0,0 +1,60 @@
+package netutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetProxy(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("NO_PROXY", "internal.example.com")
+	defer SetProxy("")
+
+	if err := SetProxy("http://proxy.example.com:3128"); err != nil {
+		t.Fatalf("SetProxy failed: %v", err)
+	}
+	tests := []struct {
+		url, want string
+	}{
+		{"https://api.openai.com/v1/chat/completions", "http://proxy.example.com:3128"},
+		{"http://example.com/sitemap.xml", "http://proxy.example.com:3128"},
+		{"https://internal.example.com/wiki", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		proxy, err := Proxy(req)
+		if err != nil {
+			t.Fatalf("Proxy failed: %v", err)
+		}
+		got := ""
+		if proxy != nil {
+			got = proxy.String()
+		}
+		if got != tt.want {
+			t.Errorf("Proxy(%s) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+
+	if err := SetProxy("not a url"); err == nil {
+		t.Error("expected an error for an invalid proxy URL")
+	}
+}
+
+func TestProxyFromEnvironment(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:8080")
+	t.Setenv("NO_PROXY", "")
+	if err := SetProxy(""); err != nil {
+		t.Fatal(err)
+	}
+	defer SetProxy("")
+
+	proxy, err := Proxy(httptest.NewRequest(http.MethodGet, "https://example.com/", nil))
+	if err != nil || proxy == nil || proxy.Host != "env-proxy:8080" {
+		t.Errorf("expected the proxy from HTTPS_PROXY, got %v (%v)", proxy, err)
+	}
+	if Transport().Proxy == nil {
+		t.Error("the shared transport does not use the proxy")
+	}
+}
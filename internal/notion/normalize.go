@@ -0,0 +1,472 @@
+package notion
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"github.com/samzong/mdctl/internal/processor"
+	"gopkg.in/yaml.v3"
+)
+
+// logger reports normalization progress
+var logger = logging.New("NOTION")
+
+var (
+	// hashRegex matches the page ID Notion appends to exported names
+	hashRegex = regexp.MustCompile(`\s+([0-9a-f]{32})$`)
+	// notionURLRegex matches links to notion.so pages, group 1 is the page ID
+	notionURLRegex = regexp.MustCompile(`^https?://(?:www\.)?notion\.so/.*?([0-9a-f]{32})(?:[?#].*)?$`)
+	// propertyRegex matches a page property line below the title
+	propertyRegex = regexp.MustCompile(`^([\p{L}][\p{L}\p{N} _-]{0,40}): (.*)$`)
+	// titleRegex matches the title heading Notion puts first in every page
+	titleRegex = regexp.MustCompile(`^#[ \t]+(.+?)[ \t]*$`)
+)
+
+// dateLayouts are the formats Notion exports date properties in
+var dateLayouts = []string{
+	"January 2, 2006 3:04 PM",
+	"January 2, 2006",
+	"2006/01/02 15:04",
+	"2006/01/02",
+	"2006-01-02",
+}
+
+// propertyKeys maps Notion property names to common front matter keys
+var propertyKeys = map[string]string{
+	"created":          "date",
+	"created time":     "date",
+	"date":             "date",
+	"last edited time": "lastmod",
+	"updated":          "lastmod",
+	"tags":             "tags",
+	"category":         "categories",
+	"categories":       "categories",
+}
+
+// listKeys are front matter keys holding comma-separated lists
+var listKeys = map[string]bool{
+	"tags":       true,
+	"categories": true,
+}
+
+// Options controls how an export is normalized
+type Options struct {
+	Source    string // Notion export zip or extracted directory
+	OutputDir string
+	IndexName string // Name of pages that have subpages, index.md or _index.md
+	DryRun    bool
+}
+
+// Stats describes a normalization run
+type Stats struct {
+	Pages   int `json:"pages"`
+	Files   int `json:"files"` // Other files such as images and CSV databases
+	Renamed int `json:"renamed"`
+	Links   int `json:"links"` // Links rewritten to the renamed files
+	Images  int `json:"images"`
+}
+
+// entry is a file of the export and its normalized destination
+type entry struct {
+	src string
+	dst string
+}
+
+// Normalize converts a Notion export into a markdown tree: page IDs are
+// removed from file and folder names, names are slugified, a page with
+// subpages becomes the index of its folder, links follow the renames, remote
+// images are downloaded and title and properties move into front matter.
+func Normalize(ctx context.Context, opts Options) (*Stats, error) {
+	if opts.IndexName == "" {
+		opts.IndexName = "index.md"
+	}
+
+	root := opts.Source
+	if strings.EqualFold(filepath.Ext(root), ".zip") {
+		tmp, err := os.MkdirTemp("", "mdctl-notion-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary directory: %v", err)
+		}
+		defer os.RemoveAll(tmp)
+		if err := extractZip(root, tmp); err != nil {
+			return nil, err
+		}
+		root = tmp
+	}
+
+	var entries []entry
+	if err := planDir(root, opts.OutputDir, opts.IndexName, &entries); err != nil {
+		return nil, err
+	}
+
+	// Links are resolved against the export, by path or by page ID
+	byPath := make(map[string]string)
+	byID := make(map[string]string)
+	for _, e := range entries {
+		byPath[e.src] = e.dst
+		stem := strings.TrimSuffix(filepath.Base(e.src), filepath.Ext(e.src))
+		if m := hashRegex.FindStringSubmatch(stem); m != nil && mddoc.IsMarkdown(e.src) {
+			byID[m[1]] = e.dst
+		}
+	}
+
+	stats := &Stats{}
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return stats, ctx.Err()
+		}
+		rel, _ := filepath.Rel(opts.OutputDir, e.dst)
+		if srcRel, _ := filepath.Rel(root, e.src); srcRel != rel {
+			stats.Renamed++
+		}
+
+		if !mddoc.IsMarkdown(e.src) {
+			stats.Files++
+			if opts.DryRun {
+				continue
+			}
+			if err := copyFile(e.src, e.dst); err != nil {
+				return stats, err
+			}
+			continue
+		}
+
+		stats.Pages++
+		if err := normalizePage(e, byPath, byID, opts.DryRun, stats); err != nil {
+			return stats, fmt.Errorf("failed to normalize %s: %v", e.src, err)
+		}
+	}
+	return stats, nil
+}
+
+// planDir assigns normalized destinations to the files below dir
+func planDir(dir, dstDir, indexName string, entries *[]entry) error {
+	items, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", dir, err)
+	}
+
+	// Directories claim their names first, a page with subpages is exported
+	// as "Page <id>.md" next to the folder "Page <id>"
+	taken := make(map[string]bool)
+	dirNames := make(map[string]string)
+	for _, item := range items {
+		if item.IsDir() {
+			name := uniqueName(cleanName(item.Name()), "", taken)
+			dirNames[item.Name()] = name
+			if err := planDir(filepath.Join(dir, item.Name()), filepath.Join(dstDir, name), indexName, entries); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, item := range items {
+		if item.IsDir() {
+			continue
+		}
+		src := filepath.Join(dir, item.Name())
+		ext := filepath.Ext(item.Name())
+		stem := strings.TrimSuffix(item.Name(), ext)
+
+		var dst string
+		if folder, ok := dirNames[stem]; ok && mddoc.IsMarkdown(src) {
+			dst = filepath.Join(dstDir, folder, indexName)
+		} else {
+			dst = filepath.Join(dstDir, uniqueName(cleanName(stem), strings.ToLower(ext), taken))
+		}
+		*entries = append(*entries, entry{src: src, dst: dst})
+	}
+	return nil
+}
+
+// cleanName removes the page ID from an exported name and slugifies it
+func cleanName(name string) string {
+	name = hashRegex.ReplaceAllString(name, "")
+	if slug := fsutil.Slugify(name); slug != "" {
+		return slug
+	}
+	return "untitled"
+}
+
+// uniqueName returns name+ext, numbered when it is already taken in the directory
+func uniqueName(name, ext string, taken map[string]bool) string {
+	candidate := name + ext
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d%s", name, i, ext)
+	}
+	taken[candidate] = true
+	return candidate
+}
+
+// normalizePage rewrites a page to its destination
+func normalizePage(e entry, byPath, byID map[string]string, dryRun bool, stats *Stats) error {
+	data, err := os.ReadFile(e.src)
+	if err != nil {
+		return err
+	}
+
+	content, err := pageFrontMatter(string(data))
+	if err != nil {
+		return err
+	}
+	content = rewriteLinks(content, e, byPath, byID, stats)
+
+	if dryRun {
+		logger.Infof("Would write %s -> %s", e.src, e.dst)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(e.dst), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	// Images still hosted by Notion or elsewhere are downloaded like `mdctl download`
+	if strings.Contains(content, "](http") {
+		p := processor.New("", "", "")
+		if content, err = p.ProcessContent(content, e.dst); err != nil {
+			return err
+		}
+		stats.Images += p.Stats.DownloadedImages
+	}
+
+	logger.Infof("Writing %s", e.dst)
+	return fsutil.WriteFileAtomic(e.dst, []byte(content), 0644)
+}
+
+// pageFrontMatter moves the title heading and the property lines below it
+// into front matter
+func pageFrontMatter(content string) (string, error) {
+	meta := make(map[string]interface{})
+	body := content
+	if strings.HasPrefix(content, "---\n") {
+		if parts := strings.SplitN(content[4:], "\n---\n", 2); len(parts) == 2 {
+			if err := yaml.Unmarshal([]byte(parts[0]), &meta); err != nil {
+				return "", fmt.Errorf("failed to parse front matter: %v", err)
+			}
+			body = parts[1]
+		}
+	}
+
+	lines := strings.Split(strings.TrimLeft(body, "\n"), "\n")
+	if m := titleRegex.FindStringSubmatch(lines[0]); m != nil {
+		if _, ok := meta["title"]; !ok {
+			meta["title"] = m[1]
+		}
+		lines = lines[1:]
+
+		// Properties form the first paragraph after the title
+		start := 0
+		for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+			start++
+		}
+		end := start
+		for end < len(lines) && strings.TrimSpace(lines[end]) != "" {
+			end++
+		}
+		if end > start && allProperties(lines[start:end]) {
+			for _, line := range lines[start:end] {
+				m := propertyRegex.FindStringSubmatch(line)
+				key, value := propertyKey(m[1]), propertyValue(m[1], strings.TrimSpace(m[2]))
+				if _, ok := meta[key]; !ok && value != nil {
+					meta[key] = value
+				}
+			}
+			lines = lines[end:]
+		}
+		body = strings.TrimLeft(strings.Join(lines, "\n"), "\n")
+	}
+
+	if len(meta) == 0 {
+		return body, nil
+	}
+	header, err := yaml.Marshal(meta)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal front matter: %v", err)
+	}
+	return fmt.Sprintf("---\n%s---\n\n%s", header, body), nil
+}
+
+// allProperties reports whether every line is a "Name: value" property
+func allProperties(lines []string) bool {
+	for _, line := range lines {
+		if !propertyRegex.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// propertyKey returns the front matter key of a Notion property
+func propertyKey(name string) string {
+	lower := strings.ToLower(strings.TrimSpace(name))
+	if key, ok := propertyKeys[lower]; ok {
+		return key
+	}
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(lower)
+}
+
+// propertyValue converts a property to a front matter value: dates become
+// YYYY-MM-DD and tags become lists
+func propertyValue(name, value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	key := propertyKey(name)
+	if listKeys[key] {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	if key == "date" || key == "lastmod" {
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.Format("2006-01-02")
+			}
+		}
+	}
+	return value
+}
+
+// rewriteLinks points inline links and images at the normalized files, code
+// is left untouched
+func rewriteLinks(content string, e entry, byPath, byID map[string]string, stats *Stats) string {
+	out, _ := mddoc.Parse([]byte(content)).ReplaceLinks(func(link mddoc.Link) (string, bool) {
+		if link.Kind != mddoc.InlineLink && link.Kind != mddoc.InlineImage {
+			return "", false
+		}
+		dest, ok := resolveLink(link.Destination, e, byPath, byID)
+		if ok {
+			stats.Links++
+		}
+		return dest, ok
+	})
+	return string(out)
+}
+
+// resolveLink returns the destination of a link as seen from the normalized page
+func resolveLink(dest string, e entry, byPath, byID map[string]string) (string, bool) {
+	var target, suffix string
+	if m := notionURLRegex.FindStringSubmatch(dest); m != nil {
+		target = byID[m[1]]
+	} else {
+		path := dest
+		if i := strings.IndexAny(path, "?#"); i >= 0 {
+			path, suffix = path[:i], path[i:]
+		}
+		if path == "" || strings.Contains(path, "://") || strings.HasPrefix(path, "/") {
+			return "", false
+		}
+		unescaped, err := url.PathUnescape(path)
+		if err != nil {
+			return "", false
+		}
+		target = byPath[filepath.Join(filepath.Dir(e.src), filepath.FromSlash(unescaped))]
+	}
+	if target == "" {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(e.dst), target)
+	if err != nil {
+		return "", false
+	}
+	return filepath.ToSlash(rel) + suffix, true
+}
+
+// extractZip extracts an export, including the nested part archives Notion
+// splits large workspaces into
+func extractZip(path, dir string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer r.Close()
+
+	var nested []string
+	for _, f := range r.File {
+		dst := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if rel, err := filepath.Rel(dir, dst); err != nil || strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("invalid path in archive: %s", f.Name)
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(dst, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := extractFile(f, dst); err != nil {
+			return err
+		}
+		if strings.EqualFold(filepath.Ext(dst), ".zip") {
+			nested = append(nested, dst)
+		}
+	}
+
+	sort.Strings(nested)
+	for _, part := range nested {
+		if err := extractZip(part, dir); err != nil {
+			return err
+		}
+		os.Remove(part)
+	}
+	return nil
+}
+
+// extractFile writes one archived file to dst
+func extractFile(f *zip.File, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	in, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s from archive: %v", f.Name, err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to extract %s: %v", f.Name, err)
+	}
+	return out.Close()
+}
+
+// copyFile copies a file that needs no rewriting
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s: %v", src, err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,86 @@
+package notion
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	guideID = "0123456789abcdef0123456789abcdef"
+	setupID = "fedcba9876543210fedcba9876543210"
+)
+
+func TestNormalize(t *testing.T) {
+	// Notion zips a workspace as one archive containing part archives
+	export := map[string]string{
+		"Guide " + guideID + ".md": "# Guide\n\nCreated: March 5, 2023 10:15 AM\nTags: go, cli\nStatus: Draft\n\n" +
+			"Start with [Setup](Guide%20" + guideID + "/Setup%20" + setupID + ".md#install).\n\n" +
+			"```\n[keep](Guide%20" + guideID + "/x.md)\n```\n",
+		"Guide " + guideID + "/Setup " + setupID + ".md": "# Setup\n\nNote: not a property\nbecause this line is prose.\n\n" +
+			"![Shot](Untitled.png) back to [guide](https://www.notion.so/Guide-" + guideID + ")\n",
+		"Guide " + guideID + "/Untitled.png": "png",
+	}
+	dir := t.TempDir()
+	part := filepath.Join(dir, "Export-Part-1.zip")
+	writeZip(t, part, export)
+	data, _ := os.ReadFile(part)
+	archive := filepath.Join(dir, "Export.zip")
+	writeZip(t, archive, map[string]string{"Export-Part-1.zip": string(data)})
+
+	out := filepath.Join(dir, "docs")
+	stats, err := Normalize(context.Background(), Options{Source: archive, OutputDir: out})
+	if err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+	if stats.Pages != 2 || stats.Files != 1 || stats.Links != 3 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	guide, err := os.ReadFile(filepath.Join(out, "guide", "index.md"))
+	if err != nil {
+		t.Fatalf("guide not written: %v", err)
+	}
+	wantGuide := "---\ndate: \"2023-03-05\"\nstatus: Draft\ntags:\n    - go\n    - cli\ntitle: Guide\n---\n\n" +
+		"Start with [Setup](setup.md#install).\n\n" +
+		"```\n[keep](Guide%20" + guideID + "/x.md)\n```\n"
+	if string(guide) != wantGuide {
+		t.Errorf("unexpected guide:\n%s", guide)
+	}
+
+	setup, err := os.ReadFile(filepath.Join(out, "guide", "setup.md"))
+	if err != nil {
+		t.Fatalf("subpage not written: %v", err)
+	}
+	for _, want := range []string{"title: Setup", "Note: not a property", "![Shot](untitled.png)", "[guide](index.md)"} {
+		if !strings.Contains(string(setup), want) {
+			t.Errorf("expected %q in subpage:\n%s", want, setup)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(out, "guide", "untitled.png")); err != nil {
+		t.Errorf("image not copied: %v", err)
+	}
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
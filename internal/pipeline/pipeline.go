@@ -0,0 +1,229 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gobwas/glob"
+	"github.com/samzong/mdctl/internal/fsutil"
+	"gopkg.in/yaml.v3"
+)
+
+// Step kinds supported in pipeline files
+const (
+	StepDownload  = "download"
+	StepLint      = "lint"
+	StepTranslate = "translate"
+	StepUpload    = "upload"
+	StepExport    = "export"
+)
+
+// Pipeline is a declarative chain of mdctl operations
+type Pipeline struct {
+	Name    string   `yaml:"name"`
+	Root    string   `yaml:"root"`    // Directory the steps operate on, relative to the pipeline file
+	Include []string `yaml:"include"` // Globs of files to process, relative to Root
+	Exclude []string `yaml:"exclude"` // Globs of files to skip, relative to Root
+	Steps   []Step   `yaml:"steps"`
+
+	dir string // Directory of the pipeline file
+}
+
+// Step is a single operation of a pipeline
+type Step struct {
+	Name            string     `yaml:"name"`
+	Run             string     `yaml:"run"`     // download, lint, translate, upload or export
+	Include         []string   `yaml:"include"` // Narrows the pipeline files for this step
+	Exclude         []string   `yaml:"exclude"`
+	When            *Condition `yaml:"when"`
+	ContinueOnError bool       `yaml:"continue_on_error"`
+	With            yaml.Node  `yaml:"with"` // Options of the step kind
+}
+
+// Condition decides whether a step runs
+type Condition struct {
+	Env     string `yaml:"env"`     // Run only if this environment variable is set
+	Changed *bool  `yaml:"changed"` // Run only if earlier steps did (true) or did not (false) change files
+}
+
+// DownloadOptions configures a download step
+type DownloadOptions struct {
+	Output string `yaml:"output"` // Directory for downloaded images
+}
+
+// LintOptions configures a lint step
+type LintOptions struct {
+	Fix          bool     `yaml:"fix"`
+	Config       string   `yaml:"config"`
+	Enable       []string `yaml:"enable"`
+	Disable      []string `yaml:"disable"`
+	FailOnIssues bool     `yaml:"fail_on_issues"` // Fail the step if unfixed issues remain
+}
+
+// TranslateOptions configures a translate step
+type TranslateOptions struct {
+	Locale   string `yaml:"locale"`
+	To       string `yaml:"to"` // Target directory mirroring Root, default name_<locale>.md next to each file
+	Force    bool   `yaml:"force"`
+	Format   bool   `yaml:"format"`
+	Catalogs bool   `yaml:"catalogs"`
+
+	CopyAssets bool `yaml:"copy_assets"` // Copy referenced images into To instead of rewriting their paths
+}
+
+// UploadOptions configures an upload step
+type UploadOptions struct {
+	Storage  string `yaml:"storage"`
+	Force    bool   `yaml:"force"`
+	Conflict string `yaml:"conflict"`
+}
+
+// ExportOptions configures an export step
+type ExportOptions struct {
+	Output              string `yaml:"output"`
+	Format              string `yaml:"format"`
+	Template            string `yaml:"template"`
+	Toc                 bool   `yaml:"toc"`
+	TocDepth            int    `yaml:"toc_depth"`
+	ShiftHeadingLevelBy int    `yaml:"shift_heading_level_by"`
+	FileAsTitle         bool   `yaml:"file_as_title"`
+	SiteType            string `yaml:"site_type"` // Read the file order from a site configuration instead
+	NavPath             string `yaml:"nav_path"`
+}
+
+// Load reads and validates a pipeline file
+func Load(path string) (*Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline: %v", err)
+	}
+
+	var p Pipeline
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline: %v", err)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	p.dir = filepath.Dir(absPath)
+	if p.Root == "" {
+		p.Root = "."
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid pipeline %s: %v", path, err)
+	}
+	return &p, nil
+}
+
+// validate checks step kinds, options and glob patterns
+func (p *Pipeline) validate() error {
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("no steps defined")
+	}
+	if err := checkGlobs(p.Include, p.Exclude); err != nil {
+		return err
+	}
+
+	for i := range p.Steps {
+		step := &p.Steps[i]
+		if step.Name == "" {
+			step.Name = step.Run
+		}
+		if err := checkGlobs(step.Include, step.Exclude); err != nil {
+			return fmt.Errorf("step %d (%s): %v", i+1, step.Name, err)
+		}
+
+		var err error
+		switch step.Run {
+		case StepDownload:
+			err = step.decode(&DownloadOptions{})
+		case StepLint:
+			err = step.decode(&LintOptions{})
+		case StepTranslate:
+			var opts TranslateOptions
+			if err = step.decode(&opts); err == nil && opts.Locale == "" {
+				err = fmt.Errorf("locale is required")
+			}
+		case StepUpload:
+			err = step.decode(&UploadOptions{})
+		case StepExport:
+			var opts ExportOptions
+			if err = step.decode(&opts); err == nil && opts.Output == "" {
+				err = fmt.Errorf("output is required")
+			}
+		case "":
+			err = fmt.Errorf("run is required")
+		default:
+			err = fmt.Errorf("unknown step kind %q (must be download, lint, translate, upload or export)", step.Run)
+		}
+		if err != nil {
+			return fmt.Errorf("step %d (%s): %v", i+1, step.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// decode reads the step options into v
+func (s *Step) decode(v interface{}) error {
+	if s.With.Kind == 0 {
+		return nil
+	}
+	if err := s.With.Decode(v); err != nil {
+		return fmt.Errorf("invalid options: %v", err)
+	}
+	return nil
+}
+
+// path resolves a path of the pipeline file relative to its directory
+func (p *Pipeline) path(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(p.dir, path)
+}
+
+// checkGlobs reports the first invalid pattern
+func checkGlobs(patterns ...[]string) error {
+	for _, list := range patterns {
+		for _, pattern := range list {
+			if _, err := glob.Compile(pattern, '/'); err != nil {
+				return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// filter returns the files matching include (all if empty) and not matching exclude.
+// Files are paths relative to the root with forward slashes.
+func filter(files, include, exclude []string) []string {
+	if len(include) == 0 && len(exclude) == 0 {
+		return files
+	}
+
+	compile := func(patterns []string) []glob.Glob {
+		var matchers []glob.Glob
+		for _, pattern := range patterns {
+			// Patterns were validated when loading
+			matchers = append(matchers, glob.MustCompile(pattern, '/'))
+		}
+		return matchers
+	}
+	includes, excludes := compile(include), compile(exclude)
+	var result []string
+	for _, file := range files {
+		if len(includes) > 0 && !fsutil.MatchAny(includes, file) {
+			continue
+		}
+		if fsutil.MatchAny(excludes, file) {
+			continue
+		}
+		result = append(result, file)
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad_Validation(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"no steps", "name: x\n", "no steps"},
+		{"unknown step", "steps:\n  - run: publish\n", "unknown step kind"},
+		{"missing locale", "steps:\n  - run: translate\n", "locale is required"},
+		{"bad glob", "include: ['[']\nsteps:\n  - run: lint\n", "invalid pattern"},
+		{"valid", "steps:\n  - run: lint\n    with: {fix: true}\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "pipeline.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRun_FiltersAndConditions(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"docs/a.md":        "#A\n",
+		"docs/guide/b.md":  "#B\n",
+		"docs/drafts/c.md": "#C\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pipelineFile := filepath.Join(dir, "pipeline.yaml")
+	content := `root: docs
+exclude: ["drafts/**"]
+steps:
+  - name: fix guide
+    run: lint
+    include: ["guide/**"]
+    with: {fix: true, enable: [MD018]}
+  - name: only unchanged
+    run: lint
+    when: {changed: false}
+  - name: needs env
+    run: lint
+    when: {env: MDCTL_PIPELINE_TEST_UNSET}
+`
+	if err := os.WriteFile(pipelineFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := Load(pipelineFile)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	summary, err := Run(context.Background(), p, Options{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if summary.Files != 2 {
+		t.Errorf("expected drafts to be excluded, got %d files", summary.Files)
+	}
+	if got := summary.Steps[0]; got.Status != StatusOK || got.Files != 1 || got.Changed != 1 {
+		t.Errorf("unexpected first step: %+v", got)
+	}
+	if got := summary.Steps[1].Status; got != StatusSkipped {
+		t.Errorf("expected changed condition to skip, got %s", got)
+	}
+	if got := summary.Steps[2].Status; got != StatusSkipped {
+		t.Errorf("expected env condition to skip, got %s", got)
+	}
+
+	fixed, _ := os.ReadFile(filepath.Join(dir, "docs", "guide", "b.md"))
+	if string(fixed) != "# B\n" {
+		t.Errorf("expected guide file to be fixed, got %q", fixed)
+	}
+	untouched, _ := os.ReadFile(filepath.Join(dir, "docs", "a.md"))
+	if string(untouched) != "#A\n" {
+		t.Errorf("expected other files untouched, got %q", untouched)
+	}
+}
@@ -0,0 +1,480 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/exporter"
+	"github.com/samzong/mdctl/internal/index"
+	"github.com/samzong/mdctl/internal/linter"
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"github.com/samzong/mdctl/internal/processor"
+	"github.com/samzong/mdctl/internal/translator"
+	"github.com/samzong/mdctl/internal/uploader"
+)
+
+// Step statuses reported in StepResult
+const (
+	StatusOK      = "ok"
+	StatusSkipped = "skipped"
+	StatusFailed  = "failed"
+)
+
+var logger = logging.New("PIPELINE")
+
+// Options controls a pipeline run
+type Options struct {
+	DryRun bool // Run every step in dry-run mode
+}
+
+// StepResult describes the outcome of a single step
+type StepResult struct {
+	Name       string `json:"name"`
+	Run        string `json:"run"`
+	Status     string `json:"status"`
+	Files      int    `json:"files"`
+	Changed    int    `json:"changed"`
+	Summary    string `json:"summary,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Summary describes a whole pipeline run
+type Summary struct {
+	Pipeline string       `json:"pipeline,omitempty"`
+	Root     string       `json:"root"`
+	Files    int          `json:"files"`
+	Steps    []StepResult `json:"steps"`
+	Failed   int          `json:"failed"`
+}
+
+// runner holds the state shared by all steps of a run
+type runner struct {
+	p       *Pipeline
+	opts    Options
+	root    string
+	files   []string     // Pipeline files relative to root, walked once
+	idx     *index.Index // Index of root, nil if none was built
+	changed int          // Files changed by earlier steps
+	cfg     *config.Config
+}
+
+// Run executes the pipeline steps in order. The directory tree is walked once
+// and an existing file index is shared by all steps. A failing step stops the
+// run unless it sets continue_on_error.
+func Run(ctx context.Context, p *Pipeline, opts Options) (*Summary, error) {
+	r := &runner{p: p, opts: opts, root: p.path(p.Root)}
+
+	files, err := r.walk()
+	if err != nil {
+		return nil, err
+	}
+	r.files = filter(files, p.Include, p.Exclude)
+
+	if r.idx, err = index.Find(r.root); err != nil {
+		logger.Warnf("Ignoring index: %v", err)
+	}
+
+	summary := &Summary{Pipeline: p.Name, Root: r.root, Files: len(r.files)}
+	var runErr error
+
+	for i, step := range p.Steps {
+		result := StepResult{Name: step.Name, Run: step.Run}
+
+		if runErr != nil {
+			result.Status = StatusSkipped
+			result.Summary = "not run after earlier failure"
+			summary.Steps = append(summary.Steps, result)
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			runErr = err
+			result.Status = StatusSkipped
+			result.Summary = "interrupted"
+			summary.Steps = append(summary.Steps, result)
+			continue
+		}
+		if reason := r.skipReason(step.When); reason != "" {
+			logger.Infof("[%d/%d] Skipping %s: %s", i+1, len(p.Steps), step.Name, reason)
+			result.Status = StatusSkipped
+			result.Summary = reason
+			summary.Steps = append(summary.Steps, result)
+			continue
+		}
+
+		stepFiles := filter(r.files, step.Include, step.Exclude)
+		result.Files = len(stepFiles)
+		logger.Infof("[%d/%d] Running %s on %d files", i+1, len(p.Steps), step.Name, len(stepFiles))
+
+		start := time.Now()
+		err := r.runStep(ctx, step, stepFiles, &result)
+		result.DurationMs = time.Since(start).Milliseconds()
+		r.changed += result.Changed
+
+		if err != nil {
+			result.Status = StatusFailed
+			result.Error = err.Error()
+			summary.Failed++
+			if !step.ContinueOnError {
+				runErr = fmt.Errorf("step %s failed: %v", step.Name, err)
+			}
+		} else {
+			result.Status = StatusOK
+		}
+		summary.Steps = append(summary.Steps, result)
+	}
+
+	return summary, runErr
+}
+
+// walk collects the markdown files below the root
+func (r *runner) walk() ([]string, error) {
+	var files []string
+	err := filepath.Walk(r.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != r.root && (strings.HasPrefix(info.Name(), ".") || info.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !mddoc.IsMarkdown(path) {
+			return nil
+		}
+		rel, err := filepath.Rel(r.root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %v", r.root, err)
+	}
+	return files, nil
+}
+
+// skipReason returns why a step's condition prevents it from running, or ""
+func (r *runner) skipReason(when *Condition) string {
+	if when == nil {
+		return ""
+	}
+	if when.Env != "" && os.Getenv(when.Env) == "" {
+		return fmt.Sprintf("environment variable %s is not set", when.Env)
+	}
+	if when.Changed != nil && *when.Changed != (r.changed > 0) {
+		if *when.Changed {
+			return "no files changed by earlier steps"
+		}
+		return "files were changed by earlier steps"
+	}
+	return ""
+}
+
+// abs converts root-relative files to absolute paths
+func (r *runner) abs(files []string) []string {
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = filepath.Join(r.root, filepath.FromSlash(file))
+	}
+	return paths
+}
+
+// withImages keeps the files that have images matching keep, files that are
+// not indexed or changed since are always kept
+func (r *runner) withImages(files []string, keep func(image string) bool) []string {
+	if r.idx == nil {
+		return files
+	}
+
+	var result []string
+	for _, file := range files {
+		entry, ok := r.idx.Lookup(file)
+		if !ok {
+			result = append(result, file)
+			continue
+		}
+		for _, image := range entry.Images {
+			if keep(image) {
+				result = append(result, file)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// isRemote reports whether an image reference is a remote URL
+func isRemote(image string) bool {
+	return strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://")
+}
+
+// config loads the mdctl configuration once per run
+func (r *runner) config() (*config.Config, error) {
+	if r.cfg == nil {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config: %v", err)
+		}
+		r.cfg = cfg
+	}
+	return r.cfg, nil
+}
+
+// runStep dispatches a step to its implementation
+func (r *runner) runStep(ctx context.Context, step Step, files []string, result *StepResult) error {
+	switch step.Run {
+	case StepDownload:
+		var opts DownloadOptions
+		if err := step.decode(&opts); err != nil {
+			return err
+		}
+		return r.download(opts, files, result)
+	case StepLint:
+		var opts LintOptions
+		if err := step.decode(&opts); err != nil {
+			return err
+		}
+		return r.lint(ctx, opts, files, result)
+	case StepTranslate:
+		var opts TranslateOptions
+		if err := step.decode(&opts); err != nil {
+			return err
+		}
+		return r.translate(ctx, opts, files, result)
+	case StepUpload:
+		var opts UploadOptions
+		if err := step.decode(&opts); err != nil {
+			return err
+		}
+		return r.upload(ctx, opts, files, result)
+	case StepExport:
+		var opts ExportOptions
+		if err := step.decode(&opts); err != nil {
+			return err
+		}
+		return r.export(ctx, opts, files, result)
+	}
+	return fmt.Errorf("unknown step kind %q", step.Run)
+}
+
+// download fetches remote images referenced by the files
+func (r *runner) download(opts DownloadOptions, files []string, result *StepResult) error {
+	if r.opts.DryRun {
+		result.Summary = "download does not support dry run, nothing fetched"
+		return nil
+	}
+
+	files = r.withImages(r.abs(files), isRemote)
+	if len(files) == 0 {
+		result.Summary = "no remote images"
+		return nil
+	}
+
+	p := &processor.Processor{Files: files, ImageOutputDir: r.p.path(opts.Output)}
+	err := p.Process()
+	result.Changed = p.Stats.ChangedFiles
+	result.Summary = fmt.Sprintf("downloaded %d images, %d failed", p.Stats.DownloadedImages, p.Stats.FailedImages)
+	return err
+}
+
+// lint checks and optionally fixes the files
+func (r *runner) lint(ctx context.Context, opts LintOptions, files []string, result *StepResult) error {
+	l := linter.New(&linter.Config{
+		AutoFix:      opts.Fix,
+		DryRun:       r.opts.DryRun,
+		RulesFile:    r.p.path(opts.Config),
+		EnableRules:  opts.Enable,
+		DisableRules: opts.Disable,
+	})
+
+	var issues, fixed, remaining int
+	for _, file := range r.abs(files) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		res, err := l.LintFile(file)
+		if err != nil {
+			return err
+		}
+		issues += len(res.Issues)
+		fixed += res.FixedCount
+		if res.FixedCount > 0 && !r.opts.DryRun {
+			result.Changed++
+		}
+		for _, issue := range res.Issues {
+			if !issue.Fixed {
+				remaining++
+			}
+		}
+	}
+
+	result.Summary = fmt.Sprintf("%d issues, %d fixed", issues, fixed)
+	if r.opts.DryRun && opts.Fix {
+		result.Summary = fmt.Sprintf("%d issues, %d would be fixed", issues, fixed)
+	}
+	if opts.FailOnIssues && remaining > 0 {
+		return fmt.Errorf("%d issues remain", remaining)
+	}
+	return nil
+}
+
+// translate translates the files into the target locale
+func (r *runner) translate(ctx context.Context, opts TranslateOptions, files []string, result *StepResult) error {
+	cfg, err := r.config()
+	if err != nil {
+		return err
+	}
+	if !translator.IsLanguageSupported(opts.Locale) {
+		return fmt.Errorf("unsupported locale: %s", opts.Locale)
+	}
+
+	report := &translator.Report{}
+	tOpts := translator.Options{
+		Format:   opts.Format,
+		Force:    opts.Force,
+		Catalogs: opts.Catalogs,
+		DryRun:   r.opts.DryRun,
+		Report:   report,
+
+		CopyAssets: opts.CopyAssets,
+	}
+	if opts.To != "" {
+		tOpts.SourceRoot = r.root
+	}
+
+	for _, file := range files {
+		src := filepath.Join(r.root, filepath.FromSlash(file))
+		var dst string
+		if opts.To != "" {
+			dst = filepath.Join(r.p.path(opts.To), filepath.FromSlash(file))
+		} else {
+			ext := filepath.Ext(src)
+			dst = strings.TrimSuffix(src, ext) + "_" + opts.Locale + ext
+		}
+
+		if err := translator.ProcessFile(ctx, src, dst, opts.Locale, cfg, tOpts); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logger.Warnf("Failed to translate %s: %v", src, err)
+		}
+	}
+
+	result.Changed = report.Translated
+	if r.opts.DryRun {
+		result.Summary = fmt.Sprintf("would translate %d (~%d tokens), skipped %d", report.Planned, report.EstimatedTokens, report.Skipped)
+	} else {
+		result.Summary = fmt.Sprintf("translated %d, skipped %d, failed %d", report.Translated, report.Skipped, report.Failed)
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d files failed to translate", report.Failed)
+	}
+	return nil
+}
+
+// upload uploads local images referenced by the files
+func (r *runner) upload(ctx context.Context, opts UploadOptions, files []string, result *StepResult) error {
+	files = r.withImages(r.abs(files), func(image string) bool { return !isRemote(image) })
+	if len(files) == 0 {
+		result.Summary = "no local images"
+		return nil
+	}
+
+	cfg, err := r.config()
+	if err != nil {
+		return err
+	}
+	cloud := cfg.GetActiveCloudConfig(opts.Storage)
+	if opts.Conflict != "" {
+		cloud.ConflictPolicy = opts.Conflict
+	}
+
+	up, err := uploader.New(uploader.UploaderConfig{
+		Files:          files,
+		Provider:       cloud.Provider,
+		Bucket:         cloud.Bucket,
+		CustomDomain:   cloud.CustomDomain,
+		PathPrefix:     cloud.PathPrefix,
+		DryRun:         r.opts.DryRun,
+		Concurrency:    cloud.Concurrency,
+		ForceUpload:    opts.Force,
+		SkipVerify:     cloud.SkipVerify,
+		CACertPath:     cloud.CACertPath,
+		ConflictPolicy: uploader.ConflictPolicy(cloud.ConflictPolicy),
+		CacheDir:       cloud.CacheDir,
+		Storage:        &cloud,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create uploader: %v", err)
+	}
+
+	stats, err := up.Process(ctx)
+	if stats != nil {
+		result.Changed = stats.ChangedFiles
+		result.Summary = fmt.Sprintf("uploaded %d images, skipped %d, failed %d",
+			stats.UploadedImages, stats.SkippedImages, stats.FailedImages)
+	}
+	return err
+}
+
+// export converts the files into a single document
+func (r *runner) export(ctx context.Context, opts ExportOptions, files []string, result *StepResult) error {
+	if !r.opts.DryRun {
+		if err := exporter.CheckPandocAvailability(); err != nil {
+			return err
+		}
+	}
+
+	if opts.Format == "" {
+		opts.Format = "docx"
+	}
+	if opts.TocDepth == 0 {
+		opts.TocDepth = 3
+	}
+
+	output := r.p.path(opts.Output)
+	options := exporter.ExportOptions{
+		Template:            r.p.path(opts.Template),
+		GenerateToc:         opts.Toc,
+		ShiftHeadingLevelBy: opts.ShiftHeadingLevelBy,
+		FileAsTitle:         opts.FileAsTitle,
+		Format:              opts.Format,
+		SiteType:            opts.SiteType,
+		TocDepth:            opts.TocDepth,
+		DryRun:              r.opts.DryRun,
+		Plan:                &exporter.ExportPlan{},
+	}
+
+	if opts.NavPath != "" {
+		options.NavPaths = []string{opts.NavPath}
+	}
+
+	exp := exporter.NewExporter()
+	var err error
+	if opts.SiteType != "" && opts.SiteType != "basic" {
+		// The site configuration decides which files are exported and in which order
+		err = exp.ExportDirectory(ctx, r.root, output, options)
+	} else {
+		err = exp.ExportFiles(ctx, r.abs(files), output, options)
+	}
+	if err != nil {
+		return err
+	}
+
+	if r.opts.DryRun {
+		result.Summary = fmt.Sprintf("would export %d files to %s", len(options.Plan.Files), output)
+	} else {
+		result.Changed = 1
+		result.Summary = "exported to " + output
+	}
+	return nil
+}
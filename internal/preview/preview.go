@@ -0,0 +1,376 @@
+// Package preview serves a markdown directory as HTML pages that reload when
+// its files change, to check the results of mdctl commands without building
+// the site
+package preview
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samzong/mdctl/internal/exporter/sitereader"
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// DefaultInterval is how often the files are checked for changes
+const DefaultInterval = 500 * time.Millisecond
+
+// eventsPath is the server-sent events stream telling pages to reload
+const eventsPath = "/_mdctl/events"
+
+// indexNames are the pages shown for a directory, in order of preference
+var indexNames = []string{"index.md", "README.md", "_index.md"}
+
+// skipDirs are neither listed nor watched
+var skipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+}
+
+var logger = logging.New("PREVIEW")
+
+// Options configures the preview server
+type Options struct {
+	Dir      string                                // Directory served, markdown is rendered and other files are served as they are
+	Nav      func() ([]sitereader.NavEntry, error) // Navigation of the sidebar, read for every page, no sidebar when nil
+	Files    []string                              // Files outside Dir watched for changes, e.g. the site configuration
+	Interval time.Duration                         // How often the files are checked for changes, DefaultInterval when 0
+}
+
+// Server renders the markdown files of a directory
+type Server struct {
+	opts    Options
+	md      goldmark.Markdown
+	mu      sync.Mutex
+	sum     uint64        // Fingerprint of the watched files
+	changed chan struct{} // Closed when the watched files change
+}
+
+// New creates a preview server of a directory
+func New(opts Options) (*Server, error) {
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access directory: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", opts.Dir)
+	}
+	opts.Dir = dir
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+
+	s := &Server{
+		opts: opts,
+		md: goldmark.New(
+			goldmark.WithExtensions(extension.GFM, extension.Footnote),
+			goldmark.WithParserOptions(parser.WithAutoHeadingID(), parser.WithAttribute()),
+			// Local files only, raw HTML is shown as the site would show it
+			goldmark.WithRendererOptions(html.WithUnsafe()),
+		),
+		changed: make(chan struct{}),
+	}
+	s.sum = s.fingerprint()
+	return s, nil
+}
+
+// Handler returns the HTTP handler serving the pages, the files and the
+// reload events
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(eventsPath, s.handleEvents)
+	mux.HandleFunc("/", s.handleFile)
+	return mux
+}
+
+// ListenAndServe serves the preview on addr and watches the files until ctx
+// is cancelled
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		// Waiting reload streams end with the context
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	go s.watch(ctx)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		logger.Infof("Shutting down preview server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// watch checks the files for changes until ctx is cancelled
+func (s *Server) watch(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.check()
+		}
+	}
+}
+
+// check wakes the waiting pages when the watched files changed, it reports
+// whether they did
+func (s *Server) check() bool {
+	sum := s.fingerprint()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sum == s.sum {
+		return false
+	}
+	s.sum = sum
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return true
+}
+
+// fingerprint hashes the paths, sizes and modification times of the watched
+// files
+func (s *Server) fingerprint() uint64 {
+	h := fnv.New64a()
+	add := func(path string, info fs.FileInfo) {
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+	filepath.WalkDir(s.opts.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != s.opts.Dir && skipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			add(path, info)
+		}
+		return nil
+	})
+	for _, file := range s.opts.Files {
+		if info, err := os.Stat(file); err == nil {
+			add(file, info)
+		}
+	}
+	return h.Sum64()
+}
+
+// handleEvents sends a reload event once the files change
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	s.mu.Lock()
+	changed := s.changed
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	select {
+	case <-changed:
+		fmt.Fprint(w, "data: reload\n\n")
+		flusher.Flush()
+	case <-r.Context().Done():
+	}
+}
+
+// handleFile renders markdown files and directories, other files are served
+// as they are
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
+	urlPath := path.Clean("/" + r.URL.Path)
+	file := filepath.Join(s.opts.Dir, filepath.FromSlash(urlPath))
+	info, err := os.Stat(file)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if info.IsDir() {
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			http.Redirect(w, r, strings.TrimSuffix(urlPath, "/")+"/", http.StatusMovedPermanently)
+			return
+		}
+		for _, name := range indexNames {
+			if index := filepath.Join(file, name); isFile(index) {
+				s.servePage(w, index)
+				return
+			}
+		}
+		s.serveListing(w, file)
+		return
+	}
+	if mddoc.IsMarkdown(file) {
+		s.servePage(w, file)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeFile(w, r, file)
+}
+
+// servePage renders a markdown file
+func (s *Server) servePage(w http.ResponseWriter, file string) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read %s: %v", s.rel(file), err), http.StatusInternalServerError)
+		return
+	}
+	doc := mddoc.Split(content)
+	var body bytes.Buffer
+	if err := s.md.Convert(doc.Body(), &body); err != nil {
+		http.Error(w, fmt.Sprintf("failed to render %s: %v", s.rel(file), err), http.StatusInternalServerError)
+		return
+	}
+	s.render(w, page{Title: pageTitle(doc, file), Path: s.rel(file), Content: template.HTML(body.String())}, file)
+}
+
+// serveListing renders a directory without index page as a list of its
+// markdown files and subdirectories
+func (s *Server) serveListing(w http.ResponseWriter, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read %s: %v", s.rel(dir), err), http.StatusInternalServerError)
+		return
+	}
+	var b strings.Builder
+	b.WriteString("<ul>\n")
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case entry.IsDir() && !skipDirs[name] && !strings.HasPrefix(name, "."):
+			name += "/"
+		case entry.IsDir() || !mddoc.IsMarkdown(name):
+			continue
+		}
+		fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a></li>\n", template.HTMLEscapeString(name), template.HTMLEscapeString(name))
+	}
+	b.WriteString("</ul>\n")
+	title := "/"
+	if rel := s.rel(dir); rel != "." {
+		title += rel + "/"
+	}
+	s.render(w, page{Title: title, Path: title, Content: template.HTML(b.String())}, dir)
+}
+
+// render writes a page with the navigation sidebar
+func (s *Server) render(w http.ResponseWriter, p page, file string) {
+	if s.opts.Nav != nil {
+		entries, err := s.opts.Nav()
+		if err != nil {
+			logger.Warnf("Failed to read the navigation: %v", err)
+		}
+		p.Nav = s.navItems(entries, file)
+	}
+	p.Events = eventsPath
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := pageTemplate.Execute(w, p); err != nil {
+		logger.Warnf("Failed to write page %s: %v", p.Path, err)
+	}
+}
+
+// navItems converts navigation entries to sidebar items linking the pages,
+// the page of file is marked current
+func (s *Server) navItems(entries []sitereader.NavEntry, file string) []navItem {
+	items := make([]navItem, 0, len(entries))
+	for _, entry := range entries {
+		item := navItem{Title: entry.Title, Children: s.navItems(entry.Children, file)}
+		if entry.File != "" {
+			item.URL = "/" + s.rel(entry.File)
+			item.Current = entry.File == file
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// rel returns the path of a file relative to the served directory with
+// forward slashes
+func (s *Server) rel(file string) string {
+	rel, err := filepath.Rel(s.opts.Dir, file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// pageTitle returns the title of the front matter, the first top-level
+// heading or the file name
+func pageTitle(doc *mddoc.Document, file string) string {
+	if meta, err := doc.Meta(); err == nil && meta != nil {
+		var fields struct {
+			Title string `yaml:"title"`
+		}
+		if meta.Decode(&fields) == nil && strings.TrimSpace(fields.Title) != "" {
+			return strings.TrimSpace(fields.Title)
+		}
+	}
+	for _, h := range mddoc.Parse(doc.Body()).Headings() {
+		if h.Level == 1 {
+			return h.Text
+		}
+	}
+	return strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+}
+
+// isFile reports whether path is a regular file
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// page is the data of the page template
+type page struct {
+	Title   string
+	Path    string
+	Content template.HTML
+	Nav     []navItem
+	Events  string
+}
+
+// navItem is an entry of the sidebar
+type navItem struct {
+	Title    string
+	URL      string // Empty for sections
+	Current  bool
+	Children []navItem
+}
@@ -0,0 +1,110 @@
+package preview
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samzong/mdctl/internal/exporter/sitereader"
+)
+
+func TestServerPages(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "guide"), 0755)
+	os.MkdirAll(filepath.Join(dir, "notes"), 0755)
+	os.WriteFile(filepath.Join(dir, "index.md"), []byte("---\ntitle: Home\n---\n\n# Welcome\n\n- [x] done\n\n![Logo](logo.png)\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "guide", "install.md"), []byte("# Install <Guide>\n\nRun ~~make~~ `go build`.\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes", "a.md"), []byte("A\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "logo.png"), []byte("png"), 0644)
+
+	s, err := New(Options{
+		Dir: dir,
+		Nav: func() ([]sitereader.NavEntry, error) {
+			return []sitereader.NavEntry{
+				{Title: "Home", File: filepath.Join(dir, "index.md")},
+				{Title: "Guide", Children: []sitereader.NavEntry{{Title: "Install", File: filepath.Join(dir, "guide", "install.md")}}},
+			}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get("/")
+	for _, want := range []string{"<title>Home</title>", `<h1 id="welcome">Welcome</h1>`, `type="checkbox"`, `<img src="logo.png" alt="Logo">`,
+		`<a href="/index.md" class="current">Home</a>`, `<span class="section">Guide</span>`, `<a href="/guide/install.md">Install</a>`, "new EventSource("} {
+		if status != http.StatusOK || !strings.Contains(body, want) {
+			t.Errorf("expected %q in the home page (%d):\n%s", want, status, body)
+		}
+	}
+
+	status, body = get("/guide/install.md")
+	for _, want := range []string{"<title>Install &lt;Guide&gt;</title>", "<del>make</del>", "<code>go build</code>", `<a href="/guide/install.md" class="current">`} {
+		if status != http.StatusOK || !strings.Contains(body, want) {
+			t.Errorf("expected %q in the page (%d):\n%s", want, status, body)
+		}
+	}
+
+	if status, body = get("/notes/"); status != http.StatusOK || !strings.Contains(body, `<a href="a.md">a.md</a>`) {
+		t.Errorf("expected a listing of the directory (%d):\n%s", status, body)
+	}
+	if status, body = get("/logo.png"); status != http.StatusOK || body != "png" {
+		t.Errorf("expected the image as it is, got %d %q", status, body)
+	}
+	if status, _ = get("/missing.md"); status != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing page, got %d", status)
+	}
+}
+
+func TestServerReload(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "index.md")
+	os.WriteFile(page, []byte("# One\n"), 0644)
+
+	s, err := New(Options{Dir: dir})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if s.check() {
+		t.Errorf("expected no change before the file is written")
+	}
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + eventsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+
+	os.WriteFile(page, []byte("# Two\n"), 0644)
+	os.Chtimes(page, time.Now(), time.Now().Add(time.Second))
+	if !s.check() {
+		t.Fatalf("expected a change after the file is written")
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != "data: reload\n" {
+		t.Errorf("expected a reload event, got %q, %v", line, err)
+	}
+}
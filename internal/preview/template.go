@@ -0,0 +1,53 @@
+package preview
+
+import "html/template"
+
+// pageTemplate lays out a rendered page with the navigation sidebar and the
+// script reloading it when the files change
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; display: flex; font: 16px/1.6 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; }
+nav { flex: 0 0 260px; box-sizing: border-box; height: 100vh; position: sticky; top: 0; overflow-y: auto; padding: 1.5em 1em; border-right: 1px solid #d0d7de; background: #f6f8fa; font-size: 14px; }
+nav ul { list-style: none; margin: 0; padding-left: 1em; }
+nav > ul { padding-left: 0; }
+nav li { margin: .25em 0; }
+nav .section { font-weight: 600; }
+nav .current { font-weight: 600; color: #1f2328; }
+main { flex: 1; min-width: 0; max-width: 900px; padding: 1.5em 2.5em; }
+.path { color: #59636e; font-size: 13px; margin-bottom: 1em; }
+a { color: #0969da; text-decoration: none; }
+a:hover { text-decoration: underline; }
+pre, code { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: 85%; }
+pre { background: #f6f8fa; padding: 1em; overflow: auto; border-radius: 6px; }
+:not(pre) > code { background: #eff1f3; padding: .2em .4em; border-radius: 6px; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d0d7de; padding: .4em .8em; }
+blockquote { margin: 0; padding: 0 1em; color: #59636e; border-left: .25em solid #d0d7de; }
+img { max-width: 100%; }
+</style>
+</head>
+<body>
+{{- define "nav"}}
+<ul>
+{{- range .}}
+<li>{{if .URL}}<a href="{{.URL}}"{{if .Current}} class="current"{{end}}>{{.Title}}</a>{{else}}<span class="section">{{.Title}}</span>{{end}}
+{{- if .Children}}{{template "nav" .Children}}{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Nav}}
+<nav>{{template "nav" .Nav}}</nav>
+{{- end}}
+<main>
+<div class="path">{{.Path}}</div>
+{{.Content}}
+</main>
+<script>new EventSource("{{.Events}}").onmessage = function () { location.reload(); };</script>
+</body>
+</html>
+`))
@@ -7,14 +7,34 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
+
+	"github.com/samzong/mdctl/internal/index"
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"github.com/samzong/mdctl/internal/metrics"
+	"github.com/samzong/mdctl/internal/netutil"
 )
 
+// logger reports download progress and problems
+var logger = logging.New("DOWNLOAD")
+
+// Stats holds statistics about a download run
+type Stats struct {
+	ProcessedFiles   int `json:"processed_files"`
+	DownloadedImages int `json:"downloaded_images"`
+	FailedImages     int `json:"failed_images"`
+	ChangedFiles     int `json:"changed_files"`
+}
+
 type Processor struct {
 	SourceFile     string
 	SourceDir      string
+	Files          []string // Explicit file list, processed instead of SourceFile/SourceDir
 	ImageOutputDir string
+	AssetsKeys     []string // Front matter fields referencing images, e.g. cover or cover.image
+	DryRun         bool     // Report the images that would be downloaded, download and write nothing
+	Stats          Stats
 }
 
 func New(sourceFile, sourceDir, imageOutputDir string) *Processor {
@@ -26,6 +46,14 @@ func New(sourceFile, sourceDir, imageOutputDir string) *Processor {
 }
 
 func (p *Processor) Process() error {
+	if len(p.Files) > 0 {
+		for _, file := range p.Files {
+			if err := p.processFile(file); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 	if p.SourceFile != "" {
 		return p.processFile(p.SourceFile)
 	}
@@ -33,12 +61,27 @@ func (p *Processor) Process() error {
 }
 
 func (p *Processor) processDirectory(dir string) error {
-	fmt.Printf("Processing directory: %s\n", dir)
+	logger.Infof("Processing directory: %s", dir)
+
+	// An index built with "mdctl index build" lets unchanged files without
+	// remote images be skipped without reading them
+	idx, err := index.Find(dir)
+	if err != nil {
+		logger.Warnf("Ignoring index: %v", err)
+	}
+
 	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && (strings.HasSuffix(path, ".md") || strings.HasSuffix(path, ".markdown")) {
+		if !info.IsDir() && mddoc.IsMarkdown(path) {
+			// The index only knows the images of the body
+			if idx != nil && len(p.AssetsKeys) == 0 {
+				if entry, ok := idx.Lookup(path); ok && !hasRemoteImage(entry.Images) {
+					logger.Debugf("Skipping %s (no remote images according to index)", path)
+					return nil
+				}
+			}
 			return p.processFile(path)
 		}
 		return nil
@@ -46,67 +89,104 @@ func (p *Processor) processDirectory(dir string) error {
 }
 
 func (p *Processor) processFile(filePath string) error {
-	fmt.Printf("Processing file: %s\n", filePath)
+	logger.Infof("Processing file: %s", filePath)
+	p.Stats.ProcessedFiles++
+	metrics.AddFiles(1)
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to read file %s: %v", filePath, err)
 	}
 
-	// Determine image output directory
-	imgDir := p.determineImageDir(filePath)
-	if err := os.MkdirAll(imgDir, 0755); err != nil {
-		return fmt.Errorf("failed to create image directory %s: %v", imgDir, err)
+	newContent, err := p.ProcessContent(string(content), filePath)
+	if err != nil {
+		return err
+	}
+
+	// Write back to file
+	if newContent == string(content) {
+		return nil
+	}
+	if err := os.WriteFile(filePath, []byte(newContent), 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %v", filePath, err)
 	}
+	p.Stats.ChangedFiles++
 
-	// Find all image links
-	imgRegex := regexp.MustCompile(`!\[([^\]]*)\]\(([^)]+)\)`)
-	matches := imgRegex.FindAllStringSubmatch(string(content), -1)
+	return nil
+}
 
-	fmt.Printf("Found %d images in file %s\n", len(matches), filePath)
+// ProcessContent downloads the remote images of markdown content and returns
+// it with local links. filePath locates the content, downloaded images are
+// linked relative to it, the file itself is neither read nor written.
+func (p *Processor) ProcessContent(content, filePath string) (string, error) {
+	// Determine image output directory
+	imgDir := p.ImageDir(filePath)
+	if !p.DryRun {
+		if err := os.MkdirAll(imgDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create image directory %s: %v", imgDir, err)
+		}
+	}
 
-	newContent := string(content)
-	for _, match := range matches {
-		imgAlt := match[1]
-		imgURL := match[2]
+	// Find all inline images, images in code are not part of the document
+	doc := mddoc.Parse([]byte(content))
+	logger.Infof("Found %d images in file %s", len(doc.Images()), filePath)
 
+	// Every remote image is downloaded once, however often it is linked
+	localLinks := make(map[string]string)
+	// localize downloads a remote image and returns its link relative to the file
+	localize := func(imgURL string) (string, bool) {
 		// Replace image URL starting with "//" to "https://"
 		if strings.HasPrefix(imgURL, "//") {
 			imgURL = strings.Replace(imgURL, "//", "https://", 1)
 		}
 		// Skip local images
 		if !strings.HasPrefix(imgURL, "http://") && !strings.HasPrefix(imgURL, "https://") {
-			continue
+			return "", false
+		}
+		if link, ok := localLinks[imgURL]; ok {
+			return link, link != ""
+		}
+		if p.DryRun {
+			logger.Infof("Would download %s to %s", imgURL, imgDir)
+			localLinks[imgURL] = ""
+			return "", false
 		}
 
 		// Download and save image
 		localPath, err := p.downloadImage(imgURL, imgDir)
 		if err != nil {
-			fmt.Printf("Warning: Failed to download image %s: %v\n", imgURL, err)
-			continue
+			logger.Warnf("Failed to download image %s: %v", imgURL, err)
+			p.Stats.FailedImages++
+			localLinks[imgURL] = ""
+			return "", false
 		}
+		p.Stats.DownloadedImages++
 
 		// Calculate relative path
 		relPath, err := filepath.Rel(filepath.Dir(filePath), localPath)
 		if err != nil {
-			fmt.Printf("Warning: Failed to calculate relative path: %v\n", err)
-			continue
+			logger.Warnf("Failed to calculate relative path: %v", err)
+			localLinks[imgURL] = ""
+			return "", false
 		}
-
-		// Replace image link
-		oldLink := fmt.Sprintf("![%s](%s)", match[1], match[2])
-		newLink := fmt.Sprintf("![%s](%s)", imgAlt, relPath)
-		newContent = strings.Replace(newContent, oldLink, newLink, 1)
+		localLinks[imgURL] = filepath.ToSlash(relPath)
+		return localLinks[imgURL], true
 	}
+	newContent, _ := doc.ReplaceImages(func(img mddoc.Image) (string, bool) {
+		return localize(img.Destination)
+	})
 
-	// Write back to file
-	if err := os.WriteFile(filePath, []byte(newContent), 0644); err != nil {
-		return fmt.Errorf("failed to write file %s: %v", filePath, err)
-	}
+	// Images of the front matter fields, such as the cover of a post
+	newContent, _ = mddoc.Split(newContent).ReplaceMetaAssets(p.AssetsKeys, func(asset mddoc.MetaAsset) (string, bool) {
+		return localize(asset.Value)
+	})
 
-	return nil
+	return string(newContent), nil
 }
 
-func (p *Processor) determineImageDir(filePath string) string {
+// ImageDir returns the directory images of a markdown file are stored in:
+// the configured output directory, images/ below the source directory, or
+// images/ next to the file
+func (p *Processor) ImageDir(filePath string) string {
 	if p.ImageOutputDir != "" {
 		return p.ImageOutputDir
 	}
@@ -117,7 +197,7 @@ func (p *Processor) determineImageDir(filePath string) string {
 }
 
 func (p *Processor) downloadImage(url string, destDir string) (string, error) {
-	resp, err := http.Get(url)
+	resp, err := netutil.NewClient(0).Get(url)
 	if err != nil {
 		return "", err
 	}
@@ -159,7 +239,7 @@ func (p *Processor) downloadImage(url string, destDir string) (string, error) {
 		return "", err
 	}
 
-	fmt.Printf("Downloaded image to: %s\n", localPath)
+	logger.Infof("Downloaded image to: %s", localPath)
 	return localPath, nil
 }
 
@@ -214,3 +294,13 @@ func getExtensionFromContentType(contentType string) string {
 		return ""
 	}
 }
+
+// hasRemoteImage reports whether any of the image references points to a remote URL
+func hasRemoteImage(images []string) bool {
+	for _, img := range images {
+		if strings.HasPrefix(img, "http://") || strings.HasPrefix(img, "https://") || strings.HasPrefix(img, "//") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package processor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProcessContent(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("png"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	img := srv.URL + "/logo.png"
+	content := strings.Join([]string{
+		"![logo](" + img + " \"Logo\")",
+		"![logo](" + img + ")",
+		"```",
+		"![logo](" + img + ")",
+		"```",
+		"Text mentioning (" + img + ") stays.",
+	}, "\n")
+
+	p := New("", "", filepath.Join(dir, "images"))
+	got, err := p.ProcessContent(content, filepath.Join(dir, "doc.md"))
+	if err != nil {
+		t.Fatalf("ProcessContent failed: %v", err)
+	}
+	if requests != 1 || p.Stats.DownloadedImages != 1 {
+		t.Errorf("expected one download, got %d requests and %d images", requests, p.Stats.DownloadedImages)
+	}
+
+	lines := strings.Split(got, "\n")
+	if !strings.HasPrefix(lines[0], "![logo](images/logo_") || !strings.HasSuffix(lines[0], ".png \"Logo\")") {
+		t.Errorf("expected a local link with title, got %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "![logo](images/logo_") {
+		t.Errorf("expected the repeated image to be replaced, got %q", lines[1])
+	}
+	if lines[3] != "![logo]("+img+")" || lines[5] != "Text mentioning ("+img+") stays." {
+		t.Errorf("expected code and text to be kept:\n%s", got)
+	}
+}
+
+func TestProcessContentAssetsKeys(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("png"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	img := srv.URL + "/cover.png"
+	content := "---\ntitle: Post\ncover:\n  image: " + img + "\nthumbnail: \"" + img + "\"\n---\n![cover](" + img + ")\n"
+
+	p := New("", "", filepath.Join(dir, "images"))
+	p.AssetsKeys = []string{"cover.image", "thumbnail"}
+	got, err := p.ProcessContent(content, filepath.Join(dir, "post.md"))
+	if err != nil {
+		t.Fatalf("ProcessContent failed: %v", err)
+	}
+	if requests != 1 || strings.Contains(got, srv.URL) {
+		t.Errorf("expected the front matter and body image downloaded once, got %d requests:\n%s", requests, got)
+	}
+	if !strings.Contains(got, "\n  image: images/cover_") || !strings.Contains(got, "\nthumbnail: \"images/cover_") {
+		t.Errorf("expected local links in the front matter:\n%s", got)
+	}
+}
@@ -0,0 +1,292 @@
+// Package relink fixes relative links and image paths after files were moved
+package relink
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/gitrepo"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"gopkg.in/yaml.v3"
+)
+
+// skipDirs are never descended into
+var skipDirs = map[string]bool{
+	".git":         true,
+	".mdctl":       true,
+	"node_modules": true,
+}
+
+// Move is a file or directory moved within the root, paths are relative to it
+type Move struct {
+	From string `yaml:"from" json:"from"`
+	To   string `yaml:"to" json:"to"`
+}
+
+// Change is a link rewritten by Relink
+type Change struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// Unresolved is a relative link whose target does not exist after the moves
+type Unresolved struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Target string `json:"target"`
+}
+
+// Result lists the rewritten links and the links left broken
+type Result struct {
+	Changes    []Change     `json:"changes"`
+	Unresolved []Unresolved `json:"unresolved"`
+}
+
+// LoadMoves reads a YAML list of from/to paths
+func LoadMoves(path string) ([]Move, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rename map: %v", err)
+	}
+	var moves []Move
+	if err := yaml.Unmarshal(data, &moves); err != nil {
+		return nil, fmt.Errorf("failed to parse rename map %s: %v", path, err)
+	}
+	for i, m := range moves {
+		if strings.TrimSpace(m.From) == "" || strings.TrimSpace(m.To) == "" {
+			return nil, fmt.Errorf("rename map %s: entry %d needs from and to", path, i+1)
+		}
+	}
+	return moves, nil
+}
+
+// GitMoves returns the renames git detects below root between since and the
+// working tree. Moves need to be staged (git mv or git add) to be detected.
+func GitMoves(root, since string) ([]Move, error) {
+	if since == "" {
+		since = "HEAD"
+	}
+	repo, err := gitrepo.Open(root)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := repo.Rel(root)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.Commit(since)
+	if err != nil {
+		return nil, err
+	}
+	renames, err := repo.Renames(commit, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// Renames are relative to the top level, moves to root
+	var moves []Move
+	for _, rename := range renames {
+		from, _ := filepath.Rel(filepath.FromSlash(prefix), filepath.FromSlash(rename[0]))
+		to, _ := filepath.Rel(filepath.FromSlash(prefix), filepath.FromSlash(rename[1]))
+		moves = append(moves, Move{From: filepath.ToSlash(from), To: filepath.ToSlash(to)})
+	}
+	return moves, nil
+}
+
+// mover maps the old paths of moved files to the new ones and back
+type mover struct {
+	moves [][2]string // Absolute from and to paths
+}
+
+// newMover resolves moves relative to root, the new paths have to exist
+func newMover(root string, moves []Move) (*mover, error) {
+	m := &mover{}
+	for _, move := range moves {
+		from := filepath.Join(root, filepath.FromSlash(move.From))
+		to := filepath.Join(root, filepath.FromSlash(move.To))
+		if _, err := os.Stat(to); err != nil {
+			return nil, fmt.Errorf("%s does not exist, move the files before relinking", move.To)
+		}
+		m.moves = append(m.moves, [2]string{from, to})
+	}
+	return m, nil
+}
+
+// newPath returns where a file that was at path is now
+func (m *mover) newPath(path string) (string, bool) {
+	return m.lookup(path, 0, 1)
+}
+
+// oldPath returns where a file that is at path was before the moves
+func (m *mover) oldPath(path string) (string, bool) {
+	return m.lookup(path, 1, 0)
+}
+
+// lookup maps path with the longest matching move, files below a moved
+// directory move along with it
+func (m *mover) lookup(path string, from, to int) (string, bool) {
+	best, result := -1, path
+	for _, move := range m.moves {
+		src := move[from]
+		if path != src && !strings.HasPrefix(path, src+string(filepath.Separator)) {
+			continue
+		}
+		if len(src) > best {
+			best = len(src)
+			result = move[to] + strings.TrimPrefix(path, src)
+		}
+	}
+	return result, best >= 0
+}
+
+// Relink rewrites the relative links and images of the markdown files below
+// root after the moves: links to moved files point at their new path and the
+// links of moved files are adjusted to their new directory. Relative links
+// whose target does not exist are reported and left as they are.
+func Relink(root string, moves []Move, dryRun bool) (*Result, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %v", err)
+	}
+	info, err := os.Stat(absRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access %s: %v", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	m, err := newMover(absRoot, moves)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	err = filepath.Walk(absRoot, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != absRoot && skipDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if mddoc.IsMarkdown(path) || mddoc.IsMDX(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %v", root, err)
+	}
+
+	result := &Result{}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return result, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		rel, err := filepath.Rel(absRoot, path)
+		if err != nil {
+			return result, err
+		}
+
+		content, changed := relinkFile(m, path, filepath.ToSlash(rel), string(data), result)
+		if !changed || dryRun {
+			continue
+		}
+		if err := fsutil.WriteFileAtomic(path, []byte(content), 0644); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// relinkFile rewrites the links of one file, found in the markdown AST so
+// that code blocks and code spans are left untouched, and records changes
+// and unresolved links in result
+func relinkFile(m *mover, path, rel, content string, result *Result) (string, bool) {
+	oldPath, _ := m.oldPath(path)
+	oldDir, newDir := filepath.Dir(oldPath), filepath.Dir(path)
+
+	out, n := mddoc.Parse([]byte(content)).ReplaceLinks(func(link mddoc.Link) (string, bool) {
+		dest, ok := rewriteDest(m, oldDir, newDir, link.Destination, link.Angled)
+		if !ok {
+			result.Unresolved = append(result.Unresolved, Unresolved{File: rel, Line: link.Line, Target: link.Destination})
+			return "", false
+		}
+		if dest != link.Destination {
+			result.Changes = append(result.Changes, Change{File: rel, Line: link.Line, Old: link.Destination, New: dest})
+		}
+		return dest, true
+	})
+	return string(out), n > 0
+}
+
+// rewriteDest returns a link destination written in oldDir as seen from
+// newDir, false when its target does not exist. Spaces are escaped unless
+// the destination is written in angle brackets.
+func rewriteDest(m *mover, oldDir, newDir, dest string, spaces bool) (string, bool) {
+	target, suffix := dest, ""
+	if i := strings.IndexAny(target, "?#"); i >= 0 {
+		target, suffix = target[:i], target[i:]
+	}
+	if !isRelativeRef(target) {
+		return dest, true
+	}
+	unescaped, err := url.PathUnescape(target)
+	if err != nil {
+		return dest, true
+	}
+
+	file, moved := m.newPath(filepath.Join(oldDir, filepath.FromSlash(unescaped)))
+	if !exists(file) {
+		return dest, false
+	}
+	if !moved && oldDir == newDir {
+		return dest, true
+	}
+
+	rel, err := filepath.Rel(newDir, file)
+	if err != nil {
+		return dest, true
+	}
+	rel = filepath.ToSlash(rel)
+	// Keep the style of the original link
+	if strings.HasPrefix(target, "./") && !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	if strings.HasSuffix(target, "/") && !strings.HasSuffix(rel, "/") {
+		rel += "/"
+	}
+	if !spaces {
+		rel = strings.ReplaceAll(rel, " ", "%20")
+	}
+	return rel + suffix, true
+}
+
+// exists reports whether a link target exists, extensionless links of
+// site generators may point at a markdown file
+func exists(file string) bool {
+	for _, candidate := range []string{file, file + ".md"} {
+		if _, err := os.Stat(candidate); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isRelativeRef reports whether a link target is a path relative to the document
+func isRelativeRef(target string) bool {
+	if target == "" || strings.HasPrefix(target, "/") || strings.HasPrefix(target, "{{") {
+		return false
+	}
+	u, err := url.Parse(target)
+	return err == nil && u.Scheme == "" && u.Host == ""
+}
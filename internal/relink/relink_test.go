@@ -0,0 +1,163 @@
+package relink
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRelink(t *testing.T) {
+	dir := t.TempDir()
+	// The tree after guide/install.md moved to setup/install.md and the
+	// images directory to assets/img
+	writeFiles(t, dir, map[string]string{
+		"index.md": "See [install](guide/install.md#linux) and [missing](gone.md).\n" +
+			"![logo](images/logo.png \"Logo\")\n" +
+			"```\n[code](guide/install.md)\n```\n" +
+			"````\n```\n[nested](guide/install.md)\n```\n````\n" +
+			"Code `[span](guide/install.md)` and\n\n    [indented](guide/install.md)\n\n" +
+			"[ref]: ./guide/install.md\n",
+		"setup/install.md": "Back to [home](../index.md), [faq](faq.md) and ![shot](../images/shot.png).\n" +
+			"<img src=\"../images/logo.png\" width=\"100\">\n",
+		"guide/faq.md":        "[Install](install.md)\n",
+		"assets/img/logo.png": "png",
+		"assets/img/shot.png": "png",
+	})
+	moves := []Move{
+		{From: "guide/install.md", To: "setup/install.md"},
+		{From: "images", To: "assets/img"},
+	}
+
+	result, err := Relink(dir, moves, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantIndex := "See [install](setup/install.md#linux) and [missing](gone.md).\n" +
+		"![logo](assets/img/logo.png \"Logo\")\n" +
+		"```\n[code](guide/install.md)\n```\n" +
+		"````\n```\n[nested](guide/install.md)\n```\n````\n" +
+		"Code `[span](guide/install.md)` and\n\n    [indented](guide/install.md)\n\n" +
+		"[ref]: ./setup/install.md\n"
+	if got := readFile(t, dir, "index.md"); got != wantIndex {
+		t.Errorf("index.md = %q, want %q", got, wantIndex)
+	}
+	wantInstall := "Back to [home](../index.md), [faq](../guide/faq.md) and ![shot](../assets/img/shot.png).\n" +
+		"<img src=\"../assets/img/logo.png\" width=\"100\">\n"
+	if got := readFile(t, dir, "setup/install.md"); got != wantInstall {
+		t.Errorf("setup/install.md = %q, want %q", got, wantInstall)
+	}
+	if got := readFile(t, dir, "guide/faq.md"); got != "[Install](../setup/install.md)\n" {
+		t.Errorf("guide/faq.md = %q", got)
+	}
+
+	if len(result.Changes) != 7 {
+		t.Errorf("got %d changes, want 7: %+v", len(result.Changes), result.Changes)
+	}
+	wantUnresolved := []Unresolved{{File: "index.md", Line: 1, Target: "gone.md"}}
+	if !reflect.DeepEqual(result.Unresolved, wantUnresolved) {
+		t.Errorf("unresolved = %+v, want %+v", result.Unresolved, wantUnresolved)
+	}
+}
+
+func TestRelinkDryRun(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"index.md": "[a](old.md)\n",
+		"new.md":   "# New\n",
+	})
+
+	result, err := Relink(dir, []Move{{From: "old.md", To: "new.md"}}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].New != "new.md" {
+		t.Errorf("changes = %+v", result.Changes)
+	}
+	if got := readFile(t, dir, "index.md"); got != "[a](old.md)\n" {
+		t.Errorf("dry run wrote index.md: %q", got)
+	}
+
+	if _, err := Relink(dir, []Move{{From: "new.md", To: "missing.md"}}, true); err == nil {
+		t.Error("expected an error for a move whose target does not exist")
+	}
+}
+
+func TestGitMoves(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, map[string]string{
+		"docs/install.md": "# Install\n\nSome text long enough to be detected as a rename.\n",
+		"docs/usage.md":   "# Usage\n\nRun the command.\nPass the flags.\nRead the output.\n",
+		"docs/index.md":   "[Install](install.md)\n",
+		"notes.md":        "# Notes\n",
+	})
+	for _, name := range []string{"docs/install.md", "docs/usage.md", "docs/index.md", "notes.md"} {
+		if _, err := wt.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signature := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	if _, err := wt.Commit("initial", &git.CommitOptions{Author: signature}); err != nil {
+		t.Fatal(err)
+	}
+
+	for from, to := range map[string]string{
+		"docs/install.md": "docs/setup/install.md",
+		"docs/usage.md":   "docs/guide.md",
+		"notes.md":        "docs/notes.md",
+	} {
+		if _, err := wt.Move(from, to); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Edited while moved, still similar enough
+	writeFiles(t, dir, map[string]string{"docs/guide.md": "# Usage\n\nRun the command.\nPass the flags.\nRead the output.\nDone.\n"})
+
+	moves, err := GitMoves(filepath.Join(dir, "docs"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Move{{From: "install.md", To: "setup/install.md"}, {From: "usage.md", To: "guide.md"}}
+	if !reflect.DeepEqual(moves, want) {
+		t.Errorf("moves = %+v, want %+v", moves, want)
+	}
+
+	if _, err := GitMoves(filepath.Join(dir, "docs"), "v9"); err == nil {
+		t.Error("expected an error for an unknown ref")
+	}
+}
@@ -0,0 +1,232 @@
+// Package scaffold creates markdown documents from templates: built-in ones,
+// those of the user configuration directory and those of the project
+package scaffold
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/fsutil"
+)
+
+// DefaultTemplate is used for kinds without a template of their name
+const DefaultTemplate = "default"
+
+// UserTemplatesDir is the directory of the user's templates inside the mdctl
+// configuration directory
+const UserTemplatesDir = "new-templates"
+
+// builtinTemplates are available without any files
+var builtinTemplates = map[string]string{
+	DefaultTemplate: `---
+title: {{quote .Title}}
+date: {{.Date}}
+---
+
+# {{.Title}}
+
+`,
+	"blog": `---
+title: {{quote .Title}}
+date: {{.DateTime}}
+{{- if .Author}}
+author: {{quote .Author}}
+{{- end}}
+description: ""
+tags: []
+draft: true
+---
+
+`,
+	"doc": `---
+title: {{quote .Title}}
+description: ""
+weight: 10
+---
+
+# {{.Title}}
+
+## Overview
+
+`,
+}
+
+// Data is what templates can use
+type Data struct {
+	Title    string // Title as given
+	Slug     string // Title as a file name, e.g. my-title
+	Kind     string // Kind of document, e.g. post
+	Author   string // Author of the configuration
+	Date     string // Creation date, 2006-01-02
+	DateTime string // Creation time, RFC 3339
+}
+
+// NewData returns the template data of a new document
+func NewData(kind, title, author string, now time.Time) Data {
+	return Data{
+		Title:    title,
+		Slug:     fsutil.Slugify(title),
+		Kind:     kind,
+		Author:   author,
+		Date:     now.Format("2006-01-02"),
+		DateTime: now.Format(time.RFC3339),
+	}
+}
+
+// Template is a template found by List
+type Template struct {
+	Name   string `json:"name"`
+	Source string `json:"source"` // File of the template, "built-in" for built-in ones
+}
+
+// Finder looks up templates and target directories for documents created
+// below a directory
+type Finder struct {
+	dirs    []string          // Template directories, nearest first
+	newDirs map[string]string // Target directories by kind, from the nearest project configuration setting one
+}
+
+// NewFinder collects the template directories of the project configuration
+// files (.mdctl.yaml) from dir upwards, .mdctl/templates next to them when
+// they set none, followed by the user's templates
+func NewFinder(dir string) (*Finder, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	f := &Finder{newDirs: make(map[string]string)}
+	for {
+		project, err := config.LoadProjectConfig(dir)
+		if err != nil {
+			return nil, err
+		}
+		if project != nil {
+			templates := project.TemplatesDir
+			if templates == "" {
+				templates = filepath.Join(".mdctl", "templates")
+			}
+			f.dirs = append(f.dirs, resolve(dir, templates))
+			for kind, newDir := range project.NewDirs {
+				if _, ok := f.newDirs[kind]; !ok {
+					f.newDirs[kind] = resolve(dir, newDir)
+				}
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	f.dirs = append(f.dirs, filepath.Join(config.ConfigDir(), UserTemplatesDir))
+	return f, nil
+}
+
+// resolve returns path relative to dir unless it is absolute
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, filepath.FromSlash(path))
+}
+
+// Find returns the text and source of a template: the file <name>.md of the
+// nearest template directory having one, or a built-in template
+func (f *Finder) Find(name string) (string, string, error) {
+	for _, dir := range f.dirs {
+		path := filepath.Join(dir, name+".md")
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return string(data), path, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", "", fmt.Errorf("failed to read template %s: %v", path, err)
+		}
+	}
+	if text, ok := builtinTemplates[name]; ok {
+		return text, "built-in", nil
+	}
+	return "", "", fmt.Errorf("template not found: %s (see mdctl new --list)", name)
+}
+
+// TemplateFor returns the name of the template of a kind: an explicit name,
+// the template named like the kind or DefaultTemplate
+func (f *Finder) TemplateFor(kind, name string) string {
+	if name != "" {
+		return name
+	}
+	if _, _, err := f.Find(kind); err == nil {
+		return kind
+	}
+	return DefaultTemplate
+}
+
+// Dir returns the directory documents of a kind are created in, fallback
+// when no project configuration sets one
+func (f *Finder) Dir(kind, fallback string) string {
+	if dir, ok := f.newDirs[kind]; ok {
+		return dir
+	}
+	return fallback
+}
+
+// List returns the available templates by name, a template of a nearer
+// directory hides those of the same name further up and the built-in ones
+func (f *Finder) List() ([]Template, error) {
+	found := make(map[string]string)
+	for _, dir := range f.dirs {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template directory %s: %v", dir, err)
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutSuffix(entry.Name(), ".md")
+			if !ok || entry.IsDir() {
+				continue
+			}
+			if _, ok := found[name]; !ok {
+				found[name] = filepath.Join(dir, entry.Name())
+			}
+		}
+	}
+	for name := range builtinTemplates {
+		if _, ok := found[name]; !ok {
+			found[name] = "built-in"
+		}
+	}
+
+	templates := make([]Template, 0, len(found))
+	for name, source := range found {
+		templates = append(templates, Template{Name: name, Source: source})
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// Render fills a template with the data of a document
+func Render(text string, data Data) (string, error) {
+	tmpl, err := template.New("document").Funcs(template.FuncMap{
+		// quote makes a value safe as a double-quoted YAML string
+		"quote": func(s string) string {
+			return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+		},
+	}).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %v", err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %v", err)
+	}
+	return out.String(), nil
+}
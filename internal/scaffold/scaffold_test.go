@@ -0,0 +1,91 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFinderAndRender(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+
+	sub := filepath.Join(root, "site", "blog")
+	files := map[string]string{
+		".mdctl.yaml":                           "new_dirs:\n  post: content/posts\n  page: pages\n",
+		".mdctl/templates/post.md":              "root post\n",
+		"site/.mdctl.yaml":                      "templates_dir: tpl\nnew_dirs:\n  post: posts\n",
+		"site/tpl/post.md":                      "---\ntitle: {{quote .Title}}\nslug: {{.Slug}}\nauthor: {{.Author}}\n---\n",
+		".config/mdctl/new-templates/doc.md":    "user doc\n",
+		".config/mdctl/new-templates/notes.txt": "not a template\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	os.MkdirAll(sub, 0755)
+
+	finder, err := NewFinder(sub)
+	if err != nil {
+		t.Fatalf("NewFinder failed: %v", err)
+	}
+	if got := finder.Dir("post", "."); got != filepath.Join(root, "site", "posts") {
+		t.Errorf("post dir = %s", got)
+	}
+	if got := finder.Dir("page", "."); got != filepath.Join(root, "pages") {
+		t.Errorf("page dir = %s", got)
+	}
+	if got := finder.Dir("note", "."); got != "." {
+		t.Errorf("note dir = %s", got)
+	}
+
+	tests := map[string]string{"post": "post", "note": DefaultTemplate}
+	for kind, want := range tests {
+		if got := finder.TemplateFor(kind, ""); got != want {
+			t.Errorf("TemplateFor(%s) = %s, want %s", kind, got, want)
+		}
+	}
+	if _, source, _ := finder.Find("doc"); source != filepath.Join(root, ".config", "mdctl", "new-templates", "doc.md") {
+		t.Errorf("doc template from %s, want the user template", source)
+	}
+	if _, _, err := finder.Find("missing"); err == nil {
+		t.Error("expected an error for a missing template")
+	}
+
+	templates, err := finder.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	want := []Template{
+		{"blog", "built-in"},
+		{DefaultTemplate, "built-in"},
+		{"doc", filepath.Join(root, ".config", "mdctl", "new-templates", "doc.md")},
+		{"post", filepath.Join(root, "site", "tpl", "post.md")},
+	}
+	if len(templates) != len(want) {
+		t.Fatalf("List = %+v, want %+v", templates, want)
+	}
+	for i := range want {
+		if templates[i] != want[i] {
+			t.Errorf("List[%d] = %+v, want %+v", i, templates[i], want[i])
+		}
+	}
+
+	text, _, _ := finder.Find("post")
+	data := NewData("post", `Hello, "World"!`, "Jo", time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC))
+	got, err := Render(text, data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if wantDoc := "---\ntitle: \"Hello, \\\"World\\\"!\"\nslug: hello-world\nauthor: Jo\n---\n"; got != wantDoc {
+		t.Errorf("Render =\n%s\nwant\n%s", got, wantDoc)
+	}
+
+	blog, err := Render(builtinTemplates["blog"], data)
+	if err != nil || !strings.HasPrefix(blog, "---\ntitle: \"Hello, \\\"World\\\"!\"\ndate: 2024-05-01T09:30:00Z\nauthor: \"Jo\"\n") {
+		t.Errorf("blog template rendered %q, %v", blog, err)
+	}
+}
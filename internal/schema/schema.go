@@ -0,0 +1,313 @@
+// Package schema validates YAML documents such as front matter against the
+// commonly used subset of JSON Schema.
+package schema
+
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is a JSON Schema. Supported keywords are type, required,
+// properties, additionalProperties, enum, const, format (date, date-time,
+// email, uri), pattern, minLength, maxLength, minimum, maximum, items,
+// minItems, maxItems and uniqueItems. Schemas may be written in JSON or YAML.
+type Schema struct {
+	Type                 StringList         `yaml:"type"`
+	Required             []string           `yaml:"required"`
+	Properties           map[string]*Schema `yaml:"properties"`
+	AdditionalProperties *bool              `yaml:"additionalProperties"`
+	Enum                 []interface{}      `yaml:"enum"`
+	Const                interface{}        `yaml:"const"`
+	Format               string             `yaml:"format"`
+	Pattern              string             `yaml:"pattern"`
+	MinLength            *int               `yaml:"minLength"`
+	MaxLength            *int               `yaml:"maxLength"`
+	Minimum              *float64           `yaml:"minimum"`
+	Maximum              *float64           `yaml:"maximum"`
+	Items                *Schema            `yaml:"items"`
+	MinItems             *int               `yaml:"minItems"`
+	MaxItems             *int               `yaml:"maxItems"`
+	UniqueItems          bool               `yaml:"uniqueItems"`
+
+	pattern *regexp.Regexp
+}
+
+// StringList is a string or a list of strings
+type StringList []string
+
+// UnmarshalYAML accepts a single string or a list
+func (l *StringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = StringList{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// Violation is a value that does not match its schema
+type Violation struct {
+	Path    string `json:"path"` // Dotted path of the value, empty for the document
+	Line    int    `json:"line"` // Line within the document, starting at 1
+	Message string `json:"message"`
+}
+
+// Load reads a schema file
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %v", err)
+	}
+	var s Schema
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse schema %s: %v", path, err)
+	}
+	if err := s.compile(); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %v", path, err)
+	}
+	return &s, nil
+}
+
+// compile compiles the patterns of a schema and its subschemas
+func (s *Schema) compile() error {
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	for _, prop := range s.Properties {
+		if err := prop.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+	return nil
+}
+
+// Validate checks a YAML document against the schema, a nil node is
+// validated as an empty mapping
+func (s *Schema) Validate(node *yaml.Node) []Violation {
+	if node == nil {
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: 1}
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	var violations []Violation
+	s.validate(node, "", &violations)
+	return violations
+}
+
+// validate appends the violations of a node
+func (s *Schema) validate(node *yaml.Node, path string, violations *[]Violation) {
+	report := func(format string, args ...interface{}) {
+		*violations = append(*violations, Violation{Path: path, Line: node.Line, Message: fmt.Sprintf(format, args...)})
+	}
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	kind := kindOf(node)
+	if len(s.Type) > 0 && !s.allows(kind) {
+		report("%s must be %s, got %s", describe(path), strings.Join(s.Type, " or "), kind)
+		return
+	}
+
+	if len(s.Enum) > 0 && !containsValue(s.Enum, node) {
+		options := make([]string, len(s.Enum))
+		for i, e := range s.Enum {
+			options[i] = fmt.Sprint(e)
+		}
+		report("%s must be one of %s, got %q", describe(path), strings.Join(options, ", "), node.Value)
+	}
+	if s.Const != nil && !containsValue([]interface{}{s.Const}, node) {
+		report("%s must be %v", describe(path), s.Const)
+	}
+
+	switch kind {
+	case "object":
+		s.validateObject(node, path, violations)
+	case "array":
+		s.validateArray(node, path, violations)
+	case "string":
+		if msg := s.checkString(node.Value); msg != "" {
+			report("%s %s", describe(path), msg)
+		}
+	case "integer", "number":
+		n, _ := strconv.ParseFloat(node.Value, 64)
+		if s.Minimum != nil && n < *s.Minimum {
+			report("%s must be at least %v", describe(path), *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			report("%s must be at most %v", describe(path), *s.Maximum)
+		}
+	}
+}
+
+// validateObject checks required keys and properties of a mapping
+func (s *Schema) validateObject(node *yaml.Node, path string, violations *[]Violation) {
+	present := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		present[key.Value] = true
+		child := join(path, key.Value)
+		if prop, ok := s.Properties[key.Value]; ok {
+			prop.validate(value, child, violations)
+		} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+			*violations = append(*violations, Violation{Path: child, Line: key.Line, Message: fmt.Sprintf("unknown key %s", child)})
+		}
+	}
+
+	for _, key := range s.Required {
+		if !present[key] {
+			*violations = append(*violations, Violation{Path: join(path, key), Line: node.Line, Message: fmt.Sprintf("missing required key %s", join(path, key))})
+		}
+	}
+}
+
+// validateArray checks the items of a sequence
+func (s *Schema) validateArray(node *yaml.Node, path string, violations *[]Violation) {
+	report := func(format string, args ...interface{}) {
+		*violations = append(*violations, Violation{Path: path, Line: node.Line, Message: fmt.Sprintf(format, args...)})
+	}
+	if s.MinItems != nil && len(node.Content) < *s.MinItems {
+		report("%s must have at least %d items", describe(path), *s.MinItems)
+	}
+	if s.MaxItems != nil && len(node.Content) > *s.MaxItems {
+		report("%s must have at most %d items", describe(path), *s.MaxItems)
+	}
+	seen := make(map[string]bool)
+	for i, item := range node.Content {
+		if s.UniqueItems && item.Kind == yaml.ScalarNode {
+			if seen[item.Value] {
+				report("%s has duplicate item %q", describe(path), item.Value)
+			}
+			seen[item.Value] = true
+		}
+		if s.Items != nil {
+			s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), violations)
+		}
+	}
+}
+
+// checkString returns why a string does not match the schema
+func (s *Schema) checkString(value string) string {
+	length := len([]rune(value))
+	if s.MinLength != nil && length < *s.MinLength {
+		return fmt.Sprintf("must be at least %d characters", *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		return fmt.Sprintf("must be at most %d characters", *s.MaxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(value) {
+		return fmt.Sprintf("must match %s", s.Pattern)
+	}
+	if !validFormat(s.Format, value) {
+		return fmt.Sprintf("must be a valid %s, got %q", s.Format, value)
+	}
+	return ""
+}
+
+// allows reports whether a JSON type is allowed, integers are numbers
+func (s *Schema) allows(kind string) bool {
+	for _, t := range s.Type {
+		if t == kind || (t == "number" && kind == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// kindOf returns the JSON type of a YAML node, timestamps are strings
+func kindOf(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch node.ShortTag() {
+	case "!!int":
+		return "integer"
+	case "!!float":
+		if f, err := strconv.ParseFloat(node.Value, 64); err == nil && f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	case "!!bool":
+		return "boolean"
+	case "!!null":
+		return "null"
+	default:
+		return "string"
+	}
+}
+
+// validFormat checks the formats of string values, unknown formats pass
+func validFormat(format, value string) bool {
+	switch format {
+	case "date":
+		_, err := time.Parse("2006-01-02", value)
+		return err == nil
+	case "date-time":
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04:05 -07:00"} {
+			if _, err := time.Parse(layout, value); err == nil {
+				return true
+			}
+		}
+		return false
+	case "email":
+		_, err := mail.ParseAddress(value)
+		return err == nil
+	case "uri":
+		u, err := url.Parse(value)
+		return err == nil && u.Scheme != ""
+	}
+	return true
+}
+
+// containsValue reports whether a scalar node equals one of values
+func containsValue(values []interface{}, node *yaml.Node) bool {
+	if node.Kind != yaml.ScalarNode {
+		return false
+	}
+	for _, v := range values {
+		if fmt.Sprint(v) == node.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// join appends a key to a dotted path
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// describe names a path in messages
+func describe(path string) string {
+	if path == "" {
+		return "document"
+	}
+	return path
+}
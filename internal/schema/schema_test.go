@@ -0,0 +1,50 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	os.WriteFile(path, []byte(`{
+  "type": "object",
+  "required": ["title", "date", "status"],
+  "additionalProperties": false,
+  "properties": {
+    "title": {"type": "string", "minLength": 3},
+    "date": {"type": "string", "format": "date"},
+    "status": {"enum": ["draft", "published"]},
+    "weight": {"type": "integer", "minimum": 0},
+    "tags": {"type": "array", "items": {"type": "string", "pattern": "^[a-z-]+$"}, "uniqueItems": true}
+  }
+}`), 0644)
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte("title: Go\ndate: 2024-13-01\nweight: -1\ntags: [go, Go, go]\nauthor: me\n"), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, v := range s.Validate(&doc) {
+		got = append(got, fmt.Sprintf("%s@%d", v.Path, v.Line))
+	}
+	want := "title@1,date@2,weight@3,tags[1]@4,tags@4,author@5,status@1"
+	if strings.Join(got, ",") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+
+	if v := s.Validate(nil); len(v) != 3 {
+		t.Errorf("empty front matter: got %d violations, want 3", len(v))
+	}
+}
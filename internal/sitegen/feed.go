@@ -0,0 +1,299 @@
+package sitegen
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+)
+
+// Feed formats
+const (
+	FeedRSS  = "rss"
+	FeedAtom = "atom"
+)
+
+// FeedOptions describes a feed and selects its items
+type FeedOptions struct {
+	Title       string
+	Description string
+	Link        string // URL of the site
+	FeedURL     string // URL the feed is published at
+	Author      string
+	Language    string
+	Limit       int  // Maximum number of items, all when 0
+	FullContent bool // Include the rendered page instead of only its description
+}
+
+// feedItem is a page of a feed with its rendered content
+type feedItem struct {
+	Page
+	Content string
+}
+
+// feedItems returns the dated pages newest first, limited to opts.Limit
+func feedItems(pages []Page, opts FeedOptions) ([]feedItem, error) {
+	var dated []Page
+	for _, page := range pages {
+		if !page.Date.IsZero() {
+			dated = append(dated, page)
+		}
+	}
+	sort.SliceStable(dated, func(i, j int) bool { return dated[i].Date.After(dated[j].Date) })
+	if opts.Limit > 0 && len(dated) > opts.Limit {
+		dated = dated[:opts.Limit]
+	}
+
+	items := make([]feedItem, 0, len(dated))
+	for _, page := range dated {
+		item := feedItem{Page: page}
+		if opts.FullContent {
+			content, err := renderHTML(page)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render %s: %v", page.Path, err)
+			}
+			item.Content = content
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// renderHTML renders the body of a page, relative links and images are
+// resolved against the page URL as feed readers show the content elsewhere
+func renderHTML(page Page) (string, error) {
+	base, err := url.Parse(page.URL)
+	if err != nil {
+		return "", err
+	}
+	resolve := func(dest []byte) []byte {
+		ref, err := url.Parse(string(dest))
+		if err != nil || ref.IsAbs() || strings.HasPrefix(string(dest), "#") {
+			return dest
+		}
+		return []byte(base.ResolveReference(ref).String())
+	}
+
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	source := []byte(page.Body)
+	doc := md.Parser().Parse(text.NewReader(source))
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch v := n.(type) {
+		case *ast.Link:
+			v.Destination = resolve(v.Destination)
+		case *ast.Image:
+			v.Destination = resolve(v.Destination)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, source, doc); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// feedUpdated returns the newest date of the items, so feeds of unchanged
+// pages are identical across builds
+func feedUpdated(items []feedItem) time.Time {
+	var updated time.Time
+	for _, item := range items {
+		if item.Updated.After(updated) {
+			updated = item.Updated
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Unix(0, 0).UTC()
+	}
+	return updated
+}
+
+// Feed renders the dated pages as an RSS 2.0 or Atom 1.0 feed
+func Feed(format string, pages []Page, opts FeedOptions) ([]byte, error) {
+	items, err := feedItems(pages, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	switch format {
+	case FeedRSS:
+		doc = rssFeed(items, opts)
+	case FeedAtom:
+		doc = atomFeed(items, opts)
+	default:
+		return nil, fmt.Errorf("unsupported feed format: %s (must be rss or atom)", format)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Content string     `xml:"xmlns:content,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          *atomLink `xml:"atom:link,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Description string   `xml:"description,omitempty"`
+	Content     string   `xml:"content:encoded,omitempty"`
+	Categories  []string `xml:"category"`
+}
+
+// rssFeed builds an RSS 2.0 document
+func rssFeed(items []feedItem, opts FeedOptions) rssDocument {
+	channel := rssChannel{
+		Title:         opts.Title,
+		Link:          opts.Link,
+		Description:   opts.Description,
+		Language:      opts.Language,
+		LastBuildDate: feedUpdated(items).Format(time.RFC1123Z),
+	}
+	if channel.Description == "" {
+		channel.Description = opts.Title
+	}
+	if opts.FeedURL != "" {
+		channel.Self = &atomLink{Href: opts.FeedURL, Rel: "self", Type: "application/rss+xml"}
+	}
+	for _, item := range items {
+		channel.Items = append(channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.URL,
+			GUID:        item.URL,
+			PubDate:     item.Date.Format(time.RFC1123Z),
+			Description: item.Description,
+			Content:     item.Content,
+			Categories:  item.Tags,
+		})
+	}
+	return rssDocument{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Content: "http://purl.org/rss/1.0/modules/content/",
+		Channel: channel,
+	}
+}
+
+type atomDocument struct {
+	XMLName  xml.Name    `xml:"feed"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Lang     string      `xml:"xml:lang,attr,omitempty"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	ID       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   *atomAuthor `xml:"author,omitempty"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Text string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     *atomAuthor    `xml:"author,omitempty"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+// atomFeed builds an Atom 1.0 document
+func atomFeed(items []feedItem, opts FeedOptions) atomDocument {
+	doc := atomDocument{
+		Xmlns:    "http://www.w3.org/2005/Atom",
+		Lang:     opts.Language,
+		Title:    opts.Title,
+		Subtitle: opts.Description,
+		ID:       opts.Link,
+		Updated:  feedUpdated(items).Format(time.RFC3339),
+		Links:    []atomLink{{Href: opts.Link, Rel: "alternate", Type: "text/html"}},
+	}
+	if opts.FeedURL != "" {
+		doc.ID = opts.FeedURL
+		doc.Links = append(doc.Links, atomLink{Href: opts.FeedURL, Rel: "self", Type: "application/atom+xml"})
+	}
+	if opts.Author != "" {
+		// Atom requires an author for the feed or every entry
+		doc.Author = &atomAuthor{Name: opts.Author}
+	}
+	for _, item := range items {
+		entry := atomEntry{
+			Title:     item.Title,
+			ID:        item.URL,
+			Link:      atomLink{Href: item.URL, Rel: "alternate", Type: "text/html"},
+			Published: item.Date.Format(time.RFC3339),
+			Updated:   item.Updated.Format(time.RFC3339),
+		}
+		if author := stringValue(item.FrontMatter["author"]); author != "" {
+			entry.Author = &atomAuthor{Name: author}
+		}
+		if item.Description != "" {
+			entry.Summary = &atomText{Text: item.Description}
+		}
+		if item.Content != "" {
+			entry.Content = &atomText{Type: "html", Text: item.Content}
+		}
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return doc
+}
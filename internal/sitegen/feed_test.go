@@ -0,0 +1,59 @@
+package sitegen
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeed(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	pages := []Page{
+		{Path: "a.md", URL: "https://example.com/posts/a/", Title: "First", Date: day(1), Updated: day(1), Body: "Hello"},
+		{Path: "b.md", URL: "https://example.com/posts/b/", Title: "Second & more", Description: "Two", Date: day(5), Updated: day(6),
+			Tags: []string{"go"}, FrontMatter: map[string]interface{}{"author": "Ada"}, Body: "![x](img/x.png) [a](../a/) [top](#top)"},
+		{Path: "index.md", URL: "https://example.com/posts/", Title: "Posts"},
+	}
+	opts := FeedOptions{Title: "Blog", Link: "https://example.com/posts", FeedURL: "https://example.com/posts/feed.xml", Limit: 1, FullContent: true}
+
+	rss, err := Feed(FeedRSS, pages, opts)
+	if err != nil {
+		t.Fatalf("RSS failed: %v", err)
+	}
+	var doc rssDocument
+	if err := xml.Unmarshal(rss, &doc); err != nil {
+		t.Fatalf("invalid RSS: %v\n%s", err, rss)
+	}
+	if len(doc.Channel.Items) != 1 || doc.Channel.Items[0].Title != "Second & more" {
+		t.Fatalf("expected the newest post only, got %+v", doc.Channel.Items)
+	}
+	if doc.Channel.LastBuildDate != day(6).Format(time.RFC1123Z) {
+		t.Errorf("unexpected build date: %s", doc.Channel.LastBuildDate)
+	}
+	for _, want := range []string{`src="https://example.com/posts/b/img/x.png"`, `href="https://example.com/posts/a/"`, `href="#top"`} {
+		if !strings.Contains(string(rss), strings.ReplaceAll(strings.ReplaceAll(want, "<", "&lt;"), `"`, "&#34;")) {
+			t.Errorf("expected %s in the content: %s", want, rss)
+		}
+	}
+
+	opts.Limit = 0
+	opts.FullContent = false
+	atom, err := Feed(FeedAtom, pages, opts)
+	if err != nil {
+		t.Fatalf("Atom failed: %v", err)
+	}
+	for _, want := range []string{`<feed xmlns="http://www.w3.org/2005/Atom">`, `<id>https://example.com/posts/feed.xml</id>`,
+		`<published>2024-03-01T00:00:00Z</published>`, `<name>Ada</name>`, `<category term="go"></category>`} {
+		if !strings.Contains(string(atom), want) {
+			t.Errorf("expected %s in %s", want, atom)
+		}
+	}
+	if strings.Contains(string(atom), "<content") || strings.Count(string(atom), "<entry>") != 2 {
+		t.Errorf("expected two entries without content: %s", atom)
+	}
+
+	if _, err := Feed("json", pages, opts); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,257 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Index page formats
+const (
+	IndexMarkdown = "markdown" // Nested markdown list of links
+	IndexMkDocs   = "mkdocs"   // MkDocs nav snippet
+)
+
+// Markers delimiting the generated index inside an existing file, only the
+// part between them is replaced
+const (
+	indexStartMarker = "<!-- mdctl:index -->"
+	indexEndMarker   = "<!-- /mdctl:index -->"
+)
+
+// IndexEntry is a page or a directory of an index
+type IndexEntry struct {
+	Title    string        `json:"title"`
+	Path     string        `json:"path,omitempty"` // Page, or index page of a directory, relative to the root
+	Link     string        `json:"link,omitempty"` // Path relative to the file the index is written to
+	Children []*IndexEntry `json:"children,omitempty"`
+}
+
+// IndexOptions controls the rendering of an index
+type IndexOptions struct {
+	Format   string // IndexMarkdown (default) or IndexMkDocs, ignored with Template
+	Title    string // Heading above the markdown list
+	Depth    int    // Maximum directory depth, all levels when 0
+	Template string // text/template receiving .Title and .Entries instead of a built-in format
+	Output   string // Path of the index relative to the root, links are relative to it
+}
+
+// IndexTree groups pages by directory, leaving out the page at skip. A
+// directory's index.md, README.md or _index.md page gives it its title and
+// link, entries are sorted by title with directories after the pages of
+// their parent.
+func IndexTree(pages []Page, skip string) []*IndexEntry {
+	root := &IndexEntry{}
+	dirs := map[string]*IndexEntry{"": root}
+
+	var dirFor func(dir string) *IndexEntry
+	dirFor = func(dir string) *IndexEntry {
+		if entry, ok := dirs[dir]; ok {
+			return entry
+		}
+		parent := dirFor(parentDir(dir))
+		entry := &IndexEntry{Title: path.Base(dir), Children: []*IndexEntry{}}
+		parent.Children = append(parent.Children, entry)
+		dirs[dir] = entry
+		return entry
+	}
+
+	for _, page := range pages {
+		if page.Path == skip {
+			continue
+		}
+		dir := parentDir(page.Path)
+		parent := dirFor(dir)
+		if isIndexName(path.Base(page.Path)) && dir != "" && parent.Path == "" {
+			parent.Title, parent.Path = page.Title, page.Path
+			continue
+		}
+		parent.Children = append(parent.Children, &IndexEntry{Title: page.Title, Path: page.Path})
+	}
+
+	var sortEntries func(entries []*IndexEntry)
+	sortEntries = func(entries []*IndexEntry) {
+		sort.SliceStable(entries, func(i, j int) bool {
+			a, b := entries[i], entries[j]
+			if (a.Children == nil) != (b.Children == nil) {
+				return a.Children == nil
+			}
+			if a.Children == nil && isIndexName(path.Base(a.Path)) != isIndexName(path.Base(b.Path)) {
+				return isIndexName(path.Base(a.Path))
+			}
+			return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+		})
+		for _, entry := range entries {
+			sortEntries(entry.Children)
+		}
+	}
+	sortEntries(root.Children)
+	return root.Children
+}
+
+// RenderIndex renders an index tree
+func RenderIndex(entries []*IndexEntry, opts IndexOptions) ([]byte, error) {
+	entries = limitDepth(entries, opts.Depth)
+	setLinks(entries, path.Dir(filepath.ToSlash(opts.Output)))
+
+	if opts.Template != "" {
+		tmpl, err := template.New("index").Parse(opts.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index template: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, struct {
+			Title   string
+			Entries []*IndexEntry
+		}{opts.Title, entries}); err != nil {
+			return nil, fmt.Errorf("failed to render index template: %v", err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	switch opts.Format {
+	case "", IndexMarkdown:
+		var b strings.Builder
+		if opts.Title != "" {
+			fmt.Fprintf(&b, "# %s\n\n", opts.Title)
+		}
+		writeMarkdownIndex(&b, entries, 0)
+		return []byte(b.String()), nil
+	case IndexMkDocs:
+		nav, err := yaml.Marshal(map[string]interface{}{"nav": mkdocsNav(entries)})
+		if err != nil {
+			return nil, err
+		}
+		return nav, nil
+	default:
+		return nil, fmt.Errorf("unsupported index format: %s (must be markdown or mkdocs)", opts.Format)
+	}
+}
+
+// writeMarkdownIndex writes the entries as a nested list of links
+func writeMarkdownIndex(b *strings.Builder, entries []*IndexEntry, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, entry := range entries {
+		if entry.Link != "" {
+			fmt.Fprintf(b, "%s- [%s](%s)\n", indent, entry.Title, strings.ReplaceAll(entry.Link, " ", "%20"))
+		} else {
+			fmt.Fprintf(b, "%s- %s\n", indent, entry.Title)
+		}
+		writeMarkdownIndex(b, entry.Children, depth+1)
+	}
+}
+
+// mkdocsNav returns the entries as MkDocs nav items, paths are relative to
+// the docs directory. The index page of a section is its first item.
+func mkdocsNav(entries []*IndexEntry) []interface{} {
+	var nav []interface{}
+	for _, entry := range entries {
+		if entry.Children == nil {
+			nav = append(nav, map[string]string{entry.Title: entry.Path})
+			continue
+		}
+		var items []interface{}
+		if entry.Path != "" {
+			items = append(items, entry.Path)
+		}
+		items = append(items, mkdocsNav(entry.Children)...)
+		nav = append(nav, map[string]interface{}{entry.Title: items})
+	}
+	return nav
+}
+
+// limitDepth drops the directories below depth levels, 0 keeps all
+func limitDepth(entries []*IndexEntry, depth int) []*IndexEntry {
+	if depth <= 0 {
+		return entries
+	}
+	var result []*IndexEntry
+	for _, entry := range entries {
+		copied := *entry
+		if entry.Children != nil {
+			if depth == 1 {
+				copied.Children = []*IndexEntry{}
+			} else {
+				copied.Children = limitDepth(entry.Children, depth-1)
+			}
+		}
+		result = append(result, &copied)
+	}
+	return result
+}
+
+// setLinks sets the links of the entries relative to a directory
+func setLinks(entries []*IndexEntry, dir string) {
+	for _, entry := range entries {
+		if entry.Path != "" {
+			rel, err := filepath.Rel(filepath.FromSlash(dir), filepath.FromSlash(entry.Path))
+			if err != nil {
+				rel = entry.Path
+			}
+			entry.Link = filepath.ToSlash(rel)
+		}
+		setLinks(entry.Children, dir)
+	}
+}
+
+// InsertIndex returns content with the index between the index markers. An
+// existing file without markers is replaced, empty content gets the index
+// with markers so later runs only update the index.
+func InsertIndex(content string, index []byte) string {
+	section := indexStartMarker + "\n" + strings.TrimRight(string(index), "\n") + "\n" + indexEndMarker
+	start := strings.Index(content, indexStartMarker)
+	if start < 0 {
+		return section + "\n"
+	}
+	end := strings.Index(content[start:], indexEndMarker)
+	if end < 0 {
+		return section + "\n"
+	}
+	end += start + len(indexEndMarker)
+	return content[:start] + section + content[end:]
+}
+
+// WriteIndexFile writes an index into a file, keeping the content around the
+// markers of an existing file. It reports whether the file changed.
+func WriteIndexFile(file string, index []byte, markers bool) (bool, error) {
+	existing, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read %s: %v", file, err)
+	}
+	content := string(index)
+	if markers {
+		content = InsertIndex(string(existing), index)
+	}
+	if content == string(existing) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		return false, fmt.Errorf("failed to write %s: %v", file, err)
+	}
+	return true, nil
+}
+
+// parentDir returns the directory of a slash-separated path, "" at the root
+func parentDir(p string) string {
+	dir := path.Dir(p)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// isIndexName reports whether a file name is the index page of its directory
+func isIndexName(name string) bool {
+	base := strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))
+	return base == "index" || base == "readme" || base == "_index"
+}
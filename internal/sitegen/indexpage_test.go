@@ -0,0 +1,115 @@
+package sitegen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func indexTestPages(t *testing.T) []Page {
+	dir := t.TempDir()
+	files := map[string]string{
+		"README.md":              "# Home\n",
+		"about.md":               "# About\n",
+		"guide/README.md":        "# The Guide\n",
+		"guide/install.md":       "---\ntitle: Install Guide\n---\n# Ignored\n",
+		"guide/advanced/deep.md": "# Deep Dive\n",
+		"blog/post.md":           "# Post\n",
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	pages, err := Collect(dir, Options{})
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	return pages
+}
+
+func TestIndexTree(t *testing.T) {
+	tree := IndexTree(indexTestPages(t), "README.md")
+
+	var titles []string
+	for _, entry := range tree {
+		titles = append(titles, entry.Title)
+	}
+	if got := strings.Join(titles, ","); got != "About,blog,The Guide" {
+		t.Fatalf("unexpected top level entries: %s", got)
+	}
+	guide := tree[2]
+	if guide.Path != "guide/README.md" || len(guide.Children) != 2 {
+		t.Fatalf("unexpected guide entry: %+v", guide)
+	}
+	if guide.Children[0].Title != "Install Guide" || guide.Children[1].Title != "advanced" {
+		t.Errorf("unexpected guide children: %+v, %+v", guide.Children[0], guide.Children[1])
+	}
+}
+
+func TestRenderIndexMarkdown(t *testing.T) {
+	tree := IndexTree(indexTestPages(t), "guide/README.md")
+	index, err := RenderIndex(tree, IndexOptions{Title: "Docs", Output: "guide/README.md"})
+	if err != nil {
+		t.Fatalf("RenderIndex failed: %v", err)
+	}
+	want := "# Docs\n\n" +
+		"- [Home](../README.md)\n" +
+		"- [About](../about.md)\n" +
+		"- blog\n" +
+		"  - [Post](../blog/post.md)\n" +
+		"- guide\n" +
+		"  - [Install Guide](install.md)\n" +
+		"  - advanced\n" +
+		"    - [Deep Dive](advanced/deep.md)\n"
+	if string(index) != want {
+		t.Errorf("unexpected index:\n%s", index)
+	}
+
+	index, err = RenderIndex(tree, IndexOptions{Depth: 1, Output: "index.md"})
+	if err != nil {
+		t.Fatalf("RenderIndex failed: %v", err)
+	}
+	if strings.Contains(string(index), "Install Guide") || !strings.Contains(string(index), "- guide\n") {
+		t.Errorf("depth 1 should only list top level entries:\n%s", index)
+	}
+}
+
+func TestRenderIndexMkDocs(t *testing.T) {
+	tree := IndexTree(indexTestPages(t), "")
+	nav, err := RenderIndex(tree, IndexOptions{Format: IndexMkDocs})
+	if err != nil {
+		t.Fatalf("RenderIndex failed: %v", err)
+	}
+	for _, want := range []string{"nav:\n", "- Home: README.md", "- The Guide:\n", "- guide/README.md", "- Install Guide: guide/install.md", "- Deep Dive: guide/advanced/deep.md"} {
+		if !strings.Contains(string(nav), want) {
+			t.Errorf("nav is missing %q:\n%s", want, nav)
+		}
+	}
+}
+
+func TestRenderIndexTemplate(t *testing.T) {
+	tree := IndexTree(indexTestPages(t), "")
+	out, err := RenderIndex(tree, IndexOptions{Title: "Pages", Template: "{{.Title}}:{{range .Entries}} {{.Title}}{{end}}"})
+	if err != nil {
+		t.Fatalf("RenderIndex failed: %v", err)
+	}
+	if string(out) != "Pages: Home About blog The Guide" {
+		t.Errorf("unexpected output: %s", out)
+	}
+	if _, err := RenderIndex(tree, IndexOptions{Format: "hugo"}); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+func TestInsertIndex(t *testing.T) {
+	content := "Intro\n\n<!-- mdctl:index -->\n- old\n<!-- /mdctl:index -->\n\nFooter\n"
+	got := InsertIndex(content, []byte("- new\n"))
+	want := "Intro\n\n<!-- mdctl:index -->\n- new\n<!-- /mdctl:index -->\n\nFooter\n"
+	if got != want {
+		t.Errorf("unexpected content:\n%s", got)
+	}
+	if got := InsertIndex("", []byte("- new\n")); got != "<!-- mdctl:index -->\n- new\n<!-- /mdctl:index -->\n" {
+		t.Errorf("unexpected content for a new file:\n%s", got)
+	}
+}
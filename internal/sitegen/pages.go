@@ -0,0 +1,289 @@
+// Package sitegen generates the artifacts of a published site, such as
+// sitemaps and feeds, from the markdown tree the site is built from
+package sitegen
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"gopkg.in/yaml.v3"
+)
+
+// URL styles of the pages
+const (
+	URLStyleDirectory = "dir"  // guide/install.md is published at guide/install/
+	URLStyleHTML      = "html" // guide/install.md is published at guide/install.html
+)
+
+// skipDirs are never descended into while collecting pages
+var skipDirs = map[string]bool{
+	".git":         true,
+	".mdctl":       true,
+	"node_modules": true,
+}
+
+// dateLayouts are the front matter date formats understood besides YAML timestamps
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Options controls which pages are collected and where they are published
+type Options struct {
+	BaseURL  string   // URL the root of the tree is published at, / when empty
+	URLStyle string   // URLStyleDirectory (default) or URLStyleHTML
+	Include  []string // Globs of the files to collect, relative to the root
+	Exclude  []string // Globs of the files to skip, relative to the root
+	Drafts   bool     // Collect pages with draft: true in their front matter
+}
+
+// Page is a markdown file of the tree and its front matter
+type Page struct {
+	Path        string                 `json:"path"` // Relative to the root, with forward slashes
+	URL         string                 `json:"url"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description,omitempty"`
+	Date        time.Time              `json:"date,omitempty"`    // Publication date
+	Updated     time.Time              `json:"updated,omitempty"` // Last modification, the publication date if unknown
+	Tags        []string               `json:"tags,omitempty"`
+	FrontMatter map[string]interface{} `json:"-"`
+	Body        string                 `json:"-"` // Markdown without front matter
+}
+
+// Collect reads the markdown files below root, sorted by path. Pages with
+// draft: true in their front matter stay out unless opts.Drafts is set.
+func Collect(root string, opts Options) ([]Page, error) {
+	// Without base URL the pages get root-relative URLs
+	base := &url.URL{}
+	if opts.BaseURL != "" {
+		var err error
+		base, err = url.Parse(strings.TrimSpace(opts.BaseURL))
+		if err != nil || base.Scheme == "" || base.Host == "" {
+			return nil, fmt.Errorf("invalid base URL: %q", opts.BaseURL)
+		}
+	}
+	if opts.URLStyle == "" {
+		opts.URLStyle = URLStyleDirectory
+	}
+	if opts.URLStyle != URLStyleDirectory && opts.URLStyle != URLStyleHTML {
+		return nil, fmt.Errorf("unsupported URL style: %s (must be dir or html)", opts.URLStyle)
+	}
+	include, err := fsutil.CompileGlobs(opts.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := fsutil.CompileGlobs(opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []Page
+	err = filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if file != root && skipDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !mddoc.IsMarkdown(file) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if len(include) > 0 && !fsutil.MatchAny(include, rel) || fsutil.MatchAny(exclude, rel) {
+			return nil
+		}
+
+		page, err := readPage(file, rel)
+		if err != nil {
+			return err
+		}
+		if isTrue(page.FrontMatter["draft"]) && !opts.Drafts {
+			return nil
+		}
+		page.URL = pageURL(base, rel, page.FrontMatter, opts.URLStyle)
+		pages = append(pages, page)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Path < pages[j].Path })
+	return pages, nil
+}
+
+// Hidden reports whether a page asks to stay out of sitemaps and search
+// indexes with noindex: true, sitemap: false or Hugo's sitemap.disable
+func (p Page) Hidden() bool {
+	switch v := p.FrontMatter["sitemap"].(type) {
+	case bool:
+		if !v {
+			return true
+		}
+	case map[string]interface{}:
+		if isTrue(v["disable"]) {
+			return true
+		}
+	}
+	return isTrue(p.FrontMatter["noindex"])
+}
+
+// readPage reads the front matter and body of a markdown file
+func readPage(file, rel string) (Page, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return Page{}, fmt.Errorf("failed to read file %s: %v", file, err)
+	}
+	frontMatter, body := mddoc.SplitFrontMatter(string(content))
+
+	page := Page{Path: rel, Body: body, FrontMatter: map[string]interface{}{}}
+	if frontMatter != "" {
+		if err := yaml.Unmarshal([]byte(frontMatter), &page.FrontMatter); err != nil {
+			return Page{}, fmt.Errorf("failed to parse front matter of %s: %v", file, err)
+		}
+		if page.FrontMatter == nil {
+			page.FrontMatter = map[string]interface{}{}
+		}
+	}
+
+	page.Title = stringValue(page.FrontMatter["title"])
+	if page.Title == "" {
+		page.Title = firstHeading(body)
+	}
+	if page.Title == "" {
+		page.Title = strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+	}
+	page.Description = stringValue(page.FrontMatter["description"])
+	if page.Description == "" {
+		page.Description = stringValue(page.FrontMatter["summary"])
+	}
+	page.Date = firstDate(page.FrontMatter, "date", "pubDate", "published")
+	page.Updated = firstDate(page.FrontMatter, "lastmod", "updated", "last_modified", "modified")
+	if page.Updated.IsZero() {
+		page.Updated = page.Date
+	}
+	page.Tags = stringList(page.FrontMatter["tags"])
+	return page, nil
+}
+
+// pageURL returns the published URL of a file. The front matter can set the
+// path with url or permalink, or the last path element with slug.
+func pageURL(base *url.URL, rel string, frontMatter map[string]interface{}, style string) string {
+	if u := stringValue(frontMatter["url"]); u != "" {
+		return resolveURL(base, u)
+	}
+	if u := stringValue(frontMatter["permalink"]); u != "" {
+		return resolveURL(base, u)
+	}
+
+	dir, name := path.Split(rel)
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if slug := stringValue(frontMatter["slug"]); slug != "" {
+		name = slug
+	}
+
+	var p string
+	switch {
+	case strings.EqualFold(name, "index") || strings.EqualFold(name, "readme") || name == "_index":
+		p = dir
+	case style == URLStyleHTML:
+		p = dir + name + ".html"
+	default:
+		p = dir + name + "/"
+	}
+	return resolveURL(base, p)
+}
+
+// resolveURL returns a path below the base URL, absolute paths stay below it as well
+func resolveURL(base *url.URL, p string) string {
+	if strings.Contains(p, "://") {
+		return p
+	}
+	u := *base
+	u.Path = strings.TrimSuffix(base.Path, "/") + "/" + strings.TrimPrefix(p, "/")
+	return u.String()
+}
+
+// firstHeading returns the text of the first ATX heading of a body
+func firstHeading(body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "# ") {
+			return strings.TrimSpace(strings.TrimRight(strings.TrimSpace(line[2:]), "#"))
+		}
+	}
+	return ""
+}
+
+// firstDate returns the first front matter field holding a date
+func firstDate(frontMatter map[string]interface{}, keys ...string) time.Time {
+	for _, key := range keys {
+		switch v := frontMatter[key].(type) {
+		case time.Time:
+			return v
+		case string:
+			for _, layout := range dateLayouts {
+				if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+					return t
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// stringValue returns a front matter value as a string
+func stringValue(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return strings.TrimSpace(s)
+	case nil:
+		return ""
+	default:
+		return strings.TrimSpace(fmt.Sprint(s))
+	}
+}
+
+// stringList returns a front matter list, a single string is a one-element list
+func stringList(v interface{}) []string {
+	switch l := v.(type) {
+	case []interface{}:
+		var result []string
+		for _, item := range l {
+			if s := stringValue(item); s != "" {
+				result = append(result, s)
+			}
+		}
+		return result
+	case string:
+		if s := strings.TrimSpace(l); s != "" {
+			return []string{s}
+		}
+	}
+	return nil
+}
+
+// isTrue reports whether a front matter value is true
+func isTrue(v interface{}) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
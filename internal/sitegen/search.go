@@ -0,0 +1,281 @@
+package sitegen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/samzong/mdctl/internal/netutil"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Search engines documents can be pushed to
+const (
+	SearchMeilisearch = "meilisearch"
+	SearchTypesense   = "typesense"
+)
+
+var (
+	searchIDRegex = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+	spaceRegex    = regexp.MustCompile(`\s+`)
+)
+
+// SearchDocument is a page of a search index. The field names work with
+// lunr.js and Fuse.js as they are and as the schema of a search engine.
+type SearchDocument struct {
+	ID          string   `json:"id"`
+	URL         string   `json:"url"`
+	Path        string   `json:"path"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Headings    []string `json:"headings,omitempty"`
+	Text        string   `json:"text"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// SearchTarget is a search engine index documents are pushed to
+type SearchTarget struct {
+	Engine   string // SearchMeilisearch or SearchTypesense
+	Endpoint string // URL of the engine
+	APIKey   string
+	Index    string       // Meilisearch index or Typesense collection, created if missing
+	Client   *http.Client // A client with a 60 second timeout when nil
+}
+
+// SearchDocuments extracts the title, headings and plain text of the pages,
+// pages hidden with noindex: true are left out
+func SearchDocuments(pages []Page) []SearchDocument {
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	docs := make([]SearchDocument, 0, len(pages))
+	for _, page := range pages {
+		if page.Hidden() {
+			continue
+		}
+		headings, body := plainText(md, page.Body)
+		// The title heading is already the title
+		if len(headings) > 0 && headings[0] == page.Title {
+			headings = headings[1:]
+		}
+		docs = append(docs, SearchDocument{
+			ID:          searchID(page.Path),
+			URL:         page.URL,
+			Path:        page.Path,
+			Title:       page.Title,
+			Description: page.Description,
+			Headings:    headings,
+			Text:        body,
+			Tags:        page.Tags,
+		})
+	}
+	return docs
+}
+
+// searchID returns a document ID from a path, restricted to the characters
+// Meilisearch accepts
+func searchID(p string) string {
+	return strings.Trim(searchIDRegex.ReplaceAllString(p, "-"), "-")
+}
+
+// plainText returns the headings and the prose of a markdown body. Code
+// blocks and raw HTML carry little searchable text and are skipped.
+func plainText(md goldmark.Markdown, body string) ([]string, string) {
+	source := []byte(body)
+	doc := md.Parser().Parse(text.NewReader(source))
+
+	var headings []string
+	var b strings.Builder
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch v := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock, *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		case *ast.Heading:
+			if entering {
+				headings = append(headings, collapseSpace(nodeText(v, source)))
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			if entering {
+				b.Write(v.Segment.Value(source))
+				if v.SoftLineBreak() || v.HardLineBreak() {
+					b.WriteByte(' ')
+				}
+			}
+		case *ast.String:
+			if entering {
+				b.Write(v.Value)
+			}
+		case *ast.Paragraph, *ast.ListItem, *extast.TableCell:
+			// Blocks are separated so their words do not run together
+			if !entering {
+				b.WriteByte(' ')
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return headings, collapseSpace(b.String())
+}
+
+// nodeText returns the text of the inline children of a node
+func nodeText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch v := c.(type) {
+		case *ast.Text:
+			b.Write(v.Segment.Value(source))
+		case *ast.String:
+			b.Write(v.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// collapseSpace trims a string and collapses its whitespace runs to single spaces
+func collapseSpace(s string) string {
+	return strings.TrimSpace(spaceRegex.ReplaceAllString(s, " "))
+}
+
+// PushSearchDocuments adds the documents to a search engine index, replacing
+// documents with the same ID
+func PushSearchDocuments(ctx context.Context, target SearchTarget, docs []SearchDocument) error {
+	if target.Endpoint == "" {
+		return fmt.Errorf("no endpoint given for %s", target.Engine)
+	}
+	if target.Index == "" {
+		return fmt.Errorf("no index name given for %s", target.Engine)
+	}
+	if target.Client == nil {
+		target.Client = netutil.NewClient(60 * time.Second)
+	}
+	target.Endpoint = strings.TrimSuffix(target.Endpoint, "/")
+
+	switch target.Engine {
+	case SearchMeilisearch:
+		return pushMeilisearch(ctx, target, docs)
+	case SearchTypesense:
+		return pushTypesense(ctx, target, docs)
+	default:
+		return fmt.Errorf("unsupported search engine: %s (must be meilisearch or typesense)", target.Engine)
+	}
+}
+
+// pushMeilisearch adds the documents to a Meilisearch index, which Meilisearch
+// creates on the first documents it receives. The update is processed
+// asynchronously by Meilisearch.
+func pushMeilisearch(ctx context.Context, target SearchTarget, docs []SearchDocument) error {
+	data, err := json.Marshal(docs)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/indexes/%s/documents?primaryKey=id", target.Endpoint, url.PathEscape(target.Index))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if target.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+target.APIKey)
+	}
+	return sendSearchRequest(target.Client, req, "meilisearch")
+}
+
+// pushTypesense imports the documents into a Typesense collection, creating
+// the collection with an auto-detected schema if it does not exist
+func pushTypesense(ctx context.Context, target SearchTarget, docs []SearchDocument) error {
+	var body bytes.Buffer
+	for _, doc := range docs {
+		line, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		body.Write(line)
+		body.WriteByte('\n')
+	}
+
+	importDocs := func() (int, []byte, error) {
+		endpoint := fmt.Sprintf("%s/collections/%s/documents/import?action=upsert", target.Endpoint, url.PathEscape(target.Index))
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body.Bytes()))
+		if err != nil {
+			return 0, nil, err
+		}
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("X-TYPESENSE-API-KEY", target.APIKey)
+		resp, err := target.Client.Do(req)
+		if err != nil {
+			return 0, nil, fmt.Errorf("typesense request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		result, err := io.ReadAll(resp.Body)
+		return resp.StatusCode, result, err
+	}
+
+	status, result, err := importDocs()
+	if err != nil {
+		return err
+	}
+	if status == http.StatusNotFound {
+		schema, _ := json.Marshal(map[string]interface{}{
+			"name":   target.Index,
+			"fields": []map[string]string{{"name": ".*", "type": "auto"}},
+		})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.Endpoint+"/collections", bytes.NewReader(schema))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-TYPESENSE-API-KEY", target.APIKey)
+		if err := sendSearchRequest(target.Client, req, "typesense"); err != nil {
+			return fmt.Errorf("failed to create collection %s: %v", target.Index, err)
+		}
+		if status, result, err = importDocs(); err != nil {
+			return err
+		}
+	}
+	if status >= 300 {
+		return fmt.Errorf("typesense returned %d: %s", status, strings.TrimSpace(string(result)))
+	}
+
+	// The import reports the result of every document on its own line
+	var failed []string
+	for _, line := range strings.Split(strings.TrimSpace(string(result)), "\n") {
+		var r struct {
+			Success bool   `json:"success"`
+			Error   string `json:"error"`
+		}
+		if json.Unmarshal([]byte(line), &r) == nil && !r.Success {
+			failed = append(failed, r.Error)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("typesense rejected %d documents: %s", len(failed), failed[0])
+	}
+	return nil
+}
+
+// sendSearchRequest sends a request and turns error statuses into errors
+func sendSearchRequest(client *http.Client, req *http.Request, engine string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %v", engine, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", engine, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package sitegen
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSearchDocuments(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"guide/install.md": "---\ntags: [setup]\n---\n# Install\n\nRun the *installer*\nonce.\n\n## Requirements\n\n- Go 1.21\n- Pandoc\n\n```sh\nmake secret\n```\n\n<div>raw html</div>\n",
+		"hidden.md":        "---\nnoindex: true\n---\n# Hidden\n",
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+
+	pages, err := Collect(dir, Options{})
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	docs := SearchDocuments(pages)
+	if len(docs) != 1 {
+		t.Fatalf("expected 1 document, got %+v", docs)
+	}
+	doc := docs[0]
+	if doc.ID != "guide-install-md" || doc.URL != "/guide/install/" || doc.Title != "Install" {
+		t.Errorf("unexpected document: %+v", doc)
+	}
+	if len(doc.Headings) != 1 || doc.Headings[0] != "Requirements" {
+		t.Errorf("unexpected headings: %v", doc.Headings)
+	}
+	if doc.Text != "Run the installer once. Go 1.21 Pandoc" {
+		t.Errorf("unexpected text: %q", doc.Text)
+	}
+	if len(doc.Tags) != 1 || doc.Tags[0] != "setup" {
+		t.Errorf("unexpected tags: %v", doc.Tags)
+	}
+}
+
+func TestPushSearchDocuments(t *testing.T) {
+	docs := []SearchDocument{{ID: "index-md", URL: "/", Path: "index.md", Title: "Home"}}
+
+	var requests []string
+	created := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/indexes/"):
+			if r.Header.Get("Authorization") != "Bearer key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var got []SearchDocument
+			if err := json.Unmarshal(body, &got); err != nil || len(got) != 1 {
+				t.Errorf("unexpected meilisearch body: %s", body)
+			}
+			w.WriteHeader(http.StatusAccepted)
+		case r.URL.Path == "/collections":
+			created = true
+		case strings.HasSuffix(r.URL.Path, "/documents/import"):
+			if r.Header.Get("X-TYPESENSE-API-KEY") != "key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if !created {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			io.WriteString(w, `{"success":true}`)
+		}
+	}))
+	defer server.Close()
+
+	for _, engine := range []string{SearchMeilisearch, SearchTypesense} {
+		target := SearchTarget{Engine: engine, Endpoint: server.URL + "/", APIKey: "key", Index: "docs"}
+		if err := PushSearchDocuments(context.Background(), target, docs); err != nil {
+			t.Errorf("push to %s failed: %v", engine, err)
+		}
+	}
+	want := []string{
+		"POST /indexes/docs/documents?primaryKey=id",
+		"POST /collections/docs/documents/import?action=upsert",
+		"POST /collections",
+		"POST /collections/docs/documents/import?action=upsert",
+	}
+	if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected requests:\n%s", strings.Join(requests, "\n"))
+	}
+
+	target := SearchTarget{Engine: "solr", Endpoint: server.URL, Index: "docs"}
+	if err := PushSearchDocuments(context.Background(), target, docs); err == nil {
+		t.Error("expected an error for an unsupported engine")
+	}
+}
@@ -0,0 +1,67 @@
+package sitegen
+
+import (
+	"bytes"
+	"encoding/xml"
+	"time"
+)
+
+// sitemapNamespace is the XML namespace of the sitemap protocol
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// SitemapEntry is a URL of a sitemap
+type SitemapEntry struct {
+	Loc     string `xml:"loc" json:"url"`
+	Lastmod string `xml:"lastmod,omitempty" json:"lastmod,omitempty"`
+	Path    string `xml:"-" json:"path"`
+	Title   string `xml:"-" json:"title"`
+}
+
+// SitemapEntries returns the sitemap entries of the pages that are not
+// hidden, with the last modification date of their front matter
+func SitemapEntries(pages []Page) []SitemapEntry {
+	entries := make([]SitemapEntry, 0, len(pages))
+	for _, page := range pages {
+		if page.Hidden() {
+			continue
+		}
+		entries = append(entries, SitemapEntry{
+			Loc:     page.URL,
+			Lastmod: formatLastmod(page.Updated),
+			Path:    page.Path,
+			Title:   page.Title,
+		})
+	}
+	return entries
+}
+
+// Sitemap renders entries as a sitemap.xml document
+func Sitemap(entries []SitemapEntry) ([]byte, error) {
+	urlset := struct {
+		XMLName xml.Name       `xml:"urlset"`
+		Xmlns   string         `xml:"xmlns,attr"`
+		URLs    []SitemapEntry `xml:"url"`
+	}{Xmlns: sitemapNamespace, URLs: entries}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(urlset); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// formatLastmod formats a date in W3C datetime format, dates without time of
+// day are written as a plain date
+func formatLastmod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Location() == time.UTC {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,76 @@
+package sitegen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCollectAndSitemap(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.md":            "# Home\n",
+		"guide/install.md":    "---\ntitle: Install\nlastmod: 2024-05-01\n---\n# Installing\n",
+		"guide/README.md":     "---\nupdated: \"2024-06-02T10:00:00+02:00\"\n---\n# Guide\n",
+		"blog/post.md":        "---\ndate: 2024-01-15\nslug: hello-world\ntags: [go, docs]\n---\n",
+		"blog/draft.md":       "---\ndraft: true\n---\n",
+		"private.md":          "---\nsitemap: false\n---\n",
+		"custom.md":           "---\nurl: /about/\n---\n",
+		"node_modules/dep.md": "# Dependency\n",
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+
+	pages, err := Collect(dir, Options{BaseURL: "https://docs.example.com/v1/"})
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	var paths []string
+	for _, page := range pages {
+		paths = append(paths, page.Path)
+	}
+	if got := strings.Join(paths, ","); got != "blog/post.md,custom.md,guide/README.md,guide/install.md,index.md,private.md" {
+		t.Errorf("unexpected pages: %s", got)
+	}
+	if pages[0].Title != "post" || len(pages[0].Tags) != 2 {
+		t.Errorf("unexpected page: %+v", pages[0])
+	}
+
+	entries := SitemapEntries(pages)
+	want := map[string]string{
+		"https://docs.example.com/v1/blog/hello-world/": "2024-01-15",
+		"https://docs.example.com/v1/about/":            "",
+		"https://docs.example.com/v1/guide/":            "2024-06-02T10:00:00+02:00",
+		"https://docs.example.com/v1/guide/install/":    "2024-05-01",
+		"https://docs.example.com/v1/":                  "",
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %v", len(want), entries)
+	}
+	for _, entry := range entries {
+		lastmod, ok := want[entry.Loc]
+		if !ok || lastmod != entry.Lastmod {
+			t.Errorf("unexpected entry %+v", entry)
+		}
+	}
+
+	sitemap, err := Sitemap(entries)
+	if err != nil {
+		t.Fatalf("Sitemap failed: %v", err)
+	}
+	if !strings.Contains(string(sitemap), `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`) ||
+		!strings.Contains(string(sitemap), "<loc>https://docs.example.com/v1/guide/install/</loc>") {
+		t.Errorf("unexpected sitemap: %s", sitemap)
+	}
+
+	pages, _ = Collect(dir, Options{BaseURL: "https://docs.example.com", URLStyle: URLStyleHTML, Include: []string{"guide/**"}})
+	if len(pages) != 2 || pages[1].URL != "https://docs.example.com/guide/install.html" {
+		t.Errorf("unexpected pages with html URLs: %+v", pages)
+	}
+	if _, err := Collect(dir, Options{BaseURL: "docs.example.com"}); err == nil {
+		t.Error("expected an error for a base URL without scheme")
+	}
+}
@@ -0,0 +1,322 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/netutil"
+)
+
+// newHTTPClient returns the HTTP client of a provider, honoring the TLS
+// settings of the configuration and the proxy
+func newHTTPClient(cfg config.CloudConfig) (*http.Client, error) {
+	httpClient := netutil.NewClient(time.Second * 30)
+
+	// Set up custom transport if needed
+	if cfg.SkipVerify || cfg.CACertPath != "" {
+		// Start with the shared transport, so the proxy settings still apply
+		transport := netutil.Transport().Clone()
+		transport.TLSHandshakeTimeout = 10 * time.Second
+
+		// Configure TLS
+		tlsConfig := &tls.Config{}
+
+		// Skip certificate verification if requested
+		if cfg.SkipVerify {
+			tlsConfig.InsecureSkipVerify = true
+		}
+
+		// Load custom CA certificate if provided
+		if cfg.CACertPath != "" {
+			rootCAs, _ := x509.SystemCertPool()
+			if rootCAs == nil {
+				rootCAs = x509.NewCertPool()
+			}
+
+			certs, err := os.ReadFile(cfg.CACertPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA cert: %v", err)
+			}
+
+			if ok := rootCAs.AppendCertsFromPEM(certs); !ok {
+				return nil, fmt.Errorf("failed to append CA cert")
+			}
+
+			tlsConfig.RootCAs = rootCAs
+		}
+
+		transport.TLSClientConfig = tlsConfig
+		httpClient.Transport = transport
+	}
+
+	return httpClient, nil
+}
+
+// objectKey returns the object key of a remote path below the path prefix,
+// with forward slashes whatever the local path separator is
+func objectKey(pathPrefix, remotePath string) string {
+	pathPrefix = strings.ReplaceAll(pathPrefix, `\`, "/")
+	remotePath = strings.ReplaceAll(remotePath, `\`, "/")
+	if pathPrefix != "" && !strings.HasPrefix(remotePath, pathPrefix) {
+		remotePath = fsutil.RemotePath(pathPrefix, remotePath)
+	}
+	return strings.TrimPrefix(remotePath, "/")
+}
+
+// escapeKey escapes an object key for use in a URL path
+func escapeKey(key string) string {
+	return (&url.URL{Path: "/" + key}).EscapedPath()
+}
+
+// hostOf strips the scheme and trailing slashes from an endpoint
+func hostOf(endpoint string) string {
+	endpoint = strings.TrimPrefix(endpoint, "https://")
+	endpoint = strings.TrimPrefix(endpoint, "http://")
+	return strings.TrimRight(endpoint, "/")
+}
+
+// signedURLTTL returns how long signed URLs of private buckets stay valid,
+// zero when the bucket is public. It is set with provider_opts.signed_url_ttl.
+func signedURLTTL(cfg config.CloudConfig) (time.Duration, error) {
+	value := cfg.ProviderOpts["signed_url_ttl"]
+	if value == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("invalid signed_url_ttl: %s (e.g. 24h)", value)
+	}
+	return ttl, nil
+}
+
+// hmacSHA1 returns the HMAC-SHA1 of data
+func hmacSHA1(key, data string) []byte {
+	mac := hmac.New(sha1.New, []byte(key))
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// responseError describes a failed request with the start of the response body
+func responseError(action string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("failed to %s: %s: %s", action, resp.Status, strings.TrimSpace(string(body)))
+}
+
+// restStore implements the object operations shared by providers with an
+// S3-like REST API that differ in request signing and header names
+type restStore struct {
+	client     *http.Client
+	baseURL    string // Scheme and host of the bucket
+	bucket     string
+	pathPrefix string
+	headerNS   string                              // Header namespace, e.g. x-oss-
+	sign       func(req *http.Request, key string) // Adds the authorization to a request
+	copySource func(key string) string             // Copy source header value of an object
+	replaceDir string                              // Metadata directive replacing metadata on copy
+}
+
+// do sends a signed request for an object
+func (s *restStore) do(method, key string, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.baseURL+escapeKey(key), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	s.sign(req, key)
+	return s.client.Do(req)
+}
+
+// metadataHeader returns the headers storing user metadata
+func (s *restStore) metadataHeader(metadata map[string]string) http.Header {
+	header := http.Header{}
+	for k, v := range metadata {
+		header.Set(s.headerNS+"meta-"+strings.ToLower(k), v)
+	}
+	return header
+}
+
+// upload stores a local file and returns its object key
+func (s *restStore) upload(localPath, remotePath string, metadata map[string]string) (string, error) {
+	key := objectKey(s.pathPrefix, remotePath)
+
+	file, err := os.Open(fsutil.LongPath(localPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+
+	header := s.metadataHeader(metadata)
+	header.Set("Content-Type", getContentType(localPath))
+	resp, err := s.do(http.MethodPut, key, file, info.Size(), header)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", responseError("upload file", resp)
+	}
+	return key, nil
+}
+
+// head returns the headers of an object, nil when it does not exist
+func (s *restStore) head(remotePath string) (http.Header, error) {
+	resp, err := s.do(http.MethodHead, objectKey(s.pathPrefix, remotePath), nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, responseError("get object", resp)
+	}
+	return resp.Header, nil
+}
+
+// ObjectExists checks if an object exists in the bucket
+func (s *restStore) ObjectExists(remotePath string) (bool, error) {
+	header, err := s.head(remotePath)
+	return header != nil, err
+}
+
+// CompareHash compares a local hash with a remote object's hash
+func (s *restStore) CompareHash(remotePath, localHash string) (bool, error) {
+	header, err := s.head(remotePath)
+	if err != nil {
+		return false, err
+	}
+	if header == nil {
+		return false, fmt.Errorf("object does not exist: %s", remotePath)
+	}
+	if hash := header.Get(s.headerNS + "meta-hash"); hash != "" {
+		return hash == localHash, nil
+	}
+	// The ETag of objects uploaded in one request is their MD5
+	etag := strings.Trim(header.Get("ETag"), "\"")
+	return strings.EqualFold(etag, localHash), nil
+}
+
+// GetObjectMetadata retrieves metadata for an object
+func (s *restStore) GetObjectMetadata(remotePath string) (map[string]string, error) {
+	header, err := s.head(remotePath)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("object does not exist: %s", remotePath)
+	}
+
+	metadata := make(map[string]string)
+	prefix := s.headerNS + "meta-"
+	for name := range header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, prefix) {
+			metadata[http.CanonicalHeaderKey(strings.TrimPrefix(lower, prefix))] = header.Get(name)
+		}
+	}
+	return metadata, nil
+}
+
+// SetObjectMetadata replaces the metadata of an object by copying it onto itself
+func (s *restStore) SetObjectMetadata(remotePath string, metadata map[string]string) error {
+	key := objectKey(s.pathPrefix, remotePath)
+	header := s.metadataHeader(metadata)
+	header.Set(s.headerNS+"copy-source", s.copySource(key))
+	header.Set(s.headerNS+"metadata-directive", s.replaceDir)
+	resp, err := s.do(http.MethodPut, key, nil, 0, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return responseError("set object metadata", resp)
+	}
+	return nil
+}
+
+// listBucketResult is the page of a bucket listing of OSS and COS
+type listBucketResult struct {
+	IsTruncated bool   `xml:"IsTruncated"`
+	NextMarker  string `xml:"NextMarker"`
+	Contents    []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+}
+
+// ListObjects returns the keys of all objects whose key starts with prefix
+func (s *restStore) ListObjects(prefix string) ([]string, error) {
+	var keys []string
+	marker := ""
+	for {
+		query := url.Values{"prefix": {prefix}, "max-keys": {"1000"}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		req, err := http.NewRequest(http.MethodGet, s.baseURL+"/?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+		s.sign(req, "")
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %v", err)
+		}
+		var page listBucketResult
+		if resp.StatusCode/100 != 2 {
+			err = responseError("list objects", resp)
+		} else if decodeErr := xml.NewDecoder(resp.Body).Decode(&page); decodeErr != nil {
+			err = fmt.Errorf("failed to parse object list: %v", decodeErr)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range page.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !page.IsTruncated || len(page.Contents) == 0 {
+			return keys, nil
+		}
+		marker = page.NextMarker
+		if marker == "" {
+			marker = page.Contents[len(page.Contents)-1].Key
+		}
+	}
+}
+
+// DeleteObject deletes the object with the given key
+func (s *restStore) DeleteObject(key string) error {
+	resp, err := s.do(http.MethodDelete, key, nil, 0, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %v", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return responseError("delete "+key, resp)
+	}
+	return nil
+}
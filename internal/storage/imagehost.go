@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/fsutil"
+)
+
+// init registers the image hosting providers
+func init() {
+	RegisterImageHost("imgur", func() Provider { return NewImgurProvider() })
+	RegisterImageHost("smms", func() Provider { return NewSMMSProvider() })
+	RegisterImageHost("picgo", func() Provider { return NewPicGoProvider() })
+}
+
+// imageHost implements the Provider methods of image hosting services. They
+// store uploads under URLs of their own choosing, so remote paths cannot be
+// looked up and every upload that is not cached creates a new image.
+type imageHost struct {
+	client *http.Client
+}
+
+// GetPublicURL returns an empty URL, image hosts assign it on upload
+func (h *imageHost) GetPublicURL(remotePath string) string {
+	return ""
+}
+
+// ObjectExists always reports a missing object
+func (h *imageHost) ObjectExists(remotePath string) (bool, error) {
+	return false, nil
+}
+
+// CompareHash never matches, image hosts do not expose hashes
+func (h *imageHost) CompareHash(remotePath, localHash string) (bool, error) {
+	return false, nil
+}
+
+// SetObjectMetadata does nothing, image hosts keep no metadata
+func (h *imageHost) SetObjectMetadata(remotePath string, metadata map[string]string) error {
+	return nil
+}
+
+// GetObjectMetadata returns no metadata
+func (h *imageHost) GetObjectMetadata(remotePath string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+// postFile uploads a file as a multipart form field and decodes the JSON response into result
+func (h *imageHost) postFile(endpoint, field, localPath string, fields map[string]string, header http.Header, result interface{}) error {
+	file, err := os.Open(fsutil.LongPath(localPath))
+	if err != nil {
+		return fmt.Errorf("failed to read file: %v", err)
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for k, v := range fields {
+		form.WriteField(k, v)
+	}
+	part, err := form.CreateFormFile(field, filepath.Base(localPath))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return fmt.Errorf("failed to read file: %v", err)
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, &body)
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return h.send(req, result)
+}
+
+// send performs a request and decodes the JSON response into result
+func (h *imageHost) send(req *http.Request, result interface{}) error {
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return responseError("upload file", resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to parse upload response: %v", err)
+	}
+	return nil
+}
+
+// ImgurProvider uploads images to Imgur, anonymously with the client ID in
+// access_key or to an account with an OAuth access token in secret_key
+type ImgurProvider struct {
+	imageHost
+	endpoint    string
+	clientID    string
+	accessToken string
+	album       string
+}
+
+// NewImgurProvider creates a new Imgur provider
+func NewImgurProvider() *ImgurProvider {
+	return &ImgurProvider{}
+}
+
+// Configure sets up the Imgur provider, provider_opts.album adds account
+// uploads to an album
+func (p *ImgurProvider) Configure(cfg config.CloudConfig) error {
+	if cfg.AccessKey == "" && cfg.SecretKey == "" {
+		return fmt.Errorf("imgur requires a client ID (access_key) or an access token (secret_key)")
+	}
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return err
+	}
+	p.client = client
+	p.endpoint = "https://api.imgur.com/3/image"
+	if cfg.Endpoint != "" {
+		p.endpoint = cfg.Endpoint
+	}
+	p.clientID = cfg.AccessKey
+	p.accessToken = cfg.SecretKey
+	p.album = cfg.ProviderOpts["album"]
+	return nil
+}
+
+// Upload uploads an image to Imgur
+func (p *ImgurProvider) Upload(localPath, remotePath string, metadata map[string]string) (string, error) {
+	header := http.Header{}
+	if p.accessToken != "" {
+		header.Set("Authorization", "Bearer "+p.accessToken)
+	} else {
+		header.Set("Authorization", "Client-ID "+p.clientID)
+	}
+	fields := map[string]string{"type": "file", "name": path.Base(objectKey("", remotePath))}
+	if p.album != "" {
+		fields["album"] = p.album
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Link  string `json:"link"`
+			Error string `json:"error"`
+		} `json:"data"`
+	}
+	if err := p.postFile(p.endpoint, "image", localPath, fields, header, &result); err != nil {
+		return "", err
+	}
+	if !result.Success || result.Data.Link == "" {
+		return "", fmt.Errorf("imgur upload failed: %s", result.Data.Error)
+	}
+	return result.Data.Link, nil
+}
+
+// SMMSProvider uploads images to SM.MS with the API token in secret_key
+type SMMSProvider struct {
+	imageHost
+	endpoint string
+	token    string
+}
+
+// NewSMMSProvider creates a new SM.MS provider
+func NewSMMSProvider() *SMMSProvider {
+	return &SMMSProvider{}
+}
+
+// Configure sets up the SM.MS provider, the endpoint defaults to https://sm.ms/api/v2
+func (p *SMMSProvider) Configure(cfg config.CloudConfig) error {
+	if cfg.SecretKey == "" {
+		return fmt.Errorf("smms requires an API token (secret_key)")
+	}
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return err
+	}
+	p.client = client
+	p.endpoint = "https://sm.ms/api/v2"
+	if cfg.Endpoint != "" {
+		p.endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	}
+	p.token = cfg.SecretKey
+	return nil
+}
+
+// Upload uploads an image to SM.MS. Images uploaded before are not stored
+// again, their existing URL is returned.
+func (p *SMMSProvider) Upload(localPath, remotePath string, metadata map[string]string) (string, error) {
+	header := http.Header{}
+	header.Set("Authorization", p.token)
+
+	var result struct {
+		Success bool   `json:"success"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Images  string `json:"images"` // URL of an image uploaded before
+		Data    struct {
+			URL string `json:"url"`
+		} `json:"data"`
+	}
+	if err := p.postFile(p.endpoint+"/upload", "smfile", localPath, nil, header, &result); err != nil {
+		return "", err
+	}
+	switch {
+	case result.Success && result.Data.URL != "":
+		return result.Data.URL, nil
+	case result.Code == "image_repeated" && result.Images != "":
+		return result.Images, nil
+	}
+	return "", fmt.Errorf("sm.ms upload failed: %s", result.Message)
+}
+
+// PicGoProvider uploads images through the HTTP server of a running PicGo,
+// which forwards them to the image host configured there
+type PicGoProvider struct {
+	imageHost
+	endpoint string
+}
+
+// NewPicGoProvider creates a new PicGo server provider
+func NewPicGoProvider() *PicGoProvider {
+	return &PicGoProvider{}
+}
+
+// Configure sets up the PicGo provider, the endpoint defaults to the PicGo
+// server address http://127.0.0.1:36677
+func (p *PicGoProvider) Configure(cfg config.CloudConfig) error {
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return err
+	}
+	p.client = client
+	p.endpoint = "http://127.0.0.1:36677"
+	if cfg.Endpoint != "" {
+		p.endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	}
+	return nil
+}
+
+// Upload asks PicGo to upload a local file
+func (p *PicGoProvider) Upload(localPath, remotePath string, metadata map[string]string) (string, error) {
+	// PicGo reads the file itself, so it needs an absolute path
+	body, err := json.Marshal(map[string][]string{"list": {absPath(localPath)}})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, p.endpoint+"/upload", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var result struct {
+		Success bool     `json:"success"`
+		Result  []string `json:"result"`
+		Message string   `json:"message"`
+	}
+	if err := p.send(req, &result); err != nil {
+		return "", err
+	}
+	if !result.Success || len(result.Result) == 0 {
+		return "", fmt.Errorf("picgo upload failed: %s", result.Message)
+	}
+	return result.Result[0], nil
+}
+
+// absPath returns the absolute form of path, or path itself if it cannot be resolved
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
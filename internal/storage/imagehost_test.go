@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+func TestImageHostUploads(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/3/image":
+			if r.Header.Get("Authorization") != "Client-ID id" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			if _, _, err := r.FormFile("image"); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"success":true,"status":200,"data":{"link":"https://i.imgur.com/abc.png"}}`))
+		case "/api/v2/upload":
+			w.Write([]byte(`{"success":false,"code":"image_repeated","message":"exists","images":"https://s2.loli.net/abc.png"}`))
+		case "/upload":
+			var body struct{ List []string }
+			json.NewDecoder(r.Body).Decode(&body)
+			if len(body.List) != 1 || !filepath.IsAbs(body.List[0]) {
+				w.Write([]byte(`{"success":false,"message":"bad list"}`))
+				return
+			}
+			w.Write([]byte(`{"success":true,"result":["https://cdn.example.com/abc.png"]}`))
+		}
+	}))
+	defer server.Close()
+
+	local := filepath.Join(t.TempDir(), "a.png")
+	os.WriteFile(local, []byte("png"), 0644)
+
+	tests := []struct {
+		name string
+		cfg  config.CloudConfig
+		want string
+	}{
+		{"imgur", config.CloudConfig{AccessKey: "id", Endpoint: server.URL + "/3/image"}, "https://i.imgur.com/abc.png"},
+		{"smms", config.CloudConfig{SecretKey: "token", Endpoint: server.URL + "/api/v2"}, "https://s2.loli.net/abc.png"},
+		{"picgo", config.CloudConfig{Endpoint: server.URL}, "https://cdn.example.com/abc.png"},
+	}
+	for _, tt := range tests {
+		if !IsImageHost(tt.name) {
+			t.Errorf("%s: expected an image host", tt.name)
+		}
+		p, _ := GetProvider(tt.name)
+		if err := p.Configure(tt.cfg); err != nil {
+			t.Fatalf("%s: Configure failed: %v", tt.name, err)
+		}
+		url, err := p.Upload(local, "a.png", nil)
+		if err != nil || url != tt.want {
+			t.Errorf("%s: Upload = %q, %v, want %q", tt.name, url, err, tt.want)
+		}
+	}
+
+	if IsImageHost("s3") {
+		t.Error("expected s3 not to be an image host")
+	}
+}
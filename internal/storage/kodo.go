@@ -0,0 +1,313 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/fsutil"
+)
+
+// init registers the Qiniu Kodo provider
+func init() {
+	RegisterProvider("kodo", func() Provider { return NewKodoProvider() })
+}
+
+// kodoNotFound is the status code of Qiniu management requests for missing objects
+const kodoNotFound = 612
+
+// KodoProvider implements the Provider interface for Qiniu Kodo
+type KodoProvider struct {
+	client       *http.Client
+	bucket       string
+	accessKey    string
+	secretKey    string
+	uploadHost   string // Upload endpoint, e.g. https://up-z0.qiniup.com
+	rsHost       string // Management endpoint, e.g. https://rs-z0.qiniuapi.com
+	rsfHost      string // Listing endpoint, e.g. https://rsf-z0.qiniuapi.com
+	customDomain string
+	pathPrefix   string
+	signedTTL    time.Duration // Validity of signed URLs of private buckets
+}
+
+// kodoStat is the response of the stat management request
+type kodoStat struct {
+	Hash     string            `json:"hash"`
+	MimeType string            `json:"mimeType"`
+	Meta     map[string]string `json:"x-qn-meta"`
+}
+
+// NewKodoProvider creates a new Qiniu Kodo provider
+func NewKodoProvider() *KodoProvider {
+	return &KodoProvider{}
+}
+
+// Configure sets up the Kodo provider. The region is a Qiniu region ID such
+// as z0, z1, z2, na0 or as0, the endpoint overrides the upload host. Kodo
+// buckets are served from a bound domain, so custom_domain is required.
+func (p *KodoProvider) Configure(cfg config.CloudConfig) error {
+	if cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return fmt.Errorf("kodo requires bucket, access_key and secret_key")
+	}
+	if cfg.CustomDomain == "" {
+		return fmt.Errorf("kodo requires custom_domain, the domain bound to the bucket")
+	}
+
+	region := cfg.Region
+	if region == "" || region == "auto" {
+		region = "z0"
+	}
+	p.uploadHost = "https://up-" + region + ".qiniup.com"
+	if cfg.Endpoint != "" {
+		p.uploadHost = "https://" + hostOf(cfg.Endpoint)
+	}
+	p.rsHost = "https://rs-" + region + ".qiniuapi.com"
+	p.rsfHost = "https://rsf-" + region + ".qiniuapi.com"
+
+	ttl, err := signedURLTTL(cfg)
+	if err != nil {
+		return err
+	}
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	p.client = client
+	p.bucket = cfg.Bucket
+	p.accessKey = cfg.AccessKey
+	p.secretKey = cfg.SecretKey
+	p.customDomain = hostOf(cfg.CustomDomain)
+	p.pathPrefix = cfg.PathPrefix
+	p.signedTTL = ttl
+	return nil
+}
+
+// Upload uploads a file to Kodo with a form upload
+func (p *KodoProvider) Upload(localPath, remotePath string, metadata map[string]string) (string, error) {
+	key := objectKey(p.pathPrefix, remotePath)
+
+	file, err := os.Open(fsutil.LongPath(localPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("token", p.uploadToken(key))
+	form.WriteField("key", key)
+	for k, v := range metadata {
+		form.WriteField("x-qn-meta-"+k, v)
+	}
+	part, err := form.CreateFormFile("file", filepath.Base(localPath))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	resp, err := p.client.Post(p.uploadHost, form.FormDataContentType(), &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", responseError("upload file", resp)
+	}
+	return p.GetPublicURL(key), nil
+}
+
+// GetPublicURL returns the URL of an object on the bound domain, signed when
+// the bucket is private
+func (p *KodoProvider) GetPublicURL(remotePath string) string {
+	objectURL := fmt.Sprintf("https://%s%s", p.customDomain, escapeKey(objectKey(p.pathPrefix, remotePath)))
+	if p.signedTTL == 0 {
+		return objectURL
+	}
+	objectURL += fmt.Sprintf("?e=%d", time.Now().Add(p.signedTTL).Unix())
+	return objectURL + "&token=" + p.accessKey + ":" + base64.URLEncoding.EncodeToString(hmacSHA1(p.secretKey, objectURL))
+}
+
+// ObjectExists checks if an object exists in the bucket
+func (p *KodoProvider) ObjectExists(remotePath string) (bool, error) {
+	stat, err := p.stat(remotePath)
+	return stat != nil, err
+}
+
+// CompareHash compares a local hash with the hash stored in the object metadata
+func (p *KodoProvider) CompareHash(remotePath, localHash string) (bool, error) {
+	stat, err := p.stat(remotePath)
+	if err != nil {
+		return false, err
+	}
+	if stat == nil {
+		return false, fmt.Errorf("object does not exist: %s", remotePath)
+	}
+	// Kodo hashes are etags of its own format, never an MD5
+	return metaValue(stat.Meta, "Hash") == localHash, nil
+}
+
+// SetObjectMetadata sets metadata for an object
+func (p *KodoProvider) SetObjectMetadata(remotePath string, metadata map[string]string) error {
+	path := "/setmeta/" + p.entry(remotePath)
+	for k, v := range metadata {
+		path += "/x-qn-meta-" + k + "/" + base64.URLEncoding.EncodeToString([]byte(v))
+	}
+	resp, err := p.manage(http.MethodPost, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return responseError("set object metadata", resp)
+	}
+	return nil
+}
+
+// GetObjectMetadata retrieves metadata for an object
+func (p *KodoProvider) GetObjectMetadata(remotePath string) (map[string]string, error) {
+	stat, err := p.stat(remotePath)
+	if err != nil {
+		return nil, err
+	}
+	if stat == nil {
+		return nil, fmt.Errorf("object does not exist: %s", remotePath)
+	}
+	metadata := make(map[string]string)
+	for k, v := range stat.Meta {
+		metadata[http.CanonicalHeaderKey(k)] = v
+	}
+	return metadata, nil
+}
+
+// ListObjects returns the keys of all objects whose key starts with prefix
+func (p *KodoProvider) ListObjects(prefix string) ([]string, error) {
+	var keys []string
+	marker := ""
+	for {
+		query := url.Values{"bucket": {p.bucket}, "prefix": {prefix}, "limit": {"1000"}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		resp, err := p.manageHost(p.rsfHost, http.MethodPost, "/list?"+query.Encode())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %v", err)
+		}
+		var page struct {
+			Marker string `json:"marker"`
+			Items  []struct {
+				Key string `json:"key"`
+			} `json:"items"`
+		}
+		if resp.StatusCode/100 != 2 {
+			err = responseError("list objects", resp)
+		} else if decodeErr := json.NewDecoder(resp.Body).Decode(&page); decodeErr != nil {
+			err = fmt.Errorf("failed to parse object list: %v", decodeErr)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			keys = append(keys, item.Key)
+		}
+		if page.Marker == "" {
+			return keys, nil
+		}
+		marker = page.Marker
+	}
+}
+
+// DeleteObject deletes the object with the given key
+func (p *KodoProvider) DeleteObject(key string) error {
+	resp, err := p.manage(http.MethodPost, "/delete/"+base64.URLEncoding.EncodeToString([]byte(p.bucket+":"+key)))
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %v", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 && resp.StatusCode != kodoNotFound {
+		return responseError("delete "+key, resp)
+	}
+	return nil
+}
+
+// stat returns the information of an object, nil when it does not exist
+func (p *KodoProvider) stat(remotePath string) (*kodoStat, error) {
+	resp, err := p.manage(http.MethodGet, "/stat/"+p.entry(remotePath))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == kodoNotFound || resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, responseError("get object", resp)
+	}
+
+	var stat kodoStat
+	if err := json.NewDecoder(resp.Body).Decode(&stat); err != nil {
+		return nil, fmt.Errorf("failed to parse object information: %v", err)
+	}
+	return &stat, nil
+}
+
+// entry returns the encoded bucket:key entry of a remote path
+func (p *KodoProvider) entry(remotePath string) string {
+	return base64.URLEncoding.EncodeToString([]byte(p.bucket + ":" + objectKey(p.pathPrefix, remotePath)))
+}
+
+// manage sends a management request signed with a QBox access token
+func (p *KodoProvider) manage(method, path string) (*http.Response, error) {
+	return p.manageHost(p.rsHost, method, path)
+}
+
+// manageHost sends a signed management request to a host, the path may contain a query
+func (p *KodoProvider) manageHost(host, method, path string) (*http.Response, error) {
+	req, err := http.NewRequest(method, host+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	signature := base64.URLEncoding.EncodeToString(hmacSHA1(p.secretKey, path+"\n"))
+	req.Header.Set("Authorization", "QBox "+p.accessKey+":"+signature)
+	return p.client.Do(req)
+}
+
+// uploadToken returns a token allowing to upload or overwrite key for an hour
+func (p *KodoProvider) uploadToken(key string) string {
+	policy, _ := json.Marshal(map[string]interface{}{
+		"scope":    p.bucket + ":" + key,
+		"deadline": time.Now().Add(time.Hour).Unix(),
+	})
+	encoded := base64.URLEncoding.EncodeToString(policy)
+	return p.accessKey + ":" + base64.URLEncoding.EncodeToString(hmacSHA1(p.secretKey, encoded)) + ":" + encoded
+}
+
+// metaValue looks up a metadata value case-insensitively
+func metaValue(meta map[string]string, key string) string {
+	for k, v := range meta {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+// init registers the Aliyun OSS provider
+func init() {
+	RegisterProvider("oss", func() Provider { return NewOSSProvider() })
+}
+
+// OSSProvider implements the Provider interface for Aliyun Object Storage Service
+type OSSProvider struct {
+	restStore
+	host         string // Bucket host, e.g. my-bucket.oss-cn-hangzhou.aliyuncs.com
+	accessKey    string
+	secretKey    string
+	customDomain string
+	signedTTL    time.Duration // Validity of signed URLs of private buckets
+}
+
+// NewOSSProvider creates a new Aliyun OSS provider
+func NewOSSProvider() *OSSProvider {
+	return &OSSProvider{}
+}
+
+// Configure sets up the OSS provider. The endpoint defaults to the public
+// endpoint of the region, e.g. oss-cn-hangzhou.aliyuncs.com for cn-hangzhou.
+func (p *OSSProvider) Configure(cfg config.CloudConfig) error {
+	if cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return fmt.Errorf("oss requires bucket, access_key and secret_key")
+	}
+
+	endpoint := hostOf(cfg.Endpoint)
+	if endpoint == "" {
+		region := cfg.Region
+		if region == "" || region == "auto" {
+			return fmt.Errorf("oss requires a region (e.g. cn-hangzhou) or an endpoint")
+		}
+		if !strings.HasPrefix(region, "oss-") {
+			region = "oss-" + region
+		}
+		endpoint = region + ".aliyuncs.com"
+	}
+
+	ttl, err := signedURLTTL(cfg)
+	if err != nil {
+		return err
+	}
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	p.host = cfg.Bucket + "." + endpoint
+	p.accessKey = cfg.AccessKey
+	p.secretKey = cfg.SecretKey
+	p.customDomain = cfg.CustomDomain
+	p.signedTTL = ttl
+	p.restStore = restStore{
+		client:     client,
+		baseURL:    "https://" + p.host,
+		bucket:     cfg.Bucket,
+		pathPrefix: cfg.PathPrefix,
+		headerNS:   "x-oss-",
+		sign:       p.sign,
+		copySource: func(key string) string { return "/" + cfg.Bucket + escapeKey(key) },
+		replaceDir: "REPLACE",
+	}
+	return nil
+}
+
+// Upload uploads a file to OSS
+func (p *OSSProvider) Upload(localPath, remotePath string, metadata map[string]string) (string, error) {
+	key, err := p.upload(localPath, remotePath, metadata)
+	if err != nil {
+		return "", err
+	}
+	return p.GetPublicURL(key), nil
+}
+
+// GetPublicURL returns the URL of an object on the custom domain or the
+// bucket host, signed when the bucket is private
+func (p *OSSProvider) GetPublicURL(remotePath string) string {
+	key := objectKey(p.pathPrefix, remotePath)
+	host := p.host
+	if p.customDomain != "" {
+		host = p.customDomain
+	}
+	objectURL := fmt.Sprintf("https://%s%s", host, escapeKey(key))
+	if p.signedTTL == 0 {
+		return objectURL
+	}
+
+	expires := fmt.Sprintf("%d", time.Now().Add(p.signedTTL).Unix())
+	stringToSign := "GET\n\n\n" + expires + "\n/" + p.bucket + "/" + key
+	query := url.Values{
+		"OSSAccessKeyId": {p.accessKey},
+		"Expires":        {expires},
+		"Signature":      {base64.StdEncoding.EncodeToString(hmacSHA1(p.secretKey, stringToSign))},
+	}
+	return objectURL + "?" + query.Encode()
+}
+
+// sign adds the OSS header signature (version 1) to a request
+func (p *OSSProvider) sign(req *http.Request, key string) {
+	var names []string
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-oss-") {
+			names = append(names, lower)
+		}
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + strings.TrimSpace(req.Header.Get(name)) + "\n")
+	}
+	stringToSign := req.Method + "\n" +
+		req.Header.Get("Content-MD5") + "\n" +
+		req.Header.Get("Content-Type") + "\n" +
+		req.Header.Get("Date") + "\n" +
+		canonical.String() +
+		"/" + p.bucket + "/" + key
+	signature := base64.StdEncoding.EncodeToString(hmacSHA1(p.secretKey, stringToSign))
+	req.Header.Set("Authorization", "OSS "+p.accessKey+":"+signature)
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+func TestOSSSign(t *testing.T) {
+	// Example of the OSS header signature documentation
+	p := &OSSProvider{accessKey: "44CF9590006BF252F707", secretKey: "OtxrzxIsfpFjA7SwPzILwy8Bw21TLhquhboDYROV"}
+	p.bucket = "oss-example"
+	req, _ := http.NewRequest(http.MethodPut, "https://oss-example.oss-cn-hangzhou.aliyuncs.com/nelson", nil)
+	req.Header.Set("Content-MD5", "ODBGOERFMDMzQTczRUY3NUE3NzA5QzdFNUYzMDQxNEM=")
+	req.Header.Set("Content-Type", "text/html")
+	req.Header.Set("Date", "Thu, 17 Nov 2005 18:49:58 GMT")
+	req.Header.Set("X-OSS-Magic", "abracadabra")
+	req.Header.Set("X-OSS-Meta-Author", "foo@bar.com")
+	p.sign(req, "nelson")
+
+	if got := req.Header.Get("Authorization"); got != "OSS 44CF9590006BF252F707:26NBxoKdsyly4EDv6inkoDft/yA=" {
+		t.Errorf("unexpected signature: %s", got)
+	}
+}
+
+func TestRestStoreRoundTrip(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string]http.Header{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "OSS key:") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			io.Copy(io.Discard, r.Body)
+			header := http.Header{}
+			for name, values := range r.Header {
+				if strings.HasPrefix(strings.ToLower(name), "x-oss-meta-") {
+					header[name] = values
+				}
+			}
+			objects[r.URL.Path] = header
+		case http.MethodHead:
+			header, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+		case http.MethodGet:
+			// Listing pages hold one key each to exercise the markers
+			var keys []string
+			for name := range objects {
+				key := strings.TrimPrefix(name, "/")
+				if strings.HasPrefix(key, r.URL.Query().Get("prefix")) && key > r.URL.Query().Get("marker") {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			w.Write([]byte("<ListBucketResult>"))
+			if len(keys) > 0 {
+				fmt.Fprintf(w, "<IsTruncated>%v</IsTruncated><Contents><Key>%s</Key></Contents>", len(keys) > 1, keys[0])
+			}
+			w.Write([]byte("</ListBucketResult>"))
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	p := NewOSSProvider()
+	cfg := config.CloudConfig{Bucket: "b", AccessKey: "key", SecretKey: "secret", Region: "cn-hangzhou", PathPrefix: "img", CustomDomain: "cdn.example.com"}
+	if err := p.Configure(cfg); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	p.baseURL = server.URL
+
+	local := filepath.Join(t.TempDir(), "a b.png")
+	os.WriteFile(local, []byte("png"), 0644)
+	url, err := p.Upload(local, "a b.png", map[string]string{"Hash": "abc"})
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if url != "https://cdn.example.com/img/a%20b.png" {
+		t.Errorf("unexpected URL: %s", url)
+	}
+
+	if exists, err := p.ObjectExists("missing.png"); err != nil || exists {
+		t.Errorf("expected missing object, got %v, %v", exists, err)
+	}
+	if same, err := p.CompareHash("a b.png", "abc"); err != nil || !same {
+		t.Errorf("expected matching hash, got %v, %v", same, err)
+	}
+	metadata, err := p.GetObjectMetadata("a b.png")
+	if err != nil || metadata["Hash"] != "abc" {
+		t.Errorf("unexpected metadata %v, %v", metadata, err)
+	}
+
+	p.Upload(local, "c.png", nil)
+	keys, err := p.ListObjects("img/")
+	if err != nil || strings.Join(keys, ",") != "img/a b.png,img/c.png" {
+		t.Fatalf("unexpected keys %v, %v", keys, err)
+	}
+	if err := p.DeleteObject("img/c.png"); err != nil {
+		t.Fatalf("DeleteObject failed: %v", err)
+	}
+	if keys, _ := p.ListObjects("img/"); len(keys) != 1 {
+		t.Errorf("expected one key after deleting, got %v", keys)
+	}
+}
+
+func TestSignedURLs(t *testing.T) {
+	cfg := config.CloudConfig{Bucket: "b-1250000000", AccessKey: "key", SecretKey: "secret", Region: "ap-guangzhou",
+		CustomDomain: "img.example.com", ProviderOpts: map[string]string{"signed_url_ttl": "24h"}}
+	for name, p := range map[string]Provider{"oss": NewOSSProvider(), "cos": NewCOSProvider(), "kodo": NewKodoProvider()} {
+		if err := p.Configure(cfg); err != nil {
+			t.Fatalf("%s: Configure failed: %v", name, err)
+		}
+		url := p.GetPublicURL("a.png")
+		if !strings.HasPrefix(url, "https://img.example.com/a.png?") {
+			t.Errorf("%s: expected a signed URL on the custom domain, got %s", name, url)
+		}
+	}
+
+	cfg.ProviderOpts["signed_url_ttl"] = "forever"
+	if err := NewOSSProvider().Configure(cfg); err == nil {
+		t.Error("expected an error for an invalid signed_url_ttl")
+	}
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+// init registers the S3-compatible services that have a preset
+func init() {
+	for name := range s3Presets {
+		RegisterProvider(name, func() Provider { return NewS3Provider() })
+	}
+}
+
+// s3Preset describes an S3-compatible service whose endpoint and public URLs
+// follow from the region and bucket
+type s3Preset struct {
+	name          string
+	defaultRegion string // Region when none is configured, required when empty
+	signingRegion string // Region requests are signed for, the configured one when empty
+	pathStyle     bool   // Address buckets in the path instead of the host name
+	endpoint      func(region string) string
+	publicURL     func(bucket, region, key string) string
+}
+
+var s3Presets = map[string]s3Preset{
+	// Backblaze B2 through its S3-compatible API, public files are served by
+	// the download host of the cluster, the last part of the region
+	"b2": {
+		name: "Backblaze B2",
+		endpoint: func(region string) string {
+			return fmt.Sprintf("https://s3.%s.backblazeb2.com", region)
+		},
+		publicURL: func(bucket, region, key string) string {
+			cluster := region[strings.LastIndex(region, "-")+1:]
+			return fmt.Sprintf("https://f%s.backblazeb2.com/file/%s/%s", cluster, bucket, key)
+		},
+	},
+	// DigitalOcean Spaces, signed as us-east-1 whatever the region
+	"spaces": {
+		name:          "DigitalOcean Spaces",
+		signingRegion: "us-east-1",
+		endpoint: func(region string) string {
+			return fmt.Sprintf("https://%s.digitaloceanspaces.com", region)
+		},
+		publicURL: func(bucket, region, key string) string {
+			return fmt.Sprintf("https://%s.%s.digitaloceanspaces.com/%s", bucket, region, key)
+		},
+	},
+	"wasabi": {
+		name:          "Wasabi",
+		defaultRegion: "us-east-1",
+		pathStyle:     true,
+		endpoint: func(region string) string {
+			return fmt.Sprintf("https://s3.%s.wasabisys.com", region)
+		},
+		publicURL: func(bucket, region, key string) string {
+			return fmt.Sprintf("https://s3.%s.wasabisys.com/%s/%s", region, bucket, key)
+		},
+	},
+}
+
+// apply fills in the endpoint and region of a preset provider's
+// configuration, an endpoint that is set explicitly is kept
+func (p s3Preset) apply(cfg config.CloudConfig) (config.CloudConfig, error) {
+	region := cfg.Region
+	if region == "" || region == "auto" {
+		region = p.defaultRegion
+	}
+	if region == "" {
+		return cfg, fmt.Errorf("%s needs a region, set cloud_storages.<name>.region", p.name)
+	}
+	cfg.Region = region
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = p.endpoint(region)
+	}
+	return cfg, nil
+}
@@ -28,16 +28,41 @@ type Provider interface {
 	GetObjectMetadata(remotePath string) (map[string]string, error)
 }
 
+// Lister is implemented by providers that can enumerate and delete objects,
+// which garbage collection of unreferenced images needs
+type Lister interface {
+	// ListObjects returns the keys of all objects whose key starts with prefix
+	ListObjects(prefix string) ([]string, error)
+
+	// DeleteObject deletes the object with the given key
+	DeleteObject(key string) error
+}
+
 // ProviderFactory is a function that creates a new storage provider
 type ProviderFactory func() Provider
 
 var providers = make(map[string]ProviderFactory)
 
+// imageHosts are the providers that store images without buckets
+var imageHosts = make(map[string]bool)
+
 // RegisterProvider registers a storage provider factory
 func RegisterProvider(name string, factory ProviderFactory) {
 	providers[name] = factory
 }
 
+// RegisterImageHost registers a provider for an image hosting service, which
+// assigns URLs to uploads itself and has no buckets
+func RegisterImageHost(name string, factory ProviderFactory) {
+	RegisterProvider(name, factory)
+	imageHosts[name] = true
+}
+
+// IsImageHost reports whether a provider is an image hosting service
+func IsImageHost(name string) bool {
+	return imageHosts[name]
+}
+
 // GetProvider returns a storage provider by name
 func GetProvider(name string) (Provider, bool) {
 	factory, exists := providers[name]
@@ -2,23 +2,25 @@ package storage
 
 import (
 	"bytes"
-	"crypto/tls"
-	"crypto/x509"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/logging"
 )
 
+// logger reports storage configuration problems
+var logger = logging.New("STORAGE")
+
 // init registers the S3 provider
 func init() {
 	RegisterProvider("s3", func() Provider { return NewS3Provider() })
@@ -26,6 +28,15 @@ func init() {
 	RegisterProvider("minio", func() Provider { return NewS3Provider() }) // MinIO (S3 compatible)
 }
 
+// Server-side encryption algorithms of S3
+const (
+	SSEAES256 = "AES256"  // SSE-S3, keys managed by S3
+	SSEKMS    = "aws:kms" // SSE-KMS, keys managed by AWS KMS
+)
+
+// defaultMultipartThreshold is the size in MiB above which files are uploaded in parts
+const defaultMultipartThreshold = 16
+
 // S3Provider implements the Provider interface for S3-compatible storage services
 type S3Provider struct {
 	client       *s3.S3
@@ -34,7 +45,13 @@ type S3Provider struct {
 	endpoint     string
 	customDomain string
 	pathPrefix   string
-	accountID    string // Add accountID field for R2
+	accountID    string    // Add accountID field for R2
+	preset       *s3Preset // Preset of the provider, nil for s3, r2 and minio
+	uploader     *s3manager.Uploader
+	storageClass string
+	acl          string
+	sse          string
+	sseKMSKeyID  string
 }
 
 // NewS3Provider creates a new S3 provider
@@ -44,6 +61,16 @@ func NewS3Provider() *S3Provider {
 
 // Configure sets up the S3 provider with the given configuration
 func (p *S3Provider) Configure(cfg config.CloudConfig) error {
+	// Presets derive the endpoint from the region
+	p.preset = nil
+	if preset, ok := s3Presets[strings.ToLower(cfg.Provider)]; ok {
+		var err error
+		if cfg, err = preset.apply(cfg); err != nil {
+			return err
+		}
+		p.preset = &preset
+	}
+
 	// Set provider configuration
 	p.bucket = cfg.Bucket
 	p.region = cfg.Region
@@ -54,6 +81,19 @@ func (p *S3Provider) Configure(cfg config.CloudConfig) error {
 	// Set accountID, prioritize AccountID from configuration
 	p.accountID = cfg.AccountID
 
+	// Object settings applied to every upload
+	sse, err := NormalizeSSE(cfg.SSE)
+	if err != nil {
+		return err
+	}
+	if cfg.SSEKMSKeyID != "" && sse != SSEKMS {
+		return fmt.Errorf("sse_kms_key_id requires SSE-KMS encryption (sse: aws:kms)")
+	}
+	p.sse = sse
+	p.sseKMSKeyID = cfg.SSEKMSKeyID
+	p.storageClass = cfg.StorageClass
+	p.acl = cfg.ACL
+
 	// Try to extract accountID from endpoint URL: https://<account_id>.r2.cloudflarestorage.com
 	if p.accountID == "" && strings.Contains(p.endpoint, "r2.cloudflarestorage.com") {
 		p.accountID = extractR2AccountID(p.endpoint)
@@ -61,65 +101,32 @@ func (p *S3Provider) Configure(cfg config.CloudConfig) error {
 
 	// If it's R2 but accountID not set, log a warning
 	if strings.ToLower(cfg.Provider) == "r2" && p.accountID == "" {
-		fmt.Printf("Warning: R2 account ID not set. r2.dev public URLs cannot be generated.")
+		logger.Warnf("R2 account ID not set. r2.dev public URLs cannot be generated.")
 	}
 
 	// Create AWS configuration
+	signingRegion := cfg.Region
+	if p.preset != nil && p.preset.signingRegion != "" {
+		signingRegion = p.preset.signingRegion
+	}
 	awsConfig := &aws.Config{
-		Region:      aws.String(cfg.Region),
+		Region:      aws.String(signingRegion),
 		Credentials: credentials.NewStaticCredentials(cfg.AccessKey, cfg.SecretKey, ""),
 	}
 
 	// Set custom endpoint if provided
 	if cfg.Endpoint != "" {
 		awsConfig.Endpoint = aws.String(cfg.Endpoint)
-		// Use path-style addressing for custom endpoints
-		awsConfig.S3ForcePathStyle = aws.Bool(true)
+		// Use path-style addressing for custom endpoints, presets know
+		// whether their service needs it
+		awsConfig.S3ForcePathStyle = aws.Bool(p.preset == nil || p.preset.pathStyle)
 	}
 
 	// Configure TLS settings
-	httpClient := &http.Client{
-		Timeout: time.Second * 30,
-	}
-
-	// Set up custom transport if needed
-	if cfg.SkipVerify || cfg.CACertPath != "" {
-		// Start with the default transport
-		transport := &http.Transport{
-			TLSHandshakeTimeout: 10 * time.Second,
-		}
-
-		// Configure TLS
-		tlsConfig := &tls.Config{}
-
-		// Skip certificate verification if requested
-		if cfg.SkipVerify {
-			tlsConfig.InsecureSkipVerify = true
-		}
-
-		// Load custom CA certificate if provided
-		if cfg.CACertPath != "" {
-			rootCAs, _ := x509.SystemCertPool()
-			if rootCAs == nil {
-				rootCAs = x509.NewCertPool()
-			}
-
-			certs, err := os.ReadFile(cfg.CACertPath)
-			if err != nil {
-				return fmt.Errorf("failed to read CA cert: %v", err)
-			}
-
-			if ok := rootCAs.AppendCertsFromPEM(certs); !ok {
-				return fmt.Errorf("failed to append CA cert")
-			}
-
-			tlsConfig.RootCAs = rootCAs
-		}
-
-		transport.TLSClientConfig = tlsConfig
-		httpClient.Transport = transport
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		return err
 	}
-
 	awsConfig.HTTPClient = httpClient
 
 	// Create session
@@ -130,21 +137,55 @@ func (p *S3Provider) Configure(cfg config.CloudConfig) error {
 
 	// Create S3 client
 	p.client = s3.New(sess)
+
+	// Files that fit in one part are uploaded with a single request, larger
+	// ones in parts of the threshold size
+	threshold := cfg.MultipartThreshold
+	if threshold <= 0 {
+		threshold = defaultMultipartThreshold
+	}
+	if int64(threshold)*1024*1024 < s3manager.MinUploadPartSize {
+		return fmt.Errorf("multipart_threshold must be at least %d MiB", s3manager.MinUploadPartSize/1024/1024)
+	}
+	p.uploader = s3manager.NewUploaderWithClient(p.client, func(u *s3manager.Uploader) {
+		u.PartSize = int64(threshold) * 1024 * 1024
+	})
 	return nil
 }
 
+// NormalizeSSE returns the S3 encryption algorithm of an sse setting, which
+// may also be given as SSE-S3 or SSE-KMS. An empty setting disables encryption.
+func NormalizeSSE(sse string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(sse)) {
+	case "":
+		return "", nil
+	case "sse-s3", "aes256":
+		return SSEAES256, nil
+	case "sse-kms", "aws:kms", "kms":
+		return SSEKMS, nil
+	}
+	return "", fmt.Errorf("unsupported server-side encryption: %s (must be SSE-S3 or SSE-KMS)", sse)
+}
+
+// optionalString returns nil for empty settings, so they are left out of requests
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return aws.String(value)
+}
+
 // Upload uploads a file to S3 storage
 func (p *S3Provider) Upload(localPath, remotePath string, metadata map[string]string) (string, error) {
 	// Ensure remotePath starts with prefix if set
-	if p.pathPrefix != "" && !strings.HasPrefix(remotePath, p.pathPrefix) {
-		remotePath = filepath.Join(p.pathPrefix, remotePath)
-	}
+	remotePath = objectKey(p.pathPrefix, remotePath)
 
-	// Read file
-	data, err := os.ReadFile(localPath)
+	// Open file, the uploader streams it in parts when it is large
+	file, err := os.Open(fsutil.LongPath(localPath))
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %v", err)
 	}
+	defer file.Close()
 
 	// Determine content type
 	contentType := getContentType(localPath)
@@ -156,13 +197,16 @@ func (p *S3Provider) Upload(localPath, remotePath string, metadata map[string]st
 	}
 
 	// Upload to S3
-	_, err = p.client.PutObject(&s3.PutObjectInput{
-		Bucket:        aws.String(p.bucket),
-		Key:           aws.String(remotePath),
-		Body:          bytes.NewReader(data),
-		ContentLength: aws.Int64(int64(len(data))),
-		ContentType:   aws.String(contentType),
-		Metadata:      s3Metadata,
+	_, err = p.uploader.Upload(&s3manager.UploadInput{
+		Bucket:               aws.String(p.bucket),
+		Key:                  aws.String(remotePath),
+		Body:                 file,
+		ContentType:          aws.String(contentType),
+		Metadata:             s3Metadata,
+		StorageClass:         optionalString(p.storageClass),
+		ACL:                  optionalString(p.acl),
+		ServerSideEncryption: optionalString(p.sse),
+		SSEKMSKeyId:          optionalString(p.sseKMSKeyID),
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to upload file: %v", err)
@@ -179,6 +223,11 @@ func (p *S3Provider) GetPublicURL(remotePath string) string {
 		return fmt.Sprintf("https://%s/%s", p.customDomain, remotePath)
 	}
 
+	// Public URL format of a preset service
+	if p.preset != nil {
+		return p.preset.publicURL(p.bucket, p.region, remotePath)
+	}
+
 	// Generate r2.dev URL for Cloudflare R2
 	if p.endpoint != "" && strings.Contains(p.endpoint, "r2.dev") {
 		// First check if accountID is set
@@ -211,9 +260,7 @@ func (p *S3Provider) GetPublicURL(remotePath string) string {
 // ObjectExists checks if an object exists in the bucket
 func (p *S3Provider) ObjectExists(remotePath string) (bool, error) {
 	// Ensure remotePath starts with prefix if set
-	if p.pathPrefix != "" && !strings.HasPrefix(remotePath, p.pathPrefix) {
-		remotePath = filepath.Join(p.pathPrefix, remotePath)
-	}
+	remotePath = objectKey(p.pathPrefix, remotePath)
 
 	_, err := p.client.HeadObject(&s3.HeadObjectInput{
 		Bucket: aws.String(p.bucket),
@@ -233,9 +280,7 @@ func (p *S3Provider) ObjectExists(remotePath string) (bool, error) {
 // CompareHash compares a local hash with a remote object's hash
 func (p *S3Provider) CompareHash(remotePath, localHash string) (bool, error) {
 	// Ensure remotePath starts with prefix if set
-	if p.pathPrefix != "" && !strings.HasPrefix(remotePath, p.pathPrefix) {
-		remotePath = filepath.Join(p.pathPrefix, remotePath)
-	}
+	remotePath = objectKey(p.pathPrefix, remotePath)
 
 	headOutput, err := p.client.HeadObject(&s3.HeadObjectInput{
 		Bucket: aws.String(p.bucket),
@@ -266,9 +311,7 @@ func (p *S3Provider) CompareHash(remotePath, localHash string) (bool, error) {
 // SetObjectMetadata sets metadata for an object
 func (p *S3Provider) SetObjectMetadata(remotePath string, metadata map[string]string) error {
 	// Ensure remotePath starts with prefix if set
-	if p.pathPrefix != "" && !strings.HasPrefix(remotePath, p.pathPrefix) {
-		remotePath = filepath.Join(p.pathPrefix, remotePath)
-	}
+	remotePath = objectKey(p.pathPrefix, remotePath)
 
 	// Get the current object
 	getObjectOutput, err := p.client.GetObject(&s3.GetObjectInput{
@@ -294,12 +337,16 @@ func (p *S3Provider) SetObjectMetadata(remotePath string, metadata map[string]st
 
 	// Upload the object with new metadata
 	_, err = p.client.PutObject(&s3.PutObjectInput{
-		Bucket:        aws.String(p.bucket),
-		Key:           aws.String(remotePath),
-		Body:          bytes.NewReader(data),
-		ContentLength: aws.Int64(int64(len(data))),
-		ContentType:   getObjectOutput.ContentType,
-		Metadata:      s3Metadata,
+		Bucket:               aws.String(p.bucket),
+		Key:                  aws.String(remotePath),
+		Body:                 bytes.NewReader(data),
+		ContentLength:        aws.Int64(int64(len(data))),
+		ContentType:          getObjectOutput.ContentType,
+		Metadata:             s3Metadata,
+		StorageClass:         optionalString(p.storageClass),
+		ACL:                  optionalString(p.acl),
+		ServerSideEncryption: optionalString(p.sse),
+		SSEKMSKeyId:          optionalString(p.sseKMSKeyID),
 	})
 
 	return err
@@ -308,9 +355,7 @@ func (p *S3Provider) SetObjectMetadata(remotePath string, metadata map[string]st
 // GetObjectMetadata retrieves metadata for an object
 func (p *S3Provider) GetObjectMetadata(remotePath string) (map[string]string, error) {
 	// Ensure remotePath starts with prefix if set
-	if p.pathPrefix != "" && !strings.HasPrefix(remotePath, p.pathPrefix) {
-		remotePath = filepath.Join(p.pathPrefix, remotePath)
-	}
+	remotePath = objectKey(p.pathPrefix, remotePath)
 
 	headOutput, err := p.client.HeadObject(&s3.HeadObjectInput{
 		Bucket: aws.String(p.bucket),
@@ -330,6 +375,36 @@ func (p *S3Provider) GetObjectMetadata(remotePath string) (map[string]string, er
 	return metadata, nil
 }
 
+// ListObjects returns the keys of all objects whose key starts with prefix
+func (p *S3Provider) ListObjects(prefix string) ([]string, error) {
+	var keys []string
+	err := p.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(p.bucket),
+		Prefix: optionalString(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, aws.StringValue(object.Key))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %v", err)
+	}
+	return keys, nil
+}
+
+// DeleteObject deletes the object with the given key
+func (p *S3Provider) DeleteObject(key string) error {
+	_, err := p.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %v", key, err)
+	}
+	return nil
+}
+
 // Helper function to determine content type from file extension
 func getContentType(filePath string) string {
 	ext := strings.ToLower(filepath.Ext(filePath))
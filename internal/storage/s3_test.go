@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+func TestNormalizeSSE(t *testing.T) {
+	tests := map[string]string{
+		"":        "",
+		"SSE-S3":  SSEAES256,
+		"AES256":  SSEAES256,
+		"sse-kms": SSEKMS,
+		"aws:kms": SSEKMS,
+	}
+	for in, want := range tests {
+		if got, err := NormalizeSSE(in); err != nil || got != want {
+			t.Errorf("NormalizeSSE(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := NormalizeSSE("SSE-C"); err == nil {
+		t.Error("expected an error for unsupported encryption")
+	}
+}
+
+func TestS3ProviderConfigure(t *testing.T) {
+	cfg := config.CloudConfig{Provider: "s3", Region: "us-east-1", Bucket: "b", SSE: "SSE-KMS", SSEKMSKeyID: "key", MultipartThreshold: 64}
+	p := NewS3Provider()
+	if err := p.Configure(cfg); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	if p.sse != SSEKMS || p.uploader.PartSize != 64*1024*1024 {
+		t.Errorf("unexpected settings: sse=%q part size=%d", p.sse, p.uploader.PartSize)
+	}
+
+	cfg.SSE = "SSE-S3"
+	if err := NewS3Provider().Configure(cfg); err == nil {
+		t.Error("expected an error for a KMS key without SSE-KMS")
+	}
+	cfg.SSE, cfg.MultipartThreshold = "", 1
+	cfg.SSEKMSKeyID = ""
+	if err := NewS3Provider().Configure(cfg); err == nil {
+		t.Error("expected an error for a threshold below the minimum part size")
+	}
+}
+
+func TestObjectKey(t *testing.T) {
+	tests := []struct {
+		prefix, remotePath, want string
+	}{
+		{"", "logo_1234abcd.png", "logo_1234abcd.png"},
+		{"images", "logo.png", "images/logo.png"},
+		{"images", `2024\01\logo.png`, "images/2024/01/logo.png"},
+		{`docs\images`, "logo.png", "docs/images/logo.png"},
+		{"images", "images/logo.png", "images/logo.png"},
+		{`docs\images`, "docs/images/logo.png", "docs/images/logo.png"},
+		{"", "/logo.png", "logo.png"},
+	}
+	for _, tt := range tests {
+		if got := objectKey(tt.prefix, tt.remotePath); got != tt.want {
+			t.Errorf("objectKey(%q, %q) = %q, want %q", tt.prefix, tt.remotePath, got, tt.want)
+		}
+	}
+}
+
+func TestS3Presets(t *testing.T) {
+	tests := []struct {
+		provider, region string
+		endpoint, url    string
+		pathStyle        bool
+	}{
+		{"b2", "us-west-004", "https://s3.us-west-004.backblazeb2.com", "https://f004.backblazeb2.com/file/docs/img/a.png", false},
+		{"spaces", "nyc3", "https://nyc3.digitaloceanspaces.com", "https://docs.nyc3.digitaloceanspaces.com/img/a.png", false},
+		{"wasabi", "auto", "https://s3.us-east-1.wasabisys.com", "https://s3.us-east-1.wasabisys.com/docs/img/a.png", true},
+	}
+	for _, tt := range tests {
+		provider, ok := GetProvider(tt.provider)
+		if !ok {
+			t.Fatalf("provider %s is not registered", tt.provider)
+		}
+		p := provider.(*S3Provider)
+		if err := p.Configure(config.CloudConfig{Provider: tt.provider, Region: tt.region, Bucket: "docs"}); err != nil {
+			t.Fatalf("%s: Configure failed: %v", tt.provider, err)
+		}
+		if p.endpoint != tt.endpoint || *p.client.Config.S3ForcePathStyle != tt.pathStyle {
+			t.Errorf("%s: endpoint %s path style %v", tt.provider, p.endpoint, *p.client.Config.S3ForcePathStyle)
+		}
+		if got := p.GetPublicURL("img/a.png"); got != tt.url {
+			t.Errorf("%s: public URL %s, want %s", tt.provider, got, tt.url)
+		}
+	}
+
+	if err := NewS3Provider().Configure(config.CloudConfig{Provider: "spaces", Bucket: "docs"}); err == nil {
+		t.Error("expected an error for spaces without a region")
+	}
+	p := NewS3Provider()
+	p.Configure(config.CloudConfig{Provider: "b2", Region: "us-west-004", Bucket: "docs", CustomDomain: "img.example.com"})
+	if got := p.GetPublicURL("a.png"); got != "https://img.example.com/a.png" {
+		t.Errorf("expected the custom domain to win, got %s", got)
+	}
+}
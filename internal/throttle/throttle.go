@@ -0,0 +1,240 @@
+package throttle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/samzong/mdctl/internal/cache"
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/metrics"
+)
+
+// usageFile stores the daily AI usage inside the cache directory
+const usageFile = "ai-usage.json"
+
+// ErrQuotaExceeded is returned once the daily token quota is used up
+var ErrQuotaExceeded = errors.New("daily AI token quota exceeded")
+
+// logger reports throttling delays
+var logger = logging.New("THROTTLE")
+
+// Limits caps AI usage, a zero value disables the corresponding limit
+type Limits struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+	DailyTokenQuota   int
+}
+
+// Usage is the AI usage recorded for a single day
+type Usage struct {
+	Date     string `json:"date"`
+	Requests int    `json:"requests"`
+	Tokens   int    `json:"tokens"`
+}
+
+// event is a request admitted within the last minute
+type event struct {
+	at     time.Time
+	tokens int
+}
+
+// Limiter enforces per-minute rate limits and a daily token quota
+type Limiter struct {
+	limits    Limits
+	usagePath string
+	mu        sync.Mutex
+	window    []event
+	now       func() time.Time
+	sleep     func(context.Context, time.Duration) error
+}
+
+var (
+	sharedOnce sync.Once
+	shared     *Limiter
+)
+
+// New creates a limiter that keeps its daily usage in cacheDir
+func New(limits Limits, cacheDir string) *Limiter {
+	if cacheDir == "" {
+		cacheDir = cache.DefaultDir()
+	}
+	return &Limiter{
+		limits:    limits,
+		usagePath: filepath.Join(cacheDir, usageFile),
+		now:       time.Now,
+		sleep:     sleepContext,
+	}
+}
+
+// Shared returns the process-wide limiter, so every AI feature draws from the same budget.
+// The limits passed by the first caller win.
+func Shared(limits Limits) *Limiter {
+	sharedOnce.Do(func() {
+		shared = New(limits, "")
+	})
+	return shared
+}
+
+// EstimateTokens roughly estimates the token count of a text
+func EstimateTokens(text string) int {
+	return len(text)/4 + 1
+}
+
+// Wait blocks until a request of the estimated size fits the rate limits,
+// and fails if it would exceed the daily quota
+func (l *Limiter) Wait(ctx context.Context, estimate int) error {
+	if l.limits.DailyTokenQuota > 0 {
+		usage, err := l.Usage()
+		if err != nil {
+			return err
+		}
+		if usage.Tokens+estimate > l.limits.DailyTokenQuota {
+			return fmt.Errorf("%w: %d of %d tokens used today", ErrQuotaExceeded, usage.Tokens, l.limits.DailyTokenQuota)
+		}
+	}
+
+	for {
+		l.mu.Lock()
+		now := l.now()
+		l.prune(now)
+		delay := l.delay(now, estimate)
+		if delay <= 0 {
+			l.window = append(l.window, event{at: now, tokens: estimate})
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		logger.Debugf("Rate limit reached, waiting %s", delay.Round(time.Millisecond))
+		if err := l.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// sleepContext waits for d or until ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Record adds the tokens consumed by a finished request to the daily usage
+func (l *Limiter) Record(tokens int) error {
+	metrics.AddTokens(tokens)
+	if l.limits.DailyTokenQuota <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.usagePath), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %v", err)
+	}
+	// Other mdctl processes update the same file
+	unlock, err := fsutil.LockFile(l.usagePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	usage, err := l.loadUsage()
+	if err != nil {
+		return err
+	}
+	usage.Requests++
+	usage.Tokens += tokens
+
+	data, err := json.MarshalIndent(usage, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage: %v", err)
+	}
+
+	// Concurrent readers must never see a partial file
+	if err := fsutil.WriteFileAtomic(l.usagePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write usage file: %v", err)
+	}
+
+	return nil
+}
+
+// Usage returns the usage recorded for today
+func (l *Limiter) Usage() (Usage, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.loadUsage()
+}
+
+// loadUsage reads today's usage, starting from zero on a new day
+func (l *Limiter) loadUsage() (Usage, error) {
+	today := Usage{Date: l.now().Format("2006-01-02")}
+
+	data, err := os.ReadFile(l.usagePath)
+	if os.IsNotExist(err) {
+		return today, nil
+	}
+	if err != nil {
+		return today, fmt.Errorf("failed to read usage file: %v", err)
+	}
+
+	var usage Usage
+	if err := json.Unmarshal(data, &usage); err != nil || usage.Date != today.Date {
+		// A corrupt or outdated file starts a fresh day
+		return today, nil
+	}
+
+	return usage, nil
+}
+
+// prune drops events older than one minute
+func (l *Limiter) prune(now time.Time) {
+	cutoff := now.Add(-time.Minute)
+	i := 0
+	for i < len(l.window) && !l.window[i].at.After(cutoff) {
+		i++
+	}
+	l.window = l.window[i:]
+}
+
+// delay returns how long to wait before a request of the estimated size is admitted
+func (l *Limiter) delay(now time.Time, estimate int) time.Duration {
+	var wait time.Duration
+
+	if rpm := l.limits.RequestsPerMinute; rpm > 0 && len(l.window) >= rpm {
+		wait = l.window[len(l.window)-rpm].at.Add(time.Minute).Sub(now)
+	}
+
+	if tpm := l.limits.TokensPerMinute; tpm > 0 && len(l.window) > 0 {
+		total := estimate
+		for _, e := range l.window {
+			total += e.tokens
+		}
+		// Wait until enough of the oldest events expire; an oversized request
+		// is admitted on its own once the window is empty
+		for _, e := range l.window {
+			if total <= tpm {
+				break
+			}
+			total -= e.tokens
+			if d := e.at.Add(time.Minute).Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+
+	return wait
+}
@@ -0,0 +1,128 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock advances only when the limiter sleeps
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func newTestLimiter(t *testing.T, limits Limits) (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	l := New(limits, t.TempDir())
+	l.now = func() time.Time { return clock.now }
+	l.sleep = func(ctx context.Context, d time.Duration) error {
+		clock.now = clock.now.Add(d)
+		clock.slept += d
+		return nil
+	}
+	return l, clock
+}
+
+func TestLimiter_RequestsPerMinute(t *testing.T) {
+	l, clock := newTestLimiter(t, Limits{RequestsPerMinute: 2})
+
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background(), 10); err != nil {
+			t.Fatalf("Wait failed: %v", err)
+		}
+	}
+
+	if clock.slept != time.Minute {
+		t.Errorf("expected third request to wait one minute, waited %s", clock.slept)
+	}
+}
+
+func TestLimiter_TokensPerMinute(t *testing.T) {
+	l, clock := newTestLimiter(t, Limits{TokensPerMinute: 100})
+
+	if err := l.Wait(context.Background(), 80); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	clock.now = clock.now.Add(10 * time.Second)
+	if err := l.Wait(context.Background(), 10); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if clock.slept != 0 {
+		t.Fatalf("expected no wait within budget, waited %s", clock.slept)
+	}
+
+	// 80 + 10 + 50 exceeds the budget until the first request expires
+	if err := l.Wait(context.Background(), 50); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if clock.slept != 50*time.Second {
+		t.Errorf("expected to wait 50s, waited %s", clock.slept)
+	}
+
+	// A request larger than the whole budget is still admitted on an empty window
+	clock.now = clock.now.Add(2 * time.Minute)
+	if err := l.Wait(context.Background(), 500); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+}
+
+func TestLimiter_DailyQuota(t *testing.T) {
+	l, clock := newTestLimiter(t, Limits{DailyTokenQuota: 1000})
+
+	if err := l.Wait(context.Background(), 400); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if err := l.Record(900); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	err := l.Wait(context.Background(), 400)
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+
+	usage, err := l.Usage()
+	if err != nil {
+		t.Fatalf("Usage failed: %v", err)
+	}
+	if usage.Requests != 1 || usage.Tokens != 900 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+
+	// The quota resets on the next day
+	clock.now = clock.now.Add(24 * time.Hour)
+	if err := l.Wait(context.Background(), 400); err != nil {
+		t.Errorf("expected quota to reset, got %v", err)
+	}
+}
+
+func TestLimiter_RecordAcrossProcesses(t *testing.T) {
+	// Limiters of their own share nothing but the usage file, like processes
+	dir := t.TempDir()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l := New(Limits{DailyTokenQuota: 1 << 30}, dir)
+			for j := 0; j < 20; j++ {
+				if err := l.Record(10); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	usage, err := New(Limits{}, dir).Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Requests != 160 || usage.Tokens != 1600 {
+		t.Errorf("expected every record to count, got %+v", usage)
+	}
+}
@@ -0,0 +1,173 @@
+package translator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/mddoc"
+)
+
+// Bilingual modes keeping the source next to its translation
+const (
+	BilingualAlternate = "alternate" // Source paragraphs rendered before their translations
+	BilingualComments  = "comments"  // Source paragraphs kept in HTML comments
+)
+
+// Markers of the source blocks kept by the bilingual modes, StripSource
+// removes the blocks between them
+const (
+	sourceStart      = "<!-- mdctl-source -->"
+	sourceEnd        = "<!-- /mdctl-source -->"
+	sourceComment    = "<!-- mdctl-source"
+	sourceCommentEnd = "-->"
+)
+
+// ValidateBilingual checks a bilingual mode, empty turns the mode off
+func ValidateBilingual(mode string) error {
+	switch mode {
+	case "", BilingualAlternate, BilingualComments:
+		return nil
+	}
+	return fmt.Errorf("unsupported bilingual mode: %s (must be %s or %s)", mode, BilingualAlternate, BilingualComments)
+}
+
+// bilingual interleaves the blocks of a source and its translation: each
+// translated block follows its source block, kept visible or in a comment
+// depending on the mode. Blocks left as they are, such as code and images,
+// appear once. When the translation has a different number of blocks the
+// whole source precedes the translation and ok is false.
+func bilingual(source, translation, mode string) (string, bool) {
+	srcBlocks := splitBlocks(strings.TrimSpace(source))
+	dstBlocks := splitBlocks(strings.TrimSpace(translation))
+	ok := len(srcBlocks) == len(dstBlocks)
+	if !ok {
+		srcBlocks = []string{strings.TrimSpace(source)}
+		dstBlocks = []string{strings.TrimSpace(translation)}
+	}
+
+	var b strings.Builder
+	for i, dst := range dstBlocks {
+		if src := srcBlocks[i]; src != dst && strings.TrimSpace(src) != "" {
+			b.WriteString(sourceBlock(src, mode))
+			b.WriteString("\n\n")
+		}
+		b.WriteString(dst)
+		b.WriteString("\n\n")
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n", ok
+}
+
+// sourceBlock wraps a source block in the markers of the mode
+func sourceBlock(src, mode string) string {
+	if mode == BilingualComments {
+		// The comment would end at the first -->
+		src = strings.ReplaceAll(src, "-->", "-- >")
+		return sourceComment + "\n" + src + "\n" + sourceCommentEnd
+	}
+	return sourceStart + "\n\n" + src + "\n\n" + sourceEnd
+}
+
+// StripSource removes the source blocks a bilingual translation kept, leaving
+// the translation alone. It returns the content and the number of blocks
+// removed, a block whose end marker is missing is kept.
+func StripSource(content string) (string, int) {
+	lines := strings.Split(content, "\n")
+	out := make([]string, 0, len(lines))
+	removed := 0
+	var fences mddoc.Fences
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if fences.Line(line) {
+			out = append(out, line)
+			continue
+		}
+
+		var end string
+		switch strings.TrimSpace(line) {
+		case sourceStart:
+			end = sourceEnd
+		case sourceComment:
+			end = sourceCommentEnd
+		default:
+			out = append(out, line)
+			continue
+		}
+
+		j := i + 1
+		for j < len(lines) && strings.TrimSpace(lines[j]) != end {
+			j++
+		}
+		if j == len(lines) {
+			out = append(out, line)
+			continue
+		}
+		// Drop the blank lines separating the block from the translation
+		for j+1 < len(lines) && strings.TrimSpace(lines[j+1]) == "" {
+			j++
+		}
+		i = j
+		removed++
+	}
+	return strings.Join(out, "\n"), removed
+}
+
+// StripResult is a file whose kept source was removed
+type StripResult struct {
+	File   string `json:"file"`
+	Blocks int    `json:"blocks"` // Source blocks removed
+}
+
+// StripSourceFiles removes the kept source from a bilingual translation or
+// the markdown files below a directory, dry runs only report the files
+func StripSourceFiles(root string, dryRun bool) ([]StripResult, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access %s: %v", root, err)
+	}
+	var files []string
+	if !info.IsDir() {
+		files = []string{root}
+	} else {
+		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path != root && (info.Name() == "node_modules" || strings.HasPrefix(info.Name(), ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if filepath.Ext(path) == ".md" || IsMDX(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %v", err)
+		}
+	}
+
+	var results []StripResult
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return results, fmt.Errorf("failed to read %s: %v", file, err)
+		}
+		stripped, n := StripSource(string(content))
+		if n == 0 {
+			continue
+		}
+		if !dryRun {
+			if err := fsutil.WriteFileAtomic(file, []byte(stripped), 0644); err != nil {
+				return results, fmt.Errorf("failed to write %s: %v", file, err)
+			}
+		}
+		results = append(results, StripResult{File: file, Blocks: n})
+	}
+	return results, nil
+}
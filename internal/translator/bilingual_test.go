@@ -0,0 +1,68 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBilingual(t *testing.T) {
+	source := "# Title\n\nHello world.\n\n```sh\nmake\n```\n\nSee --> here.\n"
+	translation := "# 标题\n\n你好，世界。\n\n```sh\nmake\n```\n\n见 --> 这里。\n"
+
+	got, ok := bilingual(source, translation, BilingualAlternate)
+	want := "<!-- mdctl-source -->\n\n# Title\n\n<!-- /mdctl-source -->\n\n# 标题\n\n" +
+		"<!-- mdctl-source -->\n\nHello world.\n\n<!-- /mdctl-source -->\n\n你好，世界。\n\n" +
+		"```sh\nmake\n```\n\n" +
+		"<!-- mdctl-source -->\n\nSee --> here.\n\n<!-- /mdctl-source -->\n\n见 --> 这里。\n"
+	if !ok || got != want {
+		t.Errorf("alternate mode:\n%s\nwant:\n%s", got, want)
+	}
+	if stripped, n := StripSource(got); n != 3 || stripped != strings.TrimSpace(translation)+"\n" {
+		t.Errorf("StripSource removed %d blocks:\n%s", n, stripped)
+	}
+
+	got, ok = bilingual(source, translation, BilingualComments)
+	if !ok || !strings.Contains(got, "<!-- mdctl-source\nSee -- > here.\n-->\n\n见 --> 这里。") {
+		t.Errorf("comments mode:\n%s", got)
+	}
+	if stripped, n := StripSource(got); n != 3 || stripped != strings.TrimSpace(translation)+"\n" {
+		t.Errorf("StripSource removed %d blocks:\n%s", n, stripped)
+	}
+
+	got, ok = bilingual("One.\n\nTwo.\n", "一和二。\n", BilingualComments)
+	if ok || got != "<!-- mdctl-source\nOne.\n\nTwo.\n-->\n\n一和二。\n" {
+		t.Errorf("expected the whole source before a translation with other paragraphs, got %v:\n%s", ok, got)
+	}
+}
+
+func TestStripSourceKeepsCodeAndUnterminated(t *testing.T) {
+	content := "```md\n<!-- mdctl-source -->\n\nExample\n\n<!-- /mdctl-source -->\n```\n\n<!-- mdctl-source\nno end\n"
+	if got, n := StripSource(content); n != 0 || got != content {
+		t.Errorf("expected code and unterminated blocks to stay, removed %d:\n%s", n, got)
+	}
+}
+
+func TestStripSourceFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "guide"), 0755)
+	bilingualDoc := "---\ntranslated: true\n---\n\n<!-- mdctl-source -->\n\nHello\n\n<!-- /mdctl-source -->\n\n你好\n"
+	os.WriteFile(filepath.Join(dir, "guide", "a.md"), []byte(bilingualDoc), 0644)
+	os.WriteFile(filepath.Join(dir, "b.md"), []byte("Plain\n"), 0644)
+
+	results, err := StripSourceFiles(dir, true)
+	if err != nil || len(results) != 1 || results[0].Blocks != 1 {
+		t.Fatalf("unexpected dry run results %+v, %v", results, err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "guide", "a.md")); string(content) != bilingualDoc {
+		t.Errorf("dry run changed the file:\n%s", content)
+	}
+
+	if _, err := StripSourceFiles(dir, false); err != nil {
+		t.Fatalf("StripSourceFiles failed: %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "guide", "a.md")); string(content) != "---\ntranslated: true\n---\n\n你好\n" {
+		t.Errorf("unexpected stripped file:\n%s", content)
+	}
+}
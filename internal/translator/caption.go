@@ -0,0 +1,37 @@
+package translator
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/throttle"
+)
+
+// captionPrompt asks a vision-capable model for the alt text of an image
+const captionPrompt = "Write the alt text of the image for a technical documentation page: " +
+	"one sentence of at most 15 words describing what the image shows, without phrases such as \"Image of\". " +
+	"Output ONLY the alt text, without quotes or markdown formatting."
+
+// captionImageTokens is the token estimate of an image in a request
+const captionImageTokens = 1000
+
+// Caption describes an image in a short sentence for its alt text. The
+// configured model has to accept images.
+func (t *Translator) Caption(image []byte, mimeType string) (string, error) {
+	messages := []OpenAIMessage{
+		{Role: "system", Content: captionPrompt},
+		{Role: "user", Parts: []ContentPart{
+			{Type: "image_url", ImageURL: &ImageURL{URL: "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(image)}},
+		}},
+	}
+	caption, err := t.chatMessages(messages, captionImageTokens+2*throttle.EstimateTokens(captionPrompt))
+	if err != nil {
+		return "", err
+	}
+	caption = strings.Trim(strings.Join(strings.Fields(caption), " "), "\"'")
+	if caption == "" {
+		return "", fmt.Errorf("empty caption")
+	}
+	return caption, nil
+}
@@ -0,0 +1,49 @@
+package translator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+func TestCaption(t *testing.T) {
+	var request struct {
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&request)
+		replyJSON(w, "\"Login form  with two fields\"\n")
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig
+	cfg.OpenAIEndpointURL = server.URL
+	caption, err := New(&cfg, false).Caption([]byte("png"), "image/png")
+	if err != nil {
+		t.Fatalf("Caption failed: %v", err)
+	}
+	if caption != "Login form with two fields" {
+		t.Errorf("unexpected caption %q", caption)
+	}
+
+	if len(request.Messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(request.Messages))
+	}
+	var parts []ContentPart
+	if err := json.Unmarshal(request.Messages[1].Content, &parts); err != nil {
+		t.Fatalf("expected content parts, got %s", request.Messages[1].Content)
+	}
+	if len(parts) != 1 || parts[0].Type != "image_url" || parts[0].ImageURL.URL != "data:image/png;base64,cG5n" {
+		t.Errorf("unexpected parts: %+v", parts)
+	}
+	var system string
+	if err := json.Unmarshal(request.Messages[0].Content, &system); err != nil || system != captionPrompt {
+		t.Errorf("expected the caption prompt as plain content, got %s", request.Messages[0].Content)
+	}
+}
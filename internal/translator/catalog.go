@@ -0,0 +1,443 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/fsutil"
+	"gopkg.in/yaml.v3"
+)
+
+// catalogPrompt is the system prompt used when translating string catalogs
+const catalogPrompt = "Translate every value of the JSON object in the user message to {TARGET_LANG} as a native speaker. " +
+	"Keep every key unchanged. Preserve placeholders such as {{ .Count }}, {name}, %s and %d, HTML tags and markdown syntax. " +
+	"Output ONLY the resulting JSON object."
+
+// catalogBatchSize limits how many strings are sent to the model in one request
+const catalogBatchSize = 50
+
+// catalogExtensions lists the string catalog formats that can be translated
+var catalogExtensions = map[string]bool{
+	".yaml": true,
+	".yml":  true,
+	".json": true,
+	".toml": true,
+}
+
+// tomlStringLine matches a TOML key/value line whose value is a basic string
+var tomlStringLine = regexp.MustCompile(`^(\s*[A-Za-z0-9_\-."']+\s*=\s*)("(?:[^"\\]|\\.)*")(\s*(?:#.*)?)$`)
+
+// catalogEntry is a single translatable string inside a catalog
+type catalogEntry struct {
+	value string
+	set   func(string)
+}
+
+// IsCatalogFile checks if the file is a YAML/TOML/JSON string catalog
+func IsCatalogFile(path string) bool {
+	return catalogExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// TranslateStrings translates a set of strings, returning the translations keyed by the original string
+func (t *Translator) TranslateStrings(values []string, lang string) (map[string]string, error) {
+	// Deduplicate values so repeated strings are only translated once
+	unique := make(map[string]bool)
+	var pending []string
+	for _, v := range values {
+		if strings.TrimSpace(v) == "" || unique[v] {
+			continue
+		}
+		unique[v] = true
+		pending = append(pending, v)
+	}
+
+	prompt := strings.ReplaceAll(catalogPrompt, "{TARGET_LANG}", lang)
+	translations := make(map[string]string, len(pending))
+
+	for start := 0; start < len(pending); start += catalogBatchSize {
+		end := start + catalogBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+
+		batch := make(map[string]string, end-start)
+		for i, v := range pending[start:end] {
+			batch[strconv.Itoa(start+i)] = v
+		}
+
+		payload, err := json.Marshal(batch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal strings: %v", err)
+		}
+
+		reply, err := t.chat(prompt, string(payload))
+		if err != nil {
+			return nil, err
+		}
+
+		var translated map[string]string
+		if err := json.Unmarshal([]byte(extractJSONObject(reply)), &translated); err != nil {
+			return nil, fmt.Errorf("failed to parse translated strings: %v\nResponse: %s", err, reply)
+		}
+
+		for id, original := range batch {
+			value, ok := translated[id]
+			if !ok || value == "" {
+				return nil, fmt.Errorf("translation missing for string %q", original)
+			}
+			translations[original] = value
+		}
+	}
+
+	return translations, nil
+}
+
+// extractJSONObject strips code fences and surrounding text from a model reply
+func extractJSONObject(reply string) string {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start == -1 || end < start {
+		return reply
+	}
+	return reply[start : end+1]
+}
+
+// translateCatalogFile translates the values (not keys) of a YAML/TOML/JSON string catalog,
+// reporting whether the target was written
+func translateCatalogFile(ctx context.Context, srcPath, dstPath, targetLang string, cfg *config.Config, opts Options) (outcome, error) {
+	t := New(cfg, false).WithContext(ctx)
+
+	// Check if target path is a directory
+	dstInfo, err := os.Stat(dstPath)
+	if err == nil && dstInfo.IsDir() {
+		dstPath = filepath.Join(dstPath, filepath.Base(srcPath))
+	}
+
+	// Catalogs carry no front matter, so an existing target counts as translated
+	if dstPath != srcPath {
+		if _, err := os.Stat(dstPath); err == nil && !opts.Force {
+			logger.Infof("Skipping %s (target already exists, use -F to force translate)", srcPath)
+			return outcome{status: statusSkipped}, nil
+		}
+	}
+
+	content, err := os.ReadFile(srcPath)
+	if err != nil {
+		return outcome{}, fmt.Errorf("failed to read source file: %v", err)
+	}
+
+	var entries []catalogEntry
+	var render func() ([]byte, error)
+
+	switch strings.ToLower(filepath.Ext(srcPath)) {
+	case ".yaml", ".yml":
+		entries, render, err = parseYAMLCatalog(content)
+	case ".json":
+		entries, render, err = parseJSONCatalog(content)
+	case ".toml":
+		entries, render, err = parseTOMLCatalog(content)
+	default:
+		return outcome{}, fmt.Errorf("unsupported catalog format: %s", srcPath)
+	}
+	if err != nil {
+		return outcome{}, fmt.Errorf("failed to parse catalog %s: %v", srcPath, err)
+	}
+
+	values := make([]string, len(entries))
+	for i, entry := range entries {
+		values[i] = entry.value
+	}
+
+	if opts.DryRun {
+		prompt := strings.ReplaceAll(catalogPrompt, "{TARGET_LANG}", targetLang)
+		tokens := estimateTokens(prompt, strings.Join(values, "\n"))
+		logger.Infof("Would translate %s -> %s (~%d tokens)", srcPath, dstPath, tokens)
+		return outcome{status: statusPlanned, tokens: tokens}, nil
+	}
+
+	translations, err := t.TranslateStrings(values, targetLang)
+	if err != nil {
+		return outcome{}, fmt.Errorf("failed to translate catalog: %v", err)
+	}
+
+	for _, entry := range entries {
+		if translated, ok := translations[entry.value]; ok {
+			entry.set(translated)
+		}
+	}
+
+	output, err := render()
+	if err != nil {
+		return outcome{}, fmt.Errorf("failed to render catalog: %v", err)
+	}
+
+	// Create target directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return outcome{}, fmt.Errorf("failed to create target directory: %v", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(dstPath, output, 0644); err != nil {
+		return outcome{}, fmt.Errorf("failed to write target file: %v", err)
+	}
+
+	return outcome{status: statusTranslated}, nil
+}
+
+// parseYAMLCatalog collects string scalars from a YAML document, keeping comments and key order
+func parseYAMLCatalog(content []byte) ([]catalogEntry, func() ([]byte, error), error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, nil, err
+	}
+
+	var entries []catalogEntry
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		switch node.Kind {
+		case yaml.DocumentNode, yaml.SequenceNode:
+			for _, child := range node.Content {
+				walk(child)
+			}
+		case yaml.MappingNode:
+			// Content alternates key and value nodes, only values are translated
+			for i := 1; i < len(node.Content); i += 2 {
+				walk(node.Content[i])
+			}
+		case yaml.ScalarNode:
+			if node.Tag == "!!str" && node.Value != "" {
+				n := node
+				entries = append(entries, catalogEntry{
+					value: n.Value,
+					set:   func(v string) { n.Value = v },
+				})
+			}
+		}
+	}
+	walk(&root)
+
+	render := func() ([]byte, error) {
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&root); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	return entries, render, nil
+}
+
+// parseJSONCatalog collects string values from a JSON document. Only the
+// values are replaced in the original bytes, so key order, indentation and
+// everything else of the document are kept.
+func parseJSONCatalog(content []byte) ([]catalogEntry, func() ([]byte, error), error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	// A span is the position of a quoted value in content and its new value
+	type span struct {
+		start, end int
+		value      string
+		set        bool
+	}
+	var spans []*span
+
+	// The objects and arrays the tokens are in, keys and values of an
+	// object alternate
+	type container struct{ object, key bool }
+	var stack []*container
+	var offset int64
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		prev := offset
+		offset = decoder.InputOffset()
+
+		if token == json.Delim('}') || token == json.Delim(']') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		isKey := false
+		if len(stack) > 0 && stack[len(stack)-1].object {
+			top := stack[len(stack)-1]
+			isKey, top.key = top.key, !top.key
+		}
+		if token == json.Delim('{') || token == json.Delim('[') {
+			stack = append(stack, &container{object: token == json.Delim('{'), key: true})
+			continue
+		}
+		value, ok := token.(string)
+		if !ok || isKey || value == "" {
+			continue
+		}
+		// The value starts at the first quote after the previous token,
+		// only whitespace, colons and commas are in between
+		start := int(prev) + bytes.IndexByte(content[prev:offset], '"')
+		spans = append(spans, &span{start: start, end: int(offset), value: value})
+	}
+
+	entries := make([]catalogEntry, len(spans))
+	for i, s := range spans {
+		entries[i] = catalogEntry{value: s.value, set: func(v string) { s.value, s.set = v, true }}
+	}
+
+	render := func() ([]byte, error) {
+		var buf bytes.Buffer
+		last := 0
+		for _, s := range spans {
+			if !s.set {
+				continue
+			}
+			buf.Write(content[last:s.start])
+			var quoted bytes.Buffer
+			encoder := json.NewEncoder(&quoted)
+			encoder.SetEscapeHTML(false)
+			if err := encoder.Encode(s.value); err != nil {
+				return nil, err
+			}
+			buf.Write(bytes.TrimSuffix(quoted.Bytes(), []byte("\n")))
+			last = s.end
+		}
+		buf.Write(content[last:])
+		return buf.Bytes(), nil
+	}
+
+	return entries, render, nil
+}
+
+// parseTOMLCatalog collects basic string values from a TOML file line by line,
+// which covers Hugo style i18n files while leaving tables, comments and keys untouched
+func parseTOMLCatalog(content []byte) ([]catalogEntry, func() ([]byte, error), error) {
+	lines := strings.Split(string(content), "\n")
+
+	var entries []catalogEntry
+	for i, line := range lines {
+		matches := tomlStringLine.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		value, err := tomlUnquote(matches[2])
+		if err != nil || value == "" {
+			continue
+		}
+
+		idx, prefix, suffix := i, matches[1], matches[3]
+		entries = append(entries, catalogEntry{
+			value: value,
+			set: func(v string) {
+				lines[idx] = prefix + tomlQuote(v) + suffix
+			},
+		})
+	}
+
+	render := func() ([]byte, error) {
+		return []byte(strings.Join(lines, "\n")), nil
+	}
+
+	return entries, render, nil
+}
+
+// tomlQuote returns s as a TOML basic string, control characters without a
+// short escape are written as \uXXXX
+func tomlQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// tomlUnquote returns the value of a TOML basic string, it fails on escapes
+// TOML does not define
+func tomlUnquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("invalid TOML string: %s", s)
+	}
+	s = s[1 : len(s)-1]
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i == len(s) {
+			return "", fmt.Errorf("invalid TOML escape at the end of %q", s)
+		}
+		switch c := s[i]; c {
+		case '"', '\\':
+			b.WriteByte(c)
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case 'u', 'U':
+			n := 4
+			if c == 'U' {
+				n = 8
+			}
+			if i+n >= len(s) {
+				return "", fmt.Errorf("invalid TOML escape in %q", s)
+			}
+			code, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", fmt.Errorf("invalid TOML escape in %q", s)
+			}
+			b.WriteRune(rune(code))
+			i += n
+		default:
+			return "", fmt.Errorf("invalid TOML escape \\%c in %q", c, s)
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,99 @@
+package translator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCatalogRoundTrip(t *testing.T) {
+	// Translations with characters every format has to escape
+	translate := map[string]string{
+		"Hello":        "Bonjour \"monde\"",
+		"Path":         `C:\docs\ tab	end`,
+		"Lines":        "ligne 1\nligne 2\r\n",
+		"Control":      "bell \a escape \x1b delete \x7f",
+		"{{ .Count }}": "{{ .Count }} éléments 🎉",
+	}
+
+	tests := []struct {
+		name    string
+		parse   func([]byte) ([]catalogEntry, func() ([]byte, error), error)
+		content string
+		keep    []string // Parts of the source that are kept
+	}{
+		{"yaml", parseYAMLCatalog, "# Greetings\nhello: Hello\npath: Path\nnested:\n  lines: Lines\n  control: Control\n  count: \"{{ .Count }}\"\nnumber: 3\n", []string{"# Greetings", "number: 3"}},
+		{"json", parseJSONCatalog, "{\n    \"hello\": \"Hello\",\n    \"path\": \"Path\",\n    \"nested\": {\"lines\": \"Lines\", \"control\": [\"Control\"]},\n    \"count\": \"{{ .Count }}\",\n    \"number\": 3\n}\n", []string{"{\n    \"hello\": \"Bonjour", "\",\n    \"path\": \"", "\",\n    \"nested\": {\"lines\": \"", "\", \"control\": [\"", "\"]},\n    \"count\": \"", "\",\n    \"number\": 3\n}\n"}},
+		{"toml", parseTOMLCatalog, "# Greetings\nhello = \"Hello\"\npath = \"Path\" # comment\n\n[nested]\nlines = \"Lines\"\ncontrol = \"Control\"\ncount = \"{{ .Count }}\"\nnumber = 3\n", []string{"# Greetings", "# comment", "[nested]", "number = 3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, render, err := tt.parse([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("parse failed: %v", err)
+			}
+			if len(entries) != len(translate) {
+				t.Fatalf("expected %d entries, got %d", len(translate), len(entries))
+			}
+			var want []string
+			for _, entry := range entries {
+				translated, ok := translate[entry.value]
+				if !ok {
+					t.Fatalf("unexpected entry %q", entry.value)
+				}
+				entry.set(translated)
+				want = append(want, translated)
+			}
+			output, err := render()
+			if err != nil {
+				t.Fatalf("render failed: %v", err)
+			}
+			for _, keep := range tt.keep {
+				if !strings.Contains(string(output), keep) {
+					t.Errorf("expected %q to be kept in:\n%s", keep, output)
+				}
+			}
+
+			entries, _, err = tt.parse(output)
+			if err != nil {
+				t.Fatalf("rendered catalog does not parse: %v\n%s", err, output)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.value)
+			}
+			if strings.Join(got, "|") != strings.Join(want, "|") {
+				t.Errorf("round trip changed the values:\n got %q\nwant %q", got, want)
+			}
+		})
+	}
+}
+
+func TestTOMLQuote(t *testing.T) {
+	tests := []struct {
+		value, quoted string
+	}{
+		{"plain", `"plain"`},
+		{"a \"b\" \\ c", `"a \"b\" \\ c"`},
+		{"tab\tnew\nfeed\f\rback\b", `"tab\tnew\nfeed\f\rback\b"`},
+		{"bell \a esc \x1b del \x7f", `"bell \u0007 esc \u001B del \u007F"`},
+		{"日本語 🎉", `"日本語 🎉"`},
+	}
+	for _, tt := range tests {
+		if got := tomlQuote(tt.value); got != tt.quoted {
+			t.Errorf("tomlQuote(%q) = %s, want %s", tt.value, got, tt.quoted)
+		}
+		if got, err := tomlUnquote(tt.quoted); err != nil || got != tt.value {
+			t.Errorf("tomlUnquote(%s) = %q, %v, want %q", tt.quoted, got, err, tt.value)
+		}
+	}
+
+	if got, err := tomlUnquote(`"\U0001F389 \u00e9"`); err != nil || got != "🎉 é" {
+		t.Errorf("tomlUnquote of unicode escapes = %q, %v", got, err)
+	}
+	// Escapes of Go that TOML does not define
+	for _, invalid := range []string{`"\x41"`, `"\a"`, `"\u12"`, `"\uD800"`, `"trailing\"`} {
+		if _, err := tomlUnquote(invalid); err == nil {
+			t.Errorf("expected an error for %s", invalid)
+		}
+	}
+}
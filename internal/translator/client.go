@@ -0,0 +1,239 @@
+package translator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of the request policy of AI calls
+const (
+	DefaultTimeout = 2 * time.Minute // Per request, or without data while streaming
+	DefaultRetries = 3
+)
+
+var (
+	// retryBackoff is the delay before the first retry, doubled for every
+	// further one up to maxBackoff
+	retryBackoff = time.Second
+	maxBackoff   = 30 * time.Second
+	// maxRetryAfter caps the delay a server asks for with Retry-After
+	maxRetryAfter = 2 * time.Minute
+)
+
+// retryableError is a failed request worth repeating, after is the delay
+// the server asked for
+type retryableError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+
+// streamChunk is a server-sent event of a streamed chat completion
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// complete sends a chat completion request and returns the reply and the
+// tokens the server reports. Network errors, timeouts, 429 and 5xx
+// responses are retried with exponential backoff and jitter, or after the
+// delay of a Retry-After header.
+func (t *Translator) complete(request OpenAIRequest, estimate int) (string, int, error) {
+	request.Stream = t.stream
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter.Wait(t.ctx, estimate); err != nil {
+			return "", 0, err
+		}
+		reply, used, err := t.send(data)
+		var retryable *retryableError
+		if err == nil || !errors.As(err, &retryable) || attempt >= t.retries {
+			return reply, used, err
+		}
+
+		delay := backoff(attempt, retryable.after)
+		logger.Warnf("AI request failed: %v, retrying in %s (%d/%d)", err, delay.Round(time.Millisecond), attempt+1, t.retries)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-t.ctx.Done():
+			timer.Stop()
+			return "", 0, t.ctx.Err()
+		}
+	}
+}
+
+// send makes a single request. The timeout covers the whole request, or
+// the time without data of a streamed one.
+func (t *Translator) send(data []byte) (string, int, error) {
+	ctx, cancel := context.WithCancel(t.ctx)
+	defer cancel()
+	timer := time.AfterFunc(t.timeout, cancel)
+	defer timer.Stop()
+
+	// timedOut turns errors of a request the timeout canceled into a retry
+	timedOut := func(err error) error {
+		if t.ctx.Err() == nil && ctx.Err() != nil {
+			return &retryableError{err: fmt.Errorf("request timed out after %s", t.timeout)}
+		}
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.config.OpenAIEndpointURL+"/chat/completions", bytes.NewReader(data))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.config.OpenAIAPIKey)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if t.ctx.Err() != nil {
+			return "", 0, t.ctx.Err()
+		}
+		if ctx.Err() != nil {
+			return "", 0, timedOut(err)
+		}
+		return "", 0, &retryableError{err: fmt.Errorf("failed to send request: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", 0, &retryableError{
+			err:   fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(body))),
+			after: retryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	if t.stream && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		reply, used, err := readStream(resp.Body, func() { timer.Reset(t.timeout) })
+		if err != nil {
+			return "", 0, timedOut(err)
+		}
+		return reply, used, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, timedOut(fmt.Errorf("failed to read response: %v", err))
+	}
+	if err := statusError(strings.TrimRight(t.config.OpenAIEndpointURL, "/"), resp, body); err != nil {
+		return "", 0, err
+	}
+
+	var response OpenAIResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", 0, fmt.Errorf("failed to parse response: %v\nResponse body: %s", err, string(body))
+	}
+	if len(response.Choices) == 0 {
+		return "", 0, fmt.Errorf("no translation result\nResponse body: %s", string(body))
+	}
+	return response.Choices[0].Message.Content, response.Usage.TotalTokens, nil
+}
+
+// readStream collects the content of a streamed chat completion, calling
+// received for every event. A stream that ends before [DONE] or a finish
+// reason was cut off, its partial reply is dropped and the request retried.
+func readStream(r io.Reader, received func()) (string, int, error) {
+	var reply strings.Builder
+	used := 0
+	finished := false
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		received()
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if payload == "[DONE]" {
+			return reply.String(), used, nil
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+			return "", 0, fmt.Errorf("failed to parse stream event: %v\nEvent: %s", err, payload)
+		}
+		if chunk.Error != nil {
+			return "", 0, fmt.Errorf("stream failed: %s", chunk.Error.Message)
+		}
+		for _, choice := range chunk.Choices {
+			reply.WriteString(choice.Delta.Content)
+			finished = finished || choice.FinishReason != ""
+		}
+		if chunk.Usage != nil {
+			used = chunk.Usage.TotalTokens
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", 0, &retryableError{err: fmt.Errorf("failed to read stream: %v", err)}
+	}
+	if !finished {
+		return "", 0, &retryableError{err: fmt.Errorf("the stream ended before the reply was complete")}
+	}
+	if reply.Len() == 0 {
+		return "", 0, fmt.Errorf("no translation result, the stream ended without content")
+	}
+	return reply.String(), used, nil
+}
+
+// backoff returns the delay before a retry: the delay the server asked for,
+// or an exponentially growing one with jitter
+func backoff(attempt int, after time.Duration) time.Duration {
+	if after > 0 {
+		if after > maxRetryAfter {
+			return maxRetryAfter
+		}
+		return after
+	}
+	delay := retryBackoff << attempt
+	if delay > maxBackoff || delay <= 0 {
+		delay = maxBackoff
+	}
+	// A random delay between half and the whole backoff keeps concurrent
+	// clients from retrying in lockstep
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryAfter parses a Retry-After header given in seconds or as a date
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(header)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
@@ -0,0 +1,176 @@
+package translator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+func replyJSON(w http.ResponseWriter, content string) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"choices": []map[string]interface{}{{"message": map[string]string{"content": content}}},
+		"usage":   map[string]int{"total_tokens": 42},
+	})
+}
+
+func TestChatRetries(t *testing.T) {
+	retryBackoff = time.Millisecond
+	defer func() { retryBackoff = time.Second }()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		case 2:
+			http.Error(w, "overloaded", http.StatusBadGateway)
+		default:
+			replyJSON(w, "Hallo")
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig
+	cfg.OpenAIEndpointURL = server.URL
+	reply, err := New(&cfg, false).Chat("translate", "Hello")
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if reply != "Hallo" || calls != 3 {
+		t.Errorf("got %q after %d calls", reply, calls)
+	}
+
+	// Client errors are not retried, exhausted retries return the last error
+	atomic.StoreInt32(&calls, 0)
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "bad key", http.StatusUnauthorized)
+	})
+	if _, err := New(&cfg, false).Chat("translate", "Hello"); err == nil || !strings.Contains(err.Error(), "401") || calls != 1 {
+		t.Errorf("expected a single failed call, got %d calls: %v", calls, err)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	})
+	cfg.AIMaxRetries = 1
+	if _, err := New(&cfg, false).Chat("translate", "Hello"); err == nil || calls != 2 {
+		t.Errorf("expected 2 calls, got %d: %v", calls, err)
+	}
+}
+
+func TestChatTimeout(t *testing.T) {
+	retryBackoff = time.Millisecond
+	defer func() { retryBackoff = time.Second }()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		replyJSON(w, "done")
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig
+	cfg.OpenAIEndpointURL = server.URL
+	translator := New(&cfg, false)
+	translator.timeout = 50 * time.Millisecond
+	reply, err := translator.Chat("translate", "Hello")
+	if err != nil || reply != "done" || calls != 2 {
+		t.Errorf("expected a retry after the timeout, got %q after %d calls: %v", reply, calls, err)
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	var stream bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		stream = req.Stream
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, part := range []string{"Hal", "lo ", "Welt"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", part)
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"total_tokens\":12}}\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig
+	cfg.OpenAIEndpointURL = server.URL
+	cfg.AIStream = true
+	reply, err := New(&cfg, false).Chat("translate", "Hello world")
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if !stream || reply != "Hallo Welt" {
+		t.Errorf("unexpected reply %q (stream requested: %v)", reply, stream)
+	}
+}
+
+func TestChatStreamCutOff(t *testing.T) {
+	retryBackoff = time.Millisecond
+	defer func() { retryBackoff = time.Second }()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hallo \"}}]}\n\n")
+		if requests == 1 {
+			// The connection closes mid-reply
+			return
+		}
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Welt\"},\"finish_reason\":\"stop\"}]}\n\n")
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig
+	cfg.OpenAIEndpointURL = server.URL
+	cfg.AIStream = true
+	cfg.AIMaxRetries = 1
+	reply, err := New(&cfg, false).Chat("translate", "Hello world")
+	if err != nil || reply != "Hallo Welt" || requests != 2 {
+		t.Errorf("expected the cut off stream to be retried, got %q, %v after %d requests", reply, err, requests)
+	}
+
+	cfg.AIMaxRetries = -1
+	requests = 0
+	if reply, err := New(&cfg, false).Chat("translate", "Hello world"); err == nil {
+		t.Errorf("expected an error for a cut off stream, got %q", reply)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if got := retryAfter("7"); got != 7*time.Second {
+		t.Errorf("retryAfter(7) = %s", got)
+	}
+	date := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	if got := retryAfter(date); got < 25*time.Second || got > 30*time.Second {
+		t.Errorf("retryAfter(%s) = %s", date, got)
+	}
+	if got := retryAfter("soon"); got != 0 {
+		t.Errorf("retryAfter(soon) = %s", got)
+	}
+	if got := backoff(2, 0); got < 2*time.Second || got > 4*time.Second {
+		t.Errorf("backoff(2) = %s, want between 2s and 4s", got)
+	}
+	if got := backoff(0, time.Hour); got != maxRetryAfter {
+		t.Errorf("backoff with a long Retry-After = %s, want %s", got, maxRetryAfter)
+	}
+}
@@ -0,0 +1,160 @@
+package translator
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/mddoc"
+)
+
+// relinker keeps relative links and images valid when a translation is
+// written to a different directory than its source
+type relinker struct {
+	srcDir     string
+	dstDir     string
+	srcRoot    string // Tree mirrored along with the file, links into it are kept
+	copyAssets bool
+}
+
+// newRelinker returns a relinker for srcPath translated to dstPath, or nil
+// when both are in the same directory
+func newRelinker(srcPath, dstPath string, opts Options) (*relinker, error) {
+	srcAbs, err := filepath.Abs(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	dstAbs, err := filepath.Abs(dstPath)
+	if err != nil {
+		return nil, err
+	}
+	r := &relinker{
+		srcDir:     filepath.Dir(srcAbs),
+		dstDir:     filepath.Dir(dstAbs),
+		copyAssets: opts.CopyAssets,
+	}
+	if r.srcDir == r.dstDir {
+		return nil, nil
+	}
+
+	if opts.SourceRoot != "" {
+		if r.srcRoot, err = filepath.Abs(opts.SourceRoot); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// rewrite updates the relative references of markdown content, found in the
+// markdown AST so that code blocks and code spans are left untouched
+func (r *relinker) rewrite(content string) (string, error) {
+	var firstErr error
+	out, _ := mddoc.Parse([]byte(content)).ReplaceLinks(func(link mddoc.Link) (string, bool) {
+		dest, err := r.rewriteDest(link.Destination, link.Angled)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return "", false
+		}
+		return dest, true
+	})
+	return string(out), firstErr
+}
+
+// rewriteDest returns the destination as seen from the target directory,
+// spaces are escaped unless it is written in angle brackets
+func (r *relinker) rewriteDest(dest string, angled bool) (string, error) {
+	target, suffix := dest, ""
+	if i := strings.IndexAny(target, "?#"); i >= 0 {
+		target, suffix = target[:i], target[i:]
+	}
+	if !isRelativeRef(target) {
+		return dest, nil
+	}
+
+	unescaped, err := url.PathUnescape(target)
+	if err != nil {
+		return dest, nil
+	}
+	srcFile := filepath.Join(r.srcDir, filepath.FromSlash(unescaped))
+
+	// Documents translated into the mirrored tree keep their relative links
+	if r.srcRoot != "" && isTranslatedDoc(srcFile) {
+		if rel, err := filepath.Rel(r.srcRoot, srcFile); err == nil && !strings.HasPrefix(rel, "..") {
+			return dest, nil
+		}
+	}
+
+	// Assets can be copied so the original relative path stays valid
+	if r.copyAssets && !isTranslatedDoc(srcFile) {
+		dstFile := filepath.Join(r.dstDir, filepath.FromSlash(unescaped))
+		if rel, err := filepath.Rel(r.dstDir, dstFile); err == nil && !strings.HasPrefix(rel, "..") {
+			if err := copyAsset(srcFile, dstFile); err != nil {
+				return "", err
+			}
+			return dest, nil
+		}
+	}
+
+	return r.relative(srcFile, suffix, angled)
+}
+
+// relative formats the path of file from the target directory
+func (r *relinker) relative(file, suffix string, angled bool) (string, error) {
+	rel, err := filepath.Rel(r.dstDir, file)
+	if err != nil {
+		return "", fmt.Errorf("failed to relativize %s: %v", file, err)
+	}
+	rel = filepath.ToSlash(rel)
+	if !angled {
+		rel = strings.ReplaceAll(rel, " ", "%20")
+	}
+	return rel + suffix, nil
+}
+
+// isRelativeRef reports whether a link target is a path relative to the document
+func isRelativeRef(target string) bool {
+	if target == "" || strings.HasPrefix(target, "/") || strings.HasPrefix(target, "{{") {
+		return false
+	}
+	u, err := url.Parse(target)
+	return err == nil && u.Scheme == "" && u.Host == ""
+}
+
+// isTranslatedDoc reports whether a linked file is a document that gets translated
+func isTranslatedDoc(path string) bool {
+	return mddoc.IsMarkdown(path) || mddoc.IsMDX(path)
+}
+
+// copyAsset copies a referenced file next to the translation unless it exists
+func copyAsset(src, dst string) error {
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		// Broken references are kept as they are
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open asset: %v", err)
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create asset directory: %v", err)
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create asset: %v", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy asset %s: %v", src, err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,93 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRelinker_Rewrite(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "docs", "guide.md")
+	dst := filepath.Join(dir, "translated", "zh", "guide.md")
+
+	content := "![Arch](images/arch.png)\n" +
+		"See [setup](../setup.md#install) and [API](<api ref.md>).\n\n" +
+		"[logo]: ./logo.svg \"Logo\"\n" +
+		"<img src=\"images/a b.png\">\n" +
+		"[web](https://example.com/x.md) [top](#top) [root](/abs.png) `[span](images/arch.png)`\n" +
+		"```\n![code](images/arch.png)\n```\n"
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "rewrite",
+			want: "![Arch](../../docs/images/arch.png)\n" +
+				"See [setup](../../setup.md#install) and [API](<../../docs/api ref.md>).\n\n" +
+				"[logo]: ../../docs/logo.svg \"Logo\"\n" +
+				"<img src=\"../../docs/images/a%20b.png\">\n" +
+				"[web](https://example.com/x.md) [top](#top) [root](/abs.png) `[span](images/arch.png)`\n" +
+				"```\n![code](images/arch.png)\n```\n",
+		},
+		{
+			name: "mirrored tree",
+			opts: Options{SourceRoot: filepath.Join(dir, "docs")},
+			want: "![Arch](../../docs/images/arch.png)\n" +
+				"See [setup](../../setup.md#install) and [API](<api ref.md>).\n\n" +
+				"[logo]: ../../docs/logo.svg \"Logo\"\n" +
+				"<img src=\"../../docs/images/a%20b.png\">\n" +
+				"[web](https://example.com/x.md) [top](#top) [root](/abs.png) `[span](images/arch.png)`\n" +
+				"```\n![code](images/arch.png)\n```\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newRelinker(src, dst, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.rewrite(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if r, _ := newRelinker(src, filepath.Join(dir, "docs", "guide_zh.md"), Options{}); r != nil {
+		t.Error("expected no rewriting for targets in the source directory")
+	}
+}
+
+func TestRelinker_CopyAssets(t *testing.T) {
+	dir := t.TempDir()
+	image := filepath.Join(dir, "docs", "images", "arch.png")
+	if err := os.MkdirAll(filepath.Dir(image), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(image, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "translated", "zh", "guide.md")
+	r, err := newRelinker(filepath.Join(dir, "docs", "guide.md"), dst, Options{CopyAssets: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.rewrite("![Arch](images/arch.png) ![Up](../up.png)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "![Arch](images/arch.png) ![Up](../../up.png)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "translated", "zh", "images", "arch.png")); err != nil || string(data) != "png" {
+		t.Errorf("expected asset to be copied, got %q, %v", data, err)
+	}
+}
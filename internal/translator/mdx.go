@@ -0,0 +1,114 @@
+package translator
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// mdxRules additionally protect the parts of MDX and HTML-heavy markdown a
+// model must not touch. They run after protectRules, so tags in code are
+// already masked and URLs in props are nested placeholders.
+var mdxRules = []protectRule{
+	// ESM import/export statements, a block ends at the next blank line
+	{pattern: regexp.MustCompile(`(?m)^(?:import|export)\b.*(?:\n.*\S.*)*`)},
+	// HTML comments
+	{pattern: regexp.MustCompile(`(?s)<!--.*?-->`)},
+	// JSX and HTML tags including their props, the children are translated
+	{pattern: componentTagRegex, component: true},
+	// JSX expressions such as {props.title} or {/* comment */}
+	{pattern: regexp.MustCompile(`\{(?:[^{}]|\{(?:[^{}]|\{[^{}]*\})*\})*\}`)},
+}
+
+// componentTagRegex matches an opening, closing or self-closing tag, props
+// may contain quoted strings and {expressions}
+var componentTagRegex = regexp.MustCompile(`</?>|</[A-Za-z][\w.:-]*\s*>|<[A-Za-z][\w.:-]*(?:[^<>{}"']|"[^"]*"|'[^']*'|\{(?:[^{}]|\{[^{}]*\})*\})*>`)
+
+// tagNameRegex extracts the name of a tag
+var tagNameRegex = regexp.MustCompile(`^</?([\w.:-]*)`)
+
+// voidElements are HTML elements that never have a closing tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// IsMDX reports whether a file is an MDX document, which is always
+// translated in MDX mode
+func IsMDX(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".mdx")
+}
+
+// checkComponents verifies that the translation kept the nesting of the
+// component tags, the translation still contains placeholders. Moving a tag
+// within its parent is fine, moving it out of its parent is not.
+func (p *protector) checkComponents(translated string) error {
+	if len(p.components) == 0 {
+		return nil
+	}
+
+	var source []string
+	positions := make(map[int]int)
+	for _, i := range p.components {
+		source = append(source, p.spans[i])
+		pos := strings.Index(translated, fmt.Sprintf(placeholderFormat, i))
+		if pos < 0 {
+			// Lost placeholders are reported by restore
+			return nil
+		}
+		positions[i] = pos
+	}
+	want, ok := componentStructure(source)
+	if !ok {
+		// Without balanced tags in the source there is no structure to keep
+		return nil
+	}
+
+	order := append([]int(nil), p.components...)
+	sort.SliceStable(order, func(a, b int) bool {
+		return positions[order[a]] < positions[order[b]]
+	})
+	var tags []string
+	for _, i := range order {
+		tags = append(tags, p.spans[i])
+	}
+	got, ok := componentStructure(tags)
+	if !ok {
+		return fmt.Errorf("translation broke the component structure: tags are no longer balanced")
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			return fmt.Errorf("translation broke the component structure: expected %s", want[i])
+		}
+	}
+	return nil
+}
+
+// componentStructure returns the sorted parent > child pairs of a tag
+// sequence, false if opening and closing tags do not match
+func componentStructure(tags []string) ([]string, bool) {
+	var stack, pairs []string
+	for _, tag := range tags {
+		name := tagNameRegex.FindStringSubmatch(tag)[1]
+		parent := "(root)"
+		if len(stack) > 0 {
+			parent = "<" + stack[len(stack)-1] + ">"
+		}
+		switch {
+		case strings.HasPrefix(tag, "</"):
+			if len(stack) == 0 || stack[len(stack)-1] != name {
+				return nil, false
+			}
+			stack = stack[:len(stack)-1]
+		case strings.HasSuffix(tag, "/>") || voidElements[name]:
+			pairs = append(pairs, parent+" > <"+name+">")
+		default:
+			pairs = append(pairs, parent+" > <"+name+">")
+			stack = append(stack, name)
+		}
+	}
+	sort.Strings(pairs)
+	return pairs, len(stack) == 0
+}
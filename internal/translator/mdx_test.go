@@ -0,0 +1,109 @@
+package translator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestProtector_MDXMaskRestore(t *testing.T) {
+	content := `import Tabs from '@theme/Tabs'
+import TabItem from '@theme/TabItem'
+
+export const meta = {
+  title: 'Install',
+}
+
+# Install
+
+<Tabs groupId="os">
+<TabItem value="mac" label={"macOS"}>
+Run <kbd>brew install mdctl</kbd> to install.<br>
+</TabItem>
+</Tabs>
+
+{/* hidden note */} See <a href="https://example.com">the site</a>.
+`
+	p := &protector{mdx: true}
+	masked := p.mask(content)
+
+	for _, span := range []string{"import", "export", "<Tabs", "groupId", "label=", "</TabItem>", "<br>", "href", "hidden note"} {
+		if strings.Contains(masked, span) {
+			t.Errorf("masked content still contains %q: %s", span, masked)
+		}
+	}
+	for _, text := range []string{"# Install", "Run ", "brew install mdctl", " to install.", "See ", "the site"} {
+		if !strings.Contains(masked, text) {
+			t.Errorf("masked content lost translatable text %q: %s", text, masked)
+		}
+	}
+
+	restored, err := p.restore(masked)
+	if err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	if restored != content {
+		t.Errorf("round trip mismatch:\nwant: %q\ngot:  %q", content, restored)
+	}
+
+	// Without MDX mode tags reach the model
+	if plain := (&protector{}).mask(content); !strings.Contains(plain, "<Tabs") {
+		t.Errorf("expected tags to be left alone without MDX mode: %s", plain)
+	}
+}
+
+func TestProtector_CheckComponents(t *testing.T) {
+	content := "<Note>\nKeep <b>this</b> bold.\n</Note>\n\nOutside."
+
+	tests := []struct {
+		name        string
+		translated  func(masked string, token func(span string) string) string
+		expectError bool
+	}{
+		{
+			name:       "unchanged",
+			translated: func(masked string, token func(string) string) string { return masked },
+		},
+		{
+			name: "inline tag moved within its parent",
+			translated: func(masked string, token func(string) string) string {
+				return token("<Note>") + "\n" + token("<b>") + "Dies" + token("</b>") + " fett halten.\n" + token("</Note>") + "\n\nDraußen."
+			},
+		},
+		{
+			name: "tag moved out of its parent",
+			translated: func(masked string, token func(string) string) string {
+				return token("<Note>") + "\nFett halten.\n" + token("</Note>") + "\n\n" + token("<b>") + "Dies" + token("</b>") + " draußen."
+			},
+			expectError: true,
+		},
+		{
+			name: "closing tags swapped",
+			translated: func(masked string, token func(string) string) string {
+				return token("<Note>") + "\n" + token("<b>") + "Dies" + token("</Note>") + " fett.\n" + token("</b>")
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &protector{mdx: true}
+			masked := p.mask(content)
+			token := func(span string) string {
+				for i, s := range p.spans {
+					if s == span {
+						return fmt.Sprintf(placeholderFormat, i)
+					}
+				}
+				t.Fatalf("span %q was not masked", span)
+				return ""
+			}
+
+			err := p.checkComponents(tt.translated(masked, token))
+			if (err != nil) != tt.expectError {
+				t.Errorf("expected error=%t, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Model is a model offered by the AI endpoint
+type Model struct {
+	ID      string `json:"id"`
+	OwnedBy string `json:"owned_by,omitempty"`
+}
+
+// ListModels returns the models of the configured endpoint (GET /models),
+// sorted by ID
+func (t *Translator) ListModels() ([]Model, error) {
+	ctx, cancel := context.WithTimeout(t.ctx, t.timeout)
+	defer cancel()
+
+	endpoint := strings.TrimRight(t.config.OpenAIEndpointURL, "/")
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AI endpoint %s: %v", endpoint, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+t.config.OpenAIAPIKey)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if t.ctx.Err() != nil {
+			return nil, t.ctx.Err()
+		}
+		return nil, fmt.Errorf("cannot reach the AI endpoint %s: %v", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the model list: %v", err)
+	}
+	if err := statusError(endpoint, resp, body); err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Data []Model `json:"data"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil || list.Data == nil {
+		return nil, fmt.Errorf("%s/models did not return an OpenAI compatible model list, check the endpoint (e.g. https://api.openai.com/v1)", endpoint)
+	}
+	sort.Slice(list.Data, func(i, j int) bool { return list.Data[i].ID < list.Data[j].ID })
+	return list.Data, nil
+}
+
+// CheckModel verifies that the endpoint accepts the API key and offers the
+// configured model, so a misconfiguration fails before a long run starts
+func (t *Translator) CheckModel() error {
+	models, err := t.ListModels()
+	if err != nil {
+		return err
+	}
+	for _, model := range models {
+		if model.ID == t.config.ModelName {
+			return nil
+		}
+	}
+	return fmt.Errorf("model %q is not available at %s, use --list-models to show the available models",
+		t.config.ModelName, strings.TrimRight(t.config.OpenAIEndpointURL, "/"))
+}
+
+// statusError explains a failed response of the AI endpoint, telling
+// rejected API keys apart from wrong endpoints. It returns nil for 2xx.
+func statusError(endpoint string, resp *http.Response, body []byte) error {
+	message := strings.TrimSpace(string(body))
+	if len(message) > 500 {
+		message = message[:500] + "..."
+	}
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("the AI endpoint %s rejected the API key (%s), check api_key: %s", endpoint, resp.Status, message)
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed:
+		return fmt.Errorf("%s is not an OpenAI compatible API (%s %s), check the endpoint, which usually ends with the API version, e.g. https://api.openai.com/v1",
+			endpoint, resp.Request.URL.Path, resp.Status)
+	default:
+		return fmt.Errorf("server returned %s: %s", resp.Status, message)
+	}
+}
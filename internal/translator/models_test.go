@@ -0,0 +1,65 @@
+package translator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+func TestListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path != "/v1/models":
+			http.NotFound(w, r)
+		case r.Header.Get("Authorization") != "Bearer secret":
+			http.Error(w, `{"error":"invalid api key"}`, http.StatusUnauthorized)
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"object": "list",
+				"data":   []map[string]string{{"id": "qwen2.5:7b"}, {"id": "llama3:8b", "owned_by": "library"}},
+			})
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig
+	cfg.OpenAIEndpointURL = server.URL + "/v1"
+	cfg.OpenAIAPIKey = "secret"
+	cfg.ModelName = "llama3:8b"
+
+	models, err := New(&cfg, false).ListModels()
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(models) != 2 || models[0].ID != "llama3:8b" || models[0].OwnedBy != "library" {
+		t.Errorf("unexpected models: %+v", models)
+	}
+	if err := New(&cfg, false).CheckModel(); err != nil {
+		t.Errorf("CheckModel failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(cfg *config.Config)
+		want   string
+	}{
+		{"missing model", func(cfg *config.Config) { cfg.ModelName = "gpt-4o" }, `model "gpt-4o" is not available`},
+		{"wrong key", func(cfg *config.Config) { cfg.OpenAIAPIKey = "wrong" }, "rejected the API key"},
+		{"wrong endpoint", func(cfg *config.Config) { cfg.OpenAIEndpointURL = server.URL }, "not an OpenAI compatible API"},
+		{"unreachable", func(cfg *config.Config) { cfg.OpenAIEndpointURL = "http://127.0.0.1:1/v1" }, "cannot reach the AI endpoint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cfg
+			tt.change(&cfg)
+			err := New(&cfg, false).CheckModel()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+// promptFor returns the system prompt translating the document at path into
+// lang. Project configuration files (.mdctl.yaml) are looked up from the
+// directory of path upwards, the nearest one with a prompt for lang or a
+// general prompt wins. Without one the translate_prompts and translate_prompt
+// of the configuration apply.
+func promptFor(cfg *config.Config, path, lang string) (string, error) {
+	prompt := cfg.PromptFor(lang)
+	if path != "" {
+		dir, err := filepath.Abs(path)
+		if err != nil {
+			return "", err
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			dir = filepath.Dir(dir)
+		}
+		for {
+			project, err := config.LoadProjectConfig(dir)
+			if err != nil {
+				return "", err
+			}
+			if project != nil && project.PromptFor(lang) != "" {
+				prompt = project.PromptFor(lang)
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	return strings.Replace(prompt, "{TARGET_LANG}", lang, 1), nil
+}
@@ -0,0 +1,59 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+func TestPromptFor(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".mdctl.yaml", "translate_prompts:\n  ja: project ja {TARGET_LANG}\n")
+	write("blog/.mdctl.yaml", "translate_prompt: blog {TARGET_LANG}\n")
+	write("api/guide.md", "# Guide\n")
+	write("blog/post.md", "# Post\n")
+
+	cfg := &config.Config{
+		TranslatePrompt:  "global {TARGET_LANG}",
+		TranslatePrompts: map[string]string{"de": "global de"},
+	}
+	tests := []struct {
+		path, lang, want string
+	}{
+		{"", "ja", "global ja"},
+		{"", "de", "global de"},
+		{"api/guide.md", "ja", "project ja ja"},
+		{"api/guide.md", "de", "global de"},
+		{"blog/post.md", "ja", "blog ja"},
+		{"blog", "fr", "blog fr"},
+	}
+	for _, tt := range tests {
+		path := tt.path
+		if path != "" {
+			path = filepath.Join(dir, filepath.FromSlash(path))
+		}
+		got, err := promptFor(cfg, path, tt.lang)
+		if err != nil {
+			t.Fatalf("promptFor(%q, %q) failed: %v", tt.path, tt.lang, err)
+		}
+		if got != tt.want {
+			t.Errorf("promptFor(%q, %q) = %q, want %q", tt.path, tt.lang, got, tt.want)
+		}
+	}
+
+	write("broken/.mdctl.yaml", "translate_prompts: [\n")
+	if _, err := promptFor(cfg, filepath.Join(dir, "broken"), "ja"); err == nil {
+		t.Error("expected an error for an invalid project config")
+	}
+}
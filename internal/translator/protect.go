@@ -0,0 +1,149 @@
+package translator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// placeholderFormat is the token substituted for protected spans. It has no
+// markdown meaning and survives translation into every supported language.
+const placeholderFormat = "@@MDCTL%d@@"
+
+// placeholderInstruction is appended to the system prompt whenever content was masked
+const placeholderInstruction = " Tokens such as @@MDCTL0@@ are placeholders: copy each of them unchanged, exactly once, in the matching position."
+
+// placeholderRegex matches any placeholder left in the model output
+var placeholderRegex = regexp.MustCompile(`@@MDCTL\d+@@`)
+
+// protectRule masks either the whole match or, when group > 0, only that submatch
+type protectRule struct {
+	pattern   *regexp.Regexp
+	group     int
+	component bool // Matches are JSX/HTML tags whose nesting is checked
+}
+
+// protectRules are applied in order, earlier rules take precedence because
+// their spans are already replaced when later rules run
+var protectRules = []protectRule{
+	// Fenced code blocks
+	{pattern: regexp.MustCompile("(?ms)^[ \\t]*```.*?^[ \\t]*```[ \\t]*$")},
+	{pattern: regexp.MustCompile(`(?ms)^[ \t]*~~~.*?^[ \t]*~~~[ \t]*$`)},
+	// Inline code spans
+	{pattern: regexp.MustCompile("``[^\\n]+?``")},
+	{pattern: regexp.MustCompile("`[^`\\n]+`")},
+	// Reference-style link definitions: [id]: https://example.com "title"
+	{pattern: regexp.MustCompile(`(?m)^[ \t]*\[[^\]\n^][^\]\n]*\]:[ \t]*\S+.*$`)},
+	// Footnote references: [^1]
+	{pattern: regexp.MustCompile(`\[\^[^\]\s]+\]`)},
+	// Link and image destinations, the link text itself is translated
+	{pattern: regexp.MustCompile(`\]\(\s*(<[^>\n]*>|[^)\s]+)(?:\s+"[^"\n]*")?\s*\)`), group: 1},
+	// Autolinks and bare URLs
+	{pattern: regexp.MustCompile(`<(?:https?|mailto):[^>\s]+>`)},
+	{pattern: regexp.MustCompile(`(?:https?|ftp)://[^\s<>()\[\]"'` + "`" + `]+`)},
+}
+
+// protector replaces spans that must never be translated with placeholders
+// and puts them back afterwards
+type protector struct {
+	mdx        bool // Also mask components, ESM statements and expressions
+	spans      []string
+	components []int // Indexes of the spans that are component tags
+}
+
+// mask replaces protected spans with placeholders
+func (p *protector) mask(content string) string {
+	rules := protectRules
+	if p.mdx {
+		rules = append(rules[:len(rules):len(rules)], mdxRules...)
+	}
+	for _, rule := range rules {
+		content = p.maskRule(content, rule)
+	}
+	return content
+}
+
+// maskRule applies a single protect rule
+func (p *protector) maskRule(content string, rule protectRule) string {
+	matches := rule.pattern.FindAllStringSubmatchIndex(content, -1)
+	if len(matches) == 0 {
+		return content
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if rule.group > 0 {
+			start, end = m[2*rule.group], m[2*rule.group+1]
+			if start < 0 {
+				continue
+			}
+		}
+		b.WriteString(content[last:start])
+		b.WriteString(p.placeholder(content[start:end]))
+		if rule.component {
+			p.components = append(p.components, len(p.spans)-1)
+		}
+		last = end
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+// placeholder records a span and returns its placeholder token
+func (p *protector) placeholder(span string) string {
+	p.spans = append(p.spans, span)
+	return fmt.Sprintf(placeholderFormat, len(p.spans)-1)
+}
+
+// restore puts the protected spans back, failing if the model dropped,
+// duplicated or invented a placeholder
+func (p *protector) restore(content string) (string, error) {
+	// A placeholder may be nested inside a span captured by a later rule, so
+	// it is counted across the output and all recorded spans
+	haystack := content + "\n" + strings.Join(p.spans, "\n")
+
+	var missing, duplicated []string
+	for i := range p.spans {
+		token := fmt.Sprintf(placeholderFormat, i)
+		switch strings.Count(haystack, token) {
+		case 0:
+			missing = append(missing, p.spans[i])
+		case 1:
+		default:
+			duplicated = append(duplicated, p.spans[i])
+		}
+	}
+
+	if len(missing) > 0 || len(duplicated) > 0 {
+		var problems []string
+		if len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("%d lost (e.g. %q)", len(missing), truncateSpan(missing[0])))
+		}
+		if len(duplicated) > 0 {
+			problems = append(problems, fmt.Sprintf("%d duplicated (e.g. %q)", len(duplicated), truncateSpan(duplicated[0])))
+		}
+		return "", fmt.Errorf("translation did not preserve protected content: %s", strings.Join(problems, ", "))
+	}
+
+	// Restore in reverse so spans captured later, which may contain earlier
+	// placeholders, are expanded first
+	for i := len(p.spans) - 1; i >= 0; i-- {
+		content = strings.Replace(content, fmt.Sprintf(placeholderFormat, i), p.spans[i], 1)
+	}
+
+	if stray := placeholderRegex.FindString(content); stray != "" {
+		return "", fmt.Errorf("translation contains unknown placeholder %s", stray)
+	}
+
+	return content, nil
+}
+
+// truncateSpan shortens a span for error messages
+func truncateSpan(span string) string {
+	if len(span) > 40 {
+		return span[:40] + "..."
+	}
+	return span
+}
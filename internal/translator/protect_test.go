@@ -0,0 +1,185 @@
+package translator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+func TestProtector_MaskRestoreRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		protected []string // Spans that must not be visible to the model
+		visible   []string // Text that must still reach the model
+	}{
+		{
+			name:      "inline code",
+			content:   "Run `kubectl apply -f app.yaml` to deploy.",
+			protected: []string{"kubectl apply -f app.yaml"},
+		},
+		{
+			name:      "double backtick code",
+			content:   "Use ``a `nested` span`` here.",
+			protected: []string{"a `nested` span"},
+		},
+		{
+			name:      "link destination keeps text translatable",
+			content:   "See [the guide](https://example.com/guide?a=1 \"Guide\") for details.",
+			protected: []string{"https://example.com/guide?a=1"},
+			visible:   []string{"[the guide]"},
+		},
+		{
+			name:      "relative image path",
+			content:   "![Architecture](../images/arch.png)",
+			protected: []string{"../images/arch.png"},
+		},
+		{
+			name:      "bare URL and autolink",
+			content:   "Visit https://example.com/docs or <https://example.org>.",
+			protected: []string{"https://example.com/docs", "https://example.org"},
+		},
+		{
+			name:      "footnote reference",
+			content:   "A claim[^note].\n\n[^note]: Source.",
+			protected: []string{"[^note]"},
+			visible:   []string{"Source."},
+		},
+		{
+			name:      "reference definition",
+			content:   "Read [the docs][docs].\n\n[docs]: https://example.com/docs \"Docs\"",
+			protected: []string{"https://example.com/docs"},
+		},
+		{
+			name:      "code nested in reference definition",
+			content:   "[cli]: https://example.com/cli \"The `mdctl` CLI\"",
+			protected: []string{"https://example.com/cli", "mdctl"},
+		},
+		{
+			name:      "fenced code block",
+			content:   "Install:\n\n```bash\npip install langchain # see https://pypi.org\n```\n\nDone.",
+			protected: []string{"pip install langchain", "https://pypi.org"},
+		},
+		{
+			name:      "plain text",
+			content:   "Nothing to protect here.",
+			protected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &protector{}
+			masked := p.mask(tt.content)
+
+			for _, span := range tt.protected {
+				if strings.Contains(masked, span) {
+					t.Errorf("masked content still contains %q: %s", span, masked)
+				}
+			}
+
+			for _, text := range tt.visible {
+				if !strings.Contains(masked, text) {
+					t.Errorf("masked content lost translatable text %q: %s", text, masked)
+				}
+			}
+
+			restored, err := p.restore(masked)
+			if err != nil {
+				t.Fatalf("restore failed: %v", err)
+			}
+			if restored != tt.content {
+				t.Errorf("round trip mismatch:\nwant: %q\ngot:  %q", tt.content, restored)
+			}
+		})
+	}
+}
+
+func TestProtector_RestoreValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		translated  func(masked string) string
+		expectError bool
+	}{
+		{
+			name:        "placeholders kept",
+			translated:  func(masked string) string { return masked },
+			expectError: false,
+		},
+		{
+			name: "placeholder dropped",
+			translated: func(masked string) string {
+				return strings.Replace(masked, "@@MDCTL0@@", "", 1)
+			},
+			expectError: true,
+		},
+		{
+			name: "placeholder duplicated",
+			translated: func(masked string) string {
+				return masked + " @@MDCTL1@@"
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown placeholder",
+			translated: func(masked string) string {
+				return masked + " @@MDCTL99@@"
+			},
+			expectError: true,
+		},
+	}
+
+	content := "Run `make build` and open https://example.com."
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &protector{}
+			masked := p.mask(content)
+
+			_, err := p.restore(tt.translated(masked))
+			if (err != nil) != tt.expectError {
+				t.Errorf("expected error=%t, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestTranslateContent_PreservesProtectedSpans(t *testing.T) {
+	// The fake model "translates" by upper-casing everything it receives,
+	// which would corrupt any code or URL that reached it unmasked
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		reply := strings.ToUpper(req.Messages[len(req.Messages)-1].Content)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": reply}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig
+	cfg.OpenAIEndpointURL = server.URL
+
+	content := "Run `kubectl get pods` and read [the docs](https://example.com/Docs).[^1]\n\n[^1]: see https://example.com/ref"
+	translated, err := New(&cfg, false).TranslateContent(content, "en")
+	if err != nil {
+		t.Fatalf("TranslateContent failed: %v", err)
+	}
+
+	for _, span := range []string{"`kubectl get pods`", "(https://example.com/Docs)", "[^1]", "https://example.com/ref"} {
+		if !strings.Contains(translated, span) {
+			t.Errorf("expected %q to survive translation, got: %s", span, translated)
+		}
+	}
+	if !strings.Contains(translated, "THE DOCS") {
+		t.Errorf("expected link text to be translated, got: %s", translated)
+	}
+}
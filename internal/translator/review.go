@@ -0,0 +1,255 @@
+package translator
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/samzong/mdctl/internal/mddoc"
+)
+
+// Review output formats
+const (
+	ReviewHTML     = "html"
+	ReviewMarkdown = "markdown"
+)
+
+// reviewPair is one aligned block of a source document and its translation
+type reviewPair struct {
+	Source      string
+	Translation string
+}
+
+// reviewPage is a side-by-side comparison of one translated file
+type reviewPage struct {
+	Source string
+	Target string
+	Pairs  []reviewPair
+}
+
+// reviewEntry is a file listed in the review index
+type reviewEntry struct {
+	FileResult
+	Page string // Review page relative to the index, empty if there is none
+}
+
+// WriteReview writes a side-by-side comparison of source and translation for
+// every translated file of the report into dir, plus an index summarizing the
+// run. Page names mirror the source files below srcRoot.
+func WriteReview(report *Report, srcRoot, dir, format string) error {
+	var templates reviewTemplates
+	ext := ".html"
+	switch format {
+	case ReviewHTML:
+		templates = htmlReviewTemplates
+	case ReviewMarkdown:
+		templates, ext = markdownReviewTemplates, ".md"
+	default:
+		return fmt.Errorf("unsupported review format: %s (must be html or markdown)", format)
+	}
+
+	if info, err := os.Stat(srcRoot); err == nil && !info.IsDir() {
+		srcRoot = filepath.Dir(srcRoot)
+	}
+
+	var entries []reviewEntry
+	for _, file := range report.Files {
+		entry := reviewEntry{FileResult: file}
+		if file.Status == statusTranslated {
+			page, err := buildReviewPage(file)
+			if err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(srcRoot, file.Source)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				rel = filepath.Base(file.Source)
+			}
+			entry.Page = filepath.ToSlash(rel) + ext
+
+			if err := writeReviewFile(templates, "page", filepath.Join(dir, filepath.FromSlash(entry.Page)), page); err != nil {
+				return err
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	index := struct {
+		Report  *Report
+		Entries []reviewEntry
+	}{report, entries}
+	return writeReviewFile(templates, "index", filepath.Join(dir, "index"+ext), index)
+}
+
+// buildReviewPage aligns the blocks of a source file and its translation
+func buildReviewPage(file FileResult) (*reviewPage, error) {
+	target := file.Target
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		target = filepath.Join(target, filepath.Base(file.Source))
+	}
+
+	source, err := os.ReadFile(file.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source file: %v", err)
+	}
+	translation, err := os.ReadFile(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read translated file: %v", err)
+	}
+
+	srcBlocks := splitBlocks(removeFrontMatter(string(source)))
+	// Bilingual translations are compared without the source they kept
+	translated, _ := StripSource(string(translation))
+	dstBlocks := splitBlocks(removeFrontMatter(translated))
+
+	page := &reviewPage{Source: file.Source, Target: target}
+	for i := 0; i < len(srcBlocks) || i < len(dstBlocks); i++ {
+		var pair reviewPair
+		if i < len(srcBlocks) {
+			pair.Source = srcBlocks[i]
+		}
+		if i < len(dstBlocks) {
+			pair.Translation = dstBlocks[i]
+		}
+		page.Pairs = append(page.Pairs, pair)
+	}
+	return page, nil
+}
+
+// splitBlocks splits markdown into blank-line separated blocks, keeping fenced
+// code blocks whole
+func splitBlocks(content string) []string {
+	var blocks []string
+	var current []string
+	var fences mddoc.Fences
+
+	flush := func() {
+		if len(current) > 0 {
+			blocks = append(blocks, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		if !fences.Line(line) && strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+
+	return blocks
+}
+
+// reviewTemplates renders the "page" and "index" templates of a review format
+type reviewTemplates interface {
+	ExecuteTemplate(w io.Writer, name string, data interface{}) error
+}
+
+// writeReviewFile renders a review template into path
+func writeReviewFile(templates reviewTemplates, name, path string, data interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create review directory: %v", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create review file: %v", err)
+	}
+	defer f.Close()
+
+	if err := templates.ExecuteTemplate(f, name, data); err != nil {
+		return fmt.Errorf("failed to write review %s: %v", path, err)
+	}
+	return nil
+}
+
+// markdownCell formats a block as a markdown table cell
+func markdownCell(block string) string {
+	block = strings.ReplaceAll(block, "|", "\\|")
+	return strings.ReplaceAll(block, "\n", "<br>")
+}
+
+// htmlReviewTemplates render side-by-side HTML pages
+var htmlReviewTemplates = htmltemplate.Must(htmltemplate.New("review").Parse(`
+{{- define "page" -}}
+<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Review: {{.Target}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; table-layout: fixed; }
+th, td { border: 1px solid #ddd; padding: 0.5em; vertical-align: top; }
+pre { white-space: pre-wrap; margin: 0; font-family: inherit; }
+</style>
+</head>
+<body>
+<h1>Translation review</h1>
+<table>
+<tr><th>{{.Source}}</th><th>{{.Target}}</th></tr>
+{{- range .Pairs}}
+<tr><td><pre>{{.Source}}</pre></td><td><pre>{{.Translation}}</pre></td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+{{end -}}
+
+{{- define "index" -}}
+<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Translation review</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ddd; padding: 0.3em 0.6em; text-align: left; }
+</style>
+</head>
+<body>
+<h1>Translation review</h1>
+<p>Translated {{.Report.Translated}}, skipped {{.Report.Skipped}}, failed {{.Report.Failed}}</p>
+<table>
+<tr><th>Source</th><th>Target</th><th>Status</th></tr>
+{{- range .Entries}}
+<tr><td>{{if .Page}}<a href="{{.Page}}">{{.Source}}</a>{{else}}{{.Source}}{{end}}</td><td>{{.Target}}</td><td>{{.Status}}{{if .Error}}: {{.Error}}{{end}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+{{end -}}
+`))
+
+// markdownReviewTemplates render side-by-side markdown tables
+var markdownReviewTemplates = template.Must(template.New("review").Funcs(template.FuncMap{
+	"cell": markdownCell,
+}).Parse(`
+{{- define "page" -}}
+# Translation review
+
+| {{cell .Source}} | {{cell .Target}} |
+| --- | --- |
+{{- range .Pairs}}
+| {{cell .Source}} | {{cell .Translation}} |
+{{- end}}
+{{end -}}
+
+{{- define "index" -}}
+# Translation review
+
+Translated {{.Report.Translated}}, skipped {{.Report.Skipped}}, failed {{.Report.Failed}}
+
+| Source | Target | Status |
+| --- | --- | --- |
+{{- range .Entries}}
+| {{if .Page}}[{{cell .Source}}]({{.Page}}){{else}}{{cell .Source}}{{end}} | {{cell .Target}} | {{.Status}}{{if .Error}}: {{cell .Error}}{{end}} |
+{{- end}}
+{{end -}}
+`))
@@ -0,0 +1,67 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteReview(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "docs", "guide", "intro.md")
+	dst := filepath.Join(dir, "docs_de", "guide", "intro.md")
+	for path, content := range map[string]string{
+		src: "# Intro\n\nHello <world> | pipe\n\n```\ncode\n\nblock\n```\n",
+		dst: "---\ntranslated: true\n---\n\n# Einleitung\n\nHallo <Welt> | Rohr\n\n```\ncode\n\nblock\n```\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report := &Report{}
+	report.add(src, dst, outcome{status: statusTranslated}, nil)
+	report.add(filepath.Join(dir, "docs", "skipped.md"), filepath.Join(dir, "docs_de", "skipped.md"), outcome{status: statusSkipped}, nil)
+
+	reviewDir := filepath.Join(dir, "review")
+	if err := WriteReview(report, filepath.Join(dir, "docs"), reviewDir, ReviewHTML); err != nil {
+		t.Fatalf("WriteReview failed: %v", err)
+	}
+
+	page, err := os.ReadFile(filepath.Join(reviewDir, "guide", "intro.md.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<td><pre># Intro</pre></td><td><pre># Einleitung</pre></td>",
+		"Hello &lt;world&gt; | pipe",
+		"<pre>```\ncode\n\nblock\n```</pre>",
+	} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("review page missing %q:\n%s", want, page)
+		}
+	}
+
+	index, err := os.ReadFile(filepath.Join(reviewDir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(index), `href="guide/intro.md.html"`) || !strings.Contains(string(index), "skipped") {
+		t.Errorf("unexpected index:\n%s", index)
+	}
+
+	if err := WriteReview(report, filepath.Join(dir, "docs"), reviewDir, ReviewMarkdown); err != nil {
+		t.Fatalf("WriteReview failed: %v", err)
+	}
+	markdown, err := os.ReadFile(filepath.Join(reviewDir, "guide", "intro.md.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(markdown), "| Hello <world> \\| pipe | Hallo <Welt> \\| Rohr |") {
+		t.Errorf("unexpected markdown review:\n%s", markdown)
+	}
+}
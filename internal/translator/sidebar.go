@@ -0,0 +1,124 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/fsutil"
+)
+
+// SidebarRecord is the file next to a Docusaurus sidebar catalog recording
+// the source labels that were translated
+const SidebarRecord = ".mdctl-sidebar.json"
+
+// sidebarMessage is an entry of a catalog of `docusaurus write-translations`
+type sidebarMessage struct {
+	Message     string `json:"message"`
+	Description string `json:"description,omitempty"`
+}
+
+// sidebarRecord maps the keys of a translated catalog to their labels
+type sidebarRecord struct {
+	Locale string                  `json:"locale"`
+	Labels map[string]sidebarLabel `json:"labels"`
+}
+
+// sidebarLabel is a translated message and the source it was translated from
+type sidebarLabel struct {
+	Source      string `json:"source"`
+	Translation string `json:"translation"`
+}
+
+// translateSidebar translates the messages of a Docusaurus sidebar catalog in
+// place. The catalog holds no source labels once translated, so every
+// translated message is recorded with its source in SidebarRecord: a message
+// equal to its recorded source (and not to its translation) was written anew
+// by write-translations, other recorded messages are translated, by mdctl or
+// by hand, and kept. Reruns without new labels send nothing to the model.
+// Descriptions are notes for translators and stay as they are.
+func translateSidebar(ctx context.Context, path, targetLang string, cfg *config.Config, opts Options) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	var catalog map[string]sidebarMessage
+	if err := json.Unmarshal(content, &catalog); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	recordPath := filepath.Join(filepath.Dir(path), SidebarRecord)
+	record := sidebarRecord{Locale: targetLang, Labels: make(map[string]sidebarLabel)}
+	if data, err := os.ReadFile(recordPath); err == nil {
+		if err := json.Unmarshal(data, &record); err != nil {
+			return fmt.Errorf("failed to parse %s: %v", recordPath, err)
+		}
+		if record.Labels == nil {
+			record.Labels = make(map[string]sidebarLabel)
+		}
+	}
+
+	var keys []string
+	for key, entry := range catalog {
+		label, recorded := record.Labels[key]
+		if entry.Message != "" && (!recorded || (entry.Message == label.Source && entry.Message != label.Translation)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 {
+		logger.Infof("Sidebar labels of %s are translated already", path)
+		return nil
+	}
+
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = catalog[key].Message
+	}
+	if opts.DryRun {
+		logger.Infof("Would translate %d sidebar labels of %s", len(keys), path)
+		return nil
+	}
+
+	translations, err := New(cfg, false).WithContext(ctx).TranslateStrings(values, targetLang)
+	if err != nil {
+		return fmt.Errorf("failed to translate sidebar labels: %v", err)
+	}
+	for _, key := range keys {
+		entry := catalog[key]
+		label := sidebarLabel{Source: entry.Message, Translation: entry.Message}
+		if translated, ok := translations[entry.Message]; ok {
+			label.Translation = translated
+		}
+		entry.Message = label.Translation
+		catalog[key] = entry
+		record.Labels[key] = label
+	}
+
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(catalog); err != nil {
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(path, out.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+
+	record.Locale = targetLang
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(recordPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", recordPath, err)
+	}
+	logger.Infof("Translated %d sidebar labels of %s", len(keys), path)
+	return nil
+}
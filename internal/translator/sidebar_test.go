@@ -0,0 +1,91 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+func TestTranslateSidebar(t *testing.T) {
+	var sent []string // Strings of every request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		var batch map[string]string
+		json.Unmarshal([]byte(request.Messages[len(request.Messages)-1].Content), &batch)
+		reply := make(map[string]string)
+		var values []string
+		for id, value := range batch {
+			reply[id] = "FR " + value
+			values = append(values, value)
+		}
+		sent = append(sent, strings.Join(values, "|"))
+		data, _ := json.Marshal(reply)
+		replyJSON(w, string(data))
+	}))
+	defer server.Close()
+	cfg := config.DefaultConfig
+	cfg.OpenAIEndpointURL = server.URL
+
+	path := filepath.Join(t.TempDir(), "current.json")
+	write := func(catalog map[string]sidebarMessage) {
+		data, _ := json.Marshal(catalog)
+		os.WriteFile(path, data, 0644)
+	}
+	read := func() map[string]sidebarMessage {
+		var catalog map[string]sidebarMessage
+		data, _ := os.ReadFile(path)
+		json.Unmarshal(data, &catalog)
+		return catalog
+	}
+	translate := func() {
+		t.Helper()
+		sent = nil
+		if err := translateSidebar(context.Background(), path, "fr", &cfg, Options{}); err != nil {
+			t.Fatalf("translateSidebar failed: %v", err)
+		}
+	}
+
+	write(map[string]sidebarMessage{
+		"sidebar.docs.category.Guide": {Message: "Guide", Description: "The label for category Guide"},
+	})
+	translate()
+	if got := read()["sidebar.docs.category.Guide"]; got.Message != "FR Guide" || got.Description != "The label for category Guide" || len(sent) != 1 || sent[0] != "Guide" {
+		t.Fatalf("unexpected first run: %+v, sent %q", got, sent)
+	}
+
+	// A rerun sends nothing
+	translate()
+	if len(sent) != 0 || read()["sidebar.docs.category.Guide"].Message != "FR Guide" {
+		t.Errorf("expected a rerun to translate nothing, sent %q", sent)
+	}
+
+	// Only new labels and labels written anew by write-translations are sent,
+	// hand-edited translations are kept
+	catalog := read()
+	catalog["sidebar.docs.category.Guide"] = sidebarMessage{Message: "Guide"}
+	catalog["sidebar.docs.category.API"] = sidebarMessage{Message: "API"}
+	write(catalog)
+	translate()
+	if len(sent) != 1 || (sent[0] != "Guide|API" && sent[0] != "API|Guide") {
+		t.Errorf("expected the new and reset labels to be sent, sent %q", sent)
+	}
+	catalog = read()
+	catalog["sidebar.docs.category.Guide"] = sidebarMessage{Message: "Le guide"}
+	write(catalog)
+	translate()
+	if len(sent) != 0 || read()["sidebar.docs.category.Guide"].Message != "Le guide" {
+		t.Errorf("expected the edited translation to be kept, sent %q", sent)
+	}
+}
@@ -0,0 +1,144 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+// Site types with a known i18n layout
+const (
+	SiteMkDocs     = "mkdocs"
+	SiteHugo       = "hugo"
+	SiteDocusaurus = "docusaurus"
+)
+
+// mkdocsNavFiles are the awesome-pages/awesome-nav files copied into translated trees
+var mkdocsNavFiles = map[string]bool{
+	".pages":   true,
+	".nav.yml": true,
+}
+
+// Layout describes where a docs framework expects the translations of a directory
+type Layout struct {
+	SiteType string
+	Target   string // Directory mirroring the source tree, empty for name.<lang>.md next to each source
+	Sidebar  string // Docusaurus sidebar label catalog translated after the documents
+}
+
+// ResolveLayout returns the i18n layout of srcDir for a site type:
+//   - mkdocs: docs/en -> docs/<lang> (mkdocs-static-i18n folder structure),
+//     otherwise page.<lang>.md next to each page (suffix structure)
+//   - hugo: content/en -> content/<lang>, otherwise page.<lang>.md (translation by filename)
+//   - docusaurus: docs -> i18n/<lang>/docusaurus-plugin-content-docs/current,
+//     blog -> i18n/<lang>/docusaurus-plugin-content-blog
+func ResolveLayout(siteType, srcDir, lang string) (*Layout, error) {
+	srcDir = filepath.Clean(srcDir)
+	layout := &Layout{SiteType: siteType}
+
+	switch siteType {
+	case SiteMkDocs, SiteHugo:
+		// A directory named after a language is one of the per-language trees
+		if IsLanguageSupported(filepath.Base(srcDir)) {
+			if filepath.Base(srcDir) == lang {
+				return nil, fmt.Errorf("source directory %s is already the %s tree", srcDir, lang)
+			}
+			layout.Target = filepath.Join(filepath.Dir(srcDir), lang)
+		}
+	case SiteDocusaurus:
+		root := filepath.Dir(srcDir)
+		if !hasDocusaurusConfig(root) {
+			logger.Warnf("No docusaurus.config.js found in %s, assuming it is the site root", root)
+		}
+		if filepath.Base(srcDir) == "blog" {
+			layout.Target = filepath.Join(root, "i18n", lang, "docusaurus-plugin-content-blog")
+		} else {
+			pluginDir := filepath.Join(root, "i18n", lang, "docusaurus-plugin-content-docs")
+			layout.Target = filepath.Join(pluginDir, "current")
+			layout.Sidebar = filepath.Join(pluginDir, "current.json")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported site type: %s (must be mkdocs, hugo or docusaurus)", siteType)
+	}
+
+	return layout, nil
+}
+
+// ProcessSite translates a docs directory into the i18n layout of a site framework
+func ProcessSite(ctx context.Context, srcDir, siteType, targetLang string, cfg *config.Config, opts Options) error {
+	layout, err := ResolveLayout(siteType, srcDir, targetLang)
+	if err != nil {
+		return err
+	}
+
+	if layout.Target == "" {
+		opts.LanguageSuffix = true
+		logger.Infof("Translating %s into <name>.%s.md files next to the sources", srcDir, targetLang)
+	} else {
+		logger.Infof("Translating %s into %s", srcDir, layout.Target)
+	}
+
+	if err := ProcessDirectory(ctx, srcDir, layout.Target, targetLang, cfg, opts); err != nil {
+		return err
+	}
+
+	return layout.syncNavigation(ctx, srcDir, targetLang, cfg, opts)
+}
+
+// syncNavigation carries nav and sidebar files over to the translated tree
+func (l *Layout) syncNavigation(ctx context.Context, srcDir, targetLang string, cfg *config.Config, opts Options) error {
+	// awesome-pages nav files describe the folder structure, which the
+	// translated tree shares
+	if l.SiteType == SiteMkDocs && l.Target != "" {
+		err := filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || !mkdocsNavFiles[info.Name()] {
+				return err
+			}
+			rel, err := filepath.Rel(srcDir, path)
+			if err != nil {
+				return err
+			}
+			dst := filepath.Join(l.Target, rel)
+			if opts.DryRun {
+				logger.Infof("Would copy %s -> %s", path, dst)
+				return nil
+			}
+			return copyAsset(path, dst)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to copy navigation files: %v", err)
+		}
+	}
+
+	// Sidebar labels live in current.json, created by `docusaurus write-translations`
+	if l.Sidebar != "" {
+		if _, err := os.Stat(l.Sidebar); err != nil {
+			logger.Infof("Run `docusaurus write-translations --locale %s` to translate sidebar labels", targetLang)
+			return nil
+		}
+		return translateSidebar(ctx, l.Sidebar, targetLang, cfg, opts)
+	}
+
+	return nil
+}
+
+// hasDocusaurusConfig reports whether dir contains a Docusaurus configuration
+func hasDocusaurusConfig(dir string) bool {
+	for _, name := range []string{"docusaurus.config.js", "docusaurus.config.ts", "docusaurus.config.mjs"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// hasLanguageSuffix reports whether a file is already a translation named name.<lang>.ext
+func hasLanguageSuffix(path string) bool {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	i := strings.LastIndex(name, ".")
+	return i > 0 && IsLanguageSupported(name[i+1:])
+}
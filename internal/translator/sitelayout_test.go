@@ -0,0 +1,66 @@
+package translator
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveLayout(t *testing.T) {
+	tests := []struct {
+		siteType string
+		srcDir   string
+		target   string
+		sidebar  string
+		wantErr  bool
+	}{
+		{siteType: SiteMkDocs, srcDir: "site/docs/en", target: "site/docs/zh"},
+		{siteType: SiteMkDocs, srcDir: "site/docs"},
+		{siteType: SiteMkDocs, srcDir: "site/docs/zh", wantErr: true},
+		{siteType: SiteHugo, srcDir: "site/content/en", target: "site/content/zh"},
+		{siteType: SiteHugo, srcDir: "site/content"},
+		{
+			siteType: SiteDocusaurus,
+			srcDir:   "site/docs",
+			target:   "site/i18n/zh/docusaurus-plugin-content-docs/current",
+			sidebar:  "site/i18n/zh/docusaurus-plugin-content-docs/current.json",
+		},
+		{siteType: SiteDocusaurus, srcDir: "site/blog", target: "site/i18n/zh/docusaurus-plugin-content-blog"},
+		{siteType: "jekyll", srcDir: "site", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.siteType+" "+tt.srcDir, func(t *testing.T) {
+			layout, err := ResolveLayout(tt.siteType, filepath.FromSlash(tt.srcDir), "zh")
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if layout.Target != filepath.FromSlash(tt.target) {
+				t.Errorf("target = %q, want %q", layout.Target, tt.target)
+			}
+			if layout.Sidebar != filepath.FromSlash(tt.sidebar) {
+				t.Errorf("sidebar = %q, want %q", layout.Sidebar, tt.sidebar)
+			}
+		})
+	}
+}
+
+func TestHasLanguageSuffix(t *testing.T) {
+	for path, want := range map[string]bool{
+		"docs/index.zh.md":      true,
+		"docs/index.md":         false,
+		"docs/v1.2.md":          false,
+		"docs/.ja.md":           false,
+		"docs/guide.en.md":      true,
+		"docs/release.notes.md": false,
+	} {
+		if got := hasLanguageSuffix(path); got != want {
+			t.Errorf("hasLanguageSuffix(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
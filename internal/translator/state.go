@@ -0,0 +1,125 @@
+package translator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/samzong/mdctl/internal/cache"
+	"github.com/samzong/mdctl/internal/fsutil"
+)
+
+// stateDir holds the run manifests of directory translations inside the cache directory
+const stateDir = "translate-runs"
+
+// runState is the manifest of a directory translation, it records finished
+// files so an interrupted or failed run can be resumed
+type runState struct {
+	Source string            `json:"source"`
+	Target string            `json:"target"`
+	Locale string            `json:"locale"`
+	Done   map[string]string `json:"done"`   // Absolute source file -> status
+	Failed map[string]string `json:"failed"` // Absolute source file -> error
+
+	path string
+}
+
+// openRunState returns the manifest of translating srcDir into dstDir, loading
+// the previous one when resuming and starting a new one otherwise. The
+// directories are made absolute, so the same run is found from any working
+// directory
+func openRunState(srcDir, dstDir, lang, cacheDir string, resume bool) (*runState, error) {
+	if cacheDir == "" {
+		cacheDir = cache.DefaultDir()
+	}
+	srcDir = absPath(srcDir)
+	if dstDir != "" {
+		dstDir = absPath(dstDir)
+	}
+	key := sha256.Sum256([]byte(srcDir + "\x00" + dstDir + "\x00" + lang))
+
+	s := &runState{
+		Source: srcDir,
+		Target: dstDir,
+		Locale: lang,
+		Done:   make(map[string]string),
+		Failed: make(map[string]string),
+		path:   filepath.Join(cacheDir, stateDir, hex.EncodeToString(key[:8])+".json"),
+	}
+	if !resume {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		logger.Infof("No previous run to resume, translating all files")
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run state: %v", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse run state %s: %v", s.path, err)
+	}
+	if s.Done == nil {
+		s.Done = make(map[string]string)
+	}
+	// Failed files are retried
+	s.Failed = make(map[string]string)
+
+	logger.Infof("Resuming previous run, %d files already done", len(s.Done))
+	return s, nil
+}
+
+// done reports whether a file was finished in a previous run
+func (s *runState) done(path string) bool {
+	_, ok := s.Done[absPath(path)]
+	return ok
+}
+
+// record stores the outcome of a file and persists the manifest
+func (s *runState) record(path, status string, err error) error {
+	path = absPath(path)
+	if err != nil {
+		s.Failed[path] = err.Error()
+	} else {
+		delete(s.Failed, path)
+		s.Done[path] = status
+	}
+	return s.save()
+}
+
+// save writes the manifest to the cache directory
+func (s *runState) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %v", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run state: %v", err)
+	}
+	if err := fsutil.WriteFileAtomic(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write run state: %v", err)
+	}
+	return nil
+}
+
+// remove deletes the manifest once the run completed
+func (s *runState) remove() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove run state: %v", err)
+	}
+	return nil
+}
+
+// absPath returns the absolute form of path, or path itself when the working
+// directory is unknown
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,124 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+func TestProcessDirectory_ResumeAndContinueOnError(t *testing.T) {
+	var requests int32
+	var broken atomic.Bool
+	broken.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		var req OpenAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		content := req.Messages[len(req.Messages)-1].Content
+		if broken.Load() && strings.Contains(content, "fail") {
+			http.Error(w, "model unavailable", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": strings.ToUpper(content)}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig
+	cfg.OpenAIEndpointURL = server.URL
+
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "docs"), filepath.Join(dir, "out")
+	for name, content := range map[string]string{"a.md": "hello", "b.md": "fail", "c.md": "world"} {
+		if err := os.MkdirAll(src, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := Options{Force: true, ContinueOnError: true, CacheDir: filepath.Join(dir, "cache")}
+	report := &Report{}
+	opts.Report = report
+	err := ProcessDirectory(context.Background(), src, dst, "de", &cfg, opts)
+	if err == nil || !strings.Contains(err.Error(), "1 files failed") {
+		t.Fatalf("expected failure to be reported at the end, got %v", err)
+	}
+	if report.Translated != 2 || report.Failed != 1 {
+		t.Errorf("expected 2 translated and 1 failed, got %+v", report)
+	}
+
+	// Resuming only retries the failed file
+	broken.Store(false)
+	atomic.StoreInt32(&requests, 0)
+	opts.Resume = true
+	opts.Report = &Report{}
+	if err := ProcessDirectory(context.Background(), src, dst, "de", &cfg, opts); err != nil {
+		t.Fatalf("resumed run failed: %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("expected only the failed file to be retried, got %d requests", got)
+	}
+	if opts.Report.Translated != 1 || opts.Report.Skipped != 2 {
+		t.Errorf("unexpected resumed report: %+v", opts.Report)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(opts.CacheDir, stateDir))
+	if len(entries) != 0 {
+		t.Errorf("expected run state to be removed after completion, found %d files", len(entries))
+	}
+}
+
+func TestRunStateAbsolutePaths(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	// A run started with relative paths...
+	state, err := openRunState("docs", "out", "de", cacheDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.record(filepath.Join("docs", "a.md"), statusTranslated, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := state.Done[filepath.Join(dir, "docs", "a.md")]; !ok {
+		t.Errorf("expected an absolute key, got %v", state.Done)
+	}
+
+	// ...is resumed with absolute paths from another directory
+	if err := os.Chdir(wd); err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := openRunState(filepath.Join(dir, "docs"), filepath.Join(dir, "out"), "de", cacheDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resumed.done(filepath.Join(dir, "docs", "a.md")) {
+		t.Errorf("expected a.md to be done in the resumed run, got %v", resumed.Done)
+	}
+	if resumed.Source != filepath.Join(dir, "docs") || resumed.Target != filepath.Join(dir, "out") {
+		t.Errorf("unexpected directories %q, %q", resumed.Source, resumed.Target)
+	}
+}
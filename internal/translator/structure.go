@@ -0,0 +1,98 @@
+package translator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// DefaultStructureRetries is the number of repair requests of a translation
+// whose structure differs from the source
+const DefaultStructureRetries = 1
+
+// repairInstruction asks the model to fix a translation that lost or
+// duplicated structure, %s lists the differences
+const repairInstruction = "Your translation does not keep the structure of the source document: %s. " +
+	"Translate the source again and return the complete translation with exactly the same headings (count and levels), " +
+	"code blocks, links and tables as the source, without any comments."
+
+// structureParser parses markdown with GFM tables, which count as structure
+var structureParser = goldmark.New(goldmark.WithExtensions(extension.Table)).Parser()
+
+// structure is the fingerprint of a document a translation must keep
+type structure struct {
+	headings   []int // Heading levels in document order
+	codeBlocks int
+	links      int
+	tables     int
+}
+
+// structureError reports a translation whose structure still differs from
+// its source after the repair requests, the translation is kept for callers
+// that write it anyway
+type structureError struct {
+	differences []string
+	translation string
+}
+
+func (e *structureError) Error() string {
+	return "translation changed the document structure: " + strings.Join(e.differences, ", ")
+}
+
+// documentStructure returns the structure of a markdown document
+func documentStructure(content string) structure {
+	var s structure
+	root := structureParser.Parse(text.NewReader([]byte(content)))
+	ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Heading:
+			s.headings = append(s.headings, node.Level)
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			s.codeBlocks++
+		case *ast.Link, *ast.AutoLink:
+			s.links++
+		case *east.Table:
+			s.tables++
+		}
+		return ast.WalkContinue, nil
+	})
+	return s
+}
+
+// differences describes how a translation's structure differs from the
+// source, nil when it is the same
+func (s structure) differences(translated structure) []string {
+	var diffs []string
+	if len(s.headings) != len(translated.headings) {
+		diffs = append(diffs, fmt.Sprintf("headings %d -> %d", len(s.headings), len(translated.headings)))
+	} else {
+		for i, level := range s.headings {
+			if translated.headings[i] != level {
+				diffs = append(diffs, fmt.Sprintf("heading %d level %d -> %d", i+1, level, translated.headings[i]))
+				break
+			}
+		}
+	}
+	counts := []struct {
+		name       string
+		source, tr int
+	}{
+		{"code blocks", s.codeBlocks, translated.codeBlocks},
+		{"links", s.links, translated.links},
+		{"tables", s.tables, translated.tables},
+	}
+	for _, c := range counts {
+		if c.source != c.tr {
+			diffs = append(diffs, fmt.Sprintf("%s %d -> %d", c.name, c.source, c.tr))
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,87 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+func TestStructureDifferences(t *testing.T) {
+	source := "# Title\n\n## Setup\n\n```sh\nmake\n```\n\nSee [docs](https://example.com).\n\n| a | b |\n|---|---|\n| 1 | 2 |\n"
+	tests := []struct {
+		translated string
+		want       string
+	}{
+		{"# 标题\n\n## 设置\n\n```sh\nmake\n```\n\n见[文档](https://example.com)。\n\n| a | b |\n|---|---|\n| 1 | 2 |\n", ""},
+		{"# 标题\n\n设置\n\n见[文档](https://example.com)。\n", "headings 2 -> 1, code blocks 1 -> 0, tables 1 -> 0"},
+		{"# 标题\n\n### 设置\n\n```sh\nmake\n```\n\n```sh\nmake\n```\n\n见文档。\n\n| a | b |\n|---|---|\n| 1 | 2 |\n", "heading 2 level 2 -> 3, code blocks 1 -> 2, links 1 -> 0"},
+	}
+	want := documentStructure(source)
+	for _, tt := range tests {
+		if got := strings.Join(want.differences(documentStructure(tt.translated)), ", "); got != tt.want {
+			t.Errorf("differences of %q = %q, want %q", tt.translated, got, tt.want)
+		}
+	}
+}
+
+func TestStructureRepair(t *testing.T) {
+	var requests []int // Messages per request
+	replies := []string{"# 标题\n\n文本\n", "# 标题\n\n## 部分\n\n文本\n"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Messages []json.RawMessage `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		reply := replies[len(requests)%len(replies)]
+		requests = append(requests, len(request.Messages))
+		replyJSON(w, reply)
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig
+	cfg.OpenAIEndpointURL = server.URL
+	source := "# Title\n\n## Part\n\nText\n"
+
+	translated, err := New(&cfg, false).WithStructureCheck(1).TranslateContent(source, "zh")
+	if err != nil {
+		t.Fatalf("TranslateContent failed: %v", err)
+	}
+	if translated != replies[1] {
+		t.Errorf("expected the repaired translation, got %q", translated)
+	}
+	if len(requests) != 2 || requests[0] != 2 || requests[1] != 4 {
+		t.Errorf("expected a translation and a repair request, got message counts %v", requests)
+	}
+
+	// Without repairs the file is written and flagged, or fails when strict
+	dir := t.TempDir()
+	src := filepath.Join(dir, "doc.md")
+	os.WriteFile(src, []byte(source), 0644)
+	for _, strict := range []bool{false, true} {
+		requests = nil
+		report := &Report{}
+		dst := filepath.Join(dir, "doc_zh.md")
+		os.Remove(dst)
+		err := ProcessFile(context.Background(), src, dst, "zh", &cfg, Options{Report: report, StructureRetries: -1, StrictStructure: strict})
+		_, statErr := os.Stat(dst)
+		if strict {
+			if err == nil || report.Failed != 1 || statErr == nil {
+				t.Errorf("strict: expected a failed file, got %v, %+v", err, report)
+			}
+			continue
+		}
+		if err != nil || report.Flagged != 1 || report.Translated != 1 || statErr != nil {
+			t.Errorf("expected a flagged translation, got %v, %+v", err, report)
+		}
+		if !strings.Contains(report.Files[0].Warning, "headings 2 -> 1") {
+			t.Errorf("unexpected warning %q", report.Files[0].Warning)
+		}
+	}
+}
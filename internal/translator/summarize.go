@@ -0,0 +1,50 @@
+package translator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/mddoc"
+)
+
+// DefaultSummaryLength is the default length of a summary in words
+const DefaultSummaryLength = 200
+
+// summarizePrompt asks for an abstract of a document, {LENGTH} is replaced by
+// the maximum number of words and {LANGUAGE} by the language instruction
+const summarizePrompt = "Summarize the markdown document in at most {LENGTH} words{LANGUAGE}. " +
+	"Describe what the document covers and its key points, without phrases such as \"This document\". " +
+	"Output ONLY the summary as a single paragraph of plain text, without markdown formatting or quotes."
+
+// Summarize returns an abstract of a markdown document of at most words
+// words. The summary is in the language of the document unless lang names
+// another one. Front matter is left out.
+func (t *Translator) Summarize(content string, words int, lang string) (string, error) {
+	if words <= 0 {
+		words = DefaultSummaryLength
+	}
+	body := strings.TrimSpace(mddoc.StripFrontMatter(content))
+	if body == "" {
+		return "", fmt.Errorf("nothing to summarize")
+	}
+
+	language := " in the language of the document"
+	if lang != "" {
+		if name, ok := SupportedLanguages[lang]; ok {
+			lang = name
+		}
+		language = " in " + lang
+	}
+	prompt := strings.NewReplacer("{LENGTH}", strconv.Itoa(words), "{LANGUAGE}", language).Replace(summarizePrompt)
+
+	summary, err := t.chat(prompt, body)
+	if err != nil {
+		return "", err
+	}
+	summary = strings.Join(strings.Fields(summary), " ")
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return summary, nil
+}
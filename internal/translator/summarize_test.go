@@ -0,0 +1,47 @@
+package translator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+func TestSummarize(t *testing.T) {
+	var prompt, content string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenAIRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt, content = req.Messages[0].Content, req.Messages[1].Content
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"content": "<think>hmm</think>\nA guide\nto installing.\n"}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig
+	cfg.OpenAIEndpointURL = server.URL
+
+	summary, err := New(&cfg, false).Summarize("---\ntitle: Install\n---\n# Install\n\nRun it.\n", 50, "ja")
+	if err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+	if summary != "A guide to installing." {
+		t.Errorf("unexpected summary: %q", summary)
+	}
+	if !strings.Contains(prompt, "at most 50 words in 日本語") {
+		t.Errorf("unexpected prompt: %s", prompt)
+	}
+	if strings.Contains(content, "title:") {
+		t.Errorf("expected the front matter to be left out: %q", content)
+	}
+
+	if _, err := New(&cfg, false).Summarize("---\ntitle: x\n---\n", 50, ""); err == nil {
+		t.Error("expected an error for an empty document")
+	}
+}
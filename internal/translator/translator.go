@@ -1,22 +1,32 @@
 package translator
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/logging"
 	"github.com/samzong/mdctl/internal/markdownfmt"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"github.com/samzong/mdctl/internal/metrics"
+	"github.com/samzong/mdctl/internal/netutil"
+	"github.com/samzong/mdctl/internal/throttle"
 	"gopkg.in/yaml.v3"
 )
 
+// logger reports translation progress
+var logger = logging.New("TRANSLATE")
+
 // SupportedLanguages defines the mapping of supported languages
 var SupportedLanguages = map[string]string{
 	"zh": "中文",
@@ -52,8 +62,33 @@ func GetSupportedLanguages() string {
 }
 
 type OpenAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string        `json:"role"`
+	Content string        `json:"content"`
+	Parts   []ContentPart `json:"-"` // Multimodal content, sent instead of Content when set
+}
+
+// ContentPart is a text or image part of a multimodal message
+type ContentPart struct {
+	Type     string    `json:"type"` // text or image_url
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL references the image of a content part, usually a data URL
+type ImageURL struct {
+	URL string `json:"url"`
+}
+
+// MarshalJSON sends the parts of multimodal messages as their content
+func (m OpenAIMessage) MarshalJSON() ([]byte, error) {
+	if len(m.Parts) == 0 {
+		type message OpenAIMessage
+		return json.Marshal(message(m))
+	}
+	return json.Marshal(struct {
+		Role    string        `json:"role"`
+		Content []ContentPart `json:"content"`
+	}{m.Role, m.Parts})
 }
 
 type OpenAIRequest struct {
@@ -61,6 +96,7 @@ type OpenAIRequest struct {
 	Messages    []OpenAIMessage `json:"messages"`
 	Temperature float64         `json:"temperature"`
 	TopP        float64         `json:"top_p"`
+	Stream      bool            `json:"stream,omitempty"`
 }
 
 type OpenAIResponse struct {
@@ -69,6 +105,9 @@ type OpenAIResponse struct {
 			Content string `json:"content"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
 }
 
 // Progress is used to track translation progress
@@ -82,26 +121,213 @@ type Progress struct {
 // ProgressCallback defines the progress callback function type
 type ProgressCallback func(progress Progress)
 
+// Options controls how files are translated
+type Options struct {
+	Format   bool     // Format markdown content after translation
+	Force    bool     // Translate even if the target is already translated
+	Catalogs bool     // Also translate YAML/TOML/JSON string catalogs in directory mode
+	DryRun   bool     // Only report which files would be translated and the estimated tokens
+	Report   *Report  // Collects per-file outcomes when set
+	Files    []string // Restricts directory mode to these files when not nil
+
+	// Include and Exclude are globs of the files of directory mode relative
+	// to the source directory, e.g. "docs/**" or "node_modules/**". Without
+	// Include every file is included, Exclude also skips whole directories.
+	Include []string
+	Exclude []string
+
+	// Directory runs keep a manifest of finished files in the cache directory
+	Resume          bool   // Skip the files finished by the previous run of the same directory
+	ContinueOnError bool   // Keep translating the remaining files when one fails
+	CacheDir        string // Cache directory of the run manifest (default ~/.cache/mdctl)
+
+	// LanguageSuffix names in-place translations name.<lang>.md instead of
+	// name_<lang>.md and skips sources that already carry a language suffix
+	LanguageSuffix bool
+
+	// Relative links and images are rewritten to stay valid from the target
+	// location, except links to documents below SourceRoot, a tree whose
+	// translations mirror its layout
+	SourceRoot string
+	CopyAssets bool // Copy referenced local assets next to the translation instead of rewriting their paths
+
+	// MDX masks JSX and HTML components, import/export statements and
+	// expressions before translation, .mdx files are always translated so
+	MDX bool
+
+	// Translations whose headings, code blocks, links or tables differ from
+	// the source are sent back for repair StructureRetries times (0 for
+	// DefaultStructureRetries, negative for none). When they still differ
+	// they are written and flagged in the report, or fail with StrictStructure.
+	StructureRetries int
+	StrictStructure  bool
+
+	// Bilingual keeps the source paragraphs before their translations, visible
+	// (BilingualAlternate) or in HTML comments (BilingualComments)
+	Bilingual string
+}
+
+// File statuses reported in FileResult
+const (
+	statusTranslated = "translated"
+	statusSkipped    = "skipped"
+	statusPlanned    = "planned"
+	statusFailed     = "failed"
+)
+
+// outcome is what happened to a single file
+type outcome struct {
+	status  string
+	tokens  int    // Estimated tokens of a planned translation
+	warning string // Problem of a translation that was written anyway
+}
+
+// FileResult describes the outcome of translating a single file
+type FileResult struct {
+	Source          string `json:"source"`
+	Target          string `json:"target"`
+	Status          string `json:"status"` // translated, skipped, planned (dry run) or failed
+	EstimatedTokens int    `json:"estimated_tokens,omitempty"`
+	Error           string `json:"error,omitempty"`
+	Warning         string `json:"warning,omitempty"` // Why a translated file is flagged for review
+}
+
+// Report collects translation outcomes across files
+type Report struct {
+	Files           []FileResult `json:"files"`
+	Translated      int          `json:"translated"`
+	Skipped         int          `json:"skipped"`
+	Planned         int          `json:"planned,omitempty"`
+	Failed          int          `json:"failed"`
+	Flagged         int          `json:"flagged,omitempty"` // Translated files with a warning
+	EstimatedTokens int          `json:"estimated_tokens,omitempty"`
+}
+
+// add records the outcome of a single file, a nil report ignores it
+func (r *Report) add(src, dst string, o outcome, err error) {
+	if err == nil && o.status == statusTranslated {
+		metrics.AddFiles(1)
+	}
+	if r == nil {
+		return
+	}
+
+	result := FileResult{Source: src, Target: dst, Status: o.status, Warning: o.warning}
+	if err == nil && o.warning != "" {
+		r.Flagged++
+	}
+	switch {
+	case err != nil:
+		result.Status = statusFailed
+		result.Error = err.Error()
+		r.Failed++
+	case o.status == statusTranslated:
+		r.Translated++
+	case o.status == statusPlanned:
+		result.EstimatedTokens = o.tokens
+		r.Planned++
+		r.EstimatedTokens += o.tokens
+	default:
+		result.Status = statusSkipped
+		r.Skipped++
+	}
+	r.Files = append(r.Files, result)
+}
+
 // Translator struct for the translator
 type Translator struct {
+	ctx      context.Context
 	config   *config.Config
 	format   bool
+	mdx      bool
 	progress ProgressCallback
+	limiter  *throttle.Limiter
+	client   *http.Client
+	timeout  time.Duration
+	retries  int
+	stream   bool
+	source   string // Translated document, selects project prompts when set
+
+	checkStructure   bool // Compare the structure of translations with their source
+	structureRetries int  // Repair requests for translations whose structure differs
+
+	bilingual string // Keeps the source next to the translations of documents, see WithBilingual
 }
 
 // New creates a new translator instance
 func New(cfg *config.Config, format bool) *Translator {
+	timeout := time.Duration(cfg.AITimeout) * time.Second
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	retries := cfg.AIMaxRetries
+	switch {
+	case retries == 0:
+		retries = DefaultRetries
+	case retries < 0:
+		retries = 0
+	}
+
 	return &Translator{
+		ctx:    context.Background(),
 		config: cfg,
 		format: format,
+		limiter: throttle.Shared(throttle.Limits{
+			RequestsPerMinute: cfg.AIRequestsPerMin,
+			TokensPerMinute:   cfg.AITokensPerMin,
+			DailyTokenQuota:   cfg.AIDailyTokenQuota,
+		}),
+		client:  netutil.NewClient(0),
+		timeout: timeout,
+		retries: retries,
+		stream:  cfg.AIStream,
 		progress: func(p Progress) {
 			if p.Total > 1 {
-				fmt.Printf("Translating file [%d/%d]: %s\n", p.Current, p.Total, p.SourceFile)
+				logger.Infof("Translating file [%d/%d]: %s", p.Current, p.Total, p.SourceFile)
 			}
 		},
 	}
 }
 
+// WithContext sets the context that cancels in-flight requests
+func (t *Translator) WithContext(ctx context.Context) *Translator {
+	t.ctx = ctx
+	return t
+}
+
+// WithSource sets the path of the translated document or its directory,
+// whose project configuration files may override the prompt
+func (t *Translator) WithSource(path string) *Translator {
+	t.source = path
+	return t
+}
+
+// WithMDX enables the MDX mode, which also masks JSX and HTML tags, ESM
+// import/export statements and expressions, and checks that the component
+// structure survived the translation
+func (t *Translator) WithMDX(enabled bool) *Translator {
+	t.mdx = enabled
+	return t
+}
+
+// WithStructureCheck compares the headings, code blocks, links and tables of
+// every translation with its source and asks the model up to retries times
+// to repair a translation that differs. A translation that still differs
+// fails with a *structureError carrying it.
+func (t *Translator) WithStructureCheck(retries int) *Translator {
+	t.checkStructure = true
+	t.structureRetries = retries
+	return t
+}
+
+// WithBilingual keeps the source paragraphs of translated documents before
+// their translations, visible with BilingualAlternate or in HTML comments
+// with BilingualComments. An empty mode writes the translation only.
+func (t *Translator) WithBilingual(mode string) *Translator {
+	t.bilingual = mode
+	return t
+}
+
 var (
 	// RegexPatterns defines patterns for removing special content blocks
 	RegexPatterns = []struct {
@@ -117,90 +343,182 @@ func (t *Translator) TranslateContent(content string, lang string) (string, erro
 	// Remove potential front matter
 	content = removeFrontMatter(content)
 
-	prompt := strings.Replace(t.config.TranslatePrompt, "{TARGET_LANG}", lang, 1)
-
-	messages := []OpenAIMessage{
-		{Role: "system", Content: prompt},
-		{Role: "user", Content: content},
+	prompt, err := promptFor(t.config, t.source, lang)
+	if err != nil {
+		return "", err
 	}
 
-	reqBody := OpenAIRequest{
-		Model:       t.config.ModelName,
-		Messages:    messages,
-		Temperature: t.config.Temperature,
-		TopP:        t.config.TopP,
+	// Mask inline code, URLs and footnote references so the model cannot alter them
+	source := content
+	p := &protector{mdx: t.mdx}
+	content = p.mask(content)
+	if len(p.spans) > 0 {
+		prompt += placeholderInstruction
 	}
 
-	jsonData, err := json.Marshal(reqBody)
+	reply, err := t.chat(prompt, content)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %v", err)
+		return "", err
 	}
 
-	req, err := http.NewRequest("POST", t.config.OpenAIEndpointURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	translatedContent, err := t.unmask(p, reply)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
+		return "", err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+t.config.OpenAIAPIKey)
+	if t.checkStructure {
+		want := documentStructure(source)
+		for attempt := 0; ; attempt++ {
+			diffs := want.differences(documentStructure(translatedContent))
+			if len(diffs) == 0 {
+				break
+			}
+			if attempt >= t.structureRetries {
+				return "", &structureError{differences: diffs, translation: t.formatted(translatedContent)}
+			}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %v", err)
+			logger.Warnf("Translation changed the document structure (%s), requesting a repair", strings.Join(diffs, ", "))
+			instruction := fmt.Sprintf(repairInstruction, strings.Join(diffs, ", "))
+			repaired, err := t.chatMessages([]OpenAIMessage{
+				{Role: "system", Content: prompt},
+				{Role: "user", Content: content},
+				{Role: "assistant", Content: reply},
+				{Role: "user", Content: instruction},
+			}, estimateTokens(prompt, content+reply+instruction))
+			if err != nil {
+				return "", err
+			}
+			repairedContent, err := t.unmask(p, repaired)
+			if err != nil {
+				// A repair that lost placeholders is no better than the translation
+				return "", &structureError{differences: diffs, translation: t.formatted(translatedContent)}
+			}
+			reply, translatedContent = repaired, repairedContent
+		}
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	return t.formatted(translatedContent), nil
+}
+
+// unmask checks the component structure of a model reply and restores its
+// placeholders
+func (t *Translator) unmask(p *protector, reply string) (string, error) {
+	if err := p.checkComponents(reply); err != nil {
+		return "", err
+	}
+
+	translatedContent, err := p.restore(reply)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %v", err)
+		return "", err
 	}
 
-	var response OpenAIResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("failed to parse response: %v\nResponse body: %s", err, string(body))
+	// Remove potential markdown code block markers
+	return strings.TrimPrefix(translatedContent, "\n"), nil
+}
+
+// formatted formats the translated content if formatting is enabled
+func (t *Translator) formatted(translatedContent string) string {
+	if t.format {
+		formatter := markdownfmt.New(true)
+		translatedContent = formatter.Format(translatedContent)
 	}
+	return translatedContent
+}
+
+// Chat sends a system prompt and content to the configured model and returns
+// its reply, for AI features other than translation
+func (t *Translator) Chat(systemPrompt, content string) (string, error) {
+	return t.chat(systemPrompt, content)
+}
 
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no translation result\nResponse body: %s", string(body))
+// chat sends a system prompt and user content to the chat completions endpoint
+// and returns the cleaned reply
+func (t *Translator) chat(systemPrompt, content string) (string, error) {
+	messages := []OpenAIMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: content},
 	}
+	return t.chatMessages(messages, estimateTokens(systemPrompt, content))
+}
 
-	// Get translated content
-	translatedContent := response.Choices[0].Message.Content
+// chatMessages sends messages to the chat completions endpoint, estimate is
+// the expected token usage, and returns the cleaned reply
+func (t *Translator) chatMessages(messages []OpenAIMessage, estimate int) (string, error) {
+	reqBody := OpenAIRequest{
+		Model:       t.config.ModelName,
+		Messages:    messages,
+		Temperature: t.config.Temperature,
+		TopP:        t.config.TopP,
+	}
 
-	// Remove special content blocks
-	for _, pattern := range RegexPatterns {
-		translatedContent = regexp.MustCompile(pattern.Pattern).ReplaceAllString(translatedContent, pattern.Replace)
+	reply, used, err := t.complete(reqBody, estimate)
+	if err != nil {
+		return "", err
 	}
 
-	// Remove potential markdown code block markers
-	translatedContent = strings.TrimPrefix(translatedContent, "\n")
+	if used == 0 {
+		used = estimate
+	}
+	if err := t.limiter.Record(used); err != nil {
+		logger.Warnf("Failed to record AI usage: %v", err)
+	}
 
-	// If formatting is enabled, format the translated content
-	if t.format {
-		formatter := markdownfmt.New(true)
-		translatedContent = formatter.Format(translatedContent)
+	// Remove special content blocks
+	for _, pattern := range RegexPatterns {
+		reply = regexp.MustCompile(pattern.Pattern).ReplaceAllString(reply, pattern.Replace)
 	}
 
-	return translatedContent, nil
+	return reply, nil
+}
+
+// estimateTokens estimates the tokens used by a request, assuming the reply is
+// about as long as the input
+func estimateTokens(systemPrompt, content string) int {
+	return 2 * throttle.EstimateTokens(systemPrompt+content)
 }
 
 // removeFrontMatter removes front matter from content
 func removeFrontMatter(content string) string {
-	// If content starts with ---, it may contain front matter
 	trimmedContent := strings.TrimSpace(content)
-	if strings.HasPrefix(trimmedContent, "---") {
-		parts := strings.SplitN(trimmedContent, "---", 3)
-		if len(parts) >= 3 {
-			return strings.TrimSpace(parts[2])
-		}
+	if body := mddoc.StripFrontMatter(trimmedContent); body != trimmedContent {
+		return strings.TrimSpace(body)
 	}
 	return content
 }
 
 // ProcessFile handles translation of a single file
-func ProcessFile(srcPath, dstPath, targetLang string, cfg *config.Config, format bool, force bool) error {
-	t := New(cfg, format)
+func ProcessFile(ctx context.Context, srcPath, dstPath, targetLang string, cfg *config.Config, opts Options) error {
+	_, err := processFile(ctx, srcPath, dstPath, targetLang, cfg, opts)
+	return err
+}
+
+// processFile translates a single file and reports its outcome
+func processFile(ctx context.Context, srcPath, dstPath, targetLang string, cfg *config.Config, opts Options) (outcome, error) {
+	var o outcome
+	var err error
+
+	// String catalogs are translated value by value
+	if IsCatalogFile(srcPath) {
+		o, err = translateCatalogFile(ctx, srcPath, dstPath, targetLang, cfg, opts)
+	} else {
+		o, err = translateMarkdownFile(ctx, srcPath, dstPath, targetLang, cfg, opts)
+	}
+
+	opts.Report.add(srcPath, dstPath, o, err)
+	return o, err
+}
+
+// translateMarkdownFile translates a markdown file, reporting whether the target was written
+func translateMarkdownFile(ctx context.Context, srcPath, dstPath, targetLang string, cfg *config.Config, opts Options) (outcome, error) {
+	retries := opts.StructureRetries
+	switch {
+	case retries == 0:
+		retries = DefaultStructureRetries
+	case retries < 0:
+		retries = 0
+	}
+	t := New(cfg, opts.Format).WithContext(ctx).WithMDX(opts.MDX || IsMDX(srcPath)).WithSource(srcPath).
+		WithStructureCheck(retries).WithBilingual(opts.Bilingual)
 
 	// Check if target path is a directory
 	dstInfo, err := os.Stat(dstPath)
@@ -212,91 +530,212 @@ func ProcessFile(srcPath, dstPath, targetLang string, cfg *config.Config, format
 	if _, err := os.Stat(dstPath); err == nil {
 		dstContent, err := os.ReadFile(dstPath)
 		if err != nil {
-			return fmt.Errorf("failed to read target file: %v", err)
+			return outcome{}, fmt.Errorf("failed to read target file: %v", err)
 		}
 
 		// Check if already translated
-		var dstFrontMatter map[string]interface{}
-		if strings.HasPrefix(string(dstContent), "---\n") {
-			parts := strings.SplitN(string(dstContent)[4:], "\n---\n", 2)
-			if len(parts) == 2 {
-				if err := yaml.Unmarshal([]byte(parts[0]), &dstFrontMatter); err != nil {
-					return fmt.Errorf("failed to parse target file front matter: %v", err)
-				}
-				if translated, ok := dstFrontMatter["translated"].(bool); ok && translated {
-					if !force {
-						fmt.Printf("Skipping %s (already translated, use -F to force translate)\n", srcPath)
-						return nil
-					}
-					fmt.Printf("Force translating %s\n", srcPath)
-				}
+		dstFrontMatter, _, err := splitFrontMatter(string(dstContent))
+		if err != nil {
+			return outcome{}, fmt.Errorf("failed to parse target file front matter: %v", err)
+		}
+		if translated, ok := dstFrontMatter["translated"].(bool); ok && translated {
+			if !opts.Force {
+				logger.Infof("Skipping %s (already translated, use -F to force translate)", srcPath)
+				return outcome{status: statusSkipped}, nil
 			}
+			logger.Infof("Force translating %s", srcPath)
 		}
 	}
 
 	// Read source file content
 	content, err := os.ReadFile(srcPath)
 	if err != nil {
-		return fmt.Errorf("failed to read source file: %v", err)
+		return outcome{}, fmt.Errorf("failed to read source file: %v", err)
 	}
 
 	// Parse front matter
-	var frontMatter map[string]interface{}
-	contentToTranslate := string(content)
-
-	// Check and parse front matter
-	if strings.HasPrefix(contentToTranslate, "---\n") {
-		parts := strings.SplitN(contentToTranslate[4:], "\n---\n", 2)
-		if len(parts) == 2 {
-			if err := yaml.Unmarshal([]byte(parts[0]), &frontMatter); err != nil {
-				return fmt.Errorf("failed to parse front matter: %v", err)
-			}
-			contentToTranslate = parts[1]
+	frontMatter, contentToTranslate, err := splitFrontMatter(string(content))
+	if err != nil {
+		return outcome{}, err
+	}
+
+	if opts.DryRun {
+		prompt, err := promptFor(cfg, srcPath, targetLang)
+		if err != nil {
+			return outcome{}, err
 		}
+		tokens := estimateTokens(prompt, contentToTranslate)
+		logger.Infof("Would translate %s -> %s (~%d tokens)", srcPath, dstPath, tokens)
+		return outcome{status: statusPlanned, tokens: tokens}, nil
 	}
 
 	// Translate content
+	var warning string
 	translatedContent, err := t.TranslateContent(contentToTranslate, targetLang)
+	var structureErr *structureError
+	if errors.As(err, &structureErr) && !opts.StrictStructure {
+		logger.Warnf("Flagging %s: %v", srcPath, err)
+		translatedContent, warning, err = structureErr.translation, err.Error(), nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to translate content: %v", err)
+		return outcome{}, fmt.Errorf("failed to translate content: %v", err)
 	}
-
-	// Update front matter
-	if frontMatter == nil {
-		frontMatter = make(map[string]interface{})
+	translatedContent, keepErr := t.keepSource(contentToTranslate, translatedContent)
+	if keepErr != nil && warning == "" {
+		logger.Warnf("Flagging %s: %v", srcPath, keepErr)
+		warning = keepErr.Error()
 	}
-	frontMatter["translated"] = true
 
-	// Generate new file content
-	frontMatterBytes, err := yaml.Marshal(frontMatter)
+	// Keep relative references valid, the kept source included from the target location
+	relinker, err := newRelinker(srcPath, dstPath, opts)
 	if err != nil {
-		return fmt.Errorf("failed to marshal front matter: %v", err)
+		return outcome{}, fmt.Errorf("failed to resolve paths: %v", err)
+	}
+	if relinker != nil {
+		if translatedContent, err = relinker.rewrite(translatedContent); err != nil {
+			return outcome{}, err
+		}
 	}
 
-	newContent := fmt.Sprintf("---\n%s---\n\n%s", string(frontMatterBytes), translatedContent)
+	newContent, err := markTranslated(frontMatter, translatedContent)
+	if err != nil {
+		return outcome{}, err
+	}
 
 	// Create target directory if it doesn't exist
 	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
-		return fmt.Errorf("failed to create target directory: %v", err)
+		return outcome{}, fmt.Errorf("failed to create target directory: %v", err)
 	}
 
 	// Write translated content to target file
-	if err := os.WriteFile(dstPath, []byte(newContent), 0644); err != nil {
-		return fmt.Errorf("failed to write target file: %v", err)
+	if err := fsutil.WriteFileAtomic(dstPath, []byte(newContent), 0644); err != nil {
+		return outcome{}, fmt.Errorf("failed to write target file: %v", err)
 	}
 
-	return nil
+	return outcome{status: statusTranslated, warning: warning}, nil
+}
+
+// TranslateDocument translates a whole markdown document, keeping its front
+// matter and marking it as translated
+func (t *Translator) TranslateDocument(content, lang string) (string, error) {
+	frontMatter, body, err := splitFrontMatter(content)
+	if err != nil {
+		return "", err
+	}
+
+	translatedContent, err := t.TranslateContent(body, lang)
+	if err != nil {
+		return "", fmt.Errorf("failed to translate content: %v", err)
+	}
+	translatedContent, err = t.keepSource(body, translatedContent)
+	if err != nil {
+		logger.Warnf("%v", err)
+	}
+
+	return markTranslated(frontMatter, translatedContent)
+}
+
+// keepSource adds the source paragraphs to a translation in bilingual mode.
+// The error tells why the source could only be kept as a whole, the returned
+// translation is usable anyway.
+func (t *Translator) keepSource(source, translation string) (string, error) {
+	if t.bilingual == "" {
+		return translation, nil
+	}
+	content, ok := bilingual(source, translation, t.bilingual)
+	if !ok {
+		return content, fmt.Errorf("the paragraphs of the translation differ from the source, the bilingual output keeps the whole source before it")
+	}
+	return content, nil
+}
+
+// splitFrontMatter separates the YAML front matter from the markdown body
+func splitFrontMatter(content string) (map[string]interface{}, string, error) {
+	var frontMatter map[string]interface{}
+	if yamlText, body := mddoc.SplitFrontMatter(content); body != content {
+		if err := yaml.Unmarshal([]byte(yamlText), &frontMatter); err != nil {
+			return nil, "", fmt.Errorf("failed to parse front matter: %v", err)
+		}
+		content = body
+	}
+	return frontMatter, content, nil
+}
+
+// markTranslated renders a translated document with translated: true in its front matter
+func markTranslated(frontMatter map[string]interface{}, translatedContent string) (string, error) {
+	if frontMatter == nil {
+		frontMatter = make(map[string]interface{})
+	}
+	frontMatter["translated"] = true
+
+	frontMatterBytes, err := yaml.Marshal(frontMatter)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal front matter: %v", err)
+	}
+
+	return fmt.Sprintf("---\n%s---\n\n%s", string(frontMatterBytes), translatedContent), nil
 }
 
 // ProcessDirectory processes all markdown files in the directory
-func ProcessDirectory(srcDir, dstDir string, targetLang string, cfg *config.Config, force bool, format bool) error {
+func ProcessDirectory(ctx context.Context, srcDir, dstDir string, targetLang string, cfg *config.Config, opts Options) error {
+	var selected map[string]bool
+	if opts.Files != nil {
+		selected = make(map[string]bool, len(opts.Files))
+		for _, file := range opts.Files {
+			selected[filepath.Clean(file)] = true
+		}
+	}
+
+	include, err := fsutil.CompileGlobs(opts.Include)
+	if err != nil {
+		return err
+	}
+	exclude, err := fsutil.CompileGlobs(opts.Exclude)
+	if err != nil {
+		return err
+	}
+	// relSlash returns a path relative to the source directory with forward slashes
+	relSlash := func(path string) string {
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return filepath.ToSlash(path)
+		}
+		return filepath.ToSlash(rel)
+	}
+	// skipDir reports whether an exclude pattern covers a whole directory,
+	// such as node_modules/**
+	skipDir := func(path string) bool {
+		return path != srcDir && fsutil.MatchAny(exclude, relSlash(path)+"/")
+	}
+
+	// isTranslatable reports whether a file should be picked up in directory mode
+	isTranslatable := func(path string) bool {
+		if selected != nil && !selected[filepath.Clean(path)] {
+			return false
+		}
+		rel := relSlash(path)
+		if (len(include) > 0 && !fsutil.MatchAny(include, rel)) || fsutil.MatchAny(exclude, rel) {
+			return false
+		}
+		if opts.LanguageSuffix && hasLanguageSuffix(path) {
+			return false
+		}
+		if filepath.Ext(path) == ".md" || IsMDX(path) {
+			return true
+		}
+		return opts.Catalogs && IsCatalogFile(path)
+	}
+
 	// First calculate the total number of files to process
 	var total int
-	err := filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && filepath.Ext(path) == ".md" {
+		if info.IsDir() && skipDir(path) {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && isTranslatable(path) {
 			total++
 		}
 		return nil
@@ -305,28 +744,50 @@ func ProcessDirectory(srcDir, dstDir string, targetLang string, cfg *config.Conf
 		return fmt.Errorf("failed to count files: %v", err)
 	}
 
-	fmt.Printf("Found %d markdown files to translate\n", total)
+	logger.Infof("Found %d files to translate", total)
+
+	// Links between translated documents stay inside the target tree
+	if dstDir != "" {
+		opts.SourceRoot = srcDir
+	}
+
+	// Dry runs change nothing, so they neither read nor write a manifest
+	var state *runState
+	if !opts.DryRun {
+		if state, err = openRunState(srcDir, dstDir, targetLang, opts.CacheDir, opts.Resume); err != nil {
+			return err
+		}
+	}
 
 	// Create translator instance
-	t := New(cfg, format)
+	t := New(cfg, opts.Format)
 	current := 0
+	failed := 0
 
 	// Walk through source directory
-	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
+		// Stop before the next file once interrupted
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// Skip directories
 		if info.IsDir() {
+			if skipDir(path) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
-		// Only process markdown files
-		ext := filepath.Ext(path)
-		if ext != ".md" {
+		// Only process markdown files and, if enabled, string catalogs
+		if !isTranslatable(path) {
 			return nil
 		}
+		ext := filepath.Ext(path)
 
 		current++
 
@@ -342,7 +803,11 @@ func ProcessDirectory(srcDir, dstDir string, targetLang string, cfg *config.Conf
 			dir := filepath.Dir(path)
 			base := filepath.Base(path)
 			nameWithoutExt := strings.TrimSuffix(base, ext)
-			dstPath = filepath.Join(dir, nameWithoutExt+"_"+targetLang+ext)
+			if opts.LanguageSuffix {
+				dstPath = filepath.Join(dir, nameWithoutExt+"."+targetLang+ext)
+			} else {
+				dstPath = filepath.Join(dir, nameWithoutExt+"_"+targetLang+ext)
+			}
 		} else {
 			// If a different target directory is specified, use the specified directory structure
 			dstPath = filepath.Join(dstDir, relPath)
@@ -355,11 +820,44 @@ func ProcessDirectory(srcDir, dstDir string, targetLang string, cfg *config.Conf
 			TargetFile: dstPath,
 		})
 
+		if state != nil && state.done(path) {
+			logger.Infof("Skipping %s (finished in previous run)", path)
+			opts.Report.add(path, dstPath, outcome{status: statusSkipped}, nil)
+			return nil
+		}
+
 		// Process file
-		if err := ProcessFile(path, dstPath, targetLang, cfg, format, force); err != nil {
-			return fmt.Errorf("failed to process file %s: %v", path, err)
+		o, err := processFile(ctx, path, dstPath, targetLang, cfg, opts)
+		if err != nil && ctx.Err() != nil {
+			return err
+		}
+		if state != nil {
+			if saveErr := state.record(path, o.status, err); saveErr != nil {
+				return saveErr
+			}
+		}
+		if err != nil {
+			if !opts.ContinueOnError {
+				return fmt.Errorf("failed to process file %s: %v", path, err)
+			}
+			logger.Warnf("Failed to translate %s: %v", path, err)
+			failed++
 		}
 
 		return nil
 	})
+	if err != nil {
+		if state != nil {
+			logger.Infof("Run state saved, use --resume to continue with the unfinished files")
+		}
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d files failed to translate, use --resume to retry them", failed)
+	}
+
+	if state != nil {
+		return state.remove()
+	}
+	return nil
 }
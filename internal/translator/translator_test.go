@@ -0,0 +1,54 @@
+package translator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/config"
+)
+
+func TestProcessDirectory_IncludeExclude(t *testing.T) {
+	src := t.TempDir()
+	for _, name := range []string{"index.md", "index_zh.md", "CHANGELOG.md", "guide/setup.md", "guide/setup_zh.md", "node_modules/pkg/README.md", "blog/post.md"} {
+		path := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("# "+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		include, exclude []string
+		want             string
+	}{
+		{nil, []string{"node_modules/**", "CHANGELOG.md", "**_zh.md"}, "blog/post.md,guide/setup.md,index.md"},
+		{[]string{"guide/**", "index.md"}, []string{"**_zh.md"}, "guide/setup.md,index.md"},
+	}
+	for _, tt := range tests {
+		cfg := config.DefaultConfig
+		opts := Options{DryRun: true, Report: &Report{}, Include: tt.include, Exclude: tt.exclude}
+		if err := ProcessDirectory(context.Background(), src, filepath.Join(t.TempDir(), "out"), "zh", &cfg, opts); err != nil {
+			t.Fatalf("ProcessDirectory failed: %v", err)
+		}
+		var got []string
+		for _, file := range opts.Report.Files {
+			rel, _ := filepath.Rel(src, file.Source)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("include %v, exclude %v: expected %s, got %v", tt.include, tt.exclude, tt.want, got)
+		}
+	}
+
+	cfg := config.DefaultConfig
+	if err := ProcessDirectory(context.Background(), src, "", "zh", &cfg, Options{DryRun: true, Exclude: []string{"[a-"}}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
@@ -0,0 +1,164 @@
+package uploader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/storage"
+)
+
+// urlRegex matches the URLs in markdown and HTML, wherever they appear
+var urlRegex = regexp.MustCompile(`(?:https?:)?//[^\s()<>"'\[\]]+`)
+
+// gcExtensions are the object extensions garbage collection considers, other
+// objects sharing the bucket are never deleted
+var gcExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true,
+	".svg": true, ".bmp": true, ".ico": true, ".avif": true, ".tif": true, ".tiff": true,
+}
+
+// GCResult holds the outcome of a garbage collection run
+type GCResult struct {
+	Objects int      `json:"objects"`           // Images found under the prefix
+	Orphans []string `json:"orphans"`           // Keys no markdown file or cache item references
+	Deleted []string `json:"deleted,omitempty"` // Keys that were deleted
+}
+
+// FindOrphans lists the images stored under the path prefix and returns the
+// ones that no markdown file of the source references and no cache item
+// records. A key counts as referenced when it is the path, or a trailing part
+// of the path, of any URL in the markdown, which covers custom domains and
+// path-style endpoints alike.
+func (u *Uploader) FindOrphans(ctx context.Context) (*GCResult, error) {
+	lister, ok := u.provider.(storage.Lister)
+	if !ok {
+		return nil, errors.New("provider does not support listing objects")
+	}
+
+	referenced, err := u.referencedPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := strings.Trim(u.pathPrefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	keys, err := lister.ListObjects(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GCResult{Orphans: []string{}}
+	for _, key := range keys {
+		if !gcExtensions[strings.ToLower(path.Ext(key))] {
+			continue
+		}
+		result.Objects++
+		if !referenced[key] {
+			result.Orphans = append(result.Orphans, key)
+		}
+	}
+	sort.Strings(result.Orphans)
+	return result, nil
+}
+
+// DeleteOrphans deletes the orphans of a result and records the deleted keys.
+// It stops at the first failure or when ctx is cancelled.
+func (u *Uploader) DeleteOrphans(ctx context.Context, result *GCResult) error {
+	lister, ok := u.provider.(storage.Lister)
+	if !ok {
+		return errors.New("provider does not support deleting objects")
+	}
+	for _, key := range result.Orphans {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := lister.DeleteObject(key); err != nil {
+			return err
+		}
+		logger.Infof("Deleted %s", key)
+		result.Deleted = append(result.Deleted, key)
+	}
+	return nil
+}
+
+// referencedPaths returns the URL paths referenced by the markdown files and
+// the cache, each with all its trailing parts
+func (u *Uploader) referencedPaths(ctx context.Context) (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	add := func(rawURL string) {
+		parsed, err := url.Parse(rawURL)
+		if err != nil {
+			return
+		}
+		p := strings.Trim(parsed.Path, "/")
+		for p != "" {
+			referenced[p] = true
+			i := strings.Index(p, "/")
+			if i < 0 {
+				break
+			}
+			p = p[i+1:]
+		}
+	}
+
+	files, err := u.markdownFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %v", file, err)
+		}
+		for _, match := range urlRegex.FindAllString(string(content), -1) {
+			add(match)
+		}
+	}
+
+	for _, item := range u.cache.Items {
+		add(item.URL)
+		if item.RemotePath != "" {
+			referenced[item.RemotePath] = true
+			referenced[path.Join(strings.Trim(u.pathPrefix, "/"), item.RemotePath)] = true
+		}
+	}
+	return referenced, nil
+}
+
+// markdownFiles returns the markdown files of the configured source
+func (u *Uploader) markdownFiles() ([]string, error) {
+	if len(u.Config.Files) > 0 {
+		return u.Config.Files, nil
+	}
+	if u.Config.SourceFile != "" {
+		return []string{u.Config.SourceFile}, nil
+	}
+	if u.Config.SourceDir == "" {
+		return nil, errors.New("either source file or source directory must be specified")
+	}
+
+	var files []string
+	err := filepath.Walk(u.Config.SourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && u.isMarkdown(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,105 @@
+package uploader
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/samzong/mdctl/internal/mddoc"
+)
+
+// Styles of the rewritten image links
+const (
+	LinkStyleInline    = "inline"    // ![alt](url), the default
+	LinkStyleHTML      = "html"      // <img src="url" alt="alt" width="...">
+	LinkStyleReference = "reference" // ![alt][image-1] with the definitions at the end of the file
+)
+
+// referencePrefix starts the labels of reference-style image links
+const referencePrefix = "image-"
+
+// definitionLabelRegex matches the label of a link reference definition
+var definitionLabelRegex = regexp.MustCompile(`(?m)^[ \t]{0,3}\[([^\]\n]+)\]:`)
+
+// ValidLinkStyle reports whether style is a supported link style, empty for
+// the default
+func ValidLinkStyle(style string) bool {
+	switch style {
+	case "", LinkStyleInline, LinkStyleHTML, LinkStyleReference:
+		return true
+	}
+	return false
+}
+
+// ValidImageWidth reports whether width is a pixel count or a percentage,
+// the values the width attribute of an image takes
+func ValidImageWidth(width string) bool {
+	n, err := strconv.Atoi(strings.TrimSuffix(width, "%"))
+	return err == nil && n > 0
+}
+
+// linkStyler renders rewritten images of one file in a link style other
+// than inline
+type linkStyler struct {
+	style       string
+	width       string
+	labels      map[string]string // Reference label by URL
+	used        map[string]bool   // Labels defined in the file
+	definitions []string          // Definitions to append to the file
+}
+
+// newLinkStyler returns the styler of a document, nil for inline links
+func newLinkStyler(style, width string, doc *mddoc.Document) *linkStyler {
+	if style == "" || style == LinkStyleInline {
+		return nil
+	}
+	s := &linkStyler{style: style, width: width, labels: make(map[string]string), used: make(map[string]bool)}
+	for _, m := range definitionLabelRegex.FindAllStringSubmatch(string(doc.Body()), -1) {
+		s.used[strings.ToLower(m[1])] = true
+	}
+	return s
+}
+
+// image returns the replacement of a whole image, alt is its alt text as
+// written in markdown
+func (s *linkStyler) image(url, alt, title string) string {
+	if s.style == LinkStyleHTML {
+		tag := fmt.Sprintf(`<img src="%s" alt="%s"`, html.EscapeString(url), html.EscapeString(mddoc.Unescape(alt)))
+		if title != "" {
+			tag += fmt.Sprintf(` title="%s"`, html.EscapeString(title))
+		}
+		if s.width != "" {
+			tag += fmt.Sprintf(` width="%s"`, s.width)
+		}
+		return tag + ">"
+	}
+
+	label, ok := s.labels[url]
+	if !ok {
+		for i := 1; ; i++ {
+			label = referencePrefix + strconv.Itoa(i)
+			if !s.used[label] {
+				break
+			}
+		}
+		s.used[label] = true
+		s.labels[url] = label
+		definition := fmt.Sprintf("[%s]: %s", label, url)
+		if title != "" {
+			definition += fmt.Sprintf(" %q", title)
+		}
+		s.definitions = append(s.definitions, definition)
+	}
+	return fmt.Sprintf("![%s][%s]", alt, label)
+}
+
+// finish appends the collected reference definitions to the content
+func (s *linkStyler) finish(content []byte) []byte {
+	if s == nil || len(s.definitions) == 0 {
+		return content
+	}
+	text := strings.TrimRight(string(content), "\n")
+	return []byte(text + "\n\n" + strings.Join(s.definitions, "\n") + "\n")
+}
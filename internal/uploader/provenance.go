@@ -0,0 +1,175 @@
+package uploader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/mddoc"
+)
+
+// Places where the original destinations of rewritten images are recorded
+// with their uploaded URLs
+const (
+	ProvenanceFrontMatter = "frontmatter" // ProvenanceKey field of the front matter of each file
+	ProvenanceSidecar     = "sidecar"     // ProvenanceFile in the source directory
+)
+
+// ProvenanceKey is the front matter field mapping original destinations to URLs
+const ProvenanceKey = "mdctl_assets"
+
+// ProvenanceFile is the sidecar mapping original destinations to URLs per file
+const ProvenanceFile = ".mdctl-assets.json"
+
+// provenanceVersion is the format version of ProvenanceFile
+const provenanceVersion = "1.0"
+
+// Provenance is the content of a ProvenanceFile
+type Provenance struct {
+	Version string `json:"version"`
+	// Files maps the markdown files, relative to the directory of the
+	// sidecar, to the original destinations of their images and the URLs
+	// they were rewritten to
+	Files map[string]map[string]string `json:"files"`
+}
+
+// ValidProvenance reports whether p is a supported place, empty records nothing
+func ValidProvenance(p string) bool {
+	switch p {
+	case "", ProvenanceFrontMatter, ProvenanceSidecar:
+		return true
+	}
+	return false
+}
+
+// LoadProvenance reads a ProvenanceFile, a missing file is empty
+func LoadProvenance(path string) (*Provenance, error) {
+	p := &Provenance{Version: provenanceVersion, Files: make(map[string]map[string]string)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if p.Files == nil {
+		p.Files = make(map[string]map[string]string)
+	}
+	return p, nil
+}
+
+// recordProvenance remembers the URL an image destination of a file was
+// rewritten to
+func (u *Uploader) recordProvenance(filePath, original, url string) {
+	if u.Config.Provenance == "" {
+		return
+	}
+	u.provenanceMutex.Lock()
+	defer u.provenanceMutex.Unlock()
+	if u.provenance == nil {
+		u.provenance = make(map[string]map[string]string)
+	}
+	if u.provenance[filePath] == nil {
+		u.provenance[filePath] = make(map[string]string)
+	}
+	u.provenance[filePath][original] = url
+}
+
+// withProvenance adds the recorded URLs of a file to the ProvenanceKey field
+// of its rewritten content, keeping the entries of earlier runs. Content
+// whose front matter cannot be updated is returned as it is.
+func (u *Uploader) withProvenance(filePath string, content []byte) []byte {
+	if u.Config.Provenance != ProvenanceFrontMatter {
+		return content
+	}
+	u.provenanceMutex.Lock()
+	recorded := u.provenance[filePath]
+	u.provenanceMutex.Unlock()
+	if len(recorded) == 0 {
+		return content
+	}
+
+	doc := mddoc.Split(content)
+	assets := make(map[string]string)
+	if meta, err := doc.Meta(); err == nil && meta != nil && len(meta.Content) > 0 {
+		mapping := meta.Content[0]
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			if mapping.Content[i].Value == ProvenanceKey {
+				// An unexpected value is replaced
+				mapping.Content[i+1].Decode(&assets)
+			}
+		}
+	}
+	for original, url := range recorded {
+		assets[original] = url
+	}
+	updated, err := doc.SetMeta(ProvenanceKey, assets)
+	if err != nil {
+		logger.Warnf("Failed to record the original images of %s in its front matter: %v", filePath, err)
+		return content
+	}
+	return updated
+}
+
+// saveProvenance merges the recorded URLs into the ProvenanceFile of the
+// source directory
+func (u *Uploader) saveProvenance() error {
+	if u.Config.Provenance != ProvenanceSidecar || u.Config.DryRun || len(u.provenance) == 0 {
+		return nil
+	}
+	root := u.Config.SourceDir
+	if root == "" && u.Config.SourceFile != "" {
+		root = filepath.Dir(u.Config.SourceFile)
+	}
+	if root == "" {
+		root = "."
+	}
+	path := filepath.Join(root, ProvenanceFile)
+	p, err := LoadProvenance(path)
+	if err != nil {
+		return err
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	files := make([]string, 0, len(u.provenance))
+	for file := range u.provenance {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(abs)
+		if rel, err := filepath.Rel(absRoot, abs); err == nil {
+			key = filepath.ToSlash(rel)
+		}
+		if p.Files[key] == nil {
+			p.Files[key] = make(map[string]string)
+		}
+		for original, url := range u.provenance[file] {
+			p.Files[key][original] = url
+		}
+	}
+
+	p.Version = provenanceVersion
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	logger.Infof("Recorded the original images of %d files in %s", len(files), path)
+	return nil
+}
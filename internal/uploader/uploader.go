@@ -1,30 +1,44 @@
 package uploader
 
 import (
+	"context"
 	"crypto/md5"
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/gobwas/glob"
 	"github.com/samzong/mdctl/internal/cache"
 	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/index"
+	"github.com/samzong/mdctl/internal/logging"
+	"github.com/samzong/mdctl/internal/mddoc"
+	"github.com/samzong/mdctl/internal/metrics"
 	"github.com/samzong/mdctl/internal/storage"
 )
 
+// logger reports upload progress and problems
+var logger = logging.New("UPLOAD")
+
 // FileStats holds statistics about processed files
 type FileStats struct {
-	TotalFiles     int
-	ProcessedFiles int
-	UploadedImages int
-	SkippedImages  int
-	FailedImages   int
-	ChangedFiles   int
+	TotalFiles     int `json:"total_files"`
+	ProcessedFiles int `json:"processed_files"`
+	UploadedImages int `json:"uploaded_images"`
+	SkippedImages  int `json:"skipped_images"`
+	FailedImages   int `json:"failed_images"`
+	ChangedFiles   int `json:"changed_files"`
+	FilledAltTexts int `json:"filled_alt_texts,omitempty"`
 }
 
 // ConflictPolicy defines how to handle naming conflicts
@@ -39,29 +53,52 @@ const (
 	ConflictPolicyOverwrite ConflictPolicy = "overwrite"
 )
 
+// Sources of the alt text filled into rewritten images without one
+const (
+	AltTextFilename = "filename" // Words of the image file name
+	AltTextAI       = "ai"       // Caption of UploaderConfig.Caption, the file name when it fails
+)
+
 // UploaderConfig holds configuration for the uploader
 type UploaderConfig struct {
-	SourceFile     string
-	SourceDir      string
-	Provider       string
-	Bucket         string
-	CustomDomain   string
-	PathPrefix     string
-	DryRun         bool
-	Concurrency    int
-	ForceUpload    bool
-	SkipVerify     bool
-	CACertPath     string
-	ConflictPolicy ConflictPolicy
-	CacheDir       string
-	FileExtensions []string
+	SourceFile      string
+	SourceDir       string
+	Files           []string // Explicit file list, processed instead of SourceFile/SourceDir
+	Provider        string
+	Bucket          string
+	CustomDomain    string
+	PathPrefix      string
+	DryRun          bool
+	Concurrency     int
+	ScanConcurrency int // Number of markdown files scanned in parallel, the number of CPUs when 0
+	ForceUpload     bool
+	SkipVerify      bool
+	CACertPath      string
+	ConflictPolicy  ConflictPolicy
+	CacheDir        string
+	FileExtensions  []string            // Extensions of the markdown files to process, .md and .markdown when empty
+	Include         []string            // Globs of the markdown files to process, relative to SourceDir
+	Exclude         []string            // Globs of the markdown files to skip, relative to SourceDir
+	Storage         *config.CloudConfig // Storage settings, read from the config file when nil
+	AltText         string              // Fill empty alt text of rewritten images (AltTextFilename, AltTextAI), kept empty when ""
+	LinkStyle       string              // Style of the rewritten images (LinkStyleInline, LinkStyleHTML, LinkStyleReference), inline when ""
+	ImageWidth      string              // Width attribute of LinkStyleHTML images in pixels or percent, none when ""
+	AssetsKeys      []string            // Front matter fields referencing images, e.g. cover or cover.image
+	Provenance      string              // Where the original destinations of rewritten images are recorded (ProvenanceFrontMatter, ProvenanceSidecar), nowhere when ""
+	// Caption describes a local image for AltTextAI
+	Caption func(imagePath string) (string, error)
 }
 
 // Uploader handles uploading images and rewriting markdown
 type Uploader struct {
 	Config         UploaderConfig
 	provider       storage.Provider
+	pathPrefix     string       // Prefix of the object keys, used by garbage collection
+	target         cache.Target // Storage the images are uploaded to, part of the cache keys
+	include        []glob.Glob
+	exclude        []glob.Glob
 	stats          FileStats
+	statsMutex     sync.Mutex // Protects stats, updated by the scans and the result processor
 	cache          *cache.Cache
 	workerWg       sync.WaitGroup
 	taskChan       chan uploadTask
@@ -69,14 +106,20 @@ type Uploader struct {
 	errorChan      chan error
 	doneProcessing bool
 	pendingFiles   map[string][]pendingReplace // Map to track pending link updates for each file
+	pendingOrder   []string                    // Files of pendingFiles in scan order
 	fileMutex      sync.Mutex                  // Mutex to protect pendingFiles
+	queued         map[string][]string         // Local images queued for upload by content hash, each content is uploaded once
+	changed        map[string]bool             // Files written, counted once in the statistics
+	altTexts       map[string]string           // Alt texts filled in by local image path
+	altMutex       sync.Mutex                  // Protects altTexts
+
+	provenance      map[string]map[string]string // URLs of the rewritten image destinations by file
+	provenanceMutex sync.Mutex                   // Protects provenance
 }
 
 // Define a struct to track pending replacements
 type pendingReplace struct {
 	LocalPath  string
-	OldLink    string
-	ImgAlt     string
 	RemotePath string // Add remote path to match during result processing
 }
 
@@ -86,6 +129,7 @@ type uploadTask struct {
 	Filename    string
 	OriginalURL string
 	AltText     string
+	Hash        string // MD5 of the local file, computed when scanning
 }
 
 type uploadResult struct {
@@ -97,6 +141,24 @@ type uploadResult struct {
 
 // New creates a new uploader
 func New(uploaderConfig UploaderConfig) (*Uploader, error) {
+	include, err := fsutil.CompileGlobs(uploaderConfig.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := fsutil.CompileGlobs(uploaderConfig.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	if !ValidLinkStyle(uploaderConfig.LinkStyle) {
+		return nil, fmt.Errorf("unsupported link style: %s (must be inline, html or reference)", uploaderConfig.LinkStyle)
+	}
+	if !ValidProvenance(uploaderConfig.Provenance) {
+		return nil, fmt.Errorf("unsupported provenance: %s (must be frontmatter or sidecar)", uploaderConfig.Provenance)
+	}
+	if uploaderConfig.ImageWidth != "" && !ValidImageWidth(uploaderConfig.ImageWidth) {
+		return nil, fmt.Errorf("invalid image width: %s (must be pixels such as 600 or a percentage such as 80%%)", uploaderConfig.ImageWidth)
+	}
+
 	// Create cache
 	cacheManager := cache.New(uploaderConfig.CacheDir)
 	if err := cacheManager.Load(); err != nil {
@@ -113,31 +175,36 @@ func New(uploaderConfig UploaderConfig) (*Uploader, error) {
 		uploaderConfig.Concurrency = 5
 	}
 
-	// Get config from file
-	appConfig, err := config.LoadConfig()
-	if err != nil {
-		return nil, fmt.Errorf("failed to load config: %v", err)
-	}
-
 	// Get active cloud storage configuration
 	var activeConfig config.CloudConfig
 
-	// If Provider is specified in command line, user wants to use command line parameters
-	// Otherwise, get active storage configuration from config
-	if uploaderConfig.Provider == "" {
-		// Check if --storage parameter was specified in command line
-		for _, arg := range os.Args {
-			if arg == "--storage" || arg == "-s" {
-				// If --storage specified, it will be handled later in cmd/upload.go
-				break
-			}
+	if uploaderConfig.Storage != nil {
+		// Embedders pass the storage settings directly
+		activeConfig = *uploaderConfig.Storage
+	} else {
+		// Get config from file
+		appConfig, err := config.LoadConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config: %v", err)
 		}
 
-		// Get active cloud storage configuration
-		activeConfig = appConfig.GetActiveCloudConfig("")
-	} else {
-		// When using command line parameters, start with default config and then override
-		activeConfig = appConfig.GetActiveCloudConfig("")
+		// If Provider is specified in command line, user wants to use command line parameters
+		// Otherwise, get active storage configuration from config
+		if uploaderConfig.Provider == "" {
+			// Check if --storage parameter was specified in command line
+			for _, arg := range os.Args {
+				if arg == "--storage" || arg == "-s" {
+					// If --storage specified, it will be handled later in cmd/upload.go
+					break
+				}
+			}
+
+			// Get active cloud storage configuration
+			activeConfig = appConfig.GetActiveCloudConfig("")
+		} else {
+			// When using command line parameters, start with default config and then override
+			activeConfig = appConfig.GetActiveCloudConfig("")
+		}
 	}
 
 	// Override with command line arguments if provided
@@ -187,13 +254,18 @@ func New(uploaderConfig UploaderConfig) (*Uploader, error) {
 	return &Uploader{
 		Config:       uploaderConfig,
 		provider:     provider,
+		pathPrefix:   activeConfig.PathPrefix,
+		target:       cache.Target{Provider: providerName, Bucket: activeConfig.Bucket, PathPrefix: activeConfig.PathPrefix},
+		include:      include,
+		exclude:      exclude,
 		cache:        cacheManager,
 		pendingFiles: make(map[string][]pendingReplace), // Initialize pendingFiles
 	}, nil
 }
 
-// Process starts the upload process
-func (u *Uploader) Process() (*FileStats, error) {
+// Process starts the upload process. When ctx is cancelled no new uploads are
+// started, finished uploads are still written back and cached.
+func (u *Uploader) Process(ctx context.Context) (*FileStats, error) {
 	// Initialize channels for worker pool
 	u.taskChan = make(chan uploadTask, u.Config.Concurrency*2)
 	u.resultChan = make(chan uploadResult, u.Config.Concurrency*2)
@@ -202,7 +274,7 @@ func (u *Uploader) Process() (*FileStats, error) {
 	// Start worker pool
 	for i := 0; i < u.Config.Concurrency; i++ {
 		u.workerWg.Add(1)
-		go u.uploadWorker()
+		go u.uploadWorker(ctx)
 	}
 
 	// Start result processor
@@ -213,15 +285,30 @@ func (u *Uploader) Process() (*FileStats, error) {
 		u.processResults()
 	}()
 
-	// Process files
+	// Collect the files, then scan them in parallel
+	var files []string
 	var err error
-	if u.Config.SourceFile != "" {
-		err = u.processFile(u.Config.SourceFile)
+	if len(u.Config.Files) > 0 {
+		for _, file := range u.Config.Files {
+			if u.isMarkdown(file) && u.selected(file) {
+				files = append(files, file)
+			}
+		}
+		u.stats.TotalFiles = len(files)
+	} else if u.Config.SourceFile != "" {
+		files = []string{u.Config.SourceFile}
 	} else if u.Config.SourceDir != "" {
-		err = u.processDirectory(u.Config.SourceDir)
+		files, err = u.collectDirectory(ctx, u.Config.SourceDir)
 	} else {
 		err = errors.New("either source file or source directory must be specified")
 	}
+	if err == nil {
+		err = u.processFiles(ctx, files)
+	}
+
+	if err == nil {
+		err = ctx.Err()
+	}
 
 	// Signal that all files have been processed
 	u.doneProcessing = true
@@ -236,97 +323,203 @@ func (u *Uploader) Process() (*FileStats, error) {
 
 	// Save cache
 	if err := u.cache.Save(); err != nil {
-		fmt.Printf("Warning: Failed to save cache: %v\n", err)
+		logger.Warnf("Failed to save cache: %v", err)
+	}
+	if err := u.saveProvenance(); err != nil {
+		logger.Warnf("Failed to record the original images: %v", err)
 	}
 
 	return &u.stats, err
 }
 
-// processDirectory processes all markdown files in a directory
-func (u *Uploader) processDirectory(dir string) error {
-	fmt.Printf("Processing directory: %s\n", dir)
+// collectDirectory returns the markdown files of a directory in walk order
+func (u *Uploader) collectDirectory(ctx context.Context, dir string) ([]string, error) {
+	logger.Infof("Processing directory: %s", dir)
 	u.stats.TotalFiles = 0
 
-	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	// An index built with "mdctl index build" lets unchanged files without
+	// local images be skipped without reading them
+	idx, err := index.Find(dir)
+	if err != nil {
+		logger.Warnf("Ignoring index: %v", err)
+	}
+
+	var files []string
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && (strings.HasSuffix(path, ".md") || strings.HasSuffix(path, ".markdown")) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !info.IsDir() && u.isMarkdown(path) && u.selected(path) {
 			u.stats.TotalFiles++
-			return u.processFile(path)
+			// The index only knows the images of the body
+			if idx != nil && len(u.Config.AssetsKeys) == 0 {
+				if entry, ok := idx.Lookup(path); ok && !hasLocalImage(entry.Images) {
+					logger.Debugf("Skipping %s (no local images according to index)", path)
+					return nil
+				}
+			}
+			files = append(files, path)
 		}
 		return nil
 	})
+	return files, err
 }
 
-// processFile processes a single markdown file
-func (u *Uploader) processFile(filePath string) error {
-	fmt.Printf("Processing file: %s\n", filePath)
-	u.stats.ProcessedFiles++
+// isMarkdown reports whether a file has one of the markdown extensions
+func (u *Uploader) isMarkdown(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	if len(u.Config.FileExtensions) == 0 {
+		return ext == ".md" || ext == ".markdown"
+	}
+	for _, allowed := range u.Config.FileExtensions {
+		if ext == "."+strings.TrimPrefix(strings.ToLower(allowed), ".") {
+			return true
+		}
+	}
+	return false
+}
 
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to read file %s: %v", filePath, err)
+// selected reports whether a file matches the include globs (all files when
+// there are none) and none of the exclude globs
+func (u *Uploader) selected(path string) bool {
+	if len(u.include) == 0 && len(u.exclude) == 0 {
+		return true
+	}
+	rel := path
+	if u.Config.SourceDir != "" {
+		if r, err := filepath.Rel(u.Config.SourceDir, path); err == nil {
+			rel = r
+		}
 	}
+	rel = filepath.ToSlash(rel)
 
-	// Find all image links
-	imgRegex := regexp.MustCompile(`!\[([^\]]*)\]\(([^)]+)\)`)
-	matches := imgRegex.FindAllStringSubmatch(string(content), -1)
+	if len(u.include) > 0 && !fsutil.MatchAny(u.include, rel) {
+		return false
+	}
+	if fsutil.MatchAny(u.exclude, rel) {
+		logger.Debugf("Skipping %s (excluded)", path)
+		return false
+	}
+	return true
+}
 
-	if len(matches) == 0 {
-		fmt.Printf("No images found in file %s\n", filePath)
-		return nil
+// fileScan is the result of scanning one markdown file. Files are scanned
+// in parallel and the scans applied in file order, so logs, uploads and
+// statistics do not depend on which scan finishes first.
+type fileScan struct {
+	path     string
+	doc      *mddoc.Document
+	images   int
+	warnings []string
+	cached   []cachedImage // Images with a cached URL, replaced right away
+	tasks    []uploadTask  // Images to upload
+	err      error
+}
+
+// cachedImage is an image found in the upload cache
+type cachedImage struct {
+	LocalPath string
+	URL       string
+}
+
+// processFiles scans the files with a bounded pool of workers and applies
+// the scans in order. At most Config.ScanConcurrency files are scanned or
+// waiting to be applied at any time.
+func (u *Uploader) processFiles(ctx context.Context, files []string) error {
+	workers := u.Config.ScanConcurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
 	}
 
-	fmt.Printf("Found %d images in file %s\n", len(matches), filePath)
+	scans := make([]chan fileScan, len(files))
+	for i := range scans {
+		scans[i] = make(chan fileScan, 1)
+	}
+	slots := make(chan struct{}, workers)
+	stop := make(chan struct{})
+	defer close(stop)
 
-	// Track changes to the file
-	newContent := string(content)
-	var contentChanged bool
+	go func() {
+		for i, file := range files {
+			select {
+			case slots <- struct{}{}:
+			case <-stop:
+				return
+			}
+			go func(i int, file string) {
+				scans[i] <- u.scanFile(file)
+			}(i, file)
+		}
+	}()
 
-	for _, match := range matches {
-		imgAlt := match[1]
-		imgURL := match[2]
+	for i := range files {
+		var scan fileScan
+		select {
+		case scan = <-scans[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		<-slots
+		if err := u.applyScan(ctx, scan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanFile finds the local images of a markdown file and hashes them, which
+// is safe to run concurrently as it only reads the file and the cache
+func (u *Uploader) scanFile(filePath string) fileScan {
+	scan := fileScan{path: filePath}
+
+	content, err := os.ReadFile(fsutil.LongPath(filePath))
+	if err != nil {
+		scan.err = fmt.Errorf("failed to read file %s: %v", filePath, err)
+		return scan
+	}
 
+	// Find all inline images, images in code are not part of the document
+	scan.doc = mddoc.Parse(content)
+	var destinations []string
+	for _, img := range scan.doc.Images() {
+		destinations = append(destinations, img.Destination)
+	}
+	// Images of the front matter fields, such as the cover of a post
+	for _, asset := range scan.doc.MetaAssets(u.Config.AssetsKeys) {
+		destinations = append(destinations, asset.Value)
+	}
+	scan.images = len(destinations)
+
+	for _, dest := range destinations {
 		// Skip remote images
-		if strings.HasPrefix(imgURL, "http://") || strings.HasPrefix(imgURL, "https://") || strings.HasPrefix(imgURL, "//") {
+		if isRemote(dest) {
 			continue
 		}
 
-		// Get absolute path for local image
-		var imgPath string
-		if filepath.IsAbs(imgURL) {
-			imgPath = imgURL
-		} else {
-			// Resolve relative to the markdown file
-			imgPath = filepath.Join(filepath.Dir(filePath), imgURL)
-		}
+		// Get absolute path for local image, relative paths are resolved against the markdown file
+		imgPath := localImagePath(filePath, dest)
 
 		// Check if file exists
-		if _, err := os.Stat(imgPath); os.IsNotExist(err) {
-			fmt.Printf("Warning: Image does not exist: %s\n", imgPath)
+		if _, err := os.Stat(fsutil.LongPath(imgPath)); os.IsNotExist(err) {
+			scan.warnings = append(scan.warnings, fmt.Sprintf("Image does not exist: %s", imgPath))
 			continue
 		}
 
 		// Calculate hash for the file
 		hash, err := u.calculateFileHash(imgPath)
 		if err != nil {
-			fmt.Printf("Warning: Failed to calculate hash for %s: %v\n", imgPath, err)
+			scan.warnings = append(scan.warnings, fmt.Sprintf("Failed to calculate hash for %s: %v", imgPath, err))
 			continue
 		}
 
-		// Check if file is already in cache
+		// Content uploaded to the same storage before is not uploaded again,
+		// whatever its path
 		if !u.Config.ForceUpload {
-			if item, exists := u.cache.GetItem(imgPath); exists {
-				// Use cached URL
-				oldLink := fmt.Sprintf("![%s](%s)", imgAlt, imgURL)
-				newLink := fmt.Sprintf("![%s](%s)", imgAlt, item.URL)
-				if oldLink != newLink {
-					newContent = strings.Replace(newContent, oldLink, newLink, 1)
-					contentChanged = true
-				}
-				fmt.Printf("Using cached URL for image: %s → %s\n", imgPath, item.URL)
-				u.stats.SkippedImages++
+			if item, exists := u.cache.HasItemWithHash(u.target, hash); exists {
+				scan.cached = append(scan.cached, cachedImage{LocalPath: imgPath, URL: item.URL})
 				continue
 			}
 		}
@@ -340,50 +533,224 @@ func (u *Uploader) processFile(filePath string) error {
 		nameWithoutExt = cleanFileName(nameWithoutExt)
 		remotePath := fmt.Sprintf("%s_%s%s", nameWithoutExt, hash[:8], ext)
 
-		// Record link replacement information
-		oldLink := fmt.Sprintf("![%s](%s)", imgAlt, imgURL)
-		u.fileMutex.Lock()
-		u.pendingFiles[filePath] = append(u.pendingFiles[filePath], pendingReplace{
+		scan.tasks = append(scan.tasks, uploadTask{
 			LocalPath:  imgPath,
-			OldLink:    oldLink,
-			ImgAlt:     imgAlt,
 			RemotePath: remotePath,
+			Filename:   filename,
+			Hash:       hash,
 		})
+	}
+	return scan
+}
+
+// applyScan logs the scan of a file, queues its uploads and points its
+// cached images at their URLs
+func (u *Uploader) applyScan(ctx context.Context, scan fileScan) error {
+	logger.Infof("Processing file: %s", scan.path)
+	u.statsMutex.Lock()
+	u.stats.ProcessedFiles++
+	u.statsMutex.Unlock()
+	metrics.AddFiles(1)
+
+	if scan.err != nil {
+		return scan.err
+	}
+	if scan.images == 0 {
+		logger.Infof("No images found in file %s", scan.path)
+		return nil
+	}
+	logger.Infof("Found %d images in file %s", scan.images, scan.path)
+	for _, warning := range scan.warnings {
+		logger.Warnf("%s", warning)
+	}
+
+	cachedURLs := make(map[string]string)
+	for _, img := range scan.cached {
+		cachedURLs[img.LocalPath] = img.URL
+		logger.Infof("Using cached URL for image: %s → %s", img.LocalPath, img.URL)
+	}
+	u.statsMutex.Lock()
+	u.stats.SkippedImages += len(scan.cached)
+	u.statsMutex.Unlock()
+
+	// Record the link replacements of the file at once
+	if len(scan.tasks) > 0 {
+		replaces := make([]pendingReplace, 0, len(scan.tasks))
+		for _, task := range scan.tasks {
+			replaces = append(replaces, pendingReplace{LocalPath: task.LocalPath, RemotePath: task.RemotePath})
+		}
+		u.fileMutex.Lock()
+		if _, ok := u.pendingFiles[scan.path]; !ok {
+			u.pendingOrder = append(u.pendingOrder, scan.path)
+		}
+		u.pendingFiles[scan.path] = append(u.pendingFiles[scan.path], replaces...)
 		u.fileMutex.Unlock()
+	}
 
-		// Add to upload queue
-		u.taskChan <- uploadTask{
-			LocalPath:  imgPath,
-			RemotePath: remotePath,
-			Filename:   filename,
+	// Add to upload queue, images shared by several files and copies of an
+	// image at other paths are uploaded once and their URL is written into
+	// all of them
+	if u.queued == nil {
+		u.queued = make(map[string][]string)
+	}
+	for _, task := range scan.tasks {
+		paths, ok := u.queued[task.Hash]
+		if !slices.Contains(paths, task.LocalPath) {
+			u.queued[task.Hash] = append(paths, task.LocalPath)
+		}
+		if ok {
+			continue
+		}
+		select {
+		case u.taskChan <- task:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 
-	if contentChanged && !u.Config.DryRun {
-		if err := os.WriteFile(filePath, []byte(newContent), 0644); err != nil {
-			return fmt.Errorf("failed to write file %s: %v", filePath, err)
+	if len(cachedURLs) == 0 {
+		return nil
+	}
+	newContent, replaced := u.replaceImages(scan.path, scan.doc, cachedURLs)
+	if replaced > 0 && !u.Config.DryRun {
+		newContent = u.withProvenance(scan.path, newContent)
+		if err := fsutil.WriteFileAtomic(scan.path, newContent, 0644); err != nil {
+			return fmt.Errorf("failed to write file %s: %v", scan.path, err)
 		}
-		u.stats.ChangedFiles++
+		u.markChanged(scan.path)
 	}
 
 	return nil
 }
 
+// markChanged counts a written file in the statistics unless it was counted before
+func (u *Uploader) markChanged(filePath string) {
+	u.statsMutex.Lock()
+	defer u.statsMutex.Unlock()
+	if u.changed == nil {
+		u.changed = make(map[string]bool)
+	}
+	if !u.changed[filePath] {
+		u.changed[filePath] = true
+		u.stats.ChangedFiles++
+	}
+}
+
+// replaceImages points the images and front matter assets whose local file
+// has a URL at that URL and fills in the alt text of images when it is
+// empty. Other link styles than inline replace the whole image.
+func (u *Uploader) replaceImages(filePath string, doc *mddoc.Document, urls map[string]string) ([]byte, int) {
+	var edits []mddoc.Edit
+	replaced := 0
+	styler := newLinkStyler(u.Config.LinkStyle, u.Config.ImageWidth, doc)
+	for _, img := range doc.Images() {
+		if isRemote(img.Destination) {
+			continue
+		}
+		localPath := localImagePath(filePath, img.Destination)
+		url, ok := urls[localPath]
+		if !ok || url == img.Destination {
+			continue
+		}
+		logger.Infof("Updated link in %s: %s -> %s", filePath, img.Destination, url)
+		u.recordProvenance(filePath, img.Destination, url)
+		alt, filled := u.altText(localPath, img)
+		if styler != nil {
+			if !filled {
+				alt = img.Alt
+			}
+			// The image starts with the "![" before its alt text
+			edits = append(edits, mddoc.Edit{Start: img.AltStart - 2, End: img.After, Text: styler.image(url, alt, img.Title)})
+			replaced++
+			continue
+		}
+		if filled {
+			edits = append(edits, mddoc.Edit{Start: img.AltStart, End: img.AltEnd, Text: alt})
+		}
+		edits = append(edits, mddoc.Edit{Start: img.Start, End: img.End, Text: url})
+		replaced++
+	}
+	for _, asset := range doc.MetaAssets(u.Config.AssetsKeys) {
+		if isRemote(asset.Value) {
+			continue
+		}
+		url, ok := urls[localImagePath(filePath, asset.Value)]
+		if !ok || url == asset.Value {
+			continue
+		}
+		logger.Infof("Updated %s in %s: %s -> %s", asset.Key, filePath, asset.Value, url)
+		u.recordProvenance(filePath, asset.Value, url)
+		edits = append(edits, asset.Edit(url))
+		replaced++
+	}
+	return styler.finish(doc.Apply(edits)), replaced
+}
+
+// altText returns the alt text filled into an image without one, false when
+// the image keeps its alt text. Every image file is described once.
+func (u *Uploader) altText(localPath string, img mddoc.Image) (string, bool) {
+	if u.Config.AltText == "" || strings.TrimSpace(img.Alt) != "" {
+		return "", false
+	}
+
+	u.altMutex.Lock()
+	defer u.altMutex.Unlock()
+	alt, ok := u.altTexts[localPath]
+	if !ok {
+		// Dry runs do not spend AI requests
+		if u.Config.AltText == AltTextAI && u.Config.Caption != nil && !u.Config.DryRun {
+			caption, err := u.Config.Caption(localPath)
+			if err != nil {
+				logger.Warnf("Failed to describe %s, using its file name as alt text: %v", localPath, err)
+			}
+			alt = caption
+		}
+		if alt == "" {
+			alt = altFromFileName(localPath)
+		}
+		alt = escapeAlt(alt)
+		if u.altTexts == nil {
+			u.altTexts = make(map[string]string)
+		}
+		u.altTexts[localPath] = alt
+	}
+	if alt == "" {
+		return "", false
+	}
+
+	u.statsMutex.Lock()
+	u.stats.FilledAltTexts++
+	u.statsMutex.Unlock()
+	return alt, true
+}
+
+// altFromFileName turns the name of an image file into words, e.g.
+// "login-page_2x.png" into "login page 2x"
+func altFromFileName(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_' || r == '.' || r == '+' || r == ' '
+	}), " ")
+}
+
+// escapeAlt makes text safe inside the brackets of an image
+func escapeAlt(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`).Replace(text)
+}
+
 // uploadWorker processes upload tasks
-func (u *Uploader) uploadWorker() {
+func (u *Uploader) uploadWorker(ctx context.Context) {
 	defer u.workerWg.Done()
 
 	for task := range u.taskChan {
-		// Calculate hash for file
-		hash, err := u.calculateFileHash(task.LocalPath)
-		if err != nil {
-			u.resultChan <- uploadResult{
-				Task: task,
-				Err:  fmt.Errorf("failed to calculate hash: %v", err),
-			}
+		// Drain queued tasks without uploading once interrupted
+		if ctx.Err() != nil {
 			continue
 		}
 
+		hash := task.Hash
+
 		// Skip upload in dry run mode
 		if u.Config.DryRun {
 			u.resultChan <- uploadResult{
@@ -460,6 +827,9 @@ func (u *Uploader) uploadWorker() {
 			}
 			continue
 		}
+		if info, err := os.Stat(task.LocalPath); err == nil {
+			metrics.AddBytesUploaded(info.Size())
+		}
 
 		u.resultChan <- uploadResult{
 			Task:     task,
@@ -475,8 +845,10 @@ func (u *Uploader) processResults() {
 
 	for result := range u.resultChan {
 		if result.Err != nil {
-			fmt.Printf("Error uploading %s: %v\n", result.Task.LocalPath, result.Err)
+			logger.Errorf("Failed to upload %s: %v", result.Task.LocalPath, result.Err)
+			u.statsMutex.Lock()
 			u.stats.FailedImages++
+			u.statsMutex.Unlock()
 			continue
 		}
 
@@ -484,15 +856,27 @@ func (u *Uploader) processResults() {
 		uploadedURLs[result.Task.LocalPath] = result.URL
 
 		if result.Uploaded {
-			fmt.Printf("Uploaded image: %s → %s\n", result.Task.LocalPath, result.URL)
+			logger.Infof("Uploaded image: %s → %s", result.Task.LocalPath, result.URL)
+			u.statsMutex.Lock()
 			u.stats.UploadedImages++
+			u.statsMutex.Unlock()
 
 			// Add to cache
-			hash, _ := u.calculateFileHash(result.Task.LocalPath)
-			u.cache.AddItem(result.Task.LocalPath, result.Task.RemotePath, result.URL, hash)
+			u.cache.AddItem(u.target, result.Task.LocalPath, result.Task.RemotePath, result.URL, result.Task.Hash)
 		} else {
-			fmt.Printf("Skipped upload (already exists): %s → %s\n", result.Task.LocalPath, result.URL)
+			logger.Infof("Skipped upload (already exists): %s → %s", result.Task.LocalPath, result.URL)
+			u.statsMutex.Lock()
 			u.stats.SkippedImages++
+			u.statsMutex.Unlock()
+		}
+	}
+
+	// Copies of an uploaded image at other paths share its URL
+	for _, paths := range u.queued {
+		if url, ok := uploadedURLs[paths[0]]; ok {
+			for _, path := range paths[1:] {
+				uploadedURLs[path] = url
+			}
 		}
 	}
 
@@ -500,40 +884,31 @@ func (u *Uploader) processResults() {
 	u.fileMutex.Lock()
 	defer u.fileMutex.Unlock()
 
-	for filePath, replaces := range u.pendingFiles {
+	for _, filePath := range u.pendingOrder {
+		replaces := u.pendingFiles[filePath]
 		if len(replaces) == 0 {
 			continue
 		}
 
 		// Read file content
-		content, err := os.ReadFile(filePath)
+		content, err := os.ReadFile(fsutil.LongPath(filePath))
 		if err != nil {
-			fmt.Printf("Error reading file %s for update: %v", filePath, err)
+			logger.Errorf("Failed to read file %s for update: %v", filePath, err)
 			continue
 		}
 
-		// Apply all replacements
-		newContent := string(content)
-		contentChanged := false
-
-		for _, replace := range replaces {
-			if newURL, exists := uploadedURLs[replace.LocalPath]; exists {
-				newLink := fmt.Sprintf("![%s](%s)", replace.ImgAlt, newURL)
-				oldNewContent := newContent
-				newContent = strings.Replace(newContent, replace.OldLink, newLink, 1)
-				if oldNewContent != newContent {
-					contentChanged = true
-					fmt.Printf("Updated link in %s: %s -> %s\n", filePath, replace.OldLink, newLink)
-				}
-			}
-		}
+		// Apply all replacements, the file is scanned again as cached
+		// images may have been replaced in the meantime
+		newContent, replaced := u.replaceImages(filePath, mddoc.Parse(content), uploadedURLs)
+		contentChanged := replaced > 0
 
 		// Save updated file
 		if contentChanged && !u.Config.DryRun {
-			if err := os.WriteFile(filePath, []byte(newContent), 0644); err != nil {
-				fmt.Printf("Error writing updated file: %v\n", err)
+			newContent = u.withProvenance(filePath, newContent)
+			if err := fsutil.WriteFileAtomic(filePath, newContent, 0644); err != nil {
+				logger.Errorf("Failed to write updated file: %v", err)
 			} else {
-				u.stats.ChangedFiles++
+				u.markChanged(filePath)
 			}
 		}
 	}
@@ -541,7 +916,7 @@ func (u *Uploader) processResults() {
 
 // calculateFileHash computes MD5 hash of a file
 func (u *Uploader) calculateFileHash(filePath string) (string, error) {
-	file, err := os.Open(filePath)
+	file, err := os.Open(fsutil.LongPath(filePath))
 	if err != nil {
 		return "", err
 	}
@@ -555,6 +930,16 @@ func (u *Uploader) calculateFileHash(filePath string) (string, error) {
 	return fmt.Sprintf("%x", hash.Sum(nil)), nil
 }
 
+// hasLocalImage reports whether any of the image references points to a local file
+func hasLocalImage(images []string) bool {
+	for _, img := range images {
+		if !strings.HasPrefix(img, "http://") && !strings.HasPrefix(img, "https://") && !strings.HasPrefix(img, "//") {
+			return true
+		}
+	}
+	return false
+}
+
 // cleanFileName removes special characters from filename
 func cleanFileName(name string) string {
 	// Replace spaces and special characters with underscores
@@ -576,3 +961,31 @@ func cleanFileName(name string) string {
 
 	return name
 }
+
+// isRemote reports whether an image destination is not a local file
+func isRemote(dest string) bool {
+	return strings.HasPrefix(dest, "http://") || strings.HasPrefix(dest, "https://") ||
+		strings.HasPrefix(dest, "//") || strings.HasPrefix(dest, "data:")
+}
+
+// localImagePath resolves an image destination against the markdown file,
+// trying the percent-decoded form when the destination is not a file
+func localImagePath(markdownFile, dest string) string {
+	dest = mddoc.Unescape(dest)
+	resolve := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(filepath.Dir(markdownFile), p)
+	}
+
+	path := resolve(dest)
+	if _, err := os.Stat(path); err != nil {
+		if decoded, err := url.PathUnescape(dest); err == nil && decoded != dest {
+			if _, err := os.Stat(resolve(decoded)); err == nil {
+				return resolve(decoded)
+			}
+		}
+	}
+	return path
+}
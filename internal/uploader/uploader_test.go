@@ -0,0 +1,322 @@
+package uploader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/samzong/mdctl/internal/cache"
+	"github.com/samzong/mdctl/internal/config"
+	"github.com/samzong/mdctl/internal/fsutil"
+	"github.com/samzong/mdctl/internal/mddoc"
+)
+
+func TestSelectedFiles(t *testing.T) {
+	include, _ := fsutil.CompileGlobs([]string{"content/posts/**"})
+	exclude, _ := fsutil.CompileGlobs([]string{"**/archive/**"})
+	u := &Uploader{
+		Config:  UploaderConfig{SourceDir: "site", FileExtensions: []string{"md", ".MDX"}},
+		include: include,
+		exclude: exclude,
+	}
+
+	tests := map[string]bool{
+		"site/content/posts/a.md":              true,
+		"site/content/posts/2020/b.mdx":        true,
+		"site/content/posts/2019/archive/c.md": false,
+		"site/content/pages/d.md":              false,
+		"site/content/posts/e.markdown":        false,
+	}
+	for file, want := range tests {
+		if got := u.isMarkdown(file) && u.selected(file); got != want {
+			t.Errorf("%s: expected %v, got %v", file, want, got)
+		}
+	}
+
+	if _, err := fsutil.CompileGlobs([]string{"[a-"}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+// memoryProvider stores uploads in memory
+type memoryProvider struct {
+	mu      sync.Mutex
+	objects map[string]string
+}
+
+func (p *memoryProvider) Upload(localPath, remotePath string, metadata map[string]string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.objects[remotePath] = metadata["Hash"]
+	return p.GetPublicURL(remotePath), nil
+}
+
+func (p *memoryProvider) Configure(cfg config.CloudConfig) error { return nil }
+func (p *memoryProvider) GetPublicURL(remotePath string) string {
+	return "https://cdn.example.com/" + remotePath
+}
+func (p *memoryProvider) ObjectExists(remotePath string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.objects[remotePath]
+	return ok, nil
+}
+func (p *memoryProvider) CompareHash(remotePath, localHash string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.objects[remotePath] == localHash, nil
+}
+func (p *memoryProvider) SetObjectMetadata(remotePath string, metadata map[string]string) error {
+	return nil
+}
+func (p *memoryProvider) GetObjectMetadata(remotePath string) (map[string]string, error) {
+	return nil, nil
+}
+
+func TestProcessScansFilesInParallel(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "shared.png"), []byte("shared"), 0644)
+	for i := 0; i < 20; i++ {
+		image := fmt.Sprintf("img%d.png", i)
+		os.WriteFile(filepath.Join(dir, image), []byte(image), 0644)
+		content := fmt.Sprintf("# Page %d\n\n![a](%s)\n\n![b](shared.png)\n", i, image)
+		os.WriteFile(filepath.Join(dir, fmt.Sprintf("page%02d.md", i)), []byte(content), 0644)
+	}
+
+	u := &Uploader{
+		Config:       UploaderConfig{SourceDir: dir, Concurrency: 3, ScanConcurrency: 4, ConflictPolicy: ConflictPolicyRename},
+		provider:     &memoryProvider{objects: map[string]string{}},
+		cache:        cache.New(t.TempDir()),
+		pendingFiles: make(map[string][]pendingReplace),
+	}
+	stats, err := u.Process(context.Background())
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if stats.TotalFiles != 20 || stats.ProcessedFiles != 20 || stats.ChangedFiles != 20 || stats.UploadedImages != 21 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	for i := 0; i < 20; i++ {
+		content, _ := os.ReadFile(filepath.Join(dir, fmt.Sprintf("page%02d.md", i)))
+		if !strings.Contains(string(content), fmt.Sprintf("](https://cdn.example.com/img%d_", i)) ||
+			!strings.Contains(string(content), "](https://cdn.example.com/shared_") {
+			t.Errorf("page %d was not rewritten: %s", i, content)
+		}
+	}
+}
+
+func TestProcessDedupesByContent(t *testing.T) {
+	cacheDir := t.TempDir()
+	provider := &memoryProvider{objects: map[string]string{}}
+	process := func(dir string, target cache.Target) *FileStats {
+		u := &Uploader{
+			Config:       UploaderConfig{SourceDir: dir, Concurrency: 2, ConflictPolicy: ConflictPolicyRename},
+			provider:     provider,
+			target:       target,
+			cache:        cache.New(cacheDir),
+			pendingFiles: make(map[string][]pendingReplace),
+		}
+		if err := u.cache.Load(); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		stats, err := u.Process(context.Background())
+		if err != nil {
+			t.Fatalf("Process failed: %v", err)
+		}
+		return stats
+	}
+	write := func(dir string) {
+		os.MkdirAll(filepath.Join(dir, "a"), 0755)
+		os.MkdirAll(filepath.Join(dir, "b"), 0755)
+		os.WriteFile(filepath.Join(dir, "a", "logo.png"), []byte("logo"), 0644)
+		os.WriteFile(filepath.Join(dir, "b", "logo.png"), []byte("logo"), 0644)
+		os.WriteFile(filepath.Join(dir, "a", "page.md"), []byte("![](logo.png)\n"), 0644)
+		os.WriteFile(filepath.Join(dir, "b", "page.md"), []byte("![](logo.png)\n"), 0644)
+	}
+
+	// Copies of an image are uploaded once
+	docs := cache.Target{Provider: "s3", Bucket: "docs"}
+	first := t.TempDir()
+	write(first)
+	if stats := process(first, docs); stats.UploadedImages != 1 || stats.ChangedFiles != 2 {
+		t.Errorf("expected one upload for both copies, got %+v", stats)
+	}
+	for _, page := range []string{"a", "b"} {
+		content, _ := os.ReadFile(filepath.Join(first, page, "page.md"))
+		if !strings.HasPrefix(string(content), "![](https://cdn.example.com/logo_") {
+			t.Errorf("page %s was not rewritten: %s", page, content)
+		}
+	}
+
+	// Another checkout of the same images uses the cache
+	second := t.TempDir()
+	write(second)
+	if stats := process(second, docs); stats.UploadedImages != 0 || stats.SkippedImages != 2 || stats.ChangedFiles != 2 || len(provider.objects) != 1 {
+		t.Errorf("expected the cached URL for the moved images, got %+v, %d objects", stats, len(provider.objects))
+	}
+
+	// Another bucket does not have them yet
+	provider = &memoryProvider{objects: map[string]string{}}
+	third := t.TempDir()
+	write(third)
+	if stats := process(third, cache.Target{Provider: "s3", Bucket: "blog"}); stats.UploadedImages != 1 || stats.SkippedImages != 0 || len(provider.objects) != 1 {
+		t.Errorf("expected the images to be uploaded to the second bucket, got %+v", stats)
+	}
+}
+
+func TestProcessRecordsProvenance(t *testing.T) {
+	cacheDir := t.TempDir()
+	provider := &memoryProvider{objects: map[string]string{}}
+	process := func(dir, provenance string) {
+		u := &Uploader{
+			Config:       UploaderConfig{SourceDir: dir, Concurrency: 2, ConflictPolicy: ConflictPolicyRename, AssetsKeys: []string{"cover"}, Provenance: provenance},
+			provider:     provider,
+			cache:        cache.New(cacheDir),
+			pendingFiles: make(map[string][]pendingReplace),
+		}
+		if err := u.cache.Load(); err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if _, err := u.Process(context.Background()); err != nil {
+			t.Fatalf("Process failed: %v", err)
+		}
+	}
+	write := func(dir string) {
+		os.MkdirAll(filepath.Join(dir, "guide", "img"), 0755)
+		os.WriteFile(filepath.Join(dir, "guide", "img", "a.png"), []byte("a"), 0644)
+		os.WriteFile(filepath.Join(dir, "guide", "img", "cover.png"), []byte("cover"), 0644)
+		os.WriteFile(filepath.Join(dir, "guide", "page.md"), []byte("---\ntitle: Page\ncover: img/cover.png\nmdctl_assets:\n  old.png: https://cdn.example.com/old.png\n---\n\n![A](img/a.png)\n"), 0644)
+	}
+
+	// The front matter keeps the entries of earlier runs
+	dir := t.TempDir()
+	write(dir)
+	process(dir, ProvenanceFrontMatter)
+	content, _ := os.ReadFile(filepath.Join(dir, "guide", "page.md"))
+	doc := mddoc.Split(content)
+	meta, err := doc.Meta()
+	if err != nil {
+		t.Fatalf("invalid front matter: %v\n%s", err, content)
+	}
+	var fields struct {
+		Cover  string            `yaml:"cover"`
+		Assets map[string]string `yaml:"mdctl_assets"`
+	}
+	meta.Decode(&fields)
+	if len(fields.Assets) != 3 || fields.Assets["old.png"] != "https://cdn.example.com/old.png" ||
+		fields.Assets["img/a.png"] == "" || fields.Assets["img/cover.png"] != fields.Cover || !strings.Contains(string(doc.Body()), fields.Assets["img/a.png"]) {
+		t.Errorf("unexpected provenance in front matter:\n%s", content)
+	}
+
+	// Cached images are recorded in the sidecar as well
+	dir = t.TempDir()
+	write(dir)
+	process(dir, ProvenanceSidecar)
+	p, err := LoadProvenance(filepath.Join(dir, ProvenanceFile))
+	if err != nil {
+		t.Fatalf("LoadProvenance failed: %v", err)
+	}
+	if assets := p.Files["guide/page.md"]; len(p.Files) != 1 || len(assets) != 2 || !strings.HasPrefix(assets["img/a.png"], "https://cdn.example.com/a_") {
+		t.Errorf("unexpected sidecar %+v", p)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "guide", "page.md")); strings.Contains(string(content), "img/a.png") {
+		t.Errorf("expected the sidecar to leave the front matter alone:\n%s", content)
+	}
+}
+
+func TestProcessFillsAltText(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "login-page_2x.png"), []byte("login"), 0644)
+	os.WriteFile(filepath.Join(dir, "chart.png"), []byte("chart"), 0644)
+	content := "![](login-page_2x.png)\n\n![Kept](chart.png)\n\n![ ](chart.png)\n"
+	os.WriteFile(filepath.Join(dir, "page.md"), []byte(content), 0644)
+
+	var captions int
+	for _, tt := range []struct {
+		mode, want string
+	}{
+		{AltTextFilename, "![login page 2x](https://cdn.example.com/login-page_2x_"},
+		{AltTextAI, "![Bar chart of \\[weekly\\] sales](https://cdn.example.com/chart_"},
+	} {
+		os.WriteFile(filepath.Join(dir, "page.md"), []byte(content), 0644)
+		u := &Uploader{
+			Config: UploaderConfig{
+				SourceFile: filepath.Join(dir, "page.md"), Concurrency: 2, ConflictPolicy: ConflictPolicyRename, ForceUpload: true,
+				AltText: tt.mode,
+				Caption: func(path string) (string, error) {
+					captions++
+					if filepath.Base(path) == "chart.png" {
+						return "Bar chart of [weekly] sales", nil
+					}
+					return "", fmt.Errorf("model does not accept images")
+				},
+			},
+			provider:     &memoryProvider{objects: map[string]string{}},
+			cache:        cache.New(t.TempDir()),
+			pendingFiles: make(map[string][]pendingReplace),
+		}
+		stats, err := u.Process(context.Background())
+		if err != nil {
+			t.Fatalf("Process failed: %v", err)
+		}
+		got, _ := os.ReadFile(filepath.Join(dir, "page.md"))
+		if !strings.Contains(string(got), tt.want) || !strings.Contains(string(got), "![Kept](https://cdn.example.com/chart_") {
+			t.Errorf("%s: unexpected content:\n%s", tt.mode, got)
+		}
+		// The AI falls back to the file name
+		if tt.mode == AltTextAI && !strings.Contains(string(got), "![login page 2x](") {
+			t.Errorf("expected the file name when captioning fails:\n%s", got)
+		}
+		if stats.FilledAltTexts != 2 {
+			t.Errorf("%s: expected 2 filled alt texts, got %d", tt.mode, stats.FilledAltTexts)
+		}
+	}
+	if captions != 2 {
+		t.Errorf("expected every image to be described once, got %d calls", captions)
+	}
+}
+
+func TestReplaceImagesLinkStyles(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "post.md")
+	content := "# Post\n\n![Login *page*](login.png \"Sign in\")\n\nText ![](chart.png) and ![again](login.png).\n\n[image-1]: https://example.com/other.png\n"
+	urls := map[string]string{
+		filepath.Join(dir, "login.png"): "https://cdn.example.com/login.png",
+		filepath.Join(dir, "chart.png"): "https://cdn.example.com/chart.png?a=1&b=2",
+	}
+
+	tests := []struct {
+		style, width, want string
+	}{
+		{LinkStyleInline, "", "# Post\n\n![Login *page*](https://cdn.example.com/login.png \"Sign in\")\n\n" +
+			"Text ![](https://cdn.example.com/chart.png?a=1&b=2) and ![again](https://cdn.example.com/login.png).\n\n[image-1]: https://example.com/other.png\n"},
+		{LinkStyleHTML, "600", "# Post\n\n<img src=\"https://cdn.example.com/login.png\" alt=\"Login *page*\" title=\"Sign in\" width=\"600\">\n\n" +
+			"Text <img src=\"https://cdn.example.com/chart.png?a=1&amp;b=2\" alt=\"\" width=\"600\"> and <img src=\"https://cdn.example.com/login.png\" alt=\"again\" width=\"600\">.\n\n" +
+			"[image-1]: https://example.com/other.png\n"},
+		{LinkStyleReference, "", "# Post\n\n![Login *page*][image-2]\n\nText ![][image-3] and ![again][image-2].\n\n[image-1]: https://example.com/other.png\n\n" +
+			"[image-2]: https://cdn.example.com/login.png \"Sign in\"\n[image-3]: https://cdn.example.com/chart.png?a=1&b=2\n"},
+	}
+	for _, tt := range tests {
+		u := &Uploader{Config: UploaderConfig{LinkStyle: tt.style, ImageWidth: tt.width}}
+		got, replaced := u.replaceImages(file, mddoc.Parse([]byte(content)), urls)
+		if string(got) != tt.want || replaced != 3 {
+			t.Errorf("%s: replaced %d\n%s\nwant\n%s", tt.style, replaced, got, tt.want)
+		}
+	}
+
+	// Front matter assets are rewritten in every link style
+	u := &Uploader{Config: UploaderConfig{LinkStyle: LinkStyleHTML, AssetsKeys: []string{"cover"}}}
+	got, replaced := u.replaceImages(file, mddoc.Parse([]byte("---\ncover: chart.png\n---\n"+content)), urls)
+	if replaced != 4 || !strings.HasPrefix(string(got), "---\ncover: https://cdn.example.com/chart.png?a=1&b=2\n---\n") {
+		t.Errorf("expected the cover rewritten, replaced %d:\n%s", replaced, got)
+	}
+
+	if ValidLinkStyle("bbcode") || !ValidImageWidth("80%") || ValidImageWidth("6in") {
+		t.Error("unexpected link style or width validation")
+	}
+}
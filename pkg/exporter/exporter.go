@@ -0,0 +1,179 @@
+// Package exporter converts markdown files and directories to DOCX, PDF or
+// EPUB using Pandoc, which must be installed and on the PATH.
+//
+//	err := exporter.ExportDirectory(ctx, "docs", "docs.pdf", exporter.Options{
+//		Format:      "pdf",
+//		GenerateToc: true,
+//	})
+package exporter
+
+import (
+	"context"
+
+	iexporter "github.com/samzong/mdctl/internal/exporter"
+)
+
+// Plan describes what a dry-run export would do
+type Plan = iexporter.ExportPlan
+
+// BuildStamp is the generation information of Options.Stamp
+type BuildStamp = iexporter.BuildStamp
+
+// Options controls the conversion
+type Options struct {
+	// Format is the output format: docx (default), pdf or epub
+	Format string
+	// Template is a Word reference document used for styling
+	Template string
+	// Theme is a template added with mdctl export templates or a built-in
+	// theme (corporate, academic, minimal)
+	Theme string
+	// GenerateToc adds a table of contents of TocDepth levels (default 3)
+	GenerateToc bool
+	TocDepth    int
+	// ShiftHeadingLevelBy shifts all heading levels
+	ShiftHeadingLevelBy int
+	// FileAsTitle inserts each file name as a section title when merging
+	FileAsTitle bool
+	// SiteType reads the file order of a directory from a site configuration
+	// (basic, mkdocs), basic sorts files by name
+	SiteType string
+	// NavPath limits a site export to a navigation section, e.g. "Guide/Install",
+	// NavPaths to several ones, levels may use wildcards such as "API/*"
+	NavPath  string
+	NavPaths []string
+	// Dedupe handles files merged more than once: link-to-first (default)
+	// links to the first copy, skip leaves repeats out, duplicate keeps them
+	Dedupe string
+	// DryRun only fills Plan, nothing is written and Pandoc is not run
+	DryRun bool
+	Plan   *Plan
+	// Title, Authors, Language and Identifier set the document metadata, an
+	// EPUB gets a random UUID identifier when none is given
+	Title      string
+	Authors    []string
+	Language   string
+	Identifier string
+	// CoverImage and EmbedFonts are added to EPUB output
+	CoverImage string
+	EmbedFonts []string
+	// MaxImageWidth downscales local images wider than a length such as 6in
+	// or 15cm and limits their display width, ImageDPI sets the resolution of
+	// images without resolution information (Pandoc's default 96 when 0)
+	MaxImageWidth string
+	ImageDPI      int
+	// LuaFilters are Pandoc Lua filters and PandocArgs extra Pandoc
+	// arguments, both added after the arguments mdctl sets
+	LuaFilters []string
+	PandocArgs []string
+	// HeaderText, FooterText, PageNumbers and Watermark decorate the pages
+	// of PDF and DOCX output
+	HeaderText  string
+	FooterText  string
+	PageNumbers bool
+	Watermark   string
+	// Stamp adds the mdctl version, docs commit and generation date to the
+	// footer of PDF and DOCX output and to the document properties
+	Stamp *BuildStamp
+	// LinkBaseURL is the URL the site is published at, relative links
+	// between its pages become absolute links below it
+	LinkBaseURL string
+	// NumberHeadings numbers the headings (1., 1.1, 1.2.3) and gives them
+	// sec-1-2-3 anchors before Pandoc runs
+	NumberHeadings bool
+	// Glossary is a YAML glossary file, the terms the content uses are
+	// appended as a glossary section; ExpandAcronyms spells out the terms
+	// with an expansion on first use
+	Glossary       string
+	ExpandAcronyms bool
+	// InputFormat is the Pandoc reader of the sources with optional
+	// extension toggles, e.g. gfm+smart or markdown (default gfm, with
+	// footnotes and heading attributes), Extensions are toggles such as
+	// +smart or -footnotes applied after it
+	InputFormat string
+	Extensions  []string
+}
+
+// internal converts the options to the internal representation
+func (o Options) internal() iexporter.ExportOptions {
+	if o.Format == "" {
+		o.Format = "docx"
+	}
+	if o.TocDepth <= 0 {
+		o.TocDepth = 3
+	}
+	return iexporter.ExportOptions{
+		Template:            o.Template,
+		Theme:               o.Theme,
+		GenerateToc:         o.GenerateToc,
+		ShiftHeadingLevelBy: o.ShiftHeadingLevelBy,
+		FileAsTitle:         o.FileAsTitle,
+		Format:              o.Format,
+		SiteType:            o.SiteType,
+		TocDepth:            o.TocDepth,
+		NavPaths:            o.navPaths(),
+		Dedupe:              o.Dedupe,
+		DryRun:              o.DryRun,
+		Plan:                o.Plan,
+		Title:               o.Title,
+		Authors:             o.Authors,
+		Language:            o.Language,
+		Identifier:          o.Identifier,
+		CoverImage:          o.CoverImage,
+		EmbedFonts:          o.EmbedFonts,
+		MaxImageWidth:       o.MaxImageWidth,
+		ImageDPI:            o.ImageDPI,
+		LuaFilters:          o.LuaFilters,
+		PandocArgs:          o.PandocArgs,
+		HeaderText:          o.HeaderText,
+		FooterText:          o.FooterText,
+		PageNumbers:         o.PageNumbers,
+		Watermark:           o.Watermark,
+		Stamp:               o.Stamp,
+		LinkBaseURL:         o.LinkBaseURL,
+		NumberHeadings:      o.NumberHeadings,
+		Glossary:            o.Glossary,
+		ExpandAcronyms:      o.ExpandAcronyms,
+		InputFormat:         o.InputFormat,
+		Extensions:          o.Extensions,
+	}
+}
+
+// navPaths returns NavPath followed by NavPaths
+func (o Options) navPaths() []string {
+	if o.NavPath == "" {
+		return o.NavPaths
+	}
+	return append([]string{o.NavPath}, o.NavPaths...)
+}
+
+// CheckPandoc reports an error with installation hints if Pandoc is missing
+func CheckPandoc() error {
+	return iexporter.CheckPandocAvailability()
+}
+
+// ExportFile converts a single markdown file. The output is replaced only when
+// the conversion succeeds, cancelling ctx stops Pandoc.
+func ExportFile(ctx context.Context, input, output string, opts Options) error {
+	return iexporter.NewExporter().ExportFile(ctx, input, output, opts.internal())
+}
+
+// ExportFiles merges markdown files in the given order and converts them into
+// a single document
+func ExportFiles(ctx context.Context, files []string, output string, opts Options) error {
+	return iexporter.NewExporter().ExportFiles(ctx, files, output, opts.internal())
+}
+
+// ExportDirectory merges the markdown files of a directory and converts them
+// into a single document
+func ExportDirectory(ctx context.Context, inputDir, output string, opts Options) error {
+	return iexporter.NewExporter().ExportDirectory(ctx, inputDir, output, opts.internal())
+}
+
+// ExportDirectoryToDir converts the markdown files of a directory into
+// separate documents in outputDir, one per top-level navigation entry when
+// splitBy is "nav" or one per source file when it is "file". It returns the
+// written documents.
+func ExportDirectoryToDir(ctx context.Context, inputDir, outputDir, splitBy string, opts Options) ([]string, error) {
+	return iexporter.NewExporter().ExportDirectoryToDir(ctx, inputDir, outputDir, splitBy, opts.internal())
+}
@@ -0,0 +1,10 @@
+// Package formatter normalizes markdown: heading spacing, link syntax,
+// spacing between CJK and latin text and consecutive blank lines.
+package formatter
+
+import "github.com/samzong/mdctl/internal/markdownfmt"
+
+// Format returns the formatted markdown content
+func Format(content string) string {
+	return markdownfmt.New(true).Format(content)
+}
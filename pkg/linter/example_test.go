@@ -0,0 +1,19 @@
+package linter_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samzong/mdctl/pkg/linter"
+)
+
+func ExampleFix() {
+	_, fixed, err := linter.Fix(context.Background(), "doc.md", "#Title\n", linter.Options{
+		EnableRules: []string{"MD018"},
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(fixed)
+	// Output: # Title
+}
@@ -0,0 +1,67 @@
+// Package linter checks markdown against markdownlint rules (MD001-MD047) and
+// fixes the issues that can be fixed automatically.
+//
+//	result, err := linter.Lint(ctx, "README.md", content, linter.Options{})
+//	for _, issue := range result.Issues {
+//		fmt.Printf("%d: %s (%s)\n", issue.Line, issue.Message, issue.Rule)
+//	}
+package linter
+
+import (
+	"context"
+	"os"
+
+	ilinter "github.com/samzong/mdctl/internal/linter"
+)
+
+// Issue is a single rule violation
+type Issue = ilinter.Issue
+
+// Result holds the issues found in a file
+type Result = ilinter.Result
+
+// Options controls which rules run
+type Options struct {
+	// RulesFile is a markdownlint configuration file (.markdownlint.json/.yaml).
+	// When empty, a configuration file in the current directory is used if present.
+	RulesFile string
+	// EnableRules runs only these rules, e.g. "MD001"
+	EnableRules []string
+	// DisableRules skips these rules
+	DisableRules []string
+}
+
+// newLinter creates an internal linter that never writes files
+func newLinter(opts Options) *ilinter.Linter {
+	return ilinter.New(&ilinter.Config{
+		RulesFile:    opts.RulesFile,
+		EnableRules:  opts.EnableRules,
+		DisableRules: opts.DisableRules,
+	})
+}
+
+// Lint checks markdown content, filename is only used in the result
+func Lint(ctx context.Context, filename, content string, opts Options) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return newLinter(opts).LintContent(filename, content)
+}
+
+// LintFile reads and checks a markdown file
+func LintFile(ctx context.Context, filename string, opts Options) (*Result, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Lint(ctx, filename, string(content), opts)
+}
+
+// Fix checks markdown content and returns it with all fixable issues fixed
+func Fix(ctx context.Context, filename, content string, opts Options) (*Result, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	result, fixed := newLinter(opts).FixContent(filename, content)
+	return result, fixed, nil
+}
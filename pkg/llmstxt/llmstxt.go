@@ -0,0 +1,81 @@
+// Package llmstxt generates llms.txt documents from a website's sitemap.xml.
+//
+//	content, stats, err := llmstxt.Generate(ctx, llmstxt.Options{
+//		SitemapURL: "https://example.com/sitemap.xml",
+//	})
+package llmstxt
+
+import (
+	"context"
+
+	illmstxt "github.com/samzong/mdctl/internal/llmstxt"
+)
+
+// Stats describes a generation run
+type Stats = illmstxt.Stats
+
+// FailedURL is a page of Stats.Failures that could not be fetched or parsed
+type FailedURL = illmstxt.FailedURL
+
+// ErrLowSuccessRate is returned when fewer pages than Options.MinSuccessRate
+// asks for could be fetched
+var ErrLowSuccessRate = illmstxt.ErrLowSuccessRate
+
+// Options controls which pages are fetched and how
+type Options struct {
+	// SitemapURL is the sitemap.xml (or sitemap index, optionally gzipped) to
+	// read, or a site URL whose sitemaps are discovered from robots.txt
+	SitemapURL string
+	// IncludePaths and ExcludePaths are glob patterns matched against URL paths
+	IncludePaths []string
+	ExcludePaths []string
+	// FullMode includes the content of every page, not only titles and descriptions
+	FullMode bool
+	// Concurrency is the number of parallel requests, default 5
+	Concurrency int
+	// Timeout is the request timeout in seconds, default 30
+	Timeout int
+	// UserAgent is sent with every request
+	UserAgent string
+	// MaxPages limits the number of pages, 0 means no limit
+	MaxPages int
+	// Template is the path of a text/template file replacing the default format
+	Template string
+	// Lang keeps only pages in this language, based on <html lang>, hreflang
+	// and language prefixes of URL paths
+	Lang string
+	// MinSuccessRate fails the run when fewer than this share of the pages
+	// (0 to 1) could be fetched, 0 means no threshold
+	MinSuccessRate float64
+}
+
+// Generate fetches the pages listed in the sitemap and returns the llms.txt
+// content. When ctx is cancelled it returns ctx.Err() with the partial stats.
+func Generate(ctx context.Context, opts Options) (string, Stats, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 5
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = "mdctl"
+	}
+
+	generator := illmstxt.NewGenerator(illmstxt.GeneratorConfig{
+		SitemapURL:   opts.SitemapURL,
+		IncludePaths: opts.IncludePaths,
+		ExcludePaths: opts.ExcludePaths,
+		FullMode:     opts.FullMode,
+		Concurrency:  opts.Concurrency,
+		Timeout:      opts.Timeout,
+		UserAgent:    opts.UserAgent,
+		MaxPages:     opts.MaxPages,
+		Template:     opts.Template,
+		Lang:         opts.Lang,
+
+		MinSuccessRate: opts.MinSuccessRate,
+	})
+	content, err := generator.Generate(ctx)
+	return content, generator.Stats(), err
+}
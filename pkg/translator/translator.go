@@ -0,0 +1,150 @@
+// Package translator translates markdown files and string catalogs with an
+// OpenAI-compatible chat completions API.
+//
+//	translated, err := translator.TranslateContent(ctx, content, "zh", translator.Options{
+//		APIKey: os.Getenv("OPENAI_API_KEY"),
+//	})
+package translator
+
+import (
+	"context"
+
+	"github.com/samzong/mdctl/internal/config"
+	itranslator "github.com/samzong/mdctl/internal/translator"
+)
+
+// Report collects per-file outcomes of file and directory translation
+type Report = itranslator.Report
+
+// FileResult is the outcome of a single file
+type FileResult = itranslator.FileResult
+
+// Bilingual modes of Options.Bilingual
+const (
+	BilingualAlternate = itranslator.BilingualAlternate
+	BilingualComments  = itranslator.BilingualComments
+)
+
+// Options configures the model and the translation behaviour
+type Options struct {
+	// Endpoint is the API base URL, default https://api.openai.com/v1
+	Endpoint string
+	// APIKey authenticates against the endpoint
+	APIKey string
+	// Model defaults to the mdctl default model
+	Model string
+	// Prompt is the system prompt, {TARGET_LANG} is replaced by the language
+	Prompt string
+	// Temperature and TopP are passed to the model, TopP defaults to 1
+	Temperature float64
+	TopP        float64
+	// RequestsPerMinute, TokensPerMinute and DailyTokenQuota throttle AI usage
+	RequestsPerMinute int
+	TokensPerMinute   int
+	DailyTokenQuota   int
+
+	// Format formats the markdown after translation
+	Format bool
+	// Force translates files whose target is already marked as translated
+	Force bool
+	// Catalogs also translates YAML/TOML/JSON string catalogs in directories
+	Catalogs bool
+	// MDX protects JSX/HTML components, import/export statements and
+	// expressions, .mdx files are always translated so
+	MDX bool
+	// DryRun only reports the files that would be translated and the estimated tokens
+	DryRun bool
+	// Resume skips the files finished by the previous run of the same directory
+	Resume bool
+	// ContinueOnError keeps translating a directory when a file fails
+	ContinueOnError bool
+	// StructureRetries is the number of repair requests of translations
+	// whose headings, code blocks, links or tables differ from the source
+	// (0 for one, negative for none). Files that still differ are written
+	// and flagged in the report, StrictStructure fails them instead.
+	StructureRetries int
+	StrictStructure  bool
+	// Bilingual keeps the source paragraphs of translated files before their
+	// translations, visible (BilingualAlternate) or in HTML comments
+	// (BilingualComments)
+	Bilingual string
+}
+
+// IsLanguageSupported reports whether lang is a supported language code
+func IsLanguageSupported(lang string) bool {
+	return itranslator.IsLanguageSupported(lang)
+}
+
+// config converts the options to the internal configuration
+func (o Options) config() *config.Config {
+	cfg := config.DefaultConfig
+	if o.Endpoint != "" {
+		cfg.OpenAIEndpointURL = o.Endpoint
+	}
+	cfg.OpenAIAPIKey = o.APIKey
+	if o.Model != "" {
+		cfg.ModelName = o.Model
+	}
+	if o.Prompt != "" {
+		cfg.TranslatePrompt = o.Prompt
+	}
+	cfg.Temperature = o.Temperature
+	if o.TopP != 0 {
+		cfg.TopP = o.TopP
+	}
+	cfg.AIRequestsPerMin = o.RequestsPerMinute
+	cfg.AITokensPerMin = o.TokensPerMinute
+	cfg.AIDailyTokenQuota = o.DailyTokenQuota
+	return &cfg
+}
+
+// internal converts the options to the internal file options
+func (o Options) internal(report *Report) itranslator.Options {
+	return itranslator.Options{
+		Format:   o.Format,
+		Force:    o.Force,
+		Catalogs: o.Catalogs,
+		MDX:      o.MDX,
+		DryRun:   o.DryRun,
+		Report:   report,
+
+		Resume:          o.Resume,
+		ContinueOnError: o.ContinueOnError,
+
+		StructureRetries: o.StructureRetries,
+		StrictStructure:  o.StrictStructure,
+
+		Bilingual: o.Bilingual,
+	}
+}
+
+// TranslateContent translates markdown content, front matter is dropped
+func TranslateContent(ctx context.Context, content, lang string, opts Options) (string, error) {
+	return itranslator.New(opts.config(), opts.Format).WithContext(ctx).WithMDX(opts.MDX).TranslateContent(content, lang)
+}
+
+// TranslateFile translates a markdown file or string catalog to dst
+func TranslateFile(ctx context.Context, src, dst, lang string, opts Options) (*Report, error) {
+	report := &Report{}
+	err := itranslator.ProcessFile(ctx, src, dst, lang, opts.config(), opts.internal(report))
+	return report, err
+}
+
+// TranslateDirectory translates all markdown files below srcDir into dstDir
+func TranslateDirectory(ctx context.Context, srcDir, dstDir, lang string, opts Options) (*Report, error) {
+	report := &Report{}
+	err := itranslator.ProcessDirectory(ctx, srcDir, dstDir, lang, opts.config(), opts.internal(report))
+	return report, err
+}
+
+// StripSource removes the source paragraphs a bilingual translation kept and
+// returns the content and the number of paragraphs removed
+func StripSource(content string) (string, int) {
+	return itranslator.StripSource(content)
+}
+
+// Summarize returns an abstract of markdown content of at most words words,
+// in lang or the language of the content when lang is empty
+func Summarize(ctx context.Context, content string, words int, lang string, opts Options) (string, error) {
+	return itranslator.New(opts.config(), false).WithContext(ctx).Summarize(content, words, lang)
+}
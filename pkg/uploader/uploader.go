@@ -0,0 +1,110 @@
+// Package uploader uploads the local images referenced by markdown files to
+// S3-compatible cloud storage and rewrites the links to the uploaded URLs.
+//
+//	stats, err := uploader.Upload(ctx, uploader.Options{
+//		SourceDir: "docs",
+//		Storage: &uploader.Storage{
+//			Provider:  "s3",
+//			Region:    "us-east-1",
+//			Bucket:    "my-docs",
+//			AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+//			SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+//		},
+//	})
+package uploader
+
+import (
+	"context"
+
+	"github.com/samzong/mdctl/internal/config"
+	iuploader "github.com/samzong/mdctl/internal/uploader"
+)
+
+// Storage holds the cloud storage settings (provider, endpoint, credentials, bucket)
+type Storage = config.CloudConfig
+
+// Stats describes an upload run
+type Stats = iuploader.FileStats
+
+// ConflictPolicy defines how to handle names that already exist remotely
+type ConflictPolicy = iuploader.ConflictPolicy
+
+// Conflict policies
+const (
+	ConflictRename    = iuploader.ConflictPolicyRename
+	ConflictVersion   = iuploader.ConflictPolicyVersion
+	ConflictOverwrite = iuploader.ConflictPolicyOverwrite
+)
+
+// Options controls an upload run
+type Options struct {
+	// SourceFile or SourceDir selects the markdown to process
+	SourceFile string
+	SourceDir  string
+	// Include and Exclude are globs of the markdown files of SourceDir to
+	// process or skip, such as "posts/**"
+	Include []string
+	Exclude []string
+	// Storage configures the target. When nil, the default storage of the
+	// mdctl configuration file (~/.config/mdctl/config.json) is used.
+	Storage *Storage
+	// DryRun reports what would be uploaded without uploading or rewriting
+	DryRun bool
+	// Concurrency is the number of parallel uploads, default 5
+	Concurrency int
+	// ForceUpload uploads images even if they are in the upload cache
+	ForceUpload bool
+	// ConflictPolicy defaults to ConflictRename
+	ConflictPolicy ConflictPolicy
+	// CacheDir holds the upload cache, default ~/.cache/mdctl
+	CacheDir string
+	// AltText fills the empty alt text of rewritten images: "filename" uses
+	// the words of the file name, "ai" the result of Caption
+	AltText string
+	Caption func(imagePath string) (string, error)
+	// LinkStyle writes the rewritten images as "inline" markdown (default),
+	// "html" img tags with the ImageWidth width attribute (pixels or a
+	// percentage) or "reference" links with definitions at the end of the file
+	LinkStyle  string
+	ImageWidth string
+	// AssetsKeys are front matter fields whose values are images to upload
+	// and rewrite as well, e.g. "cover" or "cover.image" for nested fields
+	AssetsKeys []string
+	// Provenance records the original destinations of rewritten images with
+	// their URLs: "frontmatter" in the mdctl_assets field of each file,
+	// "sidecar" in .mdctl-assets.json of the source directory
+	Provenance string
+}
+
+// Upload uploads the images and rewrites the markdown files. When ctx is
+// cancelled no new uploads start and finished ones are still written back.
+func Upload(ctx context.Context, opts Options) (*Stats, error) {
+	cfg := iuploader.UploaderConfig{
+		SourceFile:     opts.SourceFile,
+		SourceDir:      opts.SourceDir,
+		Include:        opts.Include,
+		Exclude:        opts.Exclude,
+		DryRun:         opts.DryRun,
+		Concurrency:    opts.Concurrency,
+		ForceUpload:    opts.ForceUpload,
+		ConflictPolicy: opts.ConflictPolicy,
+		CacheDir:       opts.CacheDir,
+		Storage:        opts.Storage,
+		AltText:        opts.AltText,
+		Caption:        opts.Caption,
+		LinkStyle:      opts.LinkStyle,
+		ImageWidth:     opts.ImageWidth,
+		AssetsKeys:     opts.AssetsKeys,
+		Provenance:     opts.Provenance,
+	}
+	if opts.Storage != nil {
+		cfg.Provider = opts.Storage.Provider
+		cfg.Bucket = opts.Storage.Bucket
+	}
+
+	up, err := iuploader.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return up.Process(ctx)
+}